@@ -0,0 +1,60 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+/*
+
+Allocations of a projected output row, a positional Row shares its
+column index across rows so is one slice per row instead of a map
+
+BenchmarkOutputContextSimple	 3371056	   350.1 ns/op	  416 B/op	  3 allocs/op
+BenchmarkOutputRow		 4847193	   254.3 ns/op	  160 B/op	  2 allocs/op
+
+go test -run x -bench="Output" -benchmem
+
+*/
+
+var (
+	bmCols = []string{"user_id", "name", "email", "ct", "score", "created"}
+	bmOut  expr.ContextReader // rows escape, as they do onto a channel
+)
+
+func BenchmarkOutputContextSimple(b *testing.B) {
+	b.ReportAllocs()
+	cols := bmColumns()
+	v := value.NewIntValue(1)
+	for i := 0; i < b.N; i++ {
+		row := NewContextSimple()
+		for _, col := range cols {
+			row.Put(col, nil, v)
+		}
+		bmOut = row
+	}
+}
+
+func BenchmarkOutputRow(b *testing.B) {
+	b.ReportAllocs()
+	cols := bmColumns()
+	idx := ColumnIndex(bmCols)
+	v := value.NewIntValue(1)
+	for i := 0; i < b.N; i++ {
+		row := NewRow(uint64(i), idx, make([]value.Value, len(bmCols)))
+		for _, col := range cols {
+			row.Put(col, nil, v)
+		}
+		bmOut = row
+	}
+}
+
+func bmColumns() []*expr.Column {
+	cols := make([]*expr.Column, len(bmCols))
+	for i, name := range bmCols {
+		cols[i] = &expr.Column{As: name}
+	}
+	return cols
+}
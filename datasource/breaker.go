@@ -0,0 +1,114 @@
+package datasource
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/araddon/qlbridge/logging"
+)
+
+var (
+	_ DataSource = (*CircuitBreakerSource)(nil)
+
+	// Open fast-fails with this while the circuit is open
+	ErrCircuitOpen = fmt.Errorf("datasource: circuit open")
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // normal, calls go through
+	CircuitOpen                         // failing, calls fast-fail until cooldown
+	CircuitHalfOpen                     // cooled down, one probe call is allowed
+)
+
+func (m CircuitState) String() string {
+	switch m {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerSource wraps a DataSource so a failing backend is not hammered,
+//  after @Threshold consecutive Open failures the circuit opens and Open
+//  fast-fails with ErrCircuitOpen for @Cooldown, then it half-opens letting
+//  one probe through:  success closes it, failure re-opens it.
+//
+//     src := NewCircuitBreakerSource(NewRestDataSource(...), 5, 30*time.Second)
+type CircuitBreakerSource struct {
+	DataSource
+	Threshold int
+	Cooldown  time.Duration
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int       // consecutive failures
+	openedAt  time.Time // when the circuit last opened
+	probing   bool      // a half-open probe is in flight
+	now       func() time.Time
+}
+
+func NewCircuitBreakerSource(src DataSource, threshold int, cooldown time.Duration) *CircuitBreakerSource {
+	return &CircuitBreakerSource{DataSource: src, Threshold: threshold, Cooldown: cooldown, now: time.Now}
+}
+
+// State of the circuit, an open circuit past its cooldown is half-open
+func (m *CircuitBreakerSource) State() CircuitState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.currentState()
+}
+
+func (m *CircuitBreakerSource) currentState() CircuitState {
+	if m.state == CircuitOpen && !m.clock().Before(m.openedAt.Add(m.Cooldown)) {
+		m.state = CircuitHalfOpen
+	}
+	return m.state
+}
+
+func (m *CircuitBreakerSource) clock() time.Time {
+	if m.now == nil {
+		return time.Now()
+	}
+	return m.now()
+}
+
+func (m *CircuitBreakerSource) Open(connInfo string) (SourceConn, error) {
+	m.mu.Lock()
+	switch m.currentState() {
+	case CircuitOpen:
+		m.mu.Unlock()
+		return nil, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if m.probing {
+			m.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		m.probing = true
+	}
+	m.mu.Unlock()
+
+	conn, err := m.DataSource.Open(connInfo)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probing = false
+	if err == nil {
+		if m.state != CircuitClosed {
+			logging.Infof("circuit closed for %s", connInfo)
+		}
+		m.state, m.failures = CircuitClosed, 0
+		return conn, nil
+	}
+	m.failures++
+	if m.state == CircuitHalfOpen || m.failures >= m.Threshold {
+		logging.Warnf("circuit open for %s after %d failures: %v", connInfo, m.failures, err)
+		m.state, m.openedAt = CircuitOpen, m.clock()
+	}
+	return nil, err
+}
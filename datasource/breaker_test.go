@@ -0,0 +1,51 @@
+package datasource
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestCircuitBreakerSource(t *testing.T) {
+
+	now := time.Now()
+	flaky := &flakySource{openFails: 1000, err: fmt.Errorf("connection refused")}
+	cb := NewCircuitBreakerSource(flaky, 3, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	assert.Tf(t, cb.State() == CircuitClosed, "starts closed: %v", cb.State())
+	for i := 0; i < 3; i++ {
+		assert.Tf(t, cb.State() == CircuitClosed, "closed until threshold: %v", cb.State())
+		_, err := cb.Open("flaky")
+		assert.Tf(t, err == flaky.err, "source error %v", err)
+	}
+	assert.Tf(t, cb.State() == CircuitOpen, "open: %v", cb.State())
+
+	// fast-fails without calling the source
+	_, err := cb.Open("flaky")
+	assert.Tf(t, err == ErrCircuitOpen && flaky.opens == 3, "fast-fail %v opens=%v", err, flaky.opens)
+
+	// after cooldown a failed probe re-opens it
+	now = now.Add(time.Minute)
+	assert.Tf(t, cb.State() == CircuitHalfOpen, "half-open: %v", cb.State())
+	_, err = cb.Open("flaky")
+	assert.Tf(t, err == flaky.err && flaky.opens == 4, "probe %v", err)
+	assert.Tf(t, cb.State() == CircuitOpen, "re-opened: %v", cb.State())
+
+	// a good probe closes it
+	now = now.Add(time.Minute)
+	assert.Tf(t, cb.State() == CircuitHalfOpen, "half-open: %v", cb.State())
+	flaky.openFails = 0
+	conn, err := cb.Open("flaky")
+	assert.Tf(t, err == nil && conn != nil, "probe ok %v", err)
+	assert.Tf(t, cb.State() == CircuitClosed, "closed: %v", cb.State())
+
+	// failures must be consecutive
+	flaky.openFails, flaky.opens = 2, 0
+	cb.Open("flaky")
+	cb.Open("flaky")
+	cb.Open("flaky")
+	assert.Tf(t, cb.State() == CircuitClosed, "success resets count: %v", cb.State())
+}
@@ -6,17 +6,22 @@ import (
 	"net/url"
 	"time"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
 var (
-	_ expr.ContextWriter = (*ContextSimple)(nil)
-	_ expr.ContextReader = (*ContextSimple)(nil)
-	_ expr.ContextWriter = (*ContextUrlValues)(nil)
-	_ expr.ContextReader = (*ContextUrlValues)(nil)
-	_                    = u.EMPTY
+	_ expr.ContextWriter       = (*ContextSimple)(nil)
+	_ expr.ContextReader       = (*ContextSimple)(nil)
+	_ expr.ContextDivideByZero = (*ContextSimple)(nil)
+	_ expr.ContextIntOverflow  = (*ContextSimple)(nil)
+	_ expr.ContextCollation    = (*ContextSimple)(nil)
+	_ expr.ContextWriter       = (*ContextUrlValues)(nil)
+	_ expr.ContextReader       = (*ContextUrlValues)(nil)
+	_ expr.ContextReader       = (*ContextMerged)(nil)
+	_ expr.ContextReader       = (*Row)(nil)
+	_ expr.ContextWriter       = (*Row)(nil)
 )
 
 // represents a message routable by the topology. The Key() method
@@ -61,9 +66,9 @@ func (m *ValueContextWrapper) Get(key string) (value.Value, bool) {
 		if col.Index < len(m.Vals) {
 			return value.NewValue(m.Vals[col.Index]), true
 		}
-		u.Warnf("could not find index?: %v col.idx:%v   len(vals)=%v", key, col.Index, len(m.Vals))
+		logging.Warnf("could not find index?: %v col.idx:%v   len(vals)=%v", key, col.Index, len(m.Vals))
 	} else {
-		u.Warnf("could not find key: %v", key)
+		logging.Warnf("could not find key: %v", key)
 	}
 	return value.ErrValue, false
 }
@@ -93,6 +98,14 @@ func (m *UrlValuesMsg) String() string    { return m.body.String() }
 
 type ContextSimple struct {
 	Data map[string]value.Value
+	// Policy for  x / 0  during evaluation against this context
+	DivZero expr.DivideByZeroPolicy
+	// Policy for int64 overflow of  + - * /
+	Overflow expr.IntOverflowPolicy
+	// String collation for comparisons, nil = binary
+	Collate value.Collation
+	// Policy for NULL args of greatest(), least()
+	NullArgsPolicy expr.NullArgsPolicy
 	//Rows   []map[string]value.Value
 	ts     time.Time
 	cursor int
@@ -114,13 +127,21 @@ func (m *ContextSimple) Row() map[string]value.Value { return m.Data }
 func (m *ContextSimple) Body() interface{}           { return m }
 func (m *ContextSimple) Key() uint64                 { return m.keyval }
 func (m *ContextSimple) Ts() time.Time               { return m.ts }
+func (m *ContextSimple) DivideByZero() expr.DivideByZeroPolicy {
+	return m.DivZero
+}
+func (m *ContextSimple) IntOverflow() expr.IntOverflowPolicy {
+	return m.Overflow
+}
+func (m *ContextSimple) Collation() value.Collation    { return m.Collate }
+func (m *ContextSimple) NullArgs() expr.NullArgsPolicy { return m.NullArgsPolicy }
 func (m ContextSimple) Get(key string) (value.Value, bool) {
 	val, ok := m.Data[key]
 	return val, ok
 }
 
 func (m *ContextSimple) Put(col expr.SchemaInfo, rctx expr.ContextReader, v value.Value) error {
-	//u.Infof("put context:  %v %T:%v", col.Key(), v, v)
+	//logging.Infof("put context:  %v %T:%v", col.Key(), v, v)
 	m.Data[col.Key()] = v
 	return nil
 }
@@ -133,6 +154,66 @@ func (m *ContextSimple) Delete(row map[string]value.Value) error {
 	return nil
 }
 
+// Row is a positional row, its values are a slice indexed by a column index
+//  shared by all rows of a result (see ColumnIndex), so a row is one
+//  []value.Value instead of a map per row.
+//
+//     cols := datasource.ColumnIndex([]string{"user_id", "ct"})
+//     row := datasource.NewRow(key, cols, []value.Value{uid, ct})
+type Row struct {
+	Vals []value.Value
+	cols map[string]int
+	key  uint64
+	ts   time.Time
+}
+
+// ColumnIndex is the position of each column, for Rows
+func ColumnIndex(cols []string) map[string]int {
+	idx := make(map[string]int, len(cols))
+	for i, col := range cols {
+		idx[col] = i
+	}
+	return idx
+}
+
+func NewRow(key uint64, cols map[string]int, vals []value.Value) *Row {
+	return &Row{Vals: vals, cols: cols, key: key, ts: time.Now()}
+}
+
+func (m *Row) Key() uint64       { return m.key }
+func (m *Row) Body() interface{} { return m }
+func (m *Row) Ts() time.Time     { return m.ts }
+
+// Get the value of a column, nil values (ie, not set) are not found
+func (m *Row) Get(key string) (value.Value, bool) {
+	if i, ok := m.cols[key]; ok && i < len(m.Vals) && m.Vals[i] != nil {
+		return m.Vals[i], true
+	}
+	return nil, false
+}
+
+// Row is a map of the row, this allocates, Get is preferred
+func (m *Row) Row() map[string]value.Value {
+	row := make(map[string]value.Value, len(m.cols))
+	for col, i := range m.cols {
+		if i < len(m.Vals) && m.Vals[i] != nil {
+			row[col] = m.Vals[i]
+		}
+	}
+	return row
+}
+
+func (m *Row) Put(col expr.SchemaInfo, rctx expr.ContextReader, v value.Value) error {
+	i, ok := m.cols[col.Key()]
+	if !ok || i >= len(m.Vals) {
+		return fmt.Errorf("no column %q in row", col.Key())
+	}
+	m.Vals[i] = v
+	return nil
+}
+
+func (m *Row) Delete(row map[string]value.Value) error { return nil }
+
 type ContextWriterEmpty struct{}
 
 func (m *ContextWriterEmpty) Put(col expr.SchemaInfo, rctx expr.ContextReader, v value.Value) error {
@@ -195,3 +276,51 @@ func (m ContextUrlValues) Put(col expr.SchemaInfo, rctx expr.ContextReader, v va
 	}
 	return nil
 }
+
+// ContextMerged is an overlay of multiple readers, Get() tries
+// each reader in order and returns first found value.  Useful for
+// stacking a row on top of defaults/params.
+type ContextMerged struct {
+	readers []expr.ContextReader
+}
+
+// Create a merged reader, precedence is order of @readers, first wins
+func NewContextMerged(readers ...expr.ContextReader) expr.ContextReader {
+	return &ContextMerged{readers: readers}
+}
+
+func (m *ContextMerged) Get(key string) (value.Value, bool) {
+	for _, r := range m.readers {
+		if r == nil {
+			continue
+		}
+		if val, ok := r.Get(key); ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// Row merges all rows, earlier readers take precedence over later
+func (m *ContextMerged) Row() map[string]value.Value {
+	row := make(map[string]value.Value)
+	for i := len(m.readers) - 1; i >= 0; i-- {
+		if m.readers[i] == nil {
+			continue
+		}
+		for k, v := range m.readers[i].Row() {
+			row[k] = v
+		}
+	}
+	return row
+}
+
+// Ts comes from the first reader
+func (m *ContextMerged) Ts() time.Time {
+	for _, r := range m.readers {
+		if r != nil {
+			return r.Ts()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,46 @@
+package datasource
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func TestContextMerged(t *testing.T) {
+
+	ts := time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+	row := NewContextSimpleTs(map[string]value.Value{
+		"name": value.NewStringValue("bob"),
+		"age":  value.NewIntValue(22),
+	}, ts)
+	defaults := NewContextUrlValues(url.Values{
+		"name":    {"default-name"},
+		"country": {"us"},
+	})
+
+	ctx := NewContextMerged(row, defaults)
+
+	// first reader takes precedence
+	v, ok := ctx.Get("name")
+	assert.T(t, ok)
+	assert.Tf(t, v.ToString() == "bob", "should be bob: %v", v)
+
+	// falls through to second reader
+	v, ok = ctx.Get("country")
+	assert.T(t, ok)
+	assert.Tf(t, v.ToString() == "us", "should be us: %v", v)
+
+	_, ok = ctx.Get("missing")
+	assert.T(t, !ok)
+
+	merged := ctx.Row()
+	assert.Tf(t, len(merged) == 3, "should have 3 cols: %v", merged)
+	assert.Tf(t, merged["name"].ToString() == "bob", "should be bob: %v", merged["name"])
+	assert.Tf(t, merged["age"].ToString() == "22", "should be 22: %v", merged["age"])
+	assert.Tf(t, merged["country"].ToString() == "us", "should be us: %v", merged["country"])
+
+	assert.Tf(t, ctx.Ts().Equal(ts), "Ts should come from first reader: %v", ctx.Ts())
+}
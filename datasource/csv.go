@@ -5,10 +5,12 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
 )
 
 /*
@@ -18,12 +20,11 @@ TODO:
    - support scanning/seeking by "partition" especially date based (ie, last 2 weeks )
    - share much code with json reader or flat-buffer etc
    - allow custom protobuf types
-   - allow reading x rows for type introspection
 
 */
 func init() {
 	// Note, we do not register this as it is in datasource
-	//   TODO:  move to its own folder
+	//   see datasource/csv which does
 	//datasource.Register("csv", &datasource.CsvDataSource{})
 }
 
@@ -36,6 +37,8 @@ var (
 // Csv DataStoure, implements qlbridge DataSource to scan through data
 //   see interfaces possible but they are
 //
+//  the header row is the column names, values are strings (coerced as
+//  needed when evaluated) unless InferTypes
 type CsvDataSource struct {
 	exit    <-chan bool
 	csvr    *csv.Reader
@@ -43,6 +46,11 @@ type CsvDataSource struct {
 	headers []string
 	rc      io.ReadCloser
 	filter  expr.Node
+
+	// InferTypes types each column by its first non-empty value (int,
+	//  number, bool else string), rows are then typed values
+	InferTypes bool
+	types      []value.ValueType
 }
 
 // Csv reader assumes we are getting first row as headers
@@ -61,13 +69,27 @@ func NewCsvSource(ior io.Reader, exit <-chan bool) (*CsvDataSource, error) {
 	// }
 	headers, err := m.csvr.Read()
 	if err != nil {
-		u.Warnf("err csv %v", err)
+		logging.Warnf("err csv %v", err)
 		return nil, err
 	}
 	m.headers = headers
 	return &m, nil
 }
 
+// NewCsvSourceTyped is a csv source with InferTypes, see NewCsvSource
+func NewCsvSourceTyped(ior io.Reader, exit <-chan bool) (*CsvDataSource, error) {
+	m, err := NewCsvSource(ior, exit)
+	if err != nil {
+		return nil, err
+	}
+	m.InferTypes = true
+	m.types = make([]value.ValueType, len(m.headers))
+	for i := range m.types {
+		m.types[i] = value.UnknownType
+	}
+	return m, nil
+}
+
 func (m *CsvDataSource) Tables() []string { return []string{"csv"} }
 
 func (m *CsvDataSource) Open(connInfo string) (SourceConn, error) {
@@ -76,13 +98,16 @@ func (m *CsvDataSource) Open(connInfo string) (SourceConn, error) {
 		return nil, err
 	}
 	exit := make(<-chan bool, 1)
+	if m.InferTypes {
+		return NewCsvSourceTyped(f, exit)
+	}
 	return NewCsvSource(f, exit)
 }
 
 func (m *CsvDataSource) Close() error {
 	defer func() {
 		if r := recover(); r != nil {
-			u.Errorf("close error: %v", r)
+			logging.Errorf("close error: %v", r)
 		}
 	}()
 	if m.rc != nil {
@@ -102,9 +127,9 @@ func (m *CsvDataSource) MesgChan(filter expr.Node) <-chan Message {
 }
 
 func (m *CsvDataSource) Next() Message {
-	u.Debugf("csv: %T %#v", m, m)
+	logging.Debugf("csv: %T %#v", m, m)
 	if m == nil {
-		u.Warnf("nil csv? ")
+		logging.Warnf("nil csv? ")
 	}
 	select {
 	case <-m.exit:
@@ -112,15 +137,18 @@ func (m *CsvDataSource) Next() Message {
 	default:
 		for {
 			row, err := m.csvr.Read()
-			//u.Debugf("row:   %v   %v", row, err)
+			//logging.Debugf("row:   %v   %v", row, err)
 			if err != nil {
 				if err == io.EOF {
 					return nil
 				}
-				u.Warnf("could not read row? %v", err)
+				logging.Warnf("could not read row? %v", err)
 				continue
 			}
 			m.rowct++
+			if m.types != nil {
+				return m.typedRow(row)
+			}
 			v := make(url.Values)
 
 			// If values exist for desired indexes, set them.
@@ -136,3 +164,58 @@ func (m *CsvDataSource) Next() Message {
 	}
 
 }
+
+func (m *CsvDataSource) typedRow(row []string) Message {
+	vals := make(map[string]value.Value, len(m.headers))
+	for idx, fieldName := range m.headers {
+		if idx > len(row)-1 {
+			continue
+		}
+		field := strings.TrimSpace(row[idx])
+		if field == "" {
+			vals[fieldName] = value.NewNilValue()
+			continue
+		}
+		if m.types[idx] == value.UnknownType {
+			m.types[idx] = inferCsvType(field)
+		}
+		vals[fieldName] = csvValue(m.types[idx], field)
+	}
+	return NewContextSimpleData(vals)
+}
+
+func inferCsvType(field string) value.ValueType {
+	if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return value.IntType
+	}
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return value.NumberType
+	}
+	if value.IsBool(field) {
+		return value.BoolType
+	}
+	return value.StringType
+}
+
+// the value of @field as its column type, a value that is not is a string
+func csvValue(vt value.ValueType, field string) value.Value {
+	switch vt {
+	case value.IntType:
+		if iv, err := strconv.ParseInt(field, 10, 64); err == nil {
+			return value.NewIntValue(iv)
+		}
+		// an int column may have floats in later rows
+		if fv, err := strconv.ParseFloat(field, 64); err == nil {
+			return value.NewNumberValue(fv)
+		}
+	case value.NumberType:
+		if fv, err := strconv.ParseFloat(field, 64); err == nil {
+			return value.NewNumberValue(fv)
+		}
+	case value.BoolType:
+		if value.IsBool(field) {
+			return value.NewBoolValue(value.BoolStringVal(field))
+		}
+	}
+	return value.NewStringValue(field)
+}
@@ -0,0 +1,15 @@
+// Package csv registers the "csv" DataSource, which reads csv files opened
+//  by name, the header row is the column names
+//
+//     import _ "github.com/araddon/qlbridge/datasource/csv"
+//
+//     SELECT user_id, email FROM `users.csv` WHERE item_count > 10
+package csv
+
+import (
+	"github.com/araddon/qlbridge/datasource"
+)
+
+func init() {
+	datasource.Register("csv", &datasource.CsvDataSource{})
+}
@@ -3,6 +3,7 @@ package datasource
 import (
 	"fmt"
 	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/value"
 	"github.com/bmizerany/assert"
 	"strings"
 	"testing"
@@ -53,3 +54,26 @@ func TestCsvDatasource(t *testing.T) {
 	}
 	assert.Tf(t, iterCt == 3, "should have 3 rows: %v", iterCt)
 }
+
+func TestCsvDatasourceTyped(t *testing.T) {
+	csvIn, err := NewCsvSourceTyped(strings.NewReader(testData["user.csv"]+"\nxyz,,\"golf\",,7.5"), make(<-chan bool, 1))
+	assert.Tf(t, err == nil, "should not have error: %v", err)
+	iter := csvIn.CreateIterator(nil)
+	rows := make([]*ContextSimple, 0)
+	for msg := iter.Next(); msg != nil; msg = iter.Next() {
+		row, ok := msg.(*ContextSimple)
+		assert.Tf(t, ok, "typed rows are ContextSimple: %T", msg)
+		rows = append(rows, row)
+	}
+	assert.Tf(t, len(rows) == 4, "should have 4 rows: %v", len(rows))
+
+	ct, _ := rows[0].Get("item_count")
+	assert.Tf(t, ct.Type() == value.IntType && ct.Value() == int64(82), "int column: %#v", ct)
+	email, _ := rows[1].Get("email")
+	assert.Tf(t, email.Type() == value.StringType && email.ToString() == "bob@email.com", "string column: %#v", email)
+	// empty is nil, an int column may have floats
+	email, _ = rows[3].Get("email")
+	assert.Tf(t, email.Type() == value.NilType, "empty field: %#v", email)
+	ct, _ = rows[3].Get("item_count")
+	assert.Tf(t, ct.Type() == value.NumberType && ct.Value() == 7.5, "float in int column: %#v", ct)
+}
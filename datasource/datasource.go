@@ -5,12 +5,15 @@ import (
 	"strings"
 	"sync"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
 )
 
 var (
-	_ = u.EMPTY
+	// Debug turns on the (verbose) diagnostic logging of the registry on
+	//  Register() and Get(), logged at Debug level
+	Debug = false
 
 	// the data sources mutex
 	sourceMu sync.Mutex
@@ -40,6 +43,54 @@ type Features struct {
 	GroupBy      bool
 	Sort         bool
 	Aggregations bool
+	Planner      bool // plans its own execution, SourcePlanner
+	Schema       bool
+	Insert       bool
+	Update       bool
+	Delete       bool
+	Search       bool // full text search, FullTextSearcher
+}
+
+type featureFlag struct {
+	name string
+	has  bool
+}
+
+// the features by name, in a fixed order
+func (m Features) flags() []featureFlag {
+	return []featureFlag{
+		{"scan", m.Scan}, {"seek", m.Seek}, {"where", m.Where}, {"groupby", m.GroupBy},
+		{"sort", m.Sort}, {"aggregations", m.Aggregations}, {"planner", m.Planner},
+		{"schema", m.Schema}, {"insert", m.Insert}, {"update", m.Update}, {"delete", m.Delete},
+		{"search", m.Search},
+	}
+}
+
+// Supported is the names of the supported features
+func (m Features) Supported() []string {
+	supported := make([]string, 0)
+	for _, f := range m.flags() {
+		if f.has {
+			supported = append(supported, f.name)
+		}
+	}
+	return supported
+}
+
+// Unsupported is the names of the features the engine must provide itself
+func (m Features) Unsupported() []string {
+	unsupported := make([]string, 0)
+	for _, f := range m.flags() {
+		if !f.has {
+			unsupported = append(unsupported, f.name)
+		}
+	}
+	return unsupported
+}
+
+func (m Features) String() string {
+	return fmt.Sprintf("supports: [%s] does not support: [%s]",
+		strings.Join(m.Supported(), " "), strings.Join(m.Unsupported(), " "))
 }
 
 // A datasource is most likely a database, file, api, in-mem data etc
@@ -97,6 +148,17 @@ type DataSourceFeatures struct {
 	DataSource
 }
 
+// Describe the features the source claims to support, ie for debugging why
+//  a clause is not pushed down to the source
+func (m *DataSourceFeatures) Describe() Features { return m.Features }
+
+// Summary is a human readable description of the source capabilities
+//
+//     *datasource.CsvDataSource supports: [scan] does not support: [seek where ...]
+func (m *DataSourceFeatures) Summary() string {
+	return fmt.Sprintf("%T %s", m.DataSource, m.Features)
+}
+
 // A scanner, most basic of data sources, just iterate through
 //  rows without any optimizations
 type Scanner interface {
@@ -113,6 +175,13 @@ type Iterator interface {
 	Next() Message
 }
 
+// An Iterator which can tell if it ended (Next() returned nil) because of
+//  an error, rather than at the end of the rows
+type ErrIterator interface {
+	Iterator
+	Err() error
+}
+
 // Interface for Seeking row values instead of scanning (ie, Indexed)
 type Seeker interface {
 	DataSource
@@ -125,6 +194,21 @@ type Seeker interface {
 	//Scanner
 }
 
+// Seekers which can say which column of a table their Get() key is, so
+//  an equality on it may be planned as a seek instead of a scan
+type SeekKeyer interface {
+	SeekKey(table string) string
+}
+
+// Sources with a full text index, which answer the MATCH(cols) AGAINST(term)
+//  of a where instead of every row being scanned for the words of the term
+type FullTextSearcher interface {
+	// CanSearch is true if @cols of @table are full text indexed
+	CanSearch(table string, cols []string) bool
+	// the rows of @table where any of @cols match @against
+	Search(table string, cols []string, against string) (Iterator, error)
+}
+
 type WhereFilter interface {
 	DataSource
 	Filter(expr.SqlStatement) error
@@ -145,6 +229,40 @@ type Aggregations interface {
 	Aggregate(expr.SqlStatement) error
 }
 
+// Sources which can describe the columns of their tables, ie for
+//  insert column defaults
+type SchemaProvider interface {
+	Schema(table string) (*Schema, error)
+}
+
+// Sources which accept new rows, the row is keyed by column name, the
+//  source may add generated columns (ids etc) to row
+type Inserter interface {
+	Insert(row map[string]value.Value) error
+}
+
+// Sources which can replace or delete rows, identified by the Key() of
+//  the messages they were scanned as
+type Updater interface {
+	Put(key uint64, row map[string]value.Value) error
+}
+type Deleter interface {
+	Delete(key uint64) error
+}
+
+// Sources whose writes can be made atomic, the writes (Insert, Put,
+//  Delete) to the source between Begin and the Commit or Rollback of
+//  the Tx are one unit, all kept or none
+type Transactioner interface {
+	Begin() (Tx, error)
+}
+
+// Tx is a transaction begun by a Transactioner
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
 // Some data sources that implement more features, can provide
 //  their own projection.
 type Projection interface {
@@ -174,24 +292,54 @@ func NewFeaturedSource(src DataSource) *DataSourceFeatures {
 	if _, ok := src.(Seeker); ok {
 		f.Seek = true
 	}
+	if _, ok := src.(WhereFilter); ok {
+		f.Where = true
+	}
+	if _, ok := src.(GroupBy); ok {
+		f.GroupBy = true
+	}
+	if _, ok := src.(Sort); ok {
+		f.Sort = true
+	}
+	if _, ok := src.(Aggregations); ok {
+		f.Aggregations = true
+	}
+	if _, ok := src.(SourcePlanner); ok {
+		f.Planner = true
+	}
+	if _, ok := src.(SchemaProvider); ok {
+		f.Schema = true
+	}
+	if _, ok := src.(Inserter); ok {
+		f.Insert = true
+	}
+	if _, ok := src.(Updater); ok {
+		f.Update = true
+	}
+	if _, ok := src.(Deleter); ok {
+		f.Delete = true
+	}
+	if _, ok := src.(FullTextSearcher); ok {
+		f.Search = true
+	}
 	return &DataSourceFeatures{f, src}
 }
 
 func (m *DataSources) Get(sourceType string) *DataSourceFeatures {
 	if source, ok := m.sources[strings.ToLower(sourceType)]; ok {
-		u.Infof("found source: %v", sourceType)
+		debugf("found source: %v", sourceType)
 		return NewFeaturedSource(source)
 	}
 	if len(m.sources) == 1 {
 		for _, src := range m.sources {
-			u.Warnf("only one source?")
+			debugf("only one source?")
 			return NewFeaturedSource(src)
 		}
 	}
 	if sourceType == "" {
-		u.LogTracef(u.WARN, "No Source Type?")
+		debugf("No Source Type?")
 	} else {
-		u.Debugf("datasource.Get('%v')", sourceType)
+		debugf("datasource.Get('%v')", sourceType)
 	}
 
 	if len(m.tableSources) == 0 {
@@ -199,23 +347,23 @@ func (m *DataSources) Get(sourceType string) *DataSourceFeatures {
 			tbls := src.Tables()
 			for _, tbl := range tbls {
 				if _, ok := m.tableSources[tbl]; ok {
-					u.Warnf("table names must be unique across sources %v", tbl)
+					logging.Warnf("table names must be unique across sources %v", tbl)
 				} else {
-					u.Debugf("creating tbl/source: %v  %T", tbl, src)
+					debugf("creating tbl/source: %v  %T", tbl, src)
 					m.tableSources[tbl] = src
 				}
 			}
 		}
 	}
 	if src, ok := m.tableSources[sourceType]; ok {
-		u.Debugf("found src with %v", sourceType)
+		debugf("found src with %v", sourceType)
 		return NewFeaturedSource(src)
 	} else {
 		for src, _ := range m.sources {
-			u.Debugf("source: %v", src)
+			debugf("source: %v", src)
 		}
-		u.LogTracef(u.WARN, "No table?  len(sources)=%d len(tables)=%v", len(m.sources), len(m.tableSources))
-		u.Warnf("could not find table: %v  tables:%v", sourceType, m.tableSources)
+		debugf("No table?  len(sources)=%d len(tables)=%v", len(m.sources), len(m.tableSources))
+		logging.Warnf("could not find table: %v  tables:%v", sourceType, m.tableSources)
 	}
 	return nil
 }
@@ -228,6 +376,13 @@ func (m *DataSources) String() string {
 	return fmt.Sprintf("{Sources: [%s] }", strings.Join(sourceNames, ", "))
 }
 
+// registry diagnostics, only if Debug
+func debugf(format string, v ...interface{}) {
+	if Debug {
+		logging.Debugf(format, v...)
+	}
+}
+
 // get registry of all datasource types
 func DataSourcesRegistry() *DataSources {
 	return sources
@@ -241,8 +396,8 @@ func Register(name string, source DataSource) {
 		panic("qlbridge/datasource: Register driver is nil")
 	}
 	name = strings.ToLower(name)
-	u.Warnf("register datasource: %v %T", name, source)
-	//u.LogTracef(u.WARN, "adding source %T to registry", source)
+	debugf("register datasource: %v %T", name, source)
+	//logging.Warnf("adding source %T to registry", source)
 	sourceMu.Lock()
 	defer sourceMu.Unlock()
 	if _, dup := sources.sources[name]; dup {
@@ -272,17 +427,17 @@ func SourceIterChannel(iter Iterator, filter expr.Node, sigCh <-chan bool) <-cha
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				u.Errorf("recover panic: %v", r)
+				logging.Errorf("recover panic: %v", r)
 			}
 			// Can we safely close this?
 			close(out)
 		}()
 		for item := iter.Next(); item != nil; item = iter.Next() {
 
-			//u.Infof("In source Scanner iter %#v", item)
+			//logging.Infof("In source Scanner iter %#v", item)
 			select {
 			case <-sigCh:
-				u.Warnf("got signal quit")
+				logging.Warnf("got signal quit")
 
 				return
 			case out <- item:
@@ -0,0 +1,95 @@
+package datasource
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+// captures log lines as  "LEVEL msg"
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (m *testLogger) log(lvl, format string, v ...interface{}) {
+	m.mu.Lock()
+	m.lines = append(m.lines, lvl+" "+fmt.Sprintf(format, v...))
+	m.mu.Unlock()
+}
+func (m *testLogger) Debugf(format string, v ...interface{}) { m.log("DEBUG", format, v...) }
+func (m *testLogger) Infof(format string, v ...interface{})  { m.log("INFO", format, v...) }
+func (m *testLogger) Warnf(format string, v ...interface{})  { m.log("WARN", format, v...) }
+func (m *testLogger) Errorf(format string, v ...interface{}) { m.log("ERROR", format, v...) }
+
+func TestRegisterLogging(t *testing.T) {
+
+	prev := logging.Log()
+	defer logging.SetLogger(prev)
+	defer func() { Debug = false }()
+
+	logger := &testLogger{}
+	logging.SetLogger(logger)
+	Debug = true
+	Register("log_test_source", &CsvDataSource{})
+
+	registered := false
+	for _, line := range logger.lines {
+		assert.Tf(t, !strings.HasPrefix(line, "WARN"), "register should not warn: %v", line)
+		if strings.Contains(line, "log_test_source") {
+			registered = true
+		}
+	}
+	assert.Tf(t, registered, "register logs to the injected logger: %v", logger.lines)
+
+	// by default a normal Register + Get are quiet
+	Debug = false
+	logger = &testLogger{}
+	logging.SetLogger(logger)
+	Register("quiet_test_source", &CsvDataSource{})
+	assert.T(t, DataSourcesRegistry().Get("quiet_test_source") != nil)
+	assert.Tf(t, len(logger.lines) == 0, "no log output: %v", logger.lines)
+
+	// nil is the no-op logger
+	logging.SetLogger(nil)
+	_, isNop := logging.Log().(logging.NopLogger)
+	assert.T(t, isNop)
+}
+
+// implements Scanner, WhereFilter, Sort, SourcePlanner, SchemaProvider, Inserter
+//  and Updater
+type featureSource struct {
+	CsvDataSource
+}
+
+func (m *featureSource) Filter(expr.SqlStatement) error                   { return nil }
+func (m *featureSource) Sort(expr.SqlStatement) error                     { return nil }
+func (m *featureSource) Schema(table string) (*Schema, error)             { return NewSchema(table), nil }
+func (m *featureSource) Insert(row map[string]value.Value) error          { return nil }
+func (m *featureSource) Accept(expr.SubVisitor) (Scanner, error)          { return m, nil }
+func (m *featureSource) Put(key uint64, row map[string]value.Value) error { return nil }
+
+func TestFeaturesDescribe(t *testing.T) {
+
+	f := NewFeaturedSource(&featureSource{}).Describe()
+	assert.Tf(t, f == Features{Scan: true, Where: true, Sort: true, Planner: true, Schema: true, Insert: true, Update: true},
+		"features match implemented interfaces: %#v", f)
+	assert.Equal(t, []string{"scan", "where", "sort", "planner", "schema", "insert", "update"}, f.Supported())
+	assert.Equal(t, []string{"seek", "groupby", "aggregations", "delete", "search"}, f.Unsupported())
+
+	csv := NewFeaturedSource(&CsvDataSource{})
+	assert.Tf(t, csv.Describe() == Features{Scan: true}, "csv only scans: %#v", csv.Describe())
+	assert.Equal(t, "*datasource.CsvDataSource supports: [scan] does not support: [seek where groupby sort aggregations planner schema insert update delete search]",
+		csv.Summary())
+
+	Register("feature_test_source", &featureSource{})
+	conf := NewRuntimeConfig()
+	fs := conf.SourceFeatures("feature_test_source")
+	assert.Tf(t, fs != nil && fs.Describe() == f, "runtime config describes registered source: %v", fs)
+}
@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/araddon/dateparse"
-	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/logging"
 )
 
 type TimeValue time.Time
@@ -29,7 +29,7 @@ func (m *TimeValue) UnmarshalJSON(data []byte) error {
 }
 
 func (m TimeValue) Value() (driver.Value, error) {
-	u.Debugf("Value: %v", m)
+	logging.Debugf("Value: %v", m)
 	by, err := json.Marshal(time.Time(m))
 	return by, err
 }
@@ -39,22 +39,22 @@ func (m TimeValue) Time() time.Time {
 }
 
 func (m *TimeValue) Scan(src interface{}) error {
-	//u.Debugf("scan: '%v'", src)
+	//logging.Debugf("scan: '%v'", src)
 	var t time.Time
 	switch val := src.(type) {
 	case string:
-		//u.Infof("trying to scan string: '%v'", val)
+		//logging.Infof("trying to scan string: '%v'", val)
 		t2, err := dateparse.ParseAny(val)
 		if err == nil {
 			*m = TimeValue(t2)
 			return nil
 		}
-		u.Infof("%v  %v", t2, err)
+		logging.Infof("%v  %v", t2, err)
 		err = json.Unmarshal([]byte(val), &t)
 		if err == nil {
 			*m = TimeValue(t)
 		} else {
-			u.Warnf("error? %v", err)
+			logging.Warnf("error? %v", err)
 			return err
 		}
 	case []byte:
@@ -70,14 +70,14 @@ func (m *TimeValue) Scan(src interface{}) error {
 			return err
 		}
 	default:
-		u.Warnf("unknown type: %T", m)
+		logging.Warnf("unknown type: %T", m)
 		return errors.New("Incompatible type for TimeValue")
 	}
 	return nil
 }
 
 func (m *TimeValue) Unmarshal(v interface{}) error {
-	u.Warnf("wat? %T %v", v, v)
+	logging.Warnf("wat? %T %v", v, v)
 	//return json.Unmarshal([]byte(*m), v)
 	return fmt.Errorf("not implemented")
 }
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"strings"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var MockData = map[string]string{
@@ -27,10 +27,10 @@ type MockCsvSource struct {
 func (m *MockCsvSource) Open(connInfo string) (datasource.SourceConn, error) {
 	if data, ok := m.data[connInfo]; ok {
 		sr := strings.NewReader(data)
-		u.Debugf("open mockcsv: %v", connInfo)
+		logging.Debugf("open mockcsv: %v", connInfo)
 		return datasource.NewCsvSource(sr, make(<-chan bool, 1))
 	}
-	u.Errorf("not found?  %v", connInfo)
+	logging.Errorf("not found?  %v", connInfo)
 	return nil, fmt.Errorf("not found")
 }
 func (m *MockCsvSource) Tables() []string {
@@ -2,9 +2,11 @@ package datasource
 
 import (
 	"database/sql/driver"
+	"sort"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
 )
 
 func init() {
@@ -12,7 +14,6 @@ func init() {
 }
 
 var (
-	_            = u.EMPTY
 	_ DataSource = (*StaticDataSource)(nil)
 	_ SourceConn = (*StaticDataSource)(nil)
 	_ Scanner    = (*StaticDataSource)(nil)
@@ -22,12 +23,15 @@ var (
 //   in memory native go data to have a Schema and implement
 //   other DataSource interfaces such as Open, Close
 //
+//   Scans are in insertion (row) order, or ordered by a column, see Sorted
+//
 type StaticDataSource struct {
 	name   string
 	exit   <-chan bool
 	cursor int
 	data   [][]driver.Value
 	cols   []string
+	sortBy string // column scans are ordered by, empty is insertion order
 }
 
 func NewStaticDataSource(name string, data [][]driver.Value, cols []string) *StaticDataSource {
@@ -41,10 +45,30 @@ func NewStaticDataValue(data interface{}, name string) *StaticDataSource {
 	return &m
 }
 
+// Sorted orders scans by the values of @col (ascending, stable for equal
+//  values) instead of insertion order
+//
+//     source := NewStaticDataSource("users", rows, cols).Sorted("user_id")
+func (m *StaticDataSource) Sorted(col string) *StaticDataSource {
+	m.sortBy = col
+	return m
+}
+
 func (m *StaticDataSource) Open(connInfo string) (SourceConn, error) { return nil, nil }
 func (m *StaticDataSource) Close() error                             { return nil }
-func (m *StaticDataSource) CreateIterator(filter expr.Node) Iterator { return m }
-func (m *StaticDataSource) Tables() []string                         { return []string{m.name} }
+func (m *StaticDataSource) CreateIterator(filter expr.Node) Iterator {
+	if m.sortBy == "" {
+		return m
+	}
+	for i, col := range m.cols {
+		if col == m.sortBy {
+			return &staticIter{rows: m.sortedRows(i), data: m.data, exit: m.exit}
+		}
+	}
+	logging.Warnf("static source %s has no column %q to sort by", m.name, m.sortBy)
+	return m
+}
+func (m *StaticDataSource) Tables() []string { return []string{m.name} }
 func (m *StaticDataSource) MesgChan(filter expr.Node) <-chan Message {
 	iter := m.CreateIterator(filter)
 	return SourceIterChannel(iter, filter, m.exit)
@@ -67,3 +91,54 @@ func (m *StaticDataSource) Next() Message {
 	}
 
 }
+
+// row indexes ordered by the values of column @col
+func (m *StaticDataSource) sortedRows(col int) []int {
+	rows := make([]int, len(m.data))
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.Stable(&staticSorter{rows: rows, data: m.data, col: col})
+	return rows
+}
+
+type staticSorter struct {
+	rows []int
+	data [][]driver.Value
+	col  int
+}
+
+func (m *staticSorter) Len() int      { return len(m.rows) }
+func (m *staticSorter) Swap(i, j int) { m.rows[i], m.rows[j] = m.rows[j], m.rows[i] }
+func (m *staticSorter) Less(i, j int) bool {
+	a, b := m.data[m.rows[i]][m.col], m.data[m.rows[j]][m.col]
+	cmp, err := value.CompareValues(value.NewValue(a), value.NewValue(b), nil)
+	if err != nil {
+		// no ordering between the types, order by the type
+		return value.NewValue(a).Type() < value.NewValue(b).Type()
+	}
+	return cmp < 0
+}
+
+// iterator over a static source in a given row order, the message Id is
+//  the row (insertion) index
+type staticIter struct {
+	rows   []int
+	data   [][]driver.Value
+	exit   <-chan bool
+	cursor int
+}
+
+func (m *staticIter) Next() Message {
+	select {
+	case <-m.exit:
+		return nil
+	default:
+	}
+	if m.cursor >= len(m.rows) {
+		return nil
+	}
+	m.cursor++
+	row := m.rows[m.cursor-1]
+	return &SqlDriverMessage{Id: uint64(row), Vals: m.data[row]}
+}
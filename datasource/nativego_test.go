@@ -30,3 +30,36 @@ func TestStaticDatasource(t *testing.T) {
 	}
 	assert.Tf(t, iterCt == 1, "should have 1 rows: %v", iterCt)
 }
+
+func TestStaticDatasourceOrder(t *testing.T) {
+
+	rows := [][]driver.Value{
+		{"c", int64(3)},
+		{"a", int64(1)},
+		{"b", int64(2)},
+		{"a", int64(4)},
+	}
+	scan := func(source *StaticDataSource) []int64 {
+		vals := make([]int64, 0)
+		iter := source.CreateIterator(nil)
+		for msg := iter.Next(); msg != nil; msg = iter.Next() {
+			vals = append(vals, msg.Body().([]driver.Value)[1].(int64))
+		}
+		return vals
+	}
+
+	// insertion order, stable across scans
+	static := NewStaticDataSource("letters", rows, []string{"name", "ct"})
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []int64{3, 1, 2, 4}, scan(static))
+	}
+
+	// ordered by name, equal names keep insertion order
+	static = NewStaticDataSource("letters", rows, []string{"name", "ct"}).Sorted("name")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []int64{1, 4, 2, 3}, scan(static))
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4}, scan(static.Sorted("ct")))
+	// rows are not re-ordered
+	assert.Tf(t, rows[0][0] == "c", "data unchanged %v", rows[0])
+}
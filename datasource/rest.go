@@ -0,0 +1,198 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	_ DataSource  = (*RestDataSource)(nil)
+	_ SourceConn  = (*restConn)(nil)
+	_ Scanner     = (*restConn)(nil)
+	_ ErrIterator = (*restConn)(nil)
+)
+
+// RestRequestFunc creates the http request for a page of @table, @cursor is
+//  the next-page token of the previous page ("" for the first page)
+type RestRequestFunc func(table, cursor string) (*http.Request, error)
+
+// RestResponseFunc maps a page response to its rows and the next-page
+//  cursor, "" if this was the last page
+type RestResponseFunc func(resp *http.Response) (rows []map[string]value.Value, next string, err error)
+
+// RestDataSource is a base for api backed tables, it scans through pages
+//  fetched over http following next-page cursors,  see JsonPageResponse()
+//
+//     src := NewRestDataSource([]string{"users"}, func(table, cursor string) (*http.Request, error) {
+//         return http.NewRequest("GET", "https://api.example.com/"+table+"?page="+cursor, nil)
+//     }, JsonPageResponse("data", "next_page"))
+//     datasource.Register("myapi", src)
+type RestDataSource struct {
+	Client   *http.Client // nil is http.DefaultClient
+	Request  RestRequestFunc
+	Response RestResponseFunc
+	tables   []string
+}
+
+func NewRestDataSource(tables []string, req RestRequestFunc, resp RestResponseFunc) *RestDataSource {
+	return &RestDataSource{Request: req, Response: resp, tables: tables}
+}
+
+func (m *RestDataSource) Tables() []string { return m.tables }
+func (m *RestDataSource) Close() error     { return nil }
+
+// Open a scan of @table, pages are fetched as iterated
+func (m *RestDataSource) Open(table string) (SourceConn, error) {
+	if m.Request == nil || m.Response == nil {
+		return nil, fmt.Errorf("rest source requires Request and Response funcs")
+	}
+	return &restConn{source: m, table: table, exit: make(<-chan bool, 1)}, nil
+}
+
+func (m *RestDataSource) client() *http.Client {
+	if m.Client == nil {
+		return http.DefaultClient
+	}
+	return m.Client
+}
+
+// the scan of one table, it is its own Iterator
+type restConn struct {
+	source *RestDataSource
+	table  string
+	exit   <-chan bool
+	rows   []map[string]value.Value // remaining rows of current page
+	cursor string
+	done   bool // no more pages
+	rowct  uint64
+	err    error
+}
+
+func (m *restConn) Close() error { return nil }
+
+func (m *restConn) CreateIterator(filter expr.Node) Iterator { return m }
+
+func (m *restConn) MesgChan(filter expr.Node) <-chan Message {
+	return SourceIterChannel(m.CreateIterator(filter), filter, m.exit)
+}
+
+// Err is the error (if any) fetching a page that ended the scan
+func (m *restConn) Err() error { return m.err }
+
+func (m *restConn) Next() Message {
+	// an empty page with a next cursor is not the end
+	for len(m.rows) == 0 {
+		if m.done {
+			return nil
+		}
+		if err := m.fetch(); err != nil {
+			logging.Errorf("could not fetch %s page cursor=%q: %v", m.table, m.cursor, err)
+			m.err, m.done = err, true
+			return nil
+		}
+	}
+	row := m.rows[0]
+	m.rows = m.rows[1:]
+	m.rowct++
+	msg := NewContextSimpleData(row)
+	msg.keyval = m.rowct
+	return msg
+}
+
+func (m *restConn) fetch() error {
+	req, err := m.source.Request(m.table, m.cursor)
+	if err != nil {
+		return err
+	}
+	resp, err := m.source.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	rows, next, err := m.source.Response(resp)
+	if err != nil {
+		return err
+	}
+	m.rows, m.cursor, m.done = rows, next, next == ""
+	return nil
+}
+
+// JsonPageResponse maps a json response whose @rowsField is an array of
+//  row objects and @nextField the next-page cursor, a "" @rowsField is a
+//  response that is itself the array (a single page), dotted fields are nested.
+//
+//     {"data": [{"id": 1, "name": "bob"}], "paging": {"next": "abc"}}
+//        => JsonPageResponse("data", "paging.next")
+func JsonPageResponse(rowsField, nextField string) RestResponseFunc {
+	return func(resp *http.Response) ([]map[string]value.Value, string, error) {
+		dec := json.NewDecoder(resp.Body)
+		dec.UseNumber()
+		var body interface{}
+		if err := dec.Decode(&body); err != nil {
+			return nil, "", err
+		}
+		rowsJson := jsonPath(body, rowsField)
+		arr, ok := rowsJson.([]interface{})
+		if !ok && rowsJson != nil {
+			return nil, "", fmt.Errorf("expected array of rows at %q but got %T", rowsField, rowsJson)
+		}
+		rows := make([]map[string]value.Value, 0, len(arr))
+		for _, item := range arr {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, "", fmt.Errorf("expected row object but got %T", item)
+			}
+			row := make(map[string]value.Value, len(obj))
+			for k, v := range obj {
+				row[k] = jsonValue(v)
+			}
+			rows = append(rows, row)
+		}
+		next := ""
+		if nextField != "" {
+			if v := jsonPath(body, nextField); v != nil {
+				next = fmt.Sprint(v)
+			}
+		}
+		return rows, next, nil
+	}
+}
+
+func jsonPath(v interface{}, path string) interface{} {
+	if path == "" {
+		return v
+	}
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[part]
+	}
+	return v
+}
+
+// a decoded json value, nested objects and arrays are their json string
+func jsonValue(v interface{}) value.Value {
+	switch vt := v.(type) {
+	case json.Number:
+		if iv, err := vt.Int64(); err == nil {
+			return value.NewIntValue(iv)
+		}
+		fv, _ := vt.Float64()
+		return value.NewNumberValue(fv)
+	case map[string]interface{}, []interface{}:
+		by, _ := json.Marshal(vt)
+		return value.NewStringValue(string(by))
+	}
+	return value.NewValue(v)
+}
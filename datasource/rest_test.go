@@ -0,0 +1,62 @@
+package datasource
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestRestDataSource(t *testing.T) {
+
+	pages := map[string]string{
+		"":   `{"data": [{"id": 1, "name": "aaron", "score": 2.5}, {"id": 2, "name": "bob", "tags": ["a"]}], "paging": {"next": "p2"}}`,
+		"p2": `{"data": [{"id": 3, "name": "carol"}], "paging": {}}`,
+	}
+	requested := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("page")
+		requested = append(requested, r.URL.Path+"?"+cursor)
+		page, ok := pages[cursor]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	src := NewRestDataSource([]string{"users"}, func(table, cursor string) (*http.Request, error) {
+		return http.NewRequest("GET", server.URL+"/"+table+"?page="+cursor, nil)
+	}, JsonPageResponse("data", "paging.next"))
+
+	conn, err := src.Open("users")
+	assert.Tf(t, err == nil, "no error %v", err)
+	iter := conn.(Scanner).CreateIterator(nil)
+	rows := make([]*ContextSimple, 0)
+	for msg := iter.Next(); msg != nil; msg = iter.Next() {
+		rows = append(rows, msg.(*ContextSimple))
+	}
+	assert.Tf(t, len(rows) == 3, "3 rows over 2 pages: %v", len(rows))
+	assert.Equal(t, []string{"/users?", "/users?p2"}, requested)
+	for i, name := range []string{"aaron", "bob", "carol"} {
+		id, _ := rows[i].Get("id")
+		nv, _ := rows[i].Get("name")
+		assert.Tf(t, id.Value() == int64(i+1) && nv.ToString() == name, "row %d: %v %v", i, id, nv)
+		assert.Tf(t, rows[i].Key() == uint64(i+1), "row key %d", rows[i].Key())
+	}
+	score, _ := rows[0].Get("score")
+	assert.Tf(t, score.Value() == 2.5, "number: %#v", score)
+	tags, _ := rows[1].Get("tags")
+	assert.Tf(t, tags.ToString() == `["a"]`, "nested json: %v", tags)
+
+	// a failed page ends the scan with the error
+	pages[""] = `{"data": [{"id": 1}], "paging": {"next": "missing"}}`
+	conn, _ = src.Open("users")
+	iter = conn.(Scanner).CreateIterator(nil)
+	assert.T(t, iter.Next() != nil)
+	assert.T(t, iter.Next() == nil)
+	assert.Tf(t, conn.(*restConn).Err() != nil, "has error")
+}
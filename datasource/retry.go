@@ -0,0 +1,157 @@
+package datasource
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+)
+
+var (
+	_ DataSource  = (*RetryingSource)(nil)
+	_ SourceConn  = (*retryConn)(nil)
+	_ Scanner     = (*retryScanner)(nil)
+	_ ErrIterator = (*retryIter)(nil)
+)
+
+// RetryingSource wraps a (network) DataSource retrying Open, and the first
+//  Next() of a scan, on transient errors with exponential backoff.  The first
+//  Next() can only be retried if the source Iterator is an ErrIterator.
+//
+//     src := NewRetryingSource(NewRestDataSource(...), 3, 100*time.Millisecond, nil)
+//     datasource.Register("myapi", src)
+type RetryingSource struct {
+	DataSource
+	Attempts  int              // total attempts, including the first
+	Backoff   time.Duration    // wait before the 2nd attempt, doubled after each
+	Retryable func(error) bool // classifies errors, nil retries all errors
+	sleep     func(time.Duration)
+}
+
+func NewRetryingSource(src DataSource, attempts int, backoff time.Duration, retryable func(error) bool) *RetryingSource {
+	return &RetryingSource{DataSource: src, Attempts: attempts, Backoff: backoff, Retryable: retryable, sleep: time.Sleep}
+}
+
+// Open the underlying source, retrying while errors are retryable
+func (m *RetryingSource) Open(connInfo string) (SourceConn, error) {
+	var conn SourceConn
+	err := m.retry("open "+connInfo, func() (err error) {
+		conn, err = m.DataSource.Open(connInfo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	rc := &retryConn{source: m, connInfo: connInfo, SourceConn: conn}
+	if _, ok := conn.(Scanner); !ok {
+		return rc, nil
+	}
+	return &retryScanner{retryConn: rc, exit: make(chan bool)}, nil
+}
+
+// run @fn until it succeeds, returns a fatal error, or we are out of attempts
+func (m *RetryingSource) retry(what string, fn func() error) error {
+	wait := m.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= m.Attempts || (m.Retryable != nil && !m.Retryable(err)) {
+			return err
+		}
+		logging.Warnf("retrying %s after attempt %d: %v", what, attempt, err)
+		if m.sleep != nil {
+			m.sleep(wait)
+		} else {
+			time.Sleep(wait)
+		}
+		wait *= 2
+	}
+}
+
+// the conn of a RetryingSource, only a Scanner (retryScanner) if the
+//  underlying conn is one
+type retryConn struct {
+	SourceConn
+	source   *RetryingSource
+	connInfo string
+}
+
+// the conn of a RetryingSource whose underlying conn is a Scanner, closing
+//  it stops its MesgChan
+type retryScanner struct {
+	*retryConn
+	exit      chan bool
+	closeOnce sync.Once
+}
+
+func (m *retryScanner) CreateIterator(filter expr.Node) Iterator {
+	return &retryIter{conn: m.retryConn, filter: filter, iter: m.SourceConn.(Scanner).CreateIterator(filter)}
+}
+
+func (m *retryScanner) MesgChan(filter expr.Node) <-chan Message {
+	return SourceIterChannel(m.CreateIterator(filter), filter, m.exit)
+}
+
+func (m *retryScanner) Close() error {
+	m.closeOnce.Do(func() { close(m.exit) })
+	return m.retryConn.Close()
+}
+
+// retries the first Next() by re-opening the underlying conn, after that
+//  it is a pass-through
+type retryIter struct {
+	conn    *retryConn
+	filter  expr.Node
+	iter    Iterator
+	started bool
+	err     error
+}
+
+func (m *retryIter) Err() error {
+	if m.err != nil {
+		return m.err
+	}
+	if ei, ok := m.iter.(ErrIterator); ok {
+		return ei.Err()
+	}
+	return nil
+}
+
+func (m *retryIter) Next() Message {
+	if m.started {
+		return m.iter.Next()
+	}
+	m.started = true
+	var msg Message
+	first := true
+	err := m.conn.source.retry("scan "+m.conn.connInfo, func() error {
+		if !first {
+			m.conn.SourceConn.Close()
+			conn, err := m.conn.source.DataSource.Open(m.conn.connInfo)
+			if err != nil {
+				return err
+			}
+			scanner, ok := conn.(Scanner)
+			if !ok {
+				return fmt.Errorf("%s is not a Scanner", m.conn.connInfo)
+			}
+			m.conn.SourceConn, m.iter = conn, scanner.CreateIterator(m.filter)
+		}
+		first = false
+		msg = m.iter.Next()
+		if ei, ok := m.iter.(ErrIterator); ok && msg == nil {
+			return ei.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Errorf("could not scan %s: %v", m.conn.connInfo, err)
+		m.err = err
+		return nil
+	}
+	return msg
+}
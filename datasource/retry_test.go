@@ -0,0 +1,122 @@
+package datasource
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+var errFatal = fmt.Errorf("fatal")
+
+// fails the first @openFails Opens, and the first Next of the first
+//  @scanFails scans, with @err
+type flakySource struct {
+	openFails, scanFails int
+	opens, scans         int
+	err                  error
+}
+
+func (m *flakySource) Tables() []string { return []string{"flaky"} }
+func (m *flakySource) Close() error     { return nil }
+func (m *flakySource) Open(table string) (SourceConn, error) {
+	m.opens++
+	if m.opens <= m.openFails {
+		return nil, m.err
+	}
+	return &flakyConn{source: m}, nil
+}
+
+type flakyConn struct {
+	source *flakySource
+	rows   int
+	err    error
+}
+
+func (m *flakyConn) Close() error                             { return nil }
+func (m *flakyConn) Err() error                               { return m.err }
+func (m *flakyConn) CreateIterator(filter expr.Node) Iterator { return m }
+func (m *flakyConn) MesgChan(filter expr.Node) <-chan Message { return nil }
+func (m *flakyConn) Next() Message {
+	if m.rows == 0 {
+		m.source.scans++
+		if m.source.scans <= m.source.scanFails {
+			m.err = m.source.err
+			return nil
+		}
+	}
+	if m.rows >= 2 {
+		return nil
+	}
+	m.rows++
+	return NewContextSimpleData(map[string]value.Value{"n": value.NewIntValue(int64(m.rows))})
+}
+
+func TestRetryingSource(t *testing.T) {
+
+	waits := make([]time.Duration, 0)
+	newRetrying := func(src DataSource) *RetryingSource {
+		rs := NewRetryingSource(src, 3, time.Millisecond, func(err error) bool { return err != errFatal })
+		rs.sleep = func(d time.Duration) { waits = append(waits, d) }
+		return rs
+	}
+
+	// Open succeeds on the third attempt, with backoff doubling
+	flaky := &flakySource{openFails: 2, err: fmt.Errorf("connection refused")}
+	conn, err := newRetrying(flaky).Open("flaky")
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, flaky.opens == 3, "3 attempts: %v", flaky.opens)
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, waits)
+
+	// out of attempts
+	flaky = &flakySource{openFails: 3, err: fmt.Errorf("connection refused")}
+	_, err = newRetrying(flaky).Open("flaky")
+	assert.Tf(t, err != nil && flaky.opens == 3, "gives up after 3: %v %v", err, flaky.opens)
+
+	// fatal errors are not retried
+	flaky = &flakySource{openFails: 2, err: errFatal}
+	_, err = newRetrying(flaky).Open("flaky")
+	assert.Tf(t, err == errFatal && flaky.opens == 1, "fatal not retried: %v %v", err, flaky.opens)
+
+	// first Next succeeds on the third attempt, by re-opening
+	flaky = &flakySource{scanFails: 2, err: fmt.Errorf("timeout")}
+	conn, err = newRetrying(flaky).Open("flaky")
+	assert.Tf(t, err == nil, "no error %v", err)
+	iter := conn.(Scanner).CreateIterator(nil)
+	rows := 0
+	for msg := iter.Next(); msg != nil; msg = iter.Next() {
+		rows++
+	}
+	assert.Tf(t, rows == 2 && flaky.scans == 3, "rows=%v scans=%v", rows, flaky.scans)
+	assert.Tf(t, iter.(ErrIterator).Err() == nil, "no error %v", iter.(ErrIterator).Err())
+
+	flaky = &flakySource{scanFails: 2, err: errFatal}
+	conn, _ = newRetrying(flaky).Open("flaky")
+	iter = conn.(Scanner).CreateIterator(nil)
+	assert.T(t, iter.Next() == nil)
+	assert.Tf(t, iter.(ErrIterator).Err() == errFatal && flaky.scans == 1, "fatal scan error %v", flaky.scans)
+
+	// a conn that is not a Scanner is not one when wrapped
+	conn, err = newRetrying(&connOnlySource{}).Open("conn")
+	assert.Tf(t, err == nil, "no error %v", err)
+	_, ok := conn.(Scanner)
+	assert.Tf(t, !ok, "not a Scanner: %T", conn)
+
+	// closing the conn, more than once, ends its MesgChan
+	conn, _ = newRetrying(&flakySource{}).Open("flaky")
+	ch := conn.(Scanner).MesgChan(nil)
+	assert.T(t, conn.Close() == nil)
+	assert.T(t, conn.Close() == nil)
+	for range ch {
+	}
+}
+
+// a source whose conns are not Scanners
+type connOnlySource struct{}
+
+func (m *connOnlySource) Tables() []string                      { return []string{"conn"} }
+func (m *connOnlySource) Close() error                          { return nil }
+func (m *connOnlySource) Open(table string) (SourceConn, error) { return m, nil }
@@ -1,9 +1,18 @@
 package datasource
 
 import (
+	"fmt"
 	"strings"
 
-	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Schema binds expression identity types
+	_ expr.TypeSchema = (*Schema)(nil)
+	_ expr.SchemaInfo = (*ColumnInfo)(nil)
 )
 
 // The RuntimeSchema config providing access to available datasources
@@ -14,6 +23,11 @@ type RuntimeConfig struct {
 	connInfo       string       // db.driver only allows one connection
 	db             string       // db.driver only allows one db
 	DisableRecover bool
+	Collation      value.Collation // string collation for sorting, where = LIKE etc, nil = binary
+	StrictErrors   bool            // fail on first row evaluation error, else skip row
+	MaxRows        int             // default cap on rows a job may emit, 0 = none
+	Tracer         Tracer          // spans of the parse, plan and tasks of queries, nil = none
+	MaxWorkers     int             // max goroutines the tasks of a job run on, 0 = the exec default
 }
 
 func NewRuntimeConfig() *RuntimeConfig {
@@ -23,6 +37,14 @@ func NewRuntimeConfig() *RuntimeConfig {
 	return c
 }
 
+// Tracing is the Tracer of the config, the NoopTracer if it has none
+func (m *RuntimeConfig) Tracing() Tracer {
+	if m.Tracer == nil {
+		return NoopTracer{}
+	}
+	return m.Tracer
+}
+
 // Our RunTime configuration possibly only supports a single schema/connection
 // info.  for example, the sql/driver interface, so will be set here.
 //
@@ -39,28 +61,28 @@ func (m *RuntimeConfig) SetConnInfo(connInfo string) {
 func (m *RuntimeConfig) Conn(db string) SourceConn {
 
 	if m.connInfo == "" {
-		u.Debugf("RuntimeConfig.Conn(db='%v')   // connInfo='%v'", db, m.connInfo)
+		logging.Debugf("RuntimeConfig.Conn(db='%v')   // connInfo='%v'", db, m.connInfo)
 		if source := m.Sources.Get(strings.ToLower(db)); source != nil {
-			u.Debugf("found source: db=%s   %T", db, source)
+			logging.Debugf("found source: db=%s   %T", db, source)
 			conn, err := source.Open(db)
 			if err != nil {
-				u.Errorf("could not open data source: %v  %v", db, err)
+				logging.Errorf("could not open data source: %v  %v", db, err)
 				return nil
 			}
-			//u.Infof("source: %T  %#v", conn, conn)
+			//logging.Infof("source: %T  %#v", conn, conn)
 			return conn
 		} else {
-			u.Errorf("DataSource(%s) was not found", db)
+			logging.Errorf("DataSource(%s) was not found", db)
 		}
 	} else {
-		u.Debugf("No Conn? RuntimeConfig.Conn(db='%v')   // connInfo='%v'", db, m.connInfo)
+		logging.Debugf("No Conn? RuntimeConfig.Conn(db='%v')   // connInfo='%v'", db, m.connInfo)
 		// We have connection info, likely sq/driver
 		source := m.DataSource(m.connInfo)
-		//u.Infof("source=%v    about to call Conn() db='%v'", source, db)
+		//logging.Infof("source=%v    about to call Conn() db='%v'", source, db)
 		conn, err := source.Open(db)
 
 		if err != nil {
-			u.Errorf("could not open data source: %v  %v", db, err)
+			logging.Errorf("could not open data source: %v  %v", db, err)
 			return nil
 		}
 		return conn
@@ -68,12 +90,33 @@ func (m *RuntimeConfig) Conn(db string) SourceConn {
 	return nil
 }
 
+// SourceFeatures describes the source that Conn(@db) opens from, nil if
+//  there is none
+func (m *RuntimeConfig) SourceFeatures(db string) *DataSourceFeatures {
+	var source DataSource
+	if m.connInfo == "" {
+		if src := m.Sources.Get(strings.ToLower(db)); src != nil {
+			source = src
+		}
+	} else {
+		source = m.DataSource(m.connInfo)
+	}
+	switch src := source.(type) {
+	case nil:
+		return nil
+	case *DataSourceFeatures:
+		return src
+	default:
+		return NewFeaturedSource(src)
+	}
+}
+
 // given connection info, get datasource
 //  @connInfo =    csv:///dev/stdin
 //                 mockcsv
 func (m *RuntimeConfig) DataSource(connInfo string) DataSource {
 	// if  mysql.tablename allow that convention
-	//u.Debugf("get datasource: conn=%v ", connInfo)
+	//logging.Debugf("get datasource: conn=%v ", connInfo)
 	//parts := strings.SplitN(from, ".", 2)
 	sourceType := ""
 	if len(connInfo) > 0 {
@@ -93,13 +136,144 @@ func (m *RuntimeConfig) DataSource(connInfo string) DataSource {
 	}
 
 	sourceType = strings.ToLower(sourceType)
-	//u.Debugf("source: %v", sourceType)
+	//logging.Debugf("source: %v", sourceType)
 	if source := m.Sources.Get(sourceType); source != nil {
-		u.Debugf("source: %T", source)
+		logging.Debugf("source: %T", source)
 		return source
 	} else {
-		u.Errorf("DataSource(conn) was not found: '%v'", sourceType)
+		logging.Errorf("DataSource(conn) was not found: '%v'", sourceType)
 	}
 
 	return nil
 }
+
+// Schema describes the ordered, typed columns of a table or result set
+type Schema struct {
+	Name     string
+	Fields   []*Field
+	fieldMap map[string]*Field
+}
+
+// Field is a named, typed column of a Schema
+type Field struct {
+	Name    string
+	Type    value.ValueType
+	Default value.Value // used for inserts omitting this column, nil = no default
+	NotNull bool
+	// Enum are the allowed values of an ENUM column, nil = any value
+	Enum []string
+	// Set columns are a comma separated set of the Enum values (mysql SET)
+	Set bool
+}
+
+// Validate @v is an allowed value of an ENUM or SET column, NULL is not
+//  checked (see NotNull)
+//
+//     ENUM('active','pending')          "active"
+//     SET('read','write','admin')       "read,admin"
+func (m *Field) Validate(v value.Value) error {
+	if len(m.Enum) == 0 || v == nil || v.Type() == value.NilType {
+		return nil
+	}
+	vals := []string{v.ToString()}
+	if m.Set {
+		vals = strings.Split(vals[0], ",")
+		if vals[0] == "" && len(vals) == 1 {
+			// the empty set
+			return nil
+		}
+	}
+	for _, val := range vals {
+		if !m.allowed(val) {
+			return fmt.Errorf("value %q not allowed for column %q, expected one of (%s)",
+				val, m.Name, strings.Join(m.Enum, ", "))
+		}
+	}
+	return nil
+}
+
+func (m *Field) allowed(val string) bool {
+	for _, e := range m.Enum {
+		if e == val {
+			return true
+		}
+	}
+	return false
+}
+
+func NewSchema(name string) *Schema {
+	return &Schema{Name: name, Fields: make([]*Field, 0), fieldMap: make(map[string]*Field)}
+}
+
+// Add a field to end of this schema
+func (m *Schema) AddField(name string, vt value.ValueType) *Field {
+	f := &Field{Name: name, Type: vt}
+	m.Fields = append(m.Fields, f)
+	m.fieldMap[name] = f
+	return f
+}
+
+// Field by name
+func (m *Schema) Field(name string) (*Field, bool) {
+	f, ok := m.fieldMap[name]
+	return f, ok
+}
+
+// ColumnType is the value type of field @name, for binding identities in
+//  expressions to this schema, see expr.CheckSchema
+func (m *Schema) ColumnType(name string) (value.ValueType, bool) {
+	if f, ok := m.fieldMap[name]; ok {
+		return f.Type, true
+	}
+	return value.UnknownType, false
+}
+
+// ColumnInfo is the expr.SchemaInfo of a column being written (insert,
+//  update) to a ContextWriter, see ResolveSchemaInfo
+type ColumnInfo struct {
+	Name  string
+	Type  value.ValueType // UnknownType for tables without a schema
+	Pos   int             // position in the schema, else in the written columns
+	field *Field
+}
+
+func (m *ColumnInfo) Key() string { return m.Name }
+
+// Validate @v against the schema field of the column, see Field.Validate
+func (m *ColumnInfo) Validate(v value.Value) error {
+	if m.field == nil {
+		return nil
+	}
+	return m.field.Validate(v)
+}
+
+// ResolveSchemaInfo the ColumnInfo of each of @cols being written to the
+//  table of @schema, a nil schema is untyped.  It is an error for a column
+//  to not be in the schema
+//
+//     cols, err := ResolveSchemaInfo(schema, []string{"user_id", "email"})
+//     row.Put(cols[0], nil, value.NewStringValue("abc"))
+func ResolveSchemaInfo(schema *Schema, cols []string) ([]*ColumnInfo, error) {
+	infos := make([]*ColumnInfo, len(cols))
+	for i, col := range cols {
+		if schema == nil {
+			infos[i] = &ColumnInfo{Name: col, Type: value.UnknownType, Pos: i}
+			continue
+		}
+		f, ok := schema.Field(col)
+		if !ok {
+			return nil, fmt.Errorf("no column %q in %s", col, schema.Name)
+		}
+		infos[i] = &ColumnInfo{Name: f.Name, Type: f.Type, Pos: schema.fieldPos(f), field: f}
+	}
+	return infos, nil
+}
+
+func (m *Schema) fieldPos(f *Field) int {
+	for i, sf := range m.Fields {
+		if sf == f {
+			return i
+		}
+	}
+	return -1
+}
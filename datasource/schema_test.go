@@ -0,0 +1,73 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func TestResolveSchemaInfo(t *testing.T) {
+
+	schema := NewSchema("users")
+	schema.AddField("user_id", value.StringType)
+	schema.AddField("email", value.StringType)
+	schema.AddField("age", value.IntType)
+
+	cols, err := ResolveSchemaInfo(schema, []string{"age", "user_id"})
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(cols) == 2, "2 cols %v", cols)
+	assert.Tf(t, cols[0].Key() == "age" && cols[0].Type == value.IntType && cols[0].Pos == 2, "age %#v", cols[0])
+	assert.Tf(t, cols[1].Key() == "user_id" && cols[1].Type == value.StringType && cols[1].Pos == 0, "user_id %#v", cols[1])
+
+	_, err = ResolveSchemaInfo(schema, []string{"user_id", "nope"})
+	assert.Tf(t, err != nil, "unknown column should error")
+
+	// untyped, positional by the written columns
+	cols, err = ResolveSchemaInfo(nil, []string{"a", "b"})
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, cols[1].Key() == "b" && cols[1].Type == value.UnknownType && cols[1].Pos == 1, "b %#v", cols[1])
+
+	// write a row through ContextWriters
+	cols, _ = ResolveSchemaInfo(schema, []string{"user_id", "age"})
+	vals := []value.Value{value.NewStringValue("abc"), value.NewIntValue(22)}
+	ctx := NewContextSimple()
+	row := NewRow(1, ColumnIndex([]string{"user_id", "email", "age"}), make([]value.Value, 3))
+	for i, col := range cols {
+		assert.T(t, ctx.Put(col, nil, vals[i]) == nil)
+		assert.T(t, row.Put(col, nil, vals[i]) == nil)
+	}
+	age, _ := ctx.Get("age")
+	assert.Tf(t, age.Value() == int64(22), "age %v", age)
+	assert.Tf(t, row.Vals[cols[1].Pos].Value() == int64(22), "positional age %v", row.Vals)
+	_, hasEmail := row.Get("email")
+	assert.T(t, !hasEmail)
+}
+
+func TestFieldValidate(t *testing.T) {
+
+	schema := NewSchema("users")
+	status := schema.AddField("status", value.StringType)
+	status.Enum = []string{"active", "pending"}
+	perms := schema.AddField("perms", value.StringType)
+	perms.Enum, perms.Set = []string{"read", "write", "admin"}, true
+	name := schema.AddField("name", value.StringType)
+
+	assert.T(t, status.Validate(value.NewStringValue("active")) == nil)
+	assert.T(t, status.Validate(value.NewNilValue()) == nil)
+	err := status.Validate(value.NewStringValue("deleted"))
+	assert.Tf(t, err != nil, "not an enum value")
+	assert.Equal(t, `value "deleted" not allowed for column "status", expected one of (active, pending)`, err.Error())
+
+	assert.T(t, perms.Validate(value.NewStringValue("read,admin")) == nil)
+	assert.T(t, perms.Validate(value.NewStringValue("")) == nil)
+	assert.Tf(t, perms.Validate(value.NewStringValue("read,delete")) != nil, "delete not in set")
+	assert.Tf(t, perms.Validate(value.NewStringValue("read,")) != nil, "empty member")
+
+	assert.T(t, name.Validate(value.NewStringValue("anything")) == nil)
+
+	cols, _ := ResolveSchemaInfo(schema, []string{"status"})
+	assert.Tf(t, cols[0].Validate(value.NewStringValue("nope")) != nil, "validated by the column")
+	cols, _ = ResolveSchemaInfo(nil, []string{"status"})
+	assert.Tf(t, cols[0].Validate(value.NewStringValue("nope")) == nil, "no schema, any value")
+}
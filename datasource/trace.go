@@ -0,0 +1,37 @@
+package datasource
+
+import (
+	"context"
+)
+
+var _ Tracer = NoopTracer{}
+
+// Tracer is the hook to trace queries as spans, ie to wire OpenTelemetry:
+//  the parse and plan of a statement, and the run of each task of its job.
+//  Set as RuntimeConfig.Tracer, the default is the NoopTracer.
+type Tracer interface {
+	// StartSpan starts span @name as a child of the span (if any) of @ctx,
+	//  the returned context carries the new span for its children
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation, End is called once it is done
+type Span interface {
+	SetAttribute(key string, value interface{})
+	// SetError records that the operation failed
+	SetError(err error)
+	End()
+}
+
+// NoopTracer is the default Tracer, its spans do nothing
+type NoopTracer struct{}
+
+func (NoopTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) SetError(err error)                         {}
+func (noopSpan) End()                                       {}
@@ -1,8 +1,8 @@
 package influxql
 
 import (
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"strings"
 )
 
@@ -59,7 +59,7 @@ func LexColumnsInflux(l *lex.Lexer) lex.StateFn {
 
 	keyWord := strings.ToLower(l.PeekWord())
 
-	u.Debugf("LexColumnsInflux  r= '%v'", string(keyWord))
+	logging.Debugf("LexColumnsInflux  r= '%v'", string(keyWord))
 
 	switch keyWord {
 	case "if":
@@ -99,7 +99,7 @@ func LexInfluxName(l *lex.Lexer) lex.StateFn {
 
 	l.SkipWhiteSpaces()
 	firstChar := l.Peek()
-	u.Debugf("LexInfluxName:  %v", string(firstChar))
+	logging.Debugf("LexInfluxName:  %v", string(firstChar))
 
 	switch firstChar {
 	case '"':
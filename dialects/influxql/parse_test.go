@@ -6,6 +6,7 @@ import (
 
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/bmizerany/assert"
 )
 
@@ -18,6 +19,7 @@ func init() {
 	if *VerboseTests {
 		u.SetupLogging("debug")
 		u.SetColorOutput()
+		logging.SetLogger(logging.GouLogger{})
 	}
 }
 
@@ -3,8 +3,8 @@ package influxql
 import (
 	"fmt"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 )
 
 /*
@@ -32,7 +32,7 @@ type Parser struct {
 func (m *Parser) parse() (*Ast, error) {
 
 	comment := m.initialComment()
-	u.Debug(comment)
+	logging.Debug(comment)
 	// Now, find First Keyword
 	switch m.curToken.T {
 	case lex.TokenSelect:
@@ -41,7 +41,7 @@ func (m *Parser) parse() (*Ast, error) {
 	default:
 		return nil, fmt.Errorf("Unrecognized query, expected [SELECT] influx ql")
 	}
-	u.Warnf("Whoops, that didn't work: \n%v \n\t%v", m.curToken, m.qryText)
+	logging.Warnf("Whoops, that didn't work: \n%v \n\t%v", m.curToken, m.qryText)
 	return nil, fmt.Errorf("Unkwown error on request")
 }
 
@@ -71,32 +71,32 @@ func (m *Parser) parseSelect(comment string) (*Ast, error) {
 
 	selStmt := SelectStmt{}
 	ast := Ast{Comments: comment, Select: &selStmt}
-	//u.Infof("Comment:   %v", comment)
+	//logging.Infof("Comment:   %v", comment)
 
 	// we have already parsed SELECTlex.Token to get here, so this should be first col
 	m.curToken = m.l.NextToken()
-	//u.Debug("FirstToken: ", m.curToken)
+	//logging.Debug("FirstToken: ", m.curToken)
 	if m.curToken.T != lex.TokenStar {
 		if err := m.parseColumns(&selStmt); err != nil {
-			u.Error(err)
+			logging.Error(err)
 			return nil, err
 		}
-		//u.Infof("resturned from cols: %v", len(selStmt.Columns))
+		//logging.Infof("resturned from cols: %v", len(selStmt.Columns))
 	} else {
 		// * mark as star?  TODO
 		return nil, fmt.Errorf("not implemented")
 	}
 
 	// FROM - required
-	//u.Debugf("token:  %s", m.curToken)
+	//logging.Debugf("token:  %s", m.curToken)
 	if m.curToken.T != lex.TokenFrom {
 		return nil, fmt.Errorf("expected From")
 	} else {
 		// table/metric
 		m.curToken = m.l.NextToken()
-		//u.Debugf("found from? %s", m.curToken)
+		//logging.Debugf("found from? %s", m.curToken)
 		if m.curToken.T != lex.TokenIdentity && m.curToken.T != lex.TokenValue {
-			//u.Warnf("No From? %v toktype:%v", m.curToken.V, m.curToken.T.String())
+			//logging.Warnf("No From? %v toktype:%v", m.curToken.V, m.curToken.T.String())
 			return nil, fmt.Errorf("expected from name")
 		} else if m.curToken.T == lex.TokenRegex {
 			selStmt.From = &From{Value: m.curToken.V, Regex: true}
@@ -119,7 +119,7 @@ func (m *Parser) parseSelect(comment string) (*Ast, error) {
 func (m *Parser) parseColumns(stmt *SelectStmt) error {
 
 	stmt.Columns = make([]*Column, 0)
-	u.Infof("cols: %d", len(stmt.Columns))
+	logging.Infof("cols: %d", len(stmt.Columns))
 	return nil
 }
 
@@ -134,6 +134,6 @@ func (m *Parser) parseWhere(stmt *SelectStmt) error {
 		return nil
 	}
 
-	u.Infof("wheres: %#v", stmt)
+	logging.Infof("wheres: %#v", stmt)
 	return nil
 }
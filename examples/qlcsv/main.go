@@ -8,9 +8,12 @@ import (
 
 	"database/sql"
 	u "github.com/araddon/gou"
-	"github.com/araddon/qlbridge/datasource"
+	// registers the "csv" datasource, to show that the backend/sources
+	// can be easily created/added
+	_ "github.com/araddon/qlbridge/datasource/csv"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/expr/builtins"
+	"github.com/araddon/qlbridge/logging"
 	_ "github.com/araddon/qlbridge/qlbdriver"
 	"github.com/araddon/qlbridge/value"
 )
@@ -18,19 +21,21 @@ import (
 var (
 	sqlText          string
 	flagCsvDelimiter = ","
-	logging          = "info"
+	logLevel         = "info"
 )
 
 func init() {
 
-	flag.StringVar(&logging, "logging", "info", "logging [ debug,info ]")
+	flag.StringVar(&logLevel, "logging", "info", "logging [ debug,info ]")
 	flag.StringVar(&sqlText, "sql", "", "QL ish query multi-node such as [select user_id, yy(reg_date) from stdio];")
 	flag.StringVar(&flagCsvDelimiter, "delimiter", ",", "delimiter:   default = comma [t,|]")
 	flag.Parse()
 
-	u.SetupLogging(logging)
+	u.SetupLogging(logLevel)
 	//u.SetColorIfTerminal()
 	u.SetColorOutput()
+	// qlbridge logs nothing unless given a logger
+	logging.SetLogger(logging.GouLogger{})
 }
 
 func main() {
@@ -46,10 +51,6 @@ func main() {
 	// Add a custom function to the VM to make available to SQL language
 	expr.FuncAdd("email_is_valid", EmailIsValid)
 
-	// We are registering the "csv" datasource, to show that
-	// the backend/sources can be easily created/added
-	datasource.Register("csv", &datasource.CsvDataSource{})
-
 	db, err := sql.Open("qlbridge", "csv:///dev/stdin")
 	if err != nil {
 		panic(err.Error())
@@ -0,0 +1,187 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+)
+
+// Result is the outcome of one statement of a batch, see RunBatch
+type Result struct {
+	Stmt expr.SqlStatement
+	Rows []datasource.Message // rows of a select, or of RETURNING
+	Err  error
+}
+
+// RunBatch plans and runs @stmts in order, each statement planned after
+//  the one before it ran, stopping at the first statement to fail unless
+//  ContinueOnError.   There is a Result for each statement run.
+//
+//  The sources written to by the batch that are Transactioners are
+//  written in one transaction, rolled back if the batch stops on an
+//  error, otherwise (even if statements failed with ContinueOnError)
+//  committed.   The error is that of the failed statements, if any.
+func (m *JobBuilder) RunBatch(stmts []expr.SqlStatement) ([]Result, error) {
+	return m.RunBatchContext(context.Background(), stmts)
+}
+
+// RunBatchContext is RunBatch, the statements run with @ctx, cancelling it
+//  stops the running statement and the batch, which is rolled back.
+func (m *JobBuilder) RunBatchContext(ctx context.Context, stmts []expr.SqlStatement) ([]Result, error) {
+
+	txs, err := m.beginBatch(stmts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(stmts))
+	errs := make(errList, 0)
+	for i, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			// cancelled, even ContinueOnError stops
+			errs.append(err)
+			txs.rollback()
+			return results, errs.error()
+		}
+		rows, err := m.runStmt(ctx, stmt, txs.conns())
+		results = append(results, Result{Stmt: stmt, Rows: rows, Err: err})
+		if err != nil {
+			errs.append(fmt.Errorf("statement %d: %v", i, err))
+			if !m.ContinueOnError {
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 && !m.ContinueOnError {
+		txs.rollback()
+	} else if err := txs.commit(); err != nil {
+		errs.append(err)
+	}
+	return results, errs.error()
+}
+
+// plan and run a single statement of a batch, on a builder of its own,
+//  the writes to the tables of @txConns are through the conn in the
+//  transaction of the batch
+func (m *JobBuilder) runStmt(runCtx context.Context, stmt expr.SqlStatement, txConns map[string]datasource.SourceConn) ([]datasource.Message, error) {
+
+	builder := NewJobBuilder(m.schema, m.connInfo)
+	builder.MaxRows = m.MaxRows
+	builder.traceCtx = m.traceCtx
+	builder.batchConns = txConns
+	ex, err := stmt.Accept(builder)
+	if err == nil && ex == nil {
+		err = fmt.Errorf("No job runner? %v", stmt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tasks, ok := ex.(Tasks)
+	if !ok {
+		return nil, fmt.Errorf("expected tasks but got: %T", ex)
+	}
+	defer func() {
+		for _, task := range tasks {
+			task.Close()
+		}
+	}()
+
+	msgs := make([]datasource.Message, 0)
+	tasks.Add(NewResultBuffer(&msgs))
+	if err := SetupTasks(tasks); err != nil {
+		return nil, err
+	}
+	ctx := NewContext(m.schema)
+	ctx.traceCtx = runCtx
+	if err := runTasks(runCtx, ctx, tasks); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// the transactions of a batch, one per source written to
+type batchTxs []*batchTx
+
+type batchTx struct {
+	table string
+	conn  datasource.SourceConn
+	tx    datasource.Tx
+}
+
+// begin a transaction on each Transactioner table @stmts write to
+func (m *JobBuilder) beginBatch(stmts []expr.SqlStatement) (batchTxs, error) {
+	txs := make(batchTxs, 0)
+	begun := make(map[string]bool)
+	for _, stmt := range stmts {
+		table := strings.ToLower(writeTable(stmt))
+		if table == "" || begun[table] {
+			continue
+		}
+		begun[table] = true
+		conn := m.schema.Conn(table)
+		if conn == nil {
+			// the statement fails when planned
+			continue
+		}
+		txer, ok := conn.(datasource.Transactioner)
+		if !ok {
+			conn.Close()
+			continue
+		}
+		tx, err := txer.Begin()
+		if err != nil {
+			conn.Close()
+			txs.rollback()
+			return nil, err
+		}
+		txs = append(txs, &batchTx{table: table, conn: conn, tx: tx})
+	}
+	return txs, nil
+}
+
+// the conn of each table in a transaction, by lower-case name
+func (m batchTxs) conns() map[string]datasource.SourceConn {
+	conns := make(map[string]datasource.SourceConn, len(m))
+	for _, btx := range m {
+		conns[btx.table] = btx.conn
+	}
+	return conns
+}
+
+func (m batchTxs) commit() error {
+	errs := make(errList, 0)
+	for _, btx := range m {
+		errs.append(btx.tx.Commit())
+		errs.append(btx.conn.Close())
+	}
+	return errs.error()
+}
+
+func (m batchTxs) rollback() {
+	for _, btx := range m {
+		if err := btx.tx.Rollback(); err != nil {
+			logging.Errorf("could not rollback: %v", err)
+		}
+		btx.conn.Close()
+	}
+}
+
+// the table a statement writes to, if any
+func writeTable(stmt expr.SqlStatement) string {
+	switch st := stmt.(type) {
+	case *expr.SqlInsert:
+		return st.Into
+	case *expr.SqlUpdate:
+		return st.From
+	case *expr.SqlDelete:
+		return st.Table
+	case *expr.SqlUpsert:
+		return st.Into
+	}
+	return ""
+}
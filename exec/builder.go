@@ -1,16 +1,16 @@
 package exec
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
-	_ = u.EMPTY
-
 	// Ensure that we implement the sql expr.Visitor interface
 	_ expr.Visitor    = (*JobBuilder)(nil)
 	_ expr.SubVisitor = (*JobBuilder)(nil)
@@ -19,11 +19,28 @@ var (
 // This is a simple, single source Job Executor
 //   we can create smarter ones but this is a basic implementation
 type JobBuilder struct {
-	schema   *datasource.RuntimeConfig
-	connInfo string
-	where    expr.Node
-	distinct bool
-	children Tasks
+	// MaxRows caps the rows the job emits, exceeding it fails the job
+	//  with ErrMaxRowsExceeded (unlike LIMIT), 0 = no cap
+	MaxRows int
+	// ContinueOnError runs the rest of a batch after a statement of it
+	//  fails, see RunBatch
+	ContinueOnError bool
+	schema          *datasource.RuntimeConfig
+	connInfo        string
+	where           expr.Node
+	distinct        bool
+	children        Tasks
+	ctes            map[string]*cte // common table expressions by lower-case name
+	paths           []*AccessPath   // the access path chosen for each from table
+	traceCtx        context.Context // parent of the spans of planning, see datasource.Tracer
+	// the conns of the tables in the transaction of a batch, see RunBatch
+	batchConns map[string]datasource.SourceConn
+}
+
+// a common table expression, inlined as a derived table where referenced
+type cte struct {
+	stmt     *expr.SqlSelect
+	building bool
 }
 
 // JobBuilder
@@ -33,42 +50,90 @@ func NewJobBuilder(rtConf *datasource.RuntimeConfig, connInfo string) *JobBuilde
 	b := JobBuilder{}
 	b.schema = rtConf
 	b.connInfo = connInfo
+	b.MaxRows = rtConf.MaxRows
+	b.traceCtx = context.Background()
 	return &b
 }
 
 func (m *JobBuilder) VisitSelect(stmt *expr.SqlSelect) (interface{}, error) {
-	u.Debugf("VisitSelect %+v", stmt)
+	logging.Debugf("VisitSelect %+v", stmt)
+
+	if len(stmt.SetOps) > 0 {
+		return m.visitSetOps(stmt)
+	}
 
 	tasks := make(Tasks, 0)
 
-	if len(stmt.From) == 1 {
+	for _, with := range stmt.With {
+		if m.ctes == nil {
+			m.ctes = make(map[string]*cte)
+		}
+		m.ctes[strings.ToLower(with.Alias)] = &cte{stmt: with.Source}
+	}
+
+	// a lateral table func or subquery is evaluated per row of the source before it
+	//   FROM users, unnest(tags) AS t
+	//   FROM users AS u, LATERAL (SELECT ... WHERE user_id = u.user_id) AS o
+	sources := stmt.From
+	var lateral *expr.SqlSource
+	if len(sources) == 2 && (sources[1].Func != nil || sources[1].Lateral) {
+		sources, lateral = sources[:1], sources[1]
+	}
+
+	// the where evaluated here, less any MATCH a full text index answered
+	var filter expr.Node
+	if stmt.Where != nil {
+		filter = stmt.Where.Expr
+	}
+
+	if len(sources) == 0 {
+		// SELECT 1 + 1, now()   no source, the projection is of one empty row
+		tasks.Add(NewSingleRow())
+	} else if len(sources) == 1 {
 		// One From Source   This entire Source needs to be moved into
 		//  a From().Accept(m) or m.visitSubselect()
-		from := stmt.From[0]
-		if from.Name != "" && from.Source == nil {
-			u.Infof("get SourceConn: %v", from.Name)
-			sourceConn := m.schema.Conn(from.Name)
-			u.Debugf("sourceConn: %T  %#v", sourceConn, sourceConn)
-			// Must provider either Scanner, and or Seeker interfaces
-			if scanner, ok := sourceConn.(datasource.Scanner); !ok {
-				return nil, fmt.Errorf("Must Implement Scanner")
-			} else {
-				in := NewSource(from, scanner)
-				tasks.Add(in)
+		from := sources[0]
+		if from.Func != nil {
+			in, err := NewTableFunc(from)
+			if err != nil {
+				return nil, err
+			}
+			tasks.Add(in)
+		} else if c, ok := m.ctes[strings.ToLower(from.Name)]; ok && from.Source == nil {
+			subTasks, err := m.visitCte(from, c)
+			if err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, subTasks...)
+		} else if from.Name != "" && from.Source == nil {
+			logging.Infof("get SourceConn: %v", from.Name)
+			scanner, err := m.sourceScanner(from, stmt)
+			if err != nil {
+				return nil, err
+			}
+			in := NewSource(from, scanner)
+			tasks.Add(in)
+			m.logFeatures(from.Name, stmt)
+			// the index may match words our token matching would not (stemming)
+			if path := m.paths[len(m.paths)-1]; path.Kind == AccessSearch {
+				filter = path.Filter
+			}
+		} else if from.Name == "" && from.Source != nil {
+			subTasks, err := m.VisitSubselect(from)
+			if err != nil {
+				return nil, err
 			}
+			tasks = append(tasks, subTasks.(Tasks)...)
 		}
 	} else {
 		// for now, only support 1 join
 		if len(stmt.From) != 2 {
 			return nil, fmt.Errorf("3 or more Table/Join not currently implemented")
 		}
-		// We really need to move this Rewrite into Planner or a Finalizer()?
-		// for _, from := range stmt.From {
-		// 	from.Rewrite(stmt)
-		// }
-		// Fold 0 <- 1
-		stmt.From[0].Rewrite(true, stmt)
-		stmt.From[1].Rewrite(false, stmt)
+		// Fold 0 <- 1, each source was rewritten to its own sub-select
+		if err := stmt.Rewrite(); err != nil {
+			return nil, err
+		}
 		in, err := NewSourceJoin(m, stmt.From[0], stmt.From[1], m.schema)
 		if err != nil {
 			return nil, err
@@ -76,69 +141,410 @@ func (m *JobBuilder) VisitSelect(stmt *expr.SqlSelect) (interface{}, error) {
 		tasks.Add(in)
 	}
 
-	//u.Debugf("has where? %v", stmt.Where != nil)
+	if lateral != nil && lateral.Lateral {
+		tasks.Add(NewLateral(m, sources[0], lateral))
+	} else if lateral != nil {
+		in, err := NewTableFunc(lateral)
+		if err != nil {
+			return nil, err
+		}
+		tasks.Add(in)
+	}
+
+	//logging.Debugf("has where? %v", stmt.Where != nil)
 	if stmt.Where != nil {
 		switch {
 		case stmt.Where.Source != nil:
-			u.Warnf("Found un-supported subquery: %#v", stmt.Where)
+			logging.Warnf("Found un-supported subquery: %#v", stmt.Where)
 		case stmt.Where.Expr != nil:
-			where := NewWhere(stmt.Where.Expr)
-			tasks.Add(where)
+			if filter != nil {
+				where := NewWhereCollation(filter, m.schema.Collation)
+				where.builder = m
+				tasks.Add(where)
+			}
 		default:
-			u.Warnf("Found un-supported where type: %#v", stmt.Where)
+			logging.Warnf("Found un-supported where type: %#v", stmt.Where)
+		}
+
+	}
+
+	if hasWindow(stmt) {
+		if needsGroupBy(stmt) {
+			return nil, fmt.Errorf("window functions with GROUP BY or aggregates not supported")
+		}
+		window, err := NewWindowCollation(stmt, m.schema.Collation)
+		if err != nil {
+			return nil, err
 		}
+		tasks.Add(window)
+	}
 
+	if needsGroupBy(stmt) {
+		if len(stmt.DistinctOn) > 0 {
+			return nil, fmt.Errorf("DISTINCT ON with GROUP BY or aggregates not supported")
+		}
+		// group by emits the select columns, so takes the place of projection
+		groupBy := NewGroupBy(stmt)
+		groupBy.builder = m
+		tasks.Add(groupBy)
+		if len(stmt.OrderBy) > 0 {
+			tasks.Add(NewSort(stmt.OrderBy, m.schema.Collation))
+		}
+		m.addMaxRows(&tasks)
+		return tasks, nil
+	}
+
+	if len(stmt.OrderBy) > 0 {
+		tasks.Add(NewSort(stmt.OrderBy, m.schema.Collation))
+	}
+	if len(stmt.DistinctOn) > 0 {
+		// the first row of each, in the order of the sort
+		tasks.Add(NewDistinctOn(stmt.DistinctOn))
 	}
 
 	// Add a Projection
 	projection := NewProjection(stmt)
-	u.Infof("adding projection: %#v", projection)
+	logging.Infof("adding projection: %#v", projection)
 	tasks.Add(projection)
+	m.addMaxRows(&tasks)
+
+	return tasks, nil
+}
 
+// the left select, then a task per set operation (UNION, EXCEPT, INTERSECT)
+//  on its rows, the ORDER BY and MaxRows are of the combined rows
+func (m *JobBuilder) visitSetOps(stmt *expr.SqlSelect) (interface{}, error) {
+	maxRows := m.MaxRows
+	m.MaxRows = 0
+	defer func() { m.MaxRows = maxRows }()
+
+	left := *stmt
+	left.SetOps, left.OrderBy = nil, nil
+	leftTasks, err := m.VisitSelect(&left)
+	if err != nil {
+		return nil, err
+	}
+	tasks := leftTasks.(Tasks)
+	for _, op := range stmt.SetOps {
+		right, err := m.VisitSelect(op.Right)
+		if err != nil {
+			return nil, err
+		}
+		tasks.Add(NewSetOperation(op, stmt.Columns, right.(Tasks)))
+	}
+	if len(stmt.OrderBy) > 0 {
+		tasks.Add(NewSort(stmt.OrderBy, m.schema.Collation))
+	}
+	if maxRows > 0 {
+		tasks.Add(NewMaxRows(maxRows))
+	}
 	return tasks, nil
 }
 
+// the MaxRows cap is on the output of the job, so the last task
+func (m *JobBuilder) addMaxRows(tasks *Tasks) {
+	if m.MaxRows > 0 {
+		tasks.Add(NewMaxRows(m.MaxRows))
+	}
+}
+
+// ResultSchema describes the columns (names, types) a select will return
+//  without running it.   Types are inferred from the column expressions,
+//  ie func return types, arithmetic, literals; identities are UnknownType
+//  as sources do not currently describe their columns.
+func (m *JobBuilder) ResultSchema(stmt *expr.SqlSelect) (*datasource.Schema, error) {
+	if stmt == nil {
+		return nil, fmt.Errorf("nil select statement")
+	}
+	return projectionSchema(stmt)
+}
+
+// VisitSubselect builds the tasks of a derived table  FROM (SELECT ...) AS t
+//  the inner select runs first and its output rows are the source of the
+//  outer query, under the alias
 func (m *JobBuilder) VisitSubselect(stmt *expr.SqlSource) (interface{}, error) {
-	u.Debugf("VisitSubselect %+v", stmt)
-	return nil, expr.ErrNotImplemented
+	logging.Debugf("VisitSubselect %+v", stmt)
+	if stmt.Source == nil {
+		return nil, expr.ErrNotImplemented
+	}
+	// derived tables are not the job output, so are not capped
+	maxRows := m.MaxRows
+	m.MaxRows = 0
+	inner, err := m.VisitSelect(stmt.Source)
+	m.MaxRows = maxRows
+	if err != nil {
+		return nil, err
+	}
+	tasks := inner.(Tasks)
+	tasks.Add(NewSubSelect(stmt))
+	return tasks, nil
+}
+
+// a from reference to a cte is built as a derived table of the cte select
+//  under the alias (or cte name), so each reference re-runs the cte
+func (m *JobBuilder) visitCte(from *expr.SqlSource, c *cte) (Tasks, error) {
+	if c.building {
+		return nil, fmt.Errorf("recursive common table expression not supported: %v", from.Name)
+	}
+	c.building = true
+	defer func() { c.building = false }()
+	alias := from.Alias
+	if alias == "" {
+		alias = from.Name
+	}
+	subTasks, err := m.VisitSubselect(&expr.SqlSource{Pos: from.Pos, Alias: alias, Source: c.stmt})
+	if err != nil {
+		return nil, err
+	}
+	return subTasks.(Tasks), nil
 }
 
 func (m *JobBuilder) VisitJoin(stmt *expr.SqlSource) (interface{}, error) {
-	u.Debugf("VisitJoin %+v", stmt)
+	logging.Debugf("VisitJoin %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *JobBuilder) VisitInsert(stmt *expr.SqlInsert) (interface{}, error) {
-	u.Debugf("VisitInsert %+v", stmt)
-	return nil, expr.ErrNotImplemented
+	logging.Debugf("VisitInsert %+v", stmt)
+
+	sourceConn, _ := m.writeConn(stmt.Into)
+	if sourceConn == nil {
+		return nil, fmt.Errorf("No source found for %v", stmt.Into)
+	}
+	inserter, ok := sourceConn.(datasource.Inserter)
+	if !ok {
+		return nil, fmt.Errorf("%T Must Implement Inserter", sourceConn)
+	}
+	// the schema, if the source has one, supplies column defaults
+	schema, err := sourceSchema(sourceConn, stmt.Into)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := insertRows(stmt, schema)
+	if err != nil {
+		return nil, err
+	}
+	insert := NewInsert(inserter, rows, len(stmt.Returning) > 0)
+	insert.txer = m.transactioner(sourceConn, stmt.Into)
+	if stmt.IgnoreConflicts {
+		seeker, ok := sourceConn.(datasource.Seeker)
+		if !ok {
+			return nil, fmt.Errorf("%T Must Implement Seeker for ON CONFLICT", sourceConn)
+		}
+		insert.seeker, insert.key = seeker, stmt.ConflictKey
+		if insert.key == "" && len(stmt.Columns) > 0 {
+			insert.key = stmt.Columns[0].As
+		}
+	}
+	tasks := make(Tasks, 0)
+	tasks.Add(insert)
+	m.addReturning(&tasks, stmt.Returning)
+	return tasks, nil
 }
 
 func (m *JobBuilder) VisitDelete(stmt *expr.SqlDelete) (interface{}, error) {
-	u.Debugf("VisitDelete %+v", stmt)
-	return nil, expr.ErrNotImplemented
+	logging.Debugf("VisitDelete %+v", stmt)
+
+	tasks, sourceConn, err := m.scanWhere(stmt.Table, stmt.Where)
+	if err != nil {
+		return nil, err
+	}
+	deleter, ok := sourceConn.(datasource.Deleter)
+	if !ok {
+		return nil, fmt.Errorf("%T Must Implement Deleter", sourceConn)
+	}
+	del := NewDelete(stmt, deleter)
+	del.txer = m.transactioner(sourceConn, stmt.Table)
+	tasks.Add(del)
+	m.addReturning(&tasks, stmt.Returning)
+	return tasks, nil
 }
 
 func (m *JobBuilder) VisitUpdate(stmt *expr.SqlUpdate) (interface{}, error) {
-	u.Debugf("VisitUpdate %+v", stmt)
-	return nil, expr.ErrNotImplemented
+	logging.Debugf("VisitUpdate %+v", stmt)
+
+	where := stmt.Where
+	if stmt.Source != nil {
+		// UPDATE ... FROM, the where is the condition of the join
+		where = nil
+	}
+	tasks, sourceConn, err := m.scanWhere(stmt.From, where)
+	if err != nil {
+		return nil, err
+	}
+	updater, ok := sourceConn.(datasource.Updater)
+	if !ok {
+		return nil, fmt.Errorf("%T Must Implement Updater", sourceConn)
+	}
+	update := NewUpdate(stmt, updater)
+	update.txer = m.transactioner(sourceConn, stmt.From)
+	schema, err := sourceSchema(sourceConn, stmt.From)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil {
+		if update.cols, err = datasource.ResolveSchemaInfo(schema, columnNames(stmt.Columns)); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Source != nil {
+		// opened last, once nothing else can fail, the UpdateFrom closes it
+		fromConn := m.schema.Conn(stmt.Source.Name)
+		if fromConn == nil {
+			return nil, fmt.Errorf("No source found for %v", stmt.Source.Name)
+		}
+		scanner, ok := fromConn.(datasource.Scanner)
+		if !ok {
+			fromConn.Close()
+			return nil, fmt.Errorf("Must Implement Scanner")
+		}
+		tasks.Add(NewUpdateFrom(stmt, scanner, m.schema.Collation))
+	}
+	tasks.Add(update)
+	m.addReturning(&tasks, stmt.Returning)
+	return tasks, nil
+}
+
+// the schema of @table if the source has one, else nil
+func sourceSchema(sourceConn datasource.SourceConn, table string) (*datasource.Schema, error) {
+	if provider, ok := sourceConn.(datasource.SchemaProvider); ok {
+		return provider.Schema(table)
+	}
+	return nil, nil
+}
+
+// the scanner of a from table, of the cheapest of its access paths (see
+//  accessPaths), the chosen path is kept for Explain
+func (m *JobBuilder) sourceScanner(from *expr.SqlSource, stmt *expr.SqlSelect) (datasource.Scanner, error) {
+	_, span := m.schema.Tracing().StartSpan(m.traceCtx, "access")
+	span.SetAttribute("table", from.Name)
+	paths, err := m.accessPaths(from, stmt)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	path := cheapestPath(paths)
+	logging.Debugf("access path: %s of %d", path, len(paths))
+	m.paths = append(m.paths, path)
+	span.SetAttribute("access", path.Kind)
+	span.SetAttribute("cost", path.Cost)
+	span.End()
+	return path.scanner, nil
+}
+
+// log the features of the source the plan uses vs the clauses it falls back
+//  on evaluating here, noting those the source claims it could have done
+func (m *JobBuilder) logFeatures(table string, stmt *expr.SqlSelect) {
+	f := m.schema.SourceFeatures(table)
+	if f == nil {
+		return
+	}
+	features := f.Describe()
+	fallback := make([]string, 0)
+	fellBack := func(clause string, supported bool) {
+		if supported {
+			clause += " (supported, not pushed down)"
+		}
+		fallback = append(fallback, clause)
+	}
+	if stmt.Where != nil {
+		fellBack("where", features.Where)
+	}
+	if len(stmt.GroupBy) > 0 {
+		fellBack("groupby", features.GroupBy)
+	} else if needsGroupBy(stmt) {
+		fellBack("aggregations", features.Aggregations)
+	}
+	if len(stmt.OrderBy) > 0 {
+		fellBack("sort", features.Sort)
+	}
+	used := AccessScan
+	if len(m.paths) > 0 {
+		used = m.paths[len(m.paths)-1].Kind
+	}
+	logging.Infof("source %s is %s, used: [%s] fell back on: [%s]", table, f.Summary(), used, strings.Join(fallback, ", "))
+}
+
+// the source scan, and where filter, of the rows an update or delete affects
+func (m *JobBuilder) scanWhere(table string, where expr.Node) (Tasks, datasource.SourceConn, error) {
+	sourceConn, inBatch := m.writeConn(table)
+	if sourceConn == nil {
+		return nil, nil, fmt.Errorf("No source found for %v", table)
+	}
+	scanner, ok := sourceConn.(datasource.Scanner)
+	if !ok {
+		return nil, nil, fmt.Errorf("Must Implement Scanner")
+	}
+	if inBatch {
+		// the batch closes its conn, not the scan
+		scanner = batchScanner{scanner}
+	}
+	tasks := make(Tasks, 0)
+	tasks.Add(NewSource(&expr.SqlSource{Name: table}, scanner))
+	if where != nil {
+		filter := NewWhereCollation(where, m.schema.Collation)
+		filter.builder = m
+		tasks.Add(filter)
+	}
+	return tasks, sourceConn, nil
+}
+
+// RETURNING columns are a projection of the rows emitted by insert,
+//  update and delete tasks
+func (m *JobBuilder) addReturning(tasks *Tasks, returning expr.Columns) {
+	if len(returning) == 0 {
+		return
+	}
+	tasks.Add(NewProjection(&expr.SqlSelect{Columns: returning}))
 }
 
 func (m *JobBuilder) VisitUpsert(stmt *expr.SqlUpsert) (interface{}, error) {
-	u.Debugf("VisitUpdate %+v", stmt)
+	logging.Debugf("VisitUpdate %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *JobBuilder) VisitShow(stmt *expr.SqlShow) (interface{}, error) {
-	u.Debugf("VisitShow %+v", stmt)
+	logging.Debugf("VisitShow %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
+// VisitDescribe of an EXPLAIN SELECT emits a row per from table, of the
+//  access path the select would read it by
+//
+//     EXPLAIN SELECT name FROM users WHERE user_id = "a"
 func (m *JobBuilder) VisitDescribe(stmt *expr.SqlDescribe) (interface{}, error) {
-	u.Debugf("VisitDescribe %+v", stmt)
-	return nil, expr.ErrNotImplemented
+	logging.Debugf("VisitDescribe %+v", stmt)
+	sel, ok := stmt.Stmt.(*expr.SqlSelect)
+	if !ok {
+		return nil, expr.ErrNotImplemented
+	}
+	paths, err := m.Explain(sel)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make(Tasks, 0)
+	tasks.Add(NewExplain(paths))
+	return tasks, nil
+}
+
+// Explain plans @stmt, without running it, and returns the access path
+//  chosen for each of its from tables
+func (m *JobBuilder) Explain(stmt *expr.SqlSelect) ([]*AccessPath, error) {
+	b := NewJobBuilder(m.schema, m.connInfo)
+	ex, err := stmt.Accept(b)
+	if err != nil {
+		return nil, err
+	}
+	// the tasks are never run, close the sources they opened
+	if tasks, ok := ex.(Tasks); ok {
+		for _, task := range tasks {
+			task.Close()
+		}
+	}
+	return b.paths, nil
 }
 
 func (m *JobBuilder) VisitPreparedStmt(stmt *expr.PreparedStatement) (interface{}, error) {
-	u.Debugf("VisitPreparedStmt %+v", stmt)
+	logging.Debugf("VisitPreparedStmt %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
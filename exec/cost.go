@@ -0,0 +1,360 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
+)
+
+var (
+	_ datasource.Scanner  = (*seekScanner)(nil)
+	_ datasource.Iterator = (*seekScanner)(nil)
+	_ datasource.Scanner  = (*searchScanner)(nil)
+)
+
+// The access paths of a from table
+const (
+	AccessScan    = "scan"    // iterate every row of the source
+	AccessSeek    = "seek"    // Get() the rows of the where key values
+	AccessSearch  = "search"  // Search() the full text index for the where MATCH
+	AccessPlanner = "planner" // the source planned its own scan
+)
+
+// The cost model, in units of reading one row from a source.  There are
+//  no table statistics, a table is assumed to have estimatedRows rows and
+//  a seek key to be unique.
+const (
+	costRowRead   = 1.0
+	costRowFilter = 0.5 // evaluating the where on a row
+	costSeek      = 4.0 // one Get() of a key
+	costSearch    = 4.0 // one Search() of the full text index
+	estimatedRows = 1000
+	// the share of the rows a full text search is assumed to match
+	searchSelectivity = 0.1
+)
+
+// AccessPath is how the rows of a from table are read, the planner costs
+//  each path the source supports and uses the cheapest
+//
+//     seek users key: user_id IN ("a", "b") filter: age > 20 cost: 9
+type AccessPath struct {
+	Table  string
+	Kind   string    // AccessScan, AccessSeek, AccessPlanner
+	Key    string    // the seek key column, or the searched columns
+	Keys   []string  // the seek key values, or the search term
+	Filter expr.Node // the where the access path does not answer, nil if none
+	Cost   float64
+	// the scanner of the rows
+	scanner datasource.Scanner
+}
+
+func (m *AccessPath) String() string {
+	s := fmt.Sprintf("%s %s", m.Kind, m.Table)
+	switch m.Kind {
+	case AccessSeek:
+		s += fmt.Sprintf(" key: %s IN (%s)", m.Key, strings.Join(m.Keys, ", "))
+	case AccessSearch:
+		s += fmt.Sprintf(" match: (%s) AGAINST %q", m.Key, strings.Join(m.Keys, " "))
+	}
+	if m.Filter != nil {
+		s += fmt.Sprintf(" filter: %s", m.Filter)
+	}
+	return s + fmt.Sprintf(" cost: %g", m.Cost)
+}
+
+// reading every row, and filtering each if there is a where
+func scanCost(where expr.Node) float64 {
+	if where == nil {
+		return estimatedRows * costRowRead
+	}
+	return estimatedRows * (costRowRead + costRowFilter)
+}
+
+// a Get() per key, the where is still evaluated on each row found
+func seekCost(keys int) float64 {
+	return float64(keys) * (costSeek + costRowFilter)
+}
+
+// one Search(), reading (and filtering if there is more where) the rows
+//  matched
+func searchCost(residual expr.Node) float64 {
+	matched := estimatedRows * searchSelectivity
+	if residual == nil {
+		return costSearch + matched*costRowRead
+	}
+	return costSearch + matched*(costRowRead+costRowFilter)
+}
+
+// the access paths of @from for @stmt:  a SourcePlanner plans its own, else
+//  a scan if the source is a Scanner, a seek if it is a Seeker with an
+//  equality (or IN list) of its key in the where, and a search if it is a
+//  FullTextSearcher of the columns of a MATCH in the where
+func (m *JobBuilder) accessPaths(from *expr.SqlSource, stmt *expr.SqlSelect) ([]*AccessPath, error) {
+	sourceConn := m.schema.Conn(from.Name)
+	logging.Debugf("sourceConn: %T  %#v", sourceConn, sourceConn)
+	var where expr.Node
+	if stmt != nil && stmt.Where != nil {
+		where = stmt.Where.Expr
+	}
+	if sourcePlan, ok := sourceConn.(datasource.SourcePlanner); ok {
+		plan := NewSourcePlan(from)
+		plan.Select = stmt
+		scanner, err := sourcePlan.Accept(plan)
+		if err != nil {
+			return nil, fmt.Errorf("could not source plan %v: %v", from.Name, err)
+		}
+		if scanner == nil {
+			return nil, fmt.Errorf("source plan of %v has no Scanner", from.Name)
+		}
+		return []*AccessPath{{Table: from.Name, Kind: AccessPlanner, Filter: where, scanner: scanner}}, nil
+	}
+	paths := make([]*AccessPath, 0, 2)
+	if scanner, ok := sourceConn.(datasource.Scanner); ok {
+		paths = append(paths, &AccessPath{Table: from.Name, Kind: AccessScan, Filter: where,
+			Cost: scanCost(where), scanner: scanner})
+	}
+	if seek := seekPath(sourceConn, from, where); seek != nil {
+		paths = append(paths, seek)
+	}
+	if search := searchPath(sourceConn, from, where); search != nil {
+		paths = append(paths, search)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Must Implement Scanner")
+	}
+	return paths, nil
+}
+
+// the cheapest of @paths, ties go to the first
+func cheapestPath(paths []*AccessPath) *AccessPath {
+	best := paths[0]
+	for _, path := range paths[1:] {
+		if path.Cost < best.Cost {
+			best = path
+		}
+	}
+	return best
+}
+
+// the seek of the first where conjunct that is an equality, or IN list, of
+//  literals on the seek key of the source, nil if there is none
+func seekPath(sourceConn datasource.SourceConn, from *expr.SqlSource, where expr.Node) *AccessPath {
+	seeker, ok := sourceConn.(datasource.Seeker)
+	if !ok || where == nil {
+		return nil
+	}
+	keyer, ok := sourceConn.(datasource.SeekKeyer)
+	if !ok {
+		return nil
+	}
+	key := keyer.SeekKey(from.Name)
+	if key == "" {
+		return nil
+	}
+	conjuncts := expr.SplitConjuncts(where)
+	for i, c := range conjuncts {
+		keys, ok := seekKeys(c, key, from.Alias)
+		if !ok {
+			continue
+		}
+		residual := make([]expr.Node, 0, len(conjuncts)-1)
+		residual = append(residual, conjuncts[:i]...)
+		residual = append(residual, conjuncts[i+1:]...)
+		return &AccessPath{
+			Table:   from.Name,
+			Kind:    AccessSeek,
+			Key:     key,
+			Keys:    keys,
+			Filter:  expr.JoinConjuncts(residual),
+			Cost:    seekCost(len(keys)),
+			scanner: newSeekScanner(seeker, keys),
+		}
+	}
+	return nil
+}
+
+// the search of the first where conjunct that is a MATCH of columns the
+//  source has indexed, nil if there is none.   The MATCH is answered by the
+//  index, so is not part of the Filter.
+func searchPath(sourceConn datasource.SourceConn, from *expr.SqlSource, where expr.Node) *AccessPath {
+	searcher, ok := sourceConn.(datasource.FullTextSearcher)
+	if !ok || where == nil {
+		return nil
+	}
+	conjuncts := expr.SplitConjuncts(where)
+	for i, c := range conjuncts {
+		match, ok := c.(*expr.MatchNode)
+		if !ok {
+			continue
+		}
+		cols := match.ColNames()
+		for j, col := range cols {
+			if from.Alias != "" && strings.HasPrefix(col, from.Alias+".") {
+				cols[j] = col[len(from.Alias)+1:]
+			}
+		}
+		if !searcher.CanSearch(from.Name, cols) {
+			continue
+		}
+		residual := make([]expr.Node, 0, len(conjuncts)-1)
+		residual = append(residual, conjuncts[:i]...)
+		residual = append(residual, conjuncts[i+1:]...)
+		filter := expr.JoinConjuncts(residual)
+		return &AccessPath{
+			Table:   from.Name,
+			Kind:    AccessSearch,
+			Key:     strings.Join(cols, ", "),
+			Keys:    []string{match.Against},
+			Filter:  filter,
+			Cost:    searchCost(filter),
+			scanner: newSearchScanner(sourceConn, searcher, from.Name, cols, match.Against),
+		}
+	}
+	return nil
+}
+
+// the key values of a sargable equality or IN list on column @key (or
+//  @alias.key)
+//
+//     user_id = "a"             =>  [a]
+//     u.user_id IN ("a", "b")   =>  [a, b]
+func seekKeys(n expr.Node, key, alias string) ([]string, bool) {
+	col, ok := expr.IsSargable(n)
+	if !ok {
+		return nil, false
+	}
+	if alias != "" && strings.HasPrefix(col, alias+".") {
+		col = col[len(alias)+1:]
+	}
+	if !strings.EqualFold(col, key) {
+		return nil, false
+	}
+	var literals []expr.Node
+	switch nt := n.(type) {
+	case *expr.BinaryNode:
+		switch nt.Operator.T {
+		case lex.TokenEqual, lex.TokenEqualEqual:
+		default:
+			return nil, false
+		}
+		literals = nt.Args[1:]
+		if _, isIdent := nt.Args[1].(*expr.IdentityNode); isIdent {
+			literals = nt.Args[:1]
+		}
+	case *expr.MultiArgNode:
+		literals = nt.Args[1:]
+	default:
+		return nil, false
+	}
+	keys := make([]string, 0, len(literals))
+	seen := make(map[string]struct{}, len(literals))
+	for _, lit := range literals {
+		var k string
+		switch lt := lit.(type) {
+		case *expr.StringNode:
+			k = lt.Text
+		case *expr.NumberNode:
+			k = lt.Text
+		default:
+			return nil, false
+		}
+		// a key listed twice is one row
+		if _, dup := seen[k]; !dup {
+			seen[k] = struct{}{}
+			keys = append(keys, k)
+		}
+	}
+	return keys, true
+}
+
+// scans the rows of the seek keys, in key order, keys not found are skipped,
+//  closing it stops its MesgChan and closes the conn
+type seekScanner struct {
+	datasource.Seeker
+	keys []string
+	next int
+	exit *scanExit
+}
+
+func newSeekScanner(seeker datasource.Seeker, keys []string) *seekScanner {
+	return &seekScanner{Seeker: seeker, keys: keys, exit: newScanExit()}
+}
+
+func (m *seekScanner) CreateIterator(filter expr.Node) datasource.Iterator {
+	return &seekScanner{Seeker: m.Seeker, keys: m.keys}
+}
+func (m *seekScanner) MesgChan(filter expr.Node) <-chan datasource.Message {
+	return datasource.SourceIterChannel(m.CreateIterator(filter), filter, m.exit.ch)
+}
+func (m *seekScanner) Close() error {
+	m.exit.close()
+	return m.Seeker.Close()
+}
+func (m *seekScanner) Next() datasource.Message {
+	for m.next < len(m.keys) {
+		m.next++
+		if msg := m.Get(m.keys[m.next-1]); msg != nil {
+			return msg
+		}
+	}
+	return nil
+}
+
+// scans the rows the full text index matches, closing it stops its
+//  MesgChan and closes the conn
+type searchScanner struct {
+	conn     datasource.SourceConn
+	searcher datasource.FullTextSearcher
+	table    string
+	cols     []string
+	against  string
+	exit     *scanExit
+}
+
+func newSearchScanner(conn datasource.SourceConn, searcher datasource.FullTextSearcher, table string,
+	cols []string, against string) *searchScanner {
+	return &searchScanner{conn: conn, searcher: searcher, table: table, cols: cols, against: against,
+		exit: newScanExit()}
+}
+
+func (m *searchScanner) CreateIterator(filter expr.Node) datasource.Iterator {
+	iter, err := m.searcher.Search(m.table, m.cols, m.against)
+	if err != nil {
+		logging.Errorf("could not search %s: %v", m.table, err)
+		return &errIterator{err: err}
+	}
+	return iter
+}
+func (m *searchScanner) MesgChan(filter expr.Node) <-chan datasource.Message {
+	return datasource.SourceIterChannel(m.CreateIterator(filter), filter, m.exit.ch)
+}
+func (m *searchScanner) Close() error {
+	m.exit.close()
+	return m.conn.Close()
+}
+
+// the exit channel of the MesgChan of a scanner, closed once when it is
+type scanExit struct {
+	ch   chan bool
+	once sync.Once
+}
+
+func newScanExit() *scanExit { return &scanExit{ch: make(chan bool)} }
+func (m *scanExit) close() {
+	if m != nil {
+		m.once.Do(func() { close(m.ch) })
+	}
+}
+
+// an iterator of no rows, which failed with err
+type errIterator struct {
+	err error
+}
+
+func (m *errIterator) Next() datasource.Message { return nil }
+func (m *errIterator) Err() error               { return m.err }
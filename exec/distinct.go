@@ -0,0 +1,64 @@
+package exec
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/expr"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*DistinctOn)(nil)
+)
+
+// DistinctOn forwards the first row of each distinct value of the
+//  DISTINCT ON columns, so after the Sort it is the first row of each in
+//  the ORDER BY.  The keys seen are kept, the rows are not buffered.  All
+//  NULLs are one key (see groupingKey), so only the first NULL row is kept.
+//
+//     SELECT DISTINCT ON (user_id) * FROM events ORDER BY user_id, ts DESC
+type DistinctOn struct {
+	*TaskBase
+	cols expr.Columns
+}
+
+func NewDistinctOn(cols expr.Columns) *DistinctOn {
+	return &DistinctOn{
+		TaskBase: NewTaskBase("DistinctOn"),
+		cols:     cols,
+	}
+}
+
+func (m *DistinctOn) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	seen := make(map[string]struct{})
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				return nil
+			}
+			reader, ok := msg.Body().(expr.ContextReader)
+			if !ok {
+				if err := ctx.RowError(msg, fmt.Errorf("could not convert to message reader: %T", msg.Body())); err != nil {
+					return err
+				}
+				continue
+			}
+			key := partitionKey(reader, m.cols)
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			select {
+			case m.msgOutCh <- msg:
+			case <-m.sigCh:
+				return nil
+			}
+		case <-m.sigCh:
+			return nil
+		}
+	}
+}
@@ -1,35 +1,139 @@
 package exec
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
 	ShuttingDownError = fmt.Errorf("Received Shutdown Signal")
+	// A job emitted more than its MaxRows
+	ErrMaxRowsExceeded = fmt.Errorf("Max result rows exceeded")
+	// A job ran longer than its Timeout
+	ErrJobTimeout = fmt.Errorf("Query exceeded timeout")
 
 	// SqlJob implements JobRunner
 	_ JobRunner = (*SqlJob)(nil)
 )
 
 // Job Runner is the main RunTime interface for running a SQL Job
+//   cancelling the context passed to Run() stops all tasks and closes
+//   the job's source connections
 type JobRunner interface {
 	Setup() error
-	Run() error
+	Run(ctx context.Context) error
 	Close() error
 }
 
 type Context struct {
 	DisableRecover bool
-	errRecover     interface{}
-	id             string
-	prefix         string
+	// Strict fails the job on the first row error, otherwise row errors
+	//  are collected (see RowErrors) and the row skipped
+	Strict     bool
+	errRecover interface{}
+	rowErrs    []*RowError
+	id         string
+	prefix     string
+	mu         sync.Mutex
+	tracer     datasource.Tracer
+	traceCtx   context.Context // parent of the spans of the tasks
+	runCtx     context.Context // the job runs with, see runContext
+	maxWorkers int             // of the config, see reserveWorkers
+	workers    int             // running the tasks of the job
+}
+
+func NewContext(conf *datasource.RuntimeConfig) *Context {
+	maxWorkers := conf.MaxWorkers
+	if maxWorkers <= 0 {
+		maxWorkers = DefaultMaxWorkers
+	}
+	return &Context{DisableRecover: conf.DisableRecover, Strict: conf.StrictErrors,
+		tracer: conf.Tracing(), traceCtx: context.Background(), maxWorkers: maxWorkers}
+}
+
+// StartSpan starts a span of the job (see datasource.Tracer), a child of
+//  the span of the context the job was Run with
+func (m *Context) StartSpan(name string) datasource.Span {
+	tracer, traceCtx := m.tracer, m.traceCtx
+	if tracer == nil {
+		// not of NewContext
+		tracer, traceCtx = datasource.NoopTracer{}, context.Background()
+	}
+	_, span := tracer.StartSpan(traceCtx, name)
+	return span
+}
+
+// the context the job runs with, for the jobs a task runs of its own (ie
+//  the right select of a set operation) so they are cancelled with it
+func (m *Context) runContext() context.Context {
+	if m.runCtx == nil {
+		return context.Background()
+	}
+	return m.runCtx
+}
+
+// reserve a worker for each of @n tasks, the tasks of a job and of the
+//  jobs its tasks run of their own (ie subqueries) share the MaxWorkers.
+//  A streaming pipeline needs every task running, so a job of more tasks
+//  than there are workers for is an error rather than queued
+func (m *Context) reserveWorkers(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	maxWorkers := m.maxWorkers
+	if maxWorkers <= 0 {
+		// not of NewContext
+		maxWorkers = DefaultMaxWorkers
+	}
+	if m.workers+n > maxWorkers {
+		return fmt.Errorf("job needs %d workers for its tasks but %d of MaxWorkers %d are free",
+			n, maxWorkers-m.workers, maxWorkers)
+	}
+	m.workers += n
+	return nil
+}
+
+func (m *Context) releaseWorkers(n int) {
+	m.mu.Lock()
+	m.workers -= n
+	m.mu.Unlock()
+}
+
+// RowError is an error evaluating a single message (row) of a job
+type RowError struct {
+	Key uint64 // Key() of the message
+	Err error
+}
+
+func (m *RowError) Error() string { return fmt.Sprintf("row %d: %v", m.Key, m.Err) }
+
+// RowError records an error evaluating @msg, in strict mode the error is
+//  returned and the task should stop and return it, otherwise it is nil
+//  and the task should skip the row and continue
+func (m *Context) RowError(msg datasource.Message, err error) error {
+	rowErr := &RowError{Key: msg.Key(), Err: err}
+	logging.Warnf("%v", rowErr)
+	m.mu.Lock()
+	m.rowErrs = append(m.rowErrs, rowErr)
+	m.mu.Unlock()
+	if m.Strict {
+		return rowErr
+	}
+	return nil
+}
+
+// RowErrors are the errors of rows skipped while running
+func (m *Context) RowErrors() []*RowError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*RowError(nil), m.rowErrs...)
 }
 
 func (m *Context) Recover() {
@@ -37,24 +141,79 @@ func (m *Context) Recover() {
 		return
 	}
 	if r := recover(); r != nil {
-		u.Errorf("context recover: %v", r)
+		logging.Errorf("context recover: %v", r)
+		m.mu.Lock()
 		m.errRecover = r
+		m.mu.Unlock()
 	}
 }
 
+// Err returns an error if any task panic'd and was recovered
+func (m *Context) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.errRecover == nil {
+		return nil
+	}
+	if err, ok := m.errRecover.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", m.errRecover)
+}
+
 // SqlJob is dag of tasks for sql execution
 type SqlJob struct {
 	Tasks Tasks
 	Stmt  expr.SqlStatement
 	Conf  *datasource.RuntimeConfig
+	// Timeout if > 0 aborts a Run taking longer, returning ErrJobTimeout,
+	//  the partial results of a ResultBuffer are discarded
+	Timeout time.Duration
+	ctx     *Context
 }
 
 func (m *SqlJob) Setup() error {
 	return SetupTasks(m.Tasks)
 }
 
-func (m *SqlJob) Run() error {
-	return RunJob(m.Conf, m.Tasks)
+func (m *SqlJob) Run(ctx context.Context) error {
+	runCtx := ctx
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+	m.ctx = NewContext(m.Conf)
+	m.ctx.traceCtx = ctx
+	err := runTasks(runCtx, m.ctx, m.Tasks)
+	if runCtx.Err() != nil {
+		// cancelled or timed out, tear down the source connections
+		if closeErr := m.Close(); closeErr != nil {
+			logging.Warnf("error closing cancelled job: %v", closeErr)
+		}
+	}
+	if err != nil && runCtx.Err() != nil {
+		// the rows buffered before the cancel are not a result
+		for _, task := range m.Tasks {
+			if rb, ok := task.(*ResultBuffer); ok {
+				rb.discard()
+			}
+		}
+	}
+	if err != nil && ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+		// our own timeout, not the callers deadline
+		return ErrJobTimeout
+	}
+	return err
+}
+
+// RowErrors are the errors of the rows skipped by the last Run, in strict
+//  mode the first row error also fails the Run
+func (m *SqlJob) RowErrors() []*RowError {
+	if m.ctx == nil {
+		return nil
+	}
+	return m.ctx.RowErrors()
 }
 
 func (m *SqlJob) Close() error {
@@ -78,26 +237,47 @@ func (m *SqlJob) DrainChan() MessageChan {
 // Create Job made up of sub-tasks in DAG that is the
 //  plan for execution of this query/job
 func BuildSqlJob(conf *datasource.RuntimeConfig, connInfo, sqlText string) (*SqlJob, error) {
+	return BuildSqlJobContext(context.Background(), conf, connInfo, sqlText)
+}
 
+// BuildSqlJobContext is BuildSqlJob with the "parse" and "plan" spans of
+//  the conf Tracer children of the span (if any) of @ctx
+func BuildSqlJobContext(ctx context.Context, conf *datasource.RuntimeConfig, connInfo, sqlText string) (*SqlJob, error) {
+
+	tracer := conf.Tracing()
+	_, span := tracer.StartSpan(ctx, "parse")
+	span.SetAttribute("sql", sqlText)
 	stmt, err := expr.ParseSqlVm(sqlText)
+	endSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
 
+	planCtx, span := tracer.StartSpan(ctx, "plan")
 	builder := NewJobBuilder(conf, connInfo)
+	builder.traceCtx = planCtx
 	ex, err := stmt.Accept(builder)
+	if err == nil && ex == nil {
+		err = fmt.Errorf("No job runner? %v", sqlText)
+	}
+	endSpan(span, err)
 
 	if err != nil {
 		return nil, err
 	}
-	if ex == nil {
-		return nil, fmt.Errorf("No job runner? %v", sqlText)
-	}
 	tasks, ok := ex.(Tasks)
 	if !ok {
 		return nil, fmt.Errorf("expected tasks but got: %T", ex)
 	}
-	return &SqlJob{tasks, stmt, conf}, nil
+	return &SqlJob{Tasks: tasks, Stmt: stmt, Conf: conf}, nil
+}
+
+// end the span, as failed if @err
+func endSpan(span datasource.Span, err error) {
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End()
 }
 
 func SetupTasks(tasks Tasks) error {
@@ -108,36 +288,99 @@ func SetupTasks(tasks Tasks) error {
 	}
 
 	// for i, task := range tasks {
-	// 	u.Infof("set message in: %v %T  in:%p out:%p", i, task, task.MessageIn(), task.MessageOut())
+	// 	logging.Infof("set message in: %v %T  in:%p out:%p", i, task, task.MessageIn(), task.MessageOut())
 	// }
 
 	return nil
 }
 
 // Run a Sql Job, by running to completion each task
+//
+//   Each task runs on exactly one goroutine from a pool (a streaming
+//   pipeline needs every stage running), so goroutines are bounded by the
+//   plan size not by the data, and the plan may not need more than the
+//   RuntimeConfig MaxWorkers.  The first task error signals all other
+//   tasks to shutdown and is returned.
 func RunJob(conf *datasource.RuntimeConfig, tasks Tasks) error {
+	return RunJobContext(context.Background(), conf, tasks)
+}
 
-	u.Debugf("in RunJob exec %v Recover?%v", len(tasks), conf.DisableRecover)
-	ctx := new(Context)
-	ctx.DisableRecover = conf.DisableRecover
+// Run a Sql Job until completion or until @ctx is cancelled, in which case
+//   all tasks are signaled to stop, their channels drained, and ctx.Err()
+//   is returned.
+func RunJobContext(runCtx context.Context, conf *datasource.RuntimeConfig, tasks Tasks) error {
+	return runTasks(runCtx, NewContext(conf), tasks)
+}
+
+func runTasks(runCtx context.Context, ctx *Context, tasks Tasks) error {
+
+	logging.Debugf("in RunJob exec %v Recover?%v", len(tasks), ctx.DisableRecover)
+	if ctx.runCtx == nil {
+		ctx.runCtx = runCtx
+	}
+	if err := ctx.reserveWorkers(len(tasks)); err != nil {
+		return err
+	}
+	defer ctx.releaseWorkers(len(tasks))
+
+	pool := NewWorkerPool(len(tasks))
 
-	var wg sync.WaitGroup
+	// on first error, or cancel, tell every task to stop
+	done := make(chan bool)
+	go func() {
+		select {
+		case <-runCtx.Done():
+			pool.Stop(runCtx.Err())
+		case <-pool.Quit():
+		case <-done:
+			return
+		}
+		for _, task := range tasks {
+			select {
+			case task.SigChan() <- true:
+			default:
+			}
+		}
+	}()
 
 	// start tasks in reverse order, so that by time
 	// source starts up all downstreams have started
 	for i := len(tasks) - 1; i >= 0; i-- {
-		wg.Add(1)
-		go func(taskId int) {
-			tasks[taskId].Run(ctx)
-			//u.Warnf("exiting taskId: %v %T", taskId, tasks[taskId])
-			wg.Done()
-		}(i)
+		task := tasks[i]
+		pool.Go(func() error {
+			span := ctx.StartSpan(strings.ToLower(task.Type()))
+			err := task.Run(ctx)
+			endSpan(span, err)
+			return err
+		})
 	}
 
-	wg.Wait()
-	u.Infof("RunJob(tasks) is completing")
+	err := pool.Wait()
+	close(done)
 
-	return nil
+	logging.Infof("RunJob(tasks) is completing")
+	if err != nil {
+		// stopped early, drain anything left buffered between tasks
+		for _, task := range tasks {
+			drainChan(task.MessageOut())
+		}
+		return err
+	}
+	return ctx.Err()
+}
+
+// non-blocking drain of any buffered messages
+func drainChan(ch MessageChan) {
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
 }
 
 // Create a multiple error type
@@ -1,13 +1,27 @@
 package exec
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/datasource/mockcsv"
+	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/expr/builtins"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
 	"github.com/bmizerany/assert"
 )
 
@@ -28,11 +42,64 @@ func init() {
 hT2impsOPUREcVPc,"bob@email.com","swimming","2009-12-11T19:53:31.547Z",12
 hT2impsabc345c,"not_an_email","swimming","2009-12-11T19:53:31.547Z",12`
 
+	mockcsv.MockData["names"] = `name,ct
+b,1
+B,2
+a,3
+C,4`
+
+	mockcsv.MockData["user_interests"] = `user_id,interests,item_count
+9Ip1aKbeZe2njCDM,fishing,82
+hT2impsOPUREcVPc,swimming,12
+hT2impsabc345c,swimming,12
+hT2impsxyz,swimming,5`
+
 	mockcsv.MockData["orders"] = `user_id,item_id,price,order_date,item_count
 9Ip1aKbeZe2njCDM,1,22.50,"2012-10-24T17:29:39.738Z",82
 9Ip1aKbeZe2njCDM,1,22.50,"2012-10-24T17:29:39.738Z",82
 `
 
+	mockcsv.MockData["seq"] = `n
+1
+2
+3
+4
+5
+6`
+
+	mockcsv.MockData["scores"] = `g,name,score
+a,w,3
+b,x,7
+a,y,5
+b,z,2
+a,v,5
+b,u,9`
+
+}
+
+// runs sqlText to completion and returns its result messages
+func runSqlMsgs(t *testing.T, conf *datasource.RuntimeConfig, sqlText string) []datasource.Message {
+	job, err := BuildSqlJob(conf, "mockcsv", sqlText)
+	assert.Tf(t, err == nil, "%s  no error %v", sqlText, err)
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(job.RowErrors()) == 0, "no row errors %v", job.RowErrors())
+	assert.T(t, job.Close() == nil)
+	return msgs
+}
+
+// runs sqlText and collects the string value of col from each result row
+func runSqlCollect(t *testing.T, conf *datasource.RuntimeConfig, sqlText, col string) []string {
+	msgs := runSqlMsgs(t, conf, sqlText)
+	vals := make([]string, len(msgs))
+	for i, msg := range msgs {
+		v, _ := msg.Body().(expr.ContextReader).Get(col)
+		vals[i] = v.ToString()
+	}
+	return vals
 }
 
 func TestWhere(t *testing.T) {
@@ -53,38 +120,2292 @@ func TestWhere(t *testing.T) {
 
 	err = job.Setup()
 	assert.T(t, err == nil)
-	err = job.Run()
+	err = job.Run(context.Background())
 	time.Sleep(time.Millisecond * 10)
 	assert.Tf(t, err == nil, "no error %v", err)
 	assert.Tf(t, len(msgs) == 1, "should have filtered out 2 messages %v", len(msgs))
 
 }
 
-func testSubselect(t *testing.T) {
+func TestSortCollation(t *testing.T) {
+
+	sortedNames := func(coll value.Collation, sqlText string) []string {
+		conf := *rtConf
+		conf.Collation = coll
+		return runSqlCollect(t, &conf, sqlText, "name")
+	}
+
+	sqlText := `select name FROM names ORDER BY name`
+	assert.Equal(t, []string{"B", "C", "a", "b"}, sortedNames(nil, sqlText))
+	assert.Equal(t, []string{"a", "b", "B", "C"}, sortedNames(value.CollationCaseInsensitive, sqlText))
+	assert.Equal(t, []string{"C", "b", "B", "a"},
+		sortedNames(value.CollationCaseInsensitive, `select name FROM names ORDER BY name DESC`))
+}
+
+func TestWhereCollation(t *testing.T) {
+
+	matchedNames := func(coll value.Collation, sqlText string) []string {
+		conf := *rtConf
+		conf.Collation = coll
+		return runSqlCollect(t, &conf, sqlText, "name")
+	}
+
+	sqlText := `select name FROM names WHERE name = "b" ORDER BY name`
+	assert.Equal(t, []string{"b"}, matchedNames(nil, sqlText))
+	assert.Equal(t, []string{"b", "B"}, matchedNames(value.CollationCaseInsensitive, sqlText))
+
+	sqlText = `select name FROM names WHERE name LIKE "c%" ORDER BY name`
+	assert.Equal(t, []string{}, matchedNames(nil, sqlText))
+	assert.Equal(t, []string{"C"}, matchedNames(value.CollationCaseInsensitive, sqlText))
+	assert.Equal(t, []string{"C"}, matchedNames(nil, `select name FROM names WHERE name ILIKE "c%"`))
+}
+
+func TestSelectNoFrom(t *testing.T) {
+
+	selectOne := func(sqlText string) value.Value {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		assert.Tf(t, len(msgs) == 1, "%s should have 1 row: %v", sqlText, len(msgs))
+
+		row := msgs[0].Body().(expr.ContextReader).Row()
+		assert.Tf(t, len(row) == 1, "%s should have 1 column: %v", sqlText, row)
+		for _, v := range row {
+			return v
+		}
+		return nil
+	}
+
+	v := selectOne(`SELECT 1+1`)
+	assert.Tf(t, v.Value() == int64(2), "1+1 = 2: %#v", v)
+	v = selectOne(`SELECT 1 + 1 AS two`)
+	assert.Tf(t, v.Value() == int64(2), "1+1 = 2: %#v", v)
+	v = selectOne(`SELECT now()`)
+	tv, ok := v.(value.TimeValue)
+	assert.Tf(t, ok && time.Since(tv.Val()) < time.Minute, "now(): %#v", v)
+	v = selectOne(`SELECT upper('x')`)
+	assert.Tf(t, v.ToString() == "X", "upper('x'): %#v", v)
+}
+
+func TestWindowMovingSum(t *testing.T) {
+
+	sqlText := `SELECT n, sum(n) OVER (ROWS 2 PRECEDING) AS moving,
+			avg(n) OVER (ROWS BETWEEN 2 PRECEDING AND CURRENT ROW) AS mavg,
+			count(*) OVER (ROWS 2 PRECEDING) AS ct,
+			sum(n) OVER () AS running
+		FROM seq`
+	job, err := BuildSqlJob(rtConf, "mockcsv", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 6, "should have 6 rows: %v", len(msgs))
+
+	// 3 row moving sum of 1..6
+	moving := []float64{1, 3, 6, 9, 12, 15}
+	running := []float64{1, 3, 6, 10, 15, 21}
+	for i, msg := range msgs {
+		row := msg.Body().(expr.ContextReader).Row()
+		assert.Tf(t, row["moving"].Value() == moving[i], "row %d moving sum want %v got %v", i, moving[i], row["moving"])
+		cnt := float64(i + 1)
+		if cnt > 3 {
+			cnt = 3
+		}
+		assert.Tf(t, row["mavg"].Value() == moving[i]/cnt, "row %d moving avg got %v", i, row["mavg"])
+		assert.Tf(t, row["ct"].Value() == int64(cnt), "row %d count got %v", i, row["ct"])
+		assert.Tf(t, row["running"].Value() == running[i], "row %d running sum got %v", i, row["running"])
+	}
+
+	_, err = BuildSqlJob(rtConf, "mockcsv", `SELECT upper(n) OVER (ROWS 2 PRECEDING) FROM seq`)
+	assert.Tf(t, err != nil, "upper() is not a window function")
+}
+
+func TestWindowRank(t *testing.T) {
+
+	sqlText := `SELECT name, row_number() OVER (PARTITION BY g ORDER BY score DESC) AS rn,
+			rank() OVER (PARTITION BY g ORDER BY score DESC) AS rnk,
+			count(*) OVER (PARTITION BY g) AS ct
+		FROM scores`
+	job, err := BuildSqlJob(rtConf, "mockcsv", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 6, "should have 6 rows: %v", len(msgs))
+
+	// y and v tie on score 5 in partition a, rank 1 both, w is rank 3,
+	//  the count is running over the partition in the order rows arrived
+	expected := map[string][]int64{
+		"w": {3, 3, 1}, "y": {1, 1, 2}, "v": {2, 1, 3},
+		"u": {1, 1, 3}, "x": {2, 2, 1}, "z": {3, 3, 2},
+	}
+	for i, msg := range msgs {
+		row := msg.Body().(expr.ContextReader).Row()
+		want := expected[row["name"].ToString()]
+		assert.Tf(t, want != nil, "row %d unexpected name: %v", i, row["name"])
+		assert.Tf(t, row["rn"].Value() == want[0], "%v row_number want %v got %v", row["name"], want[0], row["rn"])
+		assert.Tf(t, row["rnk"].Value() == want[1], "%v rank want %v got %v", row["name"], want[1], row["rnk"])
+		assert.Tf(t, row["ct"].Value() == want[2], "%v running count in partition want %v got %v", row["name"], want[2], row["ct"])
+	}
+
+	// without an ORDER BY every row is a peer, a frame does not cap the number
+	job, err = BuildSqlJob(rtConf, "mockcsv", `SELECT name, row_number() OVER () AS rn,
+			rank() OVER () AS rnk, row_number() OVER (ROWS 1 PRECEDING) AS rn2
+		FROM scores`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs = msgs[:0]
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	assert.T(t, job.Run(context.Background()) == nil)
+	assert.Tf(t, len(msgs) == 6, "should have 6 rows: %v", len(msgs))
+	for i, msg := range msgs {
+		row := msg.Body().(expr.ContextReader).Row()
+		assert.Tf(t, row["rn"] == value.NewIntValue(int64(i+1)), "row %d row_number got %#v", i, row["rn"])
+		assert.Tf(t, row["rn2"] == value.NewIntValue(int64(i+1)), "row %d row_number got %#v", i, row["rn2"])
+		assert.Tf(t, row["rnk"] == value.NewIntValue(1), "row %d rank got %#v", i, row["rnk"])
+	}
+
+	// the window ORDER BY compares with the collation, as ORDER BY does
+	conf := *rtConf
+	conf.Collation = value.CollationCaseInsensitive
+	job, err = BuildSqlJob(&conf, "mockcsv", `SELECT name, rank() OVER (ORDER BY name) AS rnk FROM names`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs = msgs[:0]
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	assert.T(t, job.Run(context.Background()) == nil)
+	ranks := map[string]int64{"a": 1, "b": 2, "B": 2, "C": 4}
+	for _, msg := range msgs {
+		row := msg.Body().(expr.ContextReader).Row()
+		want := ranks[row["name"].ToString()]
+		assert.Tf(t, row["rnk"].Value() == want, "%v rank want %v got %v", row["name"], want, row["rnk"])
+	}
+
+	_, err = BuildSqlJob(rtConf, "mockcsv", `SELECT rank(score) OVER (ORDER BY score) FROM scores`)
+	assert.Tf(t, err != nil, "rank() takes no arguments")
+}
+
+func TestRowToJson(t *testing.T) {
+
+	sqlText := `SELECT name, row_to_json(*) AS doc, to_map() AS m FROM names WHERE name = "B"`
+	job, err := BuildSqlJob(rtConf, "mockcsv", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 1, "should have 1 row: %v", len(msgs))
+
+	row := msgs[0].Body().(expr.ContextReader)
+	doc, ok := row.Get("doc")
+	assert.Tf(t, ok && doc.Type() == value.MapValueType, "doc is a map: %#v", doc)
+	m, _ := row.Get("m")
+	assert.Tf(t, m.ToString() == doc.ToString(), "to_map == row_to_json: %v", m)
+
+	by, err := json.Marshal(doc)
+	assert.Tf(t, err == nil, "no error %v", err)
+	cols := make(map[string]interface{})
+	assert.Tf(t, json.Unmarshal(by, &cols) == nil, "json object: %s", by)
+	for _, col := range []string{"name", "ct"} {
+		_, ok := cols[col]
+		assert.Tf(t, ok, "json should have %q: %s", col, by)
+	}
+	assert.Tf(t, len(cols) == 2, "only the source columns: %s", by)
+	assert.Tf(t, cols["name"] == "B" && cols["ct"] == "2", "values: %s", by)
+	assert.Tf(t, doc.ToString() == string(by), "ToString is json: %s", doc.ToString())
+}
+
+func TestParallelSource(t *testing.T) {
+
+	shards := []string{
+		"user_id,item_count\n1,10\n2,20\n3,30",
+		"user_id,item_count\n4,40\n5,50",
+		"user_id,item_count\n6,60\n7,70\n8,80\n9,90",
+	}
+	sources := make([]datasource.Scanner, len(shards))
+	for i, data := range shards {
+		csvSource, err := datasource.NewCsvSource(strings.NewReader(data), make(<-chan bool, 1))
+		assert.Tf(t, err == nil, "no error %v", err)
+		sources[i] = csvSource
+	}
+
+	msgs := make([]datasource.Message, 0)
+	tasks := make(Tasks, 0)
+	tasks.Add(NewParallelSource(&expr.SqlSource{Name: "users"}, sources, 2))
+	tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, SetupTasks(tasks) == nil)
+	err := RunJob(rtConf, tasks)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		row := msg.Body().(*datasource.ContextUrlValues)
+		v, _ := row.Get("user_id")
+		ids = append(ids, v.ToString())
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, ids)
+}
+
+func TestRunJobBoundedGoroutines(t *testing.T) {
+
+	var buf bytes.Buffer
+	buf.WriteString("user_id,item_count\n")
+	for i := 0; i < 5000; i++ {
+		buf.WriteString(fmt.Sprintf("%d,%d\n", i, i%100))
+	}
+	csvSource, err := datasource.NewCsvSource(strings.NewReader(buf.String()), make(<-chan bool, 1))
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	where, err := expr.ParseExpression(`item_count < 1000`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	// a deep plan, source -> 50 where's -> result
+	msgs := make([]datasource.Message, 0)
+	tasks := make(Tasks, 0)
+	tasks.Add(NewSource(&expr.SqlSource{Name: "users"}, csvSource))
+	for i := 0; i < 50; i++ {
+		tasks.Add(NewWhere(where.Root))
+	}
+	tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, SetupTasks(tasks) == nil)
+
+	base := runtime.NumGoroutine()
+	maxGoroutines := 0
+	stop := make(chan bool)
+	sampled := make(chan bool)
+	go func() {
+		defer close(sampled)
+		for {
+			if n := runtime.NumGoroutine(); n > maxGoroutines {
+				maxGoroutines = n
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	err = RunJob(rtConf, tasks)
+	close(stop)
+	<-sampled
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 5000, "should have all rows %v", len(msgs))
+
+	// one goroutine per task, plus the sampler and job watcher
+	delta := maxGoroutines - base
+	assert.Tf(t, delta <= len(tasks)+2, "goroutines should be bounded by plan size: %d > %d", delta, len(tasks)+2)
+	time.Sleep(time.Millisecond * 10)
+	assert.Tf(t, runtime.NumGoroutine() <= base, "goroutines leaked: %d > %d", runtime.NumGoroutine(), base)
+}
+
+func TestRunJobErrorPropagation(t *testing.T) {
+
+	csvSource, err := datasource.NewCsvSource(strings.NewReader("user_id\n1\n2"), make(<-chan bool, 1))
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	msgs := make([]datasource.Message, 0)
+	tasks := make(Tasks, 0)
+	tasks.Add(NewSource(&expr.SqlSource{Name: "users"}, csvSource))
+	// a task with no handler errors out of Run()
+	tasks.Add(NewTaskBase("NoHandler"))
+	tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, SetupTasks(tasks) == nil)
+
+	err = RunJob(rtConf, tasks)
+	assert.Tf(t, err != nil, "should have propagated task error")
+}
+
+// an endless source, recording if it was closed
+type endlessSource struct {
+	mu     sync.Mutex
+	id     uint64
+	closed bool
+}
+
+func (m *endlessSource) Tables() []string                                    { return []string{"endless"} }
+func (m *endlessSource) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *endlessSource) CreateIterator(filter expr.Node) datasource.Iterator { return m }
+func (m *endlessSource) MesgChan(filter expr.Node) <-chan datasource.Message { return nil }
+func (m *endlessSource) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+func (m *endlessSource) Closed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+func (m *endlessSource) Next() datasource.Message {
+	m.id++
+	return datasource.NewUrlValuesMsg(m.id, datasource.NewContextUrlValues(url.Values{"id": {"1"}}))
+}
+
+func TestJobCancel(t *testing.T) {
+
+	base := runtime.NumGoroutine()
+
+	sources := []*endlessSource{{}, {}, {}}
+	scanners := make([]datasource.Scanner, len(sources))
+	for i, source := range sources {
+		scanners[i] = source
+	}
+	where, err := expr.ParseExpression(`id == 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	ct := 0
+	resultCounter := NewTaskBase("Counter")
+	resultCounter.Handler = func(ctx *Context, msg datasource.Message) bool {
+		ct++
+		return true
+	}
+	job := &SqlJob{Conf: rtConf}
+	job.Tasks.Add(NewParallelSource(&expr.SqlSource{Name: "endless"}, scanners, 2))
+	job.Tasks.Add(NewWhere(where.Root))
+	job.Tasks.Add(resultCounter)
+	assert.T(t, job.Setup() == nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+	}()
+	err = job.Run(ctx)
+	assert.Tf(t, err == context.Canceled, "should be cancelled %v", err)
+	assert.Tf(t, ct > 0, "should have scanned some rows %v", ct)
+	for i, source := range sources {
+		assert.Tf(t, source.Closed(), "source %d should be closed", i)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+	assert.Tf(t, runtime.NumGoroutine() <= base, "goroutines leaked: %d > %d", runtime.NumGoroutine(), base)
+}
+
+func TestJobTimeout(t *testing.T) {
+
+	source := &endlessSource{}
+	msgs := make([]datasource.Message, 0)
+	job := &SqlJob{Conf: rtConf, Timeout: time.Millisecond * 20}
+	job.Tasks.Add(NewSource(&expr.SqlSource{Name: "endless"}, source))
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+
+	start := time.Now()
+	err := job.Run(context.Background())
+	assert.Tf(t, err == ErrJobTimeout, "should time out %v", err)
+	assert.Tf(t, time.Since(start) < time.Second, "should stop promptly: %v", time.Since(start))
+	assert.Tf(t, source.Closed(), "source should be closed")
+	for _, task := range job.Tasks {
+		_, open := <-task.MessageOut()
+		assert.Tf(t, !open, "%v output should be drained and closed", task.Type())
+	}
+	assert.Tf(t, len(msgs) == 0, "partial results should be discarded: %d", len(msgs))
+
+	// the callers own cancel is not a timeout, the rows already in msgs are kept
+	msgs = append(msgs, &datasource.SqlDriverMessageMap{Id: 1})
+	job = &SqlJob{Conf: rtConf, Timeout: time.Minute}
+	job.Tasks.Add(NewSource(&expr.SqlSource{Name: "endless"}, &endlessSource{}))
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err = job.Run(ctx)
+	assert.Tf(t, err == context.DeadlineExceeded, "callers deadline %v", err)
+	assert.Tf(t, len(msgs) == 1 && msgs[0].Key() == 1, "partial results should be discarded: %d", len(msgs))
+}
+
+func TestProjectionStreams(t *testing.T) {
+
+	stmt, err := expr.ParseSql(`SELECT id FROM endless`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	// the source never ends, so rows must be projected as they arrive
+	first := make(chan datasource.Message, 1)
+	reader := NewTaskBase("Reader")
+	reader.Handler = func(ctx *Context, msg datasource.Message) bool {
+		select {
+		case first <- msg:
+		default:
+		}
+		return true
+	}
+	job := &SqlJob{Conf: rtConf}
+	job.Tasks.Add(NewSource(&expr.SqlSource{Name: "endless"}, &endlessSource{}))
+	job.Tasks.Add(NewProjection(stmt.(*expr.SqlSelect)))
+	job.Tasks.Add(reader)
+	assert.T(t, job.Setup() == nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- job.Run(ctx)
+	}()
+
+	select {
+	case msg := <-first:
+		row, ok := msg.Body().(expr.ContextReader)
+		assert.Tf(t, ok, "projected row: %T", msg.Body())
+		v, _ := row.Get("id")
+		assert.Tf(t, v != nil && v.ToString() == "1", "id: %v", v)
+	case <-time.After(time.Second):
+		t.Errorf("projection did not emit before input ended")
+	}
+	cancel()
+	err = <-done
+	assert.Tf(t, err == context.Canceled, "should be cancelled %v", err)
+}
+
+// an in-memory source of rows
+type rowsSource struct {
+	rows   []map[string]value.Value
+	cursor int
+}
+
+func (m *rowsSource) Tables() []string { return nil }
+func (m *rowsSource) Close() error     { return nil }
+func (m *rowsSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return &rowsSource{rows: m.rows}, nil
+}
+func (m *rowsSource) CreateIterator(filter expr.Node) datasource.Iterator { return m }
+func (m *rowsSource) MesgChan(filter expr.Node) <-chan datasource.Message { return nil }
+func (m *rowsSource) Next() datasource.Message {
+	if m.cursor >= len(m.rows) {
+		return nil
+	}
+	m.cursor++
+	return datasource.NewContextSimpleData(m.rows[m.cursor-1])
+}
+
+func TestCountDistinct(t *testing.T) {
+
+	stmt, err := expr.ParseSql(`SELECT count(*) AS rows_ct, count(user_id) AS ct,
+		count(DISTINCT user_id) AS distinct_ct FROM t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*expr.SqlSelect)
+	fn := sel.Columns[2].Expr.(*expr.FuncNode)
+	assert.Tf(t, fn.Distinct && !sel.Columns[1].Expr.(*expr.FuncNode).Distinct, "distinct flag: %v", sel.Columns)
+	assert.Tf(t, fn.String() == "count(DISTINCT user_id)", "got %v", fn.String())
+
+	// duplicates and NULLs
+	source := &rowsSource{}
+	for _, id := range []value.Value{
+		value.NewStringValue("a"), value.NewStringValue("a"), value.NewStringValue("b"),
+		value.NewNilValue(), value.NewNilValue(),
+	} {
+		source.rows = append(source.rows, map[string]value.Value{"user_id": id})
+	}
+
+	msgs := make([]datasource.Message, 0)
+	tasks := make(Tasks, 0)
+	tasks.Add(NewSource(&expr.SqlSource{Name: "t"}, source))
+	tasks.Add(NewGroupBy(sel))
+	tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, SetupTasks(tasks) == nil)
+	err = RunJob(rtConf, tasks)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 1, "1 row: %v", len(msgs))
+
+	row := msgs[0].Body().(expr.ContextReader)
+	for col, expected := range map[string]string{"rows_ct": "5", "ct": "3", "distinct_ct": "2"} {
+		v, _ := row.Get(col)
+		assert.Tf(t, v != nil && v.ToString() == expected, "%s want %s got %v", col, expected, v)
+	}
+}
+
+func TestDerivedTable(t *testing.T) {
+
+	// inner select filters and aggregates, outer filters on the aggregate
+	sqlText := `
+		SELECT t.interests, t.ct
+		FROM (
+			SELECT interests, count(*) AS ct
+			FROM user_interests
+			WHERE item_count > 10
+			GROUP BY interests
+		) AS t
+		WHERE t.ct > 1
+	`
+	job, err := BuildSqlJob(rtConf, "mockcsv", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	err = job.Setup()
+	assert.T(t, err == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 1, "should have 1 group with ct > 1: %v", len(msgs))
+
+	row := msgs[0].Body().(expr.ContextReader)
+	interests, _ := row.Get("t.interests")
+	assert.Tf(t, interests.ToString() == "swimming", "interests: %v", interests)
+	ct, _ := row.Get("t.ct")
+	assert.Tf(t, ct.ToString() == "2", "ct: %v", ct)
+
+	// un-aliased columns resolve as well
+	job, err = BuildSqlJob(rtConf, "mockcsv",
+		`SELECT ct FROM (SELECT count(*) AS ct FROM user_interests WHERE item_count < 50) AS t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs = msgs[:0]
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 1, "aggregate without group by is 1 row: %v", len(msgs))
+	ct, _ = msgs[0].Body().(expr.ContextReader).Get("ct")
+	assert.Tf(t, ct.ToString() == "3", "ct: %v", ct)
+}
+
+func TestCommonTableExpressions(t *testing.T) {
 
-	// sub-select not implemented in lexer yet
+	runRows := func(sqlText string) []expr.ContextReader {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		rows := make([]expr.ContextReader, len(msgs))
+		for i, msg := range msgs {
+			rows[i] = msg.Body().(expr.ContextReader)
+		}
+		return rows
+	}
+
+	rows := runRows(`
+		WITH swimmers AS (
+			SELECT user_id, item_count FROM user_interests WHERE interests = "swimming"
+		)
+		SELECT user_id FROM swimmers WHERE item_count > 10
+	`)
+	assert.Tf(t, len(rows) == 2, "2 swimmers with item_count > 10: %v", len(rows))
+
+	// the second cte references the first
+	rows = runRows(`
+		WITH big AS (
+			SELECT user_id, interests FROM user_interests WHERE item_count > 10
+		), counts AS (
+			SELECT interests, count(*) AS ct FROM big GROUP BY interests
+		)
+		SELECT c.interests, c.ct FROM counts AS c ORDER BY c.ct DESC
+	`)
+	assert.Tf(t, len(rows) == 2, "2 interests: %v", len(rows))
+	interests, _ := rows[0].Get("c.interests")
+	ct, _ := rows[0].Get("c.ct")
+	assert.Tf(t, interests.ToString() == "swimming" && ct.ToString() == "2", "got %v %v", interests, ct)
+	interests, _ = rows[1].Get("c.interests")
+	ct, _ = rows[1].Get("c.ct")
+	assert.Tf(t, interests.ToString() == "fishing" && ct.ToString() == "1", "got %v %v", interests, ct)
+
+	_, err := BuildSqlJob(rtConf, "mockcsv", `WITH r AS (SELECT user_id FROM r) SELECT user_id FROM r`)
+	assert.Tf(t, err != nil, "recursive cte should error")
+}
+
+func TestSubselect(t *testing.T) {
+
+	// both of the orders are aaron's, he matches once
 	sqlText := `
 		select 
 	        user_id, email
-	    FROM mockcsv.users
+	    FROM users
 	    WHERE user_id in 
-	    	(select user_id from mockcsv.orders)
+	    	(select user_id from orders)
     `
-	job, err := BuildSqlJob(rtConf, "mockcsv", sqlText)
+	emails := runSqlCollect(t, rtConf, sqlText, "email")
+	assert.Tf(t, len(emails) == 1, "should have filtered out 2 messages: %v", emails)
+	assert.Equal(t, []string{"aaron@email.com"}, emails)
+}
+
+func TestResultSchema(t *testing.T) {
+	sqlText := `SELECT user_id, item_count * 2 AS double_ct, count(*) AS ct,
+		toint(item_count) AS ict, item_count > 10 AS big, "x" AS lit
+		FROM users`
+	stmt, err := expr.ParseSql(sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+	builder := NewJobBuilder(rtConf, "")
+	schema, err := builder.ResultSchema(stmt.(*expr.SqlSelect))
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, schema.Name == "users", "has name %v", schema.Name)
+
+	want := []struct {
+		name string
+		vt   value.ValueType
+	}{
+		{"user_id", value.UnknownType},
+		{"double_ct", value.NumberType},
+		{"ct", value.IntType},
+		{"ict", value.IntType},
+		{"big", value.BoolType},
+		{"lit", value.StringType},
+	}
+	assert.Tf(t, len(schema.Fields) == len(want), "want %d fields got %d", len(want), len(schema.Fields))
+	for i, w := range want {
+		f := schema.Fields[i]
+		assert.Tf(t, f.Name == w.name && f.Type == w.vt, "field %d want %s %s got %s %s", i, w.name, w.vt, f.Name, f.Type)
+	}
+	f, ok := schema.Field("ct")
+	assert.Tf(t, ok && f.Type == value.IntType, "field by name")
+
+	stmt, _ = expr.ParseSql(`SELECT * FROM users`)
+	_, err = builder.ResultSchema(stmt.(*expr.SqlSelect))
+	assert.Tf(t, err != nil, "select * cannot be described")
+}
+
+func TestResultSchemaFuncs(t *testing.T) {
+	sqlText := `SELECT sqrt(x) AS a, tolower(x) AS b, now() AS c, toint(x) AS d, yy(x) AS e,
+		greatest(1, 2.5) AS f, least(toint(x), toint(y)) AS g, ifnull(toint(x), 0) AS h,
+		ifnull(tolower(x), "none") AS i, ifnull(x, 0) AS j, greatest(tolower(x), 1) AS k,
+		oneof(x, y) AS l, nosuchfunc(x) AS m
+		FROM users`
+	stmt, err := expr.ParseSql(sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*expr.SqlSelect)
+
+	want := []value.ValueType{
+		value.NumberType, value.StringType, value.TimeType, value.IntType, value.IntType,
+		value.NumberType, value.IntType, value.NumberType,
+		value.StringType, value.UnknownType, value.UnknownType,
+		value.UnknownType, value.UnknownType,
+	}
+	schema, err := NewProjection(sel).Schema()
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(schema.Fields) == len(want), "want %d fields got %d", len(want), len(schema.Fields))
+	for i, vt := range want {
+		f := schema.Fields[i]
+		assert.Tf(t, f.Type == vt, "field %s want %s got %s", f.Name, vt, f.Type)
+	}
+
+	described, err := NewJobBuilder(rtConf, "").ResultSchema(sel)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, described.Fields[0].Type == value.NumberType, "same as projection: %v", described.Fields[0].Type)
+}
+
+// an in-memory table accepting inserts, with a schema
+type insertTable struct {
+	schema *datasource.Schema
+	rows   []map[string]value.Value
+}
+
+func (m *insertTable) Tables() []string                                    { return []string{m.schema.Name} }
+func (m *insertTable) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *insertTable) Close() error                                        { return nil }
+func (m *insertTable) Schema(table string) (*datasource.Schema, error)     { return m.schema, nil }
+func (m *insertTable) Insert(row map[string]value.Value) error {
+	if f, ok := m.schema.Field("id"); ok && f.Type == value.IntType {
+		// generated id
+		row["id"] = value.NewIntValue(int64(len(m.rows) + 1))
+	}
+	m.rows = append(m.rows, row)
+	return nil
+}
+func (m *insertTable) Put(key uint64, row map[string]value.Value) error {
+	m.rows[key] = row
+	return nil
+}
+func (m *insertTable) CreateIterator(filter expr.Node) datasource.Iterator {
+	return &tableIter{table: m}
+}
+func (m *insertTable) MesgChan(filter expr.Node) <-chan datasource.Message { return nil }
+func (m *insertTable) CanSeek(*expr.SqlSelect)                             {}
+func (m *insertTable) Get(key string) datasource.Message {
+	for _, row := range m.rows {
+		if id, ok := row["id"]; ok && id.ToString() == key {
+			return datasource.NewContextSimpleData(row)
+		}
+	}
+	return nil
+}
+func (m *insertTable) MultiGet(keys []string) []datasource.Message { return nil }
+
+// iterates a snapshot of the table rows, the message key is the row index
+type tableIter struct {
+	table  *insertTable
+	cursor int
+}
+
+func (m *tableIter) Next() datasource.Message {
+	if m.cursor >= len(m.table.rows) {
+		return nil
+	}
+	m.cursor++
+	return &keyedRow{datasource.NewContextSimpleData(m.table.rows[m.cursor-1]), uint64(m.cursor - 1)}
+}
+
+type keyedRow struct {
+	*datasource.ContextSimple
+	key uint64
+}
+
+func (m *keyedRow) Key() uint64 { return m.key }
+
+func TestInsertDefaults(t *testing.T) {
+
+	schema := datasource.NewSchema("insert_users")
+	schema.AddField("user_id", value.StringType).NotNull = true
+	schema.AddField("status", value.StringType).Default = value.NewStringValue("active")
+	schema.AddField("item_count", value.IntType).Default = value.NewIntValue(0)
+	schema.AddField("email", value.StringType)
+	table := &insertTable{schema: schema}
+	datasource.Register("insert_users", table)
+
+	// status omitted, item_count DEFAULT, email omitted without default is NULL
+	job, err := BuildSqlJob(rtConf, "", `INSERT INTO insert_users (user_id, item_count) VALUES ("abc", DEFAULT)`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(table.rows) == 1, "1 row inserted: %v", len(table.rows))
+	row := table.rows[0]
+	assert.Tf(t, row["user_id"].ToString() == "abc", "user_id %v", row["user_id"])
+	assert.Tf(t, row["status"].ToString() == "active", "default status %v", row["status"])
+	assert.Tf(t, row["item_count"].ToString() == "0", "default item_count %v", row["item_count"])
+	_, hasEmail := row["email"]
+	assert.Tf(t, !hasEmail, "no default for email %v", row["email"])
+
+	// NOT NULL column omitted, or given as DEFAULT, without a default
+	_, err = BuildSqlJob(rtConf, "", `INSERT INTO insert_users (email) VALUES ("bob@email.com")`)
+	assert.Tf(t, err != nil, "NOT NULL user_id omitted should error")
+	_, err = BuildSqlJob(rtConf, "", `INSERT INTO insert_users (user_id, email) VALUES (DEFAULT, "bob@email.com")`)
+	assert.Tf(t, err != nil, "NOT NULL user_id DEFAULT should error")
+	_, err = BuildSqlJob(rtConf, "", `INSERT INTO insert_users (user_id, nickname) VALUES ("abc", "bob")`)
+	assert.Tf(t, err != nil, "column not in schema should error")
+	assert.Tf(t, len(table.rows) == 1, "nothing inserted: %v", len(table.rows))
+}
+
+func TestReturning(t *testing.T) {
+
+	schema := datasource.NewSchema("returning_users")
+	schema.AddField("id", value.IntType)
+	schema.AddField("name", value.StringType)
+	schema.AddField("status", value.StringType).Default = value.NewStringValue("new")
+	table := &insertTable{schema: schema}
+	datasource.Register("returning_users", table)
+
+	runReturning := func(sqlText string) []datasource.Message {
+		msgs := make([]datasource.Message, 0)
+		job, err := BuildSqlJob(rtConf, "", sqlText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		job.Tasks.Add(NewResultBuffer(&msgs))
+		assert.T(t, job.Setup() == nil)
+		err = job.Run(context.Background())
+		assert.Tf(t, err == nil, "no error %v", err)
+		return msgs
+	}
+
+	// generated id and default are returned from the insert
+	msgs := runReturning(`INSERT INTO returning_users (name) VALUES ("bob"), ("jane") RETURNING id, status`)
+	assert.Tf(t, len(msgs) == 2, "2 rows returned: %v", len(msgs))
+	row := msgs[1].Body().(expr.ContextReader).Row()
+	assert.Tf(t, len(row) == 2, "only returning columns %v", row)
+	assert.Tf(t, row["id"].ToString() == "2" && row["status"].ToString() == "new", "got %v", row)
+
+	// updated values of only the rows updated
+	msgs = runReturning(`UPDATE returning_users SET status = "active", name = "robert" WHERE id == 1 RETURNING id, name, status`)
+	assert.Tf(t, len(msgs) == 1, "1 row returned: %v", len(msgs))
+	row = msgs[0].Body().(expr.ContextReader).Row()
+	assert.Tf(t, row["id"].ToString() == "1" && row["name"].ToString() == "robert", "got %v", row)
+	assert.Tf(t, row["status"].ToString() == "active", "got %v", row)
+	assert.Tf(t, table.rows[0]["name"].ToString() == "robert", "table updated %v", table.rows[0])
+	assert.Tf(t, table.rows[1]["status"].ToString() == "new", "not updated %v", table.rows[1])
+
+	// without returning, nothing is emitted
+	msgs = runReturning(`UPDATE returning_users SET status = "inactive"`)
+	assert.Tf(t, len(msgs) == 0, "no rows returned: %v", len(msgs))
+	assert.Tf(t, table.rows[1]["status"].ToString() == "inactive", "updated %v", table.rows[1])
+}
+
+func TestUpdateFrom(t *testing.T) {
+
+	schema := datasource.NewSchema("enrich_users")
+	schema.AddField("id", value.IntType)
+	schema.AddField("name", value.StringType)
+	schema.AddField("email", value.StringType)
+	table := &insertTable{schema: schema}
+	datasource.Register("enrich_users", table)
+	datasource.Register("email_lookup", &rowsSource{rows: []map[string]value.Value{
+		{"user_id": value.NewIntValue(2), "email": value.NewStringValue("jane@email.com")},
+		{"user_id": value.NewIntValue(3), "email": value.NewStringValue("nobody@email.com")},
+		{"user_id": value.NewIntValue(1), "email": value.NewStringValue("bob@email.com")},
+	}})
+
+	runUpdate := func(sqlText string) []datasource.Message {
+		msgs := make([]datasource.Message, 0)
+		job, err := BuildSqlJob(rtConf, "", sqlText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		job.Tasks.Add(NewResultBuffer(&msgs))
+		assert.T(t, job.Setup() == nil)
+		err = job.Run(context.Background())
+		assert.Tf(t, err == nil, "no error %v", err)
+		return msgs
+	}
+	runUpdate(`INSERT INTO enrich_users (name) VALUES ("bob"), ("jane"), ("sue")`)
+
+	stmt, err := expr.ParseSql(`UPDATE enrich_users SET email = l.email FROM email_lookup AS l WHERE enrich_users.id = l.user_id`)
 	assert.Tf(t, err == nil, "no error %v", err)
+	upd := stmt.(*expr.SqlUpdate)
+	assert.Tf(t, upd.Source != nil && upd.Source.Name == "email_lookup" && upd.Source.Alias == "l", "from: %v", upd.Source)
 
-	//writeCtx := NewContextSimple()
+	// sue (id 3) has no lookup with user_id 3 and the name condition
+	msgs := runUpdate(`UPDATE enrich_users SET email = l.email FROM email_lookup AS l
+		WHERE enrich_users.id = l.user_id AND name != "sue" RETURNING id, email`)
+	assert.Tf(t, len(msgs) == 2, "2 rows updated: %v", len(msgs))
+	for i, want := range []string{"bob@email.com", "jane@email.com", ""} {
+		row := table.rows[i]
+		email, ok := row["email"]
+		if want == "" {
+			assert.Tf(t, !ok || email.Nil(), "not updated: %v", row)
+			continue
+		}
+		assert.Tf(t, ok && email.ToString() == want, "row %d email want %s got %v", i, want, email)
+		assert.Tf(t, len(row) == 3, "the updated row has only its own columns: %v", row)
+	}
+	row := msgs[1].Body().(expr.ContextReader).Row()
+	assert.Tf(t, row["id"].ToString() == "2" && row["email"].ToString() == "jane@email.com", "returning: %v", row)
+
+	// unqualified names are of the updated table, no where is every row with
+	//  the first row of the source
+	runUpdate(`UPDATE enrich_users SET email = name FROM email_lookup`)
+	assert.Tf(t, table.rows[2]["email"].ToString() == "sue", "updated: %v", table.rows[2])
+
+	_, err = BuildSqlJob(rtConf, "", `UPDATE enrich_users SET email = l.email FROM not_a_source AS l`)
+	assert.Tf(t, err != nil, "no such source")
+}
+
+func TestTaskPipeline(t *testing.T) {
+
+	stmt, err := expr.ParseSql(`SELECT name, ct * 2 AS double_ct FROM t WHERE ct > 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*expr.SqlSelect)
+
+	source := &rowsSource{}
+	for i, name := range []string{"a", "b", "c"} {
+		source.rows = append(source.rows, map[string]value.Value{
+			"name": value.NewStringValue(name),
+			"ct":   value.NewIntValue(int64(i + 1)),
+		})
+	}
+
+	// each step is a Task, wired output to input
 	msgs := make([]datasource.Message, 0)
-	go func() {
-		outChan := job.DrainChan()
-		for msg := range outChan {
-			u.Infof("msg: %v", msg)
-			msgs = append(msgs, msg)
+	pipeline := []Task{
+		NewSource(sel.From[0], source),
+		NewWhere(sel.Where.Expr),
+		NewProjection(sel),
+		NewResultBuffer(&msgs),
+	}
+	tasks := make(Tasks, 0)
+	for _, task := range pipeline {
+		assert.Tf(t, task.Children() == nil, "no children %T", task)
+		tasks.Add(task.(TaskRunner))
+	}
+	assert.T(t, SetupTasks(tasks) == nil)
+	assert.T(t, pipeline[1].MessageIn() == pipeline[0].MessageOut())
+	err = RunJob(rtConf, tasks)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	assert.Tf(t, len(msgs) == 2, "filtered to 2 rows: %v", len(msgs))
+	for i, want := range []struct{ name, ct string }{{"b", "4"}, {"c", "6"}} {
+		row := msgs[i].Body().(expr.ContextReader).Row()
+		assert.Tf(t, len(row) == 2, "projected 2 columns %v", row)
+		assert.Tf(t, row["name"].ToString() == want.name && row["double_ct"].ToString() == want.ct, "got %v", row)
+	}
+	for _, task := range pipeline {
+		assert.T(t, task.Close() == nil)
+	}
+}
+
+// a source of messages, some not readable as rows
+type messagesSource struct {
+	msgs   []datasource.Message
+	cursor int
+}
+
+func (m *messagesSource) CreateIterator(filter expr.Node) datasource.Iterator { return m }
+func (m *messagesSource) MesgChan(filter expr.Node) <-chan datasource.Message { return nil }
+func (m *messagesSource) Next() datasource.Message {
+	if m.cursor >= len(m.msgs) {
+		return nil
+	}
+	m.cursor++
+	return m.msgs[m.cursor-1]
+}
+
+func TestRowErrors(t *testing.T) {
+
+	where, err := expr.ParseExpression(`ct > 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	runJob := func(conf *datasource.RuntimeConfig) (*SqlJob, []datasource.Message, error) {
+		source := &messagesSource{}
+		for i := 1; i <= 6; i++ {
+			if i%3 == 0 {
+				// malformed, not a row
+				source.msgs = append(source.msgs, &datasource.SqlDriverMessageMap{Id: uint64(i)})
+				continue
+			}
+			source.msgs = append(source.msgs, datasource.NewUrlValuesMsg(uint64(i),
+				datasource.NewContextUrlValues(url.Values{"ct": {fmt.Sprintf("%d", i)}})))
 		}
-	}()
-	err = job.Run()
-	time.Sleep(time.Millisecond * 30)
+		msgs := make([]datasource.Message, 0)
+		job := &SqlJob{Conf: conf}
+		job.Tasks.Add(NewSource(&expr.SqlSource{Name: "t"}, source))
+		job.Tasks.Add(NewWhere(where.Root))
+		job.Tasks.Add(NewResultBuffer(&msgs))
+		assert.T(t, job.Setup() == nil)
+		err := job.Run(context.Background())
+		return job, msgs, err
+	}
+
+	// partial results, malformed rows are skipped and collected
+	job, msgs, err := runJob(datasource.NewRuntimeConfig())
 	assert.Tf(t, err == nil, "no error %v", err)
-	assert.Tf(t, len(msgs) == 1, "should have filtered out 2 messages")
+	assert.Tf(t, len(msgs) == 3, "rows 2,4,5: %v", len(msgs))
+	rowErrs := job.RowErrors()
+	assert.Tf(t, len(rowErrs) == 2, "2 row errors: %v", rowErrs)
+	assert.Tf(t, rowErrs[0].Key == 3 && rowErrs[1].Key == 6, "row keys: %v", rowErrs)
+
+	// strict fails on the first malformed row
+	strict := datasource.NewRuntimeConfig()
+	strict.StrictErrors = true
+	job, msgs, err = runJob(strict)
+	rowErr, ok := err.(*RowError)
+	assert.Tf(t, ok && rowErr.Key == 3, "fails on row 3: %v", err)
+	assert.Tf(t, len(job.RowErrors()) == 1, "stopped at first error: %v", job.RowErrors())
+	assert.Tf(t, len(msgs) <= 1, "row 2 at most: %v", len(msgs))
+}
+
+func TestMaxRows(t *testing.T) {
+
+	runJob := func(builder *JobBuilder, sqlText string) (*SqlJob, []datasource.Message, error) {
+		stmt, err := expr.ParseSqlVm(sqlText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		tasks, err := stmt.Accept(builder)
+		assert.Tf(t, err == nil, "no error %v", err)
+		job := &SqlJob{Tasks: tasks.(Tasks), Stmt: stmt, Conf: builder.schema}
+		msgs := make([]datasource.Message, 0)
+		job.Tasks.Add(NewResultBuffer(&msgs))
+		assert.T(t, job.Setup() == nil)
+		return job, msgs, job.Run(context.Background())
+	}
+
+	// global cap from the runtime config, names has 4 rows
+	conf := *rtConf
+	conf.SetConnInfo("mockcsv")
+	conf.MaxRows = 2
+	job, msgs, err := runJob(NewJobBuilder(&conf, "mockcsv"), `SELECT name FROM names`)
+	assert.Tf(t, err == ErrMaxRowsExceeded, "should exceed max rows: %v", err)
+	maxRows, ok := job.Tasks[len(job.Tasks)-2].(*MaxRows)
+	assert.Tf(t, ok && maxRows.ct == 2, "emitted the cap: %#v", job.Tasks[len(job.Tasks)-2])
+	assert.Tf(t, len(msgs) <= 2, "no more than the cap: %v", len(msgs))
+
+	// per job cap, LIMIT is not an error
+	conf.MaxRows = 0
+	builder := NewJobBuilder(&conf, "mockcsv")
+	builder.MaxRows = 4
+	_, msgs, err = runJob(builder, `SELECT name FROM names`)
+	assert.Tf(t, err == nil && len(msgs) == 4, "at the cap is ok: %v %v", err, len(msgs))
+	builder.MaxRows = 3
+	_, _, err = runJob(builder, `SELECT name FROM names`)
+	assert.Tf(t, err == ErrMaxRowsExceeded, "should exceed max rows: %v", err)
+
+	// derived tables are not capped, only the output
+	builder.MaxRows = 1
+	_, msgs, err = runJob(builder, `SELECT count(*) AS ct FROM (SELECT name FROM names) AS t`)
+	assert.Tf(t, err == nil && len(msgs) == 1, "1 output row: %v %v", err, len(msgs))
+}
+
+func TestUnnest(t *testing.T) {
+
+	datasource.Register("tagged", &rowsSource{rows: []map[string]value.Value{
+		{"name": value.NewStringValue("bob"), "tags": value.NewSliceValues([]value.Value{
+			value.NewStringValue("a"), value.NewStringValue("b"), value.NewStringValue("c"),
+		})},
+		{"name": value.NewStringValue("none"), "tags": value.NewNilValue()},
+	}})
+
+	runRows := func(sqlText string) []expr.ContextReader {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		rows := make([]expr.ContextReader, len(msgs))
+		for i, msg := range msgs {
+			rows[i] = msg.Body().(expr.ContextReader)
+		}
+		return rows
+	}
+
+	// lateral, a row per element with the outer row, NULL tags no rows
+	rows := runRows(`SELECT name, t FROM tagged, unnest(tags) AS t`)
+	assert.Tf(t, len(rows) == 3, "3 rows: %v", len(rows))
+	for i, want := range []string{"a", "b", "c"} {
+		name, _ := rows[i].Get("name")
+		tag, _ := rows[i].Get("t")
+		assert.Tf(t, name.ToString() == "bob" && tag.ToString() == want, "row %d: %v %v", i, name, tag)
+	}
+
+	// the alias column is usable in where
+	rows = runRows(`SELECT name, t FROM tagged, unnest(tags) AS t WHERE t != "b"`)
+	assert.Tf(t, len(rows) == 2, "2 rows: %v", len(rows))
+
+	// not lateral
+	rows = runRows(`SELECT t FROM unnest(split("x,y,z", ",")) AS t`)
+	assert.Tf(t, len(rows) == 3, "3 rows: %v", len(rows))
+	tag, _ := rows[2].Get("t")
+	assert.Tf(t, tag.ToString() == "z", "last is z: %v", tag)
+
+	_, err := BuildSqlJob(rtConf, "", `SELECT t FROM tagged, tolower(name) AS t`)
+	assert.Tf(t, err != nil, "not a table func")
+}
+
+func TestLateral(t *testing.T) {
+
+	user := func(id, name string) map[string]value.Value {
+		return map[string]value.Value{"user_id": value.NewStringValue(id), "name": value.NewStringValue(name)}
+	}
+	order := func(id, userId string, amt int64) map[string]value.Value {
+		return map[string]value.Value{"order_id": value.NewStringValue(id),
+			"user_id": value.NewStringValue(userId), "amount": value.NewIntValue(amt)}
+	}
+	datasource.Register("lat_users", &rowsSource{rows: []map[string]value.Value{
+		user("1", "aaron"), user("2", "bob"), user("3", "carol"),
+	}})
+	datasource.Register("lat_orders", &rowsSource{rows: []map[string]value.Value{
+		order("a", "1", 10), order("b", "2", 20), order("c", "1", 30), order("d", "2", 5),
+	}})
+
+	runRows := func(sqlText string) []expr.ContextReader {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		rows := make([]expr.ContextReader, len(msgs))
+		for i, msg := range msgs {
+			rows[i] = msg.Body().(expr.ContextReader)
+		}
+		return rows
+	}
+
+	// the subquery filters by the outer row's key, so only that user's orders
+	rows := runRows(`SELECT u.name, o.order_id FROM lat_users AS u,
+		LATERAL (SELECT order_id FROM lat_orders WHERE user_id = u.user_id AND amount > 7) AS o`)
+	assert.Tf(t, len(rows) == 3, "3 rows: %v", len(rows))
+	for i, want := range [][2]string{{"aaron", "a"}, {"aaron", "c"}, {"bob", "b"}} {
+		name, _ := rows[i].Get("u.name")
+		id, _ := rows[i].Get("o.order_id")
+		assert.Tf(t, name.ToString() == want[0] && id.ToString() == want[1], "row %d: %v %v", i, name, id)
+	}
+
+	// aggregate per outer row
+	rows = runRows(`SELECT u.name, o.ct FROM lat_users AS u,
+		LATERAL (SELECT count(*) AS ct FROM lat_orders WHERE user_id = u.user_id) AS o`)
+	assert.Tf(t, len(rows) == 3, "3 rows: %v", len(rows))
+	for i, want := range []int64{2, 2, 0} {
+		ct, ok := rows[i].Get("o.ct")
+		assert.Tf(t, ok && ct.Value() == want, "row %d: %v", i, ct)
+	}
+}
+
+func TestRollup(t *testing.T) {
 
+	sale := func(region, product string, amt int64) map[string]value.Value {
+		return map[string]value.Value{"region": value.NewStringValue(region),
+			"product": value.NewStringValue(product), "amt": value.NewIntValue(amt)}
+	}
+	datasource.Register("rollup_sales", &rowsSource{rows: []map[string]value.Value{
+		sale("east", "apple", 10), sale("east", "pear", 5), sale("west", "apple", 7), sale("east", "apple", 3),
+	}})
+
+	job, err := BuildSqlJob(rtConf, "", `SELECT region, product, sum(amt) AS total, count(*) AS ct
+		FROM rollup_sales GROUP BY ROLLUP(region, product)`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	// (region, product), (region) subtotals, () grand total
+	wants := []struct {
+		region, product interface{}
+		total, ct       int64
+	}{
+		{"east", "apple", 13, 2},
+		{"east", "pear", 5, 1},
+		{"west", "apple", 7, 1},
+		{"east", nil, 18, 3},
+		{"west", nil, 7, 1},
+		{nil, nil, 25, 4},
+	}
+	assert.Tf(t, len(msgs) == len(wants), "%d rows: %v", len(wants), len(msgs))
+	for i, want := range wants {
+		row := msgs[i].Body().(expr.ContextReader)
+		region, _ := row.Get("region")
+		product, _ := row.Get("product")
+		total, _ := row.Get("total")
+		ct, _ := row.Get("ct")
+		assert.Tf(t, region.Value() == want.region && product.Value() == want.product,
+			"row %d: %v %v", i, region, product)
+		totalInt, _ := value.ToInt64(total.Rv())
+		assert.Tf(t, totalInt == want.total && ct.Value() == want.ct,
+			"row %d: total=%v ct=%v", i, total, ct)
+	}
+}
+
+// csv "files" by table name
+type csvStrings map[string]string
+
+func (m csvStrings) Tables() []string { return nil }
+func (m csvStrings) Close() error     { return nil }
+func (m csvStrings) Open(name string) (datasource.SourceConn, error) {
+	return datasource.NewCsvSourceTyped(strings.NewReader(m[name]), make(<-chan bool, 1))
+}
+
+func TestCsvSource(t *testing.T) {
+
+	datasource.Register("csv_typed", csvStrings{"csv_typed": `user_id,name,item_count,active
+a1,aaron,82,true
+b2,bob,12,false
+c3,carol,40,true`})
+
+	job, err := BuildSqlJob(rtConf, "", `SELECT name, item_count * 2 AS dbl FROM csv_typed
+		WHERE item_count > 20 AND active = true`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	assert.Tf(t, len(msgs) == 2, "2 rows: %v", len(msgs))
+	for i, want := range []struct {
+		name string
+		dbl  float64
+	}{{"aaron", 164}, {"carol", 80}} {
+		row := msgs[i].Body().(expr.ContextReader)
+		name, _ := row.Get("name")
+		dbl, _ := row.Get("dbl")
+		assert.Tf(t, name.ToString() == want.name, "row %d: %v", i, name)
+		assert.Tf(t, value.ToFloat64(dbl.Rv()) == want.dbl, "row %d: %v", i, dbl)
+	}
+}
+
+func TestInsertOnConflict(t *testing.T) {
+
+	schema := datasource.NewSchema("conflict_users")
+	schema.AddField("id", value.StringType)
+	schema.AddField("name", value.StringType)
+	table := &insertTable{schema: schema}
+	datasource.Register("conflict_users", table)
+
+	insert := func(sqlText string) (*SqlJob, error) {
+		job, err := BuildSqlJob(rtConf, "", sqlText)
+		if err != nil {
+			return nil, err
+		}
+		assert.T(t, job.Setup() == nil)
+		return job, job.Run(context.Background())
+	}
+	_, err := insert(`INSERT INTO conflict_users (id, name) VALUES ("a", "aaron"), ("b", "bob")`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	// a and b collide, c is new, and c repeated in the batch collides with itself
+	job, err := insert(`INSERT INTO conflict_users (id, name) VALUES ("a", "x"), ("c", "carol"), ("b", "x"), ("c", "x")
+		ON CONFLICT (id) DO NOTHING`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, job.Tasks[0].(*Insert).Skipped() == 3, "skipped 3: %v", job.Tasks[0].(*Insert).Skipped())
+	assert.Tf(t, len(table.rows) == 3, "only c written: %v", table.rows)
+	for _, row := range table.rows {
+		assert.Tf(t, row["name"].ToString() != "x", "conflicting row not written %v", row)
+	}
+
+	// without ON CONFLICT the duplicate is written (the source has no unique keys)
+	_, err = insert(`INSERT INTO conflict_users (id, name) VALUES ("a", "x")`)
+	assert.Tf(t, err == nil && len(table.rows) == 4, "no conflict check %v", err)
+
+	// a placeholder must be bound, see expr.BindParams
+	_, err = insert(`INSERT INTO conflict_users (id, name) VALUES (?, "x")`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "not bound"), "unbound ? %v", err)
+	assert.Tf(t, len(table.rows) == 4, "nothing written %v", table.rows)
+}
+
+func TestSetOperations(t *testing.T) {
+
+	setRows := func(xs ...int64) []map[string]value.Value {
+		rows := make([]map[string]value.Value, len(xs))
+		for i, x := range xs {
+			rows[i] = map[string]value.Value{"x": value.NewIntValue(x), "name": value.NewStringValue(fmt.Sprintf("n%d", x))}
+		}
+		return rows
+	}
+	datasource.Register("set_a", &rowsSource{rows: setRows(1, 1, 2, 3)})
+	datasource.Register("set_b", &rowsSource{rows: setRows(1, 3, 3, 4)})
+
+	run := func(sqlText string) []string {
+		return runSqlCollect(t, rtConf, sqlText, "x")
+	}
+
+	assert.Equal(t, []string{"2"}, run(`SELECT x FROM set_a EXCEPT SELECT x FROM set_b ORDER BY x`))
+	assert.Equal(t, []string{"1", "2"}, run(`SELECT x FROM set_a EXCEPT ALL SELECT x FROM set_b ORDER BY x`))
+	assert.Equal(t, []string{"1", "3"}, run(`SELECT x FROM set_a INTERSECT SELECT x FROM set_b ORDER BY x`))
+	assert.Equal(t, []string{"1", "3"}, run(`SELECT x FROM set_a INTERSECT ALL SELECT x FROM set_b ORDER BY x`))
+	assert.Equal(t, []string{"1", "1", "3"}, run(`SELECT x FROM set_a INTERSECT ALL SELECT x FROM set_a WHERE x != 2 ORDER BY x`))
+	assert.Equal(t, []string{"1", "2", "3", "4"}, run(`SELECT x FROM set_a UNION SELECT x FROM set_b ORDER BY x`))
+	assert.Equal(t, 8, len(run(`SELECT x FROM set_a UNION ALL SELECT x FROM set_b`)))
+	// right columns are matched by position, under the left names
+	assert.Equal(t, []string{"1", "2"}, run(`SELECT x, name FROM set_a EXCEPT SELECT x AS y, name FROM set_b WHERE x > 1 ORDER BY x`))
+	assert.Equal(t, []string{"2", "3", "4"}, run(`SELECT x FROM set_a WHERE x > 1 UNION SELECT x * 1 AS z FROM set_b WHERE x > 1 ORDER BY x`))
+	// left associative
+	assert.Equal(t, []string{"2", "4"}, run(`SELECT x FROM set_a EXCEPT SELECT x FROM set_b UNION SELECT x FROM set_b WHERE x == 4 ORDER BY x`))
+	// INTERSECT binds tighter than UNION and EXCEPT
+	assert.Equal(t, []string{"1", "2", "3"}, run(`SELECT x FROM set_a EXCEPT SELECT x FROM set_b INTERSECT SELECT x FROM set_b WHERE x == 4 ORDER BY x`))
+	assert.Equal(t, []string{"1", "2", "3"}, run(`SELECT x FROM set_a UNION SELECT x FROM set_b INTERSECT SELECT x FROM set_a WHERE x == 1 ORDER BY x`))
+
+	// rows are equal by type and value, as for GROUP BY, 1 and "1" differ as
+	//  do 1 and 1.0
+	datasource.Register("set_typed", &rowsSource{rows: []map[string]value.Value{
+		{"x": value.NewStringValue("1")},
+		{"x": value.NewNumberValue(2)},
+		{"x": value.NewIntValue(2)},
+		{"x": value.NewIntValue(3)},
+	}})
+	assert.Equal(t, 5, len(run(`SELECT x FROM set_a UNION SELECT x FROM set_typed`)))
+	assert.Equal(t, []string{"1"}, run(`SELECT x FROM set_a EXCEPT SELECT x FROM set_typed ORDER BY x`))
+	assert.Equal(t, len(run(`SELECT x FROM set_typed GROUP BY x`)),
+		len(run(`SELECT x FROM set_typed UNION SELECT x FROM set_typed`)))
+
+	_, err := BuildSqlJob(rtConf, "", `SELECT x, name FROM set_a EXCEPT SELECT x FROM set_b`)
+	assert.Tf(t, err != nil, "column count mismatch should error")
+}
+
+func TestSortNulls(t *testing.T) {
+
+	datasource.Register("sort_nulls", &rowsSource{rows: []map[string]value.Value{
+		{"name": value.NewStringValue("b"), "x": value.NewIntValue(2)},
+		{"name": value.NewStringValue("nil1"), "x": value.NewNilValue()},
+		{"name": value.NewStringValue("a"), "x": value.NewIntValue(1)},
+		{"name": value.NewStringValue("nil2")},
+		{"name": value.NewStringValue("c"), "x": value.NewIntValue(3)},
+	}})
+
+	sortedNames := func(sqlText string) []string {
+		return runSqlCollect(t, rtConf, sqlText, "name")
+	}
+
+	// default, nulls are the smallest value
+	assert.Equal(t, []string{"nil1", "nil2", "a", "b", "c"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x`))
+	assert.Equal(t, []string{"c", "b", "a", "nil1", "nil2"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x DESC`))
+
+	assert.Equal(t, []string{"a", "b", "c", "nil1", "nil2"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x NULLS LAST`))
+	assert.Equal(t, []string{"a", "b", "c", "nil1", "nil2"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x ASC NULLS LAST`))
+	assert.Equal(t, []string{"nil1", "nil2", "c", "b", "a"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x DESC NULLS FIRST`))
+	assert.Equal(t, []string{"nil2", "nil1", "c", "b", "a"}, sortedNames(`SELECT name, x FROM sort_nulls ORDER BY x DESC NULLS FIRST, name DESC`))
+}
+
+// a SourcePlanner, recording the plan it was asked to Accept, it only plans
+//  selects with a where (as its first row)
+type plannerSource struct {
+	rows    []map[string]value.Value
+	planned *SourcePlan
+}
+
+func (m *plannerSource) Tables() []string { return nil }
+func (m *plannerSource) Close() error     { return nil }
+func (m *plannerSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return m, nil
+}
+func (m *plannerSource) Accept(sub expr.SubVisitor) (datasource.Scanner, error) {
+	m.planned = sub.(*SourcePlan)
+	if m.planned.Select == nil || m.planned.Select.Where == nil {
+		return nil, fmt.Errorf("can not plan %v", m.planned.Select)
+	}
+	return &rowsSource{rows: m.rows[:1]}, nil
+}
+
+func TestSourcePlanner(t *testing.T) {
+
+	source := &plannerSource{rows: []map[string]value.Value{
+		{"name": value.NewStringValue("a")},
+		{"name": value.NewStringValue("b")},
+	}}
+	datasource.Register("planned", source)
+
+	job, err := BuildSqlJob(rtConf, "", `SELECT name FROM planned WHERE name != "z"`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, source.planned != nil, "Accept was called")
+	assert.Tf(t, source.planned.SqlSource.Name == "planned", "plan of the from: %v", source.planned.SqlSource)
+	assert.Tf(t, source.planned.Select.Where != nil, "plan of the statement: %v", source.planned.Select)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 1, "rows of the planned scanner: %v", len(msgs))
+
+	// a planner error fails the build
+	_, err = BuildSqlJob(rtConf, "", `SELECT name FROM planned`)
+	assert.Tf(t, err != nil, "plan error")
+}
+
+// a SourcePlanner that pushes the where down into its scan, as a native
+//  query would, counting the rows it scanned out
+type filterSource struct {
+	rows    []map[string]value.Value
+	where   expr.Node
+	orderBy expr.Columns
+	scanned int
+}
+
+func (m *filterSource) Tables() []string { return nil }
+func (m *filterSource) Close() error     { return nil }
+func (m *filterSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return m, nil
+}
+func (m *filterSource) Accept(sub expr.SubVisitor) (datasource.Scanner, error) {
+	plan, ok := sub.(expr.SourceVisitor)
+	if !ok {
+		return nil, fmt.Errorf("expected SourceVisitor: %T", sub)
+	}
+	m.where, m.orderBy = plan.Where(), plan.OrderBy()
+	filtered := &rowsSource{}
+	for _, row := range m.rows {
+		if m.where != nil {
+			v, ok := vm.Eval(datasource.NewContextSimpleData(row), m.where)
+			if !ok || v.Value() != true {
+				continue
+			}
+		}
+		filtered.rows = append(filtered.rows, row)
+	}
+	m.scanned = len(filtered.rows)
+	return filtered, nil
+}
+
+func TestSourceVisitor(t *testing.T) {
+
+	source := &filterSource{}
+	for i := 0; i < 10; i++ {
+		source.rows = append(source.rows, map[string]value.Value{"n": value.NewIntValue(int64(i))})
+	}
+	datasource.Register("pushdown", source)
+
+	job, err := BuildSqlJob(rtConf, "", `SELECT n FROM pushdown WHERE n > 6 ORDER BY n DESC`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, source.where != nil && source.where.String() == "n > 6", "where: %v", source.where)
+	assert.Tf(t, len(source.orderBy) == 1 && source.orderBy[0].Order == "DESC", "order by: %v", source.orderBy)
+	assert.Tf(t, source.scanned == 3, "the where is pushed down: %v", source.scanned)
+
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(msgs) == 3, "3 rows: %v", len(msgs))
+	n, _ := msgs[0].Body().(expr.ContextReader).Get("n")
+	assert.Tf(t, n.Value() == int64(9), "sorted desc: %v", n)
+
+	plan := NewSourcePlan(&expr.SqlSource{Name: "pushdown"})
+	assert.Tf(t, plan.Where() == nil && plan.Projection() == nil, "no statement: %v", plan)
+}
+
+// an insertTable whose Get() is by its id column, counting the scans and
+//  seeks of it
+type seekTable struct {
+	*insertTable
+	scans int
+	gets  int
+}
+
+func (m *seekTable) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *seekTable) SeekKey(table string) string                         { return "id" }
+func (m *seekTable) CreateIterator(filter expr.Node) datasource.Iterator {
+	m.scans++
+	return m.insertTable.CreateIterator(filter)
+}
+func (m *seekTable) Get(key string) datasource.Message {
+	m.gets++
+	return m.insertTable.Get(key)
+}
+
+func TestAccessPathCost(t *testing.T) {
+
+	schema := datasource.NewSchema("seek_users")
+	schema.AddField("id", value.IntType)
+	schema.AddField("name", value.StringType)
+	table := &seekTable{insertTable: &insertTable{schema: schema}}
+	for i, name := range []string{"bob", "jane", "sue"} {
+		table.rows = append(table.rows, map[string]value.Value{
+			"id":   value.NewIntValue(int64(i + 1)),
+			"name": value.NewStringValue(name),
+		})
+	}
+	datasource.Register("seek_users", table)
+
+	explain := func(sqlText string) *AccessPath {
+		stmt, err := expr.ParseSql(sqlText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		paths, err := NewJobBuilder(rtConf, "").Explain(stmt.(*expr.SqlSelect))
+		assert.Tf(t, err == nil, "no error %v", err)
+		assert.Tf(t, len(paths) == 1, "a path per from: %v", paths)
+		return paths[0]
+	}
+
+	// an equality on the seek key is a seek, the rest of the where filters
+	path := explain(`SELECT name FROM seek_users WHERE name != "x" AND id = 2`)
+	assert.Tf(t, path.Kind == AccessSeek, "seek: %v", path)
+	assert.Tf(t, path.Key == "id" && len(path.Keys) == 1 && path.Keys[0] == "2", "seek key: %v", path)
+	assert.Tf(t, path.Filter != nil && path.Filter.String() == `name != "x"`, "residual: %v", path.Filter)
+	assert.Tf(t, path.Cost < scanCost(path.Filter), "cheaper than a scan: %v", path)
+
+	path = explain(`SELECT name FROM seek_users AS u WHERE u.id IN (1, 3, 1)`)
+	assert.Tf(t, path.Kind == AccessSeek && len(path.Keys) == 2, "seek of distinct keys: %v", path)
+	assert.Tf(t, path.Filter == nil, "no residual: %v", path.Filter)
+
+	// not sargable on the key
+	for _, sqlText := range []string{
+		`SELECT name FROM seek_users`,
+		`SELECT name FROM seek_users WHERE name = "bob"`,
+		`SELECT name FROM seek_users WHERE id > 1`,
+		`SELECT name FROM seek_users WHERE id = 1 OR name = "sue"`,
+	} {
+		path = explain(sqlText)
+		assert.Tf(t, path.Kind == AccessScan, "scan %s: %v", sqlText, path)
+	}
+
+	runSelect := func(sqlText string) []datasource.Message {
+		return runSqlMsgs(t, rtConf, sqlText)
+	}
+
+	table.scans, table.gets = 0, 0
+	msgs := runSelect(`SELECT name FROM seek_users WHERE id = 2`)
+	assert.Tf(t, len(msgs) == 1, "1 row: %v", len(msgs))
+	name, _ := msgs[0].Body().(expr.ContextReader).Get("name")
+	assert.Tf(t, name.ToString() == "jane", "seeked row: %v", name)
+	assert.Tf(t, table.scans == 0 && table.gets == 1, "seek, not scan: scans=%d gets=%d", table.scans, table.gets)
+
+	msgs = runSelect(`SELECT name FROM seek_users WHERE id IN (3, 7) AND name != "bob"`)
+	assert.Tf(t, len(msgs) == 1 && table.scans == 0, "missing key skipped: %v scans=%d", len(msgs), table.scans)
+
+	// the plan as rows of an EXPLAIN
+	table.gets = 0
+	msgs = runSelect(`EXPLAIN SELECT name FROM seek_users WHERE id = 3`)
+	assert.Tf(t, len(msgs) == 1, "a row per path: %v", len(msgs))
+	row := msgs[0].Body().(expr.ContextReader).Row()
+	assert.Tf(t, row["access"].ToString() == "seek" && row["key"].ToString() == "id", "explained: %v", row)
+	assert.Tf(t, row["table"].ToString() == "seek_users", "explained: %v", row)
+	assert.Tf(t, table.scans == 0 && table.gets == 0, "explain does not run: scans=%d gets=%d", table.scans, table.gets)
+}
+
+func TestDistinctOn(t *testing.T) {
+
+	source := &rowsSource{}
+	day := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, user := range []string{"bob", "jane", "bob", "sue", "jane", "bob"} {
+		source.rows = append(source.rows, map[string]value.Value{
+			"user_id": value.NewStringValue(user),
+			"ts":      value.NewTimeValue(day.Add(time.Duration(i) * time.Hour)),
+			"n":       value.NewIntValue(int64(i)),
+		})
+	}
+	datasource.Register("user_events", source)
+
+	runSelect := func(sqlText string) []map[string]value.Value {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		rows := make([]map[string]value.Value, len(msgs))
+		for i, msg := range msgs {
+			rows[i] = msg.Body().(expr.ContextReader).Row()
+		}
+		return rows
+	}
+
+	// the latest event of each user
+	rows := runSelect(`SELECT DISTINCT ON (user_id) user_id, n FROM user_events ORDER BY user_id, ts DESC`)
+	assert.Tf(t, len(rows) == 3, "a row per user: %v", rows)
+	for i, want := range []string{"bob:5", "jane:4", "sue:3"} {
+		got := rows[i]["user_id"].ToString() + ":" + rows[i]["n"].ToString()
+		assert.Tf(t, got == want, "row %d want %s got %s", i, want, got)
+	}
+
+	// without an ORDER BY the first row read of each
+	rows = runSelect(`SELECT DISTINCT ON (user_id) user_id, n FROM user_events`)
+	assert.Tf(t, len(rows) == 3, "a row per user: %v", rows)
+	assert.Tf(t, rows[0]["n"].ToString() == "0" && rows[2]["n"].ToString() == "3", "first of each: %v", rows)
+
+	_, err := BuildSqlJob(rtConf, "", `SELECT DISTINCT ON (user_id) count(*) FROM user_events`)
+	assert.Tf(t, err != nil, "DISTINCT ON with aggregates should error")
+}
+
+func TestEnumColumns(t *testing.T) {
+
+	schema := datasource.NewSchema("enum_users")
+	schema.AddField("id", value.IntType)
+	schema.AddField("name", value.StringType)
+	schema.AddField("status", value.StringType).Enum = []string{"active", "pending", "banned"}
+	table := &insertTable{schema: schema}
+	datasource.Register("enum_users", table)
+
+	runDml := func(conf *datasource.RuntimeConfig, sqlText string) (*SqlJob, error) {
+		job, err := BuildSqlJob(conf, "", sqlText)
+		if err != nil {
+			return nil, err
+		}
+		assert.T(t, job.Setup() == nil)
+		return job, job.Run(context.Background())
+	}
+
+	_, err := runDml(rtConf, `INSERT INTO enum_users (name, status) VALUES ("bob", "active"), ("jane", "pending")`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(table.rows) == 2, "inserted: %v", len(table.rows))
+
+	_, err = runDml(rtConf, `INSERT INTO enum_users (name, status) VALUES ("sue", "deleted")`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), `"deleted" not allowed for column "status"`), "rejected: %v", err)
+	assert.Tf(t, len(table.rows) == 2, "nothing inserted: %v", len(table.rows))
+
+	_, err = runDml(rtConf, `UPDATE enum_users SET status = "banned" WHERE name = "bob"`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, table.rows[0]["status"].ToString() == "banned", "updated: %v", table.rows[0])
+
+	// the row with a disallowed value is skipped, strict fails
+	job, err := runDml(rtConf, `UPDATE enum_users SET status = "gone" WHERE name = "jane"`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(job.RowErrors()) == 1, "row error: %v", job.RowErrors())
+	assert.Tf(t, table.rows[1]["status"].ToString() == "pending", "not updated: %v", table.rows[1])
+
+	strict := datasource.NewRuntimeConfig()
+	strict.StrictErrors = true
+	_, err = runDml(strict, `UPDATE enum_users SET status = "gone" WHERE name = "jane"`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), `"gone" not allowed for column "status"`), "rejected: %v", err)
+	assert.Tf(t, table.rows[1]["status"].ToString() == "pending", "not updated: %v", table.rows[1])
+}
+
+// an insertTable with a full text index of its title, which (unlike the
+//  vm word matching) matches words by prefix, ie fox matches foxes
+type searchTable struct {
+	*insertTable
+	scans    int
+	searches int
+	closes   int
+}
+
+func (m *searchTable) Close() error {
+	m.closes++
+	return nil
+}
+func (m *searchTable) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *searchTable) CreateIterator(filter expr.Node) datasource.Iterator {
+	m.scans++
+	return m.insertTable.CreateIterator(filter)
+}
+func (m *searchTable) CanSearch(table string, cols []string) bool {
+	return len(cols) == 1 && cols[0] == "title"
+}
+func (m *searchTable) Search(table string, cols []string, against string) (datasource.Iterator, error) {
+	m.searches++
+	found := &insertTable{schema: m.schema}
+	for _, row := range m.rows {
+		matched := false
+		for _, word := range expr.MatchTerms(row["title"].ToString()) {
+			for _, term := range expr.MatchTerms(against) {
+				matched = matched || strings.HasPrefix(word, term)
+			}
+		}
+		if matched {
+			found.rows = append(found.rows, row)
+		}
+	}
+	return found.CreateIterator(nil), nil
+}
+
+func TestMatchAgainst(t *testing.T) {
+
+	schema := datasource.NewSchema("search_docs")
+	schema.AddField("id", value.IntType)
+	schema.AddField("title", value.StringType)
+	schema.AddField("body", value.StringType)
+	table := &searchTable{insertTable: &insertTable{schema: schema}}
+	for i, doc := range [][2]string{
+		{"The quick brown fox", "jumps"},
+		{"Lazy dogs", "sleep all day"},
+		{"Foxes and hounds", "a chase"},
+	} {
+		table.rows = append(table.rows, map[string]value.Value{
+			"id":    value.NewIntValue(int64(i + 1)),
+			"title": value.NewStringValue(doc[0]),
+			"body":  value.NewStringValue(doc[1]),
+		})
+	}
+	datasource.Register("search_docs", table)
+
+	runSelect := func(sqlText string) []string {
+		return runSqlCollect(t, rtConf, sqlText, "id")
+	}
+
+	// pushed down to the index, which matches foxes, and not re-filtered
+	ids := runSelect(`SELECT id FROM search_docs WHERE MATCH(title) AGAINST("fox")`)
+	assert.Tf(t, strings.Join(ids, ",") == "1,3", "searched: %v", ids)
+	assert.Tf(t, table.searches == 1 && table.scans == 0, "search, not scan: searches=%d scans=%d", table.searches, table.scans)
+	assert.Tf(t, table.closes == 1, "the searched conn is closed with the job: %d", table.closes)
+
+	ids = runSelect(`SELECT id FROM search_docs WHERE id > 1 AND MATCH(title) AGAINST("fox")`)
+	assert.Tf(t, strings.Join(ids, ",") == "3", "residual filtered: %v", ids)
+	ids = runSelect(`SELECT id FROM search_docs AS d WHERE MATCH(d.title) AGAINST("fox")`)
+	assert.Tf(t, strings.Join(ids, ",") == "1,3" && table.scans == 0, "searched alias: %v", ids)
+
+	// not indexed, or not a conjunct, the vm matches words of every row
+	table.searches, table.scans = 0, 0
+	ids = runSelect(`SELECT id FROM search_docs WHERE MATCH(title) AGAINST("fox") OR id = 2`)
+	assert.Tf(t, strings.Join(ids, ",") == "1,2", "local match: %v", ids)
+	ids = runSelect(`SELECT id FROM search_docs WHERE MATCH(body) AGAINST("chase DAY")`)
+	assert.Tf(t, strings.Join(ids, ",") == "2,3", "local match: %v", ids)
+	assert.Tf(t, table.searches == 0 && table.scans == 2, "scan, not search: searches=%d scans=%d", table.searches, table.scans)
+
+	stmt, err := expr.ParseSql(`SELECT id FROM search_docs WHERE MATCH(title) AGAINST("fox") AND id > 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	paths, err := NewJobBuilder(rtConf, "").Explain(stmt.(*expr.SqlSelect))
+	assert.Tf(t, err == nil && len(paths) == 1, "no error %v", err)
+	assert.Tf(t, paths[0].Kind == AccessSearch && paths[0].Key == "title", "search path: %v", paths[0])
+	assert.Tf(t, paths[0].Filter.String() == "id > 1", "residual: %v", paths[0].Filter)
+	assert.Tf(t, paths[0].Cost < scanCost(paths[0].Filter), "cheaper than a scan: %v", paths[0])
+}
+
+func TestNullGroupKeys(t *testing.T) {
+
+	source := &rowsSource{}
+	for i, status := range []value.Value{
+		value.NewStringValue("a"), value.NewNilValue(), nil, value.NewStringValue("a"),
+		value.NewNilValue(), value.NewStringValue(""), nil,
+	} {
+		row := map[string]value.Value{"n": value.NewIntValue(int64(i))}
+		if status != nil {
+			row["status"] = status
+		}
+		source.rows = append(source.rows, row)
+	}
+	datasource.Register("null_keys", source)
+
+	runSelect := func(sqlText string) []map[string]value.Value {
+		msgs := runSqlMsgs(t, rtConf, sqlText)
+		rows := make([]map[string]value.Value, len(msgs))
+		for i, msg := range msgs {
+			rows[i] = msg.Body().(expr.ContextReader).Row()
+		}
+		return rows
+	}
+	// a, a | NULL, NULL, missing, missing | ""
+	counts := func(rows []map[string]value.Value) []int64 {
+		cts := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			cts = append(cts, row["ct"].Value().(int64))
+		}
+		sort.Slice(cts, func(i, j int) bool { return cts[i] < cts[j] })
+		return cts
+	}
+	rows := runSelect(`SELECT status, count(*) AS ct FROM null_keys GROUP BY status`)
+	assert.Tf(t, len(rows) == 3, "want 3 groups, one of all the NULLs: %v", rows)
+	assert.Tf(t, fmt.Sprint(counts(rows)) == "[1 2 4]", "counts %v", rows)
+	for _, row := range rows {
+		if v := row["status"]; v == nil || v.Type() == value.NilType {
+			assert.Tf(t, row["ct"].Value().(int64) == 4, "NULL group %v", row)
+		}
+	}
+
+	// NULL args of a func are the NULL group too
+	rows = runSelect(`SELECT upper(status) AS s, count(*) AS ct FROM null_keys GROUP BY upper(status)`)
+	assert.Tf(t, len(rows) == 3, "want 3 groups: %v", rows)
+	assert.Tf(t, fmt.Sprint(counts(rows)) == "[1 2 4]", "counts %v", rows)
+
+	rows = runSelect(`SELECT status, count(*) AS ct FROM null_keys GROUP BY status, n < 3`)
+	assert.Tf(t, fmt.Sprint(counts(rows)) == "[1 1 1 2 2]", "counts %v", rows)
+
+	rows = runSelect(`SELECT DISTINCT ON (status) status, n FROM null_keys`)
+	assert.Tf(t, len(rows) == 3, "want one row per status: %v", rows)
+	nulls := 0
+	for _, row := range rows {
+		if v := row["status"]; v == nil || v.Type() == value.NilType {
+			nulls++
+			assert.Tf(t, row["n"].Value().(int64) == 1, "first NULL row %v", row)
+		}
+	}
+	assert.Tf(t, nulls == 1, "want a single NULL row: %v", rows)
+}
+
+// a rowsSource counting the times it is opened, ie scanned
+type openCountSource struct {
+	*rowsSource
+	opens int
+}
+
+func (m *openCountSource) Open(connInfo string) (datasource.SourceConn, error) {
+	m.opens++
+	return m.rowsSource.Open(connInfo)
+}
+
+func TestHavingSubQuery(t *testing.T) {
+
+	// a: 3 orders, b: 1, c: 2
+	orders := &rowsSource{}
+	for i, uid := range []string{"a", "b", "a", "c", "a", "c"} {
+		orders.rows = append(orders.rows, map[string]value.Value{
+			"user_id": value.NewStringValue(uid), "amount": value.NewIntValue(int64(i + 1))})
+	}
+	datasource.Register("having_orders", orders)
+	// the average is 2
+	limits := &openCountSource{rowsSource: &rowsSource{rows: []map[string]value.Value{
+		{"ct": value.NewIntValue(1)}, {"ct": value.NewIntValue(3)},
+	}}}
+	datasource.Register("having_limits", limits)
+
+	runSelect := func(sqlText string) map[string]int64 {
+		groups := make(map[string]int64)
+		for _, msg := range runSqlMsgs(t, rtConf, sqlText) {
+			row := msg.Body().(expr.ContextReader).Row()
+			groups[row["user_id"].ToString()] = row["ct"].Value().(int64)
+		}
+		return groups
+	}
+
+	groups := runSelect(`SELECT user_id, count(*) AS ct FROM having_orders GROUP BY user_id
+		HAVING count(*) > (SELECT avg(ct) FROM having_limits)`)
+	assert.Tf(t, len(groups) == 1 && groups["a"] == 3, "only a is over the average: %v", groups)
+	assert.Tf(t, limits.opens == 1, "sub-query should run once: %v", limits.opens)
+
+	groups = runSelect(`SELECT user_id, count(*) AS ct FROM having_orders GROUP BY user_id
+		HAVING (SELECT avg(ct) FROM having_limits) <= count(*)`)
+	assert.Tf(t, len(groups) == 2 && groups["a"] == 3 && groups["c"] == 2, "a and c: %v", groups)
+
+	// aliases of the select, aggregates not selected and group by columns
+	groups = runSelect(`SELECT user_id, count(*) AS ct FROM having_orders GROUP BY user_id HAVING ct < 3`)
+	assert.Tf(t, len(groups) == 2 && groups["b"] == 1 && groups["c"] == 2, "b and c: %v", groups)
+	groups = runSelect(`SELECT user_id, count(*) AS ct FROM having_orders GROUP BY user_id
+		HAVING sum(amount) > 9 AND user_id != "a"`)
+	assert.Tf(t, len(groups) == 1 && groups["c"] == 2, "c has 4 + 6: %v", groups)
+}
+
+// a Tracer recording its spans, with the name of their parent span
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name, parent string
+	attrs        map[string]interface{}
+	err          error
+	ended        bool
+}
+
+type spanKey struct{}
+
+func (m *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, datasource.Span) {
+	span := &recordedSpan{name: name, attrs: make(map[string]interface{})}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+	m.mu.Lock()
+	m.spans = append(m.spans, span)
+	m.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (m *recordingTracer) span(name string) *recordedSpan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, span := range m.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (m *recordedSpan) SetAttribute(key string, v interface{}) { m.attrs[key] = v }
+func (m *recordedSpan) SetError(err error)                     { m.err = err }
+func (m *recordedSpan) End()                                   { m.ended = true }
+
+func TestTracer(t *testing.T) {
+
+	source := &rowsSource{}
+	for _, id := range []string{"a", "b", "c"} {
+		source.rows = append(source.rows, map[string]value.Value{"user_id": value.NewStringValue(id)})
+	}
+	datasource.Register("traced", source)
+
+	tracer := &recordingTracer{}
+	conf := *rtConf
+	conf.Tracer = tracer
+	ctx, query := tracer.StartSpan(context.Background(), "query")
+
+	msgs := make([]datasource.Message, 0)
+	job, err := BuildSqlJobContext(ctx, &conf, "", `SELECT user_id FROM traced WHERE user_id != "b"`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	assert.T(t, job.Run(ctx) == nil)
+	query.End()
+	assert.Tf(t, len(msgs) == 2, "want 2 rows: %v", len(msgs))
+
+	parse := tracer.span("parse")
+	assert.Tf(t, parse != nil && parse.ended && parse.parent == "query", "parse span %#v", parse)
+	assert.Tf(t, parse.attrs["sql"] == `SELECT user_id FROM traced WHERE user_id != "b"`, "sql %v", parse.attrs)
+	plan := tracer.span("plan")
+	assert.Tf(t, plan != nil && plan.ended && plan.parent == "query", "plan span %#v", plan)
+	access := tracer.span("access")
+	assert.Tf(t, access != nil && access.parent == "plan" && access.attrs["access"] == AccessScan,
+		"access span %#v", access)
+
+	// a span per task, and the scan of the source
+	for _, name := range []string{"source", "where", "projection"} {
+		span := tracer.span(name)
+		assert.Tf(t, span != nil && span.ended && span.parent == "query" && span.err == nil, "%s span %#v", name, span)
+	}
+	scan := tracer.span("scan")
+	assert.Tf(t, scan != nil && scan.ended && scan.parent == "query", "scan span %#v", scan)
+	assert.Tf(t, scan.attrs["table"] == "traced" && scan.attrs["rows"] == 3, "scan attrs %v", scan.attrs)
+
+	// parse errors are recorded on the span
+	tracer.spans = nil
+	_, err = BuildSqlJobContext(ctx, &conf, "", `SELECT user_id FROM traced WHERE`)
+	assert.T(t, err != nil)
+	parse = tracer.span("parse")
+	assert.Tf(t, parse != nil && parse.ended && parse.err != nil, "parse span %#v", parse)
+	assert.T(t, tracer.span("plan") == nil)
+}
+
+// an insertTable whose writes can be rolled back, inserting the user_id
+//  failOn errors
+type txTable struct {
+	*insertTable
+	failOn                       string
+	begun, committed, rolledBack int
+}
+
+type txTableTx struct {
+	table    *txTable
+	snapshot []map[string]value.Value
+}
+
+func (m *txTable) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *txTable) Insert(row map[string]value.Value) error {
+	if id, ok := row["user_id"]; ok && m.failOn != "" && id.ToString() == m.failOn {
+		return fmt.Errorf("could not insert %s", m.failOn)
+	}
+	return m.insertTable.Insert(row)
+}
+func (m *txTable) Begin() (datasource.Tx, error) {
+	m.begun++
+	return &txTableTx{table: m, snapshot: append([]map[string]value.Value(nil), m.rows...)}, nil
+}
+func (m *txTableTx) Commit() error { m.table.committed++; return nil }
+func (m *txTableTx) Rollback() error {
+	m.table.rolledBack++
+	m.table.rows = m.snapshot
+	return nil
+}
+
+func TestRunBatch(t *testing.T) {
+
+	newTable := func(name string) *insertTable {
+		schema := datasource.NewSchema(name)
+		schema.AddField("user_id", value.StringType).NotNull = true
+		schema.AddField("email", value.StringType)
+		return &insertTable{schema: schema}
+	}
+	parseBatch := func(table string, sqls ...string) []expr.SqlStatement {
+		stmts := make([]expr.SqlStatement, len(sqls))
+		for i, sqlText := range sqls {
+			stmt, err := expr.ParseSql(strings.Replace(sqlText, "$table", table, -1))
+			assert.Tf(t, err == nil, "parse %s: %v", sqlText, err)
+			stmts[i] = stmt
+		}
+		return stmts
+	}
+	good := []string{
+		`INSERT INTO $table (user_id) VALUES ("a")`,
+		`INSERT INTO $table (user_id) VALUES ("b")`,
+		`SELECT user_id FROM $table`,
+	}
+	// user_id is NOT NULL without a default, so the 2nd statement fails
+	failing := []string{
+		`INSERT INTO $table (user_id) VALUES ("a")`,
+		`INSERT INTO $table (email) VALUES ("b@email.com")`,
+		`INSERT INTO $table (user_id) VALUES ("c")`,
+	}
+
+	table := newTable("batch_users")
+	datasource.Register("batch_users", table)
+	results, err := NewJobBuilder(rtConf, "").RunBatch(parseBatch("batch_users", good...))
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, len(results) == 3, "a result per statement: %v", len(results))
+	assert.Tf(t, len(results[2].Rows) == 2, "select sees the inserts: %v", len(results[2].Rows))
+	for _, res := range results {
+		assert.Tf(t, res.Err == nil, "no error %v", res.Err)
+	}
+
+	// stops at the failed statement
+	table = newTable("batch_stop")
+	datasource.Register("batch_stop", table)
+	results, err = NewJobBuilder(rtConf, "").RunBatch(parseBatch("batch_stop", failing...))
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, len(results) == 2 && results[1].Err != nil, "stopped at 2nd: %v", results)
+	assert.Tf(t, len(table.rows) == 1, "only 1st inserted: %v", len(table.rows))
+
+	// runs the rest after the failed statement
+	table = newTable("batch_continue")
+	datasource.Register("batch_continue", table)
+	builder := NewJobBuilder(rtConf, "")
+	builder.ContinueOnError = true
+	results, err = builder.RunBatch(parseBatch("batch_continue", failing...))
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, len(results) == 3, "all run: %v", len(results))
+	assert.Tf(t, results[0].Err == nil && results[1].Err != nil && results[2].Err == nil, "only 2nd fails: %v", results)
+	assert.Tf(t, len(table.rows) == 2, "1st and 3rd inserted: %v", len(table.rows))
+
+	// a transactional source is rolled back when the batch stops
+	txt := &txTable{insertTable: newTable("batch_tx")}
+	datasource.Register("batch_tx", txt)
+	_, err = NewJobBuilder(rtConf, "").RunBatch(parseBatch("batch_tx", failing...))
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, txt.begun == 1 && txt.rolledBack == 1 && txt.committed == 0, "rolled back %+v", txt)
+	assert.Tf(t, len(txt.rows) == 0, "nothing inserted: %v", len(txt.rows))
+
+	// and committed when it continues
+	builder = NewJobBuilder(rtConf, "")
+	builder.ContinueOnError = true
+	_, err = builder.RunBatch(parseBatch("batch_tx", failing...))
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, txt.begun == 2 && txt.rolledBack == 1 && txt.committed == 1, "committed %+v", txt)
+	assert.Tf(t, len(txt.rows) == 2, "1st and 3rd inserted: %v", len(txt.rows))
+}
+
+func TestTransactioner(t *testing.T) {
+
+	schema := datasource.NewSchema("tx_users")
+	schema.AddField("user_id", value.StringType)
+	table := &txTable{insertTable: &insertTable{schema: schema}}
+	datasource.Register("tx_users", table)
+
+	runSql := func(sqlText string) error {
+		job, err := BuildSqlJob(rtConf, "", sqlText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		assert.T(t, job.Setup() == nil)
+		return job.Run(context.Background())
+	}
+
+	err := runSql(`INSERT INTO tx_users (user_id) VALUES ("a"), ("b")`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, table.begun == 1 && table.committed == 1, "committed %+v", table)
+	assert.Tf(t, len(table.rows) == 2, "2 rows inserted: %v", len(table.rows))
+
+	// the 2nd row fails, so the 1st is rolled back with it
+	table.failOn = "d"
+	err = runSql(`INSERT INTO tx_users (user_id) VALUES ("c"), ("d"), ("e")`)
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, table.begun == 2 && table.rolledBack == 1 && table.committed == 1, "rolled back %+v", table)
+	assert.Tf(t, len(table.rows) == 2, "unchanged: %v", len(table.rows))
+	for i, id := range []string{"a", "b"} {
+		assert.Tf(t, table.rows[i]["user_id"].ToString() == id, "unchanged row %d: %v", i, table.rows[i])
+	}
+
+	// without a Transactioner each row is kept as it is inserted
+	failing := &txTable{insertTable: &insertTable{schema: datasource.NewSchema("tx_none")}, failOn: "d"}
+	datasource.Register("tx_none", &insertOnly{failing})
+	err = runSql(`INSERT INTO tx_none (user_id) VALUES ("c"), ("d"), ("e")`)
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, failing.begun == 0 && len(failing.rows) == 1, "1st row kept: %v", len(failing.rows))
+}
+
+// a source that is only an Inserter, hiding the Transactioner of a txTable
+type insertOnly struct {
+	table *txTable
+}
+
+func (m *insertOnly) Tables() []string                                    { return m.table.Tables() }
+func (m *insertOnly) Open(connInfo string) (datasource.SourceConn, error) { return m, nil }
+func (m *insertOnly) Close() error                                        { return nil }
+func (m *insertOnly) Insert(row map[string]value.Value) error             { return m.table.Insert(row) }
+
+func TestWhereInSubQuery(t *testing.T) {
+
+	datasource.Register("sub_users", &rowsSource{rows: []map[string]value.Value{
+		{"user_id": value.NewStringValue("a")},
+		{"user_id": value.NewStringValue("b")},
+		{"user_id": value.NewStringValue("c")},
+	}})
+	datasource.Register("sub_orders", &rowsSource{rows: []map[string]value.Value{
+		{"user_id": value.NewStringValue("a"), "item_count": value.NewIntValue(5)},
+		{"user_id": value.NewStringValue("a"), "item_count": value.NewIntValue(20)},
+		{"user_id": value.NewStringValue("c"), "item_count": value.NewIntValue(1)},
+	}})
+	runWhere := func(sqlText string) []string {
+		return runSqlCollect(t, rtConf, sqlText, "user_id")
+	}
+
+	ids := runWhere(`SELECT user_id FROM sub_users WHERE user_id IN (SELECT user_id FROM sub_orders)`)
+	assert.Tf(t, strings.Join(ids, ",") == "a,c", "users with orders: %v", ids)
+	ids = runWhere(`SELECT user_id FROM sub_users WHERE user_id NOT IN (SELECT user_id FROM sub_orders WHERE item_count > 10)`)
+	assert.Tf(t, strings.Join(ids, ",") == "b,c", "users without large orders: %v", ids)
+}
+
+func TestSourceFetchError(t *testing.T) {
+
+	// the 2nd page of the api is gone
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"id": 1}, {"id": 2}], "paging": {"next": "p2"}}`)
+	}))
+	defer server.Close()
+	datasource.Register("rest_users", datasource.NewRestDataSource([]string{"rest_users"}, func(table, cursor string) (*http.Request, error) {
+		return http.NewRequest("GET", server.URL+"/"+table+"?page="+cursor, nil)
+	}, datasource.JsonPageResponse("data", "paging.next")))
+
+	job, err := BuildSqlJob(rtConf, "", `SELECT id FROM rest_users`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "404"), "the failed page is the error of the job: %v", err)
+}
+
+// a source whose scan fails after @failAfter rows
+type midScanFailSource struct {
+	rowsSource
+	failAfter int
+	err       error
+}
+
+func (m *midScanFailSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return &midScanFailSource{rowsSource: rowsSource{rows: m.rows}, failAfter: m.failAfter}, nil
+}
+func (m *midScanFailSource) CreateIterator(filter expr.Node) datasource.Iterator { return m }
+func (m *midScanFailSource) Err() error                                          { return m.err }
+func (m *midScanFailSource) Next() datasource.Message {
+	if m.cursor >= m.failAfter {
+		m.err = fmt.Errorf("connection reset after %d rows", m.cursor)
+		return nil
+	}
+	return m.rowsSource.Next()
+}
+
+func TestSourceMidScanError(t *testing.T) {
+
+	rows := make([]map[string]value.Value, 0)
+	for i := 0; i < 10; i++ {
+		rows = append(rows, map[string]value.Value{"n": value.NewIntValue(int64(i))})
+	}
+	// only the first Next is retried, a scan failing part way is the
+	//  error of the job
+	datasource.Register("mid_scan_fail", datasource.NewRetryingSource(&midScanFailSource{
+		rowsSource: rowsSource{rows: rows}, failAfter: 3}, 3, time.Millisecond, nil))
+	job, err := BuildSqlJob(rtConf, "", `SELECT n FROM mid_scan_fail`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	msgs := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "after 3 rows"), "scan error: %v", err)
+
+	// one failing shard of a parallel scan
+	scanners := []datasource.Scanner{
+		&rowsSource{rows: rows},
+		&midScanFailSource{rowsSource: rowsSource{rows: rows}, failAfter: 5},
+	}
+	tasks := make(Tasks, 0)
+	tasks.Add(NewParallelSource(&expr.SqlSource{Name: "shards"}, scanners, 2))
+	tasks.Add(NewResultBuffer(&msgs))
+	assert.T(t, SetupTasks(tasks) == nil)
+	err = RunJob(rtConf, tasks)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "shard 1"), "shard error: %v", err)
+}
+
+// a source whose Open is a new conn, the writes of a conn in a transaction
+//  are only applied to the table by its Commit
+type connTxSource struct {
+	schema *datasource.Schema
+	rows   []map[string]value.Value
+}
+
+type connTx struct {
+	source  *connTxSource
+	pending []map[string]value.Value
+	inTx    bool
+	closed  bool
+}
+
+func (m *connTxSource) Tables() []string { return []string{m.schema.Name} }
+func (m *connTxSource) Close() error     { return nil }
+func (m *connTxSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return &connTx{source: m}, nil
+}
+func (m *connTx) Close() error                                    { m.closed = true; return nil }
+func (m *connTx) Schema(table string) (*datasource.Schema, error) { return m.source.schema, nil }
+func (m *connTx) Insert(row map[string]value.Value) error {
+	if m.closed {
+		return fmt.Errorf("insert on closed conn")
+	}
+	if m.inTx {
+		m.pending = append(m.pending, row)
+		return nil
+	}
+	m.source.rows = append(m.source.rows, row)
+	return nil
+}
+func (m *connTx) Begin() (datasource.Tx, error) { m.inTx = true; return m, nil }
+func (m *connTx) Commit() error {
+	m.source.rows = append(m.source.rows, m.pending...)
+	m.pending, m.inTx = nil, false
+	return nil
+}
+func (m *connTx) Rollback() error {
+	m.pending, m.inTx = nil, false
+	return nil
+}
+
+func TestRunBatchConnTx(t *testing.T) {
+
+	schema := datasource.NewSchema("batch_conn_tx")
+	schema.AddField("user_id", value.StringType).NotNull = true
+	schema.AddField("email", value.StringType)
+	source := &connTxSource{schema: schema}
+	datasource.Register("batch_conn_tx", source)
+
+	// user_id is NOT NULL without a default, so the 2nd statement fails
+	stmts := make([]expr.SqlStatement, 0)
+	for _, sqlText := range []string{
+		`INSERT INTO batch_conn_tx (user_id) VALUES ("a")`,
+		`INSERT INTO batch_conn_tx (email) VALUES ("b@email.com")`,
+		`INSERT INTO batch_conn_tx (user_id) VALUES ("c")`,
+	} {
+		stmt, err := expr.ParseSql(sqlText)
+		assert.Tf(t, err == nil, "parse %s: %v", sqlText, err)
+		stmts = append(stmts, stmt)
+	}
+
+	// the writes are through the conn of the transaction, so rolled back
+	_, err := NewJobBuilder(rtConf, "").RunBatch(stmts)
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, len(source.rows) == 0, "nothing inserted: %v", source.rows)
+
+	// or committed
+	builder := NewJobBuilder(rtConf, "")
+	builder.ContinueOnError = true
+	_, err = builder.RunBatch(stmts)
+	assert.Tf(t, err != nil, "must error")
+	assert.Tf(t, len(source.rows) == 2, "1st and 3rd inserted: %v", source.rows)
+
+	// a cancelled context stops the batch
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	source.rows = nil
+	results, err := NewJobBuilder(rtConf, "").RunBatchContext(ctx, stmts)
+	assert.Tf(t, err != nil && len(results) == 0, "nothing run: %v %v", err, results)
+	assert.Tf(t, len(source.rows) == 0, "nothing inserted: %v", source.rows)
+}
+
+func TestRunJobMaxWorkers(t *testing.T) {
+
+	conf := *rtConf
+	conf.MaxWorkers = 4
+
+	plan := func(wheres int) (Tasks, *[]datasource.Message) {
+		csvSource, err := datasource.NewCsvSource(strings.NewReader("user_id\n1\n2"), make(<-chan bool, 1))
+		assert.Tf(t, err == nil, "no error %v", err)
+		where, err := expr.ParseExpression(`user_id != "x"`)
+		assert.Tf(t, err == nil, "no error %v", err)
+		msgs := make([]datasource.Message, 0)
+		tasks := make(Tasks, 0)
+		tasks.Add(NewSource(&expr.SqlSource{Name: "users"}, csvSource))
+		for i := 0; i < wheres; i++ {
+			tasks.Add(NewWhere(where.Root))
+		}
+		tasks.Add(NewResultBuffer(&msgs))
+		assert.T(t, SetupTasks(tasks) == nil)
+		return tasks, &msgs
+	}
+
+	tasks, msgs := plan(2)
+	err := RunJob(&conf, tasks)
+	assert.Tf(t, err == nil && len(*msgs) == 2, "as many tasks as workers %v %v", err, len(*msgs))
+
+	// more tasks than workers is an error, none of them run
+	base := runtime.NumGoroutine()
+	tasks, msgs = plan(5)
+	err = RunJob(&conf, tasks)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "MaxWorkers 4"), "7 tasks on 4 workers %v", err)
+	assert.Tf(t, len(*msgs) == 0, "nothing run %v", len(*msgs))
+	assert.Tf(t, runtime.NumGoroutine() <= base, "goroutines leaked: %d > %d", runtime.NumGoroutine(), base)
+
+	// the right select of a union shares the workers of the job
+	datasource.Register("workers_a", &rowsSource{rows: []map[string]value.Value{{"x": value.NewIntValue(1)}}})
+	job, err := BuildSqlJob(&conf, "", `SELECT x FROM workers_a UNION SELECT x FROM workers_a`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	union := make([]datasource.Message, 0)
+	job.Tasks.Add(NewResultBuffer(&union))
+	conf.MaxWorkers = len(job.Tasks)
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "MaxWorkers"), "no workers for the right select %v", err)
+}
+
+// a source of rows counting the closes of its conns, which have no
+//  iterator if noIter
+type closingSource struct {
+	rows   []map[string]value.Value
+	closes int
+	noIter bool
+}
+
+func (m *closingSource) Tables() []string { return nil }
+func (m *closingSource) Close() error     { return nil }
+func (m *closingSource) Open(connInfo string) (datasource.SourceConn, error) {
+	return &closingConn{rowsSource: &rowsSource{rows: m.rows}, source: m}, nil
+}
+
+type closingConn struct {
+	*rowsSource
+	source *closingSource
+}
+
+func (m *closingConn) Close() error {
+	m.source.closes++
+	return nil
+}
+func (m *closingConn) CreateIterator(filter expr.Node) datasource.Iterator {
+	if m.source.noIter {
+		return nil
+	}
+	return m.rowsSource
+}
+
+func TestUpdateFromClose(t *testing.T) {
+
+	schema := datasource.NewSchema("close_users")
+	schema.AddField("id", value.IntType)
+	schema.AddField("email", value.StringType)
+	datasource.Register("close_users", &insertTable{schema: schema,
+		rows: []map[string]value.Value{{"id": value.NewIntValue(1)}}})
+	lookup := &closingSource{rows: []map[string]value.Value{
+		{"user_id": value.NewIntValue(1), "email": value.NewStringValue("bob@email.com")},
+	}}
+	datasource.Register("close_lookup", lookup)
+
+	sqlText := `UPDATE close_users SET email = l.email FROM close_lookup AS l WHERE close_users.id = l.user_id`
+	job, err := BuildSqlJob(rtConf, "", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.T(t, job.Close() == nil)
+	assert.Tf(t, lookup.closes == 1, "the FROM conn is closed with the job: %d", lookup.closes)
+
+	// a FROM conn without an iterator is an error, not a panic
+	lookup.noIter = true
+	job, err = BuildSqlJob(rtConf, "", sqlText)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "no iterator"), "error %v", err)
+	assert.T(t, job.Close() == nil)
 }
@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*Explain)(nil)
+)
+
+// Explain is the source of an EXPLAIN, it emits a row per access path of
+//  the explained select
+//
+//     table | access | key     | keys  | filter   | cost
+//     users | seek   | user_id | [a]   | age > 20 | 4.5
+type Explain struct {
+	*TaskBase
+	paths []*AccessPath
+}
+
+func NewExplain(paths []*AccessPath) *Explain {
+	return &Explain{TaskBase: NewTaskBase("Explain"), paths: paths}
+}
+
+func (m *Explain) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	for _, path := range m.paths {
+		row := map[string]value.Value{
+			"table":  value.NewStringValue(path.Table),
+			"access": value.NewStringValue(path.Kind),
+			"key":    value.NewNilValue(),
+			"keys":   value.NewNilValue(),
+			"filter": value.NewNilValue(),
+			"cost":   value.NewNumberValue(path.Cost),
+		}
+		if path.Kind == AccessSeek || path.Kind == AccessSearch {
+			row["key"] = value.NewStringValue(path.Key)
+			row["keys"] = value.NewStringsValue(path.Keys)
+		}
+		if path.Filter != nil {
+			row["filter"] = value.NewStringValue(path.Filter.String())
+		}
+		select {
+		case m.msgOutCh <- datasource.NewContextSimpleData(row):
+		case <-m.SigChan():
+			return nil
+		}
+	}
+	return nil
+}
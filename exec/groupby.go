@@ -0,0 +1,467 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+// GroupBy is a GROUP BY task (or aggregate select without group by), it
+//  buffers all messages from its input grouped by the group by expressions
+//  then emits one message per group of the select columns.
+//
+//  - count(*) counts rows, count(col) non-null values, and
+//    count(DISTINCT col) unique non-null values (the count_distinct
+//    Aggregator), sum, avg and registered Aggregators are aggregated
+//    over the rows of the group
+//  - all other columns are evaluated against the first row of group
+//  - with grouping sets (or ROLLUP) rows are grouped at each level, the
+//    group by columns not in a level are NULL in its rows (subtotals)
+//  - groups are only emitted if the HAVING is true, its aggregates are
+//    aggregated over the group like the select columns, and its scalar
+//    sub-selects are run once (if the task has a builder to plan them)
+//
+//     SELECT user_id, count(*) AS ct FROM orders GROUP BY user_id
+//     SELECT a, b, count(*) AS ct FROM t GROUP BY ROLLUP(a, b)
+//     SELECT user_id FROM orders GROUP BY user_id HAVING count(*) > (SELECT avg(ct) FROM daily)
+type GroupBy struct {
+	*TaskBase
+	stmt       *expr.SqlSelect
+	builder    *JobBuilder
+	having     expr.Node        // the HAVING, its aggregates read from havingAggs
+	havingAggs []*expr.FuncNode // the aggregates of the HAVING
+}
+
+func NewGroupBy(stmt *expr.SqlSelect) *GroupBy {
+	m := &GroupBy{
+		TaskBase: NewTaskBase("GroupBy"),
+		stmt:     stmt,
+	}
+	if stmt.Having != nil {
+		m.having = m.havingExpr(stmt.Having)
+	}
+	return m
+}
+
+type groupRow struct {
+	first      expr.ContextReader
+	aggs       []expr.Aggregator // per select column, nil if not aggregate
+	havingAggs []expr.Aggregator // per HAVING aggregate
+}
+
+// the groups of one grouping set
+type groupLevel struct {
+	set    []int  // indexes of the group by columns
+	nulls  []bool // per select column, is it a group by column not in set
+	groups map[string]*groupRow
+	keys   []string // groups are emitted in order first seen
+}
+
+func (m *GroupBy) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	levels := m.groupLevels()
+	subs := newSubQueries(m.builder, ctx)
+msgLoop:
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				break msgLoop
+			}
+			reader, ok := msg.Body().(expr.ContextReader)
+			if !ok {
+				if err := ctx.RowError(msg, fmt.Errorf("could not convert to message reader: %T", msg.Body())); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, level := range levels {
+				key := m.groupKey(reader, level.set)
+				g, exists := level.groups[key]
+				if !exists {
+					var err error
+					if g, err = m.newGroup(reader); err != nil {
+						return err
+					}
+					level.groups[key] = g
+					level.keys = append(level.keys, key)
+				}
+				m.accumulate(g, reader)
+			}
+		case <-m.sigCh:
+			return nil
+		}
+	}
+
+	for _, level := range levels {
+		// aggregates without group by (or the grand total grouping set)
+		//  always return a row, ie count(*) = 0
+		if len(level.keys) == 0 && len(level.set) == 0 {
+			g, err := m.newGroup(datasource.NewContextSimple())
+			if err != nil {
+				return err
+			}
+			level.groups[""] = g
+			level.keys = append(level.keys, "")
+		}
+
+		for _, key := range level.keys {
+			g := level.groups[key]
+			out := m.result(g, level.nulls)
+			if m.having != nil {
+				pass, err := m.passHaving(g, out, subs)
+				if err != nil {
+					if err := ctx.RowError(out, err); err != nil {
+						return err
+					}
+					continue
+				}
+				if !pass {
+					continue
+				}
+			}
+			select {
+			case m.msgOutCh <- out:
+			case <-m.sigCh:
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// the grouping sets, without GROUPING SETS/ROLLUP one of all group by columns
+func (m *GroupBy) groupLevels() []*groupLevel {
+	sets := m.stmt.GroupingSets
+	if sets == nil {
+		all := make([]int, len(m.stmt.GroupBy))
+		for i := range all {
+			all[i] = i
+		}
+		sets = [][]int{all}
+	}
+	levels := make([]*groupLevel, len(sets))
+	for i, set := range sets {
+		level := &groupLevel{set: set, nulls: make([]bool, len(m.stmt.Columns)),
+			groups: make(map[string]*groupRow), keys: make([]string, 0)}
+		for gi, gb := range m.stmt.GroupBy {
+			if inSet(set, gi) || gb.Expr == nil {
+				continue
+			}
+			for ci, col := range m.stmt.Columns {
+				if col.Expr != nil && col.Expr.String() == gb.Expr.String() {
+					level.nulls[ci] = true
+				}
+			}
+		}
+		levels[i] = level
+	}
+	return levels
+}
+
+func inSet(set []int, i int) bool {
+	for _, si := range set {
+		if si == i {
+			return true
+		}
+	}
+	return false
+}
+
+// the group key of the group by expressions of the set, see groupingKey
+func (m *GroupBy) groupKey(reader expr.ContextReader, set []int) string {
+	exprs := make([]expr.Node, 0, len(set))
+	for _, i := range set {
+		if col := m.stmt.GroupBy[i]; col.Expr != nil {
+			exprs = append(exprs, col.Expr)
+		}
+	}
+	return groupingKey(reader, exprs)
+}
+
+// the key of a row for grouping (GROUP BY, DISTINCT ON, PARTITION BY), the
+//  HashValue of each expression.   Unlike =, NULL equals NULL here:  a NULL,
+//  a missing column and an expression that could not be evaluated (ie of
+//  NULL args) are all the one NULL key, so NULL rows group together.
+func groupingKey(reader expr.ContextReader, exprs []expr.Node) string {
+	var buf bytes.Buffer
+	for _, n := range exprs {
+		v, ok := vm.Eval(reader, n)
+		if !ok || v == nil || v.Type() == value.NilType {
+			v = nil
+		}
+		buf.WriteString(value.HashValue(v))
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
+
+func (m *GroupBy) newGroup(first expr.ContextReader) (*groupRow, error) {
+	g := &groupRow{first: first, aggs: make([]expr.Aggregator, len(m.stmt.Columns)),
+		havingAggs: make([]expr.Aggregator, len(m.havingAggs))}
+	for i, col := range m.stmt.Columns {
+		fn, ok := col.Expr.(*expr.FuncNode)
+		if !ok || !isAggregate(fn) {
+			continue
+		}
+		agg, err := newAggregator(fn)
+		if err != nil {
+			return nil, err
+		}
+		g.aggs[i] = agg
+	}
+	for i, fn := range m.havingAggs {
+		agg, err := newAggregator(fn)
+		if err != nil {
+			return nil, err
+		}
+		g.havingAggs[i] = agg
+	}
+	return g, nil
+}
+
+func (m *GroupBy) accumulate(g *groupRow, reader expr.ContextReader) {
+	for i, agg := range g.aggs {
+		if agg != nil {
+			aggregate(agg, m.stmt.Columns[i].Expr.(*expr.FuncNode), reader)
+		}
+	}
+	for i, agg := range g.havingAggs {
+		aggregate(agg, m.havingAggs[i], reader)
+	}
+}
+
+// aggregate the arg of @fn on the row, count(*) is passed a value per row
+func aggregate(agg expr.Aggregator, fn *expr.FuncNode, reader expr.ContextReader) {
+	if len(fn.Args) == 0 || fn.Args[0].String() == "*" {
+		agg.Do(value.NewIntValue(1))
+		return
+	}
+	v, ok := vm.Eval(reader, fn.Args[0])
+	if !ok {
+		v = value.NewNilValue()
+	}
+	agg.Do(v)
+}
+
+func (m *GroupBy) result(g *groupRow, nulls []bool) *datasource.ContextSimple {
+	out := datasource.NewContextSimple()
+	for i, col := range m.stmt.Columns {
+		if nulls[i] {
+			// rolled up
+			out.Put(col, g.first, value.NewNilValue())
+			continue
+		}
+		if g.aggs[i] != nil {
+			out.Put(col, g.first, g.aggs[i].Result())
+			continue
+		}
+		if col.Star {
+			for k, v := range g.first.Row() {
+				out.Put(&expr.Column{As: k}, nil, v)
+			}
+			continue
+		}
+		if v, ok := vm.Eval(g.first, col.Expr); ok {
+			out.Put(col, g.first, v)
+		}
+	}
+	return out
+}
+
+// the HAVING with each aggregate replaced by an identity of the same text,
+//  which havingRow reads the group's result of, so the HAVING is evaluated
+//  against one row per group
+//
+//     count(*) > 2   =>   `count(*)` > 2
+func (m *GroupBy) havingExpr(n expr.Node) expr.Node {
+	switch nt := n.(type) {
+	case *expr.FuncNode:
+		if isAggregate(nt) {
+			key := nt.String()
+			found := false
+			for _, fn := range m.havingAggs {
+				found = found || fn.String() == key
+			}
+			if !found {
+				m.havingAggs = append(m.havingAggs, nt)
+			}
+			return expr.NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: key, Pos: int(nt.Pos)})
+		}
+		fn := *nt
+		fn.Args = m.havingExprs(nt.Args)
+		return &fn
+	case *expr.BinaryNode:
+		bn := *nt
+		bn.Args[0], bn.Args[1] = m.havingExpr(nt.Args[0]), m.havingExpr(nt.Args[1])
+		return &bn
+	case *expr.TriNode:
+		tn := *nt
+		for i, arg := range nt.Args {
+			tn.Args[i] = m.havingExpr(arg)
+		}
+		return &tn
+	case *expr.UnaryNode:
+		un := *nt
+		un.Arg = m.havingExpr(nt.Arg)
+		return &un
+	case *expr.MultiArgNode:
+		mn := *nt
+		mn.Args = m.havingExprs(nt.Args)
+		return &mn
+	}
+	return n
+}
+
+func (m *GroupBy) havingExprs(nodes []expr.Node) []expr.Node {
+	out := make([]expr.Node, len(nodes))
+	for i, n := range nodes {
+		out[i] = m.havingExpr(n)
+	}
+	return out
+}
+
+// is the HAVING true for the group, NULL (unknown) is not true
+func (m *GroupBy) passHaving(g *groupRow, out *datasource.ContextSimple, subs *subQueries) (bool, error) {
+	row := &havingRow{ContextReader: out, subQueries: subs, first: g.first,
+		aggs: make(map[string]value.Value, len(m.havingAggs))}
+	for i, fn := range m.havingAggs {
+		row.aggs[fn.String()] = g.havingAggs[i].Result()
+	}
+	v, ok := vm.Eval(row, m.having)
+	if !ok {
+		return false, fmt.Errorf("could not evaluate having: %v", m.stmt.Having)
+	}
+	switch hv := v.(type) {
+	case value.BoolValue:
+		return hv.Val(), nil
+	case value.NilValue:
+		return false, nil
+	}
+	return false, fmt.Errorf("having is not boolean: %v", m.stmt.Having)
+}
+
+// a group as the HAVING sees it:  its aggregates, its select columns (by
+//  alias) and then the columns of its first row
+type havingRow struct {
+	expr.ContextReader
+	*subQueries
+	first expr.ContextReader
+	aggs  map[string]value.Value
+}
+
+func (m *havingRow) Get(key string) (value.Value, bool) {
+	if v, ok := m.aggs[key]; ok {
+		return v, true
+	}
+	if v, ok := m.ContextReader.Get(key); ok {
+		return v, true
+	}
+	return m.first.Get(key)
+}
+
+// does this select need grouping?  ie has group by or aggregate columns
+func needsGroupBy(stmt *expr.SqlSelect) bool {
+	if len(stmt.GroupBy) > 0 {
+		return true
+	}
+	for _, col := range stmt.Columns {
+		if fn, ok := col.Expr.(*expr.FuncNode); ok && isAggregate(fn) && col.Over == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isAggregate(fn *expr.FuncNode) bool {
+	if fn.IsAggregate() {
+		return true
+	}
+	_, ok := expr.AggregatorGet(fn.Name)
+	return ok
+}
+
+func newAggregator(fn *expr.FuncNode) (expr.Aggregator, error) {
+	name := strings.ToLower(fn.Name)
+	switch {
+	case name == "count" && fn.Distinct:
+		name = "count_distinct"
+	case fn.Distinct:
+		return nil, fmt.Errorf("DISTINCT not supported for aggregate %s", fn.Name)
+	case name == "count":
+		return &countAgg{}, nil
+	case name == "sum":
+		return &sumAgg{}, nil
+	case name == "avg":
+		return &sumAgg{avg: true}, nil
+	}
+	maker, ok := expr.AggregatorGet(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate %q", name)
+	}
+	// literal args after the first (column) arg
+	args := make([]value.Value, 0)
+	for i := 1; i < len(fn.Args); i++ {
+		switch n := fn.Args[i].(type) {
+		case *expr.NumberNode:
+			args = append(args, n.Value())
+		case *expr.StringNode:
+			args = append(args, value.NewStringValue(n.Text))
+		default:
+			return nil, fmt.Errorf("aggregate %s args must be literals: %v", fn.Name, n)
+		}
+	}
+	return maker(args...)
+}
+
+// count of non-null values, count(*) is passed a value per row
+type countAgg struct {
+	n int64
+}
+
+func (m *countAgg) Do(v value.Value) {
+	if v == nil || v.Type() == value.NilType || v.Err() {
+		return
+	}
+	m.n++
+}
+func (m *countAgg) Result() value.Value { return value.NewIntValue(m.n) }
+func (m *countAgg) Reset()              { m.n = 0 }
+
+// sum (or average) of the non-null numeric values
+type sumAgg struct {
+	avg bool
+	n   int64
+	sum float64
+}
+
+func (m *sumAgg) Do(v value.Value) {
+	if v == nil || v.Type() == value.NilType || v.Err() {
+		return
+	}
+	fv := value.ToFloat64(v.Rv())
+	if math.IsNaN(fv) {
+		return
+	}
+	m.n++
+	m.sum += fv
+}
+func (m *sumAgg) Result() value.Value {
+	if m.n == 0 {
+		return value.NewNilValue()
+	}
+	if m.avg {
+		return value.NewNumberValue(m.sum / float64(m.n))
+	}
+	return value.NewNumberValue(m.sum)
+}
+func (m *sumAgg) Reset() { m.n, m.sum = 0, 0 }
@@ -0,0 +1,154 @@
+package exec
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+// Insert is the task for an INSERT statement, it writes its rows to
+//  the source Inserter, the rows are complete (defaults applied) when
+//  the task is built, see insertRows.  With RETURNING each inserted row
+//  is emitted (for a projection of the returning columns)
+//
+//  With ON CONFLICT DO NOTHING rows whose key the source Seeker already
+//  has are skipped, see Skipped()
+//
+//  If the source is a Transactioner the rows are inserted in one
+//  transaction, an error inserting any row inserts none of them
+type Insert struct {
+	*TaskBase
+	into      datasource.Inserter
+	rows      []map[string]value.Value
+	returning bool
+	seeker    datasource.Seeker // if set, skip rows whose key exists
+	key       string
+	skipped   int
+	txer      datasource.Transactioner // if set, the rows are one transaction
+}
+
+func NewInsert(into datasource.Inserter, rows []map[string]value.Value, returning bool) *Insert {
+	return &Insert{
+		TaskBase:  NewTaskBase("Insert"),
+		into:      into,
+		rows:      rows,
+		returning: returning,
+	}
+}
+
+func (m *Insert) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	writes, err := beginWrites(m.txer)
+	if err != nil {
+		return err
+	}
+	defer writes.finish(false, nil)
+	completed, err := m.insert()
+	return writes.finish(completed, err)
+}
+
+// insert the rows, completed is false if signalled to stop first
+func (m *Insert) insert() (completed bool, err error) {
+	for _, row := range m.rows {
+		select {
+		case <-m.sigCh:
+			return false, nil
+		default:
+		}
+		if m.seeker != nil {
+			if kv, ok := row[m.key]; ok && kv != nil && m.seeker.Get(kv.ToString()) != nil {
+				m.skipped++
+				continue
+			}
+		}
+		if err := m.into.Insert(row); err != nil {
+			logging.Errorf("could not insert: %v", err)
+			return false, err
+		}
+		if !m.returning {
+			continue
+		}
+		select {
+		case m.msgOutCh <- datasource.NewContextSimpleData(row):
+		case <-m.sigCh:
+			return false, nil
+		}
+	}
+	if m.skipped > 0 {
+		logging.Infof("insert skipped %d conflicting rows", m.skipped)
+	}
+	return true, nil
+}
+
+// Skipped is the count of rows not inserted as their key existed
+func (m *Insert) Skipped() int { return m.skipped }
+
+// insertRows builds the rows of an insert keyed by column name, columns
+//  omitted or given as DEFAULT are filled from the schema default, it is
+//  an error for a NOT NULL column to have neither value nor default, or
+//  for a value not allowed by an ENUM or SET column
+func insertRows(stmt *expr.SqlInsert, schema *datasource.Schema) ([]map[string]value.Value, error) {
+	cols, err := datasource.ResolveSchemaInfo(schema, columnNames(stmt.Columns))
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]value.Value, 0, len(stmt.Rows))
+	for _, vals := range stmt.Rows {
+		if len(vals) != len(stmt.Columns) {
+			return nil, fmt.Errorf("insert has %d columns but %d values", len(stmt.Columns), len(vals))
+		}
+		writer := datasource.NewContextSimpleData(make(map[string]value.Value, len(vals)))
+		for i, col := range cols {
+			if _, isDefault := vals[i].(expr.DefaultValue); isDefault {
+				continue
+			}
+			if pv, isParam := vals[i].(expr.ParamValue); isParam {
+				return nil, fmt.Errorf("? at %d of column %q is not bound, see expr.BindParams", pv.Param.Pos, stmt.Columns[i].As)
+			}
+			writer.Put(col, nil, vals[i])
+		}
+		row := writer.Data
+		if schema != nil {
+			for _, f := range schema.Fields {
+				if _, ok := row[f.Name]; ok {
+					continue
+				}
+				switch {
+				case f.Default != nil:
+					row[f.Name] = f.Default
+				case f.NotNull:
+					return nil, fmt.Errorf("column %q is NOT NULL and has no default", f.Name)
+				}
+			}
+			for _, f := range schema.Fields {
+				if err := f.Validate(row[f.Name]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for i, col := range stmt.Columns {
+			if _, ok := row[col.As]; !ok {
+				if _, isDefault := vals[i].(expr.DefaultValue); isDefault && schema == nil {
+					return nil, fmt.Errorf("no schema for DEFAULT of column %q", col.As)
+				}
+				row[col.As] = value.NewNilValue()
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// the column names (the As) of insert and update columns
+func columnNames(cols expr.Columns) []string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.As
+	}
+	return names
+}
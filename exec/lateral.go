@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*Lateral)(nil)
+)
+
+// Lateral is the task of a LATERAL subquery FROM entry, the subquery is
+//  planned and run once per input (outer) row, with the outer row's columns
+//  readable under the outer alias.  Each row of the subquery is emitted
+//  merged with the outer row, so both  o.col  and  u.col  resolve.
+//
+//     SELECT u.name, o.ct FROM users AS u,
+//        LATERAL (SELECT count(*) AS ct FROM orders WHERE user_id = u.user_id) AS o
+type Lateral struct {
+	*TaskBase
+	builder     *JobBuilder
+	from        *expr.SqlSource
+	outerPrefix string
+}
+
+func NewLateral(builder *JobBuilder, outer, from *expr.SqlSource) *Lateral {
+	alias := outer.Alias
+	if alias == "" {
+		alias = outer.Name
+	}
+	m := &Lateral{
+		TaskBase:    NewTaskBase("Lateral"),
+		builder:     builder,
+		from:        from,
+		outerPrefix: alias + ".",
+	}
+	m.Handler = lateralHandler(m)
+	return m
+}
+
+func lateralHandler(m *Lateral) MessageHandler {
+	out := m.MessageOut()
+	return func(ctx *Context, msg datasource.Message) bool {
+		outer, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			return rowError(ctx, m, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
+		}
+		rows, err := m.runInner(ctx, outer)
+		if err != nil {
+			return rowError(ctx, m, msg, err)
+		}
+		outerRow := &derivedRow{ContextReader: outer, prefix: m.outerPrefix}
+		for _, row := range rows {
+			inner, ok := row.Body().(expr.ContextReader)
+			if !ok {
+				continue
+			}
+			merged := datasource.NewContextMerged(inner, outerRow)
+			select {
+			case out <- &derivedRow{ContextReader: merged, key: msg.Key()}:
+			case <-m.SigChan():
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// plan and run the subquery for a single outer row
+func (m *Lateral) runInner(ctx *Context, outer expr.ContextReader) ([]datasource.Message, error) {
+	subTasks, err := m.builder.VisitSubselect(m.from)
+	if err != nil {
+		return nil, err
+	}
+	tasks := subTasks.(Tasks)
+	defer func() {
+		for _, task := range tasks {
+			task.Close()
+		}
+	}()
+
+	// the rows of the subquery's source can read the outer row
+	refs := &outerRef{ContextReader: outer, prefix: m.outerPrefix}
+	correlate := NewTaskBase("LateralRef")
+	correlate.Handler = correlateHandler(refs, correlate)
+	tasks = append(Tasks{tasks[0], correlate}, tasks[1:]...)
+
+	rows := make([]datasource.Message, 0)
+	tasks.Add(NewResultBuffer(&rows))
+	if err := SetupTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := runTasks(ctx.runContext(), ctx, tasks); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func correlateHandler(refs expr.ContextReader, task TaskRunner) MessageHandler {
+	out := task.MessageOut()
+	return func(ctx *Context, msg datasource.Message) bool {
+		reader, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			return rowError(ctx, task, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
+		}
+		select {
+		case out <- &derivedRow{ContextReader: datasource.NewContextMerged(reader, refs), key: msg.Key()}:
+			return true
+		case <-task.SigChan():
+			return false
+		}
+	}
+}
+
+// the outer row as seen from inside a lateral subquery, only reads
+//  of  alias.col  resolve, so unqualified names are the subquery's own
+type outerRef struct {
+	expr.ContextReader
+	prefix string
+}
+
+func (m *outerRef) Get(key string) (value.Value, bool) {
+	if strings.HasPrefix(key, m.prefix) {
+		return m.ContextReader.Get(key[len(m.prefix):])
+	}
+	return nil, false
+}
+
+func (m *outerRef) Row() map[string]value.Value { return nil }
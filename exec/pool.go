@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"sync"
+)
+
+// WorkerPool runs funcs on a bounded number of goroutines, the first
+//  error returned by any func is kept and signals Quit() so the rest
+//  of the work can shutdown.
+//
+//     pool := NewWorkerPool(4)
+//     for _, task := range tasks {
+//         pool.Go(func() error { return task.Run(ctx) })
+//     }
+//     err := pool.Wait()
+type WorkerPool struct {
+	slots    chan bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	err      error
+	quit     chan bool
+	quitOnce sync.Once
+}
+
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		slots: make(chan bool, size),
+		quit:  make(chan bool),
+	}
+}
+
+// Go runs fn as soon as a worker is free, blocking until one is.  If
+//  the pool has already quit fn is not run.
+func (m *WorkerPool) Go(fn func() error) {
+	select {
+	case m.slots <- true:
+	case <-m.quit:
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer func() {
+			<-m.slots
+			m.wg.Done()
+		}()
+		if err := fn(); err != nil {
+			m.Stop(err)
+		}
+	}()
+}
+
+// Stop the pool, recording the error (the first one wins) and closing Quit()
+func (m *WorkerPool) Stop(err error) {
+	m.mu.Lock()
+	if m.err == nil {
+		m.err = err
+	}
+	m.mu.Unlock()
+	m.quitOnce.Do(func() { close(m.quit) })
+}
+
+// Quit is closed when the pool is stopped, or any func errors
+func (m *WorkerPool) Quit() <-chan bool { return m.quit }
+
+// Wait for all running funcs to complete, returning the first error
+func (m *WorkerPool) Wait() error {
+	m.wg.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
@@ -1,13 +1,26 @@
 package exec
 
 import (
-	u "github.com/araddon/gou"
+	"fmt"
+
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 	"github.com/araddon/qlbridge/vm"
 )
 
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*Projection)(nil)
+)
+
+// Projection evaluates the select columns of each message, it does not
+//  buffer:  each message is transformed and forwarded as it arrives so a
+//  job streams.   Tasks needing all rows (Sort, GroupBy) buffer before it.
+//
+//  Output rows are positional datasource.Row's sharing one column index,
+//  except for  select *  whose columns vary per message (ContextSimple).
 type Projection struct {
 	*TaskBase
 	sql *expr.SqlSelect
@@ -22,39 +35,81 @@ func NewProjection(sqlSelect *expr.SqlSelect) *Projection {
 	return s
 }
 
+// Schema of the projected columns, see JobBuilder.ResultSchema
+func (m *Projection) Schema() (*datasource.Schema, error) {
+	return projectionSchema(m.sql)
+}
+
+// the output columns of a select and their value types, inferred from the
+//  column expressions (func return types, arithmetic, literals)
+func projectionSchema(stmt *expr.SqlSelect) (*datasource.Schema, error) {
+	schema := datasource.NewSchema("")
+	if len(stmt.From) > 0 {
+		schema.Name = stmt.From[0].Name
+	}
+	for _, col := range stmt.Columns {
+		if col.Star {
+			return nil, fmt.Errorf("cannot describe result of select * without source schema")
+		}
+		schema.AddField(col.As, expr.InferValueType(col.Expr))
+	}
+	return schema, nil
+}
+
 // Create handler function for evaluation (ie, field selection from tuples)
 func projectionEvaluator(sql *expr.SqlSelect, task TaskRunner) MessageHandler {
 	out := task.MessageOut()
 	//evaluator := vm.Evaluator(where)
+
+	// without a star the columns are known up front
+	var colIndex map[string]int
+	names := make([]string, 0, len(sql.Columns))
+	for _, col := range sql.Columns {
+		if col.Star {
+			names = nil
+			break
+		}
+		names = append(names, col.Key())
+	}
+	if names != nil {
+		colIndex = datasource.ColumnIndex(names)
+	}
+
 	return func(ctx *Context, msg datasource.Message) bool {
 		defer func() {
 			if r := recover(); r != nil {
-				u.Errorf("crap, %v", r)
+				logging.Errorf("crap, %v", r)
 			}
 		}()
 
 		var outMsg datasource.Message
 		// uv := msg.Body().(url.Values)
 		switch mt := msg.Body().(type) {
-		case *datasource.ContextUrlValues:
-			// readContext := datasource.NewContextUrlValues(uv)
-			// use our custom write context for example purposes
-			writeContext := datasource.NewContextSimple()
-			outMsg = writeContext
-			//u.Infof("about to project: colsct%v %#v", len(sql.Columns), outMsg)
-			for _, col := range sql.Columns {
-				//u.Debugf("col:   %#v", col)
+		case expr.ContextReader:
+			var writeContext expr.ContextWriter
+			if colIndex != nil {
+				row := datasource.NewRow(msg.Key(), colIndex, make([]value.Value, len(names)))
+				writeContext, outMsg = row, row
+			} else {
+				row := datasource.NewContextSimple()
+				writeContext, outMsg = row, row
+			}
+			// a func used by several columns is evaluated once for the row
+			memo := vm.NewMemoContext(mt)
+			//logging.Infof("about to project: colsct%v %#v", len(sql.Columns), outMsg)
+			for i, col := range sql.Columns {
+				//logging.Debugf("col:   %#v", col)
 				if col.Guard != nil {
-					ifColValue, ok := vm.Eval(mt, col.Guard)
+					ifColValue, ok := vm.Eval(memo, col.Guard)
 					if !ok {
-						u.Errorf("Could not evaluate if:   %v", col.Guard.StringAST())
+						logging.Errorf("Could not evaluate if:   %v", col.Guard.StringAST())
 						//return fmt.Errorf("Could not evaluate if clause: %v", col.Guard.String())
 					}
-					//u.Debugf("if eval val:  %T:%v", ifColValue, ifColValue)
+					//logging.Debugf("if eval val:  %T:%v", ifColValue, ifColValue)
 					switch ifColVal := ifColValue.(type) {
 					case value.BoolValue:
 						if ifColVal.Val() == false {
-							//u.Debugf("Filtering out col")
+							//logging.Debugf("Filtering out col")
 							continue
 						}
 					}
@@ -63,19 +118,25 @@ func projectionEvaluator(sql *expr.SqlSelect, task TaskRunner) MessageHandler {
 					for k, v := range mt.Row() {
 						writeContext.Put(&expr.Column{As: k}, nil, v)
 					}
+				} else if col.Over != nil {
+					if v, ok := windowValue(mt, i); ok {
+						writeContext.Put(col, mt, v)
+					}
 				} else {
-					//u.Debugf("tree.Root: as?%v %#v", col.As, col.Expr)
-					v, ok := vm.Eval(mt, col.Expr)
-					//u.Debugf("evaled: ok?%v key=%v  val=%v", ok, col.Key(), v)
+					//logging.Debugf("tree.Root: as?%v %#v", col.As, col.Expr)
+					v, ok := vm.Eval(memo, col.Expr)
+					//logging.Debugf("evaled: ok?%v key=%v  val=%v", ok, col.Key(), v)
 					if ok {
 						writeContext.Put(col, mt, v)
 					}
 				}
 
 			}
+		default:
+			return rowError(ctx, task, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
 		}
 
-		//u.Debugf("completed projection for: %p %#v", out, outMsg)
+		//logging.Debugf("completed projection for: %p %#v", out, outMsg)
 		select {
 		case out <- outMsg:
 			return true
@@ -4,14 +4,12 @@ import (
 	"io"
 
 	"database/sql/driver"
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
-	_ = u.EMPTY
-
 	// ensure our resultwrite implements database/sql driver rows
 	_ driver.Rows = (*ResultWriter)(nil)
 )
@@ -22,7 +20,9 @@ type ResultWriter struct {
 }
 type ResultBuffer struct {
 	*TaskBase
-	cols []string
+	cols    []string
+	writeTo *[]datasource.Message
+	ct      int // messages this buffer appended to writeTo
 }
 
 func NewResultWriter() *ResultWriter {
@@ -42,18 +42,63 @@ func NewResultRows(cols []string) *ResultWriter {
 	return m
 }
 
+// MaxRows is a safeguard on the rows a job emits, it passes through up
+//  to max messages, on the next it fails the job with ErrMaxRowsExceeded
+type MaxRows struct {
+	*TaskBase
+	max int
+	ct  int
+}
+
+func NewMaxRows(max int) *MaxRows {
+	m := &MaxRows{
+		TaskBase: NewTaskBase("MaxRows"),
+		max:      max,
+	}
+	out := MakeHandler(m)
+	m.Handler = func(ctx *Context, msg datasource.Message) bool {
+		if m.ct >= m.max {
+			select {
+			case m.ErrChan() <- ErrMaxRowsExceeded:
+			default:
+			}
+			return false
+		}
+		m.ct++
+		return out(ctx, msg)
+	}
+	return m
+}
+
 func NewResultBuffer(writeTo *[]datasource.Message) *ResultBuffer {
 	m := &ResultBuffer{
 		TaskBase: NewTaskBase("ResultMemWriter"),
+		writeTo:  writeTo,
 	}
 	m.Handler = func(ctx *Context, msg datasource.Message) bool {
 		*writeTo = append(*writeTo, msg)
-		//u.Infof("write to msgs: %v", len(*writeTo))
+		m.ct++
+		//logging.Infof("write to msgs: %v", len(*writeTo))
 		return true
 	}
 	return m
 }
 
+// discard the partial results of a job that was cancelled or timed out,
+//  the messages appended by this buffer, not any already in writeTo
+func (m *ResultBuffer) discard() {
+	if m.writeTo == nil || m.ct == 0 {
+		return
+	}
+	msgs := *m.writeTo
+	keep := len(msgs) - m.ct
+	for i := keep; i < len(msgs); i++ {
+		msgs[i] = nil
+	}
+	*m.writeTo = msgs[:keep]
+	m.ct = 0
+}
+
 func (m *ResultWriter) Copy() *ResultWriter { return NewResultWriter() }
 func (m *ResultWriter) Close() error        { return nil }
 func (m *ResultBuffer) Copy() *ResultBuffer { return NewResultBuffer(nil) }
@@ -71,7 +116,7 @@ func (m *ResultWriter) Next(dest []driver.Value) error {
 			return io.EOF
 		}
 		if msg == nil {
-			u.Warnf("nil message?")
+			logging.Warnf("nil message?")
 			return io.EOF
 			//return fmt.Errorf("Nil message error?")
 		}
@@ -86,12 +131,12 @@ func (m *ResultWriter) Run(ctx *Context) error {
 	defer ctx.Recover() // Our context can recover panics, save error msg
 	defer func() {
 		close(m.msgOutCh) // closing output channels is the signal to stop
-		//u.Warnf("close taskbase: %v", m.Type())
+		//logging.Warnf("close taskbase: %v", m.Type())
 	}()
-	//u.Debugf("start Run() for ResultWriter")
+	//logging.Debugf("start Run() for ResultWriter")
 	select {
 	case err := <-m.errCh:
-		u.Errorf("got error:  %v", err)
+		logging.Errorf("got error:  %v", err)
 		return err
 	case <-m.sigCh:
 		return nil
@@ -108,9 +153,9 @@ func resultWrite(m *ResultWriter) MessageHandler {
 	return func(ctx *Context, msg datasource.Message) bool {
 
 		if msgReader, ok := msg.Body().(expr.ContextReader); ok {
-			u.Debugf("got msg in result writer: %#v", msgReader)
+			logging.Debugf("got msg in result writer: %#v", msgReader)
 		} else {
-			u.Errorf("could not convert to message reader: %T", msg.Body())
+			logging.Errorf("could not convert to message reader: %T", msg.Body())
 		}
 
 		select {
@@ -124,33 +169,33 @@ func resultWrite(m *ResultWriter) MessageHandler {
 
 func msgToRow(msg datasource.Message, cols []string, dest []driver.Value) error {
 
-	//u.Debugf("msg? %v  %T \n%p %v", msg, msg, dest, dest)
+	//logging.Debugf("msg? %v  %T \n%p %v", msg, msg, dest, dest)
 	switch mt := msg.Body().(type) {
 	case *datasource.ContextUrlValues:
 		for i, key := range cols {
 			if val, ok := mt.Get(key); ok && !val.Nil() {
 				dest[i] = val.Value()
-				//u.Infof("key=%v   val=%v", key, val)
+				//logging.Infof("key=%v   val=%v", key, val)
 			} else {
-				u.Warnf("missing value? %v %T %v", key, val.Value(), val.Value())
+				logging.Warnf("missing value? %v %T %v", key, val.Value(), val.Value())
 			}
 		}
-		//u.Debugf("got msg in row result writer: %#v", mt)
-	case *datasource.ContextSimple:
+		//logging.Debugf("got msg in row result writer: %#v", mt)
+	case expr.ContextReader:
 		for i, key := range cols {
-			//u.Debugf("key=%v mt = nil? %v", key, mt)
+			//logging.Debugf("key=%v mt = nil? %v", key, mt)
 			if val, ok := mt.Get(key); ok && val != nil && !val.Nil() {
 				dest[i] = val.Value()
-				//u.Infof("key=%v   val=%v", key, val)
+				//logging.Infof("key=%v   val=%v", key, val)
 			} else if val == nil {
-				u.Errorf("could not evaluate? %v", key)
+				logging.Errorf("could not evaluate? %v", key)
 			} else {
-				u.Warnf("missing value? %v %T %v", key, val.Value(), val.Value())
+				logging.Warnf("missing value? %v %T %v", key, val.Value(), val.Value())
 			}
 		}
-		//u.Debugf("got msg in row result writer: %#v", mt)
+		//logging.Debugf("got msg in row result writer: %#v", mt)
 	default:
-		u.Errorf("unknown message type: %T", mt)
+		logging.Errorf("unknown message type: %T", mt)
 	}
 	return nil
 }
@@ -0,0 +1,186 @@
+package exec
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*SetOperation)(nil)
+)
+
+// SetOperation is the task of a UNION, EXCEPT or INTERSECT, its input is
+//  the (projected) rows of the left select.  The right select is run to
+//  completion first and its rows hashed, rows are matched by their column
+//  values by position.  Without ALL the output is distinct rows, with ALL
+//  each right row matches (removes for EXCEPT, keeps for INTERSECT) one left row.
+type SetOperation struct {
+	*TaskBase
+	op        *expr.SqlSetOp
+	right     Tasks
+	leftCols  []string // nil for select *, then all row columns by name
+	rightCols []string
+}
+
+func NewSetOperation(op *expr.SqlSetOp, leftCols expr.Columns, right Tasks) *SetOperation {
+	return &SetOperation{
+		TaskBase:  NewTaskBase("SetOperation"),
+		op:        op,
+		right:     right,
+		leftCols:  setColumnNames(leftCols),
+		rightCols: setColumnNames(op.Right.Columns),
+	}
+}
+
+func (m *SetOperation) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	right, err := m.runRight(ctx)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int, len(right))
+	for _, msg := range right {
+		counts[setRowKey(setRowValues(msg, m.rightCols))]++
+	}
+	// rows already emitted, for distinct results
+	seen := make(map[string]bool)
+
+msgLoop:
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				break msgLoop
+			}
+			key := setRowKey(setRowValues(msg, m.leftCols))
+			if !m.op.All && seen[key] {
+				continue
+			}
+			switch m.op.Op {
+			case lex.TokenExcept:
+				if counts[key] > 0 {
+					if m.op.All {
+						counts[key]--
+					}
+					continue
+				}
+			case lex.TokenIntersect:
+				if counts[key] == 0 {
+					continue
+				}
+				if m.op.All {
+					counts[key]--
+				}
+			}
+			seen[key] = true
+			select {
+			case m.msgOutCh <- msg:
+			case <-m.sigCh:
+				return nil
+			}
+		case <-m.sigCh:
+			return nil
+		}
+	}
+
+	if m.op.Op != lex.TokenUnion {
+		return nil
+	}
+	for _, msg := range right {
+		vals := setRowValues(msg, m.rightCols)
+		key := setRowKey(vals)
+		if !m.op.All && seen[key] {
+			continue
+		}
+		seen[key] = true
+		if m.leftCols != nil && m.rightCols != nil {
+			// the right row under the left column names
+			msg = datasource.NewRow(msg.Key(), datasource.ColumnIndex(m.leftCols), vals)
+		}
+		select {
+		case m.msgOutCh <- msg:
+		case <-m.sigCh:
+			return nil
+		}
+	}
+	return nil
+}
+
+// run the right select to completion
+func (m *SetOperation) runRight(ctx *Context) ([]datasource.Message, error) {
+	defer func() {
+		for _, task := range m.right {
+			task.Close()
+		}
+	}()
+	rows := make([]datasource.Message, 0)
+	tasks := append(Tasks{}, m.right...)
+	tasks.Add(NewResultBuffer(&rows))
+	if err := SetupTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := runTasks(ctx.runContext(), ctx, tasks); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// the names of the select columns, nil if there is a star
+func setColumnNames(cols expr.Columns) []string {
+	names := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if col.Star {
+			return nil
+		}
+		names = append(names, col.Key())
+	}
+	return names
+}
+
+// the values of a row by position of @cols, for select * the columns of
+//  the row sorted by name
+func setRowValues(msg datasource.Message, cols []string) []value.Value {
+	reader, ok := msg.Body().(expr.ContextReader)
+	if !ok {
+		logging.Warnf("could not convert to message reader: %T", msg.Body())
+		return nil
+	}
+	if cols == nil {
+		row := reader.Row()
+		cols = make([]string, 0, len(row))
+		for col := range row {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+	}
+	vals := make([]value.Value, len(cols))
+	for i, col := range cols {
+		if v, ok := reader.Get(col); ok && v != nil {
+			vals[i] = v
+		} else {
+			vals[i] = value.NewNilValue()
+		}
+	}
+	return vals
+}
+
+// the hash key of row values, the HashValue of each as for the keys of
+//  GROUP BY and DISTINCT, so NULLs are equal to each other and the type is
+//  part of the key, 1 and "1" (or 1.0) differ
+func setRowKey(vals []value.Value) string {
+	var buf bytes.Buffer
+	for _, v := range vals {
+		buf.WriteString(value.HashValue(v))
+		buf.WriteByte(0)
+	}
+	return buf.String()
+}
@@ -0,0 +1,52 @@
+package exec
+
+import (
+	"time"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*SingleRow)(nil)
+
+	_ expr.ContextReader = (*noSourceRow)(nil)
+)
+
+// SingleRow is the source of a select without a FROM, it emits one empty
+//  row so the projection (and where) is evaluated exactly once
+//
+//     SELECT 1 + 1 AS two, now()
+type SingleRow struct {
+	*TaskBase
+}
+
+func NewSingleRow() *SingleRow {
+	return &SingleRow{TaskBase: NewTaskBase("SingleRow")}
+}
+
+func (m *SingleRow) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	select {
+	case m.msgOutCh <- &noSourceRow{ts: time.Now()}:
+	case <-m.SigChan():
+	}
+	return nil
+}
+
+// the row of a select without a FROM, there are no columns to resolve so
+//  an identity is its own text, ie the single quoted 'x' of  upper('x')
+type noSourceRow struct {
+	ts time.Time
+}
+
+func (m *noSourceRow) Key() uint64       { return 0 }
+func (m *noSourceRow) Body() interface{} { return m }
+func (m *noSourceRow) Ts() time.Time     { return m.ts }
+func (m *noSourceRow) Get(key string) (value.Value, bool) {
+	return value.NewStringValue(key), true
+}
+func (m *noSourceRow) Row() map[string]value.Value { return map[string]value.Value{} }
@@ -0,0 +1,141 @@
+package exec
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+// Sort is an ORDER BY task, it must buffer all messages from
+//  its input before emitting them in sorted order.   Strings
+//  are compared using the collation (nil = binary).  NULLs are placed
+//  per the column NULLS FIRST|LAST, by default they sort as the smallest
+//  value (first for ASC, last for DESC).
+type Sort struct {
+	*TaskBase
+	orderBy   expr.Columns
+	collation value.Collation
+}
+
+func NewSort(orderBy expr.Columns, coll value.Collation) *Sort {
+	if coll == nil {
+		coll = value.CollationBinary
+	}
+	return &Sort{
+		TaskBase:  NewTaskBase("Sort"),
+		orderBy:   orderBy,
+		collation: coll,
+	}
+}
+
+type sortRow struct {
+	msg  datasource.Message
+	keys []value.Value
+}
+
+func (m *Sort) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	rows := make([]*sortRow, 0)
+msgLoop:
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				break msgLoop
+			}
+			rows = append(rows, m.sortRow(msg))
+		case <-m.sigCh:
+			return nil
+		}
+	}
+
+	sort.Stable(&sortRows{rows: rows, sorter: m})
+
+	for _, row := range rows {
+		select {
+		case m.msgOutCh <- row.msg:
+		case <-m.sigCh:
+			return nil
+		}
+	}
+	return nil
+}
+
+// evaluate the order by expressions once per message
+func (m *Sort) sortRow(msg datasource.Message) *sortRow {
+	row := &sortRow{msg: msg, keys: make([]value.Value, len(m.orderBy))}
+	reader, ok := msg.Body().(expr.ContextReader)
+	if !ok {
+		logging.Warnf("could not convert to message reader: %T", msg.Body())
+		return row
+	}
+	for i, col := range m.orderBy {
+		if col.Expr == nil {
+			continue
+		}
+		if v, ok := vm.Eval(reader, col.Expr); ok {
+			row.keys[i] = v
+		}
+	}
+	return row
+}
+
+// Compare two rows by order by columns, returns -1, 0, 1
+func (m *Sort) compare(a, b *sortRow) int {
+	for i, col := range m.orderBy {
+		aNull, bNull := isNullKey(a.keys[i]), isNullKey(b.keys[i])
+		if aNull || bNull {
+			if aNull && bNull {
+				continue
+			}
+			if aNull == nullsFirst(col) {
+				return -1
+			}
+			return 1
+		}
+		c, err := value.CompareValues(a.keys[i], b.keys[i], m.collation)
+		if err != nil {
+			// un-comparable types, fall back to string form
+			c = m.collation.Compare(a.keys[i].ToString(), b.keys[i].ToString())
+		}
+		if c == 0 {
+			continue
+		}
+		if strings.ToLower(col.Order) == "desc" {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
+
+func isNullKey(v value.Value) bool {
+	return v == nil || v.Type() == value.NilType
+}
+
+// do nulls sort before non-null values for this order by column
+func nullsFirst(col *expr.Column) bool {
+	switch strings.ToUpper(col.Nulls) {
+	case "FIRST":
+		return true
+	case "LAST":
+		return false
+	}
+	return strings.ToLower(col.Order) != "desc"
+}
+
+type sortRows struct {
+	rows   []*sortRow
+	sorter *Sort
+}
+
+func (m *sortRows) Len() int           { return len(m.rows) }
+func (m *sortRows) Swap(i, j int)      { m.rows[i], m.rows[j] = m.rows[j], m.rows[i] }
+func (m *sortRows) Less(i, j int) bool { return m.sorter.compare(m.rows[i], m.rows[j]) < 0 }
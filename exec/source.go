@@ -7,44 +7,85 @@ import (
 	"sync"
 	//"time"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 	"github.com/araddon/qlbridge/vm"
 	//"github.com/mdmarek/topo"
 )
 
 var (
-	_ = u.EMPTY
-
 	// Ensure that we implement the Task Runner interface
 	// to ensure this can run in exec engine
 	_ TaskRunner = (*Source)(nil)
+	_ TaskRunner = (*ParallelSource)(nil)
 
 	// Ensure that our source plan implements Subvisitor
-	_ expr.SubVisitor = (*SourcePlan)(nil)
+	_ expr.SubVisitor    = (*SourcePlan)(nil)
+	_ expr.SourceVisitor = (*SourcePlan)(nil)
 )
 
 func NewSourcePlan(sql *expr.SqlSource) *SourcePlan {
 	return &SourcePlan{SqlSource: sql}
 }
 
+// SourcePlan is passed to a SourcePlanner's Accept, the from source and the
+//  select it is of, so the source may plan its scan of the statement
 type SourcePlan struct {
 	SqlSource *expr.SqlSource
+	Select    *expr.SqlSelect // the statement, nil for a join side
+}
+
+func (m *SourcePlan) From() *expr.SqlSource { return m.SqlSource }
+func (m *SourcePlan) Projection() expr.Columns {
+	if stmt := m.stmt(); stmt != nil {
+		return stmt.Columns
+	}
+	return nil
+}
+func (m *SourcePlan) Where() expr.Node {
+	if stmt := m.stmt(); stmt != nil && stmt.Where != nil {
+		return stmt.Where.Expr
+	}
+	return nil
+}
+func (m *SourcePlan) GroupBy() expr.Columns {
+	if stmt := m.stmt(); stmt != nil {
+		return stmt.GroupBy
+	}
+	return nil
+}
+func (m *SourcePlan) OrderBy() expr.Columns {
+	if stmt := m.stmt(); stmt != nil {
+		return stmt.OrderBy
+	}
+	return nil
+}
+
+// the statement on the source, a join side has been rewritten to its own
+//  sub-select of the source
+func (m *SourcePlan) stmt() *expr.SqlSelect {
+	if m.Select != nil {
+		return m.Select
+	}
+	if m.SqlSource != nil {
+		return m.SqlSource.Source
+	}
+	return nil
 }
 
 func (m *SourcePlan) Accept(sub expr.SubVisitor) (interface{}, error) {
-	u.Debugf("Accept %+v", sub)
+	logging.Debugf("Accept %+v", sub)
 	return nil, expr.ErrNotImplemented
 }
 func (m *SourcePlan) VisitSubselect(stmt *expr.SqlSource) (interface{}, error) {
-	u.Debugf("VisitSubselect %+v", stmt)
+	logging.Debugf("VisitSubselect %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *SourcePlan) VisitJoin(stmt *expr.SqlSource) (interface{}, error) {
-	u.Debugf("VisitJoin %+v", stmt)
+	logging.Debugf("VisitJoin %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
@@ -79,7 +120,15 @@ func NewSource(from *expr.SqlSource, source datasource.Scanner) *Source {
 func (m *Source) Copy() *Source { return &Source{} }
 
 func (m *Source) Close() error {
-	if closer, ok := m.source.(datasource.DataSource); ok {
+	var closer datasource.SourceConn
+	switch source := m.source.(type) {
+	case datasource.DataSource:
+		closer = source
+	case *searchScanner:
+		// the search of a conn, see searchPath
+		closer = source
+	}
+	if closer != nil {
 		if err := closer.Close(); err != nil {
 			return err
 		}
@@ -100,26 +149,136 @@ func (m *Source) Run(context *Context) error {
 	if !ok {
 		return fmt.Errorf("Does not implement Scanner: %T", m.source)
 	}
-	//u.Debugf("scanner: %T %v", scanner, scanner)
+	span := context.StartSpan("scan")
+	span.SetAttribute("table", m.from.Name)
+	rows := 0
+	defer func() {
+		span.SetAttribute("rows", rows)
+		span.End()
+	}()
+
+	//logging.Debugf("scanner: %T %v", scanner, scanner)
 	iter := scanner.CreateIterator(nil)
-	//u.Debugf("iter in source: %T  %#v", iter, iter)
+	//logging.Debugf("iter in source: %T  %#v", iter, iter)
 
 	for item := iter.Next(); item != nil; item = iter.Next() {
 
-		//u.Infof("In source Scanner iter %#v", item)
+		//logging.Infof("In source Scanner iter %#v", item)
 		select {
 		case <-m.SigChan():
-			u.Warnf("got signal quit")
+			logging.Warnf("got signal quit")
 			return nil
 		case m.msgOutCh <- item:
-			// continue
+			rows++
 		}
 
 	}
-	//u.Debugf("leaving source scanner")
+	//logging.Debugf("leaving source scanner")
+	return iterErr(iter)
+}
+
+// the error, if any, that ended the scan of @iter early, an Iterator
+//  that is not an ErrIterator ends only at the end of the rows
+func iterErr(iter datasource.Iterator) error {
+	if ei, ok := iter.(datasource.ErrIterator); ok {
+		return ei.Err()
+	}
 	return nil
 }
 
+// Scan many data sources (shards of the same table) concurrently, merging
+//   their messages into a single output channel.   At most @concurrency
+//   sources are scanned at once (<= 0 means all of them).
+//
+//   Messages from different sources are interleaved in arrival order, so this
+//   is only correct for downstream tasks that are insensitive to order
+//   (where, projection, group by etc), sorting must happen afterwards.
+type ParallelSource struct {
+	*TaskBase
+	from        *expr.SqlSource
+	sources     []datasource.Scanner
+	concurrency int
+}
+
+// A parallel scanner to read from many sources
+func NewParallelSource(from *expr.SqlSource, sources []datasource.Scanner, concurrency int) *ParallelSource {
+	if concurrency <= 0 || concurrency > len(sources) {
+		concurrency = len(sources)
+	}
+	m := &ParallelSource{
+		TaskBase:    NewTaskBase("ParallelSource"),
+		from:        from,
+		sources:     sources,
+		concurrency: concurrency,
+	}
+	return m
+}
+
+func (m *ParallelSource) Close() error {
+	errs := make(errList, 0)
+	for _, source := range m.sources {
+		if closer, ok := source.(datasource.DataSource); ok {
+			errs.append(closer.Close())
+		}
+	}
+	errs.append(m.TaskBase.Close())
+	return errs.error()
+}
+
+func (m *ParallelSource) Run(context *Context) error {
+	defer context.Recover() // Our context can recover panics, save error msg
+	defer close(m.msgOutCh) // closing input channels is the signal to stop
+
+	// the sig chan only delivers to one listener, so fan it out
+	// to all scanners by closing quit
+	quit := make(chan bool)
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-m.SigChan():
+			logging.Warnf("got signal quit")
+			close(quit)
+		case <-done:
+		}
+	}()
+
+	slots := make(chan bool, m.concurrency)
+	wg := new(sync.WaitGroup)
+	errs := make(errList, 0)
+	errMu := new(sync.Mutex)
+	for i, source := range m.sources {
+		wg.Add(1)
+		go func(shard int, scanner datasource.Scanner) {
+			defer wg.Done()
+			select {
+			case slots <- true:
+			case <-quit:
+				return
+			}
+			defer func() { <-slots }()
+
+			iter := scanner.CreateIterator(nil)
+			for item := iter.Next(); item != nil; item = iter.Next() {
+				select {
+				case <-quit:
+					return
+				case m.msgOutCh <- item:
+					// continue
+				}
+			}
+			if err := iterErr(iter); err != nil {
+				errMu.Lock()
+				errs.append(fmt.Errorf("shard %d: %v", shard, err))
+				errMu.Unlock()
+			}
+			//logging.Debugf("finished shard %d", shard)
+		}(i, source)
+	}
+	wg.Wait()
+	return errs.error()
+}
+
 // Scan a data source for rows, feed into runner for join sources
 //
 //  1) join  SELECT t1.name, t2.salary
@@ -147,9 +306,9 @@ func NewSourceJoin(builder expr.SubVisitor, leftFrom, rightFrom *expr.SqlSource,
 	m.leftStmt = leftFrom
 	m.rightStmt = rightFrom
 
-	u.Debugf("leftFrom.Name:'%v' : %v", leftFrom.Name, leftFrom.Source.StringAST())
+	logging.Debugf("leftFrom.Name:'%v' : %v", leftFrom.Name, leftFrom.Source.StringAST())
 	source := conf.Conn(leftFrom.Name)
-	u.Debugf("left source: %T", source)
+	logging.Debugf("left source: %T", source)
 	// Must provider either Scanner, SourcePlanner, Seeker interfaces
 	if sourcePlan, ok := source.(datasource.SourcePlanner); ok {
 		//  This is flawed, visitor pattern would have you pass in a object which implements interface
@@ -159,27 +318,27 @@ func NewSourceJoin(builder expr.SubVisitor, leftFrom, rightFrom *expr.SqlSource,
 		// plan := NewSourcePlan(leftFrom)
 		// op, err := plan.Accept(sourcePlan)
 		if err != nil {
-			u.Errorf("Could not source plan for %v  %T %#v", leftFrom.Name, source, source)
+			logging.Errorf("Could not source plan for %v  %T %#v", leftFrom.Name, source, source)
 		}
-		//u.Debugf("got op: %T  %#v", op, op)
+		//logging.Debugf("got op: %T  %#v", op, op)
 		if scanner, ok := op.(datasource.Scanner); !ok {
-			u.Errorf("Could not create scanner for %v  %T %#v", leftFrom.Name, op, op)
+			logging.Errorf("Could not create scanner for %v  %T %#v", leftFrom.Name, op, op)
 			return nil, fmt.Errorf("Must Implement Scanner")
 		} else {
 			m.leftSource = scanner
 		}
 	} else {
 		if scanner, ok := source.(datasource.Scanner); !ok {
-			u.Errorf("Could not create scanner for %v  %T %#v", leftFrom.Name, source, source)
+			logging.Errorf("Could not create scanner for %v  %T %#v", leftFrom.Name, source, source)
 			return nil, fmt.Errorf("Must Implement Scanner")
 		} else {
 			m.leftSource = scanner
 		}
 	}
 
-	u.Debugf("right:  Name:'%v' : %v", rightFrom.Name, rightFrom.Source.String())
+	logging.Debugf("right:  Name:'%v' : %v", rightFrom.Name, rightFrom.Source.String())
 	source2 := conf.Conn(rightFrom.Name)
-	u.Debugf("source right: %T", source2)
+	logging.Debugf("source right: %T", source2)
 	// Must provider either Scanner, and or Seeker interfaces
 
 	// Must provider either Scanner, SourcePlanner, Seeker interfaces
@@ -191,18 +350,18 @@ func NewSourceJoin(builder expr.SubVisitor, leftFrom, rightFrom *expr.SqlSource,
 		// plan := NewSourcePlan(rightFrom)
 		// op, err := plan.Accept(sourcePlan)
 		if err != nil {
-			u.Errorf("Could not source plan for %v  %T %#v", rightFrom.Name, source2, source2)
+			logging.Errorf("Could not source plan for %v  %T %#v", rightFrom.Name, source2, source2)
 		}
-		//u.Debugf("got op: %T  %#v", op, op)
+		//logging.Debugf("got op: %T  %#v", op, op)
 		if scanner, ok := op.(datasource.Scanner); !ok {
-			u.Errorf("Could not create scanner for %v  %T %#v", rightFrom.Name, op, op)
+			logging.Errorf("Could not create scanner for %v  %T %#v", rightFrom.Name, op, op)
 			return nil, fmt.Errorf("Must Implement Scanner")
 		} else {
 			m.rightSource = scanner
 		}
 	} else {
 		if scanner, ok := source2.(datasource.Scanner); !ok {
-			u.Errorf("Could not create scanner for %v  %T %#v", rightFrom.Name, source2, source2)
+			logging.Errorf("Could not create scanner for %v  %T %#v", rightFrom.Name, source2, source2)
 			return nil, fmt.Errorf("Must Implement Scanner")
 		} else {
 			m.rightSource = scanner
@@ -235,16 +394,17 @@ func (m *SourceJoin) Run(context *Context) error {
 	defer context.Recover() // Our context can recover panics, save error msg
 	defer close(m.msgOutCh) // closing input channels is the signal to stop
 
-	//u.Infof("Run():  %T %#v", m.leftSource, m.leftSource)
-	leftIn := m.leftSource.MesgChan(nil)
-	rightIn := m.rightSource.MesgChan(nil)
+	//logging.Infof("Run():  %T %#v", m.leftSource, m.leftSource)
+	// iterate directly in our own goroutines rather than MesgChan() which
+	// would start another goroutine per source
+	leftIter := m.leftSource.CreateIterator(nil)
+	rightIter := m.rightSource.CreateIterator(nil)
 
-	//u.Warnf("leftSource: %p  rightSource: %p", m.leftSource, m.rightSource)
-	//u.Warnf("leftIn: %p  rightIn: %p", leftIn, rightIn)
+	//logging.Warnf("leftSource: %p  rightSource: %p", m.leftSource, m.rightSource)
 	outCh := m.MessageOut()
 
-	//u.Infof("Checking leftStmt:  %#v", m.leftStmt)
-	//u.Infof("Checking rightStmt:  %#v", m.rightStmt)
+	//logging.Infof("Checking leftStmt:  %#v", m.leftStmt)
+	//logging.Infof("Checking rightStmt:  %#v", m.rightStmt)
 	lhExpr, err := m.leftStmt.JoinValueExpr()
 	if err != nil {
 		return err
@@ -255,8 +415,8 @@ func (m *SourceJoin) Run(context *Context) error {
 	}
 	lcols := m.leftStmt.UnAliasedColumns()
 	rcols := m.rightStmt.UnAliasedColumns()
-	u.Infof("lcols:  %#v for sql %s", lcols, m.leftStmt.Source.String())
-	u.Infof("rcols:  %#v for sql %v", rcols, m.rightStmt.Source.String())
+	logging.Infof("lcols:  %#v for sql %s", lcols, m.leftStmt.Source.String())
+	logging.Infof("rcols:  %#v for sql %v", rcols, m.rightStmt.Source.String())
 	lh := make(map[string][]datasource.Message)
 	rh := make(map[string][]datasource.Message)
 	/*
@@ -270,65 +430,66 @@ func (m *SourceJoin) Run(context *Context) error {
 			- manage the coordination of draining both/channels
 			- evaluate hashes/output
 	*/
+	quit := make(chan bool)
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-m.SigChan():
+			logging.Warnf("got signal quit")
+			close(quit)
+		case <-done:
+		}
+	}()
 	wg := new(sync.WaitGroup)
+	var leftErr, rightErr error
 	wg.Add(1)
 	go func() {
-		for {
-			//u.Infof("In source Scanner iter %#v", item)
+		defer wg.Done()
+		defer func() { leftErr = iterErr(leftIter) }()
+		for msg := leftIter.Next(); msg != nil; msg = leftIter.Next() {
 			select {
-			case <-m.SigChan():
-				u.Warnf("got signal quit")
+			case <-quit:
 				return
-			case msg, ok := <-leftIn:
-				if !ok {
-					//u.Warnf("NICE, got left shutdown")
-					wg.Done()
-					return
-				} else {
-
-					if jv, ok := joinValue(nil, lhExpr, msg, lcols); ok {
-						//u.Debugf("left eval?:%v     %#v", jv, msg.Body())
-						lh[jv] = append(lh[jv], msg)
-					} else {
-						u.Warnf("Could not evaluate? %v msg=%v", lhExpr.String(), msg.Body())
-					}
-				}
+			default:
+			}
+			if jv, ok := joinValue(nil, lhExpr, msg, lcols); ok {
+				//logging.Debugf("left eval?:%v     %#v", jv, msg.Body())
+				lh[jv] = append(lh[jv], msg)
+			} else {
+				logging.Warnf("Could not evaluate? %v msg=%v", lhExpr.String(), msg.Body())
 			}
-
 		}
 	}()
 	wg.Add(1)
 	go func() {
-		for {
-
-			//u.Infof("In source Scanner iter %#v", item)
+		defer wg.Done()
+		defer func() { rightErr = iterErr(rightIter) }()
+		for msg := rightIter.Next(); msg != nil; msg = rightIter.Next() {
 			select {
-			case <-m.SigChan():
-				u.Warnf("got signal quit")
+			case <-quit:
 				return
-			case msg, ok := <-rightIn:
-				if !ok {
-					//u.Warnf("NICE, got right shutdown")
-					wg.Done()
-					return
-				} else {
-					if jv, ok := joinValue(nil, rhExpr, msg, rcols); ok {
-						//u.Debugf("right val:%v     %#v", jv, msg.Body())
-						rh[jv] = append(rh[jv], msg)
-					} else {
-						u.Warnf("Could not evaluate? %v msg=%v", rhExpr.String(), msg.Body())
-					}
-				}
+			default:
+			}
+			if jv, ok := joinValue(nil, rhExpr, msg, rcols); ok {
+				//logging.Debugf("right val:%v     %#v", jv, msg.Body())
+				rh[jv] = append(rh[jv], msg)
+			} else {
+				logging.Warnf("Could not evaluate? %v msg=%v", rhExpr.String(), msg.Body())
 			}
-
 		}
 	}()
 	wg.Wait()
-	//u.Info("leaving source scanner")
+	if leftErr != nil {
+		return leftErr
+	} else if rightErr != nil {
+		return rightErr
+	}
+	//logging.Info("leaving source scanner")
 	i := uint64(0)
 	for keyLeft, valLeft := range lh {
 		if valRight, ok := rh[keyLeft]; ok {
-			//u.Infof("found match?\n\t%d left=%v\n\t%d right=%v", len(valLeft), valLeft, len(valRight), valRight)
+			//logging.Infof("found match?\n\t%d left=%v\n\t%d right=%v", len(valLeft), valLeft, len(valRight), valRight)
 			msgs := mergeValuesMsgs(valLeft, valRight, m.leftStmt.Columns, m.rightStmt.Columns, nil)
 			for _, msg := range msgs {
 				//outCh <- datasource.NewUrlValuesMsg(i, msg)
@@ -344,42 +505,42 @@ func (m *SourceJoin) Run(context *Context) error {
 func joinValue(ctx *Context, node expr.Node, msg datasource.Message, cols map[string]*expr.Column) (string, bool) {
 
 	if msg == nil {
-		u.Warnf("got nil message?")
+		logging.Warnf("got nil message?")
 	}
-	//u.Infof("got message: %T  %#v", msg, cols)
+	//logging.Infof("got message: %T  %#v", msg, cols)
 	switch mt := msg.(type) {
 	case *datasource.SqlDriverMessage:
 		msgReader := datasource.NewValueContextWrapper(mt, cols)
 		joinVal, ok := vm.Eval(msgReader, node)
-		//u.Debugf("msg: %#v", msgReader)
-		//u.Infof("evaluating: ok?%v T:%T result=%v node '%v'", ok, joinVal, joinVal.ToString(), node.String())
+		//logging.Debugf("msg: %#v", msgReader)
+		//logging.Infof("evaluating: ok?%v T:%T result=%v node '%v'", ok, joinVal, joinVal.ToString(), node.String())
 		if !ok {
-			u.Errorf("could not evaluate: %T %#v   %v", joinVal, joinVal, msg)
+			logging.Errorf("could not evaluate: %T %#v   %v", joinVal, joinVal, msg)
 			return "", false
 		}
 		switch val := joinVal.(type) {
 		case value.StringValue:
 			return val.Val(), true
 		default:
-			u.Warnf("unknown type? %T", joinVal)
+			logging.Warnf("unknown type? %T", joinVal)
 		}
 	default:
 		if msgReader, ok := msg.Body().(expr.ContextReader); ok {
 			joinVal, ok := vm.Eval(msgReader, node)
-			//u.Debugf("msg: %#v", msgReader)
-			//u.Infof("evaluating: ok?%v T:%T result=%v node expr:%v", ok, joinVal, joinVal.ToString(), node.StringAST())
+			//logging.Debugf("msg: %#v", msgReader)
+			//logging.Infof("evaluating: ok?%v T:%T result=%v node expr:%v", ok, joinVal, joinVal.ToString(), node.StringAST())
 			if !ok {
-				u.Errorf("could not evaluate: %v", msg)
+				logging.Errorf("could not evaluate: %v", msg)
 				return "", false
 			}
 			switch val := joinVal.(type) {
 			case value.StringValue:
 				return val.Val(), true
 			default:
-				u.Warnf("unknown type? %T", joinVal)
+				logging.Warnf("unknown type? %T", joinVal)
 			}
 		} else {
-			u.Errorf("could not convert to message reader: %T", msg.Body())
+			logging.Errorf("could not convert to message reader: %T", msg.Body())
 		}
 	}
 
@@ -395,20 +556,20 @@ func mergeUvMsgs(lmsgs, rmsgs []datasource.Message, lcols, rcols map[string]*exp
 				switch rmt := rm.Body().(type) {
 				case *datasource.ContextUrlValues:
 					// for k, val := range rmt.Data {
-					// 	u.Debugf("k=%v v=%v", k, val)
+					// 	logging.Debugf("k=%v v=%v", k, val)
 					// }
 					newMsg := datasource.NewContextUrlValues(url.Values{})
 					newMsg = reAlias(newMsg, lmt.Data, lcols)
 					newMsg = reAlias(newMsg, rmt.Data, rcols)
-					//u.Debugf("pre:  %#v", lmt.Data)
-					//u.Debugf("post:  %#v", newMsg.Data)
+					//logging.Debugf("pre:  %#v", lmt.Data)
+					//logging.Debugf("post:  %#v", newMsg.Data)
 					out = append(out, newMsg)
 				default:
-					u.Warnf("uknown type: %T", rm)
+					logging.Warnf("uknown type: %T", rm)
 				}
 			}
 		default:
-			u.Warnf("uknown type: %T   %T", lmt, lm)
+			logging.Warnf("uknown type: %T   %T", lmt, lm)
 		}
 	}
 	return out
@@ -419,25 +580,25 @@ func mergeValuesMsgs(lmsgs, rmsgs []datasource.Message, lcols, rcols []*expr.Col
 	for _, lm := range lmsgs {
 		switch lmt := lm.(type) {
 		case *datasource.SqlDriverMessage:
-			//u.Warnf("got sql driver message: %#v", lmt.Vals)
+			//logging.Warnf("got sql driver message: %#v", lmt.Vals)
 			for _, rm := range rmsgs {
 				switch rmt := rm.(type) {
 				case *datasource.SqlDriverMessage:
 					// for k, val := range rmt.Vals {
-					// 	u.Debugf("k=%v v=%v", k, val)
+					// 	logging.Debugf("k=%v v=%v", k, val)
 					// }
 					newMsg := datasource.NewSqlDriverMessageMap()
 					newMsg = reAlias2(newMsg, lmt.Vals, lcols)
 					newMsg = reAlias2(newMsg, rmt.Vals, rcols)
-					//u.Debugf("pre:  %#v", lmt.Vals)
-					//u.Debugf("newMsg:  %#v", newMsg.Vals)
+					//logging.Debugf("pre:  %#v", lmt.Vals)
+					//logging.Debugf("newMsg:  %#v", newMsg.Vals)
 					out = append(out, newMsg)
 				default:
-					u.Warnf("uknown type: %T", rm)
+					logging.Warnf("uknown type: %T", rm)
 				}
 			}
 		default:
-			u.Warnf("uknown type: %T   %T", lmt, lm)
+			logging.Warnf("uknown type: %T   %T", lmt, lm)
 		}
 	}
 	return out
@@ -446,7 +607,7 @@ func mergeValuesMsgs(lmsgs, rmsgs []datasource.Message, lcols, rcols []*expr.Col
 func mergeUv(m1, m2 *datasource.ContextUrlValues) *datasource.ContextUrlValues {
 	out := datasource.NewContextUrlValues(m1.Data)
 	for k, val := range m2.Data {
-		//u.Debugf("k=%v v=%v", k, val)
+		//logging.Debugf("k=%v v=%v", k, val)
 		out.Data[k] = val
 	}
 	return out
@@ -454,9 +615,9 @@ func mergeUv(m1, m2 *datasource.ContextUrlValues) *datasource.ContextUrlValues {
 func reAlias(m *datasource.ContextUrlValues, vals url.Values, cols map[string]*expr.Column) *datasource.ContextUrlValues {
 	for k, val := range vals {
 		if col, ok := cols[k]; !ok {
-			u.Warnf("Should not happen? missing %v  ", k)
+			logging.Warnf("Should not happen? missing %v  ", k)
 		} else {
-			//u.Infof("found: k=%v as=%v   val=%v", k, col.As, val)
+			//logging.Infof("found: k=%v as=%v   val=%v", k, col.As, val)
 			m.Data[col.As] = val
 		}
 	}
@@ -465,11 +626,11 @@ func reAlias(m *datasource.ContextUrlValues, vals url.Values, cols map[string]*e
 func reAlias2(m *datasource.SqlDriverMessageMap, vals []driver.Value, cols []*expr.Column) *datasource.SqlDriverMessageMap {
 	for i, val := range vals {
 		if i >= len(cols) {
-			//u.Warnf("not enough cols? i=%v len(cols)=%v  %#v", i, len(cols), cols)
+			//logging.Warnf("not enough cols? i=%v len(cols)=%v  %#v", i, len(cols), cols)
 			continue
 		}
 		col := cols[i]
-		//u.Infof("found: i=%v as=%v   val=%v", i, col.As, val)
+		//logging.Infof("found: i=%v as=%v   val=%v", i, col.As, val)
 		m.Vals[col.As] = val
 	}
 	return m
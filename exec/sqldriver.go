@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -12,9 +13,9 @@ import (
 	"sync"
 	"time"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
@@ -37,7 +38,6 @@ var (
 	rtConf = datasource.NewRuntimeConfig()
 
 	// hm
-	_ = u.EMPTY
 )
 
 const (
@@ -70,7 +70,7 @@ type qlbdriver struct{}
 //
 // The returned connection is only used by one goroutine at a time.
 func (m *qlbdriver) Open(connInfo string) (driver.Conn, error) {
-	u.Infof("qlbdriver.Open():  %v", connInfo)
+	logging.Infof("qlbdriver.Open():  %v", connInfo)
 	rtConf.SetConnInfo(connInfo)
 	return &qlbConn{rtConf: rtConf, conn: connInfo}, nil
 }
@@ -124,7 +124,7 @@ func (m *qlbConn) Prepare(query string) (driver.Stmt, error) {
 // idle connections, it shouldn't be necessary for drivers to
 // do their own connection caching.
 func (m *qlbConn) Close() error {
-	u.Debugf("do we need to do anything here?   job.Close()?")
+	logging.Debugf("do we need to do anything here?   job.Close()?")
 	return nil
 }
 
@@ -189,7 +189,7 @@ func (m *qlbStmt) Query(args []driver.Value) (driver.Rows, error) {
 			return nil, err
 		}
 	}
-	u.Infof("query: %v", m.query)
+	logging.Infof("query: %v", m.query)
 
 	// Create a Job, which is Dag of Tasks that Run()
 	job, err := BuildSqlJob(m.conn.rtConf, m.conn.conn, m.query)
@@ -216,16 +216,16 @@ func (m *qlbStmt) Query(args []driver.Value) (driver.Rows, error) {
 	// TODO:   this can't run in parallel-buffered mode?
 	// how to open in go-routine and still be able to send error to rows?
 	go func() {
-		//u.Debugf("Start Job.Run")
-		err = job.Run()
-		//u.Debugf("After job.Run()")
+		//logging.Debugf("Start Job.Run")
+		err = job.Run(context.Background())
+		//logging.Debugf("After job.Run()")
 		if err != nil {
-			u.Errorf("error on Query.Run(): %v", err)
+			logging.Errorf("error on Query.Run(): %v", err)
 			//resultWriter.ErrChan() <- err
 			//job.Close()
 		}
 		//job.Close()
-		//u.Debugf("exiting Background Query")
+		//logging.Debugf("exiting Background Query")
 	}()
 
 	return resultWriter, nil
@@ -0,0 +1,95 @@
+package exec
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+var _ expr.ContextSubQuery = (*subQueries)(nil)
+
+// the (uncorrelated) sub-selects of an expression, ie of a HAVING, each is
+//  planned and run once, the first time it is evaluated, and its rows are
+//  kept for the evaluation of every later row
+//
+//     HAVING count(*) > (SELECT avg(ct) FROM daily)
+type subQueries struct {
+	builder *JobBuilder
+	ctx     *Context
+	rows    map[string][][]value.Value
+}
+
+// a message read with the sub-selects of the expression evaluated on it
+type subQueryReader struct {
+	expr.ContextReader
+	*subQueries
+}
+
+func (m *subQueryReader) Collation() value.Collation {
+	if cr, ok := m.ContextReader.(expr.ContextCollation); ok {
+		return cr.Collation()
+	}
+	return nil
+}
+
+func newSubQueries(builder *JobBuilder, ctx *Context) *subQueries {
+	return &subQueries{builder: builder, ctx: ctx, rows: make(map[string][][]value.Value)}
+}
+
+func (m *subQueries) SubQuery(stmt *expr.SqlSelect) ([][]value.Value, error) {
+	if m.builder == nil {
+		return nil, fmt.Errorf("sub-query not supported here: %s", stmt)
+	}
+	key := stmt.String()
+	if rows, ok := m.rows[key]; ok {
+		return rows, nil
+	}
+	rows, err := m.run(stmt)
+	if err != nil {
+		return nil, err
+	}
+	m.rows[key] = rows
+	return rows, nil
+}
+
+// plan and run the sub-select, its rows are the values of its columns
+func (m *subQueries) run(stmt *expr.SqlSelect) ([][]value.Value, error) {
+	// sub-selects are not the job output, so are not capped
+	maxRows := m.builder.MaxRows
+	m.builder.MaxRows = 0
+	subTasks, err := m.builder.VisitSelect(stmt)
+	m.builder.MaxRows = maxRows
+	if err != nil {
+		return nil, err
+	}
+	tasks := subTasks.(Tasks)
+	defer func() {
+		for _, task := range tasks {
+			task.Close()
+		}
+	}()
+
+	msgs := make([]datasource.Message, 0)
+	tasks.Add(NewResultBuffer(&msgs))
+	if err := SetupTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := runTasks(m.ctx.runContext(), m.ctx, tasks); err != nil {
+		return nil, err
+	}
+	rows := make([][]value.Value, len(msgs))
+	for i, msg := range msgs {
+		reader, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			return nil, fmt.Errorf("could not convert to message reader: %T", msg.Body())
+		}
+		row := make([]value.Value, len(stmt.Columns))
+		for ci, col := range stmt.Columns {
+			row[ci], _ = reader.Get(col.Key())
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
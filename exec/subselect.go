@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+// SubSelect is the source of an outer query for a derived table, it
+//  forwards the output rows of the inner select tasks exposing their
+//  columns under the table alias, so both  t.col  and  col  resolve.
+//
+//     SELECT t.user_id FROM (SELECT user_id FROM users) AS t
+type SubSelect struct {
+	*TaskBase
+	from *expr.SqlSource
+}
+
+func NewSubSelect(from *expr.SqlSource) *SubSelect {
+	s := &SubSelect{
+		TaskBase: NewTaskBase("SubSelect"),
+		from:     from,
+	}
+	prefix := ""
+	if from.Alias != "" {
+		prefix = from.Alias + "."
+	}
+	s.Handler = subSelectHandler(prefix, s)
+	return s
+}
+
+func subSelectHandler(prefix string, task TaskRunner) MessageHandler {
+	out := task.MessageOut()
+	return func(ctx *Context, msg datasource.Message) bool {
+		reader, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			logging.Warnf("could not convert to message reader: %T", msg.Body())
+			return true
+		}
+		select {
+		case out <- &derivedRow{ContextReader: reader, prefix: prefix, key: msg.Key()}:
+			return true
+		case <-task.SigChan():
+			return false
+		}
+	}
+}
+
+// a row of a derived table, reads of  alias.col  are of the inner  col
+type derivedRow struct {
+	expr.ContextReader
+	prefix string
+	key    uint64
+}
+
+func (m *derivedRow) Key() uint64       { return m.key }
+func (m *derivedRow) Body() interface{} { return m }
+func (m *derivedRow) Get(key string) (value.Value, bool) {
+	if m.prefix != "" && strings.HasPrefix(key, m.prefix) {
+		if v, ok := m.ContextReader.Get(key[len(m.prefix):]); ok {
+			return v, ok
+		}
+	}
+	return m.ContextReader.Get(key)
+}
@@ -0,0 +1,107 @@
+package exec
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*TableFunc)(nil)
+)
+
+// TableFunc is the task of a table valued func FROM entry, ie unnest(tags)
+//  As the first task (no input) the func is evaluated once, otherwise it
+//  is lateral:  evaluated per input row, emitting the input row plus the
+//  alias column once for each value the func produces.
+//
+//     SELECT name, t FROM users, unnest(tags) AS t
+type TableFunc struct {
+	*TaskBase
+	from *expr.SqlSource
+}
+
+func NewTableFunc(from *expr.SqlSource) (*TableFunc, error) {
+	fn, ok := expr.TableFuncGet(from.Func.Name)
+	if !ok {
+		return nil, fmt.Errorf("No table func found for %v", from.Func.Name)
+	}
+	m := &TableFunc{
+		TaskBase: NewTaskBase("TableFunc"),
+		from:     from,
+	}
+	alias := from.Alias
+	if alias == "" {
+		alias = from.Func.Name
+	}
+	m.Handler = tableFuncHandler(from.Func, fn, alias, m)
+	return m, nil
+}
+
+func (m *TableFunc) Run(ctx *Context) error {
+	if m.msgInCh != nil {
+		return m.TaskBase.Run(ctx)
+	}
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	// not lateral, evaluate against an empty row
+	m.Handler(ctx, datasource.NewContextSimple())
+	select {
+	case err := <-m.errCh:
+		return err
+	default:
+	}
+	return nil
+}
+
+func tableFuncHandler(node *expr.FuncNode, fn expr.TableFunc, alias string, task TaskRunner) MessageHandler {
+	out := task.MessageOut()
+	return func(ctx *Context, msg datasource.Message) bool {
+		row, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			return rowError(ctx, task, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
+		}
+		args := make([]value.Value, len(node.Args))
+		for i, arg := range node.Args {
+			v, ok := vm.Eval(row, arg)
+			if !ok || v == nil {
+				v = value.NewNilValue()
+			}
+			args[i] = v
+		}
+		vals, err := fn(row, args)
+		if err != nil {
+			return rowError(ctx, task, msg, err)
+		}
+		if len(vals) == 0 {
+			return true
+		}
+
+		// the rows of this input row share their columns
+		outer := row.Row()
+		names := make([]string, 0, len(outer)+1)
+		for col := range outer {
+			names = append(names, col)
+		}
+		names = append(names, alias)
+		cols := datasource.ColumnIndex(names)
+		for _, v := range vals {
+			rowVals := make([]value.Value, len(names))
+			for col, ov := range outer {
+				rowVals[cols[col]] = ov
+			}
+			rowVals[cols[alias]] = v
+			select {
+			case out <- datasource.NewRow(msg.Key(), cols, rowVals):
+			case <-task.SigChan():
+				return false
+			}
+		}
+		return true
+	}
+}
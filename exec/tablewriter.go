@@ -0,0 +1,236 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*TableWriter)(nil)
+)
+
+// TableWriter is a result task for the CLI/REPL, it buffers all rows and
+//  once the input is done writes them as an aligned ascii table, columns
+//  sized to their content, numbers right aligned
+//
+//     +------+----+
+//     | name | ct |
+//     +------+----+
+//     | bob  | 12 |
+//     | NULL |  3 |
+//     +------+----+
+//     2 rows
+//
+type TableWriter struct {
+	*TaskBase
+	// MaxWidth truncates cells wider than it (display width), 0 = no limit
+	MaxWidth int
+	w        io.Writer
+	cols     []string
+}
+
+// NewTableWriter writes the result as a table to @w, @cols are the columns
+//  in order, if empty the sorted column names of the rows are used
+func NewTableWriter(w io.Writer, cols []string) *TableWriter {
+	return &TableWriter{
+		TaskBase: NewTaskBase("TableWriter"),
+		w:        w,
+		cols:     cols,
+	}
+}
+
+func (m *TableWriter) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	rows := make([]map[string]value.Value, 0)
+msgLoop:
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				break msgLoop
+			}
+			reader, ok := msg.Body().(expr.ContextReader)
+			if !ok {
+				if err := ctx.RowError(msg, fmt.Errorf("could not convert to message reader: %T", msg.Body())); err != nil {
+					return err
+				}
+				continue
+			}
+			rows = append(rows, reader.Row())
+		case <-m.sigCh:
+			return nil
+		}
+	}
+
+	cols := m.cols
+	if len(cols) == 0 {
+		cols = rowColumns(rows)
+	}
+	vals := make([][]value.Value, len(rows))
+	for i, row := range rows {
+		vals[i] = make([]value.Value, len(cols))
+		for j, col := range cols {
+			vals[i][j] = row[col]
+		}
+	}
+	return WriteTable(m.w, cols, vals, m.MaxWidth)
+}
+
+// the sorted names of all columns of @rows
+func rowColumns(rows []map[string]value.Value) []string {
+	seen := make(map[string]struct{})
+	cols := make([]string, 0)
+	for _, row := range rows {
+		for col := range row {
+			if _, ok := seen[col]; !ok {
+				seen[col] = struct{}{}
+				cols = append(cols, col)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// WriteTable writes @rows as an ascii table with a header of @cols, a nil
+//  value is NULL.  Cells wider than @maxWidth are truncated (0 = no limit).
+func WriteTable(w io.Writer, cols []string, rows [][]value.Value, maxWidth int) error {
+	cells := make([][]string, len(rows))
+	right := make([]bool, len(cols))
+	widths := make([]int, len(cols))
+	header := make([]string, len(cols))
+	for j, col := range cols {
+		header[j] = truncateCell(printable(col), maxWidth)
+		widths[j] = displayWidth(header[j])
+	}
+	for i, row := range rows {
+		cells[i] = make([]string, len(cols))
+		for j := range cols {
+			var v value.Value
+			if j < len(row) {
+				v = row[j]
+			}
+			cell := "NULL"
+			if v != nil {
+				cell = v.String()
+				switch v.Type() {
+				case value.IntType, value.NumberType:
+					right[j] = true
+				}
+			}
+			cell = truncateCell(printable(cell), maxWidth)
+			cells[i][j] = cell
+			if cw := displayWidth(cell); cw > widths[j] {
+				widths[j] = cw
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	sep := tableSeparator(widths)
+	buf.WriteString(sep)
+	writeTableRow(&buf, header, widths, nil)
+	buf.WriteString(sep)
+	for _, row := range cells {
+		writeTableRow(&buf, row, widths, right)
+	}
+	buf.WriteString(sep)
+	if len(rows) == 1 {
+		buf.WriteString("1 row\n")
+	} else {
+		fmt.Fprintf(&buf, "%d rows\n", len(rows))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func tableSeparator(widths []int) string {
+	var buf bytes.Buffer
+	for _, width := range widths {
+		buf.WriteString("+")
+		buf.WriteString(strings.Repeat("-", width+2))
+	}
+	buf.WriteString("+\n")
+	return buf.String()
+}
+
+func writeTableRow(buf *bytes.Buffer, cells []string, widths []int, right []bool) {
+	for j, cell := range cells {
+		pad := strings.Repeat(" ", widths[j]-displayWidth(cell))
+		buf.WriteString("| ")
+		if right != nil && right[j] {
+			buf.WriteString(pad)
+			buf.WriteString(cell)
+		} else {
+			buf.WriteString(cell)
+			buf.WriteString(pad)
+		}
+		buf.WriteString(" ")
+	}
+	buf.WriteString("|\n")
+}
+
+// control characters (newlines, tabs) would break the table layout
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// truncate @s to @maxWidth display columns, ending in an ellipsis
+func truncateCell(s string, maxWidth int) string {
+	if maxWidth <= 0 || displayWidth(s) <= maxWidth {
+		return s
+	}
+	width := 0
+	for i, r := range s {
+		rw := runeWidth(r)
+		if width+rw > maxWidth-1 {
+			return s[:i] + "…"
+		}
+		width += rw
+	}
+	return s
+}
+
+// the terminal columns of @s, east asian wide runes are 2, combining
+//  marks 0
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), r == '\u200b':
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // hangul jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // cjk ... yi
+		r >= 0xac00 && r <= 0xd7a3,                // hangul syllables
+		r >= 0xf900 && r <= 0xfaff,                // cjk compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f,                // cjk compatibility forms
+		r >= 0xff00 && r <= 0xff60,                // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func TestWriteTable(t *testing.T) {
+
+	var buf bytes.Buffer
+	err := WriteTable(&buf, []string{"name", "ct", "note"}, [][]value.Value{
+		{value.NewStringValue("bob"), value.NewIntValue(12), value.NewStringValue("ok")},
+		{value.NewStringValue("日本語"), value.NewNumberValue(3.5), nil},
+		{value.NewNilValue(), value.NewIntValue(-1), value.NewStringValue("two\nlines")},
+		{value.NewStringValue("cafe\u0301"), value.NewNilValue(), value.NewStringValue("")},
+	}, 0)
+	assert.Tf(t, err == nil, "no error %v", err)
+	// cafe with a combining accent is 4 wide, the cjk 6, NULL in any column
+	assert.Equal(t, "+--------+------+-----------+\n"+
+		"| name   | ct   | note      |\n"+
+		"+--------+------+-----------+\n"+
+		"| bob    |   12 | ok        |\n"+
+		"| 日本語 |  3.5 | NULL      |\n"+
+		"| NULL   |   -1 | two lines |\n"+
+		"| cafe\u0301   | NULL |           |\n"+
+		"+--------+------+-----------+\n"+
+		"4 rows\n", buf.String())
+
+	// wide content is truncated to the max width
+	buf.Reset()
+	err = WriteTable(&buf, []string{"description"}, [][]value.Value{
+		{value.NewStringValue("a very long description")},
+		{value.NewStringValue("漢字漢字漢字")},
+	}, 8)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, `+----------+
+| descrip… |
++----------+
+| a very … |
+| 漢字漢…  |
++----------+
+2 rows
+`, buf.String())
+}
+
+func TestTableWriter(t *testing.T) {
+
+	job, err := BuildSqlJob(rtConf, "mockcsv", `SELECT n, n * 2 AS twice FROM seq WHERE n < 3`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	var buf bytes.Buffer
+	job.Tasks.Add(NewTableWriter(&buf, []string{"n", "twice"}))
+	assert.T(t, job.Setup() == nil)
+	err = job.Run(context.Background())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, `+---+-------+
+| n | twice |
++---+-------+
+| 1 |     2 |
+| 2 |     4 |
++---+-------+
+2 rows
+`, buf.String())
+}
@@ -3,14 +3,15 @@ package exec
 import (
 	"fmt"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/logging"
 )
 
-var _ = u.EMPTY
-
 const (
 	ItemDefaultChannelSize = 50
+
+	// max goroutines the tasks of a job run on, see RuntimeConfig.MaxWorkers
+	DefaultMaxWorkers = 256
 )
 
 type SigChan chan bool
@@ -19,26 +20,36 @@ type MessageChan chan datasource.Message
 type MessageHandler func(ctx *Context, msg datasource.Message) bool
 type Tasks []TaskRunner
 
+// Task is a single step of a pipeline:  it reads messages from its input
+//  channel and writes to its output channel, closing the output when done
+//  (which is the signal to the next task), so tasks compose by wiring the
+//  output of one to the input of the next, see SetupTasks.
+//
+//     Source -> Where -> Projection
+type Task interface {
+	Run(ctx *Context) error
+	Close() error
+	MessageIn() MessageChan
+	MessageOut() MessageChan
+	Children() Tasks
+}
+
 // TaskRunner is an interface for single dependent task in Dag of
 //  Tasks necessary to execute a Job
 // - it may have children tasks
 // - it may be parallel, distributed, etc
 type TaskRunner interface {
-	Children() Tasks
+	Task
 	Type() string
-	MessageIn() MessageChan
-	MessageOut() MessageChan
 	MessageInSet(MessageChan)
 	MessageOutSet(MessageChan)
 	ErrChan() ErrChan
 	SigChan() SigChan
-	Run(ctx *Context) error
-	Close() error
 }
 
 // Add a child Task
 func (m *Tasks) Add(task TaskRunner) {
-	//u.Debugf("add task: %T", task)
+	//logging.Debugf("add task: %T", task)
 	*m = append(*m, task)
 }
 
@@ -86,16 +97,30 @@ func MakeHandler(task TaskRunner) MessageHandler {
 	}
 }
 
+// rowError is for handlers failing on a row, the row is recorded and
+//  skipped (true is returned), or in strict mode the error is sent to
+//  the task's error channel and false returned to stop the task
+func rowError(ctx *Context, task TaskRunner, msg datasource.Message, err error) bool {
+	if err = ctx.RowError(msg, err); err == nil {
+		return true
+	}
+	select {
+	case task.ErrChan() <- err:
+	default:
+	}
+	return false
+}
+
 func (m *TaskBase) Run(ctx *Context) error {
 	defer ctx.Recover() // Our context can recover panics, save error msg
 	defer func() {
 		close(m.msgOutCh) // closing output channels is the signal to stop
-		//u.Warnf("close taskbase: %v", m.Type())
+		//logging.Warnf("close taskbase: %v", m.Type())
 	}()
 
-	//u.Debugf("TaskBase: %T inchan", m)
+	//logging.Debugf("TaskBase: %T inchan", m)
 	if m.Handler == nil {
-		u.Warnf("returning, no handler")
+		logging.Warnf("returning, no handler")
 		return fmt.Errorf("Must have a handler to run base runner")
 	}
 	ok := true
@@ -115,10 +140,10 @@ msgLoop:
 		select {
 		case msg, ok = <-m.msgInCh:
 			if ok {
-				//u.Debugf("sending to handler: %v %T  %+v", m.Type(), msg, msg)
+				//logging.Debugf("sending to handler: %v %T  %+v", m.Type(), msg, msg)
 				ok = m.Handler(ctx, msg)
 			} else {
-				//u.Warnf("Not ok?   shutting down")
+				//logging.Warnf("Not ok?   shutting down")
 				break msgLoop
 			}
 		case <-m.sigCh:
@@ -126,6 +151,13 @@ msgLoop:
 		}
 	}
 
+	if err == nil {
+		// a handler may have stopped on an error
+		select {
+		case err = <-m.errCh:
+		default:
+		}
+	}
 	return err
 }
 
@@ -144,13 +176,8 @@ func (m *TaskStepper) Run(ctx *Context) error {
 	defer ctx.Recover()     // Our context can recover panics, save error msg
 	defer close(m.msgOutCh) // closing output channels is the signal to stop
 
-	u.Infof("runner: %T inchan", m)
-	for {
-		select {
-		case <-m.sigCh:
-			break
-		}
-	}
-	u.Warnf("end of Runner")
+	logging.Infof("runner: %T inchan", m)
+	<-m.sigCh
+	logging.Warnf("end of Runner")
 	return nil
 }
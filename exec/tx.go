@@ -0,0 +1,73 @@
+package exec
+
+import (
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/logging"
+)
+
+// txWrites are the writes of a DML task (Insert, Update, Delete), in a
+//  transaction of the source so the statement is all or nothing.   A
+//  nil Transactioner (the source has none, or the writes are in the
+//  transaction of a batch, see RunBatch) keeps each write as it is made.
+type txWrites struct {
+	tx   datasource.Tx
+	done bool
+}
+
+func beginWrites(txer datasource.Transactioner) (*txWrites, error) {
+	if txer == nil {
+		return &txWrites{}, nil
+	}
+	tx, err := txer.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &txWrites{tx: tx}, nil
+}
+
+// finish commits the writes if the task wrote all of its rows without
+//  error, otherwise rolls them back, and returns @err.   Only the first
+//  call finishes, so it is deferred to roll back a task that panics
+//
+//     defer writes.finish(false, nil)
+func (m *txWrites) finish(completed bool, err error) error {
+	if m.done || m.tx == nil {
+		m.done = true
+		return err
+	}
+	m.done = true
+	if completed && err == nil {
+		return m.tx.Commit()
+	}
+	if rbErr := m.tx.Rollback(); rbErr != nil {
+		logging.Errorf("could not rollback: %v", rbErr)
+	}
+	return err
+}
+
+// the Transactioner of the writes of a DML statement to @table, nil if
+//  the source has none or @table is in the transaction of a batch
+func (m *JobBuilder) transactioner(sourceConn datasource.SourceConn, table string) datasource.Transactioner {
+	if _, inBatch := m.batchConns[strings.ToLower(table)]; inBatch {
+		return nil
+	}
+	txer, _ := sourceConn.(datasource.Transactioner)
+	return txer
+}
+
+// the conn a DML statement writes @table through, that of the transaction
+//  of the batch (@inBatch) if @table is in one, otherwise a new conn
+func (m *JobBuilder) writeConn(table string) (conn datasource.SourceConn, inBatch bool) {
+	if conn, ok := m.batchConns[strings.ToLower(table)]; ok {
+		return conn, true
+	}
+	return m.schema.Conn(table), false
+}
+
+// a Scanner of the conn of a batch, which is not a DataSource so the
+//  Source task scanning it does not close it
+type batchScanner struct {
+	datasource.Scanner
+}
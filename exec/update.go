@@ -0,0 +1,261 @@
+package exec
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+// Update is the task for an UPDATE statement, each message from its input
+//  (the scanned, filtered rows) has the SET columns evaluated against it
+//  and is written back to the source by key.  With RETURNING the updated
+//  row is emitted.  If the source is a Transactioner the rows are updated
+//  in one transaction.
+type Update struct {
+	*TaskBase
+	stmt *expr.SqlUpdate
+	into datasource.Updater
+	cols []*datasource.ColumnInfo // the SET columns, resolved against the schema
+	txer datasource.Transactioner // if set, the rows are one transaction
+}
+
+func NewUpdate(stmt *expr.SqlUpdate, into datasource.Updater) *Update {
+	cols, _ := datasource.ResolveSchemaInfo(nil, columnNames(stmt.Columns))
+	return &Update{
+		TaskBase: NewTaskBase("Update"),
+		stmt:     stmt,
+		into:     into,
+		cols:     cols,
+	}
+}
+
+func (m *Update) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	writes, err := beginWrites(m.txer)
+	if err != nil {
+		return err
+	}
+	defer writes.finish(false, nil)
+	completed, err := m.update(ctx)
+	return writes.finish(completed, err)
+}
+
+// update the rows of the input, completed is false if signalled to stop first
+func (m *Update) update(ctx *Context) (completed bool, err error) {
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				return true, nil
+			}
+			reader, ok := msg.Body().(expr.ContextReader)
+			if !ok {
+				logging.Warnf("could not convert to message reader: %T", msg.Body())
+				continue
+			}
+			row := make(map[string]value.Value, len(reader.Row()))
+			for k, v := range reader.Row() {
+				row[k] = v
+			}
+			writer := datasource.NewContextSimpleData(row)
+			var invalid error
+			for i, col := range m.stmt.Columns {
+				v, ok := vm.Eval(reader, col.Expr)
+				if !ok || v == nil {
+					v = value.NewNilValue()
+				}
+				if invalid = m.cols[i].Validate(v); invalid != nil {
+					break
+				}
+				writer.Put(m.cols[i], reader, v)
+			}
+			if invalid != nil {
+				// a value not allowed by an ENUM, SET column, the row is not updated
+				if err := ctx.RowError(msg, invalid); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if err := m.into.Put(msg.Key(), row); err != nil {
+				logging.Errorf("could not update: %v", err)
+				return false, err
+			}
+			if len(m.stmt.Returning) == 0 {
+				continue
+			}
+			select {
+			case m.msgOutCh <- datasource.NewContextSimpleData(row):
+			case <-m.sigCh:
+				return false, nil
+			}
+		case <-m.sigCh:
+			return false, nil
+		}
+	}
+}
+
+// UpdateFrom joins each row of an  UPDATE ... FROM  to the rows of the FROM
+//  source, the first joined row the WHERE matches is forwarded to the Update
+//  so the SET reads both  a.col  and  b.col.   Rows without a match are not
+//  updated.  The FROM source is read once, on the first row.
+//
+//     UPDATE users SET email = l.email FROM lookup AS l WHERE users.id = l.id
+type UpdateFrom struct {
+	*TaskBase
+	stmt         *expr.SqlUpdate
+	from         datasource.Scanner
+	collation    value.Collation
+	targetPrefix string
+	fromPrefix   string
+	rows         []expr.ContextReader
+}
+
+func NewUpdateFrom(stmt *expr.SqlUpdate, from datasource.Scanner, coll value.Collation) *UpdateFrom {
+	alias := stmt.Source.Alias
+	if alias == "" {
+		alias = stmt.Source.Name
+	}
+	m := &UpdateFrom{
+		TaskBase:     NewTaskBase("UpdateFrom"),
+		stmt:         stmt,
+		from:         from,
+		collation:    coll,
+		targetPrefix: stmt.From + ".",
+		fromPrefix:   alias + ".",
+	}
+	m.Handler = updateFromHandler(m)
+	return m
+}
+
+// Close the FROM source, as Source does
+func (m *UpdateFrom) Close() error {
+	if closer, ok := m.from.(datasource.DataSource); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return m.TaskBase.Close()
+}
+
+func updateFromHandler(m *UpdateFrom) MessageHandler {
+	out := m.MessageOut()
+	return func(ctx *Context, msg datasource.Message) bool {
+		target, ok := msg.Body().(expr.ContextReader)
+		if !ok {
+			return rowError(ctx, m, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
+		}
+		if m.rows == nil {
+			rows, err := m.readFrom()
+			if err != nil {
+				select {
+				case m.ErrChan() <- err:
+				default:
+				}
+				return false
+			}
+			m.rows = rows
+		}
+		// unqualified names are of the updated table, the FROM source is
+		//  only read as  alias.col
+		targetRow := &derivedRow{ContextReader: target, prefix: m.targetPrefix}
+		for _, row := range m.rows {
+			var joined expr.ContextReader = datasource.NewContextMerged(targetRow, &outerRef{ContextReader: row, prefix: m.fromPrefix})
+			if m.stmt.Where != nil {
+				reader := joined
+				if m.collation != nil {
+					reader = withCollation(joined, m.collation)
+				}
+				v, ok := vm.Eval(reader, m.stmt.Where)
+				if bv, isBool := v.(value.BoolValue); !ok || !isBool || !bv.Val() {
+					continue
+				}
+			}
+			select {
+			case out <- &derivedRow{ContextReader: joined, key: msg.Key()}:
+				return true
+			case <-m.SigChan():
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// all rows of the FROM source
+func (m *UpdateFrom) readFrom() ([]expr.ContextReader, error) {
+	rows := make([]expr.ContextReader, 0)
+	iter := m.from.CreateIterator(nil)
+	if iter == nil {
+		return nil, fmt.Errorf("no iterator for FROM %v", m.stmt.Source.Name)
+	}
+	for msg := iter.Next(); msg != nil; msg = iter.Next() {
+		if reader, ok := msg.Body().(expr.ContextReader); ok {
+			rows = append(rows, reader)
+		}
+	}
+	return rows, iterErr(iter)
+}
+
+// Delete is the task for a DELETE statement, each message from its input
+//  is deleted from the source by key.  With RETURNING the deleted message
+//  is emitted.  If the source is a Transactioner the rows are deleted in
+//  one transaction.
+type Delete struct {
+	*TaskBase
+	stmt *expr.SqlDelete
+	from datasource.Deleter
+	txer datasource.Transactioner // if set, the rows are one transaction
+}
+
+func NewDelete(stmt *expr.SqlDelete, from datasource.Deleter) *Delete {
+	return &Delete{
+		TaskBase: NewTaskBase("Delete"),
+		stmt:     stmt,
+		from:     from,
+	}
+}
+
+func (m *Delete) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	writes, err := beginWrites(m.txer)
+	if err != nil {
+		return err
+	}
+	defer writes.finish(false, nil)
+	completed, err := m.delete()
+	return writes.finish(completed, err)
+}
+
+// delete the rows of the input, completed is false if signalled to stop first
+func (m *Delete) delete() (completed bool, err error) {
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				return true, nil
+			}
+			if err := m.from.Delete(msg.Key()); err != nil {
+				logging.Errorf("could not delete: %v", err)
+				return false, err
+			}
+			if len(m.stmt.Returning) == 0 {
+				continue
+			}
+			select {
+			case m.msgOutCh <- msg:
+			case <-m.sigCh:
+				return false, nil
+			}
+		case <-m.sigCh:
+			return false, nil
+		}
+	}
+}
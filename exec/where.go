@@ -1,62 +1,96 @@
 package exec
 
 import (
-	u "github.com/araddon/gou"
+	"fmt"
+
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 	"github.com/araddon/qlbridge/vm"
 )
 
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*Where)(nil)
+)
+
 // A scanner to filter by where clause
 type Where struct {
 	*TaskBase
-	where expr.Node
+	where   expr.Node
+	builder *JobBuilder // plans the sub-selects of the where, see subQueries
 }
 
 func NewWhere(where expr.Node) *Where {
+	return NewWhereCollation(where, nil)
+}
+
+// NewWhereCollation filters with string comparisons (= LIKE etc) using
+//  @coll for messages that do not have their own collation, ie the
+//  connection is case-insensitive.
+func NewWhereCollation(where expr.Node, coll value.Collation) *Where {
 	s := &Where{
 		TaskBase: NewTaskBase("Where"),
 		where:    where,
 	}
-	s.Handler = whereFilter(where, s)
+	s.Handler = whereFilter(where, coll, s)
 	return s
 }
 
-func whereFilter(where expr.Node, task TaskRunner) MessageHandler {
+func whereFilter(where expr.Node, coll value.Collation, task *Where) MessageHandler {
 	out := task.MessageOut()
-	evaluator := vm.Evaluator(where)
+	evaluator, err := vm.Compile(where)
+	if err != nil {
+		logging.Warnf("could not compile where, falling back to interpreted: %v", err)
+		evaluator = vm.Evaluator(where)
+	}
+	// sub-selects are not pure, they are run once (on the first row) for
+	//  all rows, ie  WHERE user_id IN (SELECT user_id FROM orders)
+	var subs *subQueries
+	hasSubQueries := !expr.IsPure(where)
 	return func(ctx *Context, msg datasource.Message) bool {
 		// defer func() {
 		// 	if r := recover(); r != nil {
-		// 		u.Errorf("crap, %v", r)
+		// 		logging.Errorf("crap, %v", r)
 		// 	}
 		// }()
 		if msgReader, ok := msg.Body().(expr.ContextReader); ok {
 
-			whereValue, ok := evaluator(msgReader)
-			//u.Debugf("msg: %#v", msgReader)
-			//u.Infof("evaluating: ok?%v  result=%v where expr:%v", ok, whereValue.ToString(), where.StringAST())
+			if coll != nil {
+				msgReader = withCollation(msgReader, coll)
+			}
+			if hasSubQueries {
+				if subs == nil {
+					subs = newSubQueries(task.builder, ctx)
+				}
+				msgReader = &subQueryReader{ContextReader: msgReader, subQueries: subs}
+			}
+			whereValue, ok := evaluator(vm.NewMemoContext(msgReader))
+			//logging.Debugf("msg: %#v", msgReader)
+			//logging.Infof("evaluating: ok?%v  result=%v where expr:%v", ok, whereValue.ToString(), where.StringAST())
 			if !ok {
-				u.Errorf("could not evaluate: %v", msg)
-				return false
+				return rowError(ctx, task, msg, fmt.Errorf("could not evaluate where: %v", where))
 			}
 			switch whereVal := whereValue.(type) {
 			case value.BoolValue:
 				if whereVal.Val() == false {
-					//u.Debugf("Filtering out")
+					//logging.Debugf("Filtering out")
 					return true
 				} else {
-					//u.Debugf("NOT FILTERED OUT")
+					//logging.Debugf("NOT FILTERED OUT")
 				}
+			case value.NilValue:
+				// NULL (unknown) is not true
+				return true
 			default:
-				u.Warnf("unknown type? %T", whereVal)
+				logging.Warnf("unknown type? %T", whereVal)
 			}
 		} else {
-			u.Errorf("could not convert to message reader: %T", msg.Body())
+			return rowError(ctx, task, msg, fmt.Errorf("could not convert to message reader: %T", msg.Body()))
 		}
 
-		//u.Debug("about to send from where to forward")
+		//logging.Debug("about to send from where to forward")
 		select {
 		case out <- msg:
 			return true
@@ -65,3 +99,19 @@ func whereFilter(where expr.Node, task TaskRunner) MessageHandler {
 		}
 	}
 }
+
+// a message evaluated with the connection collation, unless the message
+//  already chose its own
+type collatedReader struct {
+	expr.ContextReader
+	coll value.Collation
+}
+
+func (m *collatedReader) Collation() value.Collation { return m.coll }
+
+func withCollation(r expr.ContextReader, coll value.Collation) expr.ContextReader {
+	if cr, ok := r.(expr.ContextCollation); ok && cr.Collation() != nil {
+		return r
+	}
+	return &collatedReader{ContextReader: r, coll: coll}
+}
@@ -0,0 +1,292 @@
+package exec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/araddon/qlbridge/vm"
+)
+
+var (
+	// Ensure that we implement the Task Runner interface
+	_ TaskRunner = (*Window)(nil)
+
+	_ expr.ContextReader = (*windowRow)(nil)
+	_ datasource.Message = (*windowRow)(nil)
+)
+
+// Window evaluates the window function columns of a select as the rows
+//  stream, each row is forwarded with the value of each window column
+//  over its frame, for the Projection.   Frames are a ring buffer of the
+//  preceding rows so it does not buffer the result, unless a window has
+//  a PARTITION BY or ORDER BY, then all rows are read and each partition
+//  sorted before its function is evaluated, as is a row_number() or
+//  rank() of any window.  Rows are forwarded in the order they arrived.
+//
+//     SELECT ts, avg(x) OVER (ROWS 5 PRECEDING) AS moving FROM t
+//     SELECT g, rank() OVER (PARTITION BY g ORDER BY score DESC) FROM t
+type Window struct {
+	*TaskBase
+	stmt      *expr.SqlSelect
+	collation value.Collation
+}
+
+func NewWindow(stmt *expr.SqlSelect) (*Window, error) {
+	return NewWindowCollation(stmt, nil)
+}
+
+// NewWindowCollation is a Window whose ORDER BY compares strings using
+//  @coll, as Sort does
+func NewWindowCollation(stmt *expr.SqlSelect, coll value.Collation) (*Window, error) {
+	for _, col := range stmt.Columns {
+		if col.Over == nil {
+			continue
+		}
+		if _, err := newWindowFrame(col); err != nil {
+			return nil, err
+		}
+	}
+	return &Window{
+		TaskBase:  NewTaskBase("Window"),
+		stmt:      stmt,
+		collation: coll,
+	}, nil
+}
+
+func (m *Window) Run(ctx *Context) error {
+	defer ctx.Recover()
+	defer close(m.msgOutCh)
+
+	// the streaming frames, buffered windows are evaluated once all rows
+	//  have been read
+	frames := make([]*windowFrame, len(m.stmt.Columns))
+	buffered := false
+	for i, col := range m.stmt.Columns {
+		switch {
+		case col.Over == nil:
+		case windowBuffered(col):
+			buffered = true
+		default:
+			frames[i], _ = newWindowFrame(col)
+		}
+	}
+	rows := make([]*windowRow, 0)
+msgLoop:
+	for {
+		select {
+		case msg, ok := <-m.msgInCh:
+			if !ok {
+				break msgLoop
+			}
+			reader, ok := msg.Body().(expr.ContextReader)
+			if !ok {
+				if err := ctx.RowError(msg, fmt.Errorf("could not convert to message reader: %T", msg.Body())); err != nil {
+					return err
+				}
+				continue
+			}
+			row := &windowRow{ContextReader: reader, key: msg.Key(), vals: make([]value.Value, len(frames))}
+			for i, frame := range frames {
+				if frame != nil {
+					row.vals[i] = frame.push(reader)
+				}
+			}
+			if buffered {
+				rows = append(rows, row)
+				continue
+			}
+			select {
+			case m.msgOutCh <- row:
+			case <-m.sigCh:
+				return nil
+			}
+		case <-m.sigCh:
+			return nil
+		}
+	}
+
+	for i, col := range m.stmt.Columns {
+		if col.Over != nil && windowBuffered(col) {
+			m.evalPartitions(i, col, rows)
+		}
+	}
+	for _, row := range rows {
+		select {
+		case m.msgOutCh <- row:
+		case <-m.sigCh:
+			return nil
+		}
+	}
+	return nil
+}
+
+// is window column @col evaluated over all rows rather than as they
+//  stream, a row_number() or rank() always is, without an ORDER BY every
+//  row of its partition is a peer
+func windowBuffered(col *expr.Column) bool {
+	if col.Over.Buffered() {
+		return true
+	}
+	switch strings.ToLower(col.Expr.(*expr.FuncNode).Name) {
+	case "row_number", "rank":
+		return true
+	}
+	return false
+}
+
+// evaluate window column @i over the buffered rows, partition by partition
+//  each in its window ORDER BY
+func (m *Window) evalPartitions(i int, col *expr.Column, rows []*windowRow) {
+	partitions := make(map[string][]*sortRow)
+	keys := make([]string, 0)
+	sorter := NewSort(col.Over.OrderBy, m.collation)
+	for _, row := range rows {
+		key := partitionKey(row, col.Over.PartitionBy)
+		if _, exists := partitions[key]; !exists {
+			keys = append(keys, key)
+		}
+		partitions[key] = append(partitions[key], sorter.sortRow(row))
+	}
+	for _, key := range keys {
+		part := partitions[key]
+		sort.Stable(&sortRows{rows: part, sorter: sorter})
+		frame, _ := newWindowFrame(col)
+		rank := 0
+		for k, sr := range part {
+			row := sr.msg.(*windowRow)
+			switch frame.fn {
+			case "row_number":
+				row.vals[i] = value.NewIntValue(int64(k + 1))
+			case "rank":
+				// peers, equal in the ORDER BY, share the rank of the first
+				if k == 0 || sorter.compare(part[k-1], sr) != 0 {
+					rank = k + 1
+				}
+				row.vals[i] = value.NewIntValue(int64(rank))
+			default:
+				row.vals[i] = frame.push(row.ContextReader)
+			}
+		}
+	}
+}
+
+// the partition of a row is the groupingKey of the PARTITION BY columns
+func partitionKey(reader expr.ContextReader, partitionBy expr.Columns) string {
+	exprs := make([]expr.Node, len(partitionBy))
+	for i, col := range partitionBy {
+		exprs[i] = col.Expr
+	}
+	return groupingKey(reader, exprs)
+}
+
+// does this select have window function columns
+func hasWindow(stmt *expr.SqlSelect) bool {
+	for _, col := range stmt.Columns {
+		if col.Over != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// the value of window column @i of a row from the Window task
+func windowValue(r expr.ContextReader, i int) (value.Value, bool) {
+	if wr, ok := r.(*windowRow); ok && i < len(wr.vals) && wr.vals[i] != nil {
+		return wr.vals[i], true
+	}
+	return nil, false
+}
+
+// a row with the values of its window columns (by select column index)
+type windowRow struct {
+	expr.ContextReader
+	key  uint64
+	vals []value.Value
+}
+
+func (m *windowRow) Key() uint64       { return m.key }
+func (m *windowRow) Body() interface{} { return m }
+
+// the frame of a windowed count, sum or avg:  the current row and up to
+//  Preceding rows before it, with the running count and sum of the
+//  non-null values in the ring
+type windowFrame struct {
+	fn   string
+	arg  expr.Node // nil for count(*)
+	ring []frameSlot
+	next int
+	n    int64
+	sum  float64
+}
+
+type frameSlot struct {
+	ok bool
+	f  float64
+}
+
+func newWindowFrame(col *expr.Column) (*windowFrame, error) {
+	fn := col.Expr.(*expr.FuncNode)
+	m := &windowFrame{fn: strings.ToLower(fn.Name)}
+	switch m.fn {
+	case "count", "sum", "avg":
+	case "row_number", "rank":
+		if len(fn.Args) > 0 {
+			return nil, fmt.Errorf("window function %s takes no arguments", fn.Name)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("window function %s not supported", fn.Name)
+	}
+	if fn.Distinct {
+		return nil, fmt.Errorf("DISTINCT not supported for window function %s", fn.Name)
+	}
+	if len(fn.Args) > 0 && fn.Args[0].String() != "*" {
+		m.arg = fn.Args[0]
+	}
+	if col.Over.Preceding >= 0 {
+		m.ring = make([]frameSlot, col.Over.Preceding+1)
+	}
+	return m, nil
+}
+
+// add the current row to the frame, evicting the oldest, and return the
+//  aggregate of the frame
+func (m *windowFrame) push(reader expr.ContextReader) value.Value {
+	slot := frameSlot{ok: true, f: 1}
+	if m.arg != nil {
+		v, ok := vm.Eval(reader, m.arg)
+		switch {
+		case !ok || v == nil || v.Type() == value.NilType || v.Err():
+			slot = frameSlot{}
+		case m.fn != "count":
+			slot.f = value.ToFloat64(v.Rv())
+			slot.ok = !math.IsNaN(slot.f)
+		}
+	}
+	if m.ring != nil {
+		if old := m.ring[m.next]; old.ok {
+			m.n--
+			m.sum -= old.f
+		}
+		m.ring[m.next] = slot
+		m.next = (m.next + 1) % len(m.ring)
+	}
+	if slot.ok {
+		m.n++
+		m.sum += slot.f
+	}
+	switch {
+	case m.fn == "count":
+		return value.NewIntValue(m.n)
+	case m.n == 0:
+		return value.NewNilValue()
+	case m.fn == "avg":
+		return value.NewNumberValue(m.sum / float64(m.n))
+	}
+	return value.NewNumberValue(m.sum)
+}
@@ -0,0 +1,50 @@
+package expr
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// the aggregate mutex
+	aggMu      sync.Mutex
+	aggregates = make(map[string]AggregatorMaker)
+)
+
+// Aggregator accumulates the values of many rows of a single group
+// and produces one result
+//
+//     count_distinct(user_id)
+//
+type Aggregator interface {
+	// Do adds value from current row
+	Do(v value.Value)
+	// Result is the current aggregate value
+	Result() value.Value
+	// Reset clears state so it may be re-used for a new group
+	Reset()
+}
+
+// AggregatorMaker creates a new Aggregator, @args are the literal
+// arguments after the first (column) argument
+//
+//     count_distinct(user_id, "hll")   =>  args = ["hll"]
+//
+type AggregatorMaker func(args ...value.Value) (Aggregator, error)
+
+// Register an aggregate by name
+func AggregatorAdd(name string, maker AggregatorMaker) {
+	aggMu.Lock()
+	defer aggMu.Unlock()
+	aggregates[strings.ToLower(name)] = maker
+}
+
+// Get an aggregate maker by name
+func AggregatorGet(name string) (AggregatorMaker, bool) {
+	aggMu.Lock()
+	defer aggMu.Unlock()
+	maker, ok := aggregates[strings.ToLower(name)]
+	return maker, ok
+}
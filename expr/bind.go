@@ -0,0 +1,325 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+// BindParams replaces the ? placeholders (ParamNode) of @n, a select,
+//  insert, update, delete or an expression, with literals of @args in the
+//  order the placeholders are written.   A slice arg of the only placeholder of an IN list is
+//  expanded to one arg of the list per element.   The node is bound in
+//  place, the returned node is only new if @n is itself a placeholder.
+//
+//     WHERE id IN (?)        [[1, 2, 3]]  =>  WHERE id IN (1,2,3)
+//     WHERE id IN (?, ?, ?)  [1, 2, 3]    =>  WHERE id IN (1,2,3)
+func BindParams(n Node, args []value.Value) (Node, error) {
+	params := make([]*ParamNode, 0)
+	if err := walkParams(n, func(p *ParamNode) { params = append(params, p) }); err != nil {
+		return nil, err
+	}
+	if len(params) != len(args) {
+		return nil, fmt.Errorf("expected %d params but got %d", len(params), len(args))
+	}
+	sort.SliceStable(params, func(i, j int) bool { return params[i].Pos < params[j].Pos })
+	b := &binder{args: make(map[*ParamNode]value.Value, len(params))}
+	for i, p := range params {
+		b.args[p] = args[i]
+	}
+	return b.bind(n)
+}
+
+type binder struct {
+	args map[*ParamNode]value.Value
+}
+
+// visit each placeholder of @n, nodes placeholders can not be in are errors
+func walkParams(n Node, visit func(*ParamNode)) error {
+	switch nt := n.(type) {
+	case nil:
+	case *ParamNode:
+		visit(nt)
+	case *FuncNode:
+		return walkParamNodes(nt.Args, visit)
+	case *BinaryNode:
+		return walkParamNodes(nt.Args[:], visit)
+	case *TriNode:
+		return walkParamNodes(nt.Args[:], visit)
+	case *UnaryNode:
+		return walkParams(nt.Arg, visit)
+	case *MultiArgNode:
+		return walkParamNodes(nt.Args, visit)
+	case *RowConstructorNode:
+		return walkParamNodes(nt.Args, visit)
+	case *SubscriptNode:
+		return walkParamNodes([]Node{nt.Arg, nt.Key}, visit)
+	case *SubQueryNode:
+		return walkParams(nt.Select, visit)
+	case *SqlSelect:
+		return walkParamNodes(nt.paramNodes(), visit)
+	case *SqlInsert:
+		for _, row := range nt.Rows {
+			for _, v := range row {
+				if pv, ok := v.(ParamValue); ok {
+					visit(pv.Param)
+				}
+			}
+		}
+	case *SqlUpdate:
+		for _, col := range nt.Columns {
+			if err := walkParams(col.Expr, visit); err != nil {
+				return err
+			}
+		}
+		return walkParams(nt.Where, visit)
+	case *SqlDelete:
+		return walkParams(nt.Where, visit)
+	case SqlStatement:
+		return fmt.Errorf("binding params not supported for %s", nt.NodeType())
+	}
+	return nil
+}
+
+func walkParamNodes(nodes []Node, visit func(*ParamNode)) error {
+	for _, n := range nodes {
+		if err := walkParams(n, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the expressions of a select placeholders may be in
+func (m *SqlSelect) paramNodes() []Node {
+	nodes := make([]Node, 0)
+	for _, cte := range m.With {
+		if cte.Source != nil {
+			nodes = append(nodes, cte.Source)
+		}
+	}
+	for _, col := range m.Columns {
+		nodes = append(nodes, col.Expr, col.Guard)
+	}
+	for _, from := range m.From {
+		nodes = append(nodes, from.JoinExpr)
+		if from.Source != nil {
+			nodes = append(nodes, from.Source)
+		}
+	}
+	if m.Where != nil {
+		nodes = append(nodes, m.Where.Expr)
+	}
+	for _, col := range m.GroupBy {
+		nodes = append(nodes, col.Expr)
+	}
+	nodes = append(nodes, m.Having)
+	for _, col := range m.OrderBy {
+		nodes = append(nodes, col.Expr)
+	}
+	for _, op := range m.SetOps {
+		nodes = append(nodes, op.Right)
+	}
+	return nodes
+}
+
+func (m *binder) bind(n Node) (Node, error) {
+	switch nt := n.(type) {
+	case nil:
+		return nil, nil
+	case *ParamNode:
+		return paramLiteral(nt, m.args[nt])
+	case *FuncNode:
+		return nt, m.bindNodes(nt.Args)
+	case *BinaryNode:
+		return nt, m.bindNodes(nt.Args[:])
+	case *TriNode:
+		return nt, m.bindNodes(nt.Args[:])
+	case *UnaryNode:
+		arg, err := m.bind(nt.Arg)
+		nt.Arg = arg
+		return nt, err
+	case *MultiArgNode:
+		args, err := m.bindList(nt.Args)
+		nt.Args = args
+		return nt, err
+	case *RowConstructorNode:
+		return nt, m.bindNodes(nt.Args)
+	case *SubscriptNode:
+		arg, err := m.bind(nt.Arg)
+		if err != nil {
+			return nil, err
+		}
+		key, err := m.bind(nt.Key)
+		nt.Arg, nt.Key = arg, key
+		return nt, err
+	case *SubQueryNode:
+		_, err := m.bind(nt.Select)
+		return nt, err
+	case *SqlSelect:
+		return nt, m.bindSelect(nt)
+	case *SqlInsert:
+		for _, row := range nt.Rows {
+			for i, v := range row {
+				if pv, ok := v.(ParamValue); ok {
+					if row[i] = m.args[pv.Param]; row[i] == nil {
+						row[i] = value.NewNilValue()
+					}
+				}
+			}
+		}
+		return nt, nil
+	case *SqlUpdate:
+		for _, col := range nt.Columns {
+			var err error
+			if col.Expr, err = m.bind(col.Expr); err != nil {
+				return nil, err
+			}
+		}
+		where, err := m.bind(nt.Where)
+		nt.Where = where
+		return nt, err
+	case *SqlDelete:
+		where, err := m.bind(nt.Where)
+		nt.Where = where
+		return nt, err
+	}
+	return n, nil
+}
+
+// bind each of @nodes in place
+func (m *binder) bindNodes(nodes []Node) error {
+	for i, n := range nodes {
+		bound, err := m.bind(n)
+		if err != nil {
+			return err
+		}
+		nodes[i] = bound
+	}
+	return nil
+}
+
+// the args of an IN list, a slice bound to the only placeholder of the
+//  list is expanded to the list
+//
+//     id IN (?)  =>  id IN (1,2,3)
+func (m *binder) bindList(args []Node) ([]Node, error) {
+	if len(args) == 2 {
+		if p, ok := args[1].(*ParamNode); ok {
+			if vals, ok := sliceValues(m.args[p]); ok {
+				if len(vals) == 0 {
+					return nil, fmt.Errorf("empty list bound to ? at %d", p.Pos)
+				}
+				bound, err := m.bind(args[0])
+				if err != nil {
+					return nil, err
+				}
+				list := []Node{bound}
+				for _, v := range vals {
+					lit, err := paramLiteral(p, v)
+					if err != nil {
+						return nil, err
+					}
+					list = append(list, lit)
+				}
+				return list, nil
+			}
+		}
+	}
+	return args, m.bindNodes(args)
+}
+
+func (m *binder) bindSelect(sel *SqlSelect) error {
+	for _, cte := range sel.With {
+		if cte.Source != nil {
+			if err := m.bindSelect(cte.Source); err != nil {
+				return err
+			}
+		}
+	}
+	for _, col := range sel.Columns {
+		var err error
+		if col.Expr, err = m.bind(col.Expr); err != nil {
+			return err
+		}
+		if col.Guard, err = m.bind(col.Guard); err != nil {
+			return err
+		}
+	}
+	for _, from := range sel.From {
+		var err error
+		if from.JoinExpr, err = m.bind(from.JoinExpr); err != nil {
+			return err
+		}
+		if from.Source != nil {
+			if err = m.bindSelect(from.Source); err != nil {
+				return err
+			}
+		}
+	}
+	if sel.Where != nil {
+		var err error
+		if sel.Where.Expr, err = m.bind(sel.Where.Expr); err != nil {
+			return err
+		}
+	}
+	for _, col := range sel.GroupBy {
+		var err error
+		if col.Expr, err = m.bind(col.Expr); err != nil {
+			return err
+		}
+	}
+	var err error
+	if sel.Having, err = m.bind(sel.Having); err != nil {
+		return err
+	}
+	for _, col := range sel.OrderBy {
+		if col.Expr, err = m.bind(col.Expr); err != nil {
+			return err
+		}
+	}
+	for _, op := range sel.SetOps {
+		if err = m.bindSelect(op.Right); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the elements of a slice arg
+func sliceValues(v value.Value) ([]value.Value, bool) {
+	switch vt := v.(type) {
+	case value.SliceValue:
+		return vt.Val(), true
+	case value.StringsValue:
+		vals := make([]value.Value, vt.Len())
+		for i, s := range vt.Val() {
+			vals[i] = value.NewStringValue(s)
+		}
+		return vals, true
+	}
+	return nil, false
+}
+
+// the literal node of the value bound to placeholder @p
+func paramLiteral(p *ParamNode, v value.Value) (Node, error) {
+	switch vt := v.(type) {
+	case nil, value.NilValue:
+		return &NullNode{Pos: p.Pos}, nil
+	case value.IntValue:
+		return NewNumber(p.Pos, strconv.FormatInt(vt.Val(), 10))
+	case value.NumberValue:
+		return NewNumber(p.Pos, strconv.FormatFloat(vt.Val(), 'f', -1, 64))
+	case value.StringValue:
+		return NewStringNode(p.Pos, vt.Val()), nil
+	case value.BoolValue:
+		return NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: strconv.FormatBool(vt.Val()), Pos: int(p.Pos)}), nil
+	case value.TimeValue:
+		return NewStringNode(p.Pos, vt.Val().Format(time.RFC3339Nano)), nil
+	}
+	return nil, fmt.Errorf("can not bind %s value to ? at %d", v.Type(), p.Pos)
+}
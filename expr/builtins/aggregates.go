@@ -0,0 +1,196 @@
+package builtins
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+// is sql NULL?  note Value.Nil() is also true for zero/empty values
+func isNull(v value.Value) bool {
+	return v == nil || v.Type() == value.NilType || v.Err()
+}
+
+// key for distinct-ness of a value, includes type so  1 != "1"
+func distinctKey(v value.Value) string {
+	return value.HashValue(v)
+}
+
+// count_distinct:   count of distinct non-null values
+//
+//      count_distinct(user_id)             => exact, buffers every distinct value
+//      count_distinct(user_id, "hll")      => approximate, HyperLogLog precision 14
+//      count_distinct(user_id, "hll", 12)  => approximate, HyperLogLog precision 12
+//
+func NewCountDistinct(args ...value.Value) (expr.Aggregator, error) {
+	if len(args) == 0 {
+		return &CountDistinct{seen: make(map[string]struct{})}, nil
+	}
+	method := strings.ToLower(args[0].ToString())
+	switch method {
+	case "exact":
+		return &CountDistinct{seen: make(map[string]struct{})}, nil
+	case "hll":
+		precision := HllPrecisionDefault
+		if len(args) > 1 {
+			pv, ok := value.ToInt64(args[1].Rv())
+			if !ok {
+				return nil, fmt.Errorf("count_distinct hll precision must be integer: %v", args[1].Value())
+			}
+			precision = int(pv)
+		}
+		hll, err := NewHyperLogLog(precision)
+		if err != nil {
+			return nil, err
+		}
+		return &CountDistinctApprox{hll: hll}, nil
+	}
+	return nil, fmt.Errorf("count_distinct unknown method %q", method)
+}
+
+// Exact count distinct
+type CountDistinct struct {
+	seen map[string]struct{}
+}
+
+func (m *CountDistinct) Do(v value.Value) {
+	if isNull(v) {
+		return
+	}
+	m.seen[distinctKey(v)] = struct{}{}
+}
+func (m *CountDistinct) Result() value.Value { return value.NewIntValue(int64(len(m.seen))) }
+func (m *CountDistinct) Reset()              { m.seen = make(map[string]struct{}) }
+
+// Approximate count distinct using HyperLogLog
+type CountDistinctApprox struct {
+	hll *HyperLogLog
+}
+
+func (m *CountDistinctApprox) Do(v value.Value) {
+	if isNull(v) {
+		return
+	}
+	m.hll.Add(distinctKey(v))
+}
+func (m *CountDistinctApprox) Result() value.Value { return value.NewIntValue(int64(m.hll.Count())) }
+func (m *CountDistinctApprox) Reset()              { m.hll.Reset() }
+
+// first:   first non-null value seen in group
+//
+//      first(status)
+//
+// values are taken in arrival order, so when rows of group are
+// sorted (within-group ORDER BY) this is first per that order
+func NewFirst(args ...value.Value) (expr.Aggregator, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("first takes a single argument")
+	}
+	return &First{}, nil
+}
+
+type First struct {
+	v value.Value
+}
+
+func (m *First) Do(v value.Value) {
+	if m.v == nil && !isNull(v) {
+		m.v = v
+	}
+}
+func (m *First) Result() value.Value {
+	if m.v == nil {
+		return value.NewNilValue()
+	}
+	return m.v
+}
+func (m *First) Reset() { m.v = nil }
+
+// last:   last non-null value seen in group
+//
+//      last(status)
+//
+// same arrival order semantics as first()
+func NewLast(args ...value.Value) (expr.Aggregator, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("last takes a single argument")
+	}
+	return &Last{}, nil
+}
+
+type Last struct {
+	v value.Value
+}
+
+func (m *Last) Do(v value.Value) {
+	if !isNull(v) {
+		m.v = v
+	}
+}
+func (m *Last) Result() value.Value {
+	if m.v == nil {
+		return value.NewNilValue()
+	}
+	return m.v
+}
+func (m *Last) Reset() { m.v = nil }
+
+// median:   exact median of numeric values in group, NULL if empty
+//
+//      median(price)
+//
+func NewMedian(args ...value.Value) (expr.Aggregator, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("median takes a single argument")
+	}
+	return &Percentile{p: 0.5}, nil
+}
+
+// percentile:   exact percentile (0.0 - 1.0) of numeric values in
+// group using linear interpolation between closest ranks, NULL if empty
+//
+//      percentile(price, 0.9)
+//
+func NewPercentile(args ...value.Value) (expr.Aggregator, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("percentile requires percentile arg:  percentile(col, 0.9)")
+	}
+	p := value.ToFloat64(args[0].Rv())
+	if math.IsNaN(p) || p < 0 || p > 1 {
+		return nil, fmt.Errorf("percentile must be between 0 and 1: %v", args[0].Value())
+	}
+	return &Percentile{p: p}, nil
+}
+
+// Percentile buffers all values of group and selects on Result()
+type Percentile struct {
+	p    float64
+	vals []float64
+}
+
+func (m *Percentile) Do(v value.Value) {
+	if isNull(v) {
+		return
+	}
+	fv := value.ToFloat64(v.Rv())
+	if math.IsNaN(fv) {
+		return
+	}
+	m.vals = append(m.vals, fv)
+}
+func (m *Percentile) Result() value.Value {
+	if len(m.vals) == 0 {
+		return value.NewNilValue()
+	}
+	sort.Float64s(m.vals)
+	rank := m.p * float64(len(m.vals)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := rank - float64(lower)
+	return value.NewNumberValue(m.vals[lower] + frac*(m.vals[upper]-m.vals[lower]))
+}
+func (m *Percentile) Reset() { m.vals = nil }
@@ -0,0 +1,149 @@
+package builtins
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func aggregator(t *testing.T, name string, args ...value.Value) expr.Aggregator {
+	maker, ok := expr.AggregatorGet(name)
+	assert.Tf(t, ok, "should have aggregate %v", name)
+	agg, err := maker(args...)
+	assert.Tf(t, err == nil, "should create %v: %v", name, err)
+	return agg
+}
+
+func TestCountDistinct(t *testing.T) {
+
+	agg := aggregator(t, "count_distinct")
+	for _, v := range []value.Value{
+		value.NewStringValue("a"), value.NewStringValue("b"), value.NewStringValue("a"),
+		value.NewIntValue(1), value.NewStringValue("1"), value.NewIntValue(1),
+		value.NewNilValue(), value.NewIntValue(0),
+	} {
+		agg.Do(v)
+	}
+	assert.Tf(t, agg.Result().Value() == int64(5), "should have 5 distinct: %v", agg.Result())
+
+	agg.Reset()
+	assert.Tf(t, agg.Result().Value() == int64(0), "should be reset: %v", agg.Result())
+
+	maker, _ := expr.AggregatorGet("count_distinct")
+	_, err := maker(value.NewStringValue("nope"))
+	assert.T(t, err != nil)
+	_, err = maker(value.NewStringValue("hll"), value.NewIntValue(30))
+	assert.T(t, err != nil)
+}
+
+func TestCountDistinctHll(t *testing.T) {
+
+	for _, precision := range []int{10, 14} {
+		agg := aggregator(t, "count_distinct", value.NewStringValue("hll"), value.NewIntValue(int64(precision)))
+
+		// known cardinality, with every value repeated
+		cardinality := 100000
+		for i := 0; i < cardinality; i++ {
+			v := value.NewStringValue(fmt.Sprintf("user-%d", i))
+			agg.Do(v)
+			agg.Do(v)
+		}
+		est := float64(agg.Result().Value().(int64))
+
+		// allow 3 standard errors
+		stdErr := 1.04 / math.Sqrt(float64(uint(1)<<uint(precision)))
+		relErr := math.Abs(est-float64(cardinality)) / float64(cardinality)
+		assert.Tf(t, relErr <= 3*stdErr, "p=%d estimate %v off by %.4f expected within %.4f", precision, est, relErr, 3*stdErr)
+	}
+
+	// small cardinality is nearly exact due to linear counting
+	agg := aggregator(t, "count_distinct", value.NewStringValue("hll"))
+	for i := 0; i < 100; i++ {
+		agg.Do(value.NewIntValue(int64(i % 10)))
+	}
+	assert.Tf(t, agg.Result().Value() == int64(10), "should be 10: %v", agg.Result())
+}
+
+func TestFirstLast(t *testing.T) {
+
+	strs := func(vals ...string) []value.Value {
+		vs := make([]value.Value, len(vals))
+		for i, v := range vals {
+			if v == "" {
+				vs[i] = value.NewNilValue()
+			} else {
+				vs[i] = value.NewStringValue(v)
+			}
+		}
+		return vs
+	}
+	tests := []struct {
+		in          []value.Value
+		first, last interface{}
+	}{
+		// ordered input
+		{strs("a", "b", "c", "d"), "a", "d"},
+		// unordered input, arrival order wins
+		{strs("c", "a", "d", "b"), "c", "b"},
+		// nulls are skipped
+		{strs("", "b", "c", ""), "b", "c"},
+		{strs("", ""), nil, nil},
+		{nil, nil, nil},
+	}
+	first := aggregator(t, "first")
+	last := aggregator(t, "last")
+	for _, test := range tests {
+		first.Reset()
+		last.Reset()
+		for _, v := range test.in {
+			first.Do(v)
+			last.Do(v)
+		}
+		assert.Tf(t, first.Result().Value() == test.first, "first %v want %v got %v", test.in, test.first, first.Result())
+		assert.Tf(t, last.Result().Value() == test.last, "last %v want %v got %v", test.in, test.last, last.Result())
+	}
+
+	// zero values are not null
+	first.Reset()
+	first.Do(value.NewNilValue())
+	first.Do(value.NewIntValue(0))
+	first.Do(value.NewIntValue(5))
+	assert.Tf(t, first.Result().Value() == int64(0), "should be 0: %v", first.Result())
+}
+
+func TestMedianPercentile(t *testing.T) {
+
+	median := aggregator(t, "median")
+	p90 := aggregator(t, "percentile", value.NewNumberValue(0.9))
+
+	// empty group is NULL
+	assert.Tf(t, median.Result().Type() == value.NilType, "should be null: %v", median.Result())
+	assert.Tf(t, p90.Result().Type() == value.NilType, "should be null: %v", p90.Result())
+
+	// 1..10, unsorted with nulls and strings
+	for _, i := range []int64{7, 3, 10, 1, 5, 9, 2, 8, 4, 6} {
+		median.Do(value.NewIntValue(i))
+		p90.Do(value.NewIntValue(i))
+	}
+	median.Do(value.NewNilValue())
+	p90.Do(value.NewStringValue("not a number"))
+	assert.Tf(t, median.Result().Value() == float64(5.5), "median should be 5.5: %v", median.Result())
+	assert.Tf(t, math.Abs(p90.Result().Value().(float64)-9.1) < 1e-9, "p90 should be 9.1: %v", p90.Result())
+
+	// odd count exact middle
+	median.Reset()
+	for _, f := range []float64{2.5, 100, 1} {
+		median.Do(value.NewNumberValue(f))
+	}
+	assert.Tf(t, median.Result().Value() == float64(2.5), "median should be 2.5: %v", median.Result())
+
+	maker, _ := expr.AggregatorGet("percentile")
+	_, err := maker(value.NewNumberValue(90))
+	assert.T(t, err != nil)
+	_, err = maker()
+	assert.T(t, err != nil)
+}
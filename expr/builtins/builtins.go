@@ -1,6 +1,7 @@
 package builtins
 
 import (
+	"fmt"
 	"math"
 	"net/mail"
 	"net/url"
@@ -9,13 +10,11 @@ import (
 	"time"
 
 	"github.com/araddon/dateparse"
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
-var _ = u.EMPTY
-
 const yymmTimeLayout = "0601"
 
 func LoadAllBuiltins() {
@@ -27,7 +26,7 @@ func LoadAllBuiltins() {
 	expr.FuncAdd("not", NotFunc)
 	expr.FuncAdd("eq", Eq)
 	expr.FuncAdd("exists", Exists)
-	expr.FuncAdd("now", Now)
+	expr.VolatileFuncAdd("now", Now)
 	expr.FuncAdd("yy", Yy)
 	expr.FuncAdd("yymm", YyMm)
 	expr.FuncAdd("mm", Mm)
@@ -41,10 +40,18 @@ func LoadAllBuiltins() {
 
 	expr.FuncAdd("contains", ContainsFunc)
 	expr.FuncAdd("tolower", Lower)
+	expr.FuncAdd("lower", Lower)
+	expr.FuncAdd("toupper", Upper)
+	expr.FuncAdd("upper", Upper)
 	expr.FuncAdd("toint", ToInt)
 	expr.FuncAdd("split", SplitFunc)
 	expr.FuncAdd("join", JoinFunc)
 	expr.FuncAdd("oneof", OneOfFunc)
+	expr.FuncAdd("row_to_json", RowToMap)
+	expr.FuncAdd("to_map", RowToMap)
+	expr.TableFuncAdd("unnest", UnnestFunc)
+	expr.ArgTypedFuncAdd("greatest", GreatestFunc)
+	expr.ArgTypedFuncAdd("least", LeastFunc)
 	expr.FuncAdd("any", AnyFunc)
 	expr.FuncAdd("all", AllFunc)
 	expr.FuncAdd("email", EmailFunc)
@@ -53,6 +60,16 @@ func LoadAllBuiltins() {
 	expr.FuncAdd("host", HostFunc)
 	expr.FuncAdd("path", UrlPath)
 	expr.FuncAdd("qs", Qs)
+	expr.CoalesceFuncAdd("ifnull", IfNull)
+	expr.CoalesceFuncAdd("isnull", IfNull)
+	expr.CoalesceFuncAdd("nvl", IfNull)
+
+	// aggregates
+	expr.AggregatorAdd("count_distinct", NewCountDistinct)
+	expr.AggregatorAdd("first", NewFirst)
+	expr.AggregatorAdd("last", NewLast)
+	expr.AggregatorAdd("median", NewMedian)
+	expr.AggregatorAdd("percentile", NewPercentile)
 }
 
 // Count:   count occurences of value, ignores the value and ensures it is non null
@@ -64,7 +81,7 @@ func CountFunc(ctx expr.EvalContext, val value.Value) (value.IntValue, bool) {
 	if val.Err() || val.Nil() {
 		return value.NewIntValue(0), true
 	}
-	//u.Infof("???   vals=[%v]", val.Value())
+	//logging.Infof("???   vals=[%v]", val.Value())
 	return value.NewIntValue(1), true
 }
 
@@ -84,7 +101,7 @@ func SqrtFunc(ctx expr.EvalContext, val value.Value) (value.NumberValue, bool) {
 	}
 	fv := nv.Float()
 	fv = math.Sqrt(fv)
-	//u.Infof("???   vals=[%v]", val.Value())
+	//logging.Infof("???   vals=[%v]", val.Value())
 	return value.NewNumberValue(fv), true
 }
 
@@ -96,7 +113,7 @@ func SqrtFunc(ctx expr.EvalContext, val value.Value) (value.NumberValue, bool) {
 //
 func PowFunc(ctx expr.EvalContext, val, toPower value.Value) (value.NumberValue, bool) {
 	//Pow(x, y float64) float64
-	//u.Infof("powFunc:  %T:%v %T:%v ", val, val.Value(), toPower, toPower.Value())
+	//logging.Infof("powFunc:  %T:%v %T:%v ", val, val.Value(), toPower, toPower.Value())
 	if val.Err() || val.Nil() {
 		return value.NewNumberValue(0), false
 	}
@@ -108,7 +125,7 @@ func PowFunc(ctx expr.EvalContext, val, toPower value.Value) (value.NumberValue,
 		return value.NewNumberValue(0), false
 	}
 	fv = math.Pow(fv, pow)
-	//u.Infof("pow ???   vals=[%v]", fv, pow)
+	//logging.Infof("pow ???   vals=[%v]", fv, pow)
 	return value.NewNumberValue(fv), true
 }
 
@@ -119,7 +136,7 @@ func PowFunc(ctx expr.EvalContext, val, toPower value.Value) (value.NumberValue,
 func Eq(ctx expr.EvalContext, itemA, itemB value.Value) (value.BoolValue, bool) {
 
 	eq, err := value.Equal(itemA, itemB)
-	//u.Infof("EQ:  %v  %v  ==? %v", itemA, itemB, eq)
+	//logging.Infof("EQ:  %v  %v  ==? %v", itemA, itemB, eq)
 	if err == nil {
 		return value.NewBoolValue(eq), true
 	}
@@ -148,6 +165,18 @@ func NotFunc(ctx expr.EvalContext, item value.Value) (value.BoolValue, bool) {
 	return value.BoolValueFalse, false
 }
 
+// IfNull:  the second arg if the first is NULL, registered as the null
+//  coalescing ifnull, isnull, nvl (the vm does not evaluate @b unless needed)
+//
+//     ifnull(nickname, name)   => name if nickname is NULL
+//
+func IfNull(ctx expr.EvalContext, a, b value.Value) (value.Value, bool) {
+	if a == nil || a.Type() == value.NilType {
+		return b, true
+	}
+	return a, true
+}
+
 // > GreaterThan
 //  Must be able to convert items to Floats or else not ok
 //
@@ -209,7 +238,7 @@ func LtFunc(ctx expr.EvalContext, lv, rv value.Value) (value.BoolValue, bool) {
 //
 func Exists(ctx expr.EvalContext, item interface{}) (value.BoolValue, bool) {
 
-	//u.Debugf("Exists():  %T  %v", item, item)
+	//logging.Debugf("Exists():  %T  %v", item, item)
 	switch node := item.(type) {
 	case expr.IdentityNode:
 		_, ok := ctx.Get(node.Text)
@@ -260,7 +289,7 @@ func ContainsFunc(ctx expr.EvalContext, lv, rv value.Value) (value.BoolValue, bo
 	if !leftOk || !rightOk {
 		return value.BoolValueFalse, false
 	}
-	//u.Infof("Contains(%v, %v)", left, right)
+	//logging.Infof("Contains(%v, %v)", left, right)
 	if left == "" || right == "" {
 		return value.BoolValueFalse, false
 	}
@@ -281,6 +310,17 @@ func Lower(ctx expr.EvalContext, item value.Value) (value.StringValue, bool) {
 	return value.NewStringValue(strings.ToLower(val)), true
 }
 
+// String upper function
+//   must be able to convert to string
+//
+func Upper(ctx expr.EvalContext, item value.Value) (value.StringValue, bool) {
+	val, ok := value.ToString(item.Rv())
+	if !ok {
+		return value.EmptyStringValue, false
+	}
+	return value.NewStringValue(strings.ToUpper(val)), true
+}
+
 // choose OneOf these fields, first non-null
 func OneOfFunc(ctx expr.EvalContext, vals ...value.Value) (value.Value, bool) {
 	for _, v := range vals {
@@ -293,6 +333,87 @@ func OneOfFunc(ctx expr.EvalContext, vals ...value.Value) (value.Value, bool) {
 	return value.NilValueVal, true
 }
 
+// Greatest:  the largest of the arguments, compared as value.CompareValues
+//   NULL args are ignored (NULL if all are NULL) unless the context's
+//   expr.NullArgsPolicy is NullArgsNull, args with no ordering between
+//   them (ie, string and int) can not be evaluated
+//
+//     greatest(1, 5, 3)         => 5, true
+//     greatest("a", "c", "b")   => "c", true
+//     greatest(1, "a")          => -- Could not be evaluated
+//
+func GreatestFunc(ctx expr.EvalContext, vals ...value.Value) (value.Value, bool) {
+	return extremeValue(ctx, 1, vals)
+}
+
+// Least:  the smallest of the arguments, see Greatest
+//
+//     least(1, 5, 3)         => 1, true
+//
+func LeastFunc(ctx expr.EvalContext, vals ...value.Value) (value.Value, bool) {
+	return extremeValue(ctx, -1, vals)
+}
+
+// the value v for which compare(v, others) == @sign
+func extremeValue(ctx expr.EvalContext, sign int, vals []value.Value) (value.Value, bool) {
+	propagate := false
+	if nctx, ok := ctx.(expr.ContextNullArgs); ok {
+		propagate = nctx.NullArgs() == expr.NullArgsNull
+	}
+	var coll value.Collation
+	if cctx, ok := ctx.(expr.ContextCollation); ok {
+		coll = cctx.Collation()
+	}
+	var best value.Value
+	for _, v := range vals {
+		if v == nil || v.Type() == value.NilType {
+			if propagate {
+				return value.NewNilValue(), true
+			}
+			continue
+		}
+		if best == nil {
+			best = v
+			continue
+		}
+		c, err := value.CompareValues(v, best, coll)
+		if err != nil {
+			return value.NewNilValue(), false
+		}
+		if c == sign {
+			best = v
+		}
+	}
+	if best == nil {
+		return value.NewNilValue(), true
+	}
+	return best, true
+}
+
+// Unnest:  table valued func (FROM only) with a row per element of its
+//   array args, NULL args produce no rows
+//
+//     SELECT name, t FROM users, unnest(tags) AS t
+//     SELECT t FROM unnest(split("a,b,c", ",")) AS t   => "a", "b", "c"
+//
+func UnnestFunc(ctx expr.EvalContext, args []value.Value) ([]value.Value, error) {
+	rows := make([]value.Value, 0)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case nil, value.NilValue:
+		case value.SliceValue:
+			rows = append(rows, v.Val()...)
+		case value.StringsValue:
+			for _, s := range v.Val() {
+				rows = append(rows, value.NewStringValue(s))
+			}
+		default:
+			return nil, fmt.Errorf("unnest of non array value: %v", arg)
+		}
+	}
+	return rows, nil
+}
+
 // Any:  Answers True/False if any of the arguments evaluate to truish (javascripty)
 //       type definintion of true
 //
@@ -421,7 +542,7 @@ func ToInt(ctx expr.EvalContext, item value.Value) (value.IntValue, bool) {
 //
 func Now(ctx expr.EvalContext, items ...value.Value) (value.TimeValue, bool) {
 
-	u.Debugf("Now: %v", ctx.Ts())
+	logging.Debugf("Now: %v", ctx.Ts())
 	if !ctx.Ts().IsZero() {
 		t := ctx.Ts()
 		return value.NewTimeValue(t), true
@@ -430,6 +551,26 @@ func Now(ctx expr.EvalContext, items ...value.Value) (value.TimeValue, bool) {
 	return value.NewTimeValue(time.Now().In(time.UTC)), true
 }
 
+// RowToMap:  the whole current row as a single map value, ie for
+//  passing through rows in etl.   The only allowed arg is *
+//
+//     row_to_json(*)    =>  {"user_id":"abc","item_count":5}, true
+//     to_map()          =>  {"user_id":"abc","item_count":5}, true
+//
+func RowToMap(ctx expr.EvalContext, items ...value.Value) (value.MapValue, bool) {
+	if len(items) > 1 || (len(items) == 1 && items[0].ToString() != "*") {
+		return value.NewMapValue(nil), false
+	}
+	if ctx == nil {
+		return value.NewMapValue(nil), false
+	}
+	row := make(map[string]value.Value)
+	for k, v := range ctx.Row() {
+		row[k] = v
+	}
+	return value.NewMapValue(row), true
+}
+
 // Get year in integer from field, must be able to convert to date
 //
 //    yy()                 =>  15, true    // assuming it is 2015
@@ -449,7 +590,7 @@ func Yy(ctx expr.EvalContext, items ...value.Value) (value.IntValue, bool) {
 		if !ok {
 			return value.NewIntValue(0), false
 		}
-		//u.Infof("v=%v   %v  ", v, item.Rv())
+		//logging.Infof("v=%v   %v  ", v, item.Rv())
 		if t, err := dateparse.ParseAny(dateStr); err != nil {
 			return value.NewIntValue(0), false
 		} else {
@@ -464,7 +605,7 @@ func Yy(ctx expr.EvalContext, items ...value.Value) (value.IntValue, bool) {
 	} else if yy >= 1900 {
 		yy = yy - 1900
 	}
-	//u.Infof("%v   yy = %v", item, yy)
+	//logging.Infof("%v   yy = %v", item, yy)
 	return value.NewIntValue(int64(yy)), true
 }
 
@@ -486,7 +627,7 @@ func Mm(ctx expr.EvalContext, items ...value.Value) (value.IntValue, bool) {
 		if !ok {
 			return value.NewIntValue(0), false
 		}
-		//u.Infof("v=%v   %v  ", v, items[0].Rv())
+		//logging.Infof("v=%v   %v  ", v, items[0].Rv())
 		if t, err := dateparse.ParseAny(dateStr); err == nil {
 			return value.NewIntValue(int64(t.Month())), true
 		}
@@ -509,7 +650,7 @@ func YyMm(ctx expr.EvalContext, items ...value.Value) (value.StringValue, bool)
 		if !ok {
 			return value.EmptyStringValue, false
 		}
-		//u.Infof("v=%v   %v  ", v, items[0].Rv())
+		//logging.Infof("v=%v   %v  ", v, items[0].Rv())
 		if t, err := dateparse.ParseAny(dateStr); err == nil {
 			return value.NewStringValue(t.Format(yymmTimeLayout)), true
 		}
@@ -531,7 +672,7 @@ func DayOfWeek(ctx expr.EvalContext, items ...value.Value) (value.IntValue, bool
 		if !ok {
 			return value.NewIntValue(0), false
 		}
-		//u.Infof("v=%v   %v  ", v, items[0].Rv())
+		//logging.Infof("v=%v   %v  ", v, items[0].Rv())
 		if t, err := dateparse.ParseAny(dateStr); err == nil {
 			return value.NewIntValue(int64(t.Weekday())), true
 		}
@@ -553,7 +694,7 @@ func HourOfWeek(ctx expr.EvalContext, items ...value.Value) (value.IntValue, boo
 		if !ok {
 			return value.NewIntValue(0), false
 		}
-		//u.Infof("v=%v   %v  ", v, items[0].Rv())
+		//logging.Infof("v=%v   %v  ", v, items[0].Rv())
 		if t, err := dateparse.ParseAny(dateStr); err == nil {
 			return value.NewIntValue(int64(t.Weekday()*24) + int64(t.Hour())), true
 		}
@@ -574,7 +715,7 @@ func HourOfDay(ctx expr.EvalContext, items ...value.Value) (value.IntValue, bool
 		if !ok {
 			return value.NewIntValue(0), false
 		}
-		//u.Infof("v=%v   %v  ", v, items[0].Rv())
+		//logging.Infof("v=%v   %v  ", v, items[0].Rv())
 		if t, err := dateparse.ParseAny(dateStr); err == nil {
 			return value.NewIntValue(int64(t.Hour())), true
 		}
@@ -593,7 +734,7 @@ func ToTimestamp(ctx expr.EvalContext, item value.Value) (value.IntValue, bool)
 	}
 
 	if t, err := dateparse.ParseAny(dateStr); err == nil {
-		//u.Infof("v=%v   %v  unix=%v", item, item.Rv(), t.Unix())
+		//logging.Infof("v=%v   %v  unix=%v", item, item.Rv(), t.Unix())
 		return value.NewIntValue(int64(t.Unix())), true
 	}
 
@@ -607,7 +748,7 @@ func ToDate(ctx expr.EvalContext, item value.Value) (value.TimeValue, bool) {
 	if !ok {
 		return value.TimeZeroValue, false
 	}
-	//u.Infof("v=%v   %v  ", v, item.Rv())
+	//logging.Infof("v=%v   %v  ", v, item.Rv())
 	if t, err := dateparse.ParseAny(dateStr); err == nil {
 		return value.NewTimeValue(t), true
 	}
@@ -718,7 +859,7 @@ func HostFunc(ctx expr.EvalContext, item value.Value) (value.StringValue, bool)
 		urlstr = "http://" + urlstr
 	}
 	if urlParsed, err := url.Parse(urlstr); err == nil {
-		//u.Infof("url.parse: %#v", urlParsed)
+		//logging.Infof("url.parse: %#v", urlParsed)
 		return value.NewStringValue(urlParsed.Host), true
 	}
 
@@ -753,7 +894,7 @@ func UrlPath(ctx expr.EvalContext, item value.Value) (value.StringValue, bool) {
 		urlstr = "http://" + urlstr
 	}
 	if urlParsed, err := url.Parse(urlstr); err == nil {
-		//u.Infof("url.parse: %#v", urlParsed)
+		//logging.Infof("url.parse: %#v", urlParsed)
 		return value.NewStringValue(urlParsed.Path), true
 	}
 
@@ -793,7 +934,7 @@ func Qs(ctx expr.EvalContext, urlItem, keyItem value.Value) (value.StringValue,
 		urlstr = "http://" + urlstr
 	}
 	if urlParsed, err := url.Parse(urlstr); err == nil {
-		//u.Infof("url.parse: %#v", urlParsed)
+		//logging.Infof("url.parse: %#v", urlParsed)
 		qsval, ok := urlParsed.Query()[keyVal]
 		if !ok {
 			return value.EmptyStringValue, false
@@ -9,6 +9,7 @@ import (
 
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/lex"
 	"github.com/araddon/qlbridge/value"
 	"github.com/araddon/qlbridge/vm"
@@ -89,6 +90,8 @@ var builtinTests = []testBuiltins{
 	{`contains(price,"$")`, value.BoolValueTrue},
 
 	{`tolower("Apple")`, value.NewStringValue("apple")},
+	{`lower("Apple")`, value.NewStringValue("apple")},
+	{`upper("Apple")`, value.NewStringValue("APPLE")},
 
 	{`join("apple", event, "oranges", "--")`, value.NewStringValue("apple--hello--oranges")},
 
@@ -178,6 +181,24 @@ var builtinTests = []testBuiltins{
 
 	{`count(4)`, value.NewIntValue(1)},
 	{`count(not_a_field)`, value.ErrValue},
+
+	{`ifnull(not_a_field, "x")`, value.NewStringValue("x")},
+	{`ifnull(event, "x")`, value.NewStringValue("hello")},
+	{`isnull(not_a_field, "x")`, value.NewStringValue("x")},
+	{`isnull(event, "x")`, value.NewStringValue("hello")},
+	{`nvl(not_a_field, "x")`, value.NewStringValue("x")},
+	{`nvl(event, "x")`, value.NewStringValue("hello")},
+
+	{`greatest(1, 5, 3)`, value.NewIntValue(5)},
+	{`greatest(1, 5.5, 3)`, value.NewNumberValue(5.5)},
+	{`greatest("a", "c", "b")`, value.NewStringValue("c")},
+	{`greatest(not_a_field, 2)`, value.NewIntValue(2)},
+	{`greatest(1, "a")`, value.ErrValue},
+	{`least(4, 2, 3)`, value.NewIntValue(2)},
+	{`least(4, 2.5, 3)`, value.NewNumberValue(2.5)},
+	{`least("b", "a", "c")`, value.NewStringValue("a")},
+	{`least(not_a_field, 2)`, value.NewIntValue(2)},
+	{`least("a", 1)`, value.ErrValue},
 }
 
 // Need to think about this a bit, as expression vm resolves IdentityNodes in advance
@@ -228,3 +249,102 @@ func TestBuiltins(t *testing.T) {
 
 	}
 }
+
+func TestIfNullShortCircuit(t *testing.T) {
+
+	calls := 0
+	expr.FuncAdd("counted", func(ctx expr.EvalContext, v value.Value) (value.Value, bool) {
+		calls++
+		return v, true
+	})
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"name": value.NewStringValue("bob"),
+		"none": value.NewNilValue(),
+	})
+	for _, fn := range []string{"ifnull", "isnull", "nvl"} {
+		for _, test := range []struct {
+			ql    string
+			val   string
+			calls int
+		}{
+			{fn + `(name, counted("x"))`, "bob", 0},
+			{fn + `(none, counted("x"))`, "x", 1},
+			{fn + `(not_a_field, counted("x"))`, "x", 1},
+		} {
+			exprVm, err := vm.NewVm(test.ql)
+			assert.Tf(t, err == nil, "parse %v: %v", test.ql, err)
+			compiled, err := vm.Compile(exprVm.Tree.Root)
+			assert.Tf(t, err == nil, "compile %v: %v", test.ql, err)
+
+			calls = 0
+			v, ok := vm.Eval(ctx, exprVm.Tree.Root)
+			assert.Tf(t, ok && v.ToString() == test.val, "%v should be %v: %v", test.ql, test.val, v)
+			assert.Tf(t, calls == test.calls, "%v evaluated b %d times", test.ql, calls)
+
+			calls = 0
+			v, ok = compiled(ctx)
+			assert.Tf(t, ok && v.ToString() == test.val, "compiled %v should be %v: %v", test.ql, test.val, v)
+			assert.Tf(t, calls == test.calls, "compiled %v evaluated b %d times", test.ql, calls)
+		}
+	}
+}
+
+func TestGreatestNullArgs(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"five": value.NewIntValue(5),
+		"none": value.NewNilValue(),
+	})
+	eval := func(ql string) value.Value {
+		exprVm, err := vm.NewVm(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		v, ok := vm.Eval(ctx, exprVm.Tree.Root)
+		assert.Tf(t, ok, "%v should evaluate", ql)
+		return v
+	}
+
+	// default, NULLs are ignored
+	v := eval(`greatest(none, five, 2)`)
+	assert.Tf(t, v.Value() == int64(5), "should be 5: %v", v)
+	v = eval(`least(none, five, 2)`)
+	assert.Tf(t, v.Value() == int64(2), "should be 2: %v", v)
+	v = eval(`greatest(none, not_a_field)`)
+	assert.Tf(t, v.Type() == value.NilType, "all NULL is NULL: %v", v)
+
+	// mysql-ish, any NULL is NULL
+	ctx.NullArgsPolicy = expr.NullArgsNull
+	v = eval(`greatest(none, five, 2)`)
+	assert.Tf(t, v.Type() == value.NilType, "should be NULL: %v", v)
+	v = eval(`least(five, 2)`)
+	assert.Tf(t, v.Value() == int64(2), "should be 2: %v", v)
+}
+
+func TestSubscriptArgs(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"tags": value.NewMapValue(map[string]value.Value{
+			"env": value.NewStringValue("prod"),
+		}),
+		"arr": value.NewSliceValues([]value.Value{value.NewStringValue("a")}),
+	})
+	for _, test := range []struct {
+		ql  string
+		val interface{}
+	}{
+		{`upper(tags["env"])`, "PROD"},
+		{`exists(tags["env"])`, true},
+		{`exists(tags["nope"])`, false},
+		{`upper(arr[0])`, "A"},
+		{`eq(arr[0], "a")`, true},
+	} {
+		exprVm, err := vm.NewVm(test.ql)
+		assert.Tf(t, err == nil, "parse %v: %v", test.ql, err)
+		v, ok := vm.Eval(ctx, exprVm.Tree.Root)
+		assert.Tf(t, ok && v.Value() == test.val, "%v should be %v: %v", test.ql, test.val, v)
+
+		compiled, err := vm.Compile(exprVm.Tree.Root)
+		assert.Tf(t, err == nil, "compile %v: %v", test.ql, err)
+		v, ok = compiled(ctx)
+		assert.Tf(t, ok && v.Value() == test.val, "compiled %v should be %v: %v", test.ql, test.val, v)
+	}
+}
@@ -0,0 +1,99 @@
+package builtins
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+const (
+	HllPrecisionMin     = 4
+	HllPrecisionMax     = 18
+	HllPrecisionDefault = 14
+)
+
+// HyperLogLog is a cardinality estimator using bounded memory (2^precision
+// bytes), standard error is approximately  1.04 / sqrt(2^precision)
+//
+//   precision 14 => 16KB, ~0.8% error
+//
+type HyperLogLog struct {
+	p    uint8
+	m    uint32
+	regs []uint8
+}
+
+func NewHyperLogLog(precision int) (*HyperLogLog, error) {
+	if precision < HllPrecisionMin || precision > HllPrecisionMax {
+		return nil, fmt.Errorf("hll precision must be between %d and %d: %d", HllPrecisionMin, HllPrecisionMax, precision)
+	}
+	m := uint32(1) << uint(precision)
+	return &HyperLogLog{p: uint8(precision), m: m, regs: make([]uint8, m)}, nil
+}
+
+// Add a string to the set
+func (h *HyperLogLog) Add(s string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(s))
+	x := hllMix(hasher.Sum64())
+	idx := x >> (64 - h.p)
+	// remaining bits, with a sentinel so rank is bounded
+	w := x<<h.p | 1<<(h.p-1)
+	rank := uint8(1)
+	for w&(1<<63) == 0 {
+		rank++
+		w <<= 1
+	}
+	if rank > h.regs[idx] {
+		h.regs[idx] = rank
+	}
+}
+
+// Estimate the cardinality of set
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(h.m)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.regs {
+		sum += 1.0 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := hllAlpha(h.m) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		// small range correction, linear counting
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(est + 0.5)
+}
+
+// Reset the registers
+func (h *HyperLogLog) Reset() {
+	for i := range h.regs {
+		h.regs[i] = 0
+	}
+}
+
+func hllAlpha(m uint32) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+// fnv has weak avalanche on short similar keys, mix the bits
+// (murmur3 finalizer)
+func hllMix(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,69 @@
+package expr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Dump a node tree as an indented debug string, each node annotated
+// with its NodeType and inferred ValueType
+//
+//     BinaryNode op=">" type=bool
+//       IdentityNode x type=unknown
+//       NumberNode 5 type=number
+//
+func Dump(n Node) string {
+	buf := &bytes.Buffer{}
+	dumpNode(buf, n, 0)
+	return buf.String()
+}
+
+func dumpNode(buf *bytes.Buffer, n Node, depth int) {
+	buf.WriteString(strings.Repeat("  ", depth))
+	if n == nil {
+		buf.WriteString("<nil>\n")
+		return
+	}
+	vt := InferValueType(n)
+	switch nt := n.(type) {
+	case *IdentityNode:
+		fmt.Fprintf(buf, "%s %s type=%s\n", nt.NodeType(), nt.Text, vt)
+	case *StringNode:
+		fmt.Fprintf(buf, "%s %q type=%s\n", nt.NodeType(), nt.Text, vt)
+	case *NumberNode:
+		fmt.Fprintf(buf, "%s %s type=%s\n", nt.NodeType(), nt.Text, vt)
+	case *NullNode:
+		fmt.Fprintf(buf, "%s NULL type=%s\n", nt.NodeType(), vt)
+	case *FuncNode:
+		fmt.Fprintf(buf, "%s %s() type=%s\n", nt.NodeType(), nt.Name, vt)
+		for _, arg := range nt.Args {
+			dumpNode(buf, arg, depth+1)
+		}
+	case *BinaryNode:
+		fmt.Fprintf(buf, "%s op=%q type=%s\n", nt.NodeType(), nt.Operator.V, vt)
+		for _, arg := range nt.Args {
+			dumpNode(buf, arg, depth+1)
+		}
+	case *TriNode:
+		fmt.Fprintf(buf, "%s op=%q type=%s\n", nt.NodeType(), nt.Operator.V, vt)
+		for _, arg := range nt.Args {
+			dumpNode(buf, arg, depth+1)
+		}
+	case *UnaryNode:
+		fmt.Fprintf(buf, "%s op=%q type=%s\n", nt.NodeType(), nt.Operator.V, vt)
+		dumpNode(buf, nt.Arg, depth+1)
+	case *MultiArgNode:
+		fmt.Fprintf(buf, "%s op=%q type=%s\n", nt.NodeType(), nt.Operator.V, vt)
+		for _, arg := range nt.Args {
+			dumpNode(buf, arg, depth+1)
+		}
+	case *RowConstructorNode:
+		fmt.Fprintf(buf, "%s type=%s\n", nt.NodeType(), vt)
+		for _, arg := range nt.Args {
+			dumpNode(buf, arg, depth+1)
+		}
+	default:
+		fmt.Fprintf(buf, "%s %s\n", n.NodeType(), n.String())
+	}
+}
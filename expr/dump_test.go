@@ -0,0 +1,41 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/bmizerany/assert"
+)
+
+func TestDump(t *testing.T) {
+	tree, err := expr.ParseExpression(`tolower(name) == "bob" AND (score + 5) > 10.5 AND x IN (1, "a") AND NOT exists(y) AND z BETWEEN 1 AND 3`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	dump := expr.Dump(tree.Root)
+	assert.Equal(t, dumpGolden, dump)
+}
+
+var dumpGolden = `BinaryNode op="AND" type=bool
+  BinaryNode op="AND" type=bool
+    BinaryNode op="AND" type=bool
+      BinaryNode op="AND" type=bool
+        BinaryNode op="==" type=bool
+          FuncNode tolower() type=string
+            IdentityNode name type=unknown
+          StringNode "bob" type=string
+        BinaryNode op=">" type=bool
+          BinaryNode op="+" type=number
+            IdentityNode score type=unknown
+            NumberNode 5 type=number
+          NumberNode 10.5 type=number
+      MultiArgNode op="IN" type=bool
+        IdentityNode x type=unknown
+        NumberNode 1 type=number
+        StringNode "a" type=string
+    UnaryNode op="NOT" type=bool
+      FuncNode exists() type=bool
+        IdentityNode y type=unknown
+  TriNode op="BETWEEN" type=bool
+    IdentityNode z type=unknown
+    NumberNode 1 type=number
+    NumberNode 3 type=number
+`
@@ -0,0 +1,163 @@
+package expr
+
+import (
+	"bytes"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+var elasticRangeOps = map[lex.TokenType]string{
+	lex.TokenGT: "gt",
+	lex.TokenGE: "gte",
+	lex.TokenLT: "lt",
+	lex.TokenLE: "lte",
+}
+
+// ToElasticQuery translates a where Node into an elasticsearch bool query
+//  of term, terms, range and wildcard queries
+//
+//     status = "active" AND age > 21  =>
+//        {"bool": {"filter": [{"term": {"status": "active"}}, {"range": {"age": {"gt": 21}}}]}}
+//
+//  a node (or any part of it) that cannot be translated is ErrNotSupported,
+//  use SplitPushdown() to push down what can be and filter the residual
+//  locally.
+func ToElasticQuery(n Node) (map[string]interface{}, error) {
+	switch nt := n.(type) {
+	case *BinaryNode:
+		switch nt.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenLogicOr:
+			return elasticLogical(nt)
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			col, val, _, ok := identityLiteral(nt)
+			if !ok || val == nil {
+				return nil, ErrNotSupported
+			}
+			return elasticTerm(col, val), nil
+		case lex.TokenNE:
+			col, val, _, ok := identityLiteral(nt)
+			if !ok || val == nil {
+				return nil, ErrNotSupported
+			}
+			return elasticBool("must_not", []interface{}{elasticTerm(col, val)}), nil
+		case lex.TokenLike:
+			col, ok := nt.Args[0].(*IdentityNode)
+			pattern, isStr := nt.Args[1].(*StringNode)
+			if !ok || !isStr || col.IsBooleanIdentity() {
+				return nil, ErrNotSupported
+			}
+			wildcard, ok := likeToWildcard(pattern.Text)
+			if !ok {
+				return nil, ErrNotSupported
+			}
+			return map[string]interface{}{"wildcard": map[string]interface{}{col.Text: wildcard}}, nil
+		}
+		op, ok := elasticRangeOps[nt.Operator.T]
+		if !ok {
+			return nil, ErrNotSupported
+		}
+		col, val, flipped, ok := identityLiteral(nt)
+		if !ok || val == nil {
+			return nil, ErrNotSupported
+		}
+		if flipped {
+			op = elasticRangeOps[flipComparison(nt.Operator.T)]
+		}
+		return elasticRange(col, map[string]interface{}{op: val}), nil
+	case *MultiArgNode:
+		if nt.Operator.T != lex.TokenIN || len(nt.Args) < 2 {
+			return nil, ErrNotSupported
+		}
+		col, ok := nt.Args[0].(*IdentityNode)
+		if !ok || col.IsBooleanIdentity() {
+			return nil, ErrNotSupported
+		}
+		vals := make([]interface{}, 0, len(nt.Args)-1)
+		for _, arg := range nt.Args[1:] {
+			v, ok := literalValue(arg)
+			if !ok || v == nil {
+				return nil, ErrNotSupported
+			}
+			vals = append(vals, v)
+		}
+		return map[string]interface{}{"terms": map[string]interface{}{col.Text: vals}}, nil
+	case *TriNode:
+		if nt.Operator.T != lex.TokenBetween {
+			return nil, ErrNotSupported
+		}
+		col, ok := nt.Args[0].(*IdentityNode)
+		lo, loOk := literalValue(nt.Args[1])
+		hi, hiOk := literalValue(nt.Args[2])
+		if !ok || !loOk || !hiOk || lo == nil || hi == nil || col.IsBooleanIdentity() {
+			return nil, ErrNotSupported
+		}
+		return elasticRange(col.Text, map[string]interface{}{"gte": lo, "lte": hi}), nil
+	case *UnaryNode:
+		if nt.Operator.T != lex.TokenNegate {
+			return nil, ErrNotSupported
+		}
+		q, err := ToElasticQuery(nt.Arg)
+		if err != nil {
+			return nil, err
+		}
+		return elasticBool("must_not", []interface{}{q}), nil
+	}
+	return nil, ErrNotSupported
+}
+
+func elasticLogical(n *BinaryNode) (map[string]interface{}, error) {
+	queries := make([]interface{}, 0, 2)
+	for _, arg := range flattenLogical(n) {
+		q, err := ToElasticQuery(arg)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	if n.Operator.T == lex.TokenLogicOr {
+		q := elasticBool("should", queries)
+		q["bool"].(map[string]interface{})["minimum_should_match"] = 1
+		return q, nil
+	}
+	return elasticBool("filter", queries), nil
+}
+
+func elasticBool(clause string, queries []interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{clause: queries}}
+}
+
+func elasticTerm(col string, val interface{}) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{col: val}}
+}
+
+func elasticRange(col string, bounds map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{col: bounds}}
+}
+
+// convert a sql LIKE pattern to a wildcard,  % is * and _ is ?  unless
+//  escaped by \ (the vm LikeEscapeDefault), literal * ? and \ are escaped.
+//  Not ok if the pattern ends in the escape
+func likeToWildcard(pattern string) (string, bool) {
+	var buf bytes.Buffer
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case r == '*', r == '?', escaped && r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case escaped:
+			buf.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		case r == '%':
+			buf.WriteByte('*')
+		case r == '_':
+			buf.WriteByte('?')
+		default:
+			buf.WriteRune(r)
+		}
+		escaped = false
+	}
+	return buf.String(), !escaped
+}
@@ -0,0 +1,56 @@
+package expr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestToElasticQuery(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{`status = "active"`, `{"term":{"status":"active"}}`},
+		{`status != "deleted"`, `{"bool":{"must_not":[{"term":{"status":"deleted"}}]}}`},
+		{`age > 21`, `{"range":{"age":{"gt":21}}}`},
+		{`age <= 65`, `{"range":{"age":{"lte":65}}}`},
+		{`21 >= age`, `{"range":{"age":{"lte":21}}}`},
+		{`age BETWEEN 18 AND 65`, `{"range":{"age":{"gte":18,"lte":65}}}`},
+		{`status IN ("a", "b", 3)`, `{"terms":{"status":["a","b",3]}}`},
+		{`email LIKE "bob_*%"`, `{"wildcard":{"email":"bob?\\**"}}`},
+		{`email LIKE "a\_b?\%%"`, `{"wildcard":{"email":"a_b\\?%*"}}`},
+		{`a = 1 AND b = true AND c < 2.5`,
+			`{"bool":{"filter":[{"term":{"a":1}},{"term":{"b":true}},{"range":{"c":{"lt":2.5}}}]}}`},
+		{`a = 1 OR b = 2`, `{"bool":{"minimum_should_match":1,"should":[{"term":{"a":1}},{"term":{"b":2}}]}}`},
+		{`NOT (a = 1)`, `{"bool":{"must_not":[{"term":{"a":1}}]}}`},
+	}
+	for _, test := range tests {
+		tree, err := ParseExpression(test.where)
+		assert.Tf(t, err == nil, "no error %v", err)
+		q, err := ToElasticQuery(tree.Root)
+		assert.Tf(t, err == nil, "no error %v for %s", err, test.where)
+		by, _ := json.Marshal(q)
+		assert.Tf(t, string(by) == test.want, "%s  want %s got %s", test.where, test.want, string(by))
+	}
+
+	for _, where := range []string{`a = b`, `a + 1 > 2`, `a = 1 OR b = c`, `a LIKE b`} {
+		tree, err := ParseExpression(where)
+		assert.Tf(t, err == nil, "no error %v", err)
+		_, err = ToElasticQuery(tree.Root)
+		assert.Tf(t, err == ErrNotSupported, "%s not supported: %v", where, err)
+	}
+	_, ok := likeToWildcard(`50\`)
+	assert.Tf(t, !ok, "pattern ending in the escape")
+
+	// push down what translates, the rest is filtered locally
+	tree, err := ParseExpression(`a = 1 AND b = c AND d > 2`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	pushed, residual := SplitPushdown(tree.Root, func(n Node) bool {
+		_, err := ToElasticQuery(n)
+		return err == nil
+	})
+	assert.Tf(t, pushed.String() == "a = 1 AND d > 2", "pushed: %v", pushed)
+	assert.Tf(t, residual.String() == "b = c", "residual: %v", residual)
+}
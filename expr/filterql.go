@@ -0,0 +1,111 @@
+package expr
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+// FilterStatement is a parsed FilterQL rule, a WHERE-only query language
+//  for using qlbridge as a filter engine, see ParseFilterQL
+type FilterStatement struct {
+	Raw    string // the original filter text
+	Filter Node   // the filter expression, a boolean node
+}
+
+func (m *FilterStatement) String() string { return "FILTER " + m.Filter.StringAST() }
+
+// ParseFilterQL parses a FilterQL rule, which is FILTER followed by an
+//  expression, AND(...) and OR(...) take a comma separated list of filters
+//  and nest, NOT negates a filter
+//
+//     FILTER AND (
+//         score > 20,
+//         OR ( country == "us", NOT email LIKE "*@example.com" ),
+//         tolower(name) IN ("bob", "sally")
+//     )
+func ParseFilterQL(filterText string) (stmt *FilterStatement, err error) {
+	l := lex.NewLexer(filterText, lex.LogicalExpressionDialect)
+	pager := NewLexTokenPager(l)
+	pager.end = lex.TokenEOF
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			stmt, err = nil, fmt.Errorf("filterql: %v", r)
+		}
+	}()
+
+	if tok := pager.Cur(); tok.T != lex.TokenIdentity || strings.ToUpper(tok.V) != "FILTER" {
+		return nil, fmt.Errorf("filterql: expected FILTER but got %v", tok)
+	}
+	pager.Next()
+	node := parseFilter(pager)
+	if tok := pager.Cur(); tok.T != lex.TokenEOF && tok.T != lex.TokenEOS {
+		return nil, fmt.Errorf("filterql: unexpected %v after filter", tok)
+	}
+	if err := node.Check(); err != nil {
+		return nil, err
+	}
+	return &FilterStatement{Raw: filterText, Filter: node}, nil
+}
+
+// a filter is an AND(...)/OR(...) list, a NOT filter, or an expression
+func parseFilter(pager *LexTokenPager) Node {
+	switch tok := pager.Cur(); tok.T {
+	case lex.TokenLogicAnd, lex.TokenAnd, lex.TokenLogicOr, lex.TokenOr:
+		if pager.Peek().T == lex.TokenLeftParenthesis {
+			return parseFilterList(pager, tok)
+		}
+	case lex.TokenNegate:
+		switch pager.Peek().T {
+		case lex.TokenLogicAnd, lex.TokenAnd, lex.TokenLogicOr, lex.TokenOr:
+			pager.Next()
+			return NewUnary(tok, parseFilter(pager))
+		}
+	}
+	t := NewTree(pager)
+	t.runCheck = true
+	n := t.O(0)
+	if n == nil {
+		panic(fmt.Errorf("expected filter expression but got %v", pager.Cur()))
+	}
+	return n
+}
+
+// AND ( filter, filter, ... ) is folded into the binary nodes of @op
+func parseFilterList(pager *LexTokenPager, op lex.Token) Node {
+	pager.Next() // Consume AND/OR
+	pager.Next() // Consume (
+	var n Node
+	for {
+		arg := parseFilter(pager)
+		if bn, ok := arg.(*BinaryNode); ok {
+			switch bn.Operator.T {
+			case lex.TokenLogicAnd, lex.TokenAnd, lex.TokenLogicOr, lex.TokenOr:
+				// the list groups it, ie  AND (x OR y, z)  is  (x OR y) AND z
+				bn.Paren = true
+			}
+		}
+		if n == nil {
+			n = arg
+		} else {
+			n = NewBinaryNode(op, n, arg)
+		}
+		switch tok := pager.Cur(); tok.T {
+		case lex.TokenComma:
+			pager.Next()
+		case lex.TokenRightParenthesis:
+			pager.Next()
+			if bn, ok := n.(*BinaryNode); ok && bn.Operator.T == op.T {
+				bn.Paren = true
+			}
+			return n
+		default:
+			panic(fmt.Errorf("expected , or ) in %s list but got %v", op.V, tok))
+		}
+	}
+}
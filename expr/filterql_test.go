@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParseFilterQL(t *testing.T) {
+
+	tests := []struct {
+		filter string
+		ast    string
+	}{
+		{`FILTER x > 5`, `FILTER x > 5`},
+		{`FILTER AND (x > 5, y == "a")`, `FILTER (x > 5 AND y == "a")`},
+		{`filter OR(x > 5)`, `FILTER x > 5`},
+		{`FILTER AND (
+			x > 5,
+			OR ( y == "a", NOT z < 3 ),
+			tolower(name) IN ("bob", "sally")
+		)`, `FILTER (x > 5 AND (y == "a" OR NOT z < 3) AND tolower(name) IN ("bob","sally"))`},
+		{`FILTER NOT OR (x > 5, y == "a")`, `FILTER NOT (x > 5 OR y == "a")`},
+		{`FILTER AND (x > 5 OR y < 2, OR (AND (a == 1, b == 2), c == 3))`, `FILTER ((x > 5 OR y < 2) AND ((a == 1 AND b == 2) OR c == 3))`},
+		{`FILTER AND (a == 1, AND (b == 2, c == 3))`, `FILTER (a == 1 AND (b == 2 AND c == 3))`},
+		{`FILTER OR (AND (a == 1, b == 2))`, `FILTER (a == 1 AND b == 2)`},
+	}
+	for _, test := range tests {
+		stmt, err := ParseFilterQL(test.filter)
+		assert.Tf(t, err == nil, "%s  no error %v", test.filter, err)
+		if err != nil {
+			continue
+		}
+		assert.Tf(t, stmt.String() == test.ast, "want %s got %s", test.ast, stmt)
+		assert.Tf(t, stmt.Raw == test.filter, "raw %s", stmt.Raw)
+
+		// the filter of its String() is the same
+		again, err := ParseFilterQL(stmt.String())
+		assert.Tf(t, err == nil, "%s  no error %v", stmt, err)
+		if err != nil {
+			continue
+		}
+		assert.Tf(t, again.String() == stmt.String(), "want %s got %s", stmt, again)
+		assert.Tf(t, NodesEqual(again.Filter, stmt.Filter), "round trip %s", again)
+	}
+
+	for _, filter := range []string{
+		`x > 5`,
+		`SELECT * FROM x WHERE x > 5`,
+		`FILTER AND (x > 5, y == "a"`,
+		`FILTER AND (x > 5, y == "a") z`,
+		`FILTER AND ()`,
+		`FILTER notafunc(x) > 5`,
+	} {
+		_, err := ParseFilterQL(filter)
+		assert.Tf(t, err != nil, "%s  should error", filter)
+	}
+}
@@ -6,18 +6,23 @@ import (
 	"strings"
 	"sync"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/value"
 )
 
 var (
-	_ = u.EMPTY
-
 	// the func mutext
-	funcMu sync.Mutex
-	funcs  = make(map[string]Func)
+	funcMu     sync.Mutex
+	funcs      = make(map[string]Func)
+	tableFuncs = make(map[string]TableFunc)
 )
 
+// TableFunc is a table valued func, it produces the rows of a FROM
+//  entry from its evaluated args, each row is a single value which is
+//  the column named by the FROM alias.
+//
+//     SELECT name, t FROM users, unnest(tags) AS t
+type TableFunc func(ctx EvalContext, args []value.Value) ([]value.Value, error)
+
 func FuncAdd(name string, fn interface{}) {
 	funcMu.Lock()
 	defer funcMu.Unlock()
@@ -25,6 +30,78 @@ func FuncAdd(name string, fn interface{}) {
 	funcs[name] = MakeFunc(name, fn)
 }
 
+// AggFuncAdd registers an aggregate func, ie one evaluated over the rows
+//  of a group (GROUP BY) not per row.   The per row func @fn is used when
+//  there is no grouping.
+//
+//     expr.AggFuncAdd("sum", SumFunc)
+func AggFuncAdd(name string, fn interface{}) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	name = strings.ToLower(name)
+	f := MakeFunc(name, fn)
+	f.Aggregate = true
+	funcs[name] = f
+}
+
+// CoalesceFuncAdd registers a null coalescing func, ie ifnull(a,b), whose
+//  args the vm evaluates in order only until one is not NULL, so later
+//  args are not evaluated (short-circuit).  @fn is the eager form.
+//
+//     expr.CoalesceFuncAdd("nvl", IfNull)
+func CoalesceFuncAdd(name string, fn interface{}) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	name = strings.ToLower(name)
+	f := MakeFunc(name, fn)
+	f.Coalesce = true
+	f.ArgTyped = true
+	funcs[name] = f
+}
+
+// ArgTypedFuncAdd registers a func which returns one of its args, so its
+//  value type is the common type of its args not of the go func
+//
+//     expr.ArgTypedFuncAdd("greatest", GreatestFunc)
+func ArgTypedFuncAdd(name string, fn interface{}) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	name = strings.ToLower(name)
+	f := MakeFunc(name, fn)
+	f.ArgTyped = true
+	funcs[name] = f
+}
+
+// VolatileFuncAdd registers a func which may return a different value
+//  each call with the same args, ie now(), so it is never memoized
+//
+//     expr.VolatileFuncAdd("now", Now)
+func VolatileFuncAdd(name string, fn interface{}) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	name = strings.ToLower(name)
+	f := MakeFunc(name, fn)
+	f.Volatile = true
+	funcs[name] = f
+}
+
+// TableFuncAdd registers a table valued func, usable only in FROM
+//
+//     expr.TableFuncAdd("unnest", UnnestFunc)
+func TableFuncAdd(name string, fn TableFunc) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	tableFuncs[strings.ToLower(name)] = fn
+}
+
+// TableFuncGet the table valued func registered as @name
+func TableFuncGet(name string) (TableFunc, bool) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	fn, ok := tableFuncs[strings.ToLower(name)]
+	return fn, ok
+}
+
 func FuncsGet() map[string]Func {
 	return funcs
 }
@@ -57,6 +134,10 @@ func MakeFunc(name string, fn interface{}) Func {
 	}
 
 	f.Args = make([]reflect.Value, methodNumArgs)
+	for i := range f.Args {
+		// zero value of each declared arg (after the ctx), for its type
+		f.Args[i] = reflect.Zero(funcType.In(i + 1))
+	}
 	if funcType.IsVariadic() {
 		f.VariadicArgs = true
 	}
@@ -0,0 +1,190 @@
+package expr
+
+import (
+	"github.com/araddon/qlbridge/lex"
+)
+
+// SplitConjuncts flattens the top level AND's of a where tree into its
+//  conjuncts, a node that is not an AND is a single conjunct
+//
+//     a = 1 AND (b > 2 AND c < 3)   =>  [a = 1, b > 2, c < 3]
+func SplitConjuncts(n Node) []Node {
+	if n == nil {
+		return nil
+	}
+	if bn, ok := n.(*BinaryNode); ok && bn.Operator.T == lex.TokenLogicAnd {
+		return append(SplitConjuncts(bn.Args[0]), SplitConjuncts(bn.Args[1])...)
+	}
+	return []Node{n}
+}
+
+// JoinConjuncts is the inverse of SplitConjuncts, AND'ing the nodes back
+//  together, nil if there are none
+func JoinConjuncts(nodes []Node) Node {
+	var n Node
+	for _, c := range nodes {
+		if n == nil {
+			n = c
+			continue
+		}
+		n = NewBinaryNode(lex.Token{T: lex.TokenLogicAnd, V: "AND"}, n, c)
+	}
+	return n
+}
+
+// SplitPushdown splits the conjuncts of @where into those @supported by a
+//  source (ie can be translated to its query, see ToElasticQuery) which are
+//  pushed down, and the residual that must be filtered locally, either
+//  may be nil
+//
+//     pushed, residual := SplitPushdown(where, func(n Node) bool {
+//        _, err := ToElasticQuery(n)
+//        return err == nil
+//     })
+func SplitPushdown(where Node, supported func(Node) bool) (Node, Node) {
+	pushed := make([]Node, 0)
+	residual := make([]Node, 0)
+	for _, n := range SplitConjuncts(where) {
+		if supported(n) {
+			pushed = append(pushed, n)
+		} else {
+			residual = append(residual, n)
+		}
+	}
+	return JoinConjuncts(pushed), JoinConjuncts(residual)
+}
+
+// IsSargable is true for predicates an index could answer, comparing a
+//  column to literal(s), returns the column
+//
+//     a = 1, 5 < a, a BETWEEN 1 AND 5, a IN (1,2)
+func IsSargable(n Node) (string, bool) {
+	switch nt := n.(type) {
+	case *BinaryNode:
+		switch nt.Operator.T {
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenGT, lex.TokenGE,
+			lex.TokenLT, lex.TokenLE:
+		default:
+			return "", false
+		}
+		if in, ok := nt.Args[0].(*IdentityNode); ok && isLiteral(nt.Args[1]) {
+			return in.Text, !in.IsBooleanIdentity()
+		}
+		if in, ok := nt.Args[1].(*IdentityNode); ok && isLiteral(nt.Args[0]) {
+			return in.Text, !in.IsBooleanIdentity()
+		}
+	case *TriNode:
+		if nt.Operator.T != lex.TokenBetween {
+			return "", false
+		}
+		if in, ok := nt.Args[0].(*IdentityNode); ok && isLiteral(nt.Args[1]) && isLiteral(nt.Args[2]) {
+			return in.Text, !in.IsBooleanIdentity()
+		}
+	case *MultiArgNode:
+		if nt.Operator.T != lex.TokenIN || len(nt.Args) < 2 {
+			return "", false
+		}
+		in, ok := nt.Args[0].(*IdentityNode)
+		if !ok || in.IsBooleanIdentity() {
+			return "", false
+		}
+		for _, arg := range nt.Args[1:] {
+			if !isLiteral(arg) {
+				return "", false
+			}
+		}
+		return in.Text, true
+	}
+	return "", false
+}
+
+func isLiteral(n Node) bool {
+	switch n.(type) {
+	case *NumberNode, *StringNode:
+		return true
+	}
+	return false
+}
+
+// equality (or IN list of points) predicates allow the next index column
+//  to be used, a range predicate is the last usable index column
+func isEqualityPredicate(n Node) bool {
+	switch nt := n.(type) {
+	case *BinaryNode:
+		return nt.Operator.T == lex.TokenEqual || nt.Operator.T == lex.TokenEqualEqual
+	case *MultiArgNode:
+		return true
+	}
+	return false
+}
+
+// ChooseIndex picks the index (of the ordered columns of each available
+//  index) which answers the most of the where tree, and the residual
+//  predicate the index does not answer (nil if none), for Seeker sources
+//  choosing an access path.
+//
+//  - an index is used by its leading columns having equality predicates,
+//    optionally followed by one column with range predicates
+//  - ties are broken by more equality columns, then fewer index columns
+//  - no usable index returns nil and the where unchanged
+//
+//     idx, residual := expr.ChooseIndex(where, [][]string{{"a"}, {"a", "b"}})
+func ChooseIndex(where Node, indexes [][]string) ([]string, Node) {
+	conjuncts := SplitConjuncts(where)
+	// sargable conjuncts by column
+	byCol := make(map[string][]int)
+	for i, c := range conjuncts {
+		if col, ok := IsSargable(c); ok {
+			byCol[col] = append(byCol[col], i)
+		}
+	}
+
+	var best []string
+	var bestUsed map[int]bool
+	bestCols, bestEq := 0, 0
+	for _, index := range indexes {
+		used := make(map[int]bool)
+		cols, eqs := 0, 0
+		for _, col := range index {
+			conjIdx := byCol[col]
+			if len(conjIdx) == 0 {
+				break
+			}
+			eqAt := -1
+			for _, ci := range conjIdx {
+				if isEqualityPredicate(conjuncts[ci]) {
+					eqAt = ci
+					break
+				}
+			}
+			cols++
+			if eqAt >= 0 {
+				eqs++
+				used[eqAt] = true
+				continue
+			}
+			// range column, all its predicates are answered, no more columns
+			for _, ci := range conjIdx {
+				used[ci] = true
+			}
+			break
+		}
+		if cols == 0 {
+			continue
+		}
+		if cols > bestCols || (cols == bestCols && (eqs > bestEq ||
+			(eqs == bestEq && len(index) < len(best)))) {
+			best, bestUsed, bestCols, bestEq = index, used, cols, eqs
+		}
+	}
+	if best == nil {
+		return nil, where
+	}
+	residual := make([]Node, 0)
+	for i, c := range conjuncts {
+		if !bestUsed[i] {
+			residual = append(residual, c)
+		}
+	}
+	return best, JoinConjuncts(residual)
+}
@@ -0,0 +1,48 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestSplitConjuncts(t *testing.T) {
+	tree, err := ParseExpression(`a = 1 AND (b > 2 AND c < 3) AND (d = 1 OR e = 2)`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	conj := SplitConjuncts(tree.Root)
+	assert.Tf(t, len(conj) == 4, "4 conjuncts: %v", conj)
+	assert.Tf(t, conj[1].String() == "b > 2", "got %v", conj[1])
+
+	_, ok := IsSargable(conj[0])
+	assert.Tf(t, ok, "a = 1 is sargable")
+	_, ok = IsSargable(conj[3])
+	assert.Tf(t, !ok, "OR is not sargable")
+	assert.Tf(t, JoinConjuncts(conj[1:3]).String() == "b > 2 AND c < 3", "got %v", JoinConjuncts(conj[1:3]))
+}
+
+func TestChooseIndex(t *testing.T) {
+	tree, err := ParseExpression(`user_id = 5 AND created > 10 AND name == "bob" AND email LIKE "bob%"`)
+	assert.Tf(t, err == nil, "no error %v", err)
+
+	// the composite index answers 2 predicates, the single column only 1
+	indexes := [][]string{{"user_id"}, {"user_id", "created"}, {"email"}}
+	idx, residual := ChooseIndex(tree.Root, indexes)
+	assert.Tf(t, len(idx) == 2 && idx[1] == "created", "composite index: %v", idx)
+	assert.Tf(t, residual != nil && residual.String() == `name == "bob" AND email LIKE "bob%"`, "residual %v", residual)
+
+	// created is a range so name is not usable after it
+	idx, residual = ChooseIndex(tree.Root, [][]string{{"user_id", "created", "name"}, {"user_id", "name"}})
+	assert.Tf(t, len(idx) == 2 && idx[1] == "name", "usable prefixes tie, more equality wins: %v", idx)
+	assert.Tf(t, residual.String() == `created > 10 AND email LIKE "bob%"`, "residual %v", residual)
+
+	idx, residual = ChooseIndex(tree.Root, [][]string{{"user_id"}, {"name", "user_id"}})
+	assert.Tf(t, len(idx) == 2 && idx[0] == "name", "all equality: %v", idx)
+
+	// no index on the leading column
+	idx, residual = ChooseIndex(tree.Root, [][]string{{"created_by", "user_id"}})
+	assert.Tf(t, idx == nil && residual == tree.Root, "no usable index: %v", idx)
+
+	tree, _ = ParseExpression(`user_id = 5`)
+	idx, residual = ChooseIndex(tree.Root, indexes)
+	assert.Tf(t, len(idx) == 1 && residual == nil, "single column index, no residual: %v %v", idx, residual)
+}
@@ -0,0 +1,184 @@
+package expr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+// the json filter operators which are a single binary node
+var jsonFilterBinary = map[string]lex.Token{
+	"eq":   {T: lex.TokenEqual, V: "="},
+	"ne":   {T: lex.TokenNE, V: "!="},
+	"gt":   {T: lex.TokenGT, V: ">"},
+	"gte":  {T: lex.TokenGE, V: ">="},
+	"lt":   {T: lex.TokenLT, V: "<"},
+	"lte":  {T: lex.TokenLE, V: "<="},
+	"like": {T: lex.TokenLike, V: "LIKE"},
+}
+
+// CompileJSONFilter builds a where Node from a json filter, ie from api
+//  query params.  A filter is an object with a single operator key:
+//
+//     {"and": [filter, ...]}          a AND b ...
+//     {"or": [filter, ...]}           a OR b ...
+//     {"not": filter}                 NOT (a)
+//     {"eq": ["col", value]}          col = value  (also ne, gt, gte, lt, lte, like)
+//     {"in": ["col", [value, ...]]}   col IN (value, ...)
+//     {"between": ["col", lo, hi]}    col BETWEEN lo AND hi
+//
+//  values are json strings, numbers, booleans or null
+//
+//     {"and":[{"eq":["status","active"]},{"gt":["age",21]}]}  =>  status = "active" AND age > 21
+func CompileJSONFilter(j []byte) (Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+	var filter interface{}
+	if err := dec.Decode(&filter); err != nil {
+		return nil, fmt.Errorf("invalid json filter: %v", err)
+	}
+	return compileJSONFilter(filter)
+}
+
+func compileJSONFilter(filter interface{}) (Node, error) {
+	obj, ok := filter.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, fmt.Errorf("json filter must be an object with one operator: %v", filter)
+	}
+	for op, arg := range obj {
+		switch op = strings.ToLower(op); op {
+		case "and", "or":
+			return compileJSONLogical(op, arg)
+		case "not":
+			n, err := compileJSONFilter(arg)
+			if err != nil {
+				return nil, err
+			}
+			return NewUnary(lex.Token{T: lex.TokenNegate, V: "NOT"}, n), nil
+		case "in":
+			args, err := jsonFilterArgs(op, arg, 2)
+			if err != nil {
+				return nil, err
+			}
+			list, ok := args[1].([]interface{})
+			if !ok || len(list) == 0 {
+				return nil, fmt.Errorf("json filter in expects a non-empty list: %v", args[1])
+			}
+			col, err := jsonFilterIdentity(args[0])
+			if err != nil {
+				return nil, err
+			}
+			in := NewMultiArgNode(lex.Token{T: lex.TokenIN, V: "IN"})
+			in.Append(col)
+			for _, v := range list {
+				vn, err := jsonFilterValue(v)
+				if err != nil {
+					return nil, err
+				}
+				in.Append(vn)
+			}
+			return in, nil
+		case "between":
+			args, err := jsonFilterArgs(op, arg, 3)
+			if err != nil {
+				return nil, err
+			}
+			col, err := jsonFilterIdentity(args[0])
+			if err != nil {
+				return nil, err
+			}
+			lo, err := jsonFilterValue(args[1])
+			if err != nil {
+				return nil, err
+			}
+			hi, err := jsonFilterValue(args[2])
+			if err != nil {
+				return nil, err
+			}
+			return NewTriNode(lex.Token{T: lex.TokenBetween, V: "BETWEEN"}, col, lo, hi), nil
+		default:
+			tok, ok := jsonFilterBinary[op]
+			if !ok {
+				return nil, fmt.Errorf("unknown json filter operator: %q", op)
+			}
+			args, err := jsonFilterArgs(op, arg, 2)
+			if err != nil {
+				return nil, err
+			}
+			col, err := jsonFilterIdentity(args[0])
+			if err != nil {
+				return nil, err
+			}
+			vn, err := jsonFilterValue(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return NewBinaryNode(tok, col, vn), nil
+		}
+	}
+	return nil, nil
+}
+
+func compileJSONLogical(op string, arg interface{}) (Node, error) {
+	filters, ok := arg.([]interface{})
+	if !ok || len(filters) == 0 {
+		return nil, fmt.Errorf("json filter %s expects a non-empty list: %v", op, arg)
+	}
+	tok := lex.Token{T: lex.TokenLogicAnd, V: "AND"}
+	if op == "or" {
+		tok = lex.Token{T: lex.TokenLogicOr, V: "OR"}
+	}
+	var n Node
+	for _, f := range filters {
+		fn, err := compileJSONFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		// nested and/or keep their grouping when written back out
+		if bn, ok := fn.(*BinaryNode); ok && (bn.Operator.T == lex.TokenLogicAnd || bn.Operator.T == lex.TokenLogicOr) {
+			bn.Paren = bn.Operator.T != tok.T
+		}
+		if n == nil {
+			n = fn
+			continue
+		}
+		n = NewBinaryNode(tok, n, fn)
+	}
+	return n, nil
+}
+
+func jsonFilterArgs(op string, arg interface{}, ct int) ([]interface{}, error) {
+	args, ok := arg.([]interface{})
+	if !ok || len(args) != ct {
+		return nil, fmt.Errorf("json filter %s expects %d args: %v", op, ct, arg)
+	}
+	return args, nil
+}
+
+func jsonFilterIdentity(col interface{}) (Node, error) {
+	name, ok := col.(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("json filter expected column name but got: %v", col)
+	}
+	return NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: name}), nil
+}
+
+func jsonFilterValue(v interface{}) (Node, error) {
+	switch vt := v.(type) {
+	case string:
+		return NewStringNode(0, vt), nil
+	case json.Number:
+		return NewNumber(0, vt.String())
+	case bool:
+		if vt {
+			return NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: "true"}), nil
+		}
+		return NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: "false"}), nil
+	case nil:
+		return NewNull(lex.Token{T: lex.TokenNull, V: "NULL"}), nil
+	}
+	return nil, fmt.Errorf("json filter value must be a string, number, bool or null: %v", v)
+}
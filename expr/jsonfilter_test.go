@@ -0,0 +1,41 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestCompileJSONFilter(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"eq":["status","active"]}`, `status = "active"`},
+		{`{"gt":["age",21]}`, `age > 21`},
+		{`{"lte":["score",2.5]}`, `score <= 2.5`},
+		{`{"ne":["deleted",true]}`, `deleted != true`},
+		{`{"like":["email","bob%"]}`, `email LIKE "bob%"`},
+		{`{"and":[{"eq":["status","active"]},{"gt":["age",21]}]}`, `status = "active" AND age > 21`},
+		{`{"or":[{"eq":["a",1]},{"eq":["b",2]},{"eq":["c",3]}]}`, `a = 1 OR b = 2 OR c = 3`},
+		{`{"and":[{"eq":["a",1]},{"or":[{"eq":["b",2]},{"eq":["c",3]}]}]}`, `a = 1 AND (b = 2 OR c = 3)`},
+		{`{"in":["status",["active","pending",3]]}`, `status IN ("active","pending",3)`},
+		{`{"between":["age",18,65]}`, `age BETWEEN 18 AND 65`},
+	}
+	for _, test := range tests {
+		n, err := CompileJSONFilter([]byte(test.json))
+		assert.Tf(t, err == nil, "no error %v for %s", err, test.json)
+		assert.Tf(t, n.String() == test.want, "want %s got %s", test.want, n.String())
+	}
+
+	n, err := CompileJSONFilter([]byte(`{"in":["id",[1,2]]}`))
+	assert.Tf(t, err == nil, "no error %v", err)
+	in, ok := n.(*MultiArgNode)
+	assert.Tf(t, ok && len(in.Args) == 3, "in is multi arg: %#v", n)
+
+	for _, bad := range []string{`{"eq":["status",`, `[1,2]`, `{"eq":["a",1],"gt":["b",2]}`,
+		`{"eq":["a"]}`, `{"nope":["a",1]}`, `{"in":["a",[]]}`, `{"and":[]}`, `{"eq":[1,1]}`, `{"eq":["a",{"b":1}]}`} {
+		_, err := CompileJSONFilter([]byte(bad))
+		assert.Tf(t, err != nil, "expected error for %s", bad)
+	}
+}
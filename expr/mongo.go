@@ -0,0 +1,223 @@
+package expr
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+var mongoOps = map[lex.TokenType]string{
+	lex.TokenNE: "$ne",
+	lex.TokenGT: "$gt",
+	lex.TokenGE: "$gte",
+	lex.TokenLT: "$lt",
+	lex.TokenLE: "$lte",
+}
+
+// ToMongoFilter translates a where Node into a mongo query document
+//
+//     status = "active" AND age > 21   =>  {"$and": [{"status": "active"}, {"age": {"$gt": 21}}]}
+//     email LIKE "bob%"                =>  {"email": {"$regex": "^bob.*$"}}
+//
+//  a node (or any part of it) that cannot be translated is ErrNotSupported,
+//  to push down what can be, translate each of SplitConjuncts() and apply
+//  the JoinConjuncts() of the unsupported ones locally as the residual.
+func ToMongoFilter(n Node) (map[string]interface{}, error) {
+	switch nt := n.(type) {
+	case *BinaryNode:
+		switch nt.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenLogicOr:
+			return mongoLogical(nt)
+		case lex.TokenEqual, lex.TokenEqualEqual:
+			col, val, _, ok := identityLiteral(nt)
+			if !ok || val == nil {
+				// x = NULL  is unknown, mongo would match null
+				return nil, ErrNotSupported
+			}
+			return map[string]interface{}{col: val}, nil
+		case lex.TokenIs, lex.TokenIsNot:
+			col, val, _, ok := identityLiteral(nt)
+			if !ok || val != nil {
+				return nil, ErrNotSupported
+			}
+			if nt.Operator.T == lex.TokenIsNot {
+				return map[string]interface{}{col: map[string]interface{}{"$ne": nil}}, nil
+			}
+			return map[string]interface{}{col: nil}, nil
+		case lex.TokenLike, lex.TokenILike:
+			col, ok := nt.Args[0].(*IdentityNode)
+			pattern, isStr := nt.Args[1].(*StringNode)
+			if !ok || !isStr || col.IsBooleanIdentity() {
+				return nil, ErrNotSupported
+			}
+			regex, ok := likeToRegex(pattern.Text)
+			if !ok {
+				return nil, ErrNotSupported
+			}
+			re := map[string]interface{}{"$regex": regex}
+			if nt.Operator.T == lex.TokenILike {
+				re["$options"] = "i"
+			}
+			return map[string]interface{}{col.Text: re}, nil
+		}
+		op, ok := mongoOps[nt.Operator.T]
+		if !ok {
+			return nil, ErrNotSupported
+		}
+		col, val, flipped, ok := identityLiteral(nt)
+		if !ok || val == nil {
+			return nil, ErrNotSupported
+		}
+		if flipped {
+			op = mongoOps[flipComparison(nt.Operator.T)]
+		}
+		return map[string]interface{}{col: map[string]interface{}{op: val}}, nil
+	case *MultiArgNode:
+		if nt.Operator.T != lex.TokenIN || len(nt.Args) < 2 {
+			return nil, ErrNotSupported
+		}
+		col, ok := nt.Args[0].(*IdentityNode)
+		if !ok || col.IsBooleanIdentity() {
+			return nil, ErrNotSupported
+		}
+		vals := make([]interface{}, 0, len(nt.Args)-1)
+		for _, arg := range nt.Args[1:] {
+			v, ok := literalValue(arg)
+			if !ok {
+				return nil, ErrNotSupported
+			}
+			vals = append(vals, v)
+		}
+		return map[string]interface{}{col.Text: map[string]interface{}{"$in": vals}}, nil
+	case *TriNode:
+		if nt.Operator.T != lex.TokenBetween {
+			return nil, ErrNotSupported
+		}
+		col, ok := nt.Args[0].(*IdentityNode)
+		lo, loOk := literalValue(nt.Args[1])
+		hi, hiOk := literalValue(nt.Args[2])
+		if !ok || !loOk || !hiOk || col.IsBooleanIdentity() {
+			return nil, ErrNotSupported
+		}
+		return map[string]interface{}{col.Text: map[string]interface{}{"$gte": lo, "$lte": hi}}, nil
+	case *UnaryNode:
+		if nt.Operator.T != lex.TokenNegate {
+			return nil, ErrNotSupported
+		}
+		f, err := ToMongoFilter(nt.Arg)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$nor": []interface{}{f}}, nil
+	}
+	return nil, ErrNotSupported
+}
+
+func mongoLogical(n *BinaryNode) (map[string]interface{}, error) {
+	op := "$and"
+	if n.Operator.T == lex.TokenLogicOr {
+		op = "$or"
+	}
+	filters := make([]interface{}, 0, 2)
+	for _, arg := range flattenLogical(n) {
+		f, err := ToMongoFilter(arg)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return map[string]interface{}{op: filters}, nil
+}
+
+// flatten nested AND's (or OR's) of the same operator as @n
+//
+//     a AND (b AND c)  =>  [a, b, c]
+func flattenLogical(n *BinaryNode) []Node {
+	nodes := make([]Node, 0, 2)
+	for _, arg := range n.Args {
+		if bn, ok := arg.(*BinaryNode); ok && bn.Operator.T == n.Operator.T {
+			nodes = append(nodes, flattenLogical(bn)...)
+		} else {
+			nodes = append(nodes, arg)
+		}
+	}
+	return nodes
+}
+
+// the column and literal value of a binary comparison, flipped is true
+//  if the literal was on the left  ie  5 < age
+func identityLiteral(n *BinaryNode) (string, interface{}, bool, bool) {
+	if col, ok := n.Args[0].(*IdentityNode); ok && !col.IsBooleanIdentity() {
+		if v, ok := literalValue(n.Args[1]); ok {
+			return col.Text, v, false, true
+		}
+	}
+	if col, ok := n.Args[1].(*IdentityNode); ok && !col.IsBooleanIdentity() {
+		if v, ok := literalValue(n.Args[0]); ok {
+			return col.Text, v, true, true
+		}
+	}
+	return "", nil, false, false
+}
+
+// the go value of a literal node, string, int64, float64, bool or nil
+func literalValue(n Node) (interface{}, bool) {
+	switch nt := n.(type) {
+	case *StringNode:
+		return nt.Text, true
+	case *NumberNode:
+		if nt.IsInt {
+			return nt.Int64, true
+		}
+		return nt.Float64, true
+	case *IdentityNode:
+		if nt.IsBooleanIdentity() {
+			return nt.Bool(), true
+		}
+	case *NullNode:
+		return nil, true
+	}
+	return nil, false
+}
+
+// the comparison with its args swapped,  5 < age  is  age > 5
+func flipComparison(op lex.TokenType) lex.TokenType {
+	switch op {
+	case lex.TokenGT:
+		return lex.TokenLT
+	case lex.TokenGE:
+		return lex.TokenLE
+	case lex.TokenLT:
+		return lex.TokenGT
+	case lex.TokenLE:
+		return lex.TokenGE
+	}
+	return op
+}
+
+// convert a sql LIKE pattern to an anchored regex,  % is .* and _ is .
+//  unless escaped by \ (the vm LikeEscapeDefault), not ok if the pattern
+//  ends in the escape
+func likeToRegex(pattern string) (string, bool) {
+	var buf bytes.Buffer
+	buf.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			buf.WriteString(".*")
+		case r == '_':
+			buf.WriteByte('.')
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteByte('$')
+	return buf.String(), !escaped
+}
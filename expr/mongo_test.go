@@ -0,0 +1,50 @@
+package expr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestToMongoFilter(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{`status = "active"`, `{"status":"active"}`},
+		{`status == "active"`, `{"status":"active"}`},
+		{`status != "deleted"`, `{"status":{"$ne":"deleted"}}`},
+		{`age > 21`, `{"age":{"$gt":21}}`},
+		{`age >= 21`, `{"age":{"$gte":21}}`},
+		{`score < 2.5`, `{"score":{"$lt":2.5}}`},
+		{`age <= 65`, `{"age":{"$lte":65}}`},
+		{`21 < age`, `{"age":{"$gt":21}}`},
+		{`status IN ("a", "b", 3)`, `{"status":{"$in":["a","b",3]}}`},
+		{`email LIKE "bob.%_"`, `{"email":{"$regex":"(?s)^bob\\..*.$"}}`},
+		{`email ILIKE "bob%"`, `{"email":{"$options":"i","$regex":"(?s)^bob.*$"}}`},
+		{`pct LIKE "50\%_"`, `{"pct":{"$regex":"(?s)^50%.$"}}`},
+		{`age BETWEEN 18 AND 65`, `{"age":{"$gte":18,"$lte":65}}`},
+		{`a = 1 AND b = true AND c > 2`, `{"$and":[{"a":1},{"b":true},{"c":{"$gt":2}}]}`},
+		{`a = 1 OR b = 2`, `{"$or":[{"a":1},{"b":2}]}`},
+		{`a = 1 AND (b = 2 OR c = 3)`, `{"$and":[{"a":1},{"$or":[{"b":2},{"c":3}]}]}`},
+		{`NOT (a = 1)`, `{"$nor":[{"a":1}]}`},
+	}
+	for _, test := range tests {
+		tree, err := ParseExpression(test.where)
+		assert.Tf(t, err == nil, "no error %v", err)
+		f, err := ToMongoFilter(tree.Root)
+		assert.Tf(t, err == nil, "no error %v for %s", err, test.where)
+		by, _ := json.Marshal(f)
+		assert.Tf(t, string(by) == test.want, "%s  want %s got %s", test.where, test.want, string(by))
+	}
+
+	for _, where := range []string{`a = b`, `a + 1 > 2`, `a = 1 AND b = c`, `a LIKE b`, `a * 2 > 4`} {
+		tree, err := ParseExpression(where)
+		assert.Tf(t, err == nil, "no error %v", err)
+		_, err = ToMongoFilter(tree.Root)
+		assert.Tf(t, err == ErrNotSupported, "%s not supported: %v", where, err)
+	}
+	_, ok := likeToRegex(`50\`)
+	assert.Tf(t, !ok, "pattern ending in the escape")
+}
@@ -1,10 +1,12 @@
 package expr
 
 import (
+	"strings"
 	"testing"
 
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
 	"github.com/bmizerany/assert"
 )
 
@@ -30,3 +32,103 @@ func TestBuiltins(t *testing.T) {
 		assert.Tf(t, node != nil, "has node: %v", node)
 	}
 }
+
+func TestFuncIsAggregate(t *testing.T) {
+
+	FuncAdd("lower", func(ctx EvalContext, val value.Value) (value.StringValue, bool) {
+		return value.NewStringValue(strings.ToLower(val.ToString())), true
+	})
+	// a user registered aggregate
+	AggFuncAdd("mode", func(ctx EvalContext, val value.Value) (value.Value, bool) {
+		return val, true
+	})
+
+	for exprText, isAgg := range map[string]bool{
+		`sum(price)`:       true,
+		`count(*)`:         true,
+		`avg(price)`:       true,
+		`lower(name)`:      false,
+		`sqrt(price)`:      false,
+		`mode(category)`:   true,
+		`MODE(category)`:   true,
+		`sqrt(sum(price))`: false,
+	} {
+		tree, err := ParseExpression(exprText)
+		assert.Tf(t, err == nil, "%s parse err: %v", exprText, err)
+		fn, ok := tree.Root.(*FuncNode)
+		assert.Tf(t, ok, "%s is func: %T", exprText, tree.Root)
+		assert.Tf(t, fn.IsAggregate() == isAgg, "%s IsAggregate() want %v", exprText, isAgg)
+	}
+}
+
+func TestFuncCheckNested(t *testing.T) {
+
+	FuncAdd("strlen_typed", func(ctx EvalContext, s value.StringValue) (value.IntValue, bool) {
+		return value.NewIntValue(int64(len(s.Val()))), true
+	})
+	FuncAdd("upper_typed", func(ctx EvalContext, val value.Value) (value.StringValue, bool) {
+		return value.NewStringValue(strings.ToUpper(val.ToString())), true
+	})
+	FuncAdd("double_typed", func(ctx EvalContext, i value.IntValue) (value.IntValue, bool) {
+		return value.NewIntValue(i.Val() * 2), true
+	})
+
+	for _, exprText := range []string{
+		`strlen_typed(upper_typed(name))`,
+		`double_typed(strlen_typed(name))`,
+		`double_typed(double_typed(strlen_typed(upper_typed(name))))`,
+		`upper_typed(double_typed(x))`,
+		`strlen_typed(name)`,
+	} {
+		_, err := ParseExpression(exprText)
+		assert.Tf(t, err == nil, "%s  matching composition: %v", exprText, err)
+	}
+
+	for _, exprText := range []string{
+		`strlen_typed(double_typed(x))`,
+		`double_typed(upper_typed(name))`,
+		`upper_typed(strlen_typed(double_typed(x)))`,
+	} {
+		_, err := ParseExpression(exprText)
+		assert.Tf(t, err != nil, "%s  mismatched composition should error", exprText)
+	}
+}
+
+func TestFuncCheckVariadic(t *testing.T) {
+
+	FuncAdd("concat_typed", func(ctx EvalContext, vals ...value.StringValue) (value.StringValue, bool) {
+		s := ""
+		for _, v := range vals {
+			s += v.Val()
+		}
+		return value.NewStringValue(s), true
+	})
+	FuncAdd("join_typed", func(ctx EvalContext, sep value.StringValue, vals ...value.IntValue) (value.StringValue, bool) {
+		return value.NewStringValue(sep.Val()), true
+	})
+
+	for _, exprText := range []string{
+		`concat_typed("a", "b", name)`,
+		`concat_typed("a")`,
+		`concat_typed(tolower(x), "b")`,
+		`join_typed(",", 1, 2, 3, x)`,
+		`join_typed(",")`,
+	} {
+		_, err := ParseExpression(exprText)
+		assert.Tf(t, err == nil, "%s  valid variadic args: %v", exprText, err)
+	}
+
+	for exprText, badArg := range map[string]string{
+		`concat_typed(1, "x")`:                    "arg 1",
+		`concat_typed("a", "b", 2.5)`:             "arg 3",
+		`join_typed(",", 1, "x")`:                 "arg 3",
+		`join_typed(1, 2)`:                        "arg 1",
+		`concat_typed("a", join_typed(",", "b"))`: "arg 2",
+	} {
+		_, err := ParseExpression(exprText)
+		assert.Tf(t, err != nil, "%s  invalid variadic arg should error", exprText)
+		if err != nil {
+			assert.Tf(t, strings.Contains(err.Error(), badArg), "%s  should report %s: %v", exprText, badArg, err)
+		}
+	}
+}
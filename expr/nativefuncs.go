@@ -3,17 +3,20 @@ package expr
 import (
 	"math"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/value"
 )
 
-var _ = u.EMPTY
-
 const yymmTimeLayout = "0601"
 
 func init() {
 	// agregate ops
-	FuncAdd("count", CountFunc)
+	AggFuncAdd("count", CountFunc)
+	AggFuncAdd("sum", SumFunc)
+	AggFuncAdd("avg", AvgFunc)
+
+	// window, ie  rank() OVER (PARTITION BY g ORDER BY score)
+	FuncAdd("row_number", RowNumberFunc)
+	FuncAdd("rank", RowNumberFunc)
 
 	// math
 	FuncAdd("sqrt", SqrtFunc)
@@ -25,7 +28,30 @@ func CountFunc(ctx EvalContext, val value.Value) (value.IntValue, bool) {
 	if val.Err() || val.Nil() {
 		return value.NewIntValue(0), false
 	}
-	//u.Infof("???   vals=[%v]", val.Value())
+	//logging.Infof("???   vals=[%v]", val.Value())
+	return value.NewIntValue(1), true
+}
+
+// Sum, per row is the numeric value, the sum is across rows of a group
+func SumFunc(ctx EvalContext, val value.Value) (value.NumberValue, bool) {
+	if val.Err() || val.Type() == value.NilType {
+		return value.NewNumberValue(0), false
+	}
+	fv := value.ToFloat64(val.Rv())
+	if math.IsNaN(fv) {
+		return value.NewNumberValue(0), false
+	}
+	return value.NewNumberValue(fv), true
+}
+
+// Avg, per row is the numeric value, the average is across rows of a group
+func AvgFunc(ctx EvalContext, val value.Value) (value.NumberValue, bool) {
+	return SumFunc(ctx, val)
+}
+
+// RowNumber, the row_number() and rank() of a window are evaluated by the
+//  window over its partition, without an OVER each row is its own partition
+func RowNumberFunc(ctx EvalContext) (value.IntValue, bool) {
 	return value.NewIntValue(1), true
 }
 
@@ -41,14 +67,14 @@ func SqrtFunc(ctx EvalContext, val value.Value) (value.NumberValue, bool) {
 	}
 	fv := nv.Float()
 	fv = math.Sqrt(fv)
-	//u.Infof("???   vals=[%v]", val.Value())
+	//logging.Infof("???   vals=[%v]", val.Value())
 	return value.NewNumberValue(fv), true
 }
 
 // Pow
 func PowFunc(ctx EvalContext, val, toPower value.Value) (value.NumberValue, bool) {
 	//Pow(x, y float64) float64
-	//u.Infof("powFunc:  %T:%v %T:%v ", val, val.Value(), toPower, toPower.Value())
+	//logging.Infof("powFunc:  %T:%v %T:%v ", val, val.Value(), toPower, toPower.Value())
 	if val.Err() || val.Nil() {
 		return value.NewNumberValue(0), false
 	}
@@ -60,6 +86,6 @@ func PowFunc(ctx EvalContext, val, toPower value.Value) (value.NumberValue, bool
 		return value.NewNumberValue(0), false
 	}
 	fv = math.Pow(fv, pow)
-	//u.Infof("pow ???   vals=[%v]", fv, pow)
+	//logging.Infof("pow ???   vals=[%v]", fv, pow)
 	return value.NewNumberValue(fv), true
 }
@@ -5,16 +5,17 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
-	u "github.com/araddon/gou"
+	"github.com/araddon/dateparse"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
 var (
-	_ = u.EMPTY
-
 	// our DataTypes we support, a limited sub-set of go
 	floatRv   = reflect.ValueOf(float64(1.2))
 	int64Rv   = reflect.ValueOf(int64(1))
@@ -24,6 +25,7 @@ var (
 	boolRv    = reflect.ValueOf(true)
 	mapIntRv  = reflect.ValueOf(map[string]int64{"hello": int64(1)})
 	timeRv    = reflect.ValueOf(time.Time{})
+	durRv     = reflect.ValueOf(time.Duration(0))
 	nilRv     = reflect.ValueOf(nil)
 
 	// Standard errors
@@ -46,6 +48,12 @@ const (
 	TriNodeType         NodeType = 13
 	MultiArgNodeType    NodeType = 14
 	NullNodeType        NodeType = 15
+	RowConstructorType  NodeType = 16
+	IntervalNodeType    NodeType = 17
+	SubscriptNodeType   NodeType = 18
+	MatchNodeType       NodeType = 19
+	SubQueryNodeType    NodeType = 20
+	ParamNodeType       NodeType = 21
 	SqlPreparedType     NodeType = 29
 	SqlSelectNodeType   NodeType = 30
 	SqlInsertNodeType   NodeType = 31
@@ -62,6 +70,70 @@ const (
 	//SetNodeType         NodeType = 12
 )
 
+func (m NodeType) String() string {
+	switch m {
+	case NodeNodeType:
+		return "Node"
+	case FuncNodeType:
+		return "FuncNode"
+	case IdentityNodeType:
+		return "IdentityNode"
+	case StringNodeType:
+		return "StringNode"
+	case NumberNodeType:
+		return "NumberNode"
+	case BinaryNodeType:
+		return "BinaryNode"
+	case UnaryNodeType:
+		return "UnaryNode"
+	case TriNodeType:
+		return "TriNode"
+	case MultiArgNodeType:
+		return "MultiArgNode"
+	case NullNodeType:
+		return "NullNode"
+	case RowConstructorType:
+		return "RowConstructorNode"
+	case IntervalNodeType:
+		return "IntervalNode"
+	case SubscriptNodeType:
+		return "SubscriptNode"
+	case MatchNodeType:
+		return "MatchNode"
+	case SubQueryNodeType:
+		return "SubQueryNode"
+	case ParamNodeType:
+		return "ParamNode"
+	case SqlPreparedType:
+		return "SqlPrepared"
+	case SqlSelectNodeType:
+		return "SqlSelect"
+	case SqlInsertNodeType:
+		return "SqlInsert"
+	case SqlUpdateNodeType:
+		return "SqlUpdate"
+	case SqlUpsertNodeType:
+		return "SqlUpsert"
+	case SqlDeleteNodeType:
+		return "SqlDelete"
+	case SqlDescribeNodeType:
+		return "SqlDescribe"
+	case SqlShowNodeType:
+		return "SqlShow"
+	case SqlCreateNodeType:
+		return "SqlCreate"
+	case SqlSourceNodeType:
+		return "SqlSource"
+	case SqlWhereNodeType:
+		return "SqlWhere"
+	case SqlIntoNodeType:
+		return "SqlInto"
+	case SqlJoinNodeType:
+		return "SqlJoin"
+	}
+	return "unknown"
+}
+
 // A Node is an element in the expression tree, implemented
 // by different types (string, binary, urnary, func, case, etc)
 //
@@ -98,6 +170,82 @@ type EvalContext interface {
 	ContextReader
 }
 
+// DivideByZeroPolicy determines the result of  x / 0  and  x % 0
+type DivideByZeroPolicy uint8
+
+const (
+	// Return NULL, this is the sql default
+	DivideByZeroNull DivideByZeroPolicy = 0
+	// Return an error value, evaluation fails
+	DivideByZeroError DivideByZeroPolicy = 1
+	// Return +/-Inf (NaN for 0/0 and modulus), ints are promoted to float
+	DivideByZeroInf DivideByZeroPolicy = 2
+)
+
+// EvalContext's may optionally implement this to choose the divide
+// by zero behavior, if not implemented DivideByZeroNull is used
+type ContextDivideByZero interface {
+	DivideByZero() DivideByZeroPolicy
+}
+
+// IntOverflowPolicy determines how int64 arithmetic (+ - * /) overflow
+//  is handled
+type IntOverflowPolicy uint8
+
+const (
+	// Promote the operation to float64 math and return a NumberValue
+	IntOverflowPromote IntOverflowPolicy = 0
+	// Return an error value, evaluation fails
+	IntOverflowError IntOverflowPolicy = 1
+)
+
+// EvalContext's may optionally implement this to choose the int overflow
+// behavior, if not implemented IntOverflowPromote is used
+type ContextIntOverflow interface {
+	IntOverflow() IntOverflowPolicy
+}
+
+// NullArgsPolicy determines the result of greatest(), least() when
+//  some of the args are NULL
+type NullArgsPolicy uint8
+
+const (
+	// NULL args are ignored, NULL only if all args are NULL (postgres)
+	NullArgsIgnore NullArgsPolicy = 0
+	// Any NULL arg makes the result NULL (mysql, oracle)
+	NullArgsNull NullArgsPolicy = 1
+)
+
+// EvalContext's may optionally implement this to choose how greatest()
+// least() treat NULL args, if not implemented NullArgsIgnore is used
+type ContextNullArgs interface {
+	NullArgs() NullArgsPolicy
+}
+
+// EvalContext's may optionally implement this to choose the string
+// collation used for = != < > <= >= and LIKE (a case-insensitive
+// collation makes LIKE an ILIKE), if not implemented (or nil)
+// value.CollationBinary is used
+type ContextCollation interface {
+	Collation() value.Collation
+}
+
+// EvalContext's may optionally implement this to evaluate sub-selects
+// used in expressions, returning all rows of the sub-select
+//
+//     (a, b) IN (SELECT x, y FROM t)
+type ContextSubQuery interface {
+	SubQuery(stmt *SqlSelect) ([][]value.Value, error)
+}
+
+// EvalContext's may optionally implement this to memoize the values of
+// pure func calls (see IsPure) by their Fingerprint, for the evaluation
+// of a single row, so a func used in several expressions is called once
+type ContextMemo interface {
+	Memo(fingerprint string) (value.Value, bool)
+	SetMemo(fingerprint string, v value.Value)
+}
+
 // Context Reader is interface to read the context of message/row/command
 //  being evaluated
 type ContextReader interface {
@@ -128,6 +276,21 @@ type Func struct {
 	VariadicArgs    bool
 	Return          reflect.Value
 	ReturnValueType value.ValueType
+	// Aggregate funcs are evaluated over the rows of a group (sum, count)
+	//  not per row, set by AggFuncAdd()
+	Aggregate bool
+	// Coalesce funcs return their first non NULL arg, evaluating args
+	//  only until it is found, set by CoalesceFuncAdd()
+	Coalesce bool
+	// Table valued funcs produce the rows of a FROM entry and are not
+	//  evaluated by the vm, see TableFuncAdd()
+	Table bool
+	// ArgTyped funcs return one of their args (greatest, ifnull) so their
+	//  value type is the common type of the args, see ArgTypedFuncAdd()
+	ArgTyped bool
+	// Volatile funcs may return a different value for the same args
+	//  (now), so are never memoized, see VolatileFuncAdd()
+	Volatile bool
 	// The actual Go Function
 	F reflect.Value
 }
@@ -138,9 +301,10 @@ type Func struct {
 // interfaces:   Node
 type FuncNode struct {
 	Pos
-	Name string // Name of func
-	F    Func   // The actual function that this AST maps to
-	Args []Node // Arguments are them-selves nodes
+	Name     string // Name of func
+	F        Func   // The actual function that this AST maps to
+	Args     []Node // Arguments are them-selves nodes
+	Distinct bool   // aggregate over distinct values   count(DISTINCT user_id)
 }
 
 // IdentityNode will look up a value out of a env bag
@@ -152,18 +316,42 @@ type IdentityNode struct {
 	Text  string
 	left  string
 	right string
+	vt    value.ValueType // bound from a schema by CheckSchema, 0 = unknown
 }
 
 // StringNode holds a value literal, quotes not included
 type StringNode struct {
 	Pos
-	Text string
+	Text  string
+	typed *typedLiteral // TypedValue of Text, parsed once
+}
+
+// the TypedValue of a StringNode, the literal is evaluated per row so
+//  it is only parsed the first time
+type typedLiteral struct {
+	once sync.Once
+	v    value.Value
+	ok   bool
 }
 
 type NullNode struct {
 	Pos
 }
 
+// IntervalNode is an elapsed time literal, used in time arithmetic
+//
+//    created > now() - INTERVAL "2 days"
+//    INTERVAL "1 hour 30 minutes"
+//    INTERVAL 2 HOUR
+//    INTERVAL '1' MONTH
+type IntervalNode struct {
+	Pos
+	Text     string        // original text,  "1 day", or quantity "2" if Unit
+	Unit     string        // unit keyword of   INTERVAL 2 HOUR
+	Months   int           // calendar months (month, year units)
+	Duration time.Duration // parsed duration, excluding Months
+}
+
 // NumberNode holds a number: signed or unsigned integer or float.
 // The value is parsed and stored under all the types that can represent the value.
 // This simulates in a small amount of code the behavior of Go's ideal constants.
@@ -189,6 +377,7 @@ type BinaryNode struct {
 
 // Tri Node
 //    ARG1 Between ARG2 AND ARG3
+//    ARG1 LIKE ARG2 ESCAPE ARG3
 type TriNode struct {
 	Pos
 	Args     [3]Node
@@ -223,11 +412,185 @@ type MultiArgNode struct {
 	Operator lex.Token
 }
 
+// Row Constructor node, a tuple of nodes
+//    (arg0, arg1, ...)
+//    (a, b) IN ((1,2), (3,4))
+//    (a, b) IN (SELECT x, y FROM t)
+type RowConstructorNode struct {
+	Pos
+	Args []Node
+}
+
+// Subscript node, access of a key of a map valued expression or index of
+//  an array valued one (negative from the end), missing is NULL
+//    tags['env']
+//    doc["a"]["b"]
+//    arr[0]
+//    arr[-1]
+type SubscriptNode struct {
+	Pos
+	Arg Node // the map or array valued expression, ie an identity
+	Key Node // the key, a StringNode, or index, a NumberNode
+}
+
+// Match node, a full text search predicate, true if any of the columns
+//  contain any of the words of Against.   Sources with a full text index
+//  answer it (datasource.FullTextSearcher), else the vm matches the words.
+//    MATCH(title, body) AGAINST("quick fox")
+type MatchNode struct {
+	Pos
+	Cols    []*IdentityNode
+	Against string
+}
+
+// SubQuery node, a scalar sub-select in an expression, its value is the
+//  single column of its single row (NULL if it has no rows).   Evaluated
+//  by contexts which implement ContextSubQuery.
+//    count(*) > (SELECT avg(ct) FROM daily)
+type SubQueryNode struct {
+	Pos
+	Select *SqlSelect
+}
+
+// Param node, a positional ? placeholder, replaced by the literal of its
+//  arg by BindParams
+//    WHERE id IN (?, ?) AND status = ?
+type ParamNode struct {
+	Pos
+}
+
 // Pos represents a byte position in the original input text which was parsed
 type Pos int
 
 func (p Pos) Position() Pos { return p }
 
+// NodesEqual is true if @a and @b are the same tree of nodes:  the same
+//  node types, operators, func names and literal text, positions are
+//  ignored and statements are compared by their sql
+func NodesEqual(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.NodeType() != b.NodeType() {
+		return false
+	}
+	switch at := a.(type) {
+	case *IdentityNode:
+		bt, ok := b.(*IdentityNode)
+		return ok && at.Text == bt.Text && at.Quote == bt.Quote
+	case *StringNode:
+		bt, ok := b.(*StringNode)
+		return ok && at.Text == bt.Text
+	case *NumberNode:
+		bt, ok := b.(*NumberNode)
+		return ok && at.Text == bt.Text
+	case *NullNode:
+		return true
+	case *IntervalNode:
+		bt, ok := b.(*IntervalNode)
+		return ok && at.Text == bt.Text && at.Unit == bt.Unit
+	case *FuncNode:
+		bt, ok := b.(*FuncNode)
+		return ok && strings.EqualFold(at.Name, bt.Name) && at.Distinct == bt.Distinct &&
+			nodesEqual(at.Args, bt.Args)
+	case *BinaryNode:
+		bt, ok := b.(*BinaryNode)
+		return ok && at.Operator.T == bt.Operator.T && at.Paren == bt.Paren &&
+			nodesEqual(at.Args[:], bt.Args[:])
+	case *TriNode:
+		bt, ok := b.(*TriNode)
+		return ok && at.Operator.T == bt.Operator.T && nodesEqual(at.Args[:], bt.Args[:])
+	case *UnaryNode:
+		bt, ok := b.(*UnaryNode)
+		return ok && at.Operator.T == bt.Operator.T && NodesEqual(at.Arg, bt.Arg)
+	case *MultiArgNode:
+		bt, ok := b.(*MultiArgNode)
+		return ok && at.Operator.T == bt.Operator.T && nodesEqual(at.Args, bt.Args)
+	case *RowConstructorNode:
+		bt, ok := b.(*RowConstructorNode)
+		return ok && nodesEqual(at.Args, bt.Args)
+	case *SubscriptNode:
+		bt, ok := b.(*SubscriptNode)
+		return ok && NodesEqual(at.Arg, bt.Arg) && NodesEqual(at.Key, bt.Key)
+	case *MatchNode:
+		bt, ok := b.(*MatchNode)
+		if !ok || at.Against != bt.Against || len(at.Cols) != len(bt.Cols) {
+			return false
+		}
+		for i, col := range at.Cols {
+			if !NodesEqual(col, bt.Cols[i]) {
+				return false
+			}
+		}
+		return true
+	case *SubQueryNode:
+		bt, ok := b.(*SubQueryNode)
+		return ok && at.Select.String() == bt.Select.String()
+	}
+	return a.String() == b.String()
+}
+
+func nodesEqual(a, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !NodesEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fingerprint identifies a node tree, nodes with the same Fingerprint
+//  are NodesEqual, so evaluate to the same value on the same row if pure
+//
+//     upper(name)   =>  "2:upper(name)"
+func Fingerprint(n Node) string {
+	return fmt.Sprintf("%d:%s", n.NodeType(), n.StringAST())
+}
+
+// IsPure is true if evaluating @n on a row always gives the same value:
+//  it has no Volatile (or table) funcs and no sub-selects
+//
+//     upper(name) = "BOB"    =>  true
+//     now() > ts             =>  false
+func IsPure(n Node) bool {
+	switch nt := n.(type) {
+	case nil:
+		return true
+	case *FuncNode:
+		if nt.F.Volatile || nt.F.Table {
+			return false
+		}
+		return allPure(nt.Args)
+	case *BinaryNode:
+		return allPure(nt.Args[:])
+	case *TriNode:
+		return allPure(nt.Args[:])
+	case *UnaryNode:
+		return IsPure(nt.Arg)
+	case *MultiArgNode:
+		return allPure(nt.Args)
+	case *RowConstructorNode:
+		return allPure(nt.Args)
+	case *SubscriptNode:
+		return IsPure(nt.Arg) && IsPure(nt.Key)
+	case SqlStatement, *SubQueryNode:
+		return false
+	}
+	return true
+}
+
+func allPure(nodes []Node) bool {
+	for _, n := range nodes {
+		if !IsPure(n) {
+			return false
+		}
+	}
+	return true
+}
+
 // Recursively descend down a node looking for first Identity Field
 //
 //     min(year)                 == year
@@ -237,6 +600,8 @@ func FindIdentityField(node Node) string {
 	switch n := node.(type) {
 	case *IdentityNode:
 		return n.Text
+	case *SubscriptNode:
+		return FindIdentityField(n.Arg)
 	case *BinaryNode:
 		for _, arg := range n.Args {
 			return FindIdentityField(arg)
@@ -278,36 +643,117 @@ func FindIdentityName(depth int, node Node, prefix string) string {
 
 }
 
-// Infer Value type from Node
+// Infer Value type from Node, an identity not bound to a type (see
+//  CheckSchema) is a string
 func ValueTypeFromNode(n Node) value.ValueType {
+	if nt, ok := n.(*IdentityNode); ok && nt.vt == value.NilType && !nt.IsBooleanIdentity() {
+		return value.StringType
+	}
+	return InferValueType(n)
+}
+
+// InferValueType is the value type of a node as far as it is known, an
+//  identity not bound to a type is UnknownType rather than the string of
+//  ValueTypeFromNode, ie a column of a result schema
+func InferValueType(n Node) value.ValueType {
 	switch nt := n.(type) {
 	case *FuncNode:
+		return funcValueType(nt)
 	case *StringNode:
 		return value.StringType
 	case *IdentityNode:
-		// ??
-		return value.StringType
+		// not known without a schema, see CheckSchema
+		if nt.vt != value.NilType {
+			return nt.vt
+		} else if nt.IsBooleanIdentity() {
+			return value.BoolType
+		}
+		return value.UnknownType
 	case *NumberNode:
 		return value.NumberType
+	case *NullNode:
+		return value.NilType
+	case *IntervalNode:
+		return value.DurationType
 	case *BinaryNode:
 		switch nt.Operator.T {
-		case lex.TokenLogicAnd, lex.TokenLogicOr:
+		case lex.TokenLogicAnd, lex.TokenLogicOr, lex.TokenAnd, lex.TokenOr,
+			lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
+			lex.TokenLT, lex.TokenLE, lex.TokenLike, lex.TokenILike, lex.TokenIs, lex.TokenIsNot:
 			return value.BoolType
-		case lex.TokenMultiply, lex.TokenMinus, lex.TokenAdd, lex.TokenDivide:
+		case lex.TokenMultiply, lex.TokenStar, lex.TokenMinus, lex.TokenPlus, lex.TokenDivide:
 			return value.NumberType
 		case lex.TokenModulus:
 			return value.IntType
+		case lex.TokenConcat:
+			return value.StringType
 		default:
-			u.Warnf("NoValueType? %T", n)
+			logging.Warnf("NoValueType? %T", n)
+		}
+	case *UnaryNode:
+		switch nt.Operator.T {
+		case lex.TokenNegate, lex.TokenIs:
+			return value.BoolType
+		case lex.TokenMinus:
+			return value.NumberType
 		}
+	case *TriNode, *MultiArgNode:
+		return value.BoolType
+	case *RowConstructorNode:
+		return value.SliceValueType
+	case *SubscriptNode:
+		return value.UnknownType
+	case *MatchNode:
+		return value.BoolType
+	case *SubQueryNode:
+		// the column type of another table
+		return value.UnknownType
+	case *ParamNode:
+		// not known until bound
+		return value.UnknownType
 	case nil:
 		return value.UnknownType
 	default:
-		u.Warnf("NoValueType? %T", n)
+		logging.Warnf("NoValueType? %T", n)
 	}
 	return value.UnknownType
 }
 
+// the value type a func returns, an ArgTyped func is the common type of
+//  its args, ie  ifnull(toint(x), 0)  is a number
+func funcValueType(fn *FuncNode) value.ValueType {
+	if fn.F.ArgTyped {
+		common := value.NilType
+		for _, arg := range fn.Args {
+			switch vt := InferValueType(arg); {
+			case vt == value.NilType || vt == common:
+				// NULL literals don't change the type
+			case vt == value.UnknownType:
+				return value.UnknownType
+			case common == value.NilType:
+				common = vt
+			case isNumericType(common) && isNumericType(vt):
+				common = value.NumberType
+			default:
+				return value.UnknownType
+			}
+		}
+		if common == value.NilType {
+			return value.UnknownType
+		}
+		return common
+	}
+	if fn.F.ReturnValueType == value.NilType {
+		// not a registered func (ast only parse)
+		return value.UnknownType
+	}
+	return fn.F.ReturnValueType
+}
+
+func isNumericType(vt value.ValueType) bool {
+	return vt == value.NumberType || vt == value.IntType
+}
+
 func NewFuncNode(pos Pos, name string, f Func) *FuncNode {
 	return &FuncNode{Pos: pos, Name: name, F: f}
 }
@@ -316,8 +762,15 @@ func (c *FuncNode) append(arg Node) {
 	c.Args = append(c.Args, arg)
 }
 
+// IsAggregate is this func an aggregate (sum, count, etc) over the rows
+//  of a group, rather than a per row scalar func
+func (c *FuncNode) IsAggregate() bool { return c.F.Aggregate }
+
 func (c *FuncNode) String() string {
 	s := c.Name + "("
+	if c.Distinct {
+		s += "DISTINCT "
+	}
 	for i, arg := range c.Args {
 		if i > 0 {
 			s += ", "
@@ -330,8 +783,11 @@ func (c *FuncNode) String() string {
 
 func (c *FuncNode) StringAST() string {
 	s := c.Name + "("
+	if c.Distinct {
+		s += "DISTINCT "
+	}
 	for i, arg := range c.Args {
-		//u.Debugf("arg: %v   %T %v", arg, arg, arg.StringAST())
+		//logging.Debugf("arg: %v   %T %v", arg, arg, arg.StringAST())
 		if i > 0 {
 			s += ", "
 		}
@@ -348,36 +804,64 @@ func (c *FuncNode) Check() error {
 	} else if (len(c.Args) >= len(c.F.Args)) && c.F.VariadicArgs {
 		// ok
 	} else if len(c.Args) > len(c.F.Args) {
-		u.Warnf("lenc.Args >= len(c.F.Args?  %v", (len(c.Args) >= len(c.F.Args)))
+		logging.Warnf("lenc.Args >= len(c.F.Args?  %v", (len(c.Args) >= len(c.F.Args)))
 		err := fmt.Errorf("parse: too many arguments for %s want:%v got:%v   %#v", c.Name, len(c.F.Args), len(c.Args), c.Args)
-		u.Errorf("funcNode.Check(): %v", err)
+		logging.Errorf("funcNode.Check(): %v", err)
 		return err
 	}
 	for i, a := range c.Args {
-		switch a.(type) {
-		case Node:
-			if err := a.Check(); err != nil {
-				return err
-			}
-		case value.Value:
-			// TODO: we need to check co-ercion here, ie which Args can be converted to what types
-			if nodeVal, ok := a.(NodeValueType); ok {
-				// For Env Variables, we need to Check those (On Definition?)
-				if c.F.Args[i].Kind() != nodeVal.Type().Kind() {
-					u.Errorf("error in parse Check(): %v", a)
-					return fmt.Errorf("parse: expected %v, got %v    ", nodeVal.Type().Kind(), c.F.Args[i].Kind())
-				}
-				if err := a.Check(); err != nil {
-					return err
-				}
-			}
-
+		if err := a.Check(); err != nil {
+			return err
+		}
+		// a func or literal arg must be the type the func declares, also
+		//  each variadic arg, ie outer(inner(x)) inner returns what outer expects
+		want, got := c.argValueType(i), argNodeValueType(a)
+		if want != value.UnknownType && got != value.UnknownType && want != got {
+			return fmt.Errorf("parse: %s arg %d expected %v but got %v: %s", c.Name, i+1, want, got, a)
 		}
-
 	}
 	return nil
 }
 
+// the value type the func declares for arg @i, args past the last are of
+//  the variadic element type.  UnknownType for value.Value args, or funcs
+//  we don't have the go func for
+func (c *FuncNode) argValueType(i int) value.ValueType {
+	if len(c.F.Args) == 0 {
+		return value.UnknownType
+	}
+	variadic := c.F.VariadicArgs && i >= len(c.F.Args)-1
+	if variadic {
+		i = len(c.F.Args) - 1
+	}
+	if i >= len(c.F.Args) || !c.F.Args[i].IsValid() {
+		return value.UnknownType
+	}
+	rt := c.F.Args[i].Type()
+	if variadic {
+		rt = rt.Elem()
+	}
+	switch vt := value.ValueTypeFromRT(rt); vt {
+	case value.NilType:
+		return value.UnknownType
+	default:
+		return vt
+	}
+}
+
+// the value type of a func or literal arg, not known for others until evaluated
+func argNodeValueType(n Node) value.ValueType {
+	switch nt := n.(type) {
+	case *FuncNode:
+		return funcValueType(nt)
+	case *StringNode:
+		return value.StringType
+	case *NumberNode:
+		return nt.Value().Type()
+	}
+	return value.UnknownType
+}
+
 func (f *FuncNode) NodeType() NodeType  { return FuncNodeType }
 func (f *FuncNode) Type() reflect.Value { return f.F.Return }
 
@@ -428,15 +912,206 @@ func (n *NumberNode) Check() error {
 func (m *NumberNode) NodeType() NodeType  { return NumberNodeType }
 func (n *NumberNode) Type() reflect.Value { return floatRv }
 
+// Value of this number, an IntValue if it is (or was promoted to) an
+//  integer, else a NumberValue
+//
+//     5    => IntValue(5)
+//     2.0  => IntValue(2)
+//     2.5  => NumberValue(2.5)
+func (n *NumberNode) Value() value.Value {
+	switch {
+	case n.IsInt:
+		return value.NewIntValue(n.Int64)
+	case n.IsFloat:
+		return value.NewNumberValue(n.Float64)
+	}
+	return value.NewNilValue()
+}
+
+// NewIntervalNode from the text of   INTERVAL "1 day"   or the quantity
+//  and unit keyword of    INTERVAL 2 HOUR
+func NewIntervalNode(pos Pos, text, unit string) (*IntervalNode, error) {
+	intervalText := text
+	if unit != "" {
+		intervalText = text + " " + unit
+	}
+	months, d, err := ParseInterval(intervalText)
+	if err != nil {
+		return nil, err
+	}
+	return &IntervalNode{Pos: pos, Text: text, Unit: unit, Months: months, Duration: d}, nil
+}
+
+func (m *IntervalNode) String() string { return m.StringAST() }
+func (m *IntervalNode) StringAST() string {
+	if m.Unit == "" {
+		return fmt.Sprintf("INTERVAL %q", m.Text)
+	}
+	if _, err := strconv.ParseFloat(m.Text, 64); err == nil {
+		return fmt.Sprintf("INTERVAL %s %s", m.Text, strings.ToUpper(m.Unit))
+	}
+	return fmt.Sprintf("INTERVAL %q %s", m.Text, strings.ToUpper(m.Unit))
+}
+func (m *IntervalNode) Check() error        { return nil }
+func (m *IntervalNode) NodeType() NodeType  { return IntervalNodeType }
+func (m *IntervalNode) Type() reflect.Value { return durRv }
+
+// Value of the interval as a duration, calendar months are approximated
+//  as 30 days, use AddTo() for calendar correct time arithmetic
+func (m *IntervalNode) Value() value.Value {
+	return value.NewDurationValue(m.Duration + time.Duration(m.Months)*30*24*time.Hour)
+}
+
+// AddTo adds (or subtracts if @sign is negative) this interval to @t,
+//  months and years are calendar months,  Jan 31 + 1 month = Mar 3
+func (m *IntervalNode) AddTo(t time.Time, sign int) time.Time {
+	if sign < 0 {
+		return t.AddDate(0, -m.Months, 0).Add(-m.Duration)
+	}
+	return t.AddDate(0, m.Months, 0).Add(m.Duration)
+}
+
+var intervalUnits = map[string]time.Duration{
+	"us": time.Microsecond, "microsecond": time.Microsecond,
+	"ms": time.Millisecond, "millisecond": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour,
+}
+
+// calendar units, in months
+var intervalMonthUnits = map[string]int{
+	"mon": 1, "month": 1,
+	"quarter": 3,
+	"y": 12, "year": 12,
+}
+
+// IsIntervalUnit is this word an interval unit (day, hours, MONTH, etc)
+func IsIntervalUnit(word string) bool {
+	_, _, ok := intervalUnit(word)
+	return ok
+}
+
+func intervalUnit(word string) (time.Duration, int, bool) {
+	word = strings.ToLower(word)
+	for _, w := range []string{word, strings.TrimSuffix(word, "s")} {
+		if d, ok := intervalUnits[w]; ok {
+			return d, 0, true
+		}
+		if months, ok := intervalMonthUnits[w]; ok {
+			return 0, months, true
+		}
+	}
+	return 0, 0, false
+}
+
+// ParseInterval parses the text of an interval literal, a list of
+//  quantity unit pairs, or a go duration.   Calendar units (month,
+//  quarter, year) are returned as whole months
+//
+//     1 day
+//     -2 hours 30 minutes
+//     1 year 2 months
+//     1h30m
+func ParseInterval(text string) (months int, d time.Duration, err error) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 1 {
+		if d, err := time.ParseDuration(fields[0]); err == nil {
+			return 0, d, nil
+		}
+	}
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return 0, 0, fmt.Errorf("invalid interval %q", text)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		qty, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid interval %q: %v", text, err)
+		}
+		unit, unitMonths, ok := intervalUnit(fields[i+1])
+		switch {
+		case !ok:
+			return 0, 0, fmt.Errorf("invalid interval unit %q", fields[i+1])
+		case unitMonths > 0:
+			if qty != float64(int(qty)) {
+				return 0, 0, fmt.Errorf("invalid interval %q: fractional %s", text, fields[i+1])
+			}
+			months += int(qty) * unitMonths
+		default:
+			d += time.Duration(qty * float64(unit))
+		}
+	}
+	return months, d, nil
+}
+
 func NewStringNode(pos Pos, text string) *StringNode {
-	return &StringNode{Pos: pos, Text: text}
+	return &StringNode{Pos: pos, Text: text, typed: &typedLiteral{}}
 }
+
+// StringAST double quotes and escapes (quotes, newlines, non-printable) the
+// Text, the lexer decodes these escapes back to the original Text
 func (m *StringNode) String() string      { return m.Text }
 func (m *StringNode) StringAST() string   { return fmt.Sprintf("%q", m.Text) }
 func (m *StringNode) Check() error        { return nil }
 func (m *StringNode) NodeType() NodeType  { return StringNodeType }
 func (m *StringNode) Type() reflect.Value { return stringRv }
 
+// TypedValue interprets the literal Text as a number, bool or time for
+//  implicit coercion in a typed context, ie   age > "30".   Returns the
+//  StringValue and false if the text looks like none of these.  Text
+//  remains the source for StringAST.
+//
+//     "30"          => IntValue(30)
+//     "1.5"         => NumberValue(1.5)
+//     "true"        => BoolValue(true)
+//     "2015-01-02"  => TimeValue
+//     "hello"       => StringValue("hello"), false
+func (m *StringNode) TypedValue() (value.Value, bool) {
+	if m.typed == nil {
+		// not of NewStringNode
+		return parseTypedLiteral(m.Text)
+	}
+	m.typed.once.Do(func() { m.typed.v, m.typed.ok = parseTypedLiteral(m.Text) })
+	return m.typed.v, m.typed.ok
+}
+
+func parseTypedLiteral(literal string) (value.Value, bool) {
+	text := strings.TrimSpace(literal)
+	if text == "" {
+		return value.NewStringValue(literal), false
+	}
+	if looksNumeric(text) {
+		if iv, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return value.NewIntValue(iv), true
+		}
+		if fv, err := strconv.ParseFloat(text, 64); err == nil {
+			return value.NewNumberValue(fv), true
+		}
+	}
+	switch strings.ToLower(text) {
+	case "true":
+		return value.NewBoolValue(true), true
+	case "false":
+		return value.NewBoolValue(false), true
+	}
+	if t, err := dateparse.ParseAny(text); err == nil {
+		return value.NewTimeValue(t), true
+	}
+	return value.NewStringValue(literal), false
+}
+
+// numbers start with a digit, sign or decimal, so we don't
+// treat  "Inf" or "NaN" as numbers
+func looksNumeric(text string) bool {
+	switch c := text[0]; {
+	case c >= '0' && c <= '9', c == '-', c == '+', c == '.':
+		return true
+	}
+	return false
+}
+
 func NewIdentityNode(tok *lex.Token) *IdentityNode {
 	return &IdentityNode{Pos: Pos(tok.Pos), Text: tok.V, Quote: tok.Quote}
 }
@@ -444,13 +1119,28 @@ func NewIdentityNode(tok *lex.Token) *IdentityNode {
 func (m *IdentityNode) String() string { return m.Text }
 func (m *IdentityNode) StringAST() string {
 	if m.Quote == 0 {
-		return m.Text
+		if m.IsBooleanIdentity() {
+			return m.Text
+		}
+		return lex.QuoteIdentifier(m.Text, nil)
 	}
 	return string(m.Quote) + m.Text + string(m.Quote)
 }
-func (m *IdentityNode) Check() error        { return nil }
-func (m *IdentityNode) NodeType() NodeType  { return IdentityNodeType }
-func (m *IdentityNode) Type() reflect.Value { return stringRv }
+func (m *IdentityNode) Check() error       { return nil }
+func (m *IdentityNode) NodeType() NodeType { return IdentityNodeType }
+func (m *IdentityNode) Type() reflect.Value {
+	switch m.vt {
+	case value.NumberType:
+		return floatRv
+	case value.IntType:
+		return int64Rv
+	case value.BoolType:
+		return boolRv
+	case value.TimeType:
+		return timeRv
+	}
+	return stringRv
+}
 func (m *IdentityNode) IsBooleanIdentity() bool {
 	val := strings.ToLower(m.Text)
 	if val == "true" || val == "false" {
@@ -509,7 +1199,7 @@ unary_op   = "+" | "-" | "!" | "^" | "*" | "&" | "<-" .
 //   @operator =  and, or, "is not"
 //  @lhArg, rhArg the left, right side of binary
 func NewBinaryNode(operator lex.Token, lhArg, rhArg Node) *BinaryNode {
-	//u.Debugf("NewBinaryNode: %v %v %v", lhArg, operator, rhArg)
+	//logging.Debugf("NewBinaryNode: %v %v %v", lhArg, operator, rhArg)
 	return &BinaryNode{Pos: Pos(operator.Pos), Args: [2]Node{lhArg, rhArg}, Operator: operator}
 }
 
@@ -522,6 +1212,11 @@ func (m *BinaryNode) StringAST() string {
 }
 func (m *BinaryNode) Check() error {
 	// do all args support Binary Operations?   Does that make sense or not?
+	for _, arg := range m.Args {
+		if err := arg.Check(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (m *BinaryNode) NodeType() NodeType { return BinaryNodeType }
@@ -540,7 +1235,7 @@ func (m *BinaryNode) IsSimple() bool {
 		case IdentityNodeType, StringNodeType:
 			// ok
 		default:
-			u.Warnf("is not simple: %T", arg)
+			logging.Warnf("is not simple: %T", arg)
 			return false
 		}
 	}
@@ -555,9 +1250,22 @@ func NewTriNode(operator lex.Token, arg1, arg2, arg3 Node) *TriNode {
 }
 func (m *TriNode) String() string { return m.StringAST() }
 func (m *TriNode) StringAST() string {
+	switch m.Operator.T {
+	case lex.TokenLike:
+		return fmt.Sprintf("%s LIKE %s ESCAPE %s", m.Args[0].String(), m.Args[1].StringAST(), m.Args[2].StringAST())
+	case lex.TokenILike:
+		return fmt.Sprintf("%s ILIKE %s ESCAPE %s", m.Args[0].String(), m.Args[1].StringAST(), m.Args[2].StringAST())
+	}
 	return fmt.Sprintf("%s BETWEEN %s AND %s", m.Args[0].String(), m.Args[1].String(), m.Args[2].StringAST())
 }
-func (m *TriNode) Check() error        { return nil }
+func (m *TriNode) Check() error {
+	for _, arg := range m.Args {
+		if err := arg.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func (m *TriNode) NodeType() NodeType  { return TriNodeType }
 func (m *TriNode) Type() reflect.Value { /* ?? */ return boolRv }
 
@@ -597,17 +1305,138 @@ func NewMultiArgNodeArgs(operator lex.Token, args []Node) *MultiArgNode {
 }
 func (m *MultiArgNode) String() string { return m.StringAST() }
 func (m *MultiArgNode) StringAST() string {
+	if len(m.Args) == 0 {
+		return fmt.Sprintf("%s ()", m.Operator.V)
+	}
 	args := make([]string, len(m.Args)-1)
 	for i := 1; i < len(m.Args); i++ {
 		args[i-1] = m.Args[i].StringAST()
 	}
 	return fmt.Sprintf("%s %s (%s)", m.Args[0].StringAST(), m.Operator.V, strings.Join(args, ","))
 }
-func (m *MultiArgNode) Check() error        { return nil }
+func (m *MultiArgNode) Check() error {
+	if len(m.Args) < 2 {
+		//  x IN ()
+		return fmt.Errorf("expr: empty %s list at position %d", m.Operator.V, m.Pos)
+	}
+	for _, arg := range m.Args {
+		if err := arg.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func (m *MultiArgNode) NodeType() NodeType  { return MultiArgNodeType }
 func (m *MultiArgNode) Type() reflect.Value { /* ?? */ return boolRv }
 func (m *MultiArgNode) Append(n Node)       { m.Args = append(m.Args, n) }
 
+// Create a Row Constructor node
+//   @args ....
+func NewRowConstructorNode(pos Pos, args []Node) *RowConstructorNode {
+	return &RowConstructorNode{Pos: pos, Args: args}
+}
+func (m *RowConstructorNode) String() string { return m.StringAST() }
+func (m *RowConstructorNode) StringAST() string {
+	args := make([]string, len(m.Args))
+	for i, arg := range m.Args {
+		args[i] = arg.StringAST()
+	}
+	return fmt.Sprintf("(%s)", strings.Join(args, ", "))
+}
+func (m *RowConstructorNode) Check() error {
+	for _, arg := range m.Args {
+		if err := arg.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (m *RowConstructorNode) NodeType() NodeType  { return RowConstructorType }
+func (m *RowConstructorNode) Type() reflect.Value { return reflect.ValueOf([]value.Value{}) }
+
+// Create a Subscript node
+//   @arg[@key]
+func NewSubscriptNode(pos Pos, arg, key Node) *SubscriptNode {
+	return &SubscriptNode{Pos: pos, Arg: arg, Key: key}
+}
+func (m *SubscriptNode) String() string { return m.StringAST() }
+func (m *SubscriptNode) StringAST() string {
+	return fmt.Sprintf("%s[%s]", m.Arg.StringAST(), m.Key.StringAST())
+}
+func (m *SubscriptNode) Check() error {
+	if err := m.Arg.Check(); err != nil {
+		return err
+	}
+	return m.Key.Check()
+}
+func (m *SubscriptNode) NodeType() NodeType { return SubscriptNodeType }
+
+// Create a Match node
+//   MATCH(@cols) AGAINST(@against)
+func NewMatchNode(pos Pos, cols []*IdentityNode, against string) *MatchNode {
+	return &MatchNode{Pos: pos, Cols: cols, Against: against}
+}
+func (m *MatchNode) String() string { return m.StringAST() }
+func (m *MatchNode) StringAST() string {
+	cols := make([]string, len(m.Cols))
+	for i, col := range m.Cols {
+		cols[i] = col.StringAST()
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST(%q)", strings.Join(cols, ", "), m.Against)
+}
+func (m *MatchNode) Check() error {
+	if len(m.Cols) == 0 {
+		return fmt.Errorf("MATCH requires at least one column")
+	}
+	return nil
+}
+func (m *MatchNode) NodeType() NodeType  { return MatchNodeType }
+func (m *MatchNode) Type() reflect.Value { return boolRv }
+
+// ColNames are the names of the searched columns
+func (m *MatchNode) ColNames() []string {
+	names := make([]string, len(m.Cols))
+	for i, col := range m.Cols {
+		names[i] = col.Text
+	}
+	return names
+}
+
+// Create a scalar SubQuery node
+//   (@sel)
+func NewSubQueryNode(pos Pos, sel *SqlSelect) *SubQueryNode {
+	return &SubQueryNode{Pos: pos, Select: sel}
+}
+func (m *SubQueryNode) String() string    { return m.StringAST() }
+func (m *SubQueryNode) StringAST() string { return fmt.Sprintf("(%s)", m.Select.String()) }
+func (m *SubQueryNode) Check() error {
+	if len(m.Select.Columns) != 1 {
+		return fmt.Errorf("scalar sub-query must select one column: %s", m.Select)
+	}
+	return nil
+}
+func (m *SubQueryNode) NodeType() NodeType { return SubQueryNodeType }
+
+// Create a Param node
+//   ?
+func NewParamNode(pos Pos) *ParamNode {
+	return &ParamNode{Pos: pos}
+}
+func (m *ParamNode) String() string     { return "?" }
+func (m *ParamNode) StringAST() string  { return "?" }
+func (m *ParamNode) Check() error       { return nil }
+func (m *ParamNode) NodeType() NodeType { return ParamNodeType }
+
+// MatchTerms splits text into the lower cased words MATCH compares, the
+//  runs of letters and digits
+//
+//     "The quick-brown fox!"  =>  [the quick brown fox]
+func MatchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
 /*
 func NewSetNode(operator lex.Token) *SetNode {
 	return &SetNode{Pos: Pos(operator.Pos), Args: make([]Node, 0), Operator: operator}
@@ -629,7 +1458,7 @@ func (n *SetNode) Check() error {
 		case value.Value:
 			continue
 		default:
-			u.Warnf("unknown type? %T", t)
+			logging.Warnf("unknown type? %T", t)
 			return fmt.Errorf("parse: type error in expected? got %v", t)
 		}
 	}
@@ -0,0 +1,242 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/araddon/qlbridge/lex"
+)
+
+// the json form of a node, for transporting plans between processes.  The
+//  Type tag is the NodeType the concrete node is rebuilt from, the other
+//  fields are those of that node type.  Statements (sub-selects) are their
+//  sql text in Text.
+//
+//     x > 5  =>  {"type":10,"op":{"t":72,"v":">"},"args":[{"type":3,"text":"x"},{"type":5,"text":"5"}]}
+type jsonNode struct {
+	Type     NodeType    `json:"type"`
+	Pos      int         `json:"pos,omitempty"`
+	Text     string      `json:"text,omitempty"`
+	Quote    byte        `json:"quote,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	Distinct bool        `json:"distinct,omitempty"`
+	Paren    bool        `json:"paren,omitempty"`
+	Unit     string      `json:"unit,omitempty"`
+	Op       *jsonToken  `json:"op,omitempty"`
+	Args     []*jsonNode `json:"args,omitempty"`
+}
+
+type jsonToken struct {
+	T     lex.TokenType `json:"t"`
+	V     string        `json:"v"`
+	Quote byte          `json:"quote,omitempty"`
+}
+
+// MarshalNode encodes a node tree as json, see UnmarshalNode
+//
+//     data, err := expr.MarshalNode(stmt.Where.Expr)
+func MarshalNode(n Node) ([]byte, error) {
+	jn, err := toJsonNode(n)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jn)
+}
+
+// UnmarshalNode decodes the json of MarshalNode back to the node tree,
+//  funcs are resolved against the registered funcs of this process
+func UnmarshalNode(data []byte) (Node, error) {
+	jn := &jsonNode{}
+	if err := json.Unmarshal(data, jn); err != nil {
+		return nil, err
+	}
+	return fromJsonNode(jn)
+}
+
+func toJsonToken(tok lex.Token) *jsonToken {
+	return &jsonToken{T: tok.T, V: tok.V, Quote: tok.Quote}
+}
+
+func (m *jsonToken) token(pos int) lex.Token {
+	if m == nil {
+		return lex.Token{Pos: pos}
+	}
+	return lex.Token{T: m.T, V: m.V, Quote: m.Quote, Pos: pos}
+}
+
+func toJsonNodes(nodes []Node) ([]*jsonNode, error) {
+	args := make([]*jsonNode, len(nodes))
+	for i, n := range nodes {
+		jn, err := toJsonNode(n)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = jn
+	}
+	return args, nil
+}
+
+func toJsonNode(n Node) (*jsonNode, error) {
+	if n == nil {
+		return nil, nil
+	}
+	jn := &jsonNode{Type: n.NodeType(), Pos: int(n.Position())}
+	var args []Node
+	switch nt := n.(type) {
+	case *IdentityNode:
+		jn.Text, jn.Quote = nt.Text, nt.Quote
+	case *StringNode:
+		jn.Text = nt.Text
+	case *NumberNode:
+		jn.Text = nt.Text
+	case *NullNode, *ParamNode:
+	case *IntervalNode:
+		jn.Text, jn.Unit = nt.Text, nt.Unit
+	case *FuncNode:
+		jn.Name, jn.Distinct = nt.Name, nt.Distinct
+		args = nt.Args
+	case *BinaryNode:
+		jn.Op, jn.Paren = toJsonToken(nt.Operator), nt.Paren
+		args = nt.Args[:]
+	case *TriNode:
+		jn.Op = toJsonToken(nt.Operator)
+		args = nt.Args[:]
+	case *UnaryNode:
+		jn.Op = toJsonToken(nt.Operator)
+		args = []Node{nt.Arg}
+	case *MultiArgNode:
+		jn.Op = toJsonToken(nt.Operator)
+		args = nt.Args
+	case *RowConstructorNode:
+		args = nt.Args
+	case *SubscriptNode:
+		args = []Node{nt.Arg, nt.Key}
+	case *MatchNode:
+		jn.Text = nt.Against
+		args = make([]Node, len(nt.Cols))
+		for i, col := range nt.Cols {
+			args[i] = col
+		}
+	case *SubQueryNode:
+		jn.Text = nt.Select.String()
+	case SqlStatement:
+		jn.Text = nt.String()
+	default:
+		return nil, fmt.Errorf("can not marshal node %T", n)
+	}
+	if len(args) > 0 {
+		var err error
+		if jn.Args, err = toJsonNodes(args); err != nil {
+			return nil, err
+		}
+	}
+	return jn, nil
+}
+
+func fromJsonNodes(jns []*jsonNode) ([]Node, error) {
+	nodes := make([]Node, len(jns))
+	for i, jn := range jns {
+		n, err := fromJsonNode(jn)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+func fromJsonNode(jn *jsonNode) (Node, error) {
+	if jn == nil {
+		return nil, nil
+	}
+	args, err := fromJsonNodes(jn.Args)
+	if err != nil {
+		return nil, err
+	}
+	wantArgs := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s expects %d args but got %d", jn.Type, n, len(args))
+		}
+		return nil
+	}
+	pos := Pos(jn.Pos)
+	switch jn.Type {
+	case IdentityNodeType:
+		return NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: jn.Text, Quote: jn.Quote, Pos: jn.Pos}), nil
+	case StringNodeType:
+		return NewStringNode(pos, jn.Text), nil
+	case NumberNodeType:
+		return NewNumber(pos, jn.Text)
+	case NullNodeType:
+		return &NullNode{Pos: pos}, nil
+	case ParamNodeType:
+		return NewParamNode(pos), nil
+	case IntervalNodeType:
+		return NewIntervalNode(pos, jn.Text, jn.Unit)
+	case FuncNodeType:
+		f, ok := findFunc(jn.Name)
+		if !ok {
+			return nil, fmt.Errorf("non existent function %s", jn.Name)
+		}
+		fn := NewFuncNode(pos, jn.Name, f)
+		fn.Args, fn.Distinct = args, jn.Distinct
+		return fn, nil
+	case BinaryNodeType:
+		if err := wantArgs(2); err != nil {
+			return nil, err
+		}
+		bn := NewBinaryNode(jn.Op.token(jn.Pos), args[0], args[1])
+		bn.Paren = jn.Paren
+		return bn, nil
+	case TriNodeType:
+		if err := wantArgs(3); err != nil {
+			return nil, err
+		}
+		return NewTriNode(jn.Op.token(jn.Pos), args[0], args[1], args[2]), nil
+	case UnaryNodeType:
+		if err := wantArgs(1); err != nil {
+			return nil, err
+		}
+		return NewUnary(jn.Op.token(jn.Pos), args[0]), nil
+	case MultiArgNodeType:
+		return NewMultiArgNodeArgs(jn.Op.token(jn.Pos), args), nil
+	case RowConstructorType:
+		return NewRowConstructorNode(pos, args), nil
+	case SubscriptNodeType:
+		if err := wantArgs(2); err != nil {
+			return nil, err
+		}
+		return NewSubscriptNode(pos, args[0], args[1]), nil
+	case MatchNodeType:
+		cols := make([]*IdentityNode, len(args))
+		for i, arg := range args {
+			col, ok := arg.(*IdentityNode)
+			if !ok {
+				return nil, fmt.Errorf("MATCH columns must be identities but got %s", arg.NodeType())
+			}
+			cols[i] = col
+		}
+		return NewMatchNode(pos, cols, jn.Text), nil
+	case SubQueryNodeType:
+		stmt, err := ParseSql(jn.Text)
+		if err != nil {
+			return nil, err
+		}
+		sel, ok := stmt.(*SqlSelect)
+		if !ok {
+			return nil, fmt.Errorf("sub-query must be a select but parsed %s", stmt.NodeType())
+		}
+		return NewSubQueryNode(pos, sel), nil
+	case SqlSelectNodeType, SqlInsertNodeType, SqlUpdateNodeType, SqlUpsertNodeType,
+		SqlDeleteNodeType, SqlDescribeNodeType, SqlShowNodeType, SqlPreparedType:
+		stmt, err := ParseSql(jn.Text)
+		if err != nil {
+			return nil, err
+		}
+		if stmt.NodeType() != jn.Type {
+			return nil, fmt.Errorf("expected %s but parsed %s", jn.Type, stmt.NodeType())
+		}
+		return stmt, nil
+	}
+	return nil, fmt.Errorf("can not unmarshal node type %s", jn.Type)
+}
@@ -0,0 +1,57 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestMarshalNode(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT user_id FROM users
+		WHERE (status = "active" OR tags['env'] != "dev") AND NOT (age BETWEEN 18 AND 65)
+			AND email LIKE "%@email.com" AND toint(score) * 2 >= 10.5
+			AND count IN (1, 2, NULL) AND created > now() - INTERVAL 2 DAY
+			AND (a, b) IN ((1, 2), (3, 4)) AND arr[-1] IS NOT NULL
+			AND MATCH(title, body) AGAINST("quick fox")
+			AND user_id IN (SELECT user_id FROM orders WHERE total > 100)`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	where := stmt.(*SqlSelect).Where.Expr
+
+	data, err := MarshalNode(where)
+	assert.Tf(t, err == nil, "no error %v", err)
+	n, err := UnmarshalNode(data)
+	assert.Tf(t, err == nil, "no error %v %s", err, data)
+	assert.Tf(t, NodesEqual(where, n), "round trip:\n%s\n%s", where, n)
+	assert.Equal(t, where.String(), n.String())
+
+	// funcs are resolved to those registered
+	tree, err := ParseExpression(`toint(score) * 2`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	data, _ = MarshalNode(tree.Root)
+	n, err = UnmarshalNode(data)
+	assert.Tf(t, err == nil, "no error %v", err)
+	fn, ok := n.(*BinaryNode).Args[0].(*FuncNode)
+	assert.Tf(t, ok && fn.F.F.IsValid(), "func resolved: %#v", n)
+
+	for _, exprText := range []string{`x`, `"a"`, `5`, `-5 * (2 + int)`, `NULL`, `!exists(x)`, `count(DISTINCT user_id)`} {
+		tree, err := ParseExpression(exprText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		data, err := MarshalNode(tree.Root)
+		assert.Tf(t, err == nil, "no error %v", err)
+		n, err := UnmarshalNode(data)
+		assert.Tf(t, err == nil, "no error %v", err)
+		assert.Tf(t, NodesEqual(tree.Root, n), "round trip %s: %s", exprText, n)
+	}
+
+	other, _ := ParseExpression(`status = "inactive"`)
+	same, _ := ParseExpression(`status = "active"`)
+	assert.Tf(t, !NodesEqual(other.Root, same.Root), "literals differ")
+	assert.Tf(t, !NodesEqual(where, nil) && NodesEqual(nil, nil), "nil")
+
+	for _, bad := range []string{`{"type":2,"name":"not_a_func"}`, `{"type":10,"args":[{"type":3,"text":"x"}]}`,
+		`{"type":99}`, `{"type":5,"text":"abc"}`, `[]`} {
+		_, err := UnmarshalNode([]byte(bad))
+		assert.Tf(t, err != nil, "expected error for %s", bad)
+	}
+}
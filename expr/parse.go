@@ -5,12 +5,10 @@ import (
 	"runtime"
 	"strings"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 )
 
-var _ = u.EMPTY
-
 // We have a default Dialect, which is the "Language" or rule-set of ql
 var DefaultDialect *lex.Dialect = lex.LogicalExpressionDialect
 
@@ -59,10 +57,14 @@ func (m *LexTokenPager) Next() lex.Token {
 	m.lexNext()
 	m.cursor++
 	if m.cursor+1 > len(m.tokens) {
-		//u.Warnf("Next() CRAP? increment cursor: %v of %v %v", m.cursor, len(m.tokens))
+		//logging.Warnf("Next() CRAP? increment cursor: %v of %v %v", m.cursor, len(m.tokens))
 		//panic("WTF, not enough tokens?")
 	}
-	//u.Debugf("Next(): %v of %v %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
+	if m.cursor > len(m.tokens) {
+		// read past the end, stay on the last (EOF) token
+		m.cursor = len(m.tokens)
+	}
+	//logging.Debugf("Next(): %v of %v %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
 	return m.tokens[m.cursor-1]
 }
 func (m *LexTokenPager) lexNext() {
@@ -72,14 +74,18 @@ func (m *LexTokenPager) lexNext() {
 			m.done = true
 		}
 		m.tokens = append(m.tokens, tok)
-		//u.Infof("lexNext: %v of %v cur=%v", m.cursor, len(m.tokens), tok)
+		//logging.Infof("lexNext: %v of %v cur=%v", m.cursor, len(m.tokens), tok)
 	}
 }
 func (m *LexTokenPager) Cur() lex.Token {
-	//u.Debugf("Cur(): %v of %v  %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
+	//logging.Debugf("Cur(): %v of %v  %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
 	if m.cursor+1 >= len(m.tokens) {
 		//panic("WTF, not enough tokens?")
-		//u.Warnf("Next() CRAP? increment cursor: %v of %v %v", m.cursor, len(m.tokens), m.cursor < len(m.tokens))
+		//logging.Warnf("Next() CRAP? increment cursor: %v of %v %v", m.cursor, len(m.tokens), m.cursor < len(m.tokens))
+	}
+	if m.cursor >= len(m.tokens) {
+		// read past the end, ie an unclosed  toint(5 + 4
+		return m.tokens[len(m.tokens)-1]
 	}
 	return m.tokens[m.cursor]
 }
@@ -100,29 +106,29 @@ func (m *LexTokenPager) Lexer() *lex.Lexer {
 func (m *LexTokenPager) Backup() {
 	if m.cursor > 0 {
 		m.cursor--
-		//u.Warnf("Backup?: %v", m.cursor)
+		//logging.Warnf("Backup?: %v", m.cursor)
 		return
 	}
 }
 
 // peek returns but does not consume the next token.
 func (m *LexTokenPager) Peek() lex.Token {
-	//u.Debugf("prepeek: %v of %v", m.cursor, len(m.tokens))
+	//logging.Debugf("prepeek: %v of %v", m.cursor, len(m.tokens))
 	if len(m.tokens) <= m.cursor+1 && !m.done {
 		m.lexNext()
-		//u.Warnf("lexed cursor?: %v %p", m.cursor, &m.cursor)
+		//logging.Warnf("lexed cursor?: %v %p", m.cursor, &m.cursor)
 	}
 	if len(m.tokens) < 2 {
 		m.lexNext()
 	}
 	if len(m.tokens) == m.cursor+1 {
-		u.Infof("last one?: %v of %v  %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
+		logging.Infof("last one?: %v of %v  %v", m.cursor, len(m.tokens), m.tokens[m.cursor])
 		return m.tokens[m.cursor]
 	}
 	if m.cursor == -1 {
 		return m.tokens[1]
 	}
-	//u.Infof("peek:  %v of %v %v", m.cursor, len(m.tokens), m.tokens[m.cursor+1])
+	//logging.Infof("peek:  %v of %v %v", m.cursor, len(m.tokens), m.tokens[m.cursor+1])
 	return m.tokens[m.cursor+1]
 }
 
@@ -142,12 +148,19 @@ func NewTree(pager TokenPager) *Tree {
 //
 //    ParseExpression("5 * toint(item_name)")
 //
-func ParseExpression(expressionText string) (*Tree, error) {
-	l := lex.NewLexer(expressionText, lex.LogicalExpressionDialect)
+func ParseExpression(expressionText string) (t *Tree, err error) {
+	return ParseExpressionDialect(expressionText, lex.LogicalExpressionDialect)
+}
+
+// ParseExpressionDialect parses a single Expression using a dialect other
+//  than lex.LogicalExpressionDialect, such as one with lex.ChainedRewrite
+func ParseExpressionDialect(expressionText string, dialect *lex.Dialect) (t *Tree, err error) {
+	l := lex.NewLexer(expressionText, dialect)
 	pager := NewLexTokenPager(l)
-	t := NewTree(pager)
+	t = NewTree(pager)
 	pager.end = lex.TokenEOF
-	err := t.BuildTree(true)
+	defer t.recover(&err)
+	err = t.BuildTree(true)
 	return t, err
 }
 
@@ -158,7 +171,7 @@ func (t *Tree) errorf(format string, args ...interface{}) {
 	t.Root = nil
 	format = fmt.Sprintf("expr: %s", format)
 	msg := fmt.Errorf(format, args...)
-	u.LogTracef(u.WARN, "about to panic: %v", msg)
+	logging.Warnf("about to panic: %v", msg)
 	panic(msg)
 }
 
@@ -170,9 +183,9 @@ func (t *Tree) error(err error) {
 // expect verifies the current token and guarantees it has the required type
 func (t *Tree) expect(expected lex.TokenType, context string) lex.Token {
 	token := t.Cur()
-	//u.Debugf("checking expected? %v got?: %v", expected, token)
+	//logging.Debugf("checking expected? %v got?: %v", expected, token)
 	if token.T != expected {
-		u.Warnf("unexpeted token? %v want:%v", token, expected)
+		logging.Warnf("unexpeted token? %v want:%v", token, expected)
 		t.unexpected(token, context)
 	}
 	return token
@@ -189,7 +202,7 @@ func (t *Tree) expectOneOf(expected1, expected2 lex.TokenType, context string) l
 
 // unexpected complains about the token and terminates processing.
 func (t *Tree) unexpected(token lex.Token, context string) {
-	u.Errorf("unexpected?  %v", token)
+	logging.Errorf("unexpected?  %v", token)
 	t.errorf("unexpected %s in %s", token, context)
 }
 
@@ -197,7 +210,7 @@ func (t *Tree) unexpected(token lex.Token, context string) {
 func (t *Tree) recover(errp *error) {
 	e := recover()
 	if e != nil {
-		u.Errorf("Recover():  %v", e)
+		logging.Errorf("Recover():  %v", e)
 		if _, ok := e.(runtime.Error); ok {
 			panic(e)
 		}
@@ -209,19 +222,18 @@ func (t *Tree) recover(errp *error) {
 // buildTree take the tokens and recursively build into expression tree node
 // @runCheck  Do we want to verify this tree?   If being used as VM then yes.
 func (t *Tree) BuildTree(runCheck bool) error {
-	//u.Debugf("parsing: %v", t.Cur())
+	//logging.Debugf("parsing: %v", t.Cur())
 	t.runCheck = runCheck
-	//u.Debugf("parsing: %v", t.Cur())
+	//logging.Debugf("parsing: %v", t.Cur())
 	t.Root = t.O(0)
-	//u.Debugf("after parse()")
+	//logging.Debugf("after parse()")
 	if !t.ClauseEnd() {
-		//u.Warnf("Not End? last=%v", t.TokenPager.Last())
+		//logging.Warnf("Not End? last=%v", t.TokenPager.Last())
 		//t.expect(t.TokenPager.Last(), "input")
 	}
 	if runCheck {
 		if err := t.Root.Check(); err != nil {
-			u.Errorf("found error: %v", err)
-			t.error(err)
+			logging.Errorf("found error: %v", err)
 			return err
 		}
 	}
@@ -246,6 +258,7 @@ TODO:
 O -> A {( "||" | OR  ) A}
 A -> C {( "&&" | AND ) C}
 C -> P {( "==" | "!=" | ">" | ">=" | "<" | "<=" | "LIKE" | "IN" ) P}
+     | P "LIKE" P "ESCAPE" P
 P -> M {( "+" | "-" ) M}
 M -> F {( "*" | "/" ) F}
 F -> v | "(" O ")" | "!" O | "-" O
@@ -272,40 +285,40 @@ Recursion:  We recurse so the LAST to evaluate is the highest (parent, then or)
 
 // expr:
 func (t *Tree) O(depth int) Node {
-	//u.Debugf("depth:%d t.O Cur(): %v", depth, t.Cur())
+	//logging.Debugf("depth:%d t.O Cur(): %v", depth, t.Cur())
 	n := t.A(depth)
-	//u.Debugf("depth:%d t.O AFTER: n:%v cur:%v ", depth, n, t.Cur())
+	//logging.Debugf("depth:%d t.O AFTER: n:%v cur:%v ", depth, n, t.Cur())
 	for {
 		tok := t.Cur()
-		//u.Debugf("tok:  cur=%v peek=%v", t.Cur(), t.Peek())
+		//logging.Debugf("tok:  cur=%v peek=%v", t.Cur(), t.Peek())
 		switch tok.T {
 		case lex.TokenLogicOr, lex.TokenOr:
 			t.Next()
 			n = NewBinaryNode(tok, n, t.A(depth+1))
 		case lex.TokenCommentSingleLine:
 			// we consume the comment signifier "--""   as well as comment
-			//u.Debugf("tok:  %v", t.Next())
-			//u.Debugf("tok:  %v", t.Next())
+			//logging.Debugf("tok:  %v", t.Next())
+			//logging.Debugf("tok:  %v", t.Next())
 			t.Next()
 			t.Next()
 		case lex.TokenEOF, lex.TokenEOS, lex.TokenFrom, lex.TokenComma, lex.TokenIf,
 			lex.TokenAs, lex.TokenSelect, lex.TokenLimit:
 			// these are indicators of End of Current Clause, so we can return?
-			//u.Debugf("done, return: %v", tok)
+			//logging.Debugf("done, return: %v", tok)
 			return n
 		default:
-			//u.Debugf("root couldnt evaluate node? %v", tok)
+			//logging.Debugf("root couldnt evaluate node? %v", tok)
 			return n
 		}
 	}
 }
 
 func (t *Tree) A(depth int) Node {
-	//u.Debugf("%d t.A: %v", depth, t.Cur())
+	//logging.Debugf("%d t.A: %v", depth, t.Cur())
 	n := t.C(depth)
-	//u.Debugf("%d t.A: AFTER %v", depth, t.Cur())
+	//logging.Debugf("%d t.A: AFTER %v", depth, t.Cur())
 	for {
-		//u.Debugf("tok:  cur=%v peek=%v", t.Cur(), t.Peek())
+		//logging.Debugf("tok:  cur=%v peek=%v", t.Cur(), t.Peek())
 		switch tok := t.Cur(); tok.T {
 		case lex.TokenLogicAnd, lex.TokenAnd:
 			t.Next()
@@ -317,22 +330,31 @@ func (t *Tree) A(depth int) Node {
 }
 
 func (t *Tree) C(depth int) Node {
-	//u.Debugf("%d t.C: %v", depth, t.Cur())
+	//logging.Debugf("%d t.C: %v", depth, t.Cur())
 	n := t.P(depth)
-	//u.Debugf("%d t.C: %v", depth, t.Cur())
+	//logging.Debugf("%d t.C: %v", depth, t.Cur())
 	for {
-		//u.Debugf("tok:  cur=%v peek=%v n=%v", t.Cur(), t.Peek(), n.StringAST())
+		//logging.Debugf("tok:  cur=%v peek=%v n=%v", t.Cur(), t.Peek(), n.StringAST())
 		switch cur := t.Cur(); cur.T {
 		case lex.TokenNegate:
-			//u.Infof("doing urnary node on negate: %v", cur)
+			//logging.Infof("doing urnary node on negate: %v", cur)
 			t.Next()
 			return NewUnary(cur, t.cInner(n, depth+1))
 		case lex.TokenIs:
 			t.Next()
 			if t.Cur().T == lex.TokenNegate {
-				cur = t.Next()
-				ne := lex.Token{T: lex.TokenNE, V: "!=", Pos: cur.Pos}
+				not := t.Next()
+				if t.Cur().T == lex.TokenNull {
+					//  x IS NOT NULL
+					isNot := lex.Token{T: lex.TokenIsNot, V: "IS NOT", Pos: cur.Pos}
+					return NewBinaryNode(isNot, n, t.P(depth+1))
+				}
+				ne := lex.Token{T: lex.TokenNE, V: "!=", Pos: not.Pos}
 				return NewBinaryNode(ne, n, t.P(depth+1))
+			} else if t.Cur().T == lex.TokenNull {
+				//  x IS NULL, unlike  x = NULL  which is NULL (unknown)
+				is := lex.Token{T: lex.TokenIs, V: "IS", Pos: cur.Pos}
+				return NewBinaryNode(is, n, t.P(depth+1))
 			}
 			return NewUnary(cur, t.cInner(n, depth+1))
 		default:
@@ -341,15 +363,47 @@ func (t *Tree) C(depth int) Node {
 	}
 }
 
+// the ChainedMode of the dialect being parsed
+func (t *Tree) chainedMode() lex.ChainedMode {
+	if l := t.Lexer(); l != nil && l.Dialect() != nil {
+		return l.Dialect().Chained
+	}
+	return lex.ChainedError
+}
+
 func (t *Tree) cInner(n Node, depth int) Node {
-	//u.Debugf("%d t.cInner: %v", depth, t.Cur())
+	//logging.Debugf("%d t.cInner: %v", depth, t.Cur())
+	var chained Node // right side of the last  < <= > >=
 	for {
-		//u.Debugf("cInner:  tok:  cur=%v peek=%v n=%v", t.Cur(), t.Peek(), n.StringAST())
+		//logging.Debugf("cInner:  tok:  cur=%v peek=%v n=%v", t.Cur(), t.Peek(), n.StringAST())
 		switch cur := t.Cur(); cur.T {
-		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
-			lex.TokenLE, lex.TokenLT, lex.TokenLike:
+		case lex.TokenGT, lex.TokenGE, lex.TokenLE, lex.TokenLT:
+			t.Next()
+			right := t.P(depth + 1)
+			if chained == nil {
+				n = NewBinaryNode(cur, n, right)
+			} else if t.chainedMode() == lex.ChainedRewrite {
+				//  1 < x < 10   =>   1 < x AND x < 10
+				and := lex.Token{T: lex.TokenLogicAnd, V: "AND", Pos: cur.Pos}
+				n = NewBinaryNode(and, n, NewBinaryNode(cur, chained, right))
+			} else {
+				t.errorf("chained comparison %s at position %d, use  a < b AND b < c", cur.V, cur.Pos)
+			}
+			chained = right
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE:
 			t.Next()
 			n = NewBinaryNode(cur, n, t.P(depth+1))
+			chained = nil
+		case lex.TokenLike, lex.TokenILike:
+			//  x [I]LIKE pattern [ESCAPE 'c']
+			t.Next()
+			pattern := t.P(depth + 1)
+			if t.Cur().T == lex.TokenEscape {
+				t.Next()
+				n = NewTriNode(cur, n, pattern, t.P(depth+1))
+			} else {
+				n = NewBinaryNode(cur, n, pattern)
+			}
 		case lex.TokenBetween:
 			// weird syntax:    BETWEEN x AND y     AND is ignored essentially
 			t.Next()
@@ -373,12 +427,12 @@ func (t *Tree) cInner(n Node, depth int) Node {
 }
 
 func (t *Tree) P(depth int) Node {
-	//u.Debugf("%d t.P: %v", depth, t.Cur())
+	//logging.Debugf("%d t.P: %v", depth, t.Cur())
 	n := t.M(depth)
-	//u.Debugf("%d t.P: AFTER %v", depth, t.Cur())
+	//logging.Debugf("%d t.P: AFTER %v", depth, t.Cur())
 	for {
 		switch cur := t.Cur(); cur.T {
-		case lex.TokenPlus, lex.TokenMinus:
+		case lex.TokenPlus, lex.TokenMinus, lex.TokenConcat:
 			t.Next()
 			n = NewBinaryNode(cur, n, t.M(depth+1))
 		default:
@@ -388,9 +442,9 @@ func (t *Tree) P(depth int) Node {
 }
 
 func (t *Tree) M(depth int) Node {
-	//u.Debugf("%d t.M: %v", depth, t.Cur())
+	//logging.Debugf("%d t.M: %v", depth, t.Cur())
 	n := t.F(depth)
-	//u.Debugf("%d t.M after: %v  %v", depth, t.Cur(), n)
+	//logging.Debugf("%d t.M after: %v  %v", depth, t.Cur(), n)
 	for {
 		switch cur := t.Cur(); cur.T {
 		case lex.TokenStar, lex.TokenMultiply, lex.TokenDivide, lex.TokenModulus:
@@ -403,14 +457,21 @@ func (t *Tree) M(depth int) Node {
 }
 
 func (t *Tree) MultiArg(first Node, op lex.Token, depth int) Node {
-	//u.Debugf("%d t.MultiArg: %v", depth, t.Cur())
+	//logging.Debugf("%d t.MultiArg: %v", depth, t.Cur())
 	t.expect(lex.TokenLeftParenthesis, "input")
 	t.Next() // Consume Left Paren
-	//u.Debugf("%d t.MultiArg after: %v ", depth, t.Cur())
+	//logging.Debugf("%d t.MultiArg after: %v ", depth, t.Cur())
 	multiNode := NewMultiArgNode(op)
 	multiNode.Append(first)
+	if t.Cur().T == lex.TokenSelect {
+		//  x IN (SELECT y FROM z)
+		multiNode.Append(t.SubSelect())
+		t.expect(lex.TokenRightParenthesis, "input")
+		t.Next() // Consume the Paren
+		return multiNode
+	}
 	for {
-		//u.Debugf("MultiArg iteration: %v", t.Cur())
+		//logging.Debugf("MultiArg iteration: %v", t.Cur())
 		switch cur := t.Cur(); cur.T {
 		case lex.TokenRightParenthesis:
 			t.Next() // Consume the Paren
@@ -422,15 +483,51 @@ func (t *Tree) MultiArg(first Node, op lex.Token, depth int) Node {
 			if n != nil {
 				multiNode.Append(n)
 			} else {
-				u.Warnf("invalid?  %v", t.Cur())
+				logging.Warnf("invalid?  %v", t.Cur())
 				return multiNode
 			}
 		}
 	}
 }
 
+// Row constructor, the first arg and left paren have already been consumed
+//
+//    (a, b, c)
+func (t *Tree) RowConstructor(pos Pos, first Node, depth int) Node {
+	args := []Node{first}
+	for {
+		switch cur := t.Cur(); cur.T {
+		case lex.TokenRightParenthesis:
+			t.Next() // Consume the Paren
+			return NewRowConstructorNode(pos, args)
+		case lex.TokenComma:
+			t.Next()
+			args = append(args, t.O(depth+1))
+		default:
+			t.unexpected(cur, "row constructor")
+		}
+	}
+}
+
+// Sub-select inside an expression, only available when parsing
+// sql as we need the sql parser
+//
+//    x IN (SELECT y FROM z)
+func (t *Tree) SubSelect() Node {
+	pager, ok := t.TokenPager.(*SqlTokenPager)
+	if !ok {
+		t.errorf("sub-select not supported in expression: %v", t.Cur())
+	}
+	sqlParser := &Sqlbridge{l: pager.lex, SqlTokenPager: pager}
+	stmt, err := sqlParser.parseSqlSelect()
+	if err != nil {
+		t.error(err)
+	}
+	return stmt
+}
+
 func (t *Tree) F(depth int) Node {
-	//u.Debugf("%d t.F: %v", depth, t.Cur())
+	//logging.Debugf("%d t.F: %v", depth, t.Cur())
 	switch cur := t.Cur(); cur.T {
 	case lex.TokenUdfExpr:
 		return t.v(depth)
@@ -440,18 +537,18 @@ func (t *Tree) F(depth int) Node {
 		return t.v(depth)
 	case lex.TokenValue:
 		return t.v(depth)
-	case lex.TokenNull:
+	case lex.TokenNull, lex.TokenInterval, lex.TokenParam:
 		return t.v(depth)
 	case lex.TokenStar:
 		// in special situations:   count(*) ??
 		return t.v(depth)
 	case lex.TokenNegate, lex.TokenMinus:
-		//u.Infof("doing urnary node on negate: %v", cur)
+		//logging.Infof("doing urnary node on negate: %v", cur)
 		t.Next()
 		return NewUnary(cur, t.F(depth+1))
 	case lex.TokenIs:
 		nxt := t.Next()
-		//u.Infof("doing urnary node on negate: %v  nxt=%v", cur, nxt)
+		//logging.Infof("doing urnary node on negate: %v  nxt=%v", cur, nxt)
 		if nxt.T == lex.TokenNegate {
 			return NewUnary(cur, t.F(depth+1))
 		}
@@ -460,24 +557,34 @@ func (t *Tree) F(depth int) Node {
 		// I don't think this is right, parens should be higher up
 		// in precedence stack, very top?
 		t.Next() // Consume the Paren
+		if t.Cur().T == lex.TokenSelect {
+			//  count(*) > (SELECT avg(ct) FROM daily)
+			sel := t.SubSelect().(*SqlSelect)
+			t.expect(lex.TokenRightParenthesis, "input")
+			t.Next()
+			return NewSubQueryNode(Pos(cur.Pos), sel)
+		}
 		n := t.O(depth + 1)
+		if t.Cur().T == lex.TokenComma {
+			//  (a, b)  row constructor
+			return t.RowConstructor(Pos(cur.Pos), n, depth)
+		}
 		if bn, ok := n.(*BinaryNode); ok {
 			bn.Paren = true
 		}
-		//u.Debugf("expects right paren? cur=%v p=%v", t.Cur(), t.Peek())
+		//logging.Debugf("expects right paren? cur=%v p=%v", t.Cur(), t.Peek())
 		t.expect(lex.TokenRightParenthesis, "input")
 		t.Next()
 		return n
 	default:
-		u.Warnf("unexpected? %v", cur)
-		//t.unexpected(cur, "input")
-		panic(fmt.Sprintf("unexpected token %v ", cur))
+		logging.Warnf("unexpected? %v", cur)
+		t.unexpected(cur, "input")
 	}
 	return nil
 }
 
 func (t *Tree) v(depth int) Node {
-	//u.Debugf("depth:%d t.v: cur(): %v   peek:%v", depth, t.Cur(), t.Peek())
+	//logging.Debugf("depth:%d t.v: cur(): %v   peek:%v", depth, t.Cur(), t.Peek())
 	switch cur := t.Cur(); cur.T {
 	case lex.TokenInteger, lex.TokenFloat:
 		n, err := NewNumber(Pos(cur.Pos), cur.V)
@@ -491,20 +598,68 @@ func (t *Tree) v(depth int) Node {
 		t.Next()
 		return n
 	case lex.TokenIdentity:
-		n := NewIdentityNode(&cur)
+		if cur.Quote == 0 && strings.ToLower(cur.V) == "null" {
+			// some clauses (ie BETWEEN) lex an unquoted NULL as identity
+			t.Next()
+			return NewNull(cur)
+		}
+		var n Node = NewIdentityNode(&cur)
 		t.Next()
+		for t.Cur().T == lex.TokenLeftBracket {
+			//  tags['env']   arr[0]
+			t.Next()
+			keyTok := t.expectOneOf(lex.TokenValue, lex.TokenInteger, "subscript")
+			var key Node = NewStringNode(Pos(keyTok.Pos), keyTok.V)
+			if keyTok.T == lex.TokenInteger {
+				nn, err := NewNumber(Pos(keyTok.Pos), keyTok.V)
+				if err != nil {
+					t.error(err)
+				}
+				key = nn
+			}
+			t.Next()
+			t.expect(lex.TokenRightBracket, "subscript")
+			t.Next()
+			n = NewSubscriptNode(Pos(cur.Pos), n, key)
+		}
 		return n
 	case lex.TokenNull:
 		t.Next()
 		return NewNull(cur)
+	case lex.TokenParam:
+		t.Next()
+		return NewParamNode(Pos(cur.Pos))
+	case lex.TokenInterval:
+		//  INTERVAL "1 day"   INTERVAL 2 HOUR
+		t.Next()
+		valTok := t.Cur()
+		switch valTok.T {
+		case lex.TokenValue, lex.TokenInteger, lex.TokenFloat:
+		default:
+			t.unexpected(valTok, "interval")
+		}
+		t.Next()
+		unit := ""
+		if unitTok := t.Cur(); unitTok.T == lex.TokenIdentity && IsIntervalUnit(unitTok.V) {
+			unit = unitTok.V
+			t.Next()
+		}
+		n, err := NewIntervalNode(Pos(cur.Pos), valTok.V, unit)
+		if err != nil {
+			t.error(err)
+		}
+		return n
 	case lex.TokenStar:
 		n := NewStringNode(Pos(cur.Pos), cur.V)
 		t.Next()
 		return n
 	case lex.TokenUdfExpr:
-		//u.Debugf("depth:%v t.v calling Func()?: %v", depth, cur)
+		//logging.Debugf("depth:%v t.v calling Func()?: %v", depth, cur)
 		t.Next() // consume Function Name
-		//u.Debugf("func? %v", funcTok)
+		if strings.EqualFold(cur.V, "match") {
+			return t.Match(cur)
+		}
+		//logging.Debugf("func? %v", funcTok)
 		return t.Func(depth, cur)
 	case lex.TokenLeftParenthesis:
 		// I don't think this is right, it should be higher up
@@ -514,7 +669,7 @@ func (t *Tree) v(depth int) Node {
 		if bn, ok := n.(*BinaryNode); ok {
 			bn.Paren = true
 		}
-		//u.Debugf("cur?%v n %v  ", t.Cur(), n.StringAST())
+		//logging.Debugf("cur?%v n %v  ", t.Cur(), n.StringAST())
 		t.Next()
 		t.expect(lex.TokenRightParenthesis, "input")
 		return n
@@ -522,7 +677,7 @@ func (t *Tree) v(depth int) Node {
 		if t.ClauseEnd() {
 			return nil
 		}
-		//u.Warnf("Unexpected?: %v", cur)
+		//logging.Warnf("Unexpected?: %v", cur)
 		t.unexpected(cur, "input")
 	}
 	t.Backup()
@@ -530,9 +685,9 @@ func (t *Tree) v(depth int) Node {
 }
 
 func (t *Tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
-	//u.Debugf("Func tok: %v cur:%v peek:%v", funcTok.V, t.Cur().V, t.Peek().V)
+	//logging.Debugf("Func tok: %v cur:%v peek:%v", funcTok.V, t.Cur().V, t.Peek().V)
 	if t.Cur().T != lex.TokenLeftParenthesis {
-		panic(fmt.Sprintf("must have left paren on function: %v", t.Peek()))
+		t.errorf("must have left paren on function: %v", t.Peek())
 	}
 	var node Node
 	var tok lex.Token
@@ -540,45 +695,55 @@ func (t *Tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 	funcImpl, ok := t.getFunction(funcTok.V)
 	if !ok {
 		if t.runCheck {
-			//u.Warnf("non func? %v", funcTok.V)
+			//logging.Warnf("non func? %v", funcTok.V)
 			t.errorf("non existent function %s", funcTok.V)
 		} else {
 			// if we aren't testing for validity, make a "fake" func
 			// we may not be using vm, just ast
-			//u.Warnf("non func? %v", funcTok.V)
+			//logging.Warnf("non func? %v", funcTok.V)
 			funcImpl = Func{Name: funcTok.V}
 		}
 	}
 	fn = NewFuncNode(Pos(funcTok.Pos), funcTok.V, funcImpl)
-	//u.Debugf("%d t.Func()?: %v %v", depth, t.Cur(), t.Peek())
+	//logging.Debugf("%d t.Func()?: %v %v", depth, t.Cur(), t.Peek())
 	//t.Next() // step forward to hopefully left paren
 	t.expect(lex.TokenLeftParenthesis, "func")
 
 	for {
 		node = nil
 		t.Next() // Are we sure we consume?
-		//u.Infof("%d pre loop token?: cur=%v peek=%v", depth, t.Cur(), t.Peek())
+		//logging.Infof("%d pre loop token?: cur=%v peek=%v", depth, t.Cur(), t.Peek())
+		if len(fn.Args) == 0 && !fn.Distinct && isDistinctToken(t.Cur()) {
+			switch t.Peek().T {
+			case lex.TokenComma, lex.TokenRightParenthesis:
+				// an identity named distinct
+			default:
+				//  count(DISTINCT user_id)
+				fn.Distinct = true
+				t.Next()
+			}
+		}
 		switch firstToken := t.Cur(); firstToken.T {
 		case lex.TokenRightParenthesis:
 			t.Next()
 			if node != nil {
 				fn.append(node)
 			}
-			//u.Warnf(" right paren? ")
+			//logging.Warnf(" right paren? ")
 			return
 		case lex.TokenEOF, lex.TokenEOS, lex.TokenFrom:
-			//u.Warnf("return: %v", t.Cur())
+			//logging.Warnf("return: %v", t.Cur())
 			if node != nil {
 				fn.append(node)
 			}
 			return
 		default:
-			//u.Debugf("%v getting node? t.Func()?: %v", depth, firstToken)
+			//logging.Debugf("%v getting node? t.Func()?: %v", depth, firstToken)
 			node = t.O(depth + 1)
 		}
 
 		tok = t.Cur()
-		//u.Infof("%d Func() pt2 consumed token?: %v", depth, tok)
+		//logging.Infof("%d Func() pt2 consumed token?: %v", depth, tok)
 		switch tok.T {
 		case lex.TokenComma:
 			if node != nil {
@@ -590,14 +755,14 @@ func (t *Tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 				fn.append(node)
 			}
 			t.Next()
-			//u.Warnf("found right paren %v", t.Cur())
+			//logging.Warnf("found right paren %v", t.Cur())
 			return
 		case lex.TokenEOF, lex.TokenEOS, lex.TokenFrom, lex.TokenAs:
 			if node != nil {
 				fn.append(node)
 			}
 			t.Next()
-			//u.Debugf("return: %v", t.Cur())
+			//logging.Debugf("return: %v", t.Cur())
 			return
 		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
 			lex.TokenLE, lex.TokenLT, lex.TokenStar, lex.TokenMultiply, lex.TokenDivide:
@@ -605,7 +770,7 @@ func (t *Tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 			//     toint(str_item * 5)
 
 			//t.Backup()
-			//u.Debugf("hmmmmm:  %v  cu=%v", tok, t.Cur())
+			//logging.Debugf("hmmmmm:  %v  cu=%v", tok, t.Cur())
 			node = t.O(depth + 1)
 			if node != nil {
 				fn.append(node)
@@ -616,11 +781,70 @@ func (t *Tree) Func(depth int, funcTok lex.Token) (fn *FuncNode) {
 	}
 }
 
+// Full text search predicate, the term is a string, or single quoted
+//
+//    MATCH(title, body) AGAINST("quick fox")
+//    MATCH(title) AGAINST('fox')
+func (t *Tree) Match(matchTok lex.Token) Node {
+	t.expect(lex.TokenLeftParenthesis, "match")
+	cols := make([]*IdentityNode, 0, 1)
+	for {
+		t.Next()
+		colTok := t.expect(lex.TokenIdentity, "match")
+		cols = append(cols, NewIdentityNode(&colTok))
+		t.Next()
+		if t.Cur().T != lex.TokenComma {
+			break
+		}
+	}
+	t.expect(lex.TokenRightParenthesis, "match")
+	t.Next()
+	switch againstTok := t.Cur(); {
+	case againstTok.T == lex.TokenUdfExpr && strings.EqualFold(againstTok.V, "against"):
+	case againstTok.T == lex.TokenIdentity && againstTok.Quote == 0 && strings.EqualFold(againstTok.V, "against"):
+	default:
+		t.unexpected(againstTok, "match")
+	}
+	t.Next()
+	t.expect(lex.TokenLeftParenthesis, "against")
+	t.Next()
+	termTok := t.Cur()
+	switch {
+	case termTok.T == lex.TokenValue:
+	case termTok.T == lex.TokenIdentity && termTok.Quote == '\'':
+	default:
+		t.unexpected(termTok, "against")
+	}
+	t.Next()
+	t.expect(lex.TokenRightParenthesis, "against")
+	t.Next()
+	return NewMatchNode(Pos(matchTok.Pos), cols, termTok.V)
+}
+
+// the DISTINCT qualifier of aggregate func args, un-quoted
+func isDistinctToken(tok lex.Token) bool {
+	switch tok.T {
+	case lex.TokenDistinct:
+		return true
+	case lex.TokenIdentity:
+		return tok.Quote == 0 && strings.EqualFold(tok.V, "distinct")
+	}
+	return false
+}
+
 // get Function from Global
 func (t *Tree) getFunction(name string) (v Func, ok bool) {
+	return findFunc(name)
+}
+
+// the registered scalar, aggregate or table func of @name
+func findFunc(name string) (v Func, ok bool) {
 	if v, ok = funcs[strings.ToLower(name)]; ok {
 		return
 	}
+	if _, ok = tableFuncs[strings.ToLower(name)]; ok {
+		v = Func{Name: name, Table: true, VariadicArgs: true}
+	}
 	return
 }
 
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
@@ -22,6 +23,113 @@ func ParseSqlVm(sqlQuery string) (SqlStatement, error) {
 	return m.parse()
 }
 
+// ParseSqlDialect parses using a sql dialect other than lex.SqlDialect,
+//  ie lex.AnsiSqlDialect for  ||  as string concatenation
+func ParseSqlDialect(sqlQuery string, dialect *lex.Dialect) (SqlStatement, error) {
+	l := lex.NewLexer(sqlQuery, dialect)
+	m := Sqlbridge{l: l, SqlTokenPager: NewSqlTokenPager(l), buildVm: false}
+	return m.parse()
+}
+
+// ParseMulti parses a script of  ;  separated statements, returning them
+//  in order.   Semi-colons inside quotes or comments do not end a statement.
+//  The error for a statement that fails to parse is a *StatementError
+//  with its position in the script.
+//
+//     stmts, err := expr.ParseMulti(`
+//         SELECT a FROM t1;
+//         DELETE FROM t2 WHERE x = 1;`)
+func ParseMulti(sql string) ([]SqlStatement, error) {
+	stmts := make([]SqlStatement, 0)
+	for i, chunk := range splitStatements(sql) {
+		stmt, err := parseRecover(chunk.text)
+		if err != nil {
+			line := strings.Count(sql[:chunk.pos], "\n") + 1
+			return nil, &StatementError{Index: i, Pos: chunk.pos, Line: line, Err: err}
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// StatementError is the parse error of one statement of a multi-statement
+//  script,  Index is the 0 based statement number, Pos the byte offset of
+//  the statement in the script and Line its 1 based line
+type StatementError struct {
+	Index int
+	Pos   int
+	Line  int
+	Err   error
+}
+
+func (m *StatementError) Error() string {
+	return fmt.Sprintf("statement %d (line %d, pos %d): %v", m.Index+1, m.Line, m.Pos, m.Err)
+}
+
+// the parser may panic on malformed input
+func parseRecover(sql string) (stmt SqlStatement, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return ParseSql(sql)
+}
+
+// --  must be followed by whitespace to be a comment, same as the lexer
+func isLineComment(s string) bool {
+	return strings.HasPrefix(s, "--") && (len(s) == 2 || unicode.IsSpace(rune(s[2])))
+}
+
+type statementText struct {
+	text string
+	pos  int
+}
+
+// split a script on  ;  respecting quotes, and -- /* */ comments, chunks
+//  that are only whitespace or comments are dropped
+func splitStatements(sql string) []statementText {
+	stmts := make([]statementText, 0)
+	start, hasContent := 0, false
+	add := func(end int) {
+		if hasContent {
+			text := sql[start:end]
+			trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+			stmts = append(stmts, statementText{text: strings.TrimSpace(text), pos: start + len(text) - len(trimmed)})
+		}
+		start, hasContent = end+1, false
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			hasContent = true
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\\' && c != '`' {
+					i++
+				} else if sql[i] == c {
+					break
+				}
+			}
+		case c == '-' && isLineComment(sql[i:]), c == '#':
+			for ; i < len(sql) && sql[i] != '\n'; i++ {
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(sql)
+			}
+		case c == ';':
+			add(i)
+		case !unicode.IsSpace(rune(c)):
+			hasContent = true
+		}
+	}
+	add(len(sql))
+	return stmts
+}
+
 // generic SQL parser evaluates should be sufficient for most
 //  sql compatible languages
 type Sqlbridge struct {
@@ -30,6 +138,7 @@ type Sqlbridge struct {
 	comment string
 	*SqlTokenPager
 	firstToken lex.Token
+	subSelects int // depth of FROM (SELECT ...) derived tables being parsed
 }
 
 // parse the request
@@ -41,18 +150,20 @@ func (m *Sqlbridge) parse() (SqlStatement, error) {
 		return m.parsePrepare()
 	case lex.TokenSelect:
 		return m.parseSqlSelect()
+	case lex.TokenWith:
+		return m.parseWith()
 	case lex.TokenInsert:
 		return m.parseSqlInsert()
 	case lex.TokenDelete:
 		return m.parseSqlDelete()
-		// case lex.TokenTypeSqlUpdate:
-		// 	return this.parseSqlUpdate()
+	case lex.TokenUpdate:
+		return m.parseSqlUpdate()
 	case lex.TokenShow:
 		return m.parseShow()
 	case lex.TokenExplain, lex.TokenDescribe, lex.TokenDesc:
 		return m.parseDescribe()
 	}
-	u.Warnf("Could not parse?  %v   peek=%v", m.l.RawInput(), m.l.PeekX(40))
+	logging.Warnf("Could not parse?  %v   peek=%v", m.l.RawInput(), m.l.PeekX(40))
 	return nil, fmt.Errorf("Unrecognized request type: %v", m.l.PeekWord())
 }
 
@@ -76,6 +187,41 @@ func (m *Sqlbridge) initialComment() string {
 	return comment
 }
 
+// ParseHints parses the body of a  /*+ ... */  hint comment into hints,
+//  each is a name with optional parenthesized, comma or space separated args
+//
+//     USE_INDEX(users idx_name) NO_CACHE MAX_TIME(100)
+func ParseHints(text string) []*SqlHint {
+	hints := make([]*SqlHint, 0)
+	isSep := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	for {
+		text = strings.TrimLeftFunc(text, isSep)
+		if text == "" {
+			return hints
+		}
+		nameEnd := strings.IndexFunc(text, func(r rune) bool { return r == '(' || isSep(r) })
+		if nameEnd < 0 {
+			nameEnd = len(text)
+		}
+		hint := &SqlHint{Name: strings.ToUpper(text[:nameEnd])}
+		text = strings.TrimLeftFunc(text[nameEnd:], unicode.IsSpace)
+		if strings.HasPrefix(text, "(") {
+			argEnd := strings.IndexByte(text, ')')
+			if argEnd < 0 {
+				argEnd = len(text)
+			}
+			hint.Args = strings.FieldsFunc(text[1:argEnd], isSep)
+			if argEnd < len(text) {
+				argEnd++
+			}
+			text = text[argEnd:]
+		}
+		if hint.Name != "" {
+			hints = append(hints, hint)
+		}
+	}
+}
+
 // First keyword was SELECT, so use the SELECT parser rule-set
 func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 
@@ -83,18 +229,29 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 	req.Raw = m.l.RawInput()
 	m.Next() // Consume Select?
 
+	// the lexer has skipped over any  /*+ hint */  comments after SELECT
+	for _, hintText := range m.l.Hints() {
+		req.Hints = append(req.Hints, ParseHints(hintText)...)
+	}
+
+	if m.Cur().T == lex.TokenDistinct {
+		if err := m.parseDistinctOn(req); err != nil {
+			return nil, err
+		}
+	}
+
 	// columns
 	if m.Cur().T != lex.TokenStar {
 		if err := m.parseColumns(req); err != nil {
-			u.Debug(err)
+			logging.Debug(err)
 			return nil, err
 		}
 	} else if err := m.parseSelectStar(req); err != nil {
-		u.Debug(err)
+		logging.Debug(err)
 		return nil, err
 	}
 
-	//u.Debugf("cur? %v", m.Cur())
+	//logging.Debugf("cur? %v", m.Cur())
 	// select @@myvar limit 1
 	if m.Cur().T == lex.TokenLimit {
 		if err := m.parseLimit(req); err != nil {
@@ -113,37 +270,42 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 	}
 
 	// INTO
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if errreq := m.parseInto(req); errreq != nil {
 		return nil, errreq
 	}
 
 	// FROM
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if errreq := m.parseTableReference(req); errreq != nil {
 		return nil, errreq
 	}
 
 	// WHERE
-	//u.Debugf("where? %v", m.Cur())
+	//logging.Debugf("where? %v", m.Cur())
 	if errreq := m.parseWhere(req); errreq != nil {
 		return nil, errreq
 	}
 
 	// GROUP BY
-	//u.Debugf("GroupBy?  : %v", m.Cur())
+	//logging.Debugf("GroupBy?  : %v", m.Cur())
 	if errreq := m.parseGroupBy(req); errreq != nil {
 		return nil, errreq
 	}
 
 	// HAVING
-	//u.Debugf("Having?  : %v", m.Cur())
+	//logging.Debugf("Having?  : %v", m.Cur())
 	if errreq := m.parseHaving(req); errreq != nil {
 		return nil, errreq
 	}
 
+	// UNION, EXCEPT, INTERSECT
+	if errreq := m.parseSetOps(req); errreq != nil {
+		return nil, errreq
+	}
+
 	// ORDER BY
-	//u.Debugf("OrderBy?  : %v", m.Cur())
+	//logging.Debugf("OrderBy?  : %v", m.Cur())
 	if errreq := m.parseOrderBy(req); errreq != nil {
 		return nil, errreq
 	}
@@ -155,7 +317,7 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 	if m.Cur().T == lex.TokenEOF || m.Cur().T == lex.TokenEOS || m.Cur().T == lex.TokenRightParenthesis {
 
 		if err := req.Finalize(); err != nil {
-			u.Errorf("Could not finalize: %v", err)
+			logging.Errorf("Could not finalize: %v", err)
 			return nil, err
 		}
 
@@ -163,10 +325,109 @@ func (m *Sqlbridge) parseSqlSelect() (*SqlSelect, error) {
 		return req, nil
 	}
 
-	u.Warnf("Could not complete parsing, return error: %v %v", m.Cur(), m.l.PeekWord())
+	logging.Warnf("Could not complete parsing, return error: %v %v", m.Cur(), m.l.PeekWord())
 	return nil, fmt.Errorf("Did not complete parsing input: %v", m.LexTokenPager.Cur().V)
 }
 
+// UNION, EXCEPT or INTERSECT [ALL|DISTINCT] and the right select, which
+//  may have its own set operations.  Set operations are left associative
+//  so those are flattened onto @req, as are the ORDER BY and LIMIT after
+//  the last select which are of the whole statement
+//
+//     SELECT a FROM x EXCEPT SELECT a FROM y UNION ALL SELECT a FROM z ORDER BY a
+func (m *Sqlbridge) parseSetOps(req *SqlSelect) error {
+
+	switch m.Cur().T {
+	case lex.TokenUnion, lex.TokenExcept, lex.TokenIntersect:
+	default:
+		return nil
+	}
+	op := &SqlSetOp{Op: m.Cur().T}
+	m.Next()
+	switch m.Cur().T {
+	case lex.TokenAll:
+		op.All = true
+		m.Next()
+	case lex.TokenDistinct:
+		m.Next()
+	}
+	if m.Cur().T != lex.TokenSelect {
+		return fmt.Errorf("expected SELECT after %s but got: %v", op.Op, m.Cur())
+	}
+	right, err := m.parseSqlSelect()
+	if err != nil {
+		return err
+	}
+	op.Right = right
+	// INTERSECT binds tighter than UNION and EXCEPT, the INTERSECTs
+	//  following the right select of a UNION or EXCEPT stay on it
+	rest := right.SetOps
+	if op.Op != lex.TokenIntersect {
+		n := 0
+		for n < len(rest) && rest[n].Op == lex.TokenIntersect {
+			n++
+		}
+		right.SetOps, rest = rest[:n:n], rest[n:]
+	}
+	req.SetOps = append(append(req.SetOps, op), rest...)
+	req.OrderBy, req.Limit, req.Offset = right.OrderBy, right.Limit, right.Offset
+	if op.Op == lex.TokenIntersect || len(right.SetOps) == 0 {
+		right.SetOps = nil
+	}
+	right.OrderBy, right.Limit, right.Offset = nil, 0, 0
+	return nil
+}
+
+// First keyword was WITH, common table expressions then the select using them
+//
+//     WITH cte AS (SELECT ...) [, cte2 AS (SELECT ...)]* SELECT ...
+func (m *Sqlbridge) parseWith() (*SqlSelect, error) {
+
+	ctes := make([]*SqlSource, 0)
+	m.Next() // Consume With
+	for {
+		if m.Cur().T != lex.TokenIdentity {
+			return nil, fmt.Errorf("expected cte name but got: %v", m.Cur())
+		}
+		cte := &SqlSource{Pos: Pos(m.Cur().Pos), Alias: m.Cur().V}
+		m.Next()
+		if m.Cur().T != lex.TokenAs {
+			return nil, fmt.Errorf("expected AS but got: %v", m.Cur())
+		}
+		m.Next()
+		if m.Cur().T != lex.TokenLeftParenthesis {
+			return nil, fmt.Errorf("expected left paren but got: %v", m.Cur())
+		}
+		m.Next()
+		m.subSelects++
+		sel, err := m.parseSqlSelect()
+		m.subSelects--
+		if err != nil {
+			return nil, err
+		}
+		if m.Cur().T != lex.TokenRightParenthesis {
+			return nil, fmt.Errorf("expected right paren but got: %v", m.Cur())
+		}
+		m.Next() // discard right paren
+		cte.Source = sel
+		ctes = append(ctes, cte)
+		if m.Cur().T != lex.TokenComma {
+			break
+		}
+		m.Next()
+	}
+
+	if m.Cur().T != lex.TokenSelect {
+		return nil, fmt.Errorf("expected SELECT after WITH but got: %v", m.Cur())
+	}
+	req, err := m.parseSqlSelect()
+	if err != nil {
+		return nil, err
+	}
+	req.With = ctes
+	return req, nil
+}
+
 // First keyword was INSERT
 func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 
@@ -175,13 +436,13 @@ func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 	m.Next() // Consume Insert
 
 	// into
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if m.Cur().T != lex.TokenInto {
 		return nil, fmt.Errorf("expected INTO but got: %v", m.Cur())
 	} else {
 		// table name
 		m.Next()
-		//u.Debugf("found into?  %v", m.Cur())
+		//logging.Debugf("found into?  %v", m.Cur())
 		switch m.Cur().T {
 		case lex.TokenTable:
 			req.Into = m.Cur().V
@@ -193,26 +454,167 @@ func (m *Sqlbridge) parseSqlInsert() (*SqlInsert, error) {
 	// list of fields
 	m.Next()
 	if err := m.parseFieldList(req); err != nil {
-		u.Error(err)
+		logging.Error(err)
 		return nil, err
 	}
 	m.Next()
-	//u.Debugf("found ?  %v", m.Cur())
+	//logging.Debugf("found ?  %v", m.Cur())
 	switch m.Cur().T {
 	case lex.TokenValues:
 		m.Next()
 	default:
 		return nil, fmt.Errorf("expected values but got : %v", m.Cur().V)
 	}
-	//u.Debugf("found ?  %v", m.Cur())
+	//logging.Debugf("found ?  %v", m.Cur())
 	if err := m.parseValueList(req); err != nil {
-		u.Error(err)
+		logging.Error(err)
+		return nil, err
+	}
+	if err := m.parseOnConflict(req); err != nil {
+		return nil, err
+	}
+	returning, err := m.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+	req.Returning = returning
+	if err := req.Finalize(); err != nil {
 		return nil, err
 	}
 	// we are good
 	return req, nil
 }
 
+// ON CONFLICT [(key)] DO NOTHING of insert, the lexer gives us identities
+func (m *Sqlbridge) parseOnConflict(req *SqlInsert) error {
+	if m.Cur().T != lex.TokenIdentity || !strings.EqualFold(m.Cur().V, "on") {
+		return nil
+	}
+	m.Next()
+	if !strings.EqualFold(m.Cur().V, "conflict") {
+		return fmt.Errorf("expected CONFLICT but got: %v", m.Cur())
+	}
+	m.Next()
+	if m.Cur().T == lex.TokenLeftParenthesis {
+		m.Next()
+		if m.Cur().T != lex.TokenIdentity {
+			return fmt.Errorf("expected conflict column but got: %v", m.Cur())
+		}
+		req.ConflictKey = m.Cur().V
+		m.Next()
+		if m.Cur().T != lex.TokenRightParenthesis {
+			return fmt.Errorf("expected ) but got: %v", m.Cur())
+		}
+		m.Next()
+	}
+	if !strings.EqualFold(m.Cur().V, "do") {
+		return fmt.Errorf("expected DO but got: %v", m.Cur())
+	}
+	m.Next()
+	if !strings.EqualFold(m.Cur().V, "nothing") {
+		return fmt.Errorf("only ON CONFLICT DO NOTHING is supported, got: %v", m.Cur())
+	}
+	m.Next()
+	req.IgnoreConflicts = true
+	return nil
+}
+
+// First keyword was UPDATE
+//
+//     UPDATE mytable SET str = "b", ct = ct + 1 WHERE id = 0
+func (m *Sqlbridge) parseSqlUpdate() (*SqlUpdate, error) {
+
+	req := NewSqlUpdate()
+	m.Next() // Consume Update
+
+	if m.Cur().T != lex.TokenTable {
+		return nil, fmt.Errorf("expected table name but got : %v", m.Cur().V)
+	}
+	req.From = m.Cur().V
+	m.Next()
+
+	if m.Cur().T != lex.TokenSet {
+		return nil, fmt.Errorf("expected SET but got: %v", m.Cur())
+	}
+	m.Next()
+	if err := m.parseSetList(req); err != nil {
+		return nil, err
+	}
+
+	if m.Cur().T == lex.TokenFrom {
+		// UPDATE a SET x = b.y FROM b WHERE a.id = b.id
+		src := &SqlSource{Pos: Pos(m.Cur().Pos)}
+		m.Next()
+		if m.Cur().T != lex.TokenIdentity {
+			return nil, fmt.Errorf("expected from name but got: %v", m.Cur())
+		}
+		src.Name = m.Cur().V
+		m.Next()
+		if m.Cur().T == lex.TokenAs {
+			m.Next()
+			src.Alias = m.Cur().V
+			m.Next()
+		}
+		req.Source = src
+	}
+
+	if m.Cur().T == lex.TokenWhere {
+		m.Next()
+		tree := NewTree(m.SqlTokenPager)
+		m.parseNode(tree)
+		req.Where = tree.Root
+	}
+	returning, err := m.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+	req.Returning = returning
+	if err := req.Finalize(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// the  col = expr [, col = expr]  of update set
+func (m *Sqlbridge) parseSetList(req *SqlUpdate) error {
+	for {
+		if m.Cur().T != lex.TokenIdentity {
+			return fmt.Errorf("expected column but got: %v", m.Cur())
+		}
+		col := NewColumn(m.Cur())
+		m.Next()
+		if m.Cur().T != lex.TokenEqual {
+			return fmt.Errorf("expected = but got: %v", m.Cur())
+		}
+		m.Next()
+		tree := NewTree(m.SqlTokenPager)
+		m.parseNode(tree)
+		if tree.Root == nil {
+			return fmt.Errorf("expected value for %s", col.As)
+		}
+		col.Expr = tree.Root
+		col.Index = len(req.Columns)
+		req.Columns = append(req.Columns, col)
+		if m.Cur().T != lex.TokenComma {
+			return nil
+		}
+		m.Next()
+	}
+}
+
+// RETURNING col [, col] of insert, update and delete
+func (m *Sqlbridge) parseReturning() (Columns, error) {
+	if m.Cur().T != lex.TokenReturning {
+		return nil, nil
+	}
+	m.Next()
+	sel := NewSqlSelect()
+	if err := m.parseColumns(sel); err != nil {
+		return nil, err
+	}
+	return sel.Columns, nil
+}
+
 // First keyword was DELETE
 func (m *Sqlbridge) parseSqlDelete() (*SqlDelete, error) {
 
@@ -220,13 +622,13 @@ func (m *Sqlbridge) parseSqlDelete() (*SqlDelete, error) {
 	m.Next() // Consume Delete
 
 	// from
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if m.Cur().T != lex.TokenFrom {
 		return nil, fmt.Errorf("expected FROM but got: %v", m.Cur())
 	} else {
 		// table name
 		m.Next()
-		//u.Debugf("found table?  %v", m.Cur())
+		//logging.Debugf("found table?  %v", m.Cur())
 		switch m.Cur().T {
 		case lex.TokenTable:
 			req.Table = m.Cur().V
@@ -236,10 +638,18 @@ func (m *Sqlbridge) parseSqlDelete() (*SqlDelete, error) {
 	}
 
 	m.Next()
-	//u.Debugf("cur lex.Token: %s", m.Cur().T.String())
+	//logging.Debugf("cur lex.Token: %s", m.Cur().T.String())
 	if errreq := m.parseWhereDelete(req); errreq != nil {
 		return nil, errreq
 	}
+	returning, err := m.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+	req.Returning = returning
+	if err := req.Finalize(); err != nil {
+		return nil, err
+	}
 	// we are good
 	return req, nil
 }
@@ -251,7 +661,7 @@ func (m *Sqlbridge) parsePrepare() (*PreparedStatement, error) {
 	m.Next() // Consume Prepare
 
 	// statement name/alias
-	//u.Debugf("found table?  %v", m.Cur())
+	//logging.Debugf("found table?  %v", m.Cur())
 	switch m.Cur().T {
 	case lex.TokenTable, lex.TokenIdentity:
 		req.Alias = m.Cur().V
@@ -261,7 +671,7 @@ func (m *Sqlbridge) parsePrepare() (*PreparedStatement, error) {
 
 	// from
 	m.Next()
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if m.Cur().T != lex.TokenFrom {
 		return nil, fmt.Errorf("expected FROM but got: %v", m.Cur())
 	}
@@ -286,7 +696,7 @@ func (m *Sqlbridge) parseDescribe() (SqlStatement, error) {
 	req.Tok = m.Cur()
 	m.Next() // Consume Describe
 
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	switch nextWord := strings.ToLower(m.Cur().V); nextWord {
 	case "select":
 		// TODO:  make the lexer handle this
@@ -324,7 +734,7 @@ func (m *Sqlbridge) parseShow() (*SqlShow, error) {
 	req := &SqlShow{}
 	m.Next() // Consume Show
 
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if m.Cur().T != lex.TokenIdentity {
 		return nil, fmt.Errorf("expected idenity but got: %v", m.Cur())
 	}
@@ -338,11 +748,11 @@ func (m *Sqlbridge) parseColumns(stmt *SqlSelect) error {
 
 	for {
 
-		//u.Debug(m.Cur())
+		//logging.Debug(m.Cur())
 		switch m.Cur().T {
 		case lex.TokenUdfExpr:
 			// we have a udf/functional expression column
-			//u.Infof("udf: %v", m.Cur().V)
+			//logging.Infof("udf: %v", m.Cur().V)
 			//col = &Column{As: m.Cur().V, Tree: NewTree(m.SqlTokenPager)}
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
@@ -358,30 +768,30 @@ func (m *Sqlbridge) parseColumns(stmt *SqlSelect) error {
 						col.As = n.Name
 					}
 				case *BinaryNode:
-					//u.Debugf("udf? %T ", col.Expr)
+					//logging.Debugf("udf? %T ", col.Expr)
 					col.As = FindIdentityName(0, n, "")
 					if col.As == "" {
-						u.Errorf("could not find as name: %#v", n)
+						logging.Errorf("could not find as name: %#v", n)
 					}
 
 				}
 			}
-			//u.Debugf("next? %v", m.Cur())
+			//logging.Debugf("next? %v", m.Cur())
 
 		case lex.TokenIdentity:
-			//u.Warnf("?? %v", m.Cur())
+			//logging.Warnf("?? %v", m.Cur())
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Expr = tree.Root
-		case lex.TokenValue:
-			// Value Literal
+		case lex.TokenValue, lex.TokenInteger, lex.TokenFloat:
+			// Value Literal, or arithmetic of literals   SELECT 1 + 1
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Expr = tree.Root
 		}
-		//u.Debugf("after colstart?:   %v  ", m.Cur())
+		//logging.Debugf("after colstart?:   %v  ", m.Cur())
 
 		// since we can loop inside switch statement
 		switch m.Cur().T {
@@ -395,20 +805,26 @@ func (m *Sqlbridge) parseColumns(stmt *SqlSelect) error {
 				continue
 			}
 			return fmt.Errorf("expected identity but got: %v", m.Cur().String())
+		case lex.TokenOver:
+			//  avg(x) OVER (ROWS 5 PRECEDING)
+			if err := m.parseOver(col); err != nil {
+				return err
+			}
+			continue
 		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
 			// This indicates we have come to the End of the columns
 			stmt.AddColumn(*col)
-			//u.Debugf("Ending column ")
+			//logging.Debugf("Ending column ")
 			return nil
 		case lex.TokenIf:
 			// If guard
 			m.Next()
-			//u.Infof("if guard: %v", m.Cur())
+			//logging.Infof("if guard: %v", m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Guard = tree.Root
-			//u.Infof("if guard 2: %v", m.Cur())
-			//u.Debugf("after if guard?:   %v  ", m.Cur())
+			//logging.Infof("if guard 2: %v", m.Cur())
+			//logging.Debugf("after if guard?:   %v  ", m.Cur())
 		case lex.TokenCommentSingleLine:
 			m.Next()
 			col.Comment = m.Cur().V
@@ -416,16 +832,152 @@ func (m *Sqlbridge) parseColumns(stmt *SqlSelect) error {
 			// loop on my friend
 		case lex.TokenComma:
 			stmt.AddColumn(*col)
-			//u.Debugf("comma, added cols:  %v", len(stmt.Columns))
+			//logging.Debugf("comma, added cols:  %v", len(stmt.Columns))
 		default:
 			return fmt.Errorf("expected column but got: %v", m.Cur().String())
 		}
 		m.Next()
 	}
-	//u.Debugf("cols: %d", len(stmt.Columns))
+	//logging.Debugf("cols: %d", len(stmt.Columns))
 	return nil
 }
 
+// the window of a window function column
+//
+//     OVER ( [ROWS (n | UNBOUNDED) PRECEDING]
+//            [ROWS BETWEEN (n | UNBOUNDED) PRECEDING AND CURRENT ROW] )
+func (m *Sqlbridge) parseOver(col *Column) error {
+	if _, ok := col.Expr.(*FuncNode); !ok {
+		return fmt.Errorf("OVER requires a window function but got: %v", col.Expr)
+	}
+	m.Next() // Consume OVER
+	if m.Cur().T != lex.TokenLeftParenthesis {
+		return fmt.Errorf("expected ( after OVER but got: %v", m.Cur())
+	}
+	m.Next()
+	col.Over = NewWindow()
+	for {
+		switch m.Cur().T {
+		case lex.TokenRightParenthesis:
+			m.Next()
+			return nil
+		case lex.TokenPartitionBy:
+			cols, err := m.parseWindowColumns()
+			if err != nil {
+				return err
+			}
+			col.Over.PartitionBy = cols
+		case lex.TokenOrderBy:
+			cols, err := m.parseWindowColumns()
+			if err != nil {
+				return err
+			}
+			col.Over.OrderBy = cols
+		case lex.TokenRows:
+			m.Next()
+			between := m.Cur().T == lex.TokenBetween
+			if between {
+				m.Next()
+			}
+			switch m.Cur().T {
+			case lex.TokenInteger:
+				n, err := strconv.Atoi(m.Cur().V)
+				if err != nil {
+					return err
+				}
+				col.Over.Preceding = n
+			case lex.TokenUnbounded:
+				col.Over.Preceding = -1
+			default:
+				return fmt.Errorf("expected rows preceding but got: %v", m.Cur())
+			}
+			m.Next()
+			if m.Cur().T != lex.TokenPreceding {
+				return fmt.Errorf("expected PRECEDING but got: %v", m.Cur())
+			}
+			m.Next()
+			if between {
+				if m.Cur().T != lex.TokenLogicAnd {
+					return fmt.Errorf("expected AND CURRENT ROW but got: %v", m.Cur())
+				}
+				m.Next()
+				if m.Cur().T != lex.TokenCurrentRow {
+					return fmt.Errorf("expected CURRENT ROW but got: %v", m.Cur())
+				}
+				m.Next()
+			}
+		default:
+			return fmt.Errorf("unexpected in OVER: %v", m.Cur())
+		}
+	}
+}
+
+// the comma delimited columns of a window PARTITION BY, ORDER BY
+//
+//     PARTITION BY g, h
+//     ORDER BY score DESC, name
+func (m *Sqlbridge) parseWindowColumns() (Columns, error) {
+	cols := make(Columns, 0)
+	for {
+		m.Next() // Consume PARTITION BY, ORDER BY or comma
+		if m.Cur().T != lex.TokenIdentity {
+			return nil, fmt.Errorf("expected window column but got: %v", m.Cur())
+		}
+		col := NewColumn(m.Cur())
+		tree := NewTree(m.SqlTokenPager)
+		if err := m.parseNode(tree); err != nil {
+			return nil, err
+		}
+		col.Expr = tree.Root
+		switch m.Cur().T {
+		case lex.TokenAsc, lex.TokenDesc:
+			col.Order = strings.ToUpper(m.Cur().V)
+			m.Next()
+		}
+		cols = append(cols, col)
+		if m.Cur().T != lex.TokenComma {
+			return cols, nil
+		}
+	}
+}
+
+// the columns of a DISTINCT ON, plain DISTINCT of the select columns is
+//  not supported
+//
+//     SELECT DISTINCT ON (user_id, day) ...
+func (m *Sqlbridge) parseDistinctOn(req *SqlSelect) error {
+	m.Next() // Consume DISTINCT
+	if m.Cur().T != lex.TokenOn {
+		return fmt.Errorf("SELECT DISTINCT not supported, expected DISTINCT ON but got: %v", m.Cur())
+	}
+	m.Next() // Consume ON
+	if m.Cur().T != lex.TokenLeftParenthesis {
+		return fmt.Errorf("expected ( after DISTINCT ON but got: %v", m.Cur())
+	}
+	req.DistinctOn = make(Columns, 0)
+	for {
+		m.Next() // Consume ( or comma
+		if m.Cur().T != lex.TokenIdentity {
+			return fmt.Errorf("expected DISTINCT ON column but got: %v", m.Cur())
+		}
+		col := NewColumn(m.Cur())
+		tree := NewTree(m.SqlTokenPager)
+		if err := m.parseNode(tree); err != nil {
+			return err
+		}
+		col.Expr = tree.Root
+		req.DistinctOn = append(req.DistinctOn, col)
+		switch m.Cur().T {
+		case lex.TokenComma:
+		case lex.TokenRightParenthesis:
+			m.Next()
+			return nil
+		default:
+			return fmt.Errorf("expected , or ) in DISTINCT ON but got: %v", m.Cur())
+		}
+	}
+}
+
 func (m *Sqlbridge) parseFieldList(stmt *SqlInsert) error {
 
 	var col *Column
@@ -436,7 +988,7 @@ func (m *Sqlbridge) parseFieldList(stmt *SqlInsert) error {
 
 	for {
 
-		//u.Debug(m.Cur().String())
+		//logging.Debug(m.Cur().String())
 		switch m.Cur().T {
 		// case lex.TokenUdfExpr:
 		// 	// we have a udf/functional expression column
@@ -446,7 +998,7 @@ func (m *Sqlbridge) parseFieldList(stmt *SqlInsert) error {
 			col = NewColumn(m.Cur())
 			m.Next()
 		}
-		//u.Debugf("after colstart?:   %v  ", m.Cur())
+		//logging.Debugf("after colstart?:   %v  ", m.Cur())
 
 		// since we can loop inside switch statement
 		switch m.Cur().T {
@@ -454,17 +1006,17 @@ func (m *Sqlbridge) parseFieldList(stmt *SqlInsert) error {
 			lex.TokenRightParenthesis:
 			// This indicates we have come to the End of the columns
 			stmt.Columns = append(stmt.Columns, col)
-			//u.Debugf("Ending column ")
+			//logging.Debugf("Ending column ")
 			return nil
 		case lex.TokenComma:
 			stmt.Columns = append(stmt.Columns, col)
-			//u.Debugf("comma, added cols:  %v", len(stmt.Columns))
+			//logging.Debugf("comma, added cols:  %v", len(stmt.Columns))
 		default:
 			return fmt.Errorf("expected column but got: %v", m.Cur().String())
 		}
 		m.Next()
 	}
-	//u.Debugf("cols: %d", len(stmt.Columns))
+	//logging.Debugf("cols: %d", len(stmt.Columns))
 	return nil
 }
 
@@ -478,38 +1030,50 @@ func (m *Sqlbridge) parseValueList(stmt *SqlInsert) error {
 	var row []value.Value
 	for {
 
-		//u.Debug(m.Cur().String())
+		//logging.Debug(m.Cur().String())
 		switch m.Cur().T {
 		case lex.TokenLeftParenthesis:
 			// start of row
 			row = make([]value.Value, 0)
 		case lex.TokenRightParenthesis:
 			stmt.Rows = append(stmt.Rows, row)
-		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
+		case lex.TokenFrom, lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF, lex.TokenReturning:
 			// This indicates we have come to the End of the values
-			//u.Debugf("Ending %v ", m.Cur())
+			//logging.Debugf("Ending %v ", m.Cur())
 			return nil
 		case lex.TokenValue:
 			row = append(row, value.NewStringValue(m.Cur().V))
 		case lex.TokenInteger:
 			iv, _ := strconv.ParseInt(m.Cur().V, 10, 64)
 			row = append(row, value.NewIntValue(iv))
+		case lex.TokenParam:
+			row = append(row, ParamValue{Param: NewParamNode(Pos(m.Cur().Pos))})
+		case lex.TokenIdentity:
+			switch strings.ToLower(m.Cur().V) {
+			case "default":
+				row = append(row, DefaultValue{})
+			case "on":
+				// ON CONFLICT
+				return nil
+			default:
+				return fmt.Errorf("expected value but got: %v", m.Cur().String())
+			}
 		case lex.TokenComma:
 			//row = append(row, col)
-			//u.Debugf("comma, added cols:  %v", len(stmt.Columns))
+			//logging.Debugf("comma, added cols:  %v", len(stmt.Columns))
 		default:
-			u.Warnf("don't know how to handle ?  %v", m.Cur())
+			logging.Warnf("don't know how to handle ?  %v", m.Cur())
 			return fmt.Errorf("expected column but got: %v", m.Cur().String())
 		}
 		m.Next()
 	}
-	//u.Debugf("cols: %d", len(stmt.Columns))
+	//logging.Debugf("cols: %d", len(stmt.Columns))
 	return nil
 }
 
 func (m *Sqlbridge) parseTableReference(req *SqlSelect) error {
 
-	//u.Debugf("parseTableReference cur %v", m.Cur())
+	//logging.Debugf("parseTableReference cur %v", m.Cur())
 
 	if m.Cur().T != lex.TokenFrom {
 		return fmt.Errorf("expected From but got: %v", m.Cur())
@@ -519,12 +1083,14 @@ func (m *Sqlbridge) parseTableReference(req *SqlSelect) error {
 	req.From = append(req.From, &src)
 
 	m.Next() // page forward off of From
-	//u.Debugf("found from?  %v", m.Cur())
+	//logging.Debugf("found from?  %v", m.Cur())
 
 	if m.Cur().T == lex.TokenLeftParenthesis {
 		// SELECT * FROM (SELECT 1, 2, 3) AS t1;
 		m.Next()
+		m.subSelects++
 		subQuery, err := m.parseSqlSelect()
+		m.subSelects--
 		if err != nil {
 			return err
 		}
@@ -538,32 +1104,59 @@ func (m *Sqlbridge) parseTableReference(req *SqlSelect) error {
 			src.Alias = m.Cur().V
 			m.Next()
 		}
-		u.Infof("found from subquery: %v", src)
+		logging.Infof("found from subquery: %v", src)
 		return nil
+	} else if m.Cur().T == lex.TokenUdfExpr {
+		// SELECT t FROM unnest(tags) AS t
+		if err := m.parseTableFunc(&src); err != nil {
+			return err
+		}
 	} else if m.Cur().T != lex.TokenIdentity && m.Cur().T != lex.TokenValue {
-		u.Warnf("No From? %v ", m.Cur())
+		logging.Warnf("No From? %v ", m.Cur())
 		return fmt.Errorf("expected from name but got: %v", m.Cur())
 	} else {
 		src.Name = m.Cur().V
 		m.Next()
 		// Since we found name, we can alias but not join?
-		//u.Debugf("found name: %v", src.Name)
+		//logging.Debugf("found name: %v", src.Name)
 	}
 
 	if m.Cur().T == lex.TokenAs {
 		m.Next() // Skip over As, we don't need it
 		src.Alias = m.Cur().V
 		m.Next()
-		//u.Debugf("found table alias: %v AS %v", src.Name, src.Alias)
+		//logging.Debugf("found table alias: %v AS %v", src.Name, src.Alias)
 		// select u.name, order.date FROM user AS u INNER JOIN ....
 	}
 
+	if m.Cur().T == lex.TokenComma {
+		// lateral table func or subquery, evaluated per row of the first source
+		//   FROM users, unnest(tags) AS t
+		//   FROM users AS u, LATERAL (SELECT ... WHERE user_id = u.user_id) AS o
+		m.Next()
+		lateral := SqlSource{Pos: Pos(m.Cur().Pos)}
+		switch m.Cur().T {
+		case lex.TokenUdfExpr:
+			if err := m.parseTableFunc(&lateral); err != nil {
+				return err
+			}
+		case lex.TokenLateral:
+			if err := m.parseLateral(&lateral); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("expected table func or lateral after comma in FROM but got: %v", m.Cur())
+		}
+		req.From = append(req.From, &lateral)
+		return nil
+	}
+
 	switch m.Cur().T {
 	case lex.TokenLeft, lex.TokenRight, lex.TokenInner, lex.TokenOuter, lex.TokenJoin:
 		// ok, continue
 	default:
 		// done, lets bail
-		//u.Debugf("done w table refs")
+		//logging.Debugf("done w table refs")
 		return nil
 	}
 
@@ -572,49 +1165,97 @@ func (m *Sqlbridge) parseTableReference(req *SqlSelect) error {
 
 	switch m.Cur().T {
 	case lex.TokenLeft, lex.TokenRight:
-		//u.Debugf("left/right join: %v", m.Cur())
+		//logging.Debugf("left/right join: %v", m.Cur())
 		joinSrc.LeftOrRight = m.Cur().T
 		m.Next()
 	}
 
 	switch m.Cur().T {
 	case lex.TokenInner, lex.TokenOuter:
-		//u.Debugf("inner/outer join: %v", m.Cur())
+		//logging.Debugf("inner/outer join: %v", m.Cur())
 		joinSrc.JoinType = m.Cur().T
 		m.Next()
 	}
-	//u.Debugf("cur: %v", m.Cur())
+	//logging.Debugf("cur: %v", m.Cur())
 	if m.Cur().T == lex.TokenJoin {
 		m.Next() // Skip over join, we don't need it
 	}
-	//u.Debugf("cur: %v", m.Cur())
+	//logging.Debugf("cur: %v", m.Cur())
 	// think its possible to have join sub-query/anonymous table here?
 	// ie   select ... FROM x JOIN (select a,b,c FROM mytable) AS y ON x.a = y.a
 	if m.Cur().T != lex.TokenIdentity && m.Cur().T != lex.TokenValue {
-		u.Warnf("No join name? %v ", m.Cur())
+		logging.Warnf("No join name? %v ", m.Cur())
 		return fmt.Errorf("expected from name but got: %v", m.Cur())
 	}
 	joinSrc.Name = m.Cur().V
 	m.Next()
-	//u.Debugf("found join name: %v", joinSrc.Name)
+	//logging.Debugf("found join name: %v", joinSrc.Name)
 
 	if m.Cur().T == lex.TokenAs {
 		m.Next() // Skip over As, we don't need it
 		joinSrc.Alias = m.Cur().V
 		m.Next()
-		//u.Debugf("found table alias: %v AS %v", joinSrc.Name, joinSrc.Alias)
+		//logging.Debugf("found table alias: %v AS %v", joinSrc.Name, joinSrc.Alias)
 		// select u.name, order.date FROM user AS u INNER JOIN ....
 	}
 
-	//u.Debugf("cur: %v", m.Cur())
+	//logging.Debugf("cur: %v", m.Cur())
 	if m.Cur().T == lex.TokenOn {
 		joinSrc.Op = m.Cur().T
 		m.Next()
 		tree := NewTree(m.SqlTokenPager)
 		m.parseNode(tree)
 		joinSrc.JoinExpr = tree.Root
-		//u.Debugf("got join ON: ast=%v", tree.Root.StringAST())
-		//u.Debugf("join:  %#v", joinSrc)
+		//logging.Debugf("got join ON: ast=%v", tree.Root.StringAST())
+		//logging.Debugf("join:  %#v", joinSrc)
+	}
+	return nil
+}
+
+// a table valued func FROM entry, and its alias
+func (m *Sqlbridge) parseTableFunc(src *SqlSource) error {
+	tree := NewTree(m.SqlTokenPager)
+	if err := m.parseNode(tree); err != nil {
+		return err
+	}
+	fn, ok := tree.Root.(*FuncNode)
+	if !ok {
+		return fmt.Errorf("expected table func but got: %v", tree.Root)
+	}
+	if m.buildVm && !fn.F.Table {
+		return fmt.Errorf("%s is not a table func", fn.Name)
+	}
+	src.Func = fn
+	if m.Cur().T == lex.TokenAs {
+		m.Next()
+		src.Alias = m.Cur().V
+		m.Next()
+	}
+	return nil
+}
+
+func (m *Sqlbridge) parseLateral(src *SqlSource) error {
+	m.Next() // Consume Lateral
+	if m.Cur().T != lex.TokenLeftParenthesis {
+		return fmt.Errorf("expected left paren after lateral but got: %v", m.Cur())
+	}
+	m.Next()
+	m.subSelects++
+	subQuery, err := m.parseSqlSelect()
+	m.subSelects--
+	if err != nil {
+		return err
+	}
+	if m.Cur().T != lex.TokenRightParenthesis {
+		return fmt.Errorf("expected right paren but got: %v", m.Cur())
+	}
+	m.Next() // discard right paren
+	src.Source = subQuery
+	src.Lateral = true
+	if m.Cur().T == lex.TokenAs {
+		m.Next()
+		src.Alias = m.Cur().V
+		m.Next()
 	}
 	return nil
 }
@@ -626,7 +1267,7 @@ func (m *Sqlbridge) parseInto(req *SqlSelect) error {
 	}
 	m.Next() // Consume Into token
 
-	//u.Debugf("token:  %v", m.Cur())
+	//logging.Debugf("token:  %v", m.Cur())
 	if m.Cur().T != lex.TokenTable {
 		return fmt.Errorf("expected table but got: %v", m.Cur())
 	}
@@ -637,10 +1278,10 @@ func (m *Sqlbridge) parseInto(req *SqlSelect) error {
 
 // Parse an expression tree or root Node
 func (m *Sqlbridge) parseNode(tree *Tree) error {
-	//u.Debugf("cur token parse: token=%v", m.Cur())
+	//logging.Debugf("cur token parse: token=%v", m.Cur())
 	err := tree.BuildTree(m.buildVm)
 	if err != nil {
-		u.Errorf("error: %v", err)
+		logging.Errorf("error: %v", err)
 	}
 	return err
 }
@@ -663,70 +1304,24 @@ func (m *Sqlbridge) parseWhere(req *SqlSelect) (err error) {
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			u.Errorf("where error? %v \n %v", r, m.Cur())
-			if m.Cur().T == lex.TokenSelect {
-				// TODO this is deeply flawed, need to fix/use tokenpager
-				// with rewind ability
-				err = m.parseWhereSelect(req)
-				return
-			}
+			logging.Errorf("where error? %v \n %v", r, m.Cur())
 			err = fmt.Errorf("panic err: %v", r)
 		}
 	}()
-	/*
-		TODO:   Get this working.  Right now doesn't work due to the user-id, IN, not being consumed
-		  and or available later for evaluation.  Where do we put them?  as the expression
-		  isn't complete?
-
-		  user_id IN (user_id)   leftCtx.Get("user_id") == ctx2.Get("user_id")
-		  user_id = user_id
-
-	*/
 	m.Next() // Consume the Where
-	//u.Debugf("cur: %v peek=%v", m.Cur(), m.Peek())
+	//logging.Debugf("cur: %v peek=%v", m.Cur(), m.Peek())
 
 	where := SqlWhere{}
 	req.Where = &where
-	m.Next()
-	// Check for SubSelect
+	// Sub-Selects are parsed as part of expression
 	//    SELECT name, user_id from user where user_id IN (select user_id from orders where ...)
-	//    SELECT * FROM t1 WHERE column1 = (SELECT column1 FROM t2);
-	//    SELECT * FROM t3  WHERE ROW(5*t2.s1,77)= (SELECT 50,11*s1 FROM t4)
+	//    SELECT * FROM t3  WHERE (a, b) IN (SELECT x, y FROM t4)
 	//    select name from movies where director IN ("Quentin","copola","Bay","another")
-	switch m.Cur().T {
-	case lex.TokenIN, lex.TokenEqual:
-		// How do we consume the user_id IN (   ???
-		// we possibly need some type of "Deferred Binary"?  Where the arg is added in later?
-		// Or use context for that?
-		//u.Debugf("op? %v", m.Cur())
-		opToken := m.Cur().T
-		m.Next() // consume IN/=
-		//u.Debugf("cur: %v", m.Cur())
-		if m.Cur().T != lex.TokenLeftParenthesis {
-			m.Backup()
-			m.Backup()
-			break // break out of switch?
-		}
-		m.Next() // consume (
-		//u.Debugf("cur: %v", m.Cur())
-		if m.Cur().T != lex.TokenSelect {
-			m.Backup()
-			m.Backup()
-			m.Backup()
-			break // break out of switch?
-		}
-		where.Op = opToken
-		where.Source = &SqlSelect{}
-		return m.parseWhereSelect(where.Source)
-	default:
-		// lex.TokenBetween, lex.TokenLike:
-		m.Backup()
-	}
-	//u.Debugf("doing Where: %v %v", m.Cur(), m.Peek())
+	//logging.Debugf("doing Where: %v %v", m.Cur(), m.Peek())
 	tree := NewTree(m.SqlTokenPager)
 	m.parseNode(tree)
 	where.Expr = tree.Root
-	//u.Debugf("where: %v", m.Cur())
+	//logging.Debugf("where: %v", m.Cur())
 	return err
 }
 
@@ -737,15 +1332,32 @@ func (m *Sqlbridge) parseGroupBy(req *SqlSelect) (err error) {
 	}
 	m.Next()
 
+	switch {
+	case m.Cur().T == lex.TokenGroupingSets:
+		return m.parseGroupingSets(req)
+	case m.Cur().T == lex.TokenUdfExpr && strings.ToLower(m.Cur().V) == "rollup":
+		// ROLLUP(a, b) is the grouping sets  (a, b), (a), ()
+		m.Next()
+		set, err := m.parseGroupingSet(req)
+		if err != nil {
+			return err
+		}
+		for i := len(set); i >= 0; i-- {
+			req.GroupingSets = append(req.GroupingSets, set[:i])
+		}
+		req.Rollup = true
+		return nil
+	}
+
 	var col *Column
 
 	for {
 
-		//u.Debugf("Group By? %v", m.Cur())
+		//logging.Debugf("Group By? %v", m.Cur())
 		switch m.Cur().T {
 		case lex.TokenUdfExpr:
 			// we have a udf/functional expression column
-			//u.Infof("udf: %v", m.Cur().V)
+			//logging.Infof("udf: %v", m.Cur().V)
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
@@ -759,17 +1371,17 @@ func (m *Sqlbridge) parseGroupBy(req *SqlSelect) (err error) {
 						col.As = n.Name
 					}
 				case *BinaryNode:
-					//u.Debugf("udf? %T ", n)
+					//logging.Debugf("udf? %T ", n)
 					col.As = FindIdentityName(0, n, "")
 					if col.As == "" {
-						u.Errorf("could not find as name: %#v", n)
+						logging.Errorf("could not find as name: %#v", n)
 					}
 				}
 			}
-			//u.Debugf("next? %v", m.Cur())
+			//logging.Debugf("next? %v", m.Cur())
 
 		case lex.TokenIdentity:
-			//u.Warnf("?? %v", m.Cur())
+			//logging.Warnf("?? %v", m.Cur())
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
@@ -780,54 +1392,138 @@ func (m *Sqlbridge) parseGroupBy(req *SqlSelect) (err error) {
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Expr = tree.Root
+		case lex.TokenInteger:
+			// GROUP BY 1
+			if col, err = m.positionalColumn(req); err != nil {
+				return err
+			}
 		}
-		//u.Debugf("GroupBy after colstart?:   %v  ", m.Cur())
+		//logging.Debugf("GroupBy after colstart?:   %v  ", m.Cur())
 
 		// since we can loop inside switch statement
 		switch m.Cur().T {
 		case lex.TokenAs:
 			m.Next()
-			//u.Debug(m.Cur())
+			//logging.Debug(m.Cur())
 			switch m.Cur().T {
 			case lex.TokenIdentity, lex.TokenValue:
 				col.As = m.Cur().V
 				col.originalAs = col.As
-				//u.Infof("set AS=%v", col.As)
+				//logging.Infof("set AS=%v", col.As)
 				m.Next()
 				continue
 			}
 			return fmt.Errorf("expected identity but got: %v", m.Cur().String())
-		case lex.TokenFrom, lex.TokenOrderBy, lex.TokenInto, lex.TokenLimit, lex.TokenHaving, lex.TokenEOS, lex.TokenEOF:
+		case lex.TokenFrom, lex.TokenOrderBy, lex.TokenInto, lex.TokenLimit, lex.TokenHaving, lex.TokenEOS, lex.TokenEOF,
+			lex.TokenUnion, lex.TokenExcept, lex.TokenIntersect:
 			// This indicates we have come to the End of the columns
 			req.GroupBy = append(req.GroupBy, col)
-			//u.Debugf("Ending column ")
+			//logging.Debugf("Ending column ")
 			return nil
 		case lex.TokenIf:
 			// If guard
 			m.Next()
-			//u.Infof("if guard: %v", m.Cur())
+			//logging.Infof("if guard: %v", m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Guard = tree.Root
-			//u.Debugf("after if guard?:   %v  ", m.Cur())
+			//logging.Debugf("after if guard?:   %v  ", m.Cur())
 		case lex.TokenCommentSingleLine:
 			m.Next()
 			col.Comment = m.Cur().V
 		case lex.TokenRightParenthesis:
+			if m.subSelects > 0 {
+				// end of a derived table sub-select
+				req.GroupBy = append(req.GroupBy, col)
+				return nil
+			}
 			// loop on my friend
 		case lex.TokenComma:
 			req.GroupBy = append(req.GroupBy, col)
-			//u.Debugf("comma, added groupby:  %v", len(stmt.GroupBy))
+			//logging.Debugf("comma, added groupby:  %v", len(stmt.GroupBy))
 		default:
-			u.Errorf("expected col? %v", m.Cur())
+			logging.Errorf("expected col? %v", m.Cur())
 			return fmt.Errorf("expected column but got: %v", m.Cur().String())
 		}
 		m.Next()
 	}
-	//u.Debugf("groupby: %d", len(req.GroupBy))
+	//logging.Debugf("groupby: %d", len(req.GroupBy))
 	return nil
 }
 
+// GROUPING SETS ((a, b), (a), ())
+func (m *Sqlbridge) parseGroupingSets(req *SqlSelect) error {
+	m.Next() // Consume GROUPING SETS
+	if m.Cur().T != lex.TokenLeftParenthesis {
+		return fmt.Errorf("expected left paren after grouping sets but got: %v", m.Cur())
+	}
+	m.Next()
+	for {
+		set, err := m.parseGroupingSet(req)
+		if err != nil {
+			return err
+		}
+		req.GroupingSets = append(req.GroupingSets, set)
+		switch m.Cur().T {
+		case lex.TokenComma:
+			m.Next()
+		case lex.TokenRightParenthesis:
+			m.Next()
+			return nil
+		default:
+			return fmt.Errorf("expected comma or right paren but got: %v", m.Cur())
+		}
+	}
+}
+
+// a single grouping set  (a, b)  or column  a, adding its columns to the
+//  GroupBy and returning their indexes
+func (m *Sqlbridge) parseGroupingSet(req *SqlSelect) ([]int, error) {
+	set := make([]int, 0)
+	paren := m.Cur().T == lex.TokenLeftParenthesis
+	if paren {
+		m.Next()
+	}
+	for !paren || m.Cur().T != lex.TokenRightParenthesis {
+		switch m.Cur().T {
+		case lex.TokenIdentity, lex.TokenUdfExpr, lex.TokenValue:
+		default:
+			return nil, fmt.Errorf("expected group by column but got: %v", m.Cur())
+		}
+		col := NewColumn(m.Cur())
+		tree := NewTree(m.SqlTokenPager)
+		if err := m.parseNode(tree); err != nil {
+			return nil, err
+		}
+		col.Expr = tree.Root
+		if fn, ok := col.Expr.(*FuncNode); ok {
+			if col.As = FindIdentityName(0, fn, ""); col.As == "" {
+				col.As = fn.Name
+			}
+		}
+		set = append(set, groupByIndex(req, col))
+		if !paren {
+			return set, nil
+		}
+		if m.Cur().T == lex.TokenComma {
+			m.Next()
+		}
+	}
+	m.Next() // discard right paren
+	return set, nil
+}
+
+// index of @col in the GroupBy, appending it if new
+func groupByIndex(req *SqlSelect, col *Column) int {
+	for i, gb := range req.GroupBy {
+		if gb.Expr != nil && gb.Expr.String() == col.Expr.String() {
+			return i
+		}
+	}
+	req.GroupBy = append(req.GroupBy, col)
+	return len(req.GroupBy) - 1
+}
+
 func (m *Sqlbridge) parseHaving(req *SqlSelect) (err error) {
 
 	if m.Cur().T != lex.TokenHaving {
@@ -835,7 +1531,7 @@ func (m *Sqlbridge) parseHaving(req *SqlSelect) (err error) {
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			u.Errorf("having error? %v \n %v", r, m.Cur())
+			logging.Errorf("having error? %v \n %v", r, m.Cur())
 			if m.Cur().T == lex.TokenSelect {
 				// TODO this is deeply flawed, need to fix/use tokenpager
 				// with rewind ability
@@ -846,11 +1542,11 @@ func (m *Sqlbridge) parseHaving(req *SqlSelect) (err error) {
 		}
 	}()
 	m.Next()
-	//u.Infof("%v", m.Cur())
+	//logging.Infof("%v", m.Cur())
 	tree := NewTree(m.SqlTokenPager)
 	m.parseNode(tree)
 	req.Having = tree.Root
-	//u.Debugf("having: %v", m.Cur())
+	//logging.Debugf("having: %v", m.Cur())
 	return err
 }
 
@@ -865,11 +1561,11 @@ func (m *Sqlbridge) parseOrderBy(req *SqlSelect) (err error) {
 
 	for {
 
-		//u.Debugf("Order By? %v", m.Cur())
+		//logging.Debugf("Order By? %v", m.Cur())
 		switch m.Cur().T {
 		case lex.TokenUdfExpr:
 			// we have a udf/functional expression column
-			//u.Infof("udf: %v", m.Cur().V)
+			//logging.Infof("udf: %v", m.Cur().V)
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
@@ -881,49 +1577,85 @@ func (m *Sqlbridge) parseOrderBy(req *SqlSelect) (err error) {
 					col.As = n.Name
 				}
 			case *BinaryNode:
-				//u.Debugf("udf? %T ", n)
+				//logging.Debugf("udf? %T ", n)
 				col.As = FindIdentityName(0, n, "")
 				if col.As == "" {
-					u.Errorf("could not find as name: %#v", n)
+					logging.Errorf("could not find as name: %#v", n)
 				}
 			}
-			//u.Debugf("next? %v", m.Cur())
+			//logging.Debugf("next? %v", m.Cur())
 		case lex.TokenIdentity:
-			//u.Warnf("?? %v", m.Cur())
+			//logging.Warnf("?? %v", m.Cur())
 			col = NewColumn(m.Cur())
 			tree := NewTree(m.SqlTokenPager)
 			m.parseNode(tree)
 			col.Expr = tree.Root
+		case lex.TokenInteger:
+			// ORDER BY 2 DESC
+			if col, err = m.positionalColumn(req); err != nil {
+				return err
+			}
 		}
-		//u.Debugf("OrderBy after colstart?:   %v  ", m.Cur())
+		//logging.Debugf("OrderBy after colstart?:   %v  ", m.Cur())
 
 		// since we can loop inside switch statement
 		switch m.Cur().T {
 		case lex.TokenAsc, lex.TokenDesc:
 			col.Order = strings.ToUpper(m.Cur().V)
+		case lex.TokenNulls:
+			m.Next()
+			switch m.Cur().T {
+			case lex.TokenFirst, lex.TokenLast:
+				col.Nulls = strings.ToUpper(m.Cur().V)
+			default:
+				return fmt.Errorf("expected NULLS FIRST or LAST but got: %v", m.Cur().String())
+			}
 
 		case lex.TokenInto, lex.TokenLimit, lex.TokenEOS, lex.TokenEOF:
 			// This indicates we have come to the End of the columns
 			req.OrderBy = append(req.OrderBy, col)
-			//u.Debugf("Ending column ")
+			//logging.Debugf("Ending column ")
 			return nil
 		case lex.TokenCommentSingleLine:
 			m.Next()
 			col.Comment = m.Cur().V
 		case lex.TokenRightParenthesis:
+			if m.subSelects > 0 {
+				// end of a derived table sub-select
+				req.OrderBy = append(req.OrderBy, col)
+				return nil
+			}
 			// loop on my friend
 		case lex.TokenComma:
 			req.OrderBy = append(req.OrderBy, col)
-			//u.Debugf("comma, added groupby:  %v", len(stmt.OrderBy))
+			//logging.Debugf("comma, added groupby:  %v", len(stmt.OrderBy))
 		default:
 			return fmt.Errorf("expected column but got: %v", m.Cur().String())
 		}
 		m.Next()
 	}
-	//u.Debugf("OrderBy: %d", len(req.OrderBy))
+	//logging.Debugf("OrderBy: %d", len(req.OrderBy))
 	return nil
 }
 
+// a positional reference  ORDER BY 2, GROUP BY 1  (1 based) is the
+//  expression of that select column
+func (m *Sqlbridge) positionalColumn(req *SqlSelect) (*Column, error) {
+	tok := m.Cur()
+	pos, err := strconv.Atoi(tok.V)
+	if err != nil || pos < 1 || pos > len(req.Columns) {
+		return nil, fmt.Errorf("position %s is not in the select list of %d columns", tok.V, len(req.Columns))
+	}
+	sel := req.Columns[pos-1]
+	if sel.Star || sel.Expr == nil {
+		return nil, fmt.Errorf("position %s is not a select expression: %s", tok.V, sel)
+	}
+	col := NewColumn(tok)
+	col.Expr, col.As, col.SourceField = sel.Expr, sel.As, sel.SourceField
+	m.Next()
+	return col, nil
+}
+
 func (m *Sqlbridge) parseWhereSelect(req *SqlSelect) error {
 
 	if m.Cur().T != lex.TokenSelect {
@@ -933,7 +1665,7 @@ func (m *Sqlbridge) parseWhereSelect(req *SqlSelect) error {
 	if err != nil {
 		return err
 	}
-	u.Infof("found sub-select %+v", stmt)
+	logging.Infof("found sub-select %+v", stmt)
 	req = stmt
 	return nil
 }
@@ -989,10 +1721,10 @@ func (m *SqlTokenPager) IsEnd() bool {
 }
 func (m *SqlTokenPager) ClauseEnd() bool {
 	tok := m.Cur()
-	//u.Debugf("IsEnd()? tok:  %v", tok)
+	//logging.Debugf("IsEnd()? tok:  %v", tok)
 	switch tok.T {
 	case lex.TokenEOF, lex.TokenEOS, lex.TokenFrom, lex.TokenHaving, lex.TokenComma,
-		lex.TokenIf, lex.TokenAs, lex.TokenLimit, lex.TokenSelect:
+		lex.TokenIf, lex.TokenAs, lex.TokenLimit, lex.TokenSelect, lex.TokenReturning:
 		return true
 	}
 	return false
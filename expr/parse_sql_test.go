@@ -3,7 +3,9 @@ package expr
 import (
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
 	"github.com/bmizerany/assert"
+	"strings"
 	"testing"
 )
 
@@ -194,3 +196,624 @@ func TestSqlParse(t *testing.T) {
 	assert.Tf(t, sel.Where != nil && sel.Where.NodeType() == SqlWhereNodeType, "has sub-select: %v", sel.Where)
 	u.Infof("sel:  %#v", sel.Where)
 }
+
+func TestSqlRowConstructor(t *testing.T) {
+
+	sql := `SELECT a FROM t WHERE (a, b) IN ((1, "x"), (2, "y"))`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	in, ok := sel.Where.Expr.(*MultiArgNode)
+	assert.Tf(t, ok, "is MultiArgNode: %T", sel.Where.Expr)
+	assert.Tf(t, len(in.Args) == 3, "has 3 args: %v", in.Args)
+	row, ok := in.Args[0].(*RowConstructorNode)
+	assert.Tf(t, ok && len(row.Args) == 2, "is row: %T", in.Args[0])
+	assert.Tf(t, in.String() == `(a, b) IN ((1, "x"),(2, "y"))`, "got %v", in.String())
+
+	sql = `SELECT a FROM t WHERE (a, b) IN (SELECT x, y FROM t2 WHERE z > 5)`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel = req.(*SqlSelect)
+	in, ok = sel.Where.Expr.(*MultiArgNode)
+	assert.Tf(t, ok, "is MultiArgNode: %T", sel.Where.Expr)
+	sub, ok := in.Args[1].(*SqlSelect)
+	assert.Tf(t, ok, "is sub-select: %T", in.Args[1])
+	assert.Tf(t, len(sub.Columns) == 2 && sub.Where != nil, "sub-select: %v", sub)
+}
+
+func TestSqlScalarSubQuery(t *testing.T) {
+
+	sql := `SELECT user_id, count(*) AS ct FROM orders GROUP BY user_id HAVING count(*) > (SELECT avg(ct) FROM daily WHERE ct > 1)`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	bn, ok := sel.Having.(*BinaryNode)
+	assert.Tf(t, ok, "is BinaryNode: %T", sel.Having)
+	sub, ok := bn.Args[1].(*SubQueryNode)
+	assert.Tf(t, ok, "is sub-query: %T", bn.Args[1])
+	assert.Tf(t, len(sub.Select.Columns) == 1 && sub.Select.Where != nil, "sub-select: %v", sub.Select)
+	assert.Tf(t, sub.Check() == nil, "scalar: %v", sub.Check())
+	assert.Tf(t, !IsPure(bn), "sub-selects are not pure")
+
+	// round trips through its sql and json
+	req2, err := ParseSql(sel.String())
+	assert.Tf(t, err == nil, "re-parse %s: %v", sel.String(), err)
+	assert.Tf(t, NodesEqual(sel.Having, req2.(*SqlSelect).Having), "%v", req2)
+	data, err := MarshalNode(sel.Having)
+	assert.Tf(t, err == nil, "marshal %v", err)
+	n, err := UnmarshalNode(data)
+	assert.Tf(t, err == nil && NodesEqual(sel.Having, n), "unmarshal %v %v", n, err)
+
+	sql = `SELECT a FROM t WHERE b > (SELECT x, y FROM t2)`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil, "Must parse: %s  \n\t%v", sql, err)
+	sub = req.(*SqlSelect).Where.Expr.(*BinaryNode).Args[1].(*SubQueryNode)
+	assert.Tf(t, sub.Check() != nil, "two columns is not scalar")
+}
+
+func TestSqlBindParams(t *testing.T) {
+
+	ids := value.NewSliceValues([]value.Value{value.NewIntValue(1), value.NewIntValue(2), value.NewIntValue(3)})
+	binds := []struct {
+		sql  string
+		args []value.Value
+	}{
+		// a slice bound to a single placeholder is expanded to the list
+		{`SELECT name FROM users WHERE id IN (?) AND name = ?`, []value.Value{ids, value.NewStringValue("bob")}},
+		{`SELECT name FROM users WHERE id IN (?, ?, ?) AND name = ?`,
+			[]value.Value{value.NewIntValue(1), value.NewIntValue(2), value.NewIntValue(3), value.NewStringValue("bob")}},
+	}
+	for _, bt := range binds {
+		req, err := ParseSql(bt.sql)
+		assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", bt.sql, err)
+		sel := req.(*SqlSelect)
+		_, err = BindParams(sel, bt.args)
+		assert.Tf(t, err == nil, "bind %s: %v", bt.sql, err)
+		and := sel.Where.Expr.(*BinaryNode)
+		in, ok := and.Args[0].(*MultiArgNode)
+		assert.Tf(t, ok, "is MultiArgNode: %T", and.Args[0])
+		assert.Tf(t, len(in.Args) == 4, "id and 3 values: %v", in)
+		for i, arg := range in.Args[1:] {
+			nn, ok := arg.(*NumberNode)
+			assert.Tf(t, ok && nn.Int64 == int64(i+1), "bound %d: %#v", i, arg)
+		}
+		assert.Tf(t, and.String() == `id IN (1,2,3) AND name = "bob"`, "%v", and)
+	}
+
+	// placeholders are allowed in an IN list and are unbound until bound
+	tree, err := ParseExpression(`id IN (?, 5)`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	n := tree.Root
+	_, ok := n.(*MultiArgNode).Args[1].(*ParamNode)
+	assert.Tf(t, ok, "is ParamNode: %v", n)
+	_, err = BindParams(n, []value.Value{value.NewIntValue(1), value.NewIntValue(2)})
+	assert.Tf(t, err != nil, "too many args")
+	_, err = BindParams(n, []value.Value{ids})
+	assert.Tf(t, err != nil, "slice is only a list as the only arg")
+	n, err = BindParams(n, []value.Value{value.NewIntValue(4)})
+	assert.Tf(t, err == nil && n.String() == `id IN (4,5)`, "bound %v %v", n, err)
+
+	// the selects of set operations
+	req, err := ParseSql(`SELECT name FROM users WHERE id = ? UNION SELECT name FROM admins WHERE id = ?`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	_, err = BindParams(req, []value.Value{value.NewIntValue(1), value.NewIntValue(2)})
+	assert.Tf(t, err == nil, "bind: %v", err)
+	assert.Equal(t, `SELECT name FROM users WHERE id = 1 UNION SELECT name FROM admins WHERE id = 2`, req.String())
+
+	// insert VALUES, update and delete
+	req, err = ParseSql(`INSERT INTO users (id, name) VALUES (?, "bob"), (3, ?)`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	_, err = BindParams(req, []value.Value{value.NewIntValue(1)})
+	assert.Tf(t, err != nil, "too few args")
+	_, err = BindParams(req, []value.Value{value.NewIntValue(1), value.NewStringValue("sue")})
+	assert.Tf(t, err == nil, "bind: %v", err)
+	rows := req.(*SqlInsert).Rows
+	assert.Tf(t, rows[0][0].Value() == int64(1) && rows[1][1].Value() == "sue", "bound %v", rows)
+
+	req, err = ParseSql(`UPDATE users SET name = ?, ct = ct + 1 WHERE id = ?`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	_, err = BindParams(req, []value.Value{value.NewStringValue("sue"), value.NewIntValue(3)})
+	assert.Tf(t, err == nil, "bind: %v", err)
+	up := req.(*SqlUpdate)
+	sn, ok := up.Columns[0].Expr.(*StringNode)
+	assert.Tf(t, ok && sn.Text == "sue" && up.Where.String() == `id = 3`, "bound %v %v", up.Columns[0].Expr, up.Where)
+
+	req, err = ParseSql(`DELETE FROM users WHERE id IN (?)`)
+	assert.Tf(t, err == nil, "parse: %v", err)
+	_, err = BindParams(req, []value.Value{ids})
+	assert.Tf(t, err == nil && req.(*SqlDelete).Where.String() == `id IN (1,2,3)`, "bound %v %v", req.(*SqlDelete).Where, err)
+}
+
+func TestSqlDerivedTable(t *testing.T) {
+
+	sql := `SELECT t.user_id, t.ct
+		FROM (SELECT user_id, count(*) AS ct FROM users WHERE item_count > 10 GROUP BY user_id) AS t
+		WHERE t.ct > 1`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, len(sel.From) == 1, "has 1 source: %v", len(sel.From))
+	from := sel.From[0]
+	assert.Tf(t, from.Alias == "t" && from.Source != nil, "derived table: %#v", from)
+	assert.Tf(t, len(from.Source.Columns) == 2 && len(from.Source.GroupBy) == 1, "sub-select: %v", from.Source)
+	assert.Tf(t, from.Source.Where != nil && sel.Where != nil, "both have where: %v", sel)
+	assert.Tf(t, strings.HasPrefix(from.String(), "(SELECT user_id, count("), "got %v", from.String())
+
+	// order by, limit and quoted parens within the sub-select
+	sql = `SELECT * FROM (SELECT a FROM b WHERE c = "x)" ORDER BY a DESC LIMIT 3) AS t ORDER BY a`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel = req.(*SqlSelect)
+	sub := sel.From[0].Source
+	assert.Tf(t, sub != nil && len(sub.OrderBy) == 1 && sub.Limit == 3, "sub-select: %v", sub)
+	assert.Tf(t, len(sel.OrderBy) == 1, "outer order by: %v", sel.OrderBy)
+}
+
+func TestSqlWith(t *testing.T) {
+
+	sql := `WITH big AS (SELECT user_id, interests FROM users WHERE item_count > 10),
+		counts AS (SELECT interests, count(*) AS ct FROM big GROUP BY interests)
+		SELECT interests, ct FROM counts WHERE ct > 1`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, len(sel.With) == 2, "has 2 ctes: %v", len(sel.With))
+	assert.Tf(t, sel.With[0].Alias == "big" && sel.With[0].Source.Where != nil, "cte big: %v", sel.With[0])
+	assert.Tf(t, sel.With[1].Alias == "counts" && len(sel.With[1].Source.GroupBy) == 1, "cte counts: %v", sel.With[1])
+	assert.Tf(t, sel.With[1].Source.From[0].Name == "big", "counts from big: %v", sel.With[1].Source.From[0])
+	assert.Tf(t, sel.From[0].Name == "counts" && sel.Where != nil, "select: %v", sel)
+	assert.Tf(t, strings.HasPrefix(sel.String(), "WITH big AS (SELECT user_id, interests FROM users"), "got %v", sel.String())
+
+	_, err = ParseSql(`WITH big AS (SELECT a FROM b)`)
+	assert.Tf(t, err != nil, "cte must be followed by select")
+}
+
+func TestSqlReturning(t *testing.T) {
+
+	sql := `INSERT INTO users (name, status) VALUES ("bob", DEFAULT) RETURNING id, created_at`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins := req.(*SqlInsert)
+	assert.Tf(t, len(ins.Rows) == 1 && len(ins.Rows[0]) == 2, "1 row of 2: %v", ins.Rows)
+	_, isDefault := ins.Rows[0][1].(DefaultValue)
+	assert.Tf(t, isDefault, "DEFAULT value: %#v", ins.Rows[0][1])
+	assert.Tf(t, len(ins.Returning) == 2 && ins.Returning[1].As == "created_at", "returning: %v", ins.Returning)
+
+	sql = `UPDATE users SET status = "active", ct = ct + 1 WHERE id = 5 RETURNING id, ct`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	up := req.(*SqlUpdate)
+	assert.Tf(t, up.From == "users" && len(up.Columns) == 2, "update: %v", up.Columns)
+	assert.Tf(t, up.Columns[1].As == "ct" && up.Columns[1].Expr.String() == "ct + 1", "set ct: %v", up.Columns[1].Expr)
+	assert.Tf(t, up.Where != nil && len(up.Returning) == 2, "where, returning: %v", up.Returning)
+
+	sql = `DELETE FROM users WHERE id = 5 RETURNING id`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	del := req.(*SqlDelete)
+	assert.Tf(t, del.Where != nil && len(del.Returning) == 1, "returning: %v", del.Returning)
+}
+
+func TestSqlInsertOnConflict(t *testing.T) {
+
+	sql := `INSERT INTO users (id, name) VALUES ("a", "bob") ON CONFLICT (id) DO NOTHING RETURNING id`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	ins := req.(*SqlInsert)
+	assert.Tf(t, ins.IgnoreConflicts && ins.ConflictKey == "id", "on conflict: %#v", ins)
+	assert.Tf(t, len(ins.Rows) == 1 && len(ins.Returning) == 1, "rows, returning: %v", ins.Returning)
+
+	req, err = ParseSql(`INSERT INTO users (id) VALUES ("a") on conflict do nothing`)
+	assert.Tf(t, err == nil && req.(*SqlInsert).IgnoreConflicts, "no conflict key %v", err)
+	assert.Tf(t, req.(*SqlInsert).ConflictKey == "", "no conflict key")
+
+	_, err = ParseSql(`INSERT INTO users (id) VALUES ("a") ON CONFLICT DO UPDATE`)
+	assert.Tf(t, err != nil, "only DO NOTHING")
+}
+
+func TestSqlConcat(t *testing.T) {
+
+	sql := `SELECT 'a' || 'b' AS ab FROM users`
+
+	// ansi,  || is string concat
+	req, err := ParseSqlDialect(sql, lex.AnsiSqlDialect)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	bn, ok := req.(*SqlSelect).Columns[0].Expr.(*BinaryNode)
+	assert.Tf(t, ok && bn.Operator.T == lex.TokenConcat, "concat: %#v", req.(*SqlSelect).Columns[0].Expr)
+	assert.Tf(t, ValueTypeFromNode(bn) == value.StringType, "concat is string")
+
+	// binds tighter than comparison, as +
+	req, err = ParseSqlDialect(`SELECT name FROM users WHERE first || last = 'ab'`, lex.AnsiSqlDialect)
+	assert.Tf(t, err == nil, "Must parse: %v", err)
+	bn = req.(*SqlSelect).Where.Expr.(*BinaryNode)
+	assert.Tf(t, bn.Operator.T == lex.TokenEqual, "= at top: %v", bn)
+
+	// mysql (default),  || is logical or
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	bn, ok = req.(*SqlSelect).Columns[0].Expr.(*BinaryNode)
+	assert.Tf(t, ok && bn.Operator.T == lex.TokenOr, "or: %#v", req.(*SqlSelect).Columns[0].Expr)
+}
+
+func TestSqlTableFunc(t *testing.T) {
+
+	sql := `SELECT name, t FROM users, unnest(tags) AS t WHERE name = "bob"`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, len(sel.From) == 2 && sel.From[0].Name == "users", "from: %v", sel.From)
+	lateral := sel.From[1]
+	assert.Tf(t, lateral.Func != nil && lateral.Func.Name == "unnest" && lateral.Alias == "t", "func: %#v", lateral)
+	assert.Tf(t, len(lateral.Func.Args) == 1, "args: %v", lateral.Func.Args)
+	assert.Tf(t, sel.Where != nil, "where: %v", sel)
+	assert.Tf(t, strings.Contains(sel.String(), "FROM users, unnest(tags) AS t"), "string: %v", sel.String())
+
+	req, err = ParseSql(`SELECT t FROM unnest(tags) AS t`)
+	assert.Tf(t, err == nil, "Must parse: %v", err)
+	sel = req.(*SqlSelect)
+	assert.Tf(t, len(sel.From) == 1 && sel.From[0].Func != nil && sel.From[0].Alias == "t", "from: %v", sel.From)
+
+	_, err = ParseSql(`SELECT name FROM users, orders`)
+	assert.Tf(t, err != nil, "only table funcs after comma")
+}
+
+func TestSqlLateral(t *testing.T) {
+
+	sql := `SELECT u.name, o.ct FROM users AS u, LATERAL (SELECT count(*) AS ct FROM orders WHERE user_id = u.user_id) AS o`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, len(sel.From) == 2 && sel.From[0].Alias == "u", "from: %v", sel.From)
+	lateral := sel.From[1]
+	assert.Tf(t, lateral.Lateral && lateral.Alias == "o" && lateral.Source != nil, "lateral: %#v", lateral)
+	assert.Tf(t, lateral.Source.Where != nil && lateral.Source.From[0].Name == "orders", "sub: %v", lateral.Source)
+	assert.Tf(t, strings.Contains(sel.String(), "FROM users AS u, LATERAL (SELECT"), "string: %v", sel.String())
+
+	_, err = ParseSql(`SELECT name FROM users, LATERAL orders`)
+	assert.Tf(t, err != nil, "lateral requires a subquery")
+}
+
+func TestSqlGroupingSets(t *testing.T) {
+
+	sql := `SELECT a, b, sum(c) AS ct FROM t GROUP BY ROLLUP(a, b)`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, sel.Rollup && len(sel.GroupBy) == 2, "group by: %v", sel.GroupBy)
+	assert.Equal(t, [][]int{{0, 1}, {0}, {}}, sel.GroupingSets)
+	assert.Equal(t, sql, sel.String())
+
+	sql = `SELECT a, b, sum(c) AS ct FROM t GROUP BY GROUPING SETS ((a, b), (b), ())`
+	req, err = ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel = req.(*SqlSelect)
+	assert.Tf(t, !sel.Rollup && len(sel.GroupBy) == 2, "group by: %v", sel.GroupBy)
+	assert.Equal(t, [][]int{{0, 1}, {1}, {}}, sel.GroupingSets)
+	assert.Equal(t, sql, sel.String())
+
+	sel = parseOrPanic(t, `SELECT a, count(*) FROM t GROUP BY a`).(*SqlSelect)
+	assert.Tf(t, sel.GroupingSets == nil, "plain group by has no sets")
+}
+
+func TestSqlHints(t *testing.T) {
+
+	sql := `SELECT /*+ USE_INDEX(users idx_name) no_cache */ name -- the name
+		FROM users /* comment */ WHERE age > 10`
+	req, err := ParseSql(sql)
+	assert.Tf(t, err == nil && req != nil, "Must parse: %s  \n\t%v", sql, err)
+	sel := req.(*SqlSelect)
+	assert.Tf(t, len(sel.Hints) == 2, "has 2 hints: %v", sel.Hints)
+	hint := sel.Hint("use_index")
+	assert.Tf(t, hint != nil && len(hint.Args) == 2, "use_index hint: %v", hint)
+	assert.Equal(t, []string{"users", "idx_name"}, hint.Args)
+	assert.Tf(t, sel.Hint("NO_CACHE") != nil, "no_cache hint")
+	assert.Tf(t, sel.Hint("missing") == nil, "no missing hint")
+	assert.Tf(t, sel.Where != nil && len(sel.Columns) == 1, "parsed: %v", sel)
+	assert.Equal(t, "SELECT /*+ USE_INDEX(users idx_name) NO_CACHE */ name FROM users WHERE age > 10", sel.String())
+
+	hints := ParseHints(` MAX_TIME( 100 ), ORDERED  INDEX(t1, idx_a idx_b)`)
+	assert.Tf(t, len(hints) == 3, "3 hints: %v", hints)
+	assert.Equal(t, "MAX_TIME(100)", hints[0].String())
+	assert.Equal(t, "ORDERED", hints[1].String())
+	assert.Equal(t, []string{"t1", "idx_a", "idx_b"}, hints[2].Args)
+
+	// no hints, comments stripped
+	req, err = ParseSql("SELECT a /* a */ FROM b -- b")
+	assert.Tf(t, err == nil && req != nil, "Must parse: %v", err)
+	sel = req.(*SqlSelect)
+	assert.Tf(t, len(sel.Hints) == 0, "no hints: %v", sel.Hints)
+	assert.Equal(t, "SELECT a FROM b", sel.String())
+}
+
+func TestSqlParseMulti(t *testing.T) {
+	stmts, err := ParseMulti(`
+		-- first; with a semi-colon in a comment
+		SELECT a FROM t1 WHERE name = "x;y";
+		/* second */ DELETE FROM t2 WHERE id = 5;
+		SELECT b, c FROM t3 ;
+	`)
+	assert.Tf(t, err == nil, "should parse: %v", err)
+	assert.Tf(t, len(stmts) == 3, "want 3 statements: %v", len(stmts))
+	sel, ok := stmts[0].(*SqlSelect)
+	assert.Tf(t, ok, "want select got %T", stmts[0])
+	assert.Tf(t, sel.Where.StringAST() == `name = "x;y"`, "has %s", sel.Where.StringAST())
+	_, ok = stmts[1].(*SqlDelete)
+	assert.Tf(t, ok, "want delete got %T", stmts[1])
+	sel, ok = stmts[2].(*SqlSelect)
+	assert.Tf(t, ok && len(sel.Columns) == 2, "want select got %T", stmts[2])
+
+	// error in the middle statement
+	_, err = ParseMulti("SELECT a FROM t1;\nSELEKT b FROM t2;\nSELECT c FROM t3")
+	assert.Tf(t, err != nil, "should error")
+	stmtErr, ok := err.(*StatementError)
+	assert.Tf(t, ok, "want StatementError got %T", err)
+	assert.Tf(t, stmtErr.Index == 1 && stmtErr.Line == 2 && stmtErr.Pos == 18, "%#v", stmtErr)
+	assert.Tf(t, strings.HasPrefix(err.Error(), "statement 2 (line 2, pos 18)"), "%v", err)
+}
+
+func TestSqlRequiredColumns(t *testing.T) {
+	stmt, err := ParseSql(`SELECT name, toint(age) AS age_int, count(*) AS ct
+		FROM users AS u
+		WHERE email LIKE "%@gmail.com" AND u.active = true
+		GROUP BY name, city
+		HAVING ct > 1
+		ORDER BY ct DESC, created`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	sel := stmt.(*SqlSelect)
+	cols := sel.RequiredColumns()
+	assert.Tf(t, strings.Join(cols, ",") == "u.name,u.age,u.email,u.active,u.city,u.created", "got %v", cols)
+
+	// multiple sources, columns are returned as written
+	stmt, err = ParseSql(`SELECT u.name, o.item FROM users AS u
+		INNER JOIN orders AS o ON u.user_id = o.user_id
+		WHERE o.price > 10 ORDER BY u.name`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	cols = stmt.(*SqlSelect).RequiredColumns()
+	assert.Tf(t, strings.Join(cols, ",") == "u.name,o.item,o.price,u.user_id,o.user_id", "got %v", cols)
+}
+
+func TestSqlFinalize(t *testing.T) {
+
+	_, err := ParseSql(`SELECT u.name, x.item FROM users AS u
+		INNER JOIN orders AS o ON u.user_id = o.user_id`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), `"x"`), "bad alias in columns: %v", err)
+
+	_, err = ParseSql(`SELECT u.name FROM users AS u
+		INNER JOIN orders AS o ON u.user_id = o.user_id WHERE ord.price > 10`)
+	assert.Tf(t, err != nil, "bad alias in where")
+
+	_, err = ParseSql(`SELECT u.name FROM users AS u
+		INNER JOIN orders AS o ON u.user_id = orders.user_id ORDER BY u.name`)
+	assert.Tf(t, err == nil, "source name or alias: %v", err)
+
+	// single source may have nested fields
+	_, err = ParseSql(`SELECT actor.login FROM github_push`)
+	assert.Tf(t, err == nil, "nested field: %v", err)
+
+	_, err = ParseSql(`INSERT INTO users (id, name) VALUES (1, "bob"), (2)`)
+	assert.Tf(t, err != nil, "row value count")
+
+	_, err = ParseSql(`INSERT INTO users (id, name) VALUES (1, "bob") ON CONFLICT (email) DO NOTHING`)
+	assert.Tf(t, err != nil, "conflict key must be a column")
+
+	_, err = ParseSql(`UPDATE users SET name = "bob", NAME = "x" WHERE id = 1`)
+	assert.Tf(t, err != nil, "column set twice")
+
+	_, err = ParseSql(`DELETE FROM users WHERE id = 1 RETURNING count(*)`)
+	assert.Tf(t, err != nil, "aggregate in returning")
+}
+
+func TestSqlOrderByNulls(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT a, b FROM t ORDER BY a DESC NULLS LAST, b NULLS FIRST, 1 LIMIT 3`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.OrderBy) == 3, "3 order by: %v", sel.OrderBy)
+	assert.Tf(t, sel.OrderBy[0].Order == "DESC" && sel.OrderBy[0].Nulls == "LAST", "desc nulls last: %#v", sel.OrderBy[0])
+	assert.Tf(t, sel.OrderBy[1].Order == "" && sel.OrderBy[1].Nulls == "FIRST", "nulls first: %#v", sel.OrderBy[1])
+	assert.Tf(t, sel.OrderBy[2].Nulls == "", "default: %#v", sel.OrderBy[2])
+	assert.Equal(t, `SELECT a, b FROM t ORDER BY a DESC NULLS LAST, b NULLS FIRST, a LIMIT 3`, stmt.String())
+
+	_, err = ParseSql(`SELECT a FROM t ORDER BY a NULLS`)
+	assert.Tf(t, err != nil, "NULLS requires FIRST or LAST")
+}
+
+func TestSqlPositionalRefs(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT name, count(*) AS ct FROM users GROUP BY 1 ORDER BY 2 DESC, 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.GroupBy) == 1 && sel.GroupBy[0].Expr.String() == "name", "group by name: %v", sel.GroupBy)
+	assert.Tf(t, len(sel.OrderBy) == 2, "2 order by: %v", sel.OrderBy)
+	assert.Tf(t, sel.OrderBy[0].Expr == sel.Columns[1].Expr && sel.OrderBy[0].Order == "DESC", "order by ct desc: %v", sel.OrderBy[0])
+	assert.Tf(t, sel.OrderBy[0].As == "ct", "takes select alias: %v", sel.OrderBy[0].As)
+	assert.Tf(t, sel.OrderBy[1].Expr.String() == "name", "order by name: %v", sel.OrderBy[1])
+
+	stmt, err = ParseSql(`SELECT name, age FROM users ORDER BY 1 DESC`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, stmt.String() == `SELECT name, age FROM users ORDER BY name DESC`, "got %s", stmt)
+
+	_, err = ParseSql(`SELECT name, age FROM users ORDER BY 3`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "position 3"), "beyond select list: %v", err)
+	_, err = ParseSql(`SELECT name FROM users GROUP BY 2`)
+	assert.Tf(t, err != nil, "group by beyond select list")
+	_, err = ParseSql(`SELECT * FROM users ORDER BY 1`)
+	assert.Tf(t, err != nil, "star is not positional")
+}
+
+func TestSqlSetOps(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT a, b FROM t1 EXCEPT ALL SELECT c, d FROM t2 WHERE d > 1 INTERSECT SELECT e, f FROM t3 ORDER BY a LIMIT 5`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.SetOps) == 1, "1 set op: %v", sel.SetOps)
+	assert.Tf(t, sel.SetOps[0].Op == lex.TokenExcept && sel.SetOps[0].All, "except all: %v", sel.SetOps[0])
+	// INTERSECT binds tighter, to the right select of the EXCEPT
+	right := sel.SetOps[0].Right
+	assert.Tf(t, len(right.SetOps) == 1, "intersect on the right: %v", right.SetOps)
+	assert.Tf(t, right.SetOps[0].Op == lex.TokenIntersect && !right.SetOps[0].All, "intersect: %v", right.SetOps[0])
+	assert.Tf(t, len(sel.OrderBy) == 1 && sel.Limit == 5, "order by, limit apply to the whole: %v", sel)
+	assert.Tf(t, right.OrderBy == nil && right.SetOps[0].Right.Limit == 0, "not to the right: %v", right)
+	assert.Equal(t, `SELECT a, b FROM t1 EXCEPT ALL SELECT c, d FROM t2 WHERE d > 1 INTERSECT SELECT e, f FROM t3 ORDER BY a LIMIT 5`, stmt.String())
+
+	stmt, err = ParseSql(`SELECT a FROM t1 INTERSECT SELECT b FROM t2 UNION SELECT c FROM t3 INTERSECT SELECT d FROM t4 EXCEPT SELECT e FROM t5`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel = stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.SetOps) == 3, "(a INTERSECT b) UNION (c INTERSECT d) EXCEPT e: %v", sel.SetOps)
+	assert.Tf(t, sel.SetOps[0].Op == lex.TokenIntersect && len(sel.SetOps[0].Right.SetOps) == 0, "intersect: %v", sel.SetOps[0])
+	assert.Tf(t, sel.SetOps[1].Op == lex.TokenUnion && len(sel.SetOps[1].Right.SetOps) == 1, "union of intersect: %v", sel.SetOps[1])
+	assert.Tf(t, sel.SetOps[2].Op == lex.TokenExcept && len(sel.SetOps[2].Right.SetOps) == 0, "except: %v", sel.SetOps[2])
+	reparsed, err := ParseSql(stmt.String())
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, stmt.String(), reparsed.String())
+
+	stmt, err = ParseSql(`SELECT a FROM t1 UNION DISTINCT SELECT b FROM t2`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, `SELECT a FROM t1 UNION SELECT b FROM t2`, stmt.String())
+
+	_, err = ParseSql(`SELECT a, b FROM t1 INTERSECT SELECT c FROM t2`)
+	assert.Tf(t, err != nil && strings.Contains(err.Error(), "same number of columns"), "column count mismatch: %v", err)
+	_, err = ParseSql(`SELECT * FROM t1 EXCEPT SELECT c FROM t2`)
+	assert.Tf(t, err == nil, "star is not checked %v", err)
+}
+
+func TestSqlWindow(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT n, avg(n) OVER (ROWS 5 PRECEDING) AS m FROM t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*SqlSelect)
+	assert.Tf(t, sel.Columns[0].Over == nil, "not windowed: %v", sel.Columns[0])
+	assert.Tf(t, sel.Columns[1].Over != nil && sel.Columns[1].Over.Preceding == 5, "5 preceding: %v", sel.Columns[1])
+	assert.Tf(t, sel.Columns[1].As == "m", "alias after OVER: %v", sel.Columns[1].As)
+	assert.Equal(t, `SELECT n, avg(n) OVER (ROWS 5 PRECEDING) AS m FROM t`, stmt.String())
+
+	stmt, err = ParseSql(`SELECT sum(n) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel = stmt.(*SqlSelect)
+	assert.Tf(t, sel.Columns[0].Over.Preceding == -1, "unbounded: %v", sel.Columns[0].Over)
+
+	stmt, err = ParseSql(`SELECT count(*) OVER () FROM t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Tf(t, stmt.(*SqlSelect).Columns[0].Over.Preceding == -1, "empty window is unbounded")
+
+	stmt, err = ParseSql(`SELECT g, rank() OVER (PARTITION BY g, h ORDER BY score DESC, name) AS r FROM t`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	w := stmt.(*SqlSelect).Columns[1].Over
+	assert.Tf(t, len(w.PartitionBy) == 2 && len(w.OrderBy) == 2, "partition, order by: %v", w)
+	assert.Tf(t, w.OrderBy[0].Order == "DESC" && w.OrderBy[1].Order == "", "order: %v", w.OrderBy)
+	assert.Tf(t, w.Buffered() && w.Preceding == -1, "buffered: %v", w)
+	assert.Equal(t, `SELECT g, rank() OVER (PARTITION BY g, h ORDER BY score DESC, name) AS r FROM t`, stmt.String())
+
+	for _, sql := range []string{
+		`SELECT rank() OVER (ORDER BY) FROM t`,
+		`SELECT rank() OVER (PARTITION g) FROM t`,
+		`SELECT n OVER (ROWS 5 PRECEDING) FROM t`,
+		`SELECT avg(n) OVER ROWS 5 PRECEDING FROM t`,
+		`SELECT avg(n) OVER (ROWS 5) FROM t`,
+	} {
+		_, err = ParseSql(sql)
+		assert.Tf(t, err != nil, "should error: %s", sql)
+	}
+}
+
+func TestSqlDistinctOn(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT DISTINCT ON (user_id, day) * FROM events ORDER BY user_id, ts DESC`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel := stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.DistinctOn) == 2 && sel.DistinctOn[1].Expr.String() == "day", "distinct on: %v", sel.DistinctOn)
+	assert.Tf(t, sel.Star && len(sel.OrderBy) == 2, "star, order by: %v", sel)
+	assert.Equal(t, `SELECT DISTINCT ON (user_id, day) * FROM events ORDER BY user_id, ts DESC`, stmt.String())
+
+	stmt, err = ParseSql(`SELECT DISTINCT ON (user_id) user_id, ts FROM events`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	sel = stmt.(*SqlSelect)
+	assert.Tf(t, len(sel.DistinctOn) == 1 && len(sel.Columns) == 2, "distinct on, columns: %v", sel)
+
+	for _, sql := range []string{
+		`SELECT DISTINCT user_id FROM events`,
+		`SELECT DISTINCT ON () * FROM events`,
+		`SELECT DISTINCT ON (user_id * FROM events`,
+	} {
+		_, err = ParseSql(sql)
+		assert.Tf(t, err != nil, "should error: %s", sql)
+	}
+}
+
+func TestSqlMatchAgainst(t *testing.T) {
+
+	stmt, err := ParseSql(`SELECT id FROM docs WHERE MATCH(title, d.body) AGAINST('quick fox') AND id > 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	where := stmt.(*SqlSelect).Where.Expr
+	match, ok := SplitConjuncts(where)[0].(*MatchNode)
+	assert.Tf(t, ok, "match node: %T", SplitConjuncts(where)[0])
+	assert.Tf(t, len(match.Cols) == 2 && match.Cols[1].Text == "d.body", "cols: %v", match.Cols)
+	assert.Tf(t, match.Against == "quick fox", "against: %v", match.Against)
+	assert.Equal(t, `SELECT id FROM docs WHERE MATCH(title, d.body) AGAINST("quick fox") AND id > 1`, stmt.String())
+
+	// a space before the paren, and in a NOT
+	stmt, err = ParseSql(`SELECT id FROM docs WHERE NOT MATCH(title) AGAINST ("fox")`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, `SELECT id FROM docs WHERE NOT MATCH(title) AGAINST("fox")`, stmt.String())
+
+	for _, sql := range []string{
+		`SELECT id FROM docs WHERE MATCH(title) AGAINST(fox)`,
+		`SELECT id FROM docs WHERE MATCH(title) ("fox")`,
+		`SELECT id FROM docs WHERE MATCH() AGAINST("fox")`,
+		`SELECT id FROM docs WHERE MATCH(5) AGAINST("fox")`,
+	} {
+		_, err = ParseSql(sql)
+		assert.Tf(t, err != nil, "should error: %s", sql)
+	}
+}
+
+func TestSqlDialectQuoting(t *testing.T) {
+
+	mysql, _ := lex.GetDialect("mysql")
+	ansi, _ := lex.GetDialect("ansi_quotes")
+	column := func(sql string, dialect *lex.Dialect) Node {
+		stmt, err := ParseSqlDialect(sql, dialect)
+		assert.Tf(t, err == nil, "Must parse: %s  \n\t%v", sql, err)
+		return stmt.(*SqlSelect).Columns[0].Expr
+	}
+
+	// mysql quotes identities with backticks, double quotes are strings
+	in, ok := column("SELECT `select` FROM users", mysql).(*IdentityNode)
+	assert.Tf(t, ok && in.Text == "select" && in.Quote == '`', "identity: %#v", in)
+	sn, ok := column(`SELECT "select" FROM users`, mysql).(*StringNode)
+	assert.Tf(t, ok && sn.Text == "select", "string: %#v", sn)
+
+	// ansi quotes identities with double quotes, single quotes are strings
+	in, ok = column(`SELECT "select" FROM users`, ansi).(*IdentityNode)
+	assert.Tf(t, ok && in.Text == "select" && in.Quote == '"', "identity: %#v", in)
+	sn, ok = column(`SELECT 'select' FROM users`, ansi).(*StringNode)
+	assert.Tf(t, ok && sn.Text == "select", "string: %#v", sn)
+
+	stmt, err := ParseSqlDialect(`SELECT "select", "user" FROM users WHERE "select" = 1`, ansi)
+	assert.Tf(t, err == nil, "Must parse: %v", err)
+	assert.Equal(t, `SELECT "select", "user" FROM users WHERE "select" = 1`, stmt.String())
+
+	// reserved words of the ansi_quotes dialect must be quoted, mysql
+	//  only quotes its reserved words when generating ql
+	for _, test := range []struct {
+		sql     string
+		dialect *lex.Dialect
+		ok      bool
+	}{
+		{`SELECT user FROM users`, mysql, true},
+		{`SELECT user FROM users`, ansi, false},
+		{`SELECT "user" FROM users`, ansi, true},
+		{"SELECT key FROM users", mysql, true},
+		{"SELECT `key` FROM users", mysql, true},
+		{"SELECT key FROM users", ansi, true},
+		{"SELECT select FROM users", ansi, false},
+		{"SELECT key FROM t", lex.SqlDialect, true},
+		{"SELECT a FROM t WHERE index > 1", lex.SqlDialect, true},
+		{"SELECT a FROM t WHERE mod = 1", lex.SqlDialect, true},
+		{"SELECT range AS usage FROM t", lex.SqlDialect, true},
+		{`SELECT "a" || "b" AS x FROM t`, lex.AnsiSqlDialect, true},
+	} {
+		_, err := ParseSqlDialect(test.sql, test.dialect)
+		assert.Tf(t, (err == nil) == test.ok, "%s %s want ok=%v got %v", test.dialect.Name, test.sql, test.ok, err)
+	}
+}
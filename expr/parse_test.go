@@ -1,14 +1,20 @@
 package expr_test
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/araddon/dateparse"
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/expr/builtins"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
@@ -26,6 +32,7 @@ func init() {
 	if *VerboseTests {
 		u.SetupLogging("debug")
 		u.SetColorOutput()
+		logging.SetLogger(logging.GouLogger{})
 	}
 
 	builtins.LoadAllBuiltins()
@@ -167,10 +174,167 @@ var parseTests = []parseTest{
 	{"compound where", `eq(event,"stuff") AND ge(party, 1)`, noError, `eq(event, "stuff") AND ge(party, 1)`},
 	{"compound where", `eq(event,"stuff") OR ge(party, 1)`, noError, `eq(event, "stuff") OR ge(party, 1)`},
 	{"general parse test", `item * 5`, noError, `item * 5`},
+	{"reserved word identity quoted", "`select` * 5", noError, "`select` * 5"},
+	{"identity with space quoted", "`first name` == \"bob\"", noError, "`first name` == \"bob\""},
 	{"general parse test", `eq(toint(item),5)`, noError, `eq(toint(item), 5)`},
 	{"general parse test", `eq(5,5)`, noError, `eq(5, 5)`},
 	{"general parse test", `oneof("1",item,4)`, noError, `oneof("1", item, 4)`},
 	{"general parse test", `toint("1")`, noError, `toint("1")`},
+	{"map subscript", `tags['env'] == "prod"`, noError, `tags["env"] == "prod"`},
+	{"nested map subscript", `doc["a"]["b"] > 2`, noError, `doc["a"]["b"] > 2`},
+	{"array subscript", `arr[0] == "a"`, noError, `arr[0] == "a"`},
+	{"array subscript from end", `arr[-1]`, noError, `arr[-1]`},
+}
+
+func TestNumberValue(t *testing.T) {
+	tests := []struct {
+		text string
+		v    value.Value
+	}{
+		{"5", value.NewIntValue(5)},
+		{"-12", value.NewIntValue(-12)},
+		{"0x10", value.NewIntValue(16)},
+		{"2.5", value.NewNumberValue(2.5)},
+		{"-0.25", value.NewNumberValue(-0.25)},
+		// floats with integer values are promoted to int
+		{"2.0", value.NewIntValue(2)},
+		{"1e3", value.NewIntValue(1000)},
+	}
+	for _, test := range tests {
+		n, err := expr.NewNumber(0, test.text)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", test.text, err)
+			continue
+		}
+		v := n.Value()
+		if v.Type() != test.v.Type() || v.Value() != test.v.Value() {
+			t.Errorf("%q want %T(%v) got %T(%v)", test.text, test.v, test.v.Value(), v, v.Value())
+		}
+	}
+	// ints are also promoted to float, but Value() remains an int
+	n, _ := expr.NewNumber(0, "7")
+	if !n.IsFloat || n.Float64 != 7 {
+		t.Errorf("expected int promoted to float: %#v", n)
+	}
+	if _, isInt := n.Value().(value.IntValue); !isInt {
+		t.Errorf("expected IntValue got %T", n.Value())
+	}
+}
+
+func TestStringTypedValue(t *testing.T) {
+	tests := []struct {
+		text  string
+		typed bool
+		vt    value.ValueType
+	}{
+		{"30", true, value.IntType},
+		{" -12 ", true, value.IntType},
+		{"1.5", true, value.NumberType},
+		{"true", true, value.BoolType},
+		{"FALSE", true, value.BoolType},
+		{"2015-01-02", true, value.TimeType},
+		{"2015-01-02T15:04:05Z", true, value.TimeType},
+		{"hello", false, value.StringType},
+		{"NaN", false, value.StringType},
+		{"", false, value.StringType},
+	}
+	for _, test := range tests {
+		sn := expr.NewStringNode(0, test.text)
+		v, typed := sn.TypedValue()
+		if typed != test.typed || v.Type() != test.vt {
+			t.Errorf("%q want %v typed=%v got %v typed=%v", test.text, test.vt, test.typed, v.Type(), typed)
+		}
+		if sn.StringAST() != fmt.Sprintf("%q", test.text) {
+			t.Errorf("StringAST should be unchanged: %s", sn.StringAST())
+		}
+		// the literal is parsed once, not per row
+		if allocs := testing.AllocsPerRun(10, func() { sn.TypedValue() }); allocs != 0 {
+			t.Errorf("%q TypedValue should be cached but allocs %v", test.text, allocs)
+		}
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		text   string
+		months int
+		d      time.Duration
+		ok     bool
+	}{
+		{"1 day", 0, 24 * time.Hour, true},
+		{"2 hours", 0, 2 * time.Hour, true},
+		{"1 hour 30 minutes", 0, 90 * time.Minute, true},
+		{"-1 week", 0, -7 * 24 * time.Hour, true},
+		{"1.5 seconds", 0, 1500 * time.Millisecond, true},
+		{"1h30m", 0, 90 * time.Minute, true},
+		{"1 month", 1, 0, true},
+		{"2 years 1 day", 24, 24 * time.Hour, true},
+		{"1.5 months", 0, 0, false},
+		{"1 fortnight", 0, 0, false},
+		{"day", 0, 0, false},
+	}
+	for _, test := range tests {
+		months, d, err := expr.ParseInterval(test.text)
+		if (err == nil) != test.ok {
+			t.Errorf("%q unexpected err=%v", test.text, err)
+			continue
+		}
+		if d != test.d || months != test.months {
+			t.Errorf("%q want %v months %v got %v months %v", test.text, test.months, test.d, months, d)
+		}
+	}
+}
+
+func TestIntervalNode(t *testing.T) {
+	tests := []struct {
+		qlText string
+		ast    string
+		months int
+		d      time.Duration
+	}{
+		{`INTERVAL "1 day"`, `INTERVAL "1 day"`, 0, 24 * time.Hour},
+		{`INTERVAL "1" DAY`, `INTERVAL 1 DAY`, 0, 24 * time.Hour},
+		{`INTERVAL 2 HOUR`, `INTERVAL 2 HOUR`, 0, 2 * time.Hour},
+		{`interval 90 minutes`, `INTERVAL 90 MINUTES`, 0, 90 * time.Minute},
+		{`INTERVAL 3 MONTH`, `INTERVAL 3 MONTH`, 3, 0},
+		{`INTERVAL "1" YEAR`, `INTERVAL 1 YEAR`, 12, 0},
+	}
+	for _, test := range tests {
+		exprTree, err := expr.ParseExpression(test.qlText)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.qlText, err)
+			continue
+		}
+		n, ok := exprTree.Root.(*expr.IntervalNode)
+		if !ok {
+			t.Errorf("%s: expected IntervalNode got %T", test.qlText, exprTree.Root)
+			continue
+		}
+		if n.Months != test.months || n.Duration != test.d {
+			t.Errorf("%s: want %d months %v got %d months %v", test.qlText, test.months, test.d, n.Months, n.Duration)
+		}
+		if n.StringAST() != test.ast {
+			t.Errorf("%s: want %s got %s", test.qlText, test.ast, n.StringAST())
+		}
+		// round trip
+		exprTree2, err := expr.ParseExpression(n.StringAST())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", n.StringAST(), err)
+			continue
+		}
+		n2 := exprTree2.Root.(*expr.IntervalNode)
+		if n2.StringAST() != n.StringAST() || n2.Months != n.Months || n2.Duration != n.Duration {
+			t.Errorf("round trip %s != %s", n2.StringAST(), n.StringAST())
+		}
+	}
+	jan31 := time.Date(2015, 1, 31, 0, 0, 0, 0, time.UTC)
+	n, _ := expr.NewIntervalNode(0, "1", "month")
+	if got := n.AddTo(jan31, 1); !got.Equal(time.Date(2015, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("calendar month add got %v", got)
+	}
+	if got := n.AddTo(jan31, -1); !got.Equal(time.Date(2014, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("calendar month subtract got %v", got)
+	}
 }
 
 func TestParseExpressions(t *testing.T) {
@@ -200,3 +364,178 @@ func TestParseExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestStringEscapesRoundTrip(t *testing.T) {
+	tests := []struct{ qlText, text string }{
+		{`eq(name, "it\'s")`, "it's"},
+		{`eq(name, "say \"hi\"")`, `say "hi"`},
+		{`eq(name, "line1\nline2")`, "line1\nline2"},
+		{`eq(name, "café \\ back")`, `café \ back`},
+		{`eq(name, "caf\u00e9")`, "café"},
+	}
+	for _, test := range tests {
+		exprTree, err := expr.ParseExpression(test.qlText)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.qlText, err)
+			continue
+		}
+		fn := exprTree.Root.(*expr.FuncNode)
+		sn := fn.Args[1].(*expr.StringNode)
+		if sn.Text != test.text {
+			t.Errorf("want %q got %q", test.text, sn.Text)
+		}
+		// StringAST must re-escape so it parses back to the same value
+		ast := exprTree.Root.StringAST()
+		exprTree2, err := expr.ParseExpression(ast)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", ast, err)
+			continue
+		}
+		sn2 := exprTree2.Root.(*expr.FuncNode).Args[1].(*expr.StringNode)
+		if sn2.Text != test.text {
+			t.Errorf("round trip %s want %q got %q", ast, test.text, sn2.Text)
+		}
+	}
+}
+
+func TestDollarQuotedJson(t *testing.T) {
+	blob := `{"name": "it's", "tags": ["a\"b", "c\\d"], "re": "^\\d+$", "n": 2}`
+	exprTree, err := expr.ParseExpression(`eq(doc, $$` + blob + `$$)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sn := exprTree.Root.(*expr.FuncNode).Args[1].(*expr.StringNode)
+	if sn.Text != blob {
+		t.Fatalf("want verbatim %s got %s", blob, sn.Text)
+	}
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(sn.Text), &doc); err != nil {
+		t.Fatalf("not valid json %s: %v", sn.Text, err)
+	}
+	if doc["name"] != "it's" || doc["re"] != `^\d+$` {
+		t.Errorf("json values: %v", doc)
+	}
+	// StringAST is a regular quoted string of the same value
+	exprTree2, err := expr.ParseExpression(exprTree.Root.StringAST())
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", exprTree.Root.StringAST(), err)
+	}
+	if sn2 := exprTree2.Root.(*expr.FuncNode).Args[1].(*expr.StringNode); sn2.Text != blob {
+		t.Errorf("round trip want %s got %s", blob, sn2.Text)
+	}
+
+	stmt, err := expr.ParseSql(`SELECT name FROM docs WHERE doc = $j$` + blob + `$j$`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	where := stmt.(*expr.SqlSelect).Where.Expr.(*expr.BinaryNode)
+	if where.Args[1].(*expr.StringNode).Text != blob {
+		t.Errorf("sql want verbatim %s got %s", blob, where.Args[1])
+	}
+}
+
+func TestMultiArgNodeEmpty(t *testing.T) {
+	// found by FuzzParseEval, a freshly constructed node has no args
+	n := expr.NewMultiArgNode(lex.Token{T: lex.TokenIN, V: "IN"})
+	if got := n.StringAST(); got != "IN ()" {
+		t.Errorf("want IN () got %s", got)
+	}
+	if got := n.String(); got != "IN ()" {
+		t.Errorf("want IN () got %s", got)
+	}
+	if err := n.Check(); err == nil {
+		t.Errorf("empty IN should not pass Check")
+	}
+	n.Append(expr.NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: "x"}))
+	if got := n.StringAST(); got != "x IN ()" {
+		t.Errorf("want x IN () got %s", got)
+	}
+	if err := n.Check(); err == nil {
+		t.Errorf("empty IN list should not pass Check")
+	}
+	n.Append(expr.NewStringNode(0, "a"))
+	if got := n.StringAST(); got != `x IN ("a")` {
+		t.Errorf(`want x IN ("a") got %s`, got)
+	}
+	if err := n.Check(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := expr.ParseExpression("x IN ()"); err == nil {
+		t.Errorf("x IN () expected error")
+	}
+	if _, err := expr.ParseExpression("x IN (1)"); err != nil {
+		t.Errorf("x IN (1) unexpected error: %v", err)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	// malformed expressions are errors, not panics or hangs
+	for _, qlText := range []string{
+		``, `;`, `NOT`, `(((`, `)`, `IN`, `AND OR`, `-`,
+		`tags[`, `true[],`, `str LIKE INTERVAL )`, "&\u039e",
+	} {
+		_, err := expr.ParseExpression(qlText)
+		if err == nil {
+			t.Errorf("%q: expected error", qlText)
+		}
+	}
+}
+
+func TestChainedComparison(t *testing.T) {
+	// the default is an error, pointing at the second comparison
+	_, err := expr.ParseExpression("1 < x < 10")
+	if err == nil || !strings.Contains(err.Error(), "position 6") {
+		t.Errorf("expected chained comparison error got %v", err)
+	}
+	for _, qlText := range []string{
+		"(1 < x) < 10",
+		"1 < x AND x < 10",
+		"1 < x = true",
+		"a = b = c",
+	} {
+		if _, err := expr.ParseExpression(qlText); err != nil {
+			t.Errorf("%s: unexpected error: %v", qlText, err)
+		}
+	}
+
+	// the rewrite is an option of the dialect, not of every parser
+	rewrite := *lex.LogicalExpressionDialect
+	rewrite.Chained = lex.ChainedRewrite
+	tests := []struct {
+		qlText string
+		ast    string
+	}{
+		{"1 < x < 10", "1 < x AND x < 10"},
+		{"1 <= x < 10", "1 <= x AND x < 10"},
+		{"10 > x >= y > 1", "10 > x AND x >= y AND y > 1"},
+		{"1 < x < 10 OR x > 20", "1 < x AND x < 10 OR x > 20"},
+		{"(1 < x) < 10", "(1 < x) < 10"},
+	}
+	for _, test := range tests {
+		exprTree, err := expr.ParseExpressionDialect(test.qlText, &rewrite)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.qlText, err)
+			continue
+		}
+		if got := exprTree.Root.StringAST(); got != test.ast {
+			t.Errorf("%s: want %s got %s", test.qlText, test.ast, got)
+		}
+	}
+	if _, err := expr.ParseExpression("1 < x < 10"); err == nil {
+		t.Errorf("expected the default dialect to still reject a chained comparison")
+	}
+
+	sqlRewrite := *lex.SqlDialect
+	sqlRewrite.Chained = lex.ChainedRewrite
+	stmt, err := expr.ParseSqlDialect("SELECT a FROM t WHERE 1 < x < 10", &sqlRewrite)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stmt.(*expr.SqlSelect).Where.Expr.String(); got != "1 < x AND x < 10" {
+		t.Errorf("want 1 < x AND x < 10 got %s", got)
+	}
+	if _, err := expr.ParseSql("SELECT a FROM t WHERE 1 < x < 10"); err == nil {
+		t.Errorf("expected the sql dialect to reject a chained comparison")
+	}
+}
@@ -6,16 +6,15 @@ import (
 	"reflect"
 	"strings"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
 var (
-	_ = u.EMPTY
-
 	// Ensure SqlSelect and cousins etc are NodeTypes as well as SqlStatements
 	_ SqlStatement    = (*SqlSelect)(nil)
+	_ ParsedNode      = (*SqlSelect)(nil)
 	_ SqlSubStatement = (*SqlSource)(nil)
 	//_ SqlSubStatement = (*Join)(nil)
 	_ Node = (*SqlWhere)(nil)
@@ -25,6 +24,11 @@ var (
 	_ SqlStatement = (*SqlUpsert)(nil)
 	_ SqlStatement = (*SqlUpdate)(nil)
 	_ SqlStatement = (*SqlDelete)(nil)
+
+	// statements validated/normalized by the parser once parsed
+	_ ParsedNode   = (*SqlInsert)(nil)
+	_ ParsedNode   = (*SqlUpdate)(nil)
+	_ ParsedNode   = (*SqlDelete)(nil)
 	_ SqlStatement = (*SqlShow)(nil)
 	_ SqlStatement = (*SqlDescribe)(nil)
 )
@@ -65,7 +69,52 @@ type SqlSelect struct {
 	OrderBy Columns
 	Limit   int
 	Offset  int
-	proj    *Projection // Projected fields
+	Hints   []*SqlHint   // Planner hints   SELECT /*+ USE_INDEX(users idx_name) */ ...
+	With    []*SqlSource // Common table expressions  WITH name AS (SELECT ...) SELECT ...
+	proj    *Projection  // Projected fields
+
+	rewritten bool // has Rewrite() run
+
+	// DISTINCT ON (cols), the first row (in the ORDER BY) of each distinct
+	//  value of the columns
+	DistinctOn Columns
+
+	// GROUP BY ROLLUP(a, b) or GROUPING SETS ((a, b), (a), ()) are grouping
+	//  levels as indexes of GroupBy, nil is the one level of all GroupBy
+	GroupingSets [][]int
+	Rollup       bool // GroupingSets are the ROLLUP of GroupBy
+
+	// UNION, EXCEPT, INTERSECT of this select with others, applied in
+	//  order (left associative), OrderBy and Limit are of the combined rows
+	SetOps []*SqlSetOp
+}
+
+// SqlSetOp is a set operation combining the rows of a select with the rows
+//  of Right, matching columns by position.   Without ALL the result rows
+//  are distinct, with ALL duplicates count (ie EXCEPT ALL removes one left
+//  row per matching right row)
+//
+//     SELECT user_id FROM users EXCEPT ALL SELECT user_id FROM orders
+type SqlSetOp struct {
+	Op    lex.TokenType // TokenUnion, TokenExcept, TokenIntersect
+	All   bool
+	Right *SqlSelect
+}
+
+func (m *SqlSetOp) String() string {
+	op := strings.ToUpper(m.Op.String())
+	if m.All {
+		op += " ALL"
+	}
+	return fmt.Sprintf("%s %s", op, m.Right.String())
+}
+
+// SqlHint is a planner hint from a comment of form
+//
+//     SELECT /*+ USE_INDEX(users idx_name) NO_CACHE */ name FROM users
+type SqlHint struct {
+	Name string   // Upper-cased name   USE_INDEX
+	Args []string // [users, idx_name]
 }
 
 // Source is a table name, sub-query, or join
@@ -80,6 +129,8 @@ type SqlSource struct {
 	LeftOrRight lex.TokenType      // Left, Right
 	JoinType    lex.TokenType      // INNER, OUTER
 	Source      *SqlSelect         // optional, Join or SubSelect statement
+	Func        *FuncNode          // optional, table valued func  FROM unnest(tags) AS t
+	Lateral     bool               // Source is LATERAL, may reference the preceding FROM item
 	JoinExpr    Node               // Join expression       x.y = q.y
 	cols        map[string]*Column // Un-aliased columns
 
@@ -103,10 +154,31 @@ type SqlWhere struct {
 
 type SqlInsert struct {
 	Pos
-	Columns Columns
-	Rows    [][]value.Value
-	Into    string
+	Columns         Columns
+	Rows            [][]value.Value
+	Into            string
+	Returning       Columns // columns of inserted rows to return
+	IgnoreConflicts bool    // ON CONFLICT DO NOTHING
+	ConflictKey     string  // ON CONFLICT (key), empty is first column
+}
+
+// DefaultValue is the value of a column given as DEFAULT in INSERT VALUES,
+//  it is filled from the source schema's column default
+//
+//     INSERT INTO users (name, created) VALUES ("bob", DEFAULT)
+type DefaultValue struct {
+	value.NilValue
+}
+
+// ParamValue is a ? placeholder in INSERT VALUES, replaced by the arg
+//  bound to it by BindParams
+//
+//     INSERT INTO users (name, created) VALUES (?, ?)
+type ParamValue struct {
+	value.NilValue
+	Param *ParamNode
 }
+
 type SqlUpsert struct {
 	Pos
 	Columns Columns
@@ -115,16 +187,19 @@ type SqlUpsert struct {
 }
 type SqlUpdate struct {
 	Pos
-	kw      lex.TokenType // Update, Upsert
-	Columns Columns
-	Where   Node
-	From    string
+	kw        lex.TokenType // Update, Upsert
+	Columns   Columns       // SET columns, the As is column name, Expr new value
+	Where     Node
+	From      string
+	Source    *SqlSource // optional joined source  UPDATE a SET x = b.y FROM b
+	Returning Columns    // columns of updated rows to return
 }
 type SqlDelete struct {
 	Pos
-	Table string
-	Where Node
-	Limit int
+	Table     string
+	Where     Node
+	Limit     int
+	Returning Columns // columns of deleted rows to return
 }
 type SqlShow struct {
 	Pos
@@ -229,7 +304,7 @@ func (m *Columns) FieldNames() []string {
 }
 func (m *Columns) ByName(name string) (*Column, bool) {
 	for _, col := range *m {
-		//u.Debugf("col.SourceField='%s' key()='%s' As='%s' ", col.SourceField, col.Key(), col.As)
+		//logging.Debugf("col.SourceField='%s' key()='%s' As='%s' ", col.SourceField, col.Key(), col.As)
 		if col.SourceField == name {
 			return col, true
 		}
@@ -253,14 +328,16 @@ type Column struct {
 	originalAs      string
 	left            string
 	right           string
-	Index           int    // Field Position Order in original query
-	SourceField     string // field name of underlying field
-	As              string // As field, auto-populate the Field Name if exists
-	Comment         string // optional in-line comments
-	Order           string // (ASC | DESC)
-	Star            bool   // If   just *
-	Expr            Node   // Expression, optional, often Identity.Node
-	Guard           Node   // If
+	Index           int     // Field Position Order in original query
+	SourceField     string  // field name of underlying field
+	As              string  // As field, auto-populate the Field Name if exists
+	Comment         string  // optional in-line comments
+	Order           string  // (ASC | DESC)
+	Nulls           string  // (FIRST | LAST) placement of nulls, empty for the default
+	Star            bool    // If   just *
+	Expr            Node    // Expression, optional, often Identity.Node
+	Guard           Node    // If
+	Over            *Window // OVER window of a window function column
 }
 
 func NewColumn(tok lex.Token) *Column {
@@ -281,14 +358,17 @@ func (m *Column) String() string {
 	if m.Expr != nil {
 		exprStr = m.Expr.StringAST()
 		buf.WriteString(exprStr)
-		//u.Debugf("has expr: %T %#v  str=%s=%s", m.Expr, m.Expr, m.Expr.StringAST(), exprStr)
+		//logging.Debugf("has expr: %T %#v  str=%s=%s", m.Expr, m.Expr, m.Expr.StringAST(), exprStr)
+	}
+	if m.Over != nil {
+		buf.WriteString(fmt.Sprintf(" OVER (%s)", m.Over))
 	}
 	if m.asQuoteByte != 0 && m.originalAs != "" {
 		as := string(m.asQuoteByte) + m.originalAs + string(m.asQuoteByte)
-		//u.Warnf("%s", as)
+		//logging.Warnf("%s", as)
 		buf.WriteString(fmt.Sprintf(" AS %v", as))
 	} else if m.originalAs != "" && exprStr != m.originalAs {
-		//u.Warnf("%s", m.originalAs)
+		//logging.Warnf("%s", m.originalAs)
 		buf.WriteString(fmt.Sprintf(" AS %v", m.originalAs))
 	}
 	if m.Guard != nil {
@@ -297,6 +377,9 @@ func (m *Column) String() string {
 	if m.Order != "" {
 		buf.WriteString(fmt.Sprintf(" %s", m.Order))
 	}
+	if m.Nulls != "" {
+		buf.WriteString(fmt.Sprintf(" NULLS %s", m.Nulls))
+	}
 	return buf.String()
 }
 
@@ -323,13 +406,13 @@ func (m *Column) RewriteFor(alias string) *Column {
 		originalAs:      right,
 	}
 	//Expr:            m.Expr,
-	//u.Warnf("in rewrite:  Alias:'%s'  '%s'.'%s'  sourcefield:'%v' ok?%v", alias, left, right, m.SourceField, ok)
+	//logging.Warnf("in rewrite:  Alias:'%s'  '%s'.'%s'  sourcefield:'%v' ok?%v", alias, left, right, m.SourceField, ok)
 	if left == alias {
 		newCol.SourceField = right
 		newCol.right = right
 	}
 	newCol.Expr = &IdentityNode{Text: right}
-	//u.Infof("%s", newCol.String())
+	//logging.Infof("%s", newCol.String())
 	return newCol
 }
 func (m *Column) Copy() *Column {
@@ -341,9 +424,11 @@ func (m *Column) Copy() *Column {
 		As:              m.right,
 		Comment:         m.Comment,
 		Order:           m.Order,
+		Nulls:           m.Nulls,
 		Star:            m.Star,
 		Expr:            m.Expr,
 		Guard:           m.Guard,
+		Over:            m.Over,
 	}
 }
 
@@ -362,6 +447,41 @@ func (m *Column) LeftRight() (string, string, bool) {
 	return m.left, m.right, m.left != ""
 }
 
+// Window is the OVER clause of a window function column, the function is
+//  evaluated over a frame of rows as they stream, so is a moving aggregate.
+//  With a PARTITION BY or ORDER BY the rows are buffered, each partition
+//  sorted and the function evaluated over it, ie a ROW_NUMBER() or RANK()
+//
+//     avg(x) OVER (ROWS 5 PRECEDING)
+//     sum(x) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+//     rank() OVER (PARTITION BY g ORDER BY score DESC)
+type Window struct {
+	PartitionBy Columns
+	OrderBy     Columns
+	// Preceding is the count of rows before the current row in the frame,
+	//  -1 is UNBOUNDED PRECEDING (the default, a running aggregate)
+	Preceding int
+}
+
+func NewWindow() *Window { return &Window{Preceding: -1} }
+
+// Buffered is true if the rows of the window are partitioned or sorted, so
+//  must all be read before the function is evaluated
+func (m *Window) Buffered() bool { return len(m.PartitionBy) > 0 || len(m.OrderBy) > 0 }
+func (m *Window) String() string {
+	parts := make([]string, 0, 3)
+	if len(m.PartitionBy) > 0 {
+		parts = append(parts, fmt.Sprintf("PARTITION BY %s", m.PartitionBy.String()))
+	}
+	if len(m.OrderBy) > 0 {
+		parts = append(parts, fmt.Sprintf("ORDER BY %s", m.OrderBy.String()))
+	}
+	if m.Preceding >= 0 {
+		parts = append(parts, fmt.Sprintf("ROWS %d PRECEDING", m.Preceding))
+	}
+	return strings.Join(parts, " ")
+}
+
 func (m *PreparedStatement) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitPreparedStmt(m)
 }
@@ -380,13 +500,40 @@ func (m *SqlSelect) Type() reflect.Value                         { return nilRv
 func (m *SqlSelect) StringAST() string                           { return m.String() }
 func (m *SqlSelect) String() string {
 	buf := bytes.Buffer{}
-	buf.WriteString(fmt.Sprintf("SELECT %s", m.Columns.String()))
+	for i, cte := range m.With {
+		if i == 0 {
+			buf.WriteString("WITH ")
+		} else {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(fmt.Sprintf("%s AS (%s)", cte.Alias, cte.Source.String()))
+	}
+	if len(m.With) > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("SELECT ")
+	if len(m.Hints) > 0 {
+		buf.WriteString("/*+")
+		for _, hint := range m.Hints {
+			buf.WriteByte(' ')
+			buf.WriteString(hint.String())
+		}
+		buf.WriteString(" */ ")
+	}
+	if len(m.DistinctOn) > 0 {
+		buf.WriteString(fmt.Sprintf("DISTINCT ON (%s) ", m.DistinctOn.String()))
+	}
+	buf.WriteString(m.Columns.String())
 	if m.Into != nil {
 		buf.WriteString(fmt.Sprintf(" INTO %v", m.Into))
 	}
 	if m.From != nil {
 		buf.WriteString(" FROM")
-		for _, from := range m.From {
+		for i, from := range m.From {
+			if i > 0 && (from.Func != nil || from.Lateral) {
+				// lateral table func   FROM users, unnest(tags) AS t
+				buf.WriteByte(',')
+			}
 			buf.WriteByte(' ')
 			buf.WriteString(from.StringAST())
 		}
@@ -394,12 +541,15 @@ func (m *SqlSelect) String() string {
 	if m.Where != nil {
 		buf.WriteString(fmt.Sprintf(" WHERE %s", m.Where.String()))
 	}
-	if m.GroupBy != nil {
-		buf.WriteString(fmt.Sprintf(" GROUP BY %s", m.GroupBy.String()))
+	if m.GroupBy != nil || m.GroupingSets != nil {
+		buf.WriteString(fmt.Sprintf(" GROUP BY %s", m.groupByString()))
 	}
 	if m.Having != nil {
 		buf.WriteString(fmt.Sprintf(" HAVING %s", m.Having.String()))
 	}
+	for _, op := range m.SetOps {
+		buf.WriteString(" " + op.String())
+	}
 	if m.OrderBy != nil {
 		buf.WriteString(fmt.Sprintf(" ORDER BY %s", m.OrderBy.String()))
 	}
@@ -409,6 +559,43 @@ func (m *SqlSelect) String() string {
 	return buf.String()
 }
 
+// the GROUP BY clause, ROLLUP() or GROUPING SETS if those were used
+func (m *SqlSelect) groupByString() string {
+	switch {
+	case m.Rollup:
+		return fmt.Sprintf("ROLLUP(%s)", m.GroupBy.String())
+	case m.GroupingSets != nil:
+		sets := make([]string, len(m.GroupingSets))
+		for i, set := range m.GroupingSets {
+			cols := make([]string, len(set))
+			for j, idx := range set {
+				cols[j] = m.GroupBy[idx].String()
+			}
+			sets[i] = "(" + strings.Join(cols, ", ") + ")"
+		}
+		return fmt.Sprintf("GROUPING SETS (%s)", strings.Join(sets, ", "))
+	}
+	return m.GroupBy.String()
+}
+
+// Hint returns the planner hint of this name (case-insensitive), nil if
+//  there is none
+func (m *SqlSelect) Hint(name string) *SqlHint {
+	for _, hint := range m.Hints {
+		if strings.EqualFold(hint.Name, name) {
+			return hint
+		}
+	}
+	return nil
+}
+
+func (m *SqlHint) String() string {
+	if len(m.Args) == 0 {
+		return m.Name
+	}
+	return fmt.Sprintf("%s(%s)", m.Name, strings.Join(m.Args, " "))
+}
+
 func (m *SqlSelect) Projection(p *Projection) *Projection {
 	if p != nil {
 		m.proj = p
@@ -426,10 +613,18 @@ func (m *SqlSelect) Projection(p *Projection) *Projection {
 // Finalize this Query plan by preparing sub-sources
 //  ie we need to rewrite some things into sub-statements
 //  - we need to share the join expression across sources
+//  - with more than one source, qualified columns  u.name  must refer
+//    to a source alias (or name)
 func (m *SqlSelect) Finalize() error {
+	if err := m.checkSetOps(); err != nil {
+		return err
+	}
 	if len(m.From) == 0 {
 		return nil
 	}
+	if err := m.checkAliases(); err != nil {
+		return err
+	}
 	// TODO:   This is invalid, as you can have more than one join on a table
 	exprs := make(map[string]Node)
 
@@ -441,21 +636,21 @@ func (m *SqlSelect) Finalize() error {
 		//left, right, ok := from.LeftRight()
 		if from.JoinExpr != nil {
 			left, right := from.findFromAliases()
-			//u.Debugf("from1:%v  from2:%v   joinexpr:  %v", left, right, from.JoinExpr.String())
+			//logging.Debugf("from1:%v  from2:%v   joinexpr:  %v", left, right, from.JoinExpr.String())
 			exprs[left] = from.JoinExpr
 			exprs[right] = from.JoinExpr
 		}
-		//u.Debugf("from.Alias:%v from.Name:%v  from:%#v", from.Alias, from.Name, from)
+		//logging.Debugf("from.Alias:%v from.Name:%v  from:%#v", from.Alias, from.Name, from)
 		//exprs[strings.ToLower(from.Alias)] = from.JoinExpr
 	}
 	// for name, expr := range exprs {
-	// 	u.Debugf("EXPR:   name: %v  expr:%v", name, expr.String())
+	// 	logging.Debugf("EXPR:   name: %v  expr:%v", name, expr.String())
 	// }
 	for _, from := range m.From {
 		if from.JoinExpr == nil {
-			//u.Debugf("from join nil?%v  %v", from.JoinExpr == nil, from)
+			//logging.Debugf("from join nil?%v  %v", from.JoinExpr == nil, from)
 			if expr, ok := exprs[from.alias]; ok {
-				//u.Warnf("NICE found: %#v", expr)
+				//logging.Warnf("NICE found: %#v", expr)
 				from.JoinExpr = expr
 			}
 		}
@@ -464,12 +659,133 @@ func (m *SqlSelect) Finalize() error {
 	return nil
 }
 
+// the selects of set operations must have the same number of columns, a
+//  select * is only known at runtime
+func (m *SqlSelect) checkSetOps() error {
+	for _, op := range m.SetOps {
+		// the INTERSECTs that bind tighter than a UNION or EXCEPT
+		if err := op.Right.checkSetOps(); err != nil {
+			return err
+		}
+		if m.Star || op.Right.Star {
+			continue
+		}
+		if len(op.Right.Columns) != len(m.Columns) {
+			return fmt.Errorf("each %s select must have the same number of columns: %d and %d",
+				strings.ToUpper(op.Op.String()), len(m.Columns), len(op.Right.Columns))
+		}
+	}
+	return nil
+}
+
+// the left side of every qualified identity must be a source, only checked
+//  for multiple sources as a single source may have nested fields  actor.login
+func (m *SqlSelect) checkAliases() error {
+	if len(m.From) < 2 {
+		return nil
+	}
+	aliases := make(map[string]bool)
+	for _, from := range m.From {
+		aliases[strings.ToLower(from.Alias)] = true
+		aliases[strings.ToLower(from.Name)] = true
+	}
+	// ORDER BY  AS  aliases of select columns
+	selectAliases := make(map[string]bool)
+	for _, col := range m.Columns {
+		if id, ok := col.Expr.(*IdentityNode); ok && id.Text == col.As {
+			continue
+		}
+		selectAliases[col.As] = true
+	}
+	check := func(n Node) error {
+		for _, name := range identityNames(n, nil) {
+			parts := strings.SplitN(name, ".", 2)
+			if len(parts) == 1 || selectAliases[name] {
+				continue
+			}
+			if !aliases[strings.ToLower(parts[0])] {
+				return fmt.Errorf("unknown source alias %q in %s", parts[0], name)
+			}
+		}
+		return nil
+	}
+	nodes := make([]Node, 0)
+	for _, col := range m.Columns {
+		nodes = append(nodes, col.Expr, col.Guard)
+	}
+	if m.Where != nil {
+		nodes = append(nodes, m.Where.Expr)
+	}
+	for _, from := range m.From {
+		nodes = append(nodes, from.JoinExpr)
+	}
+	for _, col := range m.GroupBy {
+		nodes = append(nodes, col.Expr)
+	}
+	for _, col := range m.OrderBy {
+		nodes = append(nodes, col.Expr)
+	}
+	nodes = append(nodes, m.Having)
+	for _, n := range nodes {
+		if err := check(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite normalizes a join statement for the planner, rewriting each source
+//  into the stand-alone query sent to its backend, once (calling it again
+//  is a no-op)
+//  - source aliases are resolved, the join expression shared (Finalize)
+//  - SELECT * is expanded to  SELECT *  of each source
+//  - columns qualified by a source alias  u.name  are projected by that
+//    source only, un-qualified columns by each, plus the join columns
+//  - the where is split into the sources it qualifies
+//
+//     SELECT * FROM users AS u INNER JOIN orders AS o ON u.id = o.user_id WHERE o.amt > 10
+//        u => SELECT * FROM users
+//        o => SELECT * FROM orders WHERE amt > 10
+//
+//  A single source is not rewritten to a sub-select, it is scanned with the
+//  statement as is, but its columns are normalized the same way, * expanded
+//  to the * of the source and each column qualified by the source alias
+//
+//     SELECT name, u.age FROM users AS u   =>  u.Columns  u.name, u.age
+func (m *SqlSelect) Rewrite() error {
+	if m.rewritten {
+		return nil
+	}
+	m.rewritten = true
+	if err := m.Finalize(); err != nil {
+		return err
+	}
+	// table funcs and lateral subqueries are evaluated per row, not joined
+	sources := make([]*SqlSource, 0, len(m.From))
+	for _, from := range m.From {
+		if from.Func == nil && !from.Lateral {
+			sources = append(sources, from)
+		}
+	}
+	if len(sources) == 1 && sources[0].Source == nil && sources[0].Name != "" {
+		sources[0].qualifyColumns(m)
+		return nil
+	}
+	if len(sources) < 2 {
+		return nil
+	}
+	for i, from := range sources {
+		from.Rewrite(i == 0, m)
+	}
+	return nil
+}
+
 func (m *SqlSelect) UnAliasedColumns() map[string]*Column {
 	cols := make(map[string]*Column)
-	//u.Infof("doing ALIAS: %v", len(m.Columns))
+	//logging.Infof("doing ALIAS: %v", len(m.Columns))
 	for _, col := range m.Columns {
 		_, right, ok := col.LeftRight()
-		//u.Debugf("aliasing: l:%v r:%v ok?%v", left, right, ok)
+		//logging.Debugf("aliasing: l:%v r:%v ok?%v", left, right, ok)
 		if ok {
 			cols[right] = col
 		} else {
@@ -479,6 +795,102 @@ func (m *SqlSelect) UnAliasedColumns() map[string]*Column {
 	return cols
 }
 
+// RequiredColumns is every source column referenced by this statement, in
+//  SELECT, WHERE, GROUP BY, HAVING, ORDER BY and join ON expressions, for
+//  column pushdown.   Deduped, in order of first reference.  If there is a
+//  single source un-qualified columns are qualified with its alias, with
+//  multiple sources they are returned as written.   References to select
+//  column aliases (ORDER BY total) and * are not included.
+//
+//     SELECT name, count(*) AS ct FROM users AS u WHERE age > 21 ORDER BY ct
+//        => [u.name, u.age]
+func (m *SqlSelect) RequiredColumns() []string {
+	alias := ""
+	if len(m.From) == 1 && m.From[0].Source == nil {
+		alias = m.From[0].Alias
+		if alias == "" {
+			alias = m.From[0].Name
+		}
+	}
+	// names that refer to a select column  AS alias,  not source columns
+	selectAliases := make(map[string]bool)
+	for _, col := range m.Columns {
+		if id, ok := col.Expr.(*IdentityNode); ok && id.Text == col.As {
+			continue
+		}
+		if col.As != "" {
+			selectAliases[col.As] = true
+		}
+	}
+	seen := make(map[string]bool)
+	cols := make([]string, 0)
+	add := func(n Node, allowAliases bool) {
+		for _, name := range identityNames(n, nil) {
+			if !allowAliases && selectAliases[name] {
+				continue
+			}
+			if alias != "" && !strings.Contains(name, ".") {
+				name = alias + "." + name
+			}
+			if !seen[name] {
+				seen[name] = true
+				cols = append(cols, name)
+			}
+		}
+	}
+	for _, col := range m.Columns {
+		add(col.Expr, true)
+		add(col.Guard, true)
+	}
+	if m.Where != nil {
+		add(m.Where.Expr, true)
+	}
+	for _, col := range m.GroupBy {
+		add(col.Expr, false)
+	}
+	add(m.Having, false)
+	for _, col := range m.OrderBy {
+		add(col.Expr, false)
+	}
+	for _, from := range m.From {
+		add(from.JoinExpr, true)
+	}
+	return cols
+}
+
+// identityNames appends the names of all identities (not boolean literals)
+//  in the node tree
+func identityNames(n Node, names []string) []string {
+	switch nt := n.(type) {
+	case *IdentityNode:
+		if !nt.IsBooleanIdentity() {
+			names = append(names, nt.Text)
+		}
+	case *FuncNode:
+		for _, arg := range nt.Args {
+			names = identityNames(arg, names)
+		}
+	case *BinaryNode:
+		names = identityNames(nt.Args[0], names)
+		names = identityNames(nt.Args[1], names)
+	case *UnaryNode:
+		names = identityNames(nt.Arg, names)
+	case *TriNode:
+		for _, arg := range nt.Args {
+			names = identityNames(arg, names)
+		}
+	case *MultiArgNode:
+		for _, arg := range nt.Args {
+			names = identityNames(arg, names)
+		}
+	case *RowConstructorNode:
+		for _, arg := range nt.Args {
+			names = identityNames(arg, names)
+		}
+	}
+	return names
+}
+
 func (m *SqlSelect) AddColumn(colArg Column) error {
 	col := &colArg
 	//curCol := m.ColumnsAsMap[col.As]
@@ -489,10 +901,10 @@ func (m *SqlSelect) AddColumn(colArg Column) error {
 	m.Columns = append(m.Columns, col)
 
 	if col.As == "" {
-		u.Errorf("no as on col, is required?  %#s", col)
+		logging.Errorf("no as on col, is required?  %#s", col)
 	}
 	//m.ColumnsAsMap[col.As] = col
-	//u.Infof("added col: %p %#v", col, col)
+	//logging.Infof("added col: %p %#v", col, col)
 	return nil
 }
 
@@ -543,15 +955,34 @@ func (m *SqlSource) StringAST() string                              { return m.S
 func (m *SqlSource) String() string {
 
 	if int(m.Op) == 0 && int(m.LeftOrRight) == 0 && int(m.JoinType) == 0 {
+		if m.Func != nil {
+			if m.Alias != "" {
+				return fmt.Sprintf("%s AS %v", m.Func.String(), m.Alias)
+			}
+			return m.Func.String()
+		}
+		if m.Lateral {
+			if m.Alias != "" {
+				return fmt.Sprintf("LATERAL (%s) AS %v", m.Source.String(), m.Alias)
+			}
+			return fmt.Sprintf("LATERAL (%s)", m.Source.String())
+		}
+		if m.Source != nil && m.Name == "" {
+			// derived table:   (SELECT ...) AS t
+			if m.Alias != "" {
+				return fmt.Sprintf("(%s) AS %v", m.Source.String(), m.Alias)
+			}
+			return fmt.Sprintf("(%s)", m.Source.String())
+		}
 		if m.Alias != "" {
 			return fmt.Sprintf("%s AS %v", m.Name, m.Alias)
 		}
 		return m.Name
 	}
 	buf := bytes.Buffer{}
-	//u.Warnf("op:%d leftright:%d jointype:%d", m.Op, m.LeftRight, m.JoinType)
-	//u.Warnf("op:%s leftright:%s jointype:%s", m.Op, m.LeftRight, m.JoinType)
-	//u.Infof("%#v", m)
+	//logging.Warnf("op:%d leftright:%d jointype:%d", m.Op, m.LeftRight, m.JoinType)
+	//logging.Warnf("op:%s leftright:%s jointype:%s", m.Op, m.LeftRight, m.JoinType)
+	//logging.Infof("%#v", m)
 	//   Jointype                Op
 	//  INNER JOIN orders AS o 	ON
 	if int(m.JoinType) != 0 {
@@ -568,13 +999,13 @@ func (m *SqlSource) String() string {
 	buf.WriteByte(' ')
 	buf.WriteString(strings.ToTitle(m.Op.String()))
 
-	//u.Warnf("JoinExpr? %#v", m.JoinExpr)
+	//logging.Warnf("JoinExpr? %#v", m.JoinExpr)
 	if m.JoinExpr != nil {
 		buf.WriteByte(' ')
 		buf.WriteString(m.JoinExpr.String())
 		//buf.WriteByte(' ')
 	}
-	//u.Warnf("source? %#v", m.Source)
+	//logging.Warnf("source? %#v", m.Source)
 	// if m.Source != nil {
 	// 	buf.WriteString(m.Source.String())
 	// }
@@ -593,21 +1024,23 @@ func (m *SqlSource) Rewrite(isLeft bool, fullStmt *SqlSelect) *SqlSelect {
 	//   4)  if we need different sort for our join algo?
 
 	if fullStmt.Star {
+		// the join columns are already in *
 		m.Star = true
+		m.Columns = Columns{&Column{Star: true}}
 	} else {
 		m.Columns = make(Columns, 0)
 		for _, col := range fullStmt.Columns {
 			left, _, ok := col.LeftRight()
-			//u.Infof("col: P:%p ok?%v %#v", col, ok, col)
+			//logging.Infof("col: P:%p ok?%v %#v", col, ok, col)
 			if !ok {
 				// Was not left/right qualified, so use as is
-				//u.Debugf("Copy col: %#v", col)
+				//logging.Debugf("Copy col: %#v", col)
 				newCol := col.Copy()
 				newCol.Index = len(m.Columns)
 				m.Columns = append(m.Columns, newCol)
 
 			} else if ok && left == m.Alias {
-				//u.Debugf("RewriteFor: %v  P:%p %#v", m.Alias, col, col)
+				//logging.Debugf("RewriteFor: %v  P:%p %#v", m.Alias, col, col)
 				newCol := col.RewriteFor(m.Alias)
 				// Now Rewrite the Join Expression
 				n := rewriteNode(m, isLeft, col.Expr)
@@ -617,10 +1050,10 @@ func (m *SqlSource) Rewrite(isLeft bool, fullStmt *SqlSelect) *SqlSelect {
 				newCol.Index = len(m.Columns)
 				m.Columns = append(m.Columns, newCol)
 
-				//u.Debugf("appending col: %#v", newCol)
+				//logging.Debugf("appending col: %#v", newCol)
 			} else {
 				// not used in this source
-				//u.Debugf("sub-query does not use this parent col?  FROM %v  col:%v", m.Name, col.As)
+				//logging.Debugf("sub-query does not use this parent col?  FROM %v  col:%v", m.Name, col.As)
 			}
 		}
 	}
@@ -629,24 +1062,57 @@ func (m *SqlSource) Rewrite(isLeft bool, fullStmt *SqlSelect) *SqlSelect {
 	//  - rewrite the Sort
 	sql2 := &SqlSelect{Columns: m.Columns, Star: m.Star}
 	sql2.From = append(sql2.From, &SqlSource{Name: m.Name})
-	//u.Debugf("colsFromNode? left?%v joinExpr:%#v  %#v", isLeft, m.JoinExpr, sql2.Columns)
-	sql2.Columns = columnsFromNode(m, isLeft, m.JoinExpr, sql2.Columns)
-	//u.Debugf("cols len: %v", len(sql2.Columns))
+	//logging.Debugf("colsFromNode? left?%v joinExpr:%#v  %#v", isLeft, m.JoinExpr, sql2.Columns)
+	if !m.Star {
+		sql2.Columns = columnsFromNode(m, isLeft, m.JoinExpr, sql2.Columns)
+	}
+	//logging.Debugf("cols len: %v", len(sql2.Columns))
 	if fullStmt.Where != nil {
 		node := rewriteWhere(fullStmt, m, fullStmt.Where.Expr)
 		if node != nil {
-			//u.Warnf("node string():  %v", node.String())
+			//logging.Warnf("node string():  %v", node.String())
 			sql2.Where = &SqlWhere{Expr: node}
 		}
-		//u.Warnf("new where node:   %#v", node)
+		//logging.Warnf("new where node:   %#v", node)
 	}
 	m.Source = sql2
-	//u.Infof("going to unaliase: #cols=%v %#v", len(sql2.Columns), sql2.Columns)
+	//logging.Infof("going to unaliase: #cols=%v %#v", len(sql2.Columns), sql2.Columns)
 	m.cols = sql2.UnAliasedColumns()
-	//u.Infof("after aliasing: %#v", m.cols)
+	//logging.Infof("after aliasing: %#v", m.cols)
 	return sql2
 }
 
+// the columns of the single source of @stmt, qualified by its alias,
+//  columns qualified by another alias (an outer query) are not its
+func (m *SqlSource) qualifyColumns(stmt *SqlSelect) {
+	if stmt.Star {
+		m.Star = true
+		m.Columns = Columns{&Column{Star: true}}
+		return
+	}
+	m.Columns = make(Columns, 0, len(stmt.Columns))
+	for _, col := range stmt.Columns {
+		newCol := col.Copy()
+		newCol.As = col.As
+		if in, ok := col.Expr.(*IdentityNode); ok {
+			field := in.Text
+			if left, right, ok := in.LeftRight(); ok {
+				if strings.ToLower(left) != m.alias {
+					continue
+				}
+				field = right
+			}
+			newCol.SourceField = field
+			newCol.Expr = &IdentityNode{Text: m.alias + "." + field}
+			if col.As != field {
+				newCol.originalAs = col.As
+			}
+		}
+		newCol.Index = len(m.Columns)
+		m.Columns = append(m.Columns, newCol)
+	}
+}
+
 func (m *SqlSource) findFromAliases() (string, string) {
 	from1, from2 := m.alias, ""
 	if m.JoinExpr != nil {
@@ -663,7 +1129,7 @@ func (m *SqlSource) findFromAliases() (string, string) {
 				}
 			}
 		default:
-			u.Warnf("%T node types are not suppored yet for join rewrite", m.JoinExpr)
+			logging.Warnf("%T node types are not suppored yet for join rewrite", m.JoinExpr)
 		}
 	}
 	return from1, from2
@@ -673,21 +1139,21 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node Node) Node {
 	switch nt := node.(type) {
 	case *IdentityNode:
 		if left, right, ok := nt.LeftRight(); ok {
-			//u.Debugf("rewriteWhere  from.Name:%v l:%v  r:%v", from.alias, left, right)
+			//logging.Debugf("rewriteWhere  from.Name:%v l:%v  r:%v", from.alias, left, right)
 			if left == from.alias {
 				in := IdentityNode{Text: right}
-				//u.Warnf("nice, found it! in = %v", in)
+				//logging.Warnf("nice, found it! in = %v", in)
 				return &in
 			} else {
-				//u.Warnf("what to do? source:%v    %v", from.alias, nt.String())
+				//logging.Warnf("what to do? source:%v    %v", from.alias, nt.String())
 			}
 		} else {
-			//u.Warnf("dropping where: %#v", nt)
+			//logging.Warnf("dropping where: %#v", nt)
 		}
-	case *NumberNode, *NullNode, *StringNode:
+	case *NumberNode, *NullNode, *StringNode, *IntervalNode:
 		return nt
 	case *BinaryNode:
-		//u.Infof("binaryNode  T:%v", nt.Operator.T.String())
+		//logging.Infof("binaryNode  T:%v", nt.Operator.T.String())
 		switch nt.Operator.T {
 		case lex.TokenAnd, lex.TokenLogicAnd, lex.TokenLogicOr:
 			n1 := rewriteWhere(stmt, from, nt.Args[0])
@@ -700,12 +1166,13 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node Node) Node {
 			} else if n2 != nil {
 				return n2
 			} else {
-				//u.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
+				//logging.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
 			}
-		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenGT, lex.TokenGE, lex.TokenLE, lex.TokenNE:
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenGT, lex.TokenGE, lex.TokenLE, lex.TokenNE,
+			lex.TokenIs, lex.TokenIsNot:
 			n1 := rewriteWhere(stmt, from, nt.Args[0])
 			n2 := rewriteWhere(stmt, from, nt.Args[1])
-			//u.Debugf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
+			//logging.Debugf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
 			if n1 != nil && n2 != nil {
 				return &BinaryNode{Operator: nt.Operator, Args: [2]Node{n1, n2}}
 				// } else if n1 != nil {
@@ -713,13 +1180,13 @@ func rewriteWhere(stmt *SqlSelect, from *SqlSource, node Node) Node {
 				// } else if n2 != nil {
 				// 	return n2
 			} else {
-				//u.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
+				//logging.Warnf("n1=%#v  n2=%#v    %#v", n1, n2, nt)
 			}
 		default:
-			u.Warnf("un-implemented op: %#v", nt)
+			logging.Warnf("un-implemented op: %#v", nt)
 		}
 	default:
-		u.Warnf("%T node types are not suppored yet for where rewrite", node)
+		logging.Warnf("%T node types are not suppored yet for where rewrite", node)
 	}
 	return nil
 }
@@ -730,27 +1197,27 @@ func columnsFromNode(from *SqlSource, isLeft bool, node Node, cols Columns) Colu
 	switch nt := node.(type) {
 	case *IdentityNode:
 		if left, right, ok := nt.LeftRight(); ok {
-			//u.Debugf("from.Name:%v AS %v   Joinnode l:%v  r:%v    %#v", from.Name, from.alias, left, right, nt)
-			//u.Warnf("check cols against join expr arg: %#v", nt)
+			//logging.Debugf("from.Name:%v AS %v   Joinnode l:%v  r:%v    %#v", from.Name, from.alias, left, right, nt)
+			//logging.Warnf("check cols against join expr arg: %#v", nt)
 			if left == from.alias {
 				found := false
 				for _, col := range cols {
 					colLeft, colRight, _ := col.LeftRight()
-					//u.Debugf("left='%s'  colLeft='%s' right='%s'  %#v", left, colLeft, colRight,  col)
-					//u.Debugf("col:  From %s AS '%s'   '%s'.'%s'  JoinExpr: '%v'.'%v' col:%#v", from.Name, from.alias, colLeft, colRight, left, right, col)
+					//logging.Debugf("left='%s'  colLeft='%s' right='%s'  %#v", left, colLeft, colRight,  col)
+					//logging.Debugf("col:  From %s AS '%s'   '%s'.'%s'  JoinExpr: '%v'.'%v' col:%#v", from.Name, from.alias, colLeft, colRight, left, right, col)
 					if left == colLeft || colRight == right {
 						found = true
-						//u.Infof("columnsFromNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
+						//logging.Infof("columnsFromNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
 					} else {
-						//u.Warnf("not?   isLeft?%v from.Name:%v l:%v  r:%v   col: P:%p %#v", isLeft, from.alias, left, right, col, col)
+						//logging.Warnf("not?   isLeft?%v from.Name:%v l:%v  r:%v   col: P:%p %#v", isLeft, from.alias, left, right, col, col)
 					}
 				}
 				if !found {
-					//u.Debugf("columnsFromNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
+					//logging.Debugf("columnsFromNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
 					newCol := &Column{As: right, SourceField: right, Expr: &IdentityNode{Text: right}}
 					newCol.Index = len(cols)
 					cols = append(cols, newCol)
-					//u.Warnf("sure we want to add?, found it! %s len(cols) = %v", right, len(cols))
+					//logging.Warnf("sure we want to add?, found it! %s len(cols) = %v", right, len(cols))
 				}
 			}
 		}
@@ -763,11 +1230,11 @@ func columnsFromNode(from *SqlSource, isLeft bool, node Node, cols Columns) Colu
 			cols = columnsFromNode(from, isLeft, nt.Args[0], cols)
 			cols = columnsFromNode(from, isLeft, nt.Args[1], cols)
 		default:
-			u.Warnf("un-implemented op: %v", nt.Operator)
+			logging.Warnf("un-implemented op: %v", nt.Operator)
 		}
 	default:
-		u.LogTracef(u.INFO, "whoops")
-		u.Warnf("%T node types are not suppored yet for join rewrite %s", node, from.String())
+		logging.Infof("whoops")
+		logging.Warnf("%T node types are not suppored yet for join rewrite %s", node, from.String())
 	}
 	return cols
 }
@@ -776,10 +1243,10 @@ func rewriteNode(from *SqlSource, isLeft bool, node Node) Node {
 	switch nt := node.(type) {
 	case *IdentityNode:
 		if left, right, ok := nt.LeftRight(); ok {
-			//u.Debugf("rewriteNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
+			//logging.Debugf("rewriteNode   isLeft?%v from.Name:%v l:%v  r:%v", isLeft, from.alias, left, right)
 			if left == from.alias {
 				in := IdentityNode{Text: right}
-				//u.Warnf("nice, found it! in = %v", in)
+				//logging.Warnf("nice, found it! in = %v", in)
 				return &in
 			}
 		}
@@ -798,12 +1265,12 @@ func rewriteNode(from *SqlSource, isLeft bool, node Node) Node {
 			if n != nil {
 				return n
 			}
-			u.Warnf("Could not find node: %#v", node)
+			logging.Warnf("Could not find node: %#v", node)
 		default:
-			u.Warnf("un-implemented op: %v", nt.Operator)
+			logging.Warnf("un-implemented op: %v", nt.Operator)
 		}
 	default:
-		u.Warnf("%T node types are not suppored yet for join rewrite", node)
+		logging.Warnf("%T node types are not suppored yet for join rewrite", node)
 	}
 	return nil
 }
@@ -812,10 +1279,10 @@ func rewriteNode(from *SqlSource, isLeft bool, node Node) Node {
 func (m *SqlSource) UnAliasedColumns() map[string]*Column {
 	return m.cols
 	cols := make(map[string]*Column)
-	//u.Infof("doing ALIAS: %v", len(m.Columns))
+	//logging.Infof("doing ALIAS: %v", len(m.Columns))
 	for _, col := range m.Columns {
 		left, right, ok := col.LeftRight()
-		//u.Debugf("aliasing: l:%v r:%v ok?%v", left, right, ok)
+		//logging.Debugf("aliasing: l:%v r:%v ok?%v", left, right, ok)
 		if ok {
 			cols[right] = col
 		} else {
@@ -846,14 +1313,14 @@ func (m *SqlSource) UnAliasedColumns() map[string]*Column {
 //
 func (m *SqlSource) JoinValueExpr() (Node, error) {
 
-	//u.Debugf("alias:%v get JoinExpr: T:%T v:%#v", m.alias, m.JoinExpr, m.JoinExpr)
-	//u.Debugf("source: T:%T  v:%#v", m, m)
+	//logging.Debugf("alias:%v get JoinExpr: T:%T v:%#v", m.alias, m.JoinExpr, m.JoinExpr)
+	//logging.Debugf("source: T:%T  v:%#v", m, m)
 	bn, ok := m.JoinExpr.(*BinaryNode)
 	if !ok {
 		return nil, fmt.Errorf("Could not evaluate node %v", m.JoinExpr.String())
 	}
 	if bn.IsSimple() {
-		//u.Debugf("is simple binary node: %v", bn.Operator.T.String())
+		//logging.Debugf("is simple binary node: %v", bn.Operator.T.String())
 		for _, arg := range bn.Args {
 			switch n := arg.(type) {
 			case *IdentityNode:
@@ -861,10 +1328,10 @@ func (m *SqlSource) JoinValueExpr() (Node, error) {
 				if ok {
 					if left == m.alias && right != "" {
 						// this is correct node
-						//u.Warnf("NICE, found: %v     right=%v", n.String(), right)
+						//logging.Warnf("NICE, found: %v     right=%v", n.String(), right)
 						return &IdentityNode{Text: right}, nil
 					} else if left == m.alias && right == "" {
-						//u.Warnf("NICE2, found: %v     right=%v", n.String(), right)
+						//logging.Warnf("NICE2, found: %v     right=%v", n.String(), right)
 					}
 				}
 			}
@@ -879,7 +1346,7 @@ func (m *SqlSource) Finalize() error {
 	if m.alias == "" {
 		m.alias = strings.ToLower(m.Name)
 	}
-	//u.Warnf("finalize sqlsource: %v", len(m.Columns))
+	//logging.Warnf("finalize sqlsource: %v", len(m.Columns))
 	return nil
 }
 
@@ -895,7 +1362,7 @@ func (m *SqlWhere) StringAST() string {
 	if int(m.Op) != 0 && m.Source != nil {
 		return fmt.Sprintf("%s (%s)", m.Op.String(), m.Source.StringAST())
 	}
-	u.Warnf("what is this? %#v", m)
+	logging.Warnf("what is this? %#v", m)
 	return ""
 }
 func (m *SqlWhere) String() string { return m.StringAST() }
@@ -916,6 +1383,35 @@ func (m *Join) NodeType() NodeType                             { return SqlJoinN
 func (m *Join) StringAST() string                              { return m.String() }
 func (m *Join) String() string                                 { return fmt.Sprintf("%s", m.Table) }
 */
+// Finalize validates the values of each row and the ON CONFLICT key
+//  against the columns
+func (m *SqlInsert) Finalize() error {
+	if len(m.Columns) == 0 {
+		return checkReturning(m.Returning)
+	}
+	for i, row := range m.Rows {
+		if len(row) != len(m.Columns) {
+			return fmt.Errorf("insert row %d has %d values, expected %d columns", i+1, len(row), len(m.Columns))
+		}
+	}
+	if m.ConflictKey != "" {
+		if _, ok := m.Columns.ByName(m.ConflictKey); !ok {
+			return fmt.Errorf("conflict key %q is not an insert column", m.ConflictKey)
+		}
+	}
+	return checkReturning(m.Returning)
+}
+
+// RETURNING is evaluated per row, so may not aggregate
+func checkReturning(cols Columns) error {
+	for _, col := range cols {
+		if fn, ok := col.Expr.(*FuncNode); ok && fn.IsAggregate() {
+			return fmt.Errorf("aggregate %s not allowed in RETURNING", fn.Name)
+		}
+	}
+	return nil
+}
+
 func (m *SqlInsert) Keyword() lex.TokenType                      { return lex.TokenInsert }
 func (m *SqlInsert) Check() error                                { return nil }
 func (m *SqlInsert) Type() reflect.Value                         { return nilRv }
@@ -932,6 +1428,19 @@ func (m *SqlUpsert) StringAST() string                           { return fmt.Sp
 func (m *SqlUpsert) String() string                              { return fmt.Sprintf("%s ", m.Keyword()) }
 func (m *SqlUpsert) Accept(visitor Visitor) (interface{}, error) { return visitor.VisitUpsert(m) }
 
+// Finalize validates a column is SET only once
+func (m *SqlUpdate) Finalize() error {
+	set := make(map[string]bool, len(m.Columns))
+	for _, col := range m.Columns {
+		name := strings.ToLower(col.As)
+		if set[name] {
+			return fmt.Errorf("column %s is SET more than once", col.As)
+		}
+		set[name] = true
+	}
+	return checkReturning(m.Returning)
+}
+
 func (m *SqlUpdate) Keyword() lex.TokenType                      { return m.kw }
 func (m *SqlUpdate) Check() error                                { return nil }
 func (m *SqlUpdate) Type() reflect.Value                         { return nilRv }
@@ -940,6 +1449,9 @@ func (m *SqlUpdate) StringAST() string                           { return fmt.Sp
 func (m *SqlUpdate) String() string                              { return fmt.Sprintf("%s ", m.Keyword()) }
 func (m *SqlUpdate) Accept(visitor Visitor) (interface{}, error) { return visitor.VisitUpdate(m) }
 
+// Finalize validates the RETURNING columns
+func (m *SqlDelete) Finalize() error { return checkReturning(m.Returning) }
+
 func (m *SqlDelete) Keyword() lex.TokenType                      { return lex.TokenDelete }
 func (m *SqlDelete) Check() error                                { return nil }
 func (m *SqlDelete) Type() reflect.Value                         { return nilRv }
@@ -211,11 +211,60 @@ func TestSqlRewrite(t *testing.T) {
 	rw1 = sql.From[1].Rewrite(false, sql)
 	assert.Tf(t, rw0 != nil, "should not be nil:")
 	assert.Tf(t, len(rw0.Columns) == 2, "has 2 cols: %v", rw0.String())
-	assert.Tf(t, rw0.String() == "SELECT title, author FROM article WHERE email != NULL", "Wrong SQL 0: %v", rw0.String())
+	assert.Tf(t, rw0.String() == "SELECT title, author FROM article WHERE email IS NOT NULL", "Wrong SQL 0: %v", rw0.String())
 	assert.Tf(t, rw1 != nil, "should not be nil:")
 	assert.Tf(t, len(rw1.Columns) == 2, "has 2 cols: %v", rw1.Columns.String())
 	assert.Tf(t, rw1.String() == "SELECT actor, repository.name FROM github_push WHERE follow_ct > 20", "Wrong SQL 1: %v", rw1.String())
 
 	// Original should still be the same
-	assert.Tf(t, sql.String() == "SELECT p.actor, p.repository.name, a.title FROM article AS a INNER JOIN github_push AS p ON p.actor = a.author WHERE p.follow_ct > 20 AND a.email != NULL", "Wrong Full SQL?: '%v'", sql.String())
+	assert.Tf(t, sql.String() == "SELECT p.actor, p.repository.name, a.title FROM article AS a INNER JOIN github_push AS p ON p.actor = a.author WHERE p.follow_ct > 20 AND a.email IS NOT NULL", "Wrong Full SQL?: '%v'", sql.String())
+}
+
+func TestSqlSelectRewrite(t *testing.T) {
+	s := `SELECT * FROM users AS u INNER JOIN orders AS o ON u.user_id = o.user_id WHERE o.amount > 10`
+	sql := parseOrPanic(t, s).(*SqlSelect)
+	assert.T(t, sql.Rewrite() == nil)
+	assert.Tf(t, sql.From[0].Source != nil && sql.From[1].Source != nil, "sources rewritten")
+	assert.Tf(t, sql.From[0].Source.String() == "SELECT * FROM users", "%v", sql.From[0].Source)
+	assert.Tf(t, sql.From[1].Source.String() == "SELECT * FROM orders WHERE amount > 10", "%v", sql.From[1].Source)
+	assert.Tf(t, sql.From[0].Star && sql.From[1].Star, "star expanded to each source")
+	// the join expression is shared by both sources
+	assert.Tf(t, sql.From[0].JoinExpr == sql.From[1].JoinExpr, "join %v", sql.From[0].JoinExpr)
+
+	// only once
+	rw0 := sql.From[0].Source
+	assert.T(t, sql.Rewrite() == nil)
+	assert.T(t, sql.From[0].Source == rw0)
+	assert.Equal(t, s, sql.String())
+
+	sql = parseOrPanic(t, `SELECT u.name, o.amount FROM users AS u
+		INNER JOIN orders AS o ON u.user_id = o.user_id`).(*SqlSelect)
+	assert.T(t, sql.Rewrite() == nil)
+	assert.Tf(t, sql.From[0].Source.String() == "SELECT name, user_id FROM users", "%v", sql.From[0].Source)
+	assert.Tf(t, sql.From[1].Source.String() == "SELECT amount, user_id FROM orders", "%v", sql.From[1].Source)
+
+	// single source is not rewritten to a sub-select, its columns are
+	//  normalized, * expanded and columns qualified
+	s = `SELECT * FROM users`
+	sql = parseOrPanic(t, s).(*SqlSelect)
+	assert.T(t, sql.Rewrite() == nil)
+	assert.T(t, sql.From[0].Source == nil)
+	assert.Tf(t, sql.From[0].Star, "star expanded to the source")
+	assert.Tf(t, sql.From[0].Columns.String() == "*", "%v", sql.From[0].Columns)
+	assert.Equal(t, s, sql.String())
+
+	s = `SELECT name, u.age, lower(city) AS c FROM users AS u WHERE age > 21`
+	sql = parseOrPanic(t, s).(*SqlSelect)
+	assert.T(t, sql.Rewrite() == nil)
+	assert.T(t, sql.From[0].Source == nil)
+	assert.Tf(t, !sql.From[0].Star, "no star")
+	assert.Tf(t, sql.From[0].Columns.String() == "u.name, u.age, lower(city) AS c", "%v", sql.From[0].Columns.String())
+	assert.Tf(t, sql.From[0].Columns[0].SourceField == "name", "%v", sql.From[0].Columns[0].SourceField)
+	assert.Tf(t, sql.From[0].Columns[1].SourceField == "age", "%v", sql.From[0].Columns[1].SourceField)
+	assert.Equal(t, s, sql.String())
+
+	// un-aliased source is qualified by its name
+	sql = parseOrPanic(t, `SELECT name FROM users`).(*SqlSelect)
+	assert.T(t, sql.Rewrite() == nil)
+	assert.Tf(t, sql.From[0].Columns.String() == "users.name", "%v", sql.From[0].Columns)
 }
@@ -0,0 +1,152 @@
+package expr
+
+import (
+	"bytes"
+
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+var sqlWhereOps = map[lex.TokenType]string{
+	lex.TokenEqual:      "=",
+	lex.TokenEqualEqual: "=",
+	lex.TokenNE:         "!=",
+	lex.TokenGT:         ">",
+	lex.TokenGE:         ">=",
+	lex.TokenLT:         "<",
+	lex.TokenLE:         "<=",
+	lex.TokenLike:       "LIKE",
+	lex.TokenIs:         "IS",
+	lex.TokenIsNot:      "IS NOT",
+}
+
+// ToSQLWhere renders a where Node as a parameterized sql where clause for
+//  a source that is itself a sql database, literals are the ? args
+//
+//     status = "active" AND id IN (1, 2)  =>  status = ? AND id IN (?, ?)   ["active", 1, 2]
+//
+//  identities are quoted per @dialect (nil = SqlDialect) if needed, a node
+//  that cannot be rendered (ie funcs) is ErrNotSupported, see SplitPushdown()
+func ToSQLWhere(n Node, dialect *lex.Dialect) (string, []value.Value, error) {
+	w := &sqlWhereWriter{dialect: dialect, args: make([]value.Value, 0)}
+	if err := w.write(n); err != nil {
+		return "", nil, err
+	}
+	return w.buf.String(), w.args, nil
+}
+
+type sqlWhereWriter struct {
+	buf     bytes.Buffer
+	args    []value.Value
+	dialect *lex.Dialect
+}
+
+func (m *sqlWhereWriter) write(n Node) error {
+	switch nt := n.(type) {
+	case *BinaryNode:
+		switch nt.Operator.T {
+		case lex.TokenLogicAnd, lex.TokenLogicOr:
+			op := " AND "
+			if nt.Operator.T == lex.TokenLogicOr {
+				op = " OR "
+			}
+			for i, arg := range flattenLogical(nt) {
+				if i > 0 {
+					m.buf.WriteString(op)
+				}
+				if err := m.writeGrouped(arg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		op, ok := sqlWhereOps[nt.Operator.T]
+		if !ok {
+			return ErrNotSupported
+		}
+		if err := m.writeOperand(nt.Args[0]); err != nil {
+			return err
+		}
+		m.buf.WriteString(" " + op + " ")
+		return m.writeOperand(nt.Args[1])
+	case *MultiArgNode:
+		if nt.Operator.T != lex.TokenIN || len(nt.Args) < 2 {
+			return ErrNotSupported
+		}
+		if err := m.writeOperand(nt.Args[0]); err != nil {
+			return err
+		}
+		m.buf.WriteString(" IN (")
+		for i, arg := range nt.Args[1:] {
+			if i > 0 {
+				m.buf.WriteString(", ")
+			}
+			if err := m.writeOperand(arg); err != nil {
+				return err
+			}
+		}
+		m.buf.WriteByte(')')
+		return nil
+	case *TriNode:
+		if nt.Operator.T != lex.TokenBetween {
+			return ErrNotSupported
+		}
+		if err := m.writeOperand(nt.Args[0]); err != nil {
+			return err
+		}
+		m.buf.WriteString(" BETWEEN ")
+		if err := m.writeOperand(nt.Args[1]); err != nil {
+			return err
+		}
+		m.buf.WriteString(" AND ")
+		return m.writeOperand(nt.Args[2])
+	case *UnaryNode:
+		if nt.Operator.T != lex.TokenNegate {
+			return ErrNotSupported
+		}
+		m.buf.WriteString("NOT (")
+		if err := m.write(nt.Arg); err != nil {
+			return err
+		}
+		m.buf.WriteByte(')')
+		return nil
+	}
+	return ErrNotSupported
+}
+
+// and/or nested in the other are parenthesized
+func (m *sqlWhereWriter) writeGrouped(n Node) error {
+	if bn, ok := n.(*BinaryNode); ok && (bn.Operator.T == lex.TokenLogicAnd || bn.Operator.T == lex.TokenLogicOr) {
+		m.buf.WriteByte('(')
+		if err := m.write(n); err != nil {
+			return err
+		}
+		m.buf.WriteByte(')')
+		return nil
+	}
+	return m.write(n)
+}
+
+// an identity, or a literal as a ? placeholder arg
+func (m *sqlWhereWriter) writeOperand(n Node) error {
+	switch nt := n.(type) {
+	case *IdentityNode:
+		if nt.IsBooleanIdentity() {
+			m.buf.WriteByte('?')
+			m.args = append(m.args, value.NewBoolValue(nt.Bool()))
+			return nil
+		}
+		m.buf.WriteString(lex.QuoteIdentifier(nt.Text, m.dialect))
+	case *StringNode:
+		m.buf.WriteByte('?')
+		m.args = append(m.args, value.NewStringValue(nt.Text))
+	case *NumberNode:
+		m.buf.WriteByte('?')
+		m.args = append(m.args, nt.Value())
+	case *NullNode:
+		m.buf.WriteString("NULL")
+	default:
+		return ErrNotSupported
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func TestToSQLWhere(t *testing.T) {
+	tests := []struct {
+		where string
+		sql   string
+		args  []interface{}
+	}{
+		{`status = "active"`, `status = ?`, []interface{}{"active"}},
+		{`status == "active" AND age > 21`, `status = ? AND age > ?`, []interface{}{"active", int64(21)}},
+		{`id IN (1, 2, "x")`, `id IN (?, ?, ?)`, []interface{}{int64(1), int64(2), "x"}},
+		{`a = 1 AND b IN (2, 3)`, `a = ? AND b IN (?, ?)`, []interface{}{int64(1), int64(2), int64(3)}},
+		{`a = 1 AND (b = 2.5 OR c LIKE "x%")`, `a = ? AND (b = ? OR c LIKE ?)`, []interface{}{int64(1), 2.5, "x%"}},
+		{`age BETWEEN 18 AND 65`, `age BETWEEN ? AND ?`, []interface{}{int64(18), int64(65)}},
+		{`NOT (deleted = true)`, `NOT (deleted = ?)`, []interface{}{true}},
+		{`a = b`, `a = b`, []interface{}{}},
+		{`x IS NOT NULL`, `x IS NOT NULL`, []interface{}{}},
+		{"`select` = 1", "`select` = ?", []interface{}{int64(1)}},
+	}
+	for _, test := range tests {
+		tree, err := ParseExpression(test.where)
+		assert.Tf(t, err == nil, "no error %v", err)
+		sql, args, err := ToSQLWhere(tree.Root, nil)
+		assert.Tf(t, err == nil, "no error %v for %s", err, test.where)
+		assert.Tf(t, sql == test.sql, "%s  want %s got %s", test.where, test.sql, sql)
+		assert.Tf(t, len(args) == len(test.args), "%s  args %v", test.where, args)
+		for i, arg := range args {
+			assert.Tf(t, arg.Value() == test.args[i], "%s  arg %d want %v got %v", test.where, i, test.args[i], arg)
+		}
+	}
+
+	_, args, _ := ToSQLWhere(mustParse(t, `id IN ("a", "b")`), nil)
+	_, isStr := args[1].(value.StringValue)
+	assert.Tf(t, isStr, "args are values: %T", args[1])
+
+	for _, where := range []string{`tolower(a) = "x"`, `a + 1 > 2`, `a = 1 AND b * 2 > 3`} {
+		_, _, err := ToSQLWhere(mustParse(t, where), nil)
+		assert.Tf(t, err == ErrNotSupported, "%s not supported: %v", where, err)
+	}
+}
+
+func mustParse(t *testing.T, exprText string) Node {
+	tree, err := ParseExpression(exprText)
+	assert.Tf(t, err == nil, "no error %v", err)
+	return tree.Root
+}
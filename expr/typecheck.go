@@ -0,0 +1,127 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+// TypeSchema is the column types of a source, used to bind the value type
+//  of identities, see CheckSchema
+type TypeSchema interface {
+	ColumnType(name string) (value.ValueType, bool)
+}
+
+// CheckSchema is a schema aware Check(), it binds the value type of each
+//  identity in @n found in @schema (qualified  t.col  falls back to  col)
+//  then type checks comparisons, so with a numeric age
+//
+//     age > 30      ok
+//     age > "abc"   error
+//     age = true    error
+func CheckSchema(n Node, schema TypeSchema) error {
+	if err := n.Check(); err != nil {
+		return err
+	}
+	return checkSchema(n, schema)
+}
+
+func checkSchema(n Node, schema TypeSchema) error {
+	switch nt := n.(type) {
+	case *IdentityNode:
+		if nt.IsBooleanIdentity() {
+			return nil
+		}
+		if vt, ok := schema.ColumnType(nt.Text); ok {
+			nt.vt = vt
+		} else if _, right, hasLeft := nt.LeftRight(); hasLeft {
+			if vt, ok := schema.ColumnType(right); ok {
+				nt.vt = vt
+			}
+		}
+	case *BinaryNode:
+		for _, arg := range nt.Args {
+			if err := checkSchema(arg, schema); err != nil {
+				return err
+			}
+		}
+		switch nt.Operator.T {
+		case lex.TokenEqual, lex.TokenEqualEqual, lex.TokenNE, lex.TokenGT, lex.TokenGE,
+			lex.TokenLT, lex.TokenLE:
+			if !comparableNodes(nt.Args[0], nt.Args[1]) {
+				return fmt.Errorf("type mismatch: cannot compare %v (%v) to %v (%v)", nt.Args[0],
+					InferValueType(nt.Args[0]), nt.Args[1], InferValueType(nt.Args[1]))
+			}
+		}
+	case *TriNode:
+		for _, arg := range nt.Args {
+			if err := checkSchema(arg, schema); err != nil {
+				return err
+			}
+		}
+	case *UnaryNode:
+		return checkSchema(nt.Arg, schema)
+	case *SubscriptNode:
+		return checkSchema(nt.Arg, schema)
+	case *MatchNode:
+		for _, col := range nt.Cols {
+			if err := checkSchema(col, schema); err != nil {
+				return err
+			}
+		}
+	case *MultiArgNode:
+		for _, arg := range nt.Args {
+			if err := checkSchema(arg, schema); err != nil {
+				return err
+			}
+		}
+	case *FuncNode:
+		for _, arg := range nt.Args {
+			if err := checkSchema(arg, schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// can @a and @b be compared, only known and conflicting types are not, a
+//  string literal compares to a number if it parses as one, and to a time
+func comparableNodes(a, b Node) bool {
+	at, bt := InferValueType(a), InferValueType(b)
+	if typeKind(at) == typeKind(bt) || typeKind(at) == "" || typeKind(bt) == "" {
+		return true
+	}
+	if at == value.StringType {
+		a, b, at, bt = b, a, bt, at
+	}
+	if bt != value.StringType {
+		return false
+	}
+	switch typeKind(at) {
+	case "number":
+		if sn, ok := b.(*StringNode); ok {
+			_, err := strconv.ParseFloat(sn.Text, 64)
+			return err == nil
+		}
+	case "time":
+		return true
+	}
+	return false
+}
+
+func typeKind(vt value.ValueType) string {
+	switch vt {
+	case value.NumberType, value.IntType:
+		return "number"
+	case value.StringType:
+		return "string"
+	case value.BoolType:
+		return "bool"
+	case value.TimeType:
+		return "time"
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package expr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+type typeSchema map[string]value.ValueType
+
+func (m typeSchema) ColumnType(name string) (value.ValueType, bool) {
+	vt, ok := m[name]
+	return vt, ok
+}
+
+func TestCheckSchema(t *testing.T) {
+	schema := typeSchema{"age": value.NumberType, "visits": value.IntType, "name": value.StringType,
+		"created": value.TimeType}
+
+	tree, err := ParseExpression(`age > 30`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	age := tree.Root.(*BinaryNode).Args[0].(*IdentityNode)
+	assert.Tf(t, ValueTypeFromNode(age) == value.StringType, "unbound: %v", ValueTypeFromNode(age))
+	assert.Tf(t, InferValueType(age) == value.UnknownType, "unbound: %v", InferValueType(age))
+	assert.Tf(t, age.Type().Kind() == reflect.String, "unbound: %v", age.Type().Kind())
+
+	assert.Tf(t, CheckSchema(tree.Root, schema) == nil, "age > 30 type checks")
+	assert.Tf(t, ValueTypeFromNode(age) == value.NumberType, "bound: %v", ValueTypeFromNode(age))
+	assert.Tf(t, age.Type().Kind() == reflect.Float64, "bound: %v", age.Type().Kind())
+
+	// qualified falls back to the column
+	tree, _ = ParseExpression(`u.visits >= 2 AND name == "bob"`)
+	assert.Tf(t, CheckSchema(tree.Root, schema) == nil, "type checks")
+	visits := tree.Root.(*BinaryNode).Args[0].(*BinaryNode).Args[0]
+	assert.Tf(t, ValueTypeFromNode(visits) == value.IntType, "bound: %v", ValueTypeFromNode(visits))
+
+	for _, exprText := range []string{`age > "30"`, `created > "2015-01-01"`, `unknown_col > 30`,
+		`NOT (age < 5)`} {
+		tree, err = ParseExpression(exprText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		assert.Tf(t, CheckSchema(tree.Root, schema) == nil, "%s type checks", exprText)
+	}
+
+	for _, exprText := range []string{`age > "abc"`, `name > 30`, `age = true`, `x = 1 OR age == name`} {
+		tree, err = ParseExpression(exprText)
+		assert.Tf(t, err == nil, "no error %v", err)
+		assert.Tf(t, CheckSchema(tree.Root, schema) != nil, "%s type mismatch", exprText)
+	}
+}
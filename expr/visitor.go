@@ -20,3 +20,18 @@ type SubVisitor interface {
 	VisitSubselect(stmt *SqlSource) (interface{}, error)
 	VisitJoin(stmt *SqlSource) (interface{}, error)
 }
+
+// SourceVisitor is the SubVisitor a source planner is given to Accept, it
+//  exposes the pieces of the statement on the source so the planner can
+//  introspect it and build its native query, ie push down the where
+type SourceVisitor interface {
+	SubVisitor
+	// From is the source (table) being planned
+	From() *SqlSource
+	// Projection is the select columns
+	Projection() Columns
+	// Where is the filter expression, nil if none
+	Where() Node
+	GroupBy() Columns
+	OrderBy() Columns
+}
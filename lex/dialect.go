@@ -1,11 +1,21 @@
 package lex
 
 import (
-	u "github.com/araddon/gou"
 	"strings"
+	"sync"
+	"unicode"
 )
 
-var _ = u.EMPTY
+var (
+	// the dialects mutex
+	dialectMu sync.Mutex
+	// registry of dialects by lower case name
+	dialects = map[string]*Dialect{
+		"mysql":       SqlDialect,
+		"ansi":        AnsiSqlDialect,
+		"ansi_quotes": AnsiQuotesDialect,
+	}
+)
 
 // Dialect is a Language made up of multiple Statement Options
 //   SQL
@@ -15,14 +25,176 @@ var _ = u.EMPTY
 type Dialect struct {
 	Name       string
 	Statements []*Clause
+	Concat     ConcatMode // how  ||  is lexed
+	// IdentityQuote quotes identities, ie a reserved word used as a column
+	//  name, the default (0) is the mysql backtick.   A dialect quoting
+	//  with double quotes (ansi) has single quoted string values.
+	IdentityQuote byte
+	// Reserved are words of the dialect, besides the ql keywords, which
+	//  are quoted when generating ql (see QuoteIdentifier), lower case
+	Reserved []string
+	// RejectReserved makes an un-quoted Reserved word used as an identity
+	//  (column, alias) a lex error, off by default as existing ql uses
+	//  words such as  key, index  as column names
+	RejectReserved bool
+	// Chained is how a chained comparison such as  1 < x < 10  is parsed
+	Chained ChainedMode
 }
 
+// ConcatMode is the meaning of  ||  in a dialect, mysql (the default)
+//  treats it as logical OR, ansi sql as string concatenation
+//
+//     'a' || 'b'   => ansi:  "ab"    mysql:  false
+type ConcatMode int
+
+const (
+	ConcatMySql ConcatMode = iota // || is logical or
+	ConcatAnsi                    // || is string concat
+)
+
+// ChainedMode is how a dialect parses  a < b < c,  which would otherwise
+//  compare the bool of  a < b  to c,   the default is to reject it
+type ChainedMode int
+
+const (
+	ChainedError   ChainedMode = iota // parse error at the second comparison
+	ChainedRewrite                    // the range  a < b AND b < c
+)
+
 func (m *Dialect) Init() {
 	for _, s := range m.Statements {
 		s.init()
 	}
 }
 
+// RegisterDialect makes a dialect available by its Name to GetDialect,
+//  replacing any of the same name
+func RegisterDialect(d *Dialect) {
+	if d == nil || d.Name == "" {
+		panic("qlbridge/lex: RegisterDialect dialect must have a Name")
+	}
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	dialects[strings.ToLower(d.Name)] = d
+}
+
+// GetDialect the registered dialect of @name (case-insensitive)
+//
+//     d, ok := lex.GetDialect("ansi")
+func GetDialect(name string) (*Dialect, bool) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	d, ok := dialects[strings.ToLower(name)]
+	return d, ok
+}
+
+// Quote is the identity quote mark of the dialect, see IdentityQuote
+func (m *Dialect) Quote() byte {
+	if m == nil || m.IdentityQuote == 0 {
+		return '`'
+	}
+	return m.IdentityQuote
+}
+
+// isReservedWord is @word one of the dialect Reserved words
+func (m *Dialect) isReservedWord(word string) bool {
+	if m == nil {
+		return false
+	}
+	for _, r := range m.Reserved {
+		if strings.EqualFold(r, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectsReserved is @word a Reserved word this dialect requires be quoted
+func (m *Dialect) rejectsReserved(word string) bool {
+	return m != nil && m.RejectReserved && m.isReservedWord(word)
+}
+
+// IsReserved is this word (case-insensitive) a keyword of the ql
+//  or of this dialect's clauses or Reserved words
+func (m *Dialect) IsReserved(word string) bool {
+	word = strings.ToLower(word)
+	if reservedWords[word] {
+		return true
+	}
+	if m == nil {
+		return false
+	}
+	if m.isReservedWord(word) {
+		return true
+	}
+	for _, s := range m.Statements {
+		if s.hasKeyword(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuoteIdentifier quotes an identity (column, table name) if necessary to
+//  generate valid ql: if it is a reserved word, starts with a digit, or
+//  contains chars not valid in an un-quoted identity.   Each part of a
+//  dotted  table.column  name is quoted separately, with the dialect Quote.
+//  Nil dialect uses SqlDialect.
+//
+//     name         => name
+//     select       => `select`      ansi: "select"
+//     first name   => `first name`
+//     users.from   => users.`from`
+func QuoteIdentifier(name string, dialect *Dialect) string {
+	if dialect == nil {
+		dialect = SqlDialect
+	}
+	quote := dialect.Quote()
+	if !identityNeedsQuote(name, dialect) {
+		return name
+	}
+	parts := strings.Split(name, ".")
+	if len(parts) > 1 {
+		for _, part := range parts {
+			if part == "" || strings.IndexByte(part, quote) >= 0 {
+				parts = []string{name}
+				break
+			}
+		}
+	}
+	for i, part := range parts {
+		if identityNeedsQuote(part, dialect) {
+			q := string(quote)
+			parts[i] = q + strings.Replace(part, q, q+q, -1) + q
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func identityNeedsQuote(name string, dialect *Dialect) bool {
+	if name == "" {
+		return true
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case unicode.IsDigit(r):
+			if i == 0 {
+				return true
+			}
+		case strings.IndexRune(IDENTITY_SQL_CHARS, r) >= 0:
+		default:
+			return true
+		}
+	}
+	for _, part := range strings.Split(name, ".") {
+		if dialect.IsReserved(part) {
+			return true
+		}
+	}
+	return false
+}
+
 // Statement is a specific Statement of a dialetc, identified generally by the first KEYWORD
 // type Statement struct {
 // 	Keyword TokenType // Keywords do not have to exist, optional
@@ -49,6 +221,18 @@ type Clause struct {
 	Clauses   []*Clause // Children Clauses
 }
 
+func (c *Clause) hasKeyword(word string) bool {
+	if c.keyword != "" && c.keyword == word {
+		return true
+	}
+	for _, clause := range c.Clauses {
+		if clause.hasKeyword(word) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Clause) init() {
 	// Find the Keyword, MultiWord options
 	c.fullWord = c.Token.String()
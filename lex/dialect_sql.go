@@ -7,8 +7,11 @@ var SqlSelect = []*Clause{
 	{Token: TokenInto, Lexer: LexIdentifierOfType(TokenTable), Optional: true},
 	{Token: TokenFrom, Lexer: LexTableReferences, Optional: true, Repeat: true, Clauses: sqlSubQuery},
 	{Token: TokenWhere, Lexer: LexConditionalClause, Optional: true, Clauses: sqlSubQuery},
-	{Token: TokenGroupBy, Lexer: LexColumns, Optional: true},
+	{Token: TokenGroupBy, Lexer: LexGroupBy, Optional: true},
 	{Token: TokenHaving, Lexer: LexConditionalClause, Optional: true},
+	{Token: TokenUnion, Lexer: LexSetOperation, Optional: true},
+	{Token: TokenExcept, Lexer: LexSetOperation, Optional: true},
+	{Token: TokenIntersect, Lexer: LexSetOperation, Optional: true},
 	{Token: TokenOrderBy, Lexer: LexOrderByColumn, Optional: true},
 	{Token: TokenLimit, Lexer: LexNumber, Optional: true},
 	{Token: TokenWith, Lexer: LexJson, Optional: true},
@@ -20,16 +23,26 @@ var sqlSubQuery = []*Clause{
 	{Token: TokenFrom, Lexer: LexTableReferences, Optional: true, Repeat: true},
 	{Token: TokenWhere, Lexer: LexConditionalClause, Optional: true},
 	{Token: TokenHaving, Lexer: LexConditionalClause, Optional: true},
-	{Token: TokenGroupBy, Lexer: LexColumns, Optional: true},
+	{Token: TokenGroupBy, Lexer: LexGroupBy, Optional: true},
 	{Token: TokenOrderBy, Lexer: LexOrderByColumn, Optional: true},
 	{Token: TokenLimit, Lexer: LexNumber, Optional: true},
 }
 
+// common table expressions, the select they are for is lexed as its own
+//  statement
+//
+//    WITH cte AS (SELECT ...), cte2 AS (SELECT ... FROM cte) SELECT ...
+var SqlWith = []*Clause{
+	{Token: TokenWith, Lexer: LexCommonTableExpressions},
+}
+
 var SqlUpdate = []*Clause{
 	{Token: TokenUpdate, Lexer: LexIdentifierOfType(TokenTable)},
 	{Token: TokenSet, Lexer: LexColumns},
+	{Token: TokenFrom, Lexer: LexTableReferences, Optional: true},
 	{Token: TokenWhere, Lexer: LexColumns, Optional: true},
 	{Token: TokenLimit, Lexer: LexNumber, Optional: true},
+	{Token: TokenReturning, Lexer: LexColumns, Optional: true},
 }
 
 var SqlInsert = []*Clause{
@@ -37,6 +50,7 @@ var SqlInsert = []*Clause{
 	{Token: TokenInto, Lexer: LexIdentifierOfType(TokenTable)},
 	{Token: TokenSet, Lexer: LexTableColumns, Optional: true},
 	{Token: TokenLeftParenthesis, Lexer: LexTableColumns, Optional: true},
+	{Token: TokenReturning, Lexer: LexColumns, Optional: true},
 }
 
 var SqlDelete = []*Clause{
@@ -45,6 +59,7 @@ var SqlDelete = []*Clause{
 	{Token: TokenSet, Lexer: LexColumns, Optional: true},
 	{Token: TokenWhere, Lexer: LexColumns, Optional: true},
 	{Token: TokenLimit, Lexer: LexNumber, Optional: true},
+	{Token: TokenReturning, Lexer: LexColumns, Optional: true},
 }
 
 var SqlAlter = []*Clause{
@@ -79,6 +94,7 @@ var SqlPrepare = []*Clause{
 // SqlDialect is a SQL like dialect
 //
 //    SELECT
+//    WITH ... SELECT
 //    UPDATE
 //    INSERT
 //    UPSERT
@@ -95,9 +111,13 @@ var SqlPrepare = []*Clause{
 //      CREATE
 //      VIEW
 var SqlDialect *Dialect = &Dialect{
+	Name:          "mysql",
+	IdentityQuote: '`',
+	Reserved:      []string{"key", "index", "range", "div", "mod", "usage", "condition"},
 	Statements: []*Clause{
 		&Clause{Token: TokenPrepare, Clauses: SqlPrepare},
 		&Clause{Token: TokenSelect, Clauses: SqlSelect},
+		&Clause{Token: TokenWith, Clauses: SqlWith},
 		&Clause{Token: TokenUpdate, Clauses: SqlUpdate},
 		&Clause{Token: TokenInsert, Clauses: SqlInsert},
 		&Clause{Token: TokenDelete, Clauses: SqlDelete},
@@ -108,3 +128,28 @@ var SqlDialect *Dialect = &Dialect{
 		&Clause{Token: TokenShow, Clauses: SqlShow},
 	},
 }
+
+// AnsiSqlDialect is SqlDialect but with ansi  ||  string concatenation
+//  instead of logical OR
+//
+//     SELECT first_name || ' ' || last_name AS name FROM users
+var AnsiSqlDialect *Dialect = &Dialect{
+	Name:       "ansi",
+	Statements: SqlDialect.Statements,
+	Concat:     ConcatAnsi,
+}
+
+// AnsiQuotesDialect is AnsiSqlDialect but with double quoted identities
+//  and single quoted string values (the mysql ANSI_QUOTES mode), and its
+//  reserved words must be quoted to be used as identities
+//
+//     SELECT first_name || ' ' || last_name AS "user" FROM users
+var AnsiQuotesDialect *Dialect = &Dialect{
+	Name:          "ansi_quotes",
+	Statements:    SqlDialect.Statements,
+	Concat:        ConcatAnsi,
+	IdentityQuote: '"',
+	Reserved: []string{"user", "current_user", "session_user", "system_user",
+		"current_date", "current_time", "current_timestamp", "value"},
+	RejectReserved: true,
+}
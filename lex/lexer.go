@@ -3,14 +3,13 @@ package lex
 import (
 	"bytes"
 	"fmt"
-	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/logging"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
-var _ = u.EMPTY
-
 var (
 	// FEATURE FLAGS
 	SUPPORT_DURATION = true
@@ -90,6 +89,7 @@ type Lexer struct {
 	peekedWordPos int
 	peekedWord    string
 	lastQuoteMark byte
+	hints         []string // /*+ hint */ comments skipped within statement
 
 	//statementPos  int
 	//entryStateFn StateFn    // The current clause top level StateFn
@@ -103,7 +103,7 @@ type Lexer struct {
 func (l *Lexer) NextToken() Token {
 
 	for {
-		//u.Debugf("token: start=%v  pos=%v  peek5=%s", l.start, l.pos, l.PeekX(5))
+		//logging.Debugf("token: start=%v  pos=%v  peek5=%s", l.start, l.pos, l.PeekX(5))
 		select {
 		case token := <-l.tokens:
 			return token
@@ -120,8 +120,8 @@ func (l *Lexer) NextToken() Token {
 }
 
 func (l *Lexer) Push(name string, state StateFn) {
-	//u.LogTracef(u.INFO, "pushed item onto stack: %v", len(l.stack))
-	//u.Infof("pushed item onto stack: %v  %v", name, len(l.stack))
+	//logging.Infof("pushed item onto stack: %v", len(l.stack))
+	//logging.Infof("pushed item onto stack: %v  %v", name, len(l.stack))
 	l.stack = append(l.stack, NamedStateFn{name, state})
 }
 
@@ -132,7 +132,7 @@ func (l *Lexer) pop() StateFn {
 	li := len(l.stack) - 1
 	last := l.stack[li]
 	l.stack = l.stack[0:li]
-	//u.Infof("popped item off stack:  %v", last.Name)
+	//logging.Infof("popped item off stack:  %v", last.Name)
 	return last.StateFn
 }
 
@@ -157,10 +157,18 @@ func (l *Lexer) RawInput() string {
 	return l.input
 }
 
+// Dialect is the dialect being lexed
+func (l *Lexer) Dialect() *Dialect {
+	return l.dialect
+}
+
 // peek returns but does not consume the next rune in the input.
 func (l *Lexer) Peek() rune {
+	// a backup() after peeking still undoes the prior Next()
+	width := l.width
 	r := l.Next()
 	l.backup()
+	l.width = width
 	return r
 }
 
@@ -174,7 +182,7 @@ func (l *Lexer) PeekX(x int) string {
 
 // get single character
 func (l *Lexer) peekXrune(x int) rune {
-	if l.pos+x > len(l.input) {
+	if l.pos+x >= len(l.input) {
 		return rune(0)
 	}
 	return rune(l.input[l.pos+x])
@@ -195,7 +203,7 @@ func (l *Lexer) PeekWord2() string {
 	for i := skipWs; i < len(l.input)-l.pos; i++ {
 		r, _ := utf8.DecodeRuneInString(l.input[l.pos+i:])
 		if unicode.IsSpace(r) || !isIdentifierRune(r) {
-			u.Infof("hm:   '%v' word='%s' %v", l.input[l.pos:l.pos+i], word, l.input[l.pos:l.pos+i] == word)
+			logging.Infof("hm:   '%v' word='%s' %v", l.input[l.pos:l.pos+i], word, l.input[l.pos:l.pos+i] == word)
 			return word
 		} else {
 			word = word + string(r)
@@ -226,13 +234,13 @@ func (l *Lexer) PeekWord() string {
 	i := skipWs
 	for ; i < len(l.input)-l.pos; i++ {
 		r, ri := utf8.DecodeRuneInString(l.input[l.pos+i:])
-		//u.Debugf("r: %v", string(r))
+		//logging.Debugf("r: %v", string(r))
 		if ri != 1 {
 			//i += (ri - 1)
 		}
 		if unicode.IsSpace(r) || !isIdentifierRune(r) || r == '(' {
 			if i > 0 {
-				//u.Infof("hm:   '%v'", l.input[l.pos+skipWs:l.pos+i])
+				//logging.Infof("hm:   '%v'", l.input[l.pos+skipWs:l.pos+i])
 				l.peekedWordPos = l.pos
 				l.peekedWord = l.input[l.pos+skipWs : l.pos+i]
 				return l.peekedWord
@@ -243,7 +251,7 @@ func (l *Lexer) PeekWord() string {
 
 		}
 	}
-	//u.Infof("hm:   '%v'", l.input[l.pos+skipWs:l.pos+i])
+	//logging.Infof("hm:   '%v'", l.input[l.pos+skipWs:l.pos+i])
 	l.peekedWordPos = l.pos
 	l.peekedWord = l.input[l.pos+skipWs : l.pos+i]
 	return l.peekedWord
@@ -271,7 +279,7 @@ func (l *Lexer) backup() {
 
 // have we consumed all input
 func (l *Lexer) IsEnd() bool {
-	//u.Infof("isEnd? %v:%v", l.pos, len(l.input))
+	//logging.Infof("isEnd? %v:%v", l.pos, len(l.input))
 	if l.pos >= len(l.input) {
 		return true
 	}
@@ -283,12 +291,18 @@ func (l *Lexer) IsEnd() bool {
 
 // emit passes an token back to the client.
 func (l *Lexer) Emit(t TokenType) {
-	//u.Debugf("emit: %s  '%s'  stack=%v", t, l.input[l.start:l.pos], len(l.stack))
+	l.emit(t, l.input[l.start:l.pos])
+}
+
+// emit a token with value @v which may differ from the raw input, ie
+// escaped strings
+func (l *Lexer) emit(t TokenType, v string) {
+	//logging.Debugf("emit: %s  '%s'  stack=%v", t, l.input[l.start:l.pos], len(l.stack))
 	if l.lastQuoteMark != 0 {
-		l.lastToken = Token{T: t, V: l.input[l.start:l.pos], Pos: l.start, Quote: l.lastQuoteMark}
+		l.lastToken = Token{T: t, V: v, Pos: l.start, Quote: l.lastQuoteMark}
 		l.lastQuoteMark = 0
 	} else {
-		l.lastToken = Token{T: t, V: l.input[l.start:l.pos], Pos: l.start}
+		l.lastToken = Token{T: t, V: v, Pos: l.start}
 	}
 	l.tokens <- l.lastToken
 	l.start = l.pos
@@ -365,19 +379,71 @@ func (l *Lexer) errorf(format string, args ...interface{}) StateFn {
 
 // Skips white space characters in the input.
 func (l *Lexer) SkipWhiteSpaces() {
+	for {
+		l.skipWhiteSpacesOnly()
+		if !l.skipComment() {
+			return
+		}
+	}
+}
+
+func (l *Lexer) skipWhiteSpacesOnly() {
 	for rune := l.Next(); unicode.IsSpace(rune); rune = l.Next() {
 	}
 	l.backup()
 	l.ignore()
 }
 
+// skip a comment embedded within a statement, either
+//
+//     /* comment */
+//     -- comment to end of line
+//
+//  a /*+ hint */ style comment is saved to be retrieved by Hints()
+func (l *Lexer) skipComment() bool {
+	remainder := l.input[l.pos:]
+	switch {
+	case strings.HasPrefix(remainder, "/*"):
+		end := strings.Index(remainder[2:], "*/")
+		if end < 0 {
+			return false
+		}
+		comment := remainder[2 : end+2]
+		if strings.HasPrefix(comment, "+") {
+			l.hints = append(l.hints, strings.TrimSpace(comment[1:]))
+		}
+		l.ignoreWord(remainder[:end+4])
+		return true
+	case strings.HasPrefix(remainder, "--"):
+		// require whitespace after -- so  5 --3  is still math
+		if len(remainder) > 2 && !unicode.IsSpace(rune(remainder[2])) {
+			return false
+		}
+		end := strings.IndexByte(remainder, '\n')
+		if end < 0 {
+			end = len(remainder)
+		}
+		l.ignoreWord(remainder[:end])
+		return true
+	}
+	return false
+}
+
+// Hints returns, and clears, the /*+ hint */ comments found
+//  so far, with the /*+ and */ removed
+func (l *Lexer) Hints() []string {
+	hints := l.hints
+	l.hints = nil
+	return hints
+}
+
 // Skips white space characters at end by trimming so we can recognize the end
 //  more easily
 func (l *Lexer) ReverseTrim() {
 	for i := len(l.input) - 1; i >= 0; i-- {
 		if !unicode.IsSpace(rune(l.input[i])) {
 			if i < (len(l.input) - 1) {
-				//u.Warnf("trim: '%v'", l.input[:i+1])
+				//logging.Warnf("trim: '%v'", l.input[:i+1])
 				l.input = l.input[:i+1]
 			}
 			break
@@ -390,18 +456,18 @@ func (l *Lexer) ReverseTrim() {
 // expects matchTo to be a lower case string
 func (l *Lexer) match(matchTo string, skip int) bool {
 
-	//u.Debugf("match() : %v", matchTo)
+	//logging.Debugf("match() : %v", matchTo)
 	for _, matchRune := range matchTo {
-		//u.Debugf("match rune? %v", string(matchRune))
+		//logging.Debugf("match rune? %v", string(matchRune))
 		if skip > 0 {
 			skip--
 			continue
 		}
 
 		nr := l.Next()
-		//u.Debugf("rune=%s n=%s   %v  %v", string(matchRune), string(nr), matchRune != nr, unicode.ToLower(nr) != matchRune)
+		//logging.Debugf("rune=%s n=%s   %v  %v", string(matchRune), string(nr), matchRune != nr, unicode.ToLower(nr) != matchRune)
 		if matchRune != nr && unicode.ToLower(nr) != matchRune {
-			//u.Debugf("setting done = false?, ie did not match")
+			//logging.Debugf("setting done = false?, ie did not match")
 			return false
 		}
 	}
@@ -410,7 +476,7 @@ func (l *Lexer) match(matchTo string, skip int) bool {
 	if !isWhiteSpace(l.Peek()) {
 		return false
 	}
-	//u.Debugf("Found match():  %v", matchTo)
+	//logging.Debugf("Found match():  %v", matchTo)
 	return true
 }
 
@@ -421,7 +487,7 @@ func (l *Lexer) match(matchTo string, skip int) bool {
 // NOTE:  this assumes the @val you are trying to match against is LOWER CASE
 func (l *Lexer) tryMatch(matchTo string) bool {
 	i := 0
-	//u.Debugf("tryMatch:  start='%v'", l.PeekWord())
+	//logging.Debugf("tryMatch:  start='%v'", l.PeekWord())
 	for _, matchRune := range matchTo {
 		i++
 		nextRune := l.Next()
@@ -429,11 +495,11 @@ func (l *Lexer) tryMatch(matchTo string) bool {
 			for ; i > 0; i-- {
 				l.backup()
 			}
-			//u.Warnf("not found:  %v:%v", string(nextRune), matchTo)
+			//logging.Warnf("not found:  %v:%v", string(nextRune), matchTo)
 			return false
 		}
 	}
-	//u.Debugf("tryMatch:  good='%v'", matchTo)
+	//logging.Debugf("tryMatch:  good='%v'", matchTo)
 	return true
 }
 
@@ -462,7 +528,7 @@ func (l *Lexer) isExpr() bool {
 		// first character of expression cannot be digit
 		return false
 	case r == '!':
-		//u.Debugf("found negation! : %v", string(r))
+		//logging.Debugf("found negation! : %v", string(r))
 		// Negation is possible?
 		l.Next()
 		if l.isExpr() {
@@ -496,27 +562,27 @@ func (l *Lexer) isNextKeyword(peekWord string) bool {
 		return false
 	}
 	kwMaybe := strings.ToLower(peekWord)
-	//u.Debugf("isNextKeyword?  '%s'   len:%v", kwMaybe, len(l.statement.Clauses))
+	//logging.Debugf("isNextKeyword?  '%s'   len:%v", kwMaybe, len(l.statement.Clauses))
 
 	clause := l.curClause.next
-	//u.Infof("clause: %+v", clause)
+	//logging.Infof("clause: %+v", clause)
 
 	//for i := l.statementPos; i < len(l.statement.Clauses); i++ {
 	for {
 		if clause == nil {
-			//u.Warnf("returning, not keyword")
+			//logging.Warnf("returning, not keyword")
 			break
 		}
 		//clause = l.statement.Clauses[i]
-		//u.Infof("clause: %+v", clause)
-		//u.Debugf("clause next keyword?    peek=%s  keyword=%v multi?%v children?%v", kwMaybe, clause.keyword, clause.multiWord, len(clause.Clauses))
+		//logging.Infof("clause: %+v", clause)
+		//logging.Debugf("clause next keyword?    peek=%s  keyword=%v multi?%v children?%v", kwMaybe, clause.keyword, clause.multiWord, len(clause.Clauses))
 		if clause.keyword == kwMaybe || (clause.multiWord && strings.ToLower(l.PeekX(len(clause.fullWord))) == clause.fullWord) {
-			//u.Infof("return true:  %v", strings.ToLower(l.PeekX(len(clause.fullWord))))
+			//logging.Infof("return true:  %v", strings.ToLower(l.PeekX(len(clause.fullWord))))
 			return true
 		}
 		switch kwMaybe {
 		case "select", "insert", "delete", "update", "from":
-			//u.Warnf("doing true: %v", kwMaybe)
+			//logging.Warnf("doing true: %v", kwMaybe)
 			return true
 		}
 		if !clause.Optional {
@@ -534,7 +600,7 @@ func (l *Lexer) isIdentity() bool {
 	// Identity are strings not values
 	r := l.Peek()
 	switch {
-	case isIdentityQuoteMark(r):
+	case l.isIdentityQuoteMark(r):
 		// are these always identities?  or do we need
 		// to also check first identifier
 		peek2 := l.PeekX(2)
@@ -549,13 +615,13 @@ func (l *Lexer) isIdentity() bool {
 // matches expected tokentype emitting the token on success
 // and returning passed state function.
 func (l *Lexer) LexMatchSkip(tok TokenType, skip int, fn StateFn) StateFn {
-	//u.Debugf("lexMatch   t=%s peek=%s", tok, l.PeekWord())
+	//logging.Debugf("lexMatch   t=%s peek=%s", tok, l.PeekWord())
 	if l.match(tok.String(), skip) {
-		//u.Debugf("found match: %s   %v", tok, fn)
+		//logging.Debugf("found match: %s   %v", tok, fn)
 		l.Emit(tok)
 		return fn
 	}
-	u.Error("unexpected token", tok)
+	logging.Error("unexpected token", tok)
 	return l.errorToken("Unexpected token:" + l.current())
 }
 
@@ -579,25 +645,25 @@ func (l *Lexer) clauseState() StateFn {
 		}
 		return l.curClause.Lexer
 	}
-	u.Debugf("curClause? %v", l.curClause)
-	//u.Debugf("curClause: %v", len(l.curClause.Clauses))
-	u.Warnf("empty lex fn? %v", l.PeekX(10))
+	logging.Debugf("curClause? %v", l.curClause)
+	//logging.Debugf("curClause: %v", len(l.curClause.Clauses))
+	logging.Warnf("empty lex fn? %v", l.PeekX(10))
 	return emptyLexFn
 }
 
-var emptyLexFn = func(*Lexer) StateFn { u.Debugf("empty statefun"); return nil }
+var emptyLexFn = func(*Lexer) StateFn { logging.Debugf("empty statefun"); return nil }
 
 // matches expected tokentype emitting the token on success
 // and returning passed state function.
 func LexMatchClosure(tok TokenType, nextFn StateFn) StateFn {
 	return func(l *Lexer) StateFn {
-		//u.Debugf("lexMatch   t=%s peek=%s", tok, l.PeekWord())
+		//logging.Debugf("lexMatch   t=%s peek=%s", tok, l.PeekWord())
 		if l.match(tok.String(), 0) {
-			//u.Debugf("found match: %s   %v", tok, fn)
+			//logging.Debugf("found match: %s   %v", tok, fn)
 			l.Emit(tok)
 			return nextFn
 		}
-		u.Error("unexpected token ", tok, l.PeekX(20))
+		logging.Error("unexpected token ", tok, l.PeekX(20))
 		return l.errorToken("Unexpected token:" + l.current())
 	}
 }
@@ -608,12 +674,10 @@ func LexMatchClosure(tok TokenType, nextFn StateFn) StateFn {
 // ie [SELECT, ALTER, CREATE, INSERT] in sql
 func LexDialectForStatement(l *Lexer) StateFn {
 
-	l.SkipWhiteSpaces()
+	l.skipWhiteSpacesOnly()
 
-	r := l.Peek()
-
-	switch r {
-	case '/', '-', '#':
+	switch {
+	case l.isComment():
 		// ensure we have consumed all initial pre-statement comments
 		l.Push("LexDialectForStatement", LexDialectForStatement)
 		return LexComment(l)
@@ -623,7 +687,7 @@ func LexDialectForStatement(l *Lexer) StateFn {
 			if l.IsEnd() {
 				break
 			}
-			//u.Debugf("stmt lexer?  peek=%s  keyword=%v ", peekWord, stmt.Token.String())
+			//logging.Debugf("stmt lexer?  peek=%s  keyword=%v ", peekWord, stmt.Token.String())
 			if stmt.Token.String() == peekWord {
 				// We aren't actually going to consume anything here, just find
 				// the correct statement
@@ -664,23 +728,21 @@ func LexDialectForStatement(l *Lexer) StateFn {
 // lexer
 func LexStatement(l *Lexer) StateFn {
 
-	l.SkipWhiteSpaces()
-
-	r := l.Peek()
+	l.skipWhiteSpacesOnly()
 
-	switch r {
-	case '/', '-', '#':
+	switch {
+	case l.isComment():
 		// ensure we have consumed all comments
 		l.Push("LexStatement", LexStatement)
 		return LexComment(l)
 	default:
-		//u.Warnf("isCur Nil? %v", l.curClause)
+		//logging.Warnf("isCur Nil? %v", l.curClause)
 		clause := l.curClause
 
 		peekWord := strings.ToLower(l.PeekWord())
 		for {
 			if clause == nil {
-				//u.Warnf("nil clause")
+				//logging.Warnf("nil clause")
 				break
 			}
 			if l.IsEnd() {
@@ -689,14 +751,14 @@ func LexStatement(l *Lexer) StateFn {
 
 			// we only ever consume each clause once
 			//l.statementPos++
-			//u.Debugf("stmt.clause parser?  peek=%s  keyword=%v multi?%v", peekWord, clause.keyword, clause.multiWord)
+			//logging.Debugf("stmt.clause parser?  peek=%s  keyword=%v multi?%v", peekWord, clause.keyword, clause.multiWord)
 			if clause.keyword == peekWord || (clause.multiWord && strings.ToLower(l.PeekX(len(clause.keyword))) == clause.keyword) {
 
 				// Set the default entry point for this keyword
 				//l.clauseState() = clause.Lexer
 				l.curClause = clause
 
-				//u.Debugf("dialect clause:  '%v' \n\t %s ", clause.keyword, l.input)
+				//logging.Debugf("dialect clause:  '%v' \n\t %s ", clause.keyword, l.input)
 				l.Push("LexStatement", LexStatement)
 				if clause.Optional {
 					return l.lexIfMatch(clause.Token, clause.Lexer)
@@ -709,9 +771,9 @@ func LexStatement(l *Lexer) StateFn {
 
 		}
 		// If we have consumed all clauses, we are ready to be done?
-		//u.Infof("not found? word? '%s' %v", peekWord, clause)
+		//logging.Infof("not found? word? '%s' %v", peekWord, clause)
 		if clause == nil {
-			//u.Infof("Run End of statement")
+			//logging.Infof("Run End of statement")
 			return LexEndOfStatement
 		}
 
@@ -729,7 +791,7 @@ func LexStatement(l *Lexer) StateFn {
 // LexLogical is a lex entry function for logical expression language (+-/> etc)
 func LexLogical(l *Lexer) StateFn {
 
-	//u.Debug("in lexLogical: ", l.PeekX(5))
+	//logging.Debug("in lexLogical: ", l.PeekX(5))
 	l.SkipWhiteSpaces()
 
 	// r := l.Peek()
@@ -744,9 +806,15 @@ func LexLogical(l *Lexer) StateFn {
 		l.Emit(TokenEOF)
 		return nil
 	}
+	if l.Peek() == ';' {
+		// LexExpression leaves the ; statement end for the caller
+		l.Next()
+		l.Emit(TokenEOS)
+		return LexLogical
+	}
 
 	l.Push("LexLogical", LexLogical)
-	//u.Debugf("LexLogical:  %v", l.PeekWord())
+	//logging.Debugf("LexLogical:  %v", l.PeekWord())
 	return LexExpression(l)
 }
 
@@ -757,6 +825,8 @@ func LexLogical(l *Lexer) StateFn {
 //  "stuff"    -> stuff
 //  'stuff'    ->
 //  "items's with quote"
+//  $$ {"json": "it's \n verbatim"} $$
+//  $tag$ may contain $$ $tag$
 //  1.23
 //  100
 //
@@ -770,24 +840,28 @@ func LexValue(l *Lexer) StateFn {
 	typ := TokenValue
 	if rune == ')' {
 		// Whoops
-		u.Warnf("why did we get paren? ")
-		panic("should not have paren")
-		return nil
+		logging.Warnf("why did we get paren? ")
+		l.backup()
+		return l.errorToken("expected value but got: )")
 	}
 	if rune == '*' {
-		u.LogTracef(u.WARN, "why are we having a star here? %v", l.PeekX(10))
+		logging.Warnf("why are we having a star here? %v", l.PeekX(10))
 	}
 
-	//u.Debugf("in LexValue: %v", string(rune))
+	//logging.Debugf("in LexValue: %v", string(rune))
+
+	if rune == '$' {
+		return lexDollarQuoted(l)
+	}
 
 	// quoted string
 	if rune == '\'' || rune == '"' {
 		firstRune := rune
 		l.ignore() // consume the quote mark
-		previousEscaped := rune == '\\'
+		previousEscaped := false
 		for rune = l.Next(); ; rune = l.Next() {
 
-			//u.Debugf("LexValue rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
+			//logging.Debugf("LexValue rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
 			if (rune == '\'' || rune == '"') && rune == firstRune && !previousEscaped {
 				if !l.IsEnd() {
 					rune = l.Next()
@@ -798,9 +872,10 @@ func LexValue(l *Lexer) StateFn {
 						// since we read lookahead after single quote that ends the string
 						// for lookahead
 						l.backup()
-						// for single quote which is not part of the value
-						l.backup()
-						l.Emit(typ)
+						// for single quote which is not part of the value, backup()
+						// only knows the width of the lookahead rune
+						l.pos--
+						l.emitValue(typ)
 						// now ignore that single quote
 						l.Next()
 						l.ignore()
@@ -809,22 +884,23 @@ func LexValue(l *Lexer) StateFn {
 				} else {
 					// at the very end
 					l.backup()
-					l.Emit(typ)
+					l.emitValue(typ)
 					l.Next()
 					return nil
 				}
 			}
-			if rune == 0 {
+			if rune == 0 || rune == eof {
 				return l.errorToken("string value was not delimited")
 			}
-			previousEscaped = rune == '\\'
+			// an escaped back-slash  \\  does not escape the next rune
+			previousEscaped = rune == '\\' && !previousEscaped
 		}
 	} else {
 		// Non-Quoted String?   Should this be a numeric?   or date or what?  duration?  what kinds are valid?
 		//  A:   numbers
 		//
 		l.backup()
-		//u.Debugf("lexNumber?  %v", string(l.PeekX(5)))
+		//logging.Debugf("lexNumber?  %v", string(l.PeekX(5)))
 		return LexNumber(l)
 		// for rune = l.Next(); !isWhiteSpace(rune) && rune != ',' && rune != ')'; rune = l.Next() {
 		// }
@@ -834,6 +910,84 @@ func LexValue(l *Lexer) StateFn {
 	return nil
 }
 
+// lex a dollar quoted string  $$ ... $$  or  $tag$ ... $tag$  (the opening $
+//  already consumed), the value is verbatim without any escape processing
+//  so is handy for json, regex etc.
+func lexDollarQuoted(l *Lexer) StateFn {
+	tagEnd := strings.IndexByte(l.input[l.pos:], '$')
+	if tagEnd < 0 {
+		return l.errorToken("expected dollar quoted string: " + l.PeekX(10))
+	}
+	for _, r := range l.input[l.pos : l.pos+tagEnd] {
+		if !isIdentifierRune(r) {
+			return l.errorToken("invalid dollar quote tag: " + l.input[l.start:l.pos+tagEnd+1])
+		}
+	}
+	delim := l.input[l.start : l.pos+tagEnd+1]
+	l.pos += tagEnd + 1
+	l.ignore() // the opening delimiter
+	valEnd := strings.Index(l.input[l.pos:], delim)
+	if valEnd < 0 {
+		return l.errorToken("dollar quoted string was not delimited")
+	}
+	l.pos += valEnd
+	l.emit(TokenValue, l.input[l.start:l.pos])
+	l.pos += len(delim)
+	l.ignore()
+	return nil
+}
+
+// emit a quoted string value, decoding escape sequences
+func (l *Lexer) emitValue(t TokenType) {
+	if t != TokenValue {
+		l.Emit(t)
+		return
+	}
+	l.emit(t, UnescapeString(l.input[l.start:l.pos]))
+}
+
+// UnescapeString decodes the back-slash escapes of a string literal (quotes
+//  already removed).   Both quote chars, and the standard go escapes
+//  \n \t \\ \xNN \uNNNN \UNNNNNNNN etc are decoded, unknown escapes such
+//  as the  \%  of a like pattern are left as is.
+//
+//     it\'s      =>  it's
+//     a\tb       =>  a<tab>b
+//     \u00e9     =>  é
+func UnescapeString(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var buf bytes.Buffer
+	for len(s) > 0 {
+		if s[0] != '\\' || len(s) == 1 {
+			_, size := utf8.DecodeRuneInString(s)
+			buf.WriteString(s[:size])
+			s = s[size:]
+			continue
+		}
+		if s[1] == '\'' || s[1] == '"' {
+			buf.WriteByte(s[1])
+			s = s[2:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, 0)
+		if err != nil {
+			// not an escape we know, keep the back-slash
+			buf.WriteByte('\\')
+			s = s[1:]
+			continue
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			buf.WriteByte(byte(r))
+		} else {
+			buf.WriteRune(r)
+		}
+		s = tail
+	}
+	return buf.String()
+}
+
 // lex a regex:   first character must be a /
 //
 //  /^stats\./i
@@ -844,13 +998,13 @@ func LexRegex(l *Lexer) StateFn {
 
 	l.SkipWhiteSpaces()
 	if l.IsEnd() {
-		u.Error("wat?")
+		logging.Error("wat?")
 		return l.errorToken("expected value but got EOF")
 	}
 
 	rune := l.Next()
 	if rune != '/' {
-		u.Errorf("wat? %v", string(rune))
+		logging.Errorf("wat? %v", string(rune))
 		return nil
 	}
 
@@ -860,12 +1014,12 @@ func LexRegex(l *Lexer) StateFn {
 		if rune == eof {
 			return l.errorToken("expected value but got EOF")
 		}
-		//u.Debugf("LexRegex rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
+		//logging.Debugf("LexRegex rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
 		if rune == '/' && !previousEscaped {
 			// now that we have found what appears to be end, lets see if it
 			// has a modifier - the i/g at end of    /^stats\./i
 			for rune = l.Next(); ; rune = l.Next() {
-				//u.Debugf("LexRegex rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
+				//logging.Debugf("LexRegex rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
 				if rune == eof {
 					l.Emit(TokenRegex)
 					return nil
@@ -916,17 +1070,28 @@ func LexExpressionOrIdentity(l *Lexer) StateFn {
 		l.Emit(TokenLeftParenthesis)
 		return LexExpressionOrIdentity
 	}
-	//u.Debugf("LexExpressionOrIdentity identity?%v expr?%v %v peek5='%v'", l.isIdentity(), l.isExpr(), string(l.Peek()), string(l.PeekX(5)))
+	if r == '?' {
+		//  WHERE id IN (?, ?)
+		l.Next()
+		l.Emit(TokenParam)
+		return nil
+	}
+	//logging.Debugf("LexExpressionOrIdentity identity?%v expr?%v %v peek5='%v'", l.isIdentity(), l.isExpr(), string(l.Peek()), string(l.PeekX(5)))
 	// Expressions end in Parens:     LOWER(item)
 	if l.isExpr() {
 		return lexExpressionIdentifier(l)
+	} else if word := strings.ToLower(l.PeekWord()); word == "interval" {
+		//  INTERVAL "1 day"
+		l.ConsumeWord(word)
+		l.Emit(TokenInterval)
+		return LexValue
 	} else if l.isIdentity() {
 		// Non Expressions are Identities, or Columns
-		//u.Warnf("in expr is identity? %s", l.PeekWord())
+		//logging.Warnf("in expr is identity? %s", l.PeekWord())
 		// by passing nil here, we are going to go back to Pull items off stack)
 		return LexIdentifier(l)
 	} else {
-		//u.Warnf("LexExpressionOrIdentity ??? -> LexValue")
+		//logging.Warnf("LexExpressionOrIdentity ??? -> LexValue")
 		return LexValue(l)
 	}
 
@@ -942,13 +1107,13 @@ func LexExpressionParens(l *Lexer) StateFn {
 
 	// first rune must be opening Parenthesis
 	firstChar := l.Next()
-	//u.Debugf("LexExpressionParens:  %v", string(firstChar))
+	//logging.Debugf("LexExpressionParens:  %v", string(firstChar))
 	if firstChar != '(' {
-		u.Errorf("bad expression? %v", string(firstChar))
+		logging.Errorf("bad expression? %v", string(firstChar))
 		return l.errorToken("expression must begin with a paren: ( " + l.current())
 	}
 	l.Emit(TokenLeftParenthesis)
-	//u.Infof("LexExpressionParens:   %v", string(firstChar))
+	//logging.Infof("LexExpressionParens:   %v", string(firstChar))
 	return LexListOfArgs
 }
 
@@ -960,7 +1125,7 @@ func lexExpressionIdentifier(l *Lexer) StateFn {
 
 	l.SkipWhiteSpaces()
 
-	//u.Debugf("lexExpressionIdentifier identity?%v expr?%v %v:%v", l.isIdentity(), l.isExpr(), string(l.Peek()), string(l.PeekWord()))
+	//logging.Debugf("lexExpressionIdentifier identity?%v expr?%v %v:%v", l.isIdentity(), l.isExpr(), string(l.Peek()), string(l.PeekWord()))
 
 	// first rune has to be valid unicode letter
 	firstChar := l.Next()
@@ -969,7 +1134,7 @@ func lexExpressionIdentifier(l *Lexer) StateFn {
 		return lexExpressionIdentifier
 	}
 	if !unicode.IsLetter(firstChar) {
-		//u.Warnf("lexExpressionIdentifier couldnt find expression idenity?  %v stack=%v", string(firstChar), len(l.stack))
+		//logging.Warnf("lexExpressionIdentifier couldnt find expression idenity?  %v stack=%v", string(firstChar), len(l.stack))
 		return l.errorToken("identifier must begin with a letter " + string(l.input[l.start:l.pos]))
 	}
 	// Now look for run of runes, where run is ended by first non-identifier character
@@ -1002,7 +1167,7 @@ func LexListOfArgs(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 
 	r := l.Next()
-	//u.Debugf("in LexListOfArgs:  '%s'", string(r))
+	//logging.Debugf("in LexListOfArgs:  '%s'", string(r))
 
 	switch r {
 	case ')':
@@ -1035,19 +1200,19 @@ func LexListOfArgs(l *Lexer) StateFn {
 		// So, not comma, * so either is Expression, Identity, Value
 		l.backup()
 		peekWord := strings.ToLower(l.PeekWord())
-		//u.Debugf("in LexListOfArgs:  '%s'", peekWord)
+		//logging.Debugf("in LexListOfArgs:  '%s'", peekWord)
 		// First, lets ensure we haven't blown past into keyword?
 		if l.isNextKeyword(peekWord) {
-			//u.Warnf("found keyword while looking for arg? %v", string(r))
+			//logging.Warnf("found keyword while looking for arg? %v", string(r))
 			return nil
 		}
 
-		//u.Debugf("LexListOfArgs sending LexExpressionOrIdentity: %v", string(peekWord))
+		//logging.Debugf("LexListOfArgs sending LexExpressionOrIdentity: %v", string(peekWord))
 		l.Push("LexListOfArgs", LexListOfArgs)
 		return LexExpressionOrIdentity
 	}
 
-	//u.Warnf("exit LexListOfArgs")
+	//logging.Warnf("exit LexListOfArgs")
 	return nil
 }
 
@@ -1084,8 +1249,8 @@ func LexIdentifierOfType(forToken TokenType) StateFn {
 		wasQouted := false
 		// first rune has to be valid unicode letter
 		firstChar := l.Next()
-		//u.Debugf("LexIdentifierOfType:   '%s'  peek6'%v'", string(firstChar), l.PeekX(6))
-		//u.LogTracef(u.INFO, "LexIdentifierOfType: %v", string(firstChar))
+		//logging.Debugf("LexIdentifierOfType:   '%s'  peek6'%v'", string(firstChar), l.PeekX(6))
+		//logging.Infof("LexIdentifierOfType: %v", string(firstChar))
 		switch {
 		case firstChar == '`':
 			// Fields with escape identity can be pretty much any illegal character
@@ -1104,31 +1269,31 @@ func LexIdentifierOfType(forToken TokenType) StateFn {
 			if firstChar == lastRune {
 				// valid
 			} else {
-				u.Errorf("unexpected character in identifier?  %v", string(lastRune))
+				logging.Errorf("unexpected character in identifier?  %v", string(lastRune))
 				return l.errorToken("unexpected character in identifier:  " + string(lastRune))
 			}
 			wasQouted = true
 			l.backup()
-			//u.Debugf("quoted?:   %v  peek='%v'", l.input[l.start:l.pos], l.PeekX(5))
+			//logging.Debugf("quoted?:   %v  peek='%v'", l.input[l.start:l.pos], l.PeekX(5))
 			l.lastQuoteMark = byte(firstChar)
-			//u.Infof("set last quote mark: %v %v", firstChar, string(firstChar))
+			//logging.Infof("set last quote mark: %v %v", firstChar, string(firstChar))
 			l.Emit(forToken)
 			l.Next()
 			l.ignore()
 			return nil // pop up to parent
 
-		case isIdentityQuoteMark(firstChar):
+		case l.isIdentityQuoteMark(firstChar):
 			// Fields can be bracket or single quote escaped
 			//  [user]
 			//  [email]
 			//  'email'
-			//u.Debugf("in quoted identity")
+			//logging.Debugf("in quoted identity")
 			l.ignore()
 			l.lastQuoteMark = byte(firstChar)
 			nextChar := l.Next()
 			if !unicode.IsLetter(nextChar) {
 				l.ignore()
-				u.Warnf("aborting LexIdentifierOfType: %v", l.PeekX(5))
+				logging.Warnf("aborting LexIdentifierOfType: %v", l.PeekX(5))
 				return nil
 				//return l.errorToken("identifier must begin with a letter " + l.PeekX(3))
 			}
@@ -1139,19 +1304,19 @@ func LexIdentifierOfType(forToken TokenType) StateFn {
 			// iterate until we find non-identifier, then make sure it is valid/end
 			if firstChar == '[' && nextChar == ']' {
 				// valid
-			} else if firstChar == nextChar && isIdentityQuoteMark(nextChar) {
+			} else if firstChar == nextChar && l.isIdentityQuoteMark(nextChar) {
 				// also valid
 			} else {
-				u.Errorf("unexpected character in identifier?  %v", string(nextChar))
+				logging.Errorf("unexpected character in identifier?  %v", string(nextChar))
 				return l.errorToken("unexpected character in identifier:  " + string(nextChar))
 			}
 			wasQouted = true
 			l.backup()
-			//u.Debugf("quoted?:   %v  ", l.input[l.start:l.pos])
+			//logging.Debugf("quoted?:   %v  ", l.input[l.start:l.pos])
 		default:
 			l.lastQuoteMark = 0
 			if !isIdentifierFirstRune(firstChar) && !isDigit(firstChar) {
-				//u.Warnf("aborting LexIdentifier: '%v'", string(firstChar))
+				//logging.Warnf("aborting LexIdentifier: '%v'", string(firstChar))
 				return l.errorToken("identifier must begin with a letter " + string(l.input[l.start:l.pos]))
 			}
 			allDigits := isDigit(firstChar)
@@ -1165,21 +1330,68 @@ func LexIdentifierOfType(forToken TokenType) StateFn {
 				return l.errorToken("identifier must begin with a letter " + string(l.input[l.start:l.pos]))
 			}
 			l.backup()
+			if word := l.input[l.start:l.pos]; l.dialect.rejectsReserved(word) {
+				return l.errorf("reserved word %q must be quoted to be an identity", word)
+			}
 		}
 
-		//u.Debugf("about to emit: %v", forToken)
+		//logging.Debugf("about to emit: %v", forToken)
 		l.Emit(forToken)
 		if wasQouted {
 			// need to skip last character bc it was quoted
 			l.Next()
 			l.ignore()
 		}
+		if forToken == TokenIdentity && l.Peek() == '[' {
+			//  tags['env']
+			return lexSubscript
+		}
 
-		//u.Debugf("about to return:  %v", nextFn)
+		//logging.Debugf("about to return:  %v", nextFn)
 		return nil // pop up to parent
 	}
 }
 
+// lexSubscript scans the subscript(s) directly following an identity, a
+//  quoted map key or (signed) integer array index
+//
+//     tags['env']
+//     doc["a"]["b"]
+//     arr[0]
+//     arr[-1]
+//
+func lexSubscript(l *Lexer) StateFn {
+	if l.Peek() != '[' {
+		return nil // pop up to parent
+	}
+	l.Next()
+	l.Emit(TokenLeftBracket)
+	return lexSubscriptKey
+}
+
+func lexSubscriptKey(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	switch r := l.Peek(); {
+	case r == '\'' || r == '"':
+		l.Push("lexSubscriptEnd", lexSubscriptEnd)
+		return LexValue
+	case r == '-' || isDigit(r):
+		l.Push("lexSubscriptEnd", lexSubscriptEnd)
+		return LexNumber
+	}
+	return l.errorToken("expected subscript key or index but got: " + l.PeekX(5))
+}
+
+func lexSubscriptEnd(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if l.Next() != ']' {
+		l.backup()
+		return l.errorToken("expected ] but got: " + l.PeekX(5))
+	}
+	l.Emit(TokenRightBracket)
+	return lexSubscript
+}
+
 var LexDataTypeIdentity = LexDataType(TokenDataType)
 
 // LexDataType scans and finds datatypes
@@ -1195,12 +1407,12 @@ func LexDataType(forToken TokenType) StateFn {
 	return func(l *Lexer) StateFn {
 		l.SkipWhiteSpaces()
 
-		//u.Debugf("LexDataType: %v", l.PeekX(5))
+		//logging.Debugf("LexDataType: %v", l.PeekX(5))
 
 		// Since we escaped this with a quote we allow laxIdentifier characters
 		for {
 			r := l.Next()
-			//u.Infof("r=%v %v    ws=%v", string(r), r, isWhiteSpace(r))
+			//logging.Infof("r=%v %v    ws=%v", string(r), r, isWhiteSpace(r))
 			switch {
 			case r == '[' || r == ']':
 				// ok, continue
@@ -1224,7 +1436,7 @@ func LexDataType(forToken TokenType) StateFn {
 func LexEndOfStatement(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	r := l.Next()
-	//u.Debugf("sqlend of statement  '%s' r=%d", string(r), r)
+	//logging.Debugf("sqlend of statement  '%s' r=%d", string(r), r)
 	if r == ';' {
 		l.Emit(TokenEOS)
 	}
@@ -1232,7 +1444,7 @@ func LexEndOfStatement(l *Lexer) StateFn {
 	if l.IsEnd() {
 		return nil
 	}
-	u.Warnf("error looking for end of statement: '%v'", l.remainder())
+	logging.Warnf("error looking for end of statement: '%v'", l.remainder())
 	return l.errorToken("Unexpected token:" + l.current())
 }
 
@@ -1252,7 +1464,7 @@ func LexSelectClause(l *Lexer) StateFn {
 	}
 	first := strings.ToLower(l.PeekX(2))
 
-	//u.Debugf("LexSelectClause  '%v'  %v", first, l.PeekX(10))
+	//logging.Debugf("LexSelectClause  '%v'  %v", first, l.PeekX(10))
 
 	switch first {
 	case "al": //ALL?
@@ -1266,26 +1478,27 @@ func LexSelectClause(l *Lexer) StateFn {
 		if word == "distinct" {
 			l.ConsumeWord(word)
 			l.Emit(TokenDistinct)
+			return lexDistinct
 		} // DISTINCTROW?
 	case "* ":
 		// Look for keyword, ie something like FROM, or possibly end of statement
 		l.Next()           // consume the *
 		pw := l.PeekWord() // this will skip whitespace
-		//u.Debugf("* ?'%v'  keyword='%v'", first, pw)
+		//logging.Debugf("* ?'%v'  keyword='%v'", first, pw)
 		if l.isNextKeyword(pw) {
 			//   select * from
 			l.Emit(TokenStar)
 			return nil
 		}
 		l.backup()
-		//u.Warnf("What is this? %v", l.PeekX(10))
+		//logging.Warnf("What is this? %v", l.PeekX(10))
 	case "@@": //  mysql system variables start with @@
 		l.Next()
 		l.Next()
 		word := strings.ToLower(l.PeekWord())
 		l.ConsumeWord(word)
 		l.Emit(TokenIdentity)
-		//u.Debugf("Found Sql Variable:  @@%v", word)
+		//logging.Debugf("Found Sql Variable:  @@%v", word)
 		return nil
 	}
 
@@ -1298,11 +1511,54 @@ func LexSelectClause(l *Lexer) StateFn {
 	return LexSelectList
 }
 
+// after SELECT DISTINCT, an ON (columns) else the select list
+func lexDistinct(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if strings.ToLower(l.PeekWord()) == "on" {
+		l.ConsumeWord("on")
+		l.Emit(TokenOn)
+		return lexDistinctOn
+	}
+	return LexSelectClause
+}
+
+// the parenthesized columns of a DISTINCT ON, then the select list
+//
+//     SELECT DISTINCT ON (user_id) * FROM events ORDER BY user_id, ts DESC
+func lexDistinctOn(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if l.IsEnd() {
+		return l.errorToken("unexpected eof in DISTINCT ON")
+	}
+	switch r := l.Peek(); {
+	case r == '(':
+		l.Next()
+		l.Emit(TokenLeftParenthesis)
+		return lexDistinctOn
+	case r == ')':
+		l.Next()
+		l.Emit(TokenRightParenthesis)
+		return LexSelectClause
+	case r == ',':
+		l.Next()
+		l.Emit(TokenComma)
+		return lexDistinctOn
+	case r == '`' || r == '[':
+		l.Push("lexDistinctOn", lexDistinctOn)
+		return LexIdentifier
+	}
+	if l.PeekWord() == "" {
+		return l.errorToken("unexpected in DISTINCT ON: " + l.PeekX(10))
+	}
+	l.Push("lexDistinctOn", lexDistinctOn)
+	return LexIdentifier
+}
+
 // Handle recursive subqueries
 //
 func LexSubQuery(l *Lexer) StateFn {
 
-	//u.Debugf("LexSubQuery  '%v'", l.PeekX(10))
+	//logging.Debugf("LexSubQuery  '%v'", l.PeekX(10))
 	l.SkipWhiteSpaces()
 	if l.IsEnd() {
 		return nil
@@ -1335,6 +1591,86 @@ func LexSubQuery(l *Lexer) StateFn {
 	return LexSelectClause
 }
 
+// Handle common table expressions, followed by the statement using them
+//
+//    WITH <cte> [, <cte>]* SELECT ...
+//
+//    <cte> := <identity> AS '(' <select_stmt> ')'
+//
+func LexCommonTableExpressions(l *Lexer) StateFn {
+
+	l.SkipWhiteSpaces()
+	if l.IsEnd() {
+		return nil
+	}
+	switch l.Peek() {
+	case ',':
+		l.Next()
+		l.Emit(TokenComma)
+		return LexCommonTableExpressions
+	case '(':
+		l.Next()
+		l.Emit(TokenLeftParenthesis)
+		l.SkipWhiteSpaces()
+		return lexSubSelect(l, LexCommonTableExpressions)
+	}
+
+	word := strings.ToLower(l.PeekWord())
+	switch word {
+	case "as":
+		l.ConsumeWord(word)
+		l.Emit(TokenAs)
+		return LexCommonTableExpressions
+	case "select":
+		// the statement the cte's are for, lex it as a whole new statement
+		for _, stmt := range l.dialect.Statements {
+			if stmt.Token == TokenSelect {
+				l.stack = l.stack[:0]
+				l.statement = stmt
+				l.curClause = stmt.Clauses[0]
+				return LexStatement
+			}
+		}
+		return l.errorToken("dialect has no select statement")
+	}
+	l.Push("LexCommonTableExpressions", LexCommonTableExpressions)
+	return LexIdentifier
+}
+
+// LexSetOperation is the rest of a set operation after its UNION, EXCEPT or
+//  INTERSECT keyword, the right select is lexed as a whole new statement
+//
+//    SELECT ... EXCEPT [ALL|DISTINCT] SELECT ...
+func LexSetOperation(l *Lexer) StateFn {
+
+	l.SkipWhiteSpaces()
+	if l.IsEnd() {
+		return nil
+	}
+	word := strings.ToLower(l.PeekWord())
+	switch word {
+	case "all":
+		l.ConsumeWord(word)
+		l.Emit(TokenAll)
+		return LexSetOperation
+	case "distinct":
+		l.ConsumeWord(word)
+		l.Emit(TokenDistinct)
+		return LexSetOperation
+	case "select":
+		for _, stmt := range l.dialect.Statements {
+			if stmt.Token == TokenSelect {
+				l.stack = l.stack[:0]
+				l.statement = stmt
+				l.curClause = stmt.Clauses[0]
+				return LexStatement
+			}
+		}
+		return l.errorToken("dialect has no select statement")
+	}
+	return l.errorToken("expected select after set operation but got: " + word)
+}
+
 // Handle prepared statements
 //
 // <PREPARE_STMT> := PREPARE <identity>	FROM <string_value>
@@ -1345,7 +1681,7 @@ func LexPreparedStatement(l *Lexer) StateFn {
 	if l.IsEnd() {
 		return nil
 	}
-	//u.Debugf("LexPreparedStatement  '%v'", l.PeekX(10))
+	//logging.Debugf("LexPreparedStatement  '%v'", l.PeekX(10))
 
 	/*
 		TODO:   this is a bit different from others, as after we get FROM
@@ -1382,12 +1718,12 @@ func LexSelectList(l *Lexer) StateFn {
 	if l.IsEnd() {
 		return nil
 	}
-	//u.Debugf("LexSelectList  '%v'", l.PeekX(10))
+	//logging.Debugf("LexSelectList  '%v'", l.PeekX(10))
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("LexTableReferences looking for operator:  word=%s", word)
+	//logging.Debugf("LexTableReferences looking for operator:  word=%s", word)
 	switch word {
 	case "as":
-		//u.Warnf("emit from")
+		//logging.Warnf("emit from")
 		l.ConsumeWord(word)
 		l.Emit(TokenAs)
 		l.Push("LexSelectList", LexSelectList)
@@ -1399,10 +1735,98 @@ func LexSelectList(l *Lexer) StateFn {
 		l.Push("LexSelectList", LexSelectList)
 		//l.Push("LexExpression", LexExpression)
 		return LexExpression
+	case "over":
+		l.ConsumeWord(word)
+		l.Emit(TokenOver)
+		l.Push("LexSelectList", LexSelectList)
+		return lexWindow
 	}
 	return LexExpression
 }
 
+// the parenthesized window of a window function column, after OVER
+//
+//     avg(x) OVER (ROWS 5 PRECEDING)
+//     row_number() OVER (PARTITION BY g ORDER BY n DESC)
+//     sum(x) OVER (ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+func lexWindow(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if l.IsEnd() {
+		return l.errorToken("unexpected eof in window")
+	}
+	switch r := l.Peek(); {
+	case r == '(':
+		l.Next()
+		l.Emit(TokenLeftParenthesis)
+		return lexWindow
+	case r == ')':
+		l.Next()
+		l.Emit(TokenRightParenthesis)
+		return nil
+	case isDigit(r):
+		l.Push("lexWindow", lexWindow)
+		return LexNumber
+	case r == ',':
+		l.Next()
+		l.Emit(TokenComma)
+		return lexWindow
+	case r == '`' || r == '[':
+		l.Push("lexWindow", lexWindow)
+		return LexIdentifier
+	}
+	word := strings.ToLower(l.PeekWord())
+	switch word {
+	case "partition", "order":
+		l.ConsumeWord(word)
+		l.SkipWhiteSpaces()
+		if strings.ToLower(l.PeekWord()) != "by" {
+			return l.errorToken("expected BY but got: " + l.PeekX(10))
+		}
+		l.ConsumeWord("by")
+		if word == "order" {
+			l.emit(TokenOrderBy, "ORDER BY")
+		} else {
+			l.emit(TokenPartitionBy, "PARTITION BY")
+		}
+	case "asc":
+		l.ConsumeWord(word)
+		l.Emit(TokenAsc)
+	case "desc":
+		l.ConsumeWord(word)
+		l.Emit(TokenDesc)
+	case "rows":
+		l.ConsumeWord(word)
+		l.Emit(TokenRows)
+	case "between":
+		l.ConsumeWord(word)
+		l.Emit(TokenBetween)
+	case "and":
+		l.ConsumeWord(word)
+		l.Emit(TokenLogicAnd)
+	case "preceding":
+		l.ConsumeWord(word)
+		l.Emit(TokenPreceding)
+	case "unbounded":
+		l.ConsumeWord(word)
+		l.Emit(TokenUnbounded)
+	case "current":
+		l.ConsumeWord(word)
+		l.SkipWhiteSpaces()
+		if strings.ToLower(l.PeekWord()) != "row" {
+			return l.errorToken("expected CURRENT ROW but got: " + l.PeekX(10))
+		}
+		l.ConsumeWord("row")
+		l.emit(TokenCurrentRow, "CURRENT ROW")
+	case "":
+		return l.errorToken("unexpected in window: " + l.PeekX(10))
+	default:
+		// a column of the partition by, order by
+		l.Push("lexWindow", lexWindow)
+		return LexIdentifier
+	}
+	return lexWindow
+}
+
 // Handle Table References ie From table, and SubSelects, Joins
 //
 //    SELECT ...  [FROM <table_references>]
@@ -1422,7 +1846,7 @@ func LexTableReferences(l *Lexer) StateFn {
 
 	l.SkipWhiteSpaces()
 
-	//u.Debugf("LexTableReferences  peek2= '%v'  isEnd?%v", l.PeekX(2), l.IsEnd())
+	//logging.Debugf("LexTableReferences  peek2= '%v'  isEnd?%v", l.PeekX(2), l.IsEnd())
 
 	if l.IsEnd() {
 		return nil
@@ -1438,6 +1862,11 @@ func LexTableReferences(l *Lexer) StateFn {
 	case '(':
 		l.Next()
 		l.Emit(TokenLeftParenthesis)
+		l.SkipWhiteSpaces()
+		if strings.ToLower(l.PeekWord()) == "select" {
+			// FROM (SELECT ...) AS t
+			return lexSubSelect(l, LexTableReferences)
+		}
 		// subquery?
 		l.Push("LexTableReferences", LexTableReferences)
 		//l.clauseState() = LexSelectClause
@@ -1456,10 +1885,10 @@ func LexTableReferences(l *Lexer) StateFn {
 	}
 
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("LexTableReferences looking for operator:  word=%s", word)
+	//logging.Debugf("LexTableReferences looking for operator:  word=%s", word)
 	switch word {
 	case "from", "select", "where":
-		//u.Warnf("emit from")
+		//logging.Warnf("emit from")
 		// l.ConsumeWord("FROM")
 		// l.Emit(TokenFrom)
 		// l.Push("LexTableReferences", LexTableReferences)
@@ -1493,6 +1922,10 @@ func LexTableReferences(l *Lexer) StateFn {
 		//l.Push("LexTableReferences", LexTableReferences)
 		//l.Push("LexExpression", LexExpression)
 		return LexTableReferences
+	case "lateral":
+		l.ConsumeWord(word)
+		l.Emit(TokenLateral)
+		return LexTableReferences
 	case "on": //
 		l.ConsumeWord(word)
 		l.Emit(TokenOn)
@@ -1508,28 +1941,91 @@ func LexTableReferences(l *Lexer) StateFn {
 	default:
 		r = l.Peek()
 		if r == ',' {
+			// FROM users, unnest(tags) AS t
+			l.Next()
 			l.Emit(TokenComma)
-			l.Push("LexTableReferences", LexTableReferences)
-			return LexExpressionOrIdentity
+			return LexTableReferences
 		}
 		if l.isNextKeyword(word) {
-			//u.Warnf("found keyword? %v ", word)
+			//logging.Warnf("found keyword? %v ", word)
 			return nil
 		}
 	}
-	//u.LogTracef(u.WARN, "hmmmmmmm")
-	//u.Debugf("LexTableReferences = '%v'", string(r))
+	//logging.Warnf("hmmmmmmm")
+	//logging.Debugf("LexTableReferences = '%v'", string(r))
 	// ensure we don't get into a recursive death spiral here?
 	if len(l.stack) < 100 {
 		l.Push("LexTableReferences", LexTableReferences)
 	} else {
-		u.Errorf("Gracefully refusing to add more LexTableReferences: ")
+		logging.Errorf("Gracefully refusing to add more LexTableReferences: ")
 	}
 
 	// Since we did Not find anything, we are going to go for a Expression or Identity
 	return LexExpressionOrIdentity
 }
 
+// lexSubSelect lexes the parenthesized select of a derived table
+//  ie  FROM (SELECT ...) AS t   using a nested lexer on the text up to the
+//  matching right paren, so the sub-select gets the full set of select
+//  clauses (group by, having etc).   Token positions are those of the
+//  outer input.   After the right paren lexing continues with @after.
+func lexSubSelect(l *Lexer, after StateFn) StateFn {
+	offset := l.pos
+	end := matchingParen(l.input[offset:])
+	if end < 0 {
+		return l.errorToken("missing right paren for sub-select")
+	}
+	sub := NewLexer(l.input[offset:offset+end], l.dialect)
+	var next StateFn
+	next = func(l *Lexer) StateFn {
+		tok := sub.NextToken()
+		switch tok.T {
+		case TokenEOF, TokenEOS:
+			l.pos = offset + end
+			l.start = l.pos
+			l.Next()
+			l.Emit(TokenRightParenthesis)
+			return after
+		}
+		tok.Pos += offset
+		l.lastToken = tok
+		l.tokens <- tok
+		if tok.T == TokenError {
+			return nil
+		}
+		return next
+	}
+	return next
+}
+
+// find the index of the right paren closing an already consumed left
+//  paren, skipping over quoted text.  Returns -1 if not found.
+func matchingParen(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
 // Handle repeating Insert/Upsert/Update statements
 //
 //     <insert_into> ( SET <upsert_cols> | <col_names> VALUES <col_value_list> )
@@ -1560,7 +2056,7 @@ func LexTableColumns(l *Lexer) StateFn {
 		return LexTableColumns
 	}
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("looking for tablecolumns:  word=%s r=%s", word, string(r))
+	//logging.Debugf("looking for tablecolumns:  word=%s r=%s", word, string(r))
 	switch word {
 	case "values":
 		l.ConsumeWord(word)
@@ -1599,7 +2095,7 @@ func LexTableColumns(l *Lexer) StateFn {
 //
 func LexConditionalClause(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
-	//u.Debugf("lexConditional: %v", l.PeekX(14))
+	//logging.Debugf("lexConditional: %v", l.PeekX(14))
 	if l.IsEnd() {
 		return nil
 	}
@@ -1621,7 +2117,7 @@ func LexConditionalClause(l *Lexer) StateFn {
 		return LexConditionalClause
 	}
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("word: %v", word)
+	//logging.Debugf("word: %v", word)
 	switch word {
 	case "select", "where", "from":
 		return LexSubQuery
@@ -1630,7 +2126,7 @@ func LexConditionalClause(l *Lexer) StateFn {
 		return nil
 	}
 	l.Push("LexConditionalClause", LexConditionalClause)
-	//u.Debugf("go to lex expression: %v", l.PeekX(20))
+	//logging.Debugf("go to lex expression: %v", l.PeekX(20))
 	return LexExpression(l)
 	//return XXXLexConditionalClause(l)
 }
@@ -1640,6 +2136,54 @@ func LexColumns(l *Lexer) StateFn {
 	return LexExpression(l)
 }
 
+// LexGroupBy lexes the GROUP BY expressions, or grouping sets
+//
+//     GROUP BY a, b
+//     GROUP BY ROLLUP(a, b)
+//     GROUP BY GROUPING SETS ((a, b), (a), ())
+func LexGroupBy(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if strings.ToLower(l.PeekWord()) == "grouping" {
+		l.ConsumeWord("grouping")
+		for isWhiteSpace(l.Peek()) {
+			l.Next()
+		}
+		if strings.ToLower(l.PeekWord()) != "sets" {
+			return l.errorf("expected SETS after GROUPING but got: %v", l.PeekWord())
+		}
+		l.ConsumeWord("sets")
+		l.emit(TokenGroupingSets, "GROUPING SETS")
+		return lexGroupingSets
+	}
+	return LexColumns
+}
+
+// the parenthesized lists of grouping sets,  ((a, b), (a), ())
+func lexGroupingSets(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	if l.IsEnd() {
+		return nil
+	}
+	switch l.Next() {
+	case '(':
+		l.Emit(TokenLeftParenthesis)
+		return lexGroupingSets
+	case ')':
+		l.Emit(TokenRightParenthesis)
+		return lexGroupingSets
+	case ',':
+		l.Emit(TokenComma)
+		return lexGroupingSets
+	}
+	l.backup()
+	word := l.PeekWord()
+	if word == "" || l.isNextKeyword(word) {
+		return nil
+	}
+	l.Push("lexGroupingSets", lexGroupingSets)
+	return LexExpressionOrIdentity
+}
+
 // <expr>   Handle single logical expression which may be nested and  has
 //           udf names that are NOT validated by lexer
 //
@@ -1669,7 +2213,7 @@ func LexExpression(l *Lexer) StateFn {
 	}
 	r := l.Next()
 
-	//u.Debugf("LexExpression  r= '%v'", string(r))
+	//logging.Debugf("LexExpression  r= '%v'", string(r))
 
 	// Cover the logic and grouping
 	switch r {
@@ -1697,10 +2241,10 @@ func LexExpression(l *Lexer) StateFn {
 		case '(': // this is a logical Grouping/Ordering
 			//l.Push("LexParenEnd", LexParenEnd)
 			l.Emit(TokenLeftParenthesis)
-			//u.Debugf("return from left paren %v", l.PeekX(5))
+			//logging.Debugf("return from left paren %v", l.PeekX(5))
 			return LexExpression //l.clauseState()
 		case ')': // this is a logical Grouping/Ordering
-			//u.Debugf("emit right paren")
+			//logging.Debugf("emit right paren")
 			l.Emit(TokenRightParenthesis)
 			return nil
 		case ',':
@@ -1713,14 +2257,14 @@ func LexExpression(l *Lexer) StateFn {
 				foundLogical = true
 			} else {
 				l.Emit(TokenNegate)
-				//u.Debugf("Found ! Negate")
+				//logging.Debugf("Found ! Negate")
 				return nil
 			}
 		case '=':
 			if r2 := l.Peek(); r2 == '=' {
 				l.Next()
 				l.Emit(TokenEqualEqual)
-				//u.Infof("found ==  peek5='%v'", string(l.PeekX(5)))
+				//logging.Infof("found ==  peek5='%v'", string(l.PeekX(5)))
 				foundOperator = true
 			} else {
 				l.Emit(TokenEqual)
@@ -1729,7 +2273,11 @@ func LexExpression(l *Lexer) StateFn {
 		case '|':
 			if r2 := l.Peek(); r2 == '|' {
 				l.Next()
-				l.Emit(TokenOr)
+				if l.dialect != nil && l.dialect.Concat == ConcatAnsi {
+					l.Emit(TokenConcat)
+				} else {
+					l.Emit(TokenOr)
+				}
 				foundOperator = true
 			}
 		case '&':
@@ -1784,12 +2332,12 @@ func LexExpression(l *Lexer) StateFn {
 			foundOperator = true
 		}
 		if foundLogical == true {
-			//u.Debugf("found LexExpression = '%v'", string(r))
+			//logging.Debugf("found LexExpression = '%v'", string(r))
 			// There may be more than one item here
 			//l.Push("l.clauseState()", l.clauseState())
 			return LexExpression
 		} else if foundOperator {
-			//u.Debugf("found LexExpression = peek5='%v'", string(l.PeekX(5)))
+			//logging.Debugf("found LexExpression = peek5='%v'", string(l.PeekX(5)))
 			// There may be more than one item here
 			//l.Push("l.clauseState()", l.clauseState())
 			return LexExpression
@@ -1798,18 +2346,29 @@ func LexExpression(l *Lexer) StateFn {
 
 	l.backup()
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("looking for operator:  word=%s", word)
+	//logging.Debugf("looking for operator:  word=%s", word)
 	switch word {
-	case "in", "like", "between": // what is complete list here?
+	case "in", "like", "ilike", "between": // what is complete list here?
 		switch word {
 		case "in":
 			l.ConsumeWord(word)
 			l.Emit(TokenIN)
 			l.Push("LexListOfArgs", LexListOfArgs)
 			return nil
-		case "like":
+		case "like", "ilike":
 			l.ConsumeWord(word)
-			l.Emit(TokenLike)
+			if word == "ilike" {
+				l.Emit(TokenILike)
+			} else {
+				l.Emit(TokenLike)
+			}
+			// pattern may be followed by an optional ESCAPE clause
+			l.Push("LexExpression", l.clauseState())
+			l.SkipWhiteSpaces()
+			if r := l.Peek(); r == '\'' || r == '"' {
+				// quoted patterns are always values, never identities
+				return LexValue
+			}
 			return LexExpressionOrIdentity
 		case "between":
 			l.ConsumeWord(word)
@@ -1818,6 +2377,11 @@ func LexExpression(l *Lexer) StateFn {
 			l.Push("LexExpressionOrIdentity", LexExpressionOrIdentity)
 			return nil
 		}
+	case "escape":
+		//  x LIKE 'a!%' ESCAPE '!'
+		l.ConsumeWord(word)
+		l.Emit(TokenEscape)
+		return LexValue
 	case "is":
 		l.ConsumeWord(word)
 		l.Emit(TokenIs)
@@ -1830,7 +2394,7 @@ func LexExpression(l *Lexer) StateFn {
 		// somewhat weird edge case, not is either word not, or expression
 		// not exactly context-free
 		pr := l.peekXrune(len(word))
-		//u.Infof("not?  %v", string(pr))
+		//logging.Infof("not?  %v", string(pr))
 		if pr != '(' {
 			l.ConsumeWord(word)
 			l.Emit(TokenNegate)
@@ -1860,24 +2424,24 @@ func LexExpression(l *Lexer) StateFn {
 			return LexExpressionOrIdentity
 		}
 		if l.isNextKeyword(word) {
-			//u.Debugf("found keyword? %v ", word)
+			//logging.Debugf("found keyword? %v ", word)
 			return nil
 		}
 	}
-	//u.LogTracef(u.WARN, "hmmmmmmm")
-	//u.Debugf("LexExpression = '%v'", string(r))
+	//logging.Warnf("hmmmmmmm")
+	//logging.Debugf("LexExpression = '%v'", string(r))
 	// ensure we don't get into a recursive death spiral here?
 	if len(l.stack) < 100 {
 		l.Push("LexExpression", l.clauseState())
 	} else {
-		u.Errorf("Gracefully refusing to add more LexExpression: ")
+		logging.Errorf("Gracefully refusing to add more LexExpression: ")
 	}
 	return LexExpressionOrIdentity
 }
 
-// Handle columnar identies with keyword appendate (ASC, DESC)
+// Handle columnar identies with keyword appendate (ASC, DESC, NULLS FIRST|LAST)
 //
-//     [ORDER BY] ( <identity> | <expr> ) [(ASC | DESC)]
+//     [ORDER BY] ( <identity> | <expr> ) [(ASC | DESC)] [NULLS (FIRST | LAST)]
 //
 func LexOrderByColumn(l *Lexer) StateFn {
 
@@ -1887,7 +2451,7 @@ func LexOrderByColumn(l *Lexer) StateFn {
 	}
 
 	r := l.Peek()
-	//u.Debugf("LexOrderBy  r= '%v'  %v", string(r), l.PeekX(10))
+	//logging.Debugf("LexOrderBy  r= '%v'  %v", string(r), l.PeekX(10))
 
 	switch r {
 	case '`':
@@ -1903,11 +2467,11 @@ func LexOrderByColumn(l *Lexer) StateFn {
 	}
 
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("word: %v", word)
+	//logging.Debugf("word: %v", word)
 	if l.isNextKeyword(word) {
 		return nil
 	}
-	//u.Debugf("looking for operator:  word=%s", word)
+	//logging.Debugf("looking for operator:  word=%s", word)
 	switch word {
 	case "asc":
 		l.ConsumeWord(word)
@@ -1917,12 +2481,16 @@ func LexOrderByColumn(l *Lexer) StateFn {
 		l.ConsumeWord(word)
 		l.Emit(TokenDesc)
 		return LexOrderByColumn
+	case "nulls":
+		l.ConsumeWord(word)
+		l.Emit(TokenNulls)
+		return lexNullsPlacement
 	default:
 		if len(l.stack) < 2 {
 			l.Push("LexOrderByColumn", LexOrderByColumn)
 			return LexExpressionOrIdentity
 		} else {
-			u.Errorf("Gracefully refusing to add more LexOrderByColumn: ")
+			logging.Errorf("Gracefully refusing to add more LexOrderByColumn: ")
 		}
 	}
 
@@ -1930,6 +2498,20 @@ func LexOrderByColumn(l *Lexer) StateFn {
 	return nil
 }
 
+// the FIRST | LAST of an order by NULLS placement
+func lexNullsPlacement(l *Lexer) StateFn {
+	l.SkipWhiteSpaces()
+	switch word := strings.ToLower(l.PeekWord()); word {
+	case "first":
+		l.ConsumeWord(word)
+		l.Emit(TokenFirst)
+	case "last":
+		l.ConsumeWord(word)
+		l.Emit(TokenLast)
+	}
+	return LexOrderByColumn
+}
+
 // data definition language column
 //
 //   CHANGE col1_old col1_new varchar(10),
@@ -1942,7 +2524,7 @@ func LexDdlColumn(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	r := l.Next()
 
-	//u.Debugf("LexDdlColumn  r= '%v'", string(r))
+	//logging.Debugf("LexDdlColumn  r= '%v'", string(r))
 
 	// Cover the logic and grouping
 	switch r {
@@ -1964,7 +2546,7 @@ func LexDdlColumn(l *Lexer) StateFn {
 
 	l.backup()
 	word := strings.ToLower(l.PeekWord())
-	//u.Debugf("looking for operator:  word=%s", word)
+	//logging.Debugf("looking for operator:  word=%s", word)
 	switch word {
 	case "change":
 		l.ConsumeWord(word)
@@ -2016,18 +2598,18 @@ func LexDdlColumn(l *Lexer) StateFn {
 			return LexExpressionOrIdentity
 		}
 		if l.isNextKeyword(word) {
-			u.Infof("found keyword? %v ", word)
+			logging.Infof("found keyword? %v ", word)
 			return nil
 		}
 	}
-	//u.LogTracef(u.WARN, "hmmmmmmm")
-	//u.Infof("LexDdlColumn = '%v'", string(r))
+	//logging.Warnf("hmmmmmmm")
+	//logging.Infof("LexDdlColumn = '%v'", string(r))
 
 	// ensure we don't get into a recursive death spiral here?
 	if len(l.stack) < 100 {
 		l.Push("LexDdlColumn", l.clauseState())
 	} else {
-		u.Errorf("Gracefully refusing to add more LexDdlColumn: ")
+		logging.Errorf("Gracefully refusing to add more LexDdlColumn: ")
 	}
 	return LexExpressionOrIdentity
 }
@@ -2043,12 +2625,12 @@ func LexJson(l *Lexer) StateFn {
 		return nil
 	}
 	r := l.Peek()
-	//u.Debugf("LexJson  '%v'  %v", string(r), l.PeekX(10))
+	//logging.Debugf("LexJson  '%v'  %v", string(r), l.PeekX(10))
 	switch r {
 	case '{', '[':
 		return LexJsonValue
 	}
-	//u.Warnf("Did not find json? %v", l.PeekX(20))
+	//logging.Warnf("Did not find json? %v", l.PeekX(20))
 	return nil
 }
 
@@ -2070,7 +2652,7 @@ func LexJsonValue(l *Lexer) StateFn {
 		return nil
 	}
 	r := l.Peek()
-	//u.Debugf("LexJsonValue  '%v'  %v", string(r), l.PeekX(10))
+	//logging.Debugf("LexJsonValue  '%v'  %v", string(r), l.PeekX(10))
 	switch r {
 	case '}', ']':
 		return nil // recurse back up one level
@@ -2084,7 +2666,7 @@ func LexJsonValue(l *Lexer) StateFn {
 		l.Emit(TokenLeftBracket)
 		return LexJsonArray
 	case ',':
-		u.Warnf("Should not be possible to get comma here?")
+		logging.Warnf("Should not be possible to get comma here?")
 	default:
 		return LexValue(l)
 	}
@@ -2102,7 +2684,7 @@ func LexJsonArray(l *Lexer) StateFn {
 		return nil
 	}
 	r := l.Peek()
-	//u.Debugf("LexJsonArray  '%v'  %v", string(r), l.PeekX(10))
+	//logging.Debugf("LexJsonArray  '%v'  %v", string(r), l.PeekX(10))
 	switch r {
 	case ']':
 		l.Next()
@@ -2124,12 +2706,12 @@ func LexJsonArray(l *Lexer) StateFn {
 		return LexJsonArray
 	default:
 		// value
-		//u.Debug("call lex value: %v", l.PeekX(10))
+		//logging.Debug("call lex value: %v", l.PeekX(10))
 		l.Push("LexJsonArray", LexJsonArray)
 		return LexValue(l)
 	}
 
-	//u.Warnf("Did not find json? %v", l.PeekX(20))
+	//logging.Warnf("Did not find json? %v", l.PeekX(20))
 	return nil
 }
 
@@ -2144,7 +2726,7 @@ func LexJsonObject(l *Lexer) StateFn {
 		return nil
 	}
 	r := l.Peek()
-	//u.Debugf("LexJsonObject  '%v'  %v", string(r), l.PeekX(10))
+	//logging.Debugf("LexJsonObject  '%v'  %v", string(r), l.PeekX(10))
 	switch r {
 	case '}':
 		l.Next()
@@ -2172,7 +2754,7 @@ func LexJsonObject(l *Lexer) StateFn {
 		return LexJsonArray
 	}
 
-	u.Warnf("Did not find json? %v", l.PeekX(20))
+	logging.Warnf("Did not find json? %v", l.PeekX(20))
 	return nil
 }
 
@@ -2192,7 +2774,7 @@ func LexJsonIdentity(l *Lexer) StateFn {
 	rune := l.Next()
 
 	typ := TokenIdentity
-	//u.Debugf("in LexStringValue: %v", string(rune))
+	//logging.Debugf("in LexStringValue: %v", string(rune))
 	// quoted string
 	if rune == '\'' || rune == '"' {
 		firstRune := rune
@@ -2200,7 +2782,7 @@ func LexJsonIdentity(l *Lexer) StateFn {
 		previousEscaped := rune == '\\'
 		for rune = l.Next(); ; rune = l.Next() {
 
-			//u.Debugf("LexValue rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
+			//logging.Debugf("LexValue rune=%v  end?%v  prevEscape?%v", string(rune), rune == eof, previousEscaped)
 			if (rune == '\'' || rune == '"') && rune == firstRune && !previousEscaped {
 				if !l.IsEnd() {
 					rune = l.Next()
@@ -2249,6 +2831,18 @@ func lexJsonColon(l *Lexer) StateFn {
 }
 */
 
+// is the remainder a comment LexComment will consume, as opposed to
+//  the leading -, / of an expression such as   -5
+func (l *Lexer) isComment() bool {
+	remainder := l.input[l.pos:]
+	for _, prefix := range []string{"/*", "//", "--", "#"} {
+		if strings.HasPrefix(remainder, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // LexComment looks for valid comments which are any of the following
 //   including the in-line comment blocks
 //
@@ -2260,18 +2854,18 @@ func lexJsonColon(l *Lexer) StateFn {
 //         , age FROM `USER` ...
 //
 func LexComment(l *Lexer) StateFn {
-	//u.Debugf("checking comment: '%s' ", l.input[l.pos:l.pos+2])
+	//logging.Debugf("checking comment: '%s' ", l.input[l.pos:l.pos+2])
 	// TODO:  switch statement instead of strings has prefix
 	if strings.HasPrefix(l.input[l.pos:], "/*") {
 		return LexMultilineComment(l)
 	} else if strings.HasPrefix(l.input[l.pos:], "//") {
-		//u.Debugf("found single line comment:  // ")
+		//logging.Debugf("found single line comment:  // ")
 		return LexInlineComment(l)
 	} else if strings.HasPrefix(l.input[l.pos:], "--") {
-		//u.Debugf("found single line comment:  -- ")
+		//logging.Debugf("found single line comment:  -- ")
 		return LexInlineComment(l)
 	} else if strings.HasPrefix(l.input[l.pos:], "#") {
-		//u.Debugf("found single line comment:  # ")
+		//logging.Debugf("found single line comment:  # ")
 		return LexInlineComment(l)
 	}
 	return nil
@@ -2388,7 +2982,7 @@ func lexSingleLineComment(l *Lexer) StateFn {
 func LexNumber(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	typ, ok := scanNumericOrDuration(l, SUPPORT_DURATION)
-	//u.Debugf("typ  %v   %v", typ, ok)
+	//logging.Debugf("typ  %v   %v", typ, ok)
 	if !ok {
 		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 	}
@@ -2422,7 +3016,7 @@ func LexNumber(l *Lexer) StateFn {
 func LexNumberOrDuration(l *Lexer) StateFn {
 	l.SkipWhiteSpaces()
 	typ, ok := scanNumericOrDuration(l, true)
-	u.Debugf("typ%T   %v", typ, ok)
+	logging.Debugf("typ%T   %v", typ, ok)
 	if !ok {
 		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 	}
@@ -2463,7 +3057,7 @@ func scanNumericOrDuration(l *Lexer, doDuration bool) (typ TokenType, ok bool) {
 	// Optional leading sign.
 	hasSign := l.accept("+-")
 	peek2 := l.PeekX(2)
-	//u.Debugf("scanNumericOrDuration?  '%v'", string(peek2))
+	//logging.Debugf("scanNumericOrDuration?  '%v'", string(peek2))
 	if peek2 == "0x" {
 		// Hexadecimal.
 		if hasSign {
@@ -2493,12 +3087,12 @@ func scanNumericOrDuration(l *Lexer, doDuration bool) (typ TokenType, ok bool) {
 			}
 			typ = TokenFloat
 		} else {
-			if (!hasSign && l.input[l.start] == '0') ||
-				(hasSign && l.input[l.start+1] == '0') {
-				if peek2 == "0 " || peek2 == "0," || peek2 == "0)" {
-					return typ, true
-				}
-				// Integers can't start with 0.
+			digits := l.input[l.start:l.pos]
+			if hasSign {
+				digits = digits[1:]
+			}
+			if len(digits) > 1 && digits[0] == '0' {
+				// Integers can't start with 0, other than 0 itself.
 				return
 			}
 		}
@@ -2621,6 +3215,20 @@ func isIdentityQuoteMark(r rune) bool {
 	return bytes.IndexByte(IdentityQuoting, byte(r)) >= 0
 }
 
+// the identity quote marks of the lexer dialect: a dialect quoting
+//  identities with double quotes (ansi) has single quoted values
+func (l *Lexer) isIdentityQuoteMark(r rune) bool {
+	if quote := rune(l.dialect.Quote()); quote != '`' {
+		switch r {
+		case quote:
+			return true
+		case '\'', '"':
+			return false
+		}
+	}
+	return isIdentityQuoteMark(r)
+}
+
 func isJsonStart(r rune) bool {
 	if r == '{' || r == '[' {
 		return true
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/bmizerany/assert"
 	"strings"
 	"testing"
@@ -18,6 +19,7 @@ func init() {
 	if *VerboseTests {
 		u.SetupLogging("debug")
 		u.SetColorOutput()
+		logging.SetLogger(logging.GouLogger{})
 	}
 }
 
@@ -59,24 +61,60 @@ func TestLexValue(t *testing.T) {
 	tok := token(`"hello's with quote"`, LexValue)
 	assert.T(t, tok.T == TokenValue && tok.V == "hello's with quote")
 
+	// escaped quotes are decoded into the value
 	rawValue := `hello\"s with quote`
 	quotedValue := fmt.Sprintf(`"%s"`, rawValue)
 	tok = token(quotedValue, LexValue)
-	assert.Tf(t, tok.T == TokenValue && strings.EqualFold(rawValue, tok.V), "%v", tok)
+	assert.Tf(t, tok.T == TokenValue && tok.V == `hello"s with quote`, "%v", tok)
 
 	rawValue = `string with \"double quotes\"`
 	quotedValue = fmt.Sprintf(`"%s"`, rawValue)
 	tok = token(quotedValue, LexValue)
-	assert.Tf(t, tok.T == TokenValue && strings.EqualFold(rawValue, tok.V), "%v", tok)
+	assert.Tf(t, tok.T == TokenValue && tok.V == `string with "double quotes"`, "%v", tok)
 
 	rawValue = `string with \'single quotes\'`
 	quotedValue = fmt.Sprintf(`"%s"`, rawValue)
 	tok = token(quotedValue, LexValue)
-	assert.Tf(t, tok.T == TokenValue && strings.EqualFold(rawValue, tok.V), "%v", tok)
+	assert.Tf(t, tok.T == TokenValue && tok.V == `string with 'single quotes'`, "%v", tok)
 	//u.Debugf("qv: %v rv:%v ", quotedValue, rawValue)
 	//u.Debugf("%v", strings.EqualFold(rawValue, tok.V), tok.V)
 }
 
+func TestLexDollarQuoted(t *testing.T) {
+	tests := []struct{ quoted, v string }{
+		{`$$it's$$`, "it's"},
+		{`$$ {"a": "b\n"} $$`, ` {"a": "b\n"} `},
+		{`$re$^a\d+$$re$`, `^a\d+$`},
+		{`$$$$`, ""},
+	}
+	for _, test := range tests {
+		tok := token(test.quoted, LexValue)
+		assert.Tf(t, tok.T == TokenValue && tok.V == test.v, "%s want %q got %q", test.quoted, test.v, tok.V)
+	}
+	tok := token(`$$not closed`, LexValue)
+	assert.Tf(t, tok.T == TokenError, "undelimited %v", tok)
+	tok = token(`$a b$ x $a b$`, LexValue)
+	assert.Tf(t, tok.T == TokenError, "bad tag %v", tok)
+}
+
+func TestLexValueEscapes(t *testing.T) {
+	tests := []struct{ quoted, v string }{
+		{`'it\'s'`, "it's"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'line1\nline2'`, "line1\nline2"},
+		{`"tab\there"`, "tab\there"},
+		{`'back\\slash'`, `back\slash`},
+		{`'ends in slash\\'`, `ends in slash\`},
+		{`"caf\u00e9"`, "café"},
+		{`"\U0001F600"`, "\U0001F600"},
+		{`'like\%pattern'`, `like\%pattern`},
+	}
+	for _, test := range tests {
+		tok := token(test.quoted, LexValue)
+		assert.Tf(t, tok.T == TokenValue && tok.V == test.v, "%s want %q got %q", test.quoted, test.v, tok.V)
+	}
+}
+
 func TestLexRegex(t *testing.T) {
 	tok := token(` /^stats\./i `, LexRegex)
 	assert.Tf(t, tok.T == TokenRegex && tok.V == `/^stats\./i`, "%v", tok)
@@ -91,6 +129,8 @@ func TestLexNumber(t *testing.T) {
 		// Decimal
 		"42",
 		"-827",
+		"0",
+		"-0",
 		// Hexadecimal
 		"0x1A2B",
 	}
@@ -98,6 +138,7 @@ func TestLexNumber(t *testing.T) {
 		// Decimal
 		"042",
 		"-0827",
+		"00",
 		// Hexadecimal
 		"-0x1A2B",
 		"0X1A2B",
@@ -232,6 +273,13 @@ func TestLexExpressions(t *testing.T) {
 			tv(TokenInteger, "0"),
 			tv(TokenRightParenthesis, ")"),
 		})
+	// a 0 at the end of the input
+	verifyExpr2Tokens(t, `x / 0`,
+		[]Token{
+			tv(TokenIdentity, "x"),
+			tv(TokenDivide, "/"),
+			tv(TokenInteger, "0"),
+		})
 }
 
 func TestLexPosition(t *testing.T) {
@@ -246,6 +294,34 @@ LIMIT 10`)
 	assert.Tf(t, tokens[4].Pos == 27, "want 27 Pos?%v but has %d", tokens[4], tokens[4].Pos)
 }
 
+func TestLexCommentsInStatement(t *testing.T) {
+	verifyTokens(t, `SELECT /*+ USE_INDEX(users idx_name) */ name /* inline */, age -- the age
+		FROM users WHERE x > 1 /* why */ AND y <= 2 -- trailing`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "name"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "age"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "users"),
+			tv(TokenWhere, "WHERE"),
+			tv(TokenIdentity, "x"),
+			tv(TokenGT, ">"),
+			tv(TokenInteger, "1"),
+			tv(TokenLogicAnd, "AND"),
+			tv(TokenIdentity, "y"),
+			tv(TokenLE, "<="),
+			tv(TokenInteger, "2"),
+		})
+
+	l := NewSqlLexer(`SELECT /*+ NO_CACHE */ a /* not a hint */ FROM t`)
+	for tok := l.NextToken(); tok.T != TokenEOF; tok = l.NextToken() {
+	}
+	hints := l.Hints()
+	assert.Tf(t, len(hints) == 1 && hints[0] == "NO_CACHE", "hints: %v", hints)
+	assert.Tf(t, len(l.Hints()) == 0, "hints are cleared")
+}
+
 func TestLexCommentTypes(t *testing.T) {
 	verifyTokens(t, `--hello
 -- multiple single -- / # line comments w /* more */
@@ -336,7 +412,7 @@ func TestWithDialect(t *testing.T) {
 		{Token: TokenWith, Lexer: LexColumns, Optional: true},
 	}}
 	withDialect := &Dialect{
-		"QL With", []*Clause{withStatement},
+		Name: "QL With", Statements: []*Clause{withStatement},
 	}
 	withDialect.Init()
 	/* Many *ql languages support some type of columnar layout such as:
@@ -434,6 +510,74 @@ func TestWhereClauses(t *testing.T) {
 			tv(TokenValue, "%bob"),
 		})
 
+	verifyTokens(t, `SELECT tags['env'] FROM p WHERE doc["a"]["b"] > 1`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "tags"),
+			tv(TokenLeftBracket, "["),
+			tv(TokenValue, "env"),
+			tv(TokenRightBracket, "]"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "p"),
+			tv(TokenWhere, "WHERE"),
+			tv(TokenIdentity, "doc"),
+			tv(TokenLeftBracket, "["),
+			tv(TokenValue, "a"),
+			tv(TokenRightBracket, "]"),
+			tv(TokenLeftBracket, "["),
+			tv(TokenValue, "b"),
+			tv(TokenRightBracket, "]"),
+			tv(TokenGT, ">"),
+			tv(TokenInteger, "1"),
+		})
+
+	verifyTokens(t, `SELECT arr[0], arr[-1] FROM p`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "arr"),
+			tv(TokenLeftBracket, "["),
+			tv(TokenInteger, "0"),
+			tv(TokenRightBracket, "]"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "arr"),
+			tv(TokenLeftBracket, "["),
+			tv(TokenInteger, "-1"),
+			tv(TokenRightBracket, "]"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "p"),
+		})
+
+	verifyTokens(t, `SELECT x FROM p WHERE Name ILIKE "%bob"`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "x"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "p"),
+			tv(TokenWhere, "WHERE"),
+			tv(TokenIdentity, "Name"),
+			tv(TokenILike, "ILIKE"),
+			tv(TokenValue, "%bob"),
+		})
+
+	verifyTokens(t, `SELECT x FROM p
+		WHERE Name LIKE 'a!%b' ESCAPE '!' AND x > 1`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "x"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "p"),
+			tv(TokenWhere, "WHERE"),
+			tv(TokenIdentity, "Name"),
+			tv(TokenLike, "LIKE"),
+			tv(TokenValue, "a!%b"),
+			tv(TokenEscape, "ESCAPE"),
+			tv(TokenValue, "!"),
+			tv(TokenLogicAnd, "AND"),
+			tv(TokenIdentity, "x"),
+			tv(TokenGT, ">"),
+			tv(TokenInteger, "1"),
+		})
+
 	verifyTokens(t, `SELECT x FROM p
 		WHERE
 			eq(name,"bob")
@@ -691,6 +835,25 @@ func TestLexOrderBy(t *testing.T) {
 			tv(TokenEOS, ";"),
 		})
 
+	verifyTokens(t, "SELECT a FROM t ORDER BY a DESC NULLS LAST, b nulls first LIMIT 3",
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenIdentity, "a"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "t"),
+			tv(TokenOrderBy, "ORDER BY"),
+			tv(TokenIdentity, "a"),
+			tv(TokenDesc, "DESC"),
+			tv(TokenNulls, "NULLS"),
+			tv(TokenLast, "LAST"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "b"),
+			tv(TokenNulls, "nulls"),
+			tv(TokenFirst, "first"),
+			tv(TokenLimit, "LIMIT"),
+			tv(TokenInteger, "3"),
+		})
+
 }
 
 func TestLexTSQL(t *testing.T) {
@@ -821,6 +984,89 @@ func TestLexSelectIfGuard(t *testing.T) {
 		})
 }
 
+func TestLexSelectWindow(t *testing.T) {
+
+	verifyTokens(t, `SELECT avg(x) OVER (ROWS BETWEEN 5 PRECEDING AND CURRENT ROW) AS m FROM t`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenUdfExpr, "avg"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenIdentity, "x"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenOver, "OVER"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenRows, "ROWS"),
+			tv(TokenBetween, "BETWEEN"),
+			tv(TokenInteger, "5"),
+			tv(TokenPreceding, "PRECEDING"),
+			tv(TokenLogicAnd, "AND"),
+			tv(TokenCurrentRow, "CURRENT ROW"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenAs, "AS"),
+			tv(TokenIdentity, "m"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "t"),
+		})
+
+	verifyTokens(t, `SELECT rank() OVER (PARTITION BY g, h ORDER BY n DESC) FROM t`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenUdfExpr, "rank"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenOver, "OVER"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenPartitionBy, "PARTITION BY"),
+			tv(TokenIdentity, "g"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "h"),
+			tv(TokenOrderBy, "ORDER BY"),
+			tv(TokenIdentity, "n"),
+			tv(TokenDesc, "DESC"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "t"),
+		})
+}
+
+func TestLexSelectDistinctOn(t *testing.T) {
+
+	verifyTokens(t, `SELECT DISTINCT ON (user_id, day) * FROM events ORDER BY user_id, ts DESC`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenDistinct, "DISTINCT"),
+			tv(TokenOn, "ON"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenIdentity, "user_id"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "day"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenStar, "*"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "events"),
+			tv(TokenOrderBy, "ORDER BY"),
+			tv(TokenIdentity, "user_id"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "ts"),
+			tv(TokenDesc, "DESC"),
+		})
+
+	verifyTokens(t, `SELECT DISTINCT ON (user_id) user_id, ts FROM events`,
+		[]Token{
+			tv(TokenSelect, "SELECT"),
+			tv(TokenDistinct, "DISTINCT"),
+			tv(TokenOn, "ON"),
+			tv(TokenLeftParenthesis, "("),
+			tv(TokenIdentity, "user_id"),
+			tv(TokenRightParenthesis, ")"),
+			tv(TokenIdentity, "user_id"),
+			tv(TokenComma, ","),
+			tv(TokenIdentity, "ts"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "events"),
+		})
+}
+
 func TestLexSelectLogicalColumns(t *testing.T) {
 
 	verifyTokens(t, `SELECT item > 5 AS item1, item > itemb, itemx > "value", itema + 5 > 4 FROM Product`,
@@ -999,6 +1245,24 @@ func TestLexUpdate(t *testing.T) {
 			tv(TokenInteger, "10"),
 			tv(TokenEOS, ";"),
 		})
+
+	verifyTokens(t, `UPDATE a SET x = b.y FROM b AS l WHERE a.id = l.id`,
+		[]Token{
+			tv(TokenUpdate, "UPDATE"),
+			tv(TokenTable, "a"),
+			tv(TokenSet, "SET"),
+			tv(TokenIdentity, "x"),
+			tv(TokenEqual, "="),
+			tv(TokenIdentity, "b.y"),
+			tv(TokenFrom, "FROM"),
+			tv(TokenIdentity, "b"),
+			tv(TokenAs, "AS"),
+			tv(TokenIdentity, "l"),
+			tv(TokenWhere, "WHERE"),
+			tv(TokenIdentity, "a.id"),
+			tv(TokenEqual, "="),
+			tv(TokenIdentity, "l.id"),
+		})
 }
 
 func TestLexInsert(t *testing.T) {
@@ -1177,3 +1441,36 @@ func TestWithJson(t *testing.T) {
 			TokenRightBrace,
 		})
 }
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct{ name, quoted string }{
+		{"name", "name"},
+		{"user_id2", "user_id2"},
+		{"users.name", "users.name"},
+		{"select", "`select`"},
+		{"FROM", "`FROM`"},
+		{"first name", "`first name`"},
+		{"users.from", "users.`from`"},
+		{"2col", "`2col`"},
+		{"has`tick", "`has``tick`"},
+	}
+	for _, test := range tests {
+		quoted := QuoteIdentifier(test.name, SqlDialect)
+		assert.Tf(t, quoted == test.quoted, "want %s got %s", test.quoted, quoted)
+	}
+
+	// ansi quotes double quotes, and has its own reserved words
+	ansi, ok := GetDialect("ANSI_QUOTES")
+	assert.Tf(t, ok && ansi == AnsiQuotesDialect, "registered ansi_quotes: %v", ansi)
+	for name, quoted := range map[string]string{
+		"name":    `name`,
+		"select":  `"select"`,
+		"user":    `"user"`,
+		"u.value": `u."value"`,
+	} {
+		got := QuoteIdentifier(name, ansi)
+		assert.Tf(t, got == quoted, "want %s got %s", quoted, got)
+	}
+	assert.Equal(t, "user", QuoteIdentifier("user", SqlDialect))
+	assert.Equal(t, "`key`", QuoteIdentifier("key", SqlDialect))
+}
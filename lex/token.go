@@ -2,12 +2,9 @@ package lex
 
 import (
 	"fmt"
-	u "github.com/araddon/gou"
 	"strings"
 )
 
-var _ = u.EMPTY
-
 // Tokens ---------------------------------------------------------------------
 
 // TokenType identifies the type of lexical tokens.
@@ -117,6 +114,10 @@ const (
 	TokenFalse            TokenType = 86 // False
 	TokenIs               TokenType = 87 // IS
 	TokenNull             TokenType = 88 // NULL
+	TokenEscape           TokenType = 89 // ESCAPE
+	TokenConcat           TokenType = 90 // || in ansi dialects
+	TokenILike            TokenType = 91 // ILIKE, case-insensitive LIKE
+	TokenIsNot            TokenType = 92 // IS NOT, of  x IS NOT NULL
 
 	// ql top-level keywords, these first keywords determine parser
 	TokenPrepare   TokenType = 100
@@ -156,6 +157,20 @@ const (
 	TokenDistinct TokenType = 141 // DISTINCT
 	TokenAll      TokenType = 142 // all
 
+	// dml result columns, ie insert ... RETURNING id
+	TokenReturning TokenType = 143 // returning
+	TokenLateral   TokenType = 144 // lateral, ie FROM a, LATERAL (SELECT ...)
+	// GROUP BY GROUPING SETS ((a, b), (a), ())
+	TokenGroupingSets TokenType = 145 // grouping sets
+	// set operations of selects, ie SELECT ... EXCEPT ALL SELECT ...
+	TokenUnion     TokenType = 146 // union
+	TokenExcept    TokenType = 147 // except
+	TokenIntersect TokenType = 148 // intersect
+	// window functions, ie avg(x) OVER (ROWS 5 PRECEDING)
+	TokenOver        TokenType = 149 // over
+	TokenRows        TokenType = 150 // rows, the frame of a window
+	TokenPartitionBy TokenType = 138 // partition by, of a window
+
 	// ddl
 	TokenChange       TokenType = 151 // change
 	TokenAdd          TokenType = 152 // add
@@ -164,10 +179,17 @@ const (
 	TokenCharacterSet TokenType = 155 // character set
 
 	// Other QL keywords
-	TokenSet  TokenType = 170 // set
-	TokenAs   TokenType = 171 // as
-	TokenAsc  TokenType = 172 // ascending
-	TokenDesc TokenType = 173 // descending
+	TokenSet      TokenType = 170 // set
+	TokenAs       TokenType = 171 // as
+	TokenAsc      TokenType = 172 // ascending
+	TokenDesc     TokenType = 173 // descending
+	TokenInterval TokenType = 174 // interval
+	TokenNulls    TokenType = 175 // nulls, ie ORDER BY x NULLS FIRST
+	TokenLast     TokenType = 176 // last
+	// window frame bounds, ie ROWS BETWEEN 5 PRECEDING AND CURRENT ROW
+	TokenPreceding  TokenType = 177 // preceding
+	TokenUnbounded  TokenType = 178 // unbounded
+	TokenCurrentRow TokenType = 179 // current row
 
 	// User defined function/expression
 	TokenUdfExpr TokenType = 180
@@ -178,6 +200,7 @@ const (
 	TokenValueWithSingleQuote TokenType = 192 // '' becomes ' inside the string, parser will need to replace the string
 	TokenRegex                TokenType = 193 // regex
 	TokenDuration             TokenType = 194 // 14d , 22w, 3y, 45ms, 45us, 24hr, 2h, 45m, 30s
+	TokenParam                TokenType = 195 // ? positional parameter placeholder
 
 	// Primitive literal data-types
 	TokenDataType TokenType = 200 // A generic Identifier of DataTypes
@@ -258,6 +281,10 @@ var (
 		TokenBetween:    {Kw: "between", Description: "between"},
 		TokenIs:         {Kw: "is", Description: "IS"},
 		TokenNull:       {Kw: "null", Description: "NULL"},
+		TokenEscape:     {Kw: "escape", Description: "ESCAPE"},
+		TokenConcat:     {Kw: "||", Description: "Concat ||"},
+		TokenILike:      {Kw: "ilike", Description: "ILIKE"},
+		TokenIsNot:      {Description: "IS NOT"},
 
 		// Identity ish bools
 		TokenTrue:  {Kw: "true", Description: "True"},
@@ -309,6 +336,16 @@ var (
 		TokenDistinct: {Description: "distinct"},
 		TokenAll:      {Description: "all"},
 
+		TokenReturning:    {Description: "returning"},
+		TokenLateral:      {Description: "lateral"},
+		TokenGroupingSets: {Description: "grouping sets"},
+		TokenUnion:        {Description: "union"},
+		TokenExcept:       {Description: "except"},
+		TokenIntersect:    {Description: "intersect"},
+		TokenOver:         {Description: "over"},
+		TokenRows:         {Description: "rows"},
+		TokenPartitionBy:  {Description: "partition by"},
+
 		// ddl keywords
 		TokenChange:       {Description: "change"},
 		TokenCharacterSet: {Description: "character set"},
@@ -317,10 +354,17 @@ var (
 		TokenAfter:        {Description: "after"},
 
 		// QL Keywords, all lower-case
-		TokenSet:  {Description: "set"},
-		TokenAs:   {Description: "as"},
-		TokenAsc:  {Description: "asc"},
-		TokenDesc: {Description: "desc"},
+		TokenSet:      {Description: "set"},
+		TokenAs:       {Description: "as"},
+		TokenAsc:      {Description: "asc"},
+		TokenDesc:     {Description: "desc"},
+		TokenInterval: {Description: "interval"},
+		TokenNulls:    {Description: "nulls"},
+		TokenLast:     {Description: "last"},
+
+		TokenPreceding:  {Description: "preceding"},
+		TokenUnbounded:  {Description: "unbounded"},
+		TokenCurrentRow: {Description: "current row"},
 
 		// value types
 		TokenIdentity:             {Description: "identity"},
@@ -328,6 +372,7 @@ var (
 		TokenValueWithSingleQuote: {Description: "valueWithSingleQuote"},
 		TokenRegex:                {Description: "regex"},
 		TokenDuration:             {Description: "duration"},
+		TokenParam:                {Description: "param"},
 
 		// Primitive literals.
 		TokenBool:    {Description: "Bool"},
@@ -346,12 +391,38 @@ var (
 	}
 )
 
+// lower case keywords that must be quoted to be used as an identity
+var reservedWords = make(map[string]bool)
+
 func init() {
 	LoadTokenInfo()
 }
 
+// keywords (select, from, and, like) as opposed to literal,
+// data-type, or operator tokens
+func (typ TokenType) isReserved() bool {
+	switch typ {
+	case TokenIf, TokenLogicOr, TokenLogicAnd, TokenIN, TokenLike, TokenILike, TokenNegate,
+		TokenBetween, TokenIs, TokenNull, TokenEscape:
+		return true
+	}
+	return typ >= TokenPrepare && typ < TokenUdfExpr
+}
+
+func (m *TokenInfo) keyword() string {
+	if m.Kw != "" {
+		return m.Kw
+	}
+	return m.Description
+}
+
 func LoadTokenInfo() {
 	for tok, ti := range TokenNameMap {
+		if tok.isReserved() {
+			for _, word := range strings.Split(ti.keyword(), " ") {
+				reservedWords[word] = true
+			}
+		}
 		ti.T = tok
 		if ti.Kw == "" {
 			ti.Kw = ti.Description
@@ -379,7 +450,7 @@ func (typ TokenType) String() string {
 // OR in case of spaces such as "group by" look for group
 func (typ TokenType) MatchString() string {
 	tokInfo, ok := TokenNameMap[typ]
-	//u.Debugf("matchstring: '%v' '%v'  '%v'", tokInfo.T, tokInfo.Kw, tokInfo.Description)
+	//logging.Debugf("matchstring: '%v' '%v'  '%v'", tokInfo.T, tokInfo.Kw, tokInfo.Description)
 	if ok {
 		if tokInfo.HasSpaces {
 			return tokInfo.firstWord
@@ -0,0 +1,72 @@
+// Package logging is the logger used internally by qlbridge packages, by
+//  default nothing is logged, set a Logger to see it
+//
+//     logging.SetLogger(logging.GouLogger{})   // log via github.com/araddon/gou
+package logging
+
+import (
+	"fmt"
+	"sync"
+
+	u "github.com/araddon/gou"
+)
+
+var (
+	logMu  sync.RWMutex
+	logger Logger = NopLogger{}
+
+	// ensure our loggers are Loggers
+	_ Logger = NopLogger{}
+	_ Logger = GouLogger{}
+)
+
+// Logger is the leveled logger qlbridge logs to
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// SetLogger sets the Logger for all qlbridge packages, nil discards log output
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger{}
+	}
+	logMu.Lock()
+	logger = l
+	logMu.Unlock()
+}
+
+// Log returns the current Logger
+func Log() Logger {
+	logMu.RLock()
+	l := logger
+	logMu.RUnlock()
+	return l
+}
+
+func Debugf(format string, v ...interface{}) { Log().Debugf(format, v...) }
+func Infof(format string, v ...interface{})  { Log().Infof(format, v...) }
+func Warnf(format string, v ...interface{})  { Log().Warnf(format, v...) }
+func Errorf(format string, v ...interface{}) { Log().Errorf(format, v...) }
+func Debug(v ...interface{})                 { Log().Debugf("%s", fmt.Sprint(v...)) }
+func Info(v ...interface{})                  { Log().Infof("%s", fmt.Sprint(v...)) }
+func Error(v ...interface{})                 { Log().Errorf("%s", fmt.Sprint(v...)) }
+
+// NopLogger discards all log output, the default
+type NopLogger struct{}
+
+func (NopLogger) Debugf(format string, v ...interface{}) {}
+func (NopLogger) Infof(format string, v ...interface{})  {}
+func (NopLogger) Warnf(format string, v ...interface{})  {}
+func (NopLogger) Errorf(format string, v ...interface{}) {}
+
+// GouLogger logs to github.com/araddon/gou, its level is set by
+//  u.SetupLogging()
+type GouLogger struct{}
+
+func (GouLogger) Debugf(format string, v ...interface{}) { u.Debugf(format, v...) }
+func (GouLogger) Infof(format string, v ...interface{})  { u.Infof(format, v...) }
+func (GouLogger) Warnf(format string, v ...interface{})  { u.Warnf(format, v...) }
+func (GouLogger) Errorf(format string, v ...interface{}) { u.Errorf(format, v...) }
@@ -3,14 +3,12 @@ package plan
 import (
 	"encoding/json"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
-	_ = u.EMPTY
-
 	// Ensure that we implement the sql expr.Visitor interface
 	_ expr.Visitor = (*Planner)(nil)
 )
@@ -36,7 +34,7 @@ func NewPlanner(schema string, stmt expr.SqlStatement,
 		plan.where = sql.Where
 	}
 	task, err := stmt.Accept(plan)
-	u.Debugf("task:  %T  %#v", task, task)
+	logging.Debugf("task:  %T  %#v", task, task)
 	if err != nil {
 		return nil, err
 	}
@@ -52,41 +50,41 @@ type Planner struct {
 }
 
 func (m *Planner) VisitSelect(stmt *expr.SqlSelect) (interface{}, error) {
-	u.Debugf("VisitSource %+v", stmt)
+	logging.Debugf("VisitSource %+v", stmt)
 	return nil, nil
 }
 
 func (m *Planner) VisitInsert(stmt *expr.SqlInsert) (interface{}, error) {
-	u.Debugf("VisitInsert %+v", stmt)
+	logging.Debugf("VisitInsert %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitDelete(stmt *expr.SqlDelete) (interface{}, error) {
-	u.Debugf("VisitDelete %+v", stmt)
+	logging.Debugf("VisitDelete %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitUpdate(stmt *expr.SqlUpdate) (interface{}, error) {
-	u.Debugf("VisitUpdate %+v", stmt)
+	logging.Debugf("VisitUpdate %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitUpsert(stmt *expr.SqlUpsert) (interface{}, error) {
-	u.Debugf("VisitUpdate %+v", stmt)
+	logging.Debugf("VisitUpdate %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitShow(stmt *expr.SqlShow) (interface{}, error) {
-	u.Debugf("VisitShow %+v", stmt)
+	logging.Debugf("VisitShow %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitDescribe(stmt *expr.SqlDescribe) (interface{}, error) {
-	u.Debugf("VisitDescribe %+v", stmt)
+	logging.Debugf("VisitDescribe %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
 
 func (m *Planner) VisitPreparedStmt(stmt *expr.PreparedStatement) (interface{}, error) {
-	u.Debugf("VisitPreparedStmt %+v", stmt)
+	logging.Debugf("VisitPreparedStmt %+v", stmt)
 	return nil, expr.ErrNotImplemented
 }
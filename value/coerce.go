@@ -8,11 +8,9 @@ import (
 	"strconv"
 	"strings"
 
-	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/logging"
 )
 
-var _ = u.EMPTY
-
 func CanCoerce(from, to reflect.Value) bool {
 	if from.Kind() == reflect.Interface {
 		from = from.Elem()
@@ -94,7 +92,8 @@ func CoerceTo(to, itemToConvert reflect.Value) reflect.Value {
 		iv, _ := ToInt64(itemToConvert)
 		return reflect.ValueOf(iv)
 	case reflect.Bool:
-		return reflect.ValueOf(itemToConvert.Bool())
+		bv, _ := ToBool(itemToConvert)
+		return reflect.ValueOf(bv)
 	case reflect.String:
 		return reflect.ValueOf(ToStringUnchecked(itemToConvert))
 	}
@@ -211,6 +210,51 @@ func ToValue(v interface{}) (Value, error) {
 	return NilStructValue, fmt.Errorf("Could not coerce to Value: %T %v", v, v)
 }
 
+// PromoteNumeric applies the numeric promotion rules of binary arithmetic
+//  to @a and @b, returning both as IntValue or both as NumberValue:
+//
+//     int   op int     =>  int
+//     int   op float   =>  float
+//     float op float   =>  float
+//
+//  numeric strings are coerced first, "3" is an int and "3.5" a float.
+//  ok is false if either side is not numeric.
+func PromoteNumeric(a, b Value) (Value, Value, bool) {
+	an, aok := numericValue(a)
+	bn, bok := numericValue(b)
+	if !aok || !bok {
+		return a, b, false
+	}
+	ai, aIsInt := an.(IntValue)
+	bi, bIsInt := bn.(IntValue)
+	switch {
+	case aIsInt && bIsInt:
+		return ai, bi, true
+	case aIsInt:
+		return ai.NumberValue(), bn, true
+	case bIsInt:
+		return an, bi.NumberValue(), true
+	}
+	return an, bn, true
+}
+
+// the IntValue or NumberValue of a numeric value or string
+func numericValue(v Value) (Value, bool) {
+	switch vt := v.(type) {
+	case IntValue, NumberValue:
+		return v, true
+	case StringValue:
+		s := strings.TrimSpace(vt.Val())
+		if iv, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return NewIntValue(iv), true
+		}
+		if fv, err := strconv.ParseFloat(s, 64); err == nil {
+			return NewNumberValue(fv), true
+		}
+	}
+	return v, false
+}
+
 //  Equal function
 //
 //   returns bool, error
@@ -218,6 +262,11 @@ func ToValue(v interface{}) (Value, error) {
 //       error if it could not evaluate
 func Equal(itemA, itemB Value) (bool, error) {
 	//return BoolValue(itemA == itemB)
+	if ab, isBool := itemA.(BoolValue); isBool {
+		// a value that is not a bool is not equal to one, ie  false = "b"
+		bv, ok := ToBool(itemB.Rv())
+		return ok && bv == ab.Val(), nil
+	}
 	rvb := CoerceTo(itemA.Rv(), itemB.Rv())
 
 	switch rvb.Kind() {
@@ -228,12 +277,12 @@ func Equal(itemA, itemB Value) (bool, error) {
 	case reflect.Float64:
 		return rvb.Float() == itemA.Rv().Float(), nil
 	case reflect.Bool:
-		//u.Infof("Equal?  %v  %v  ==? %v", itemA.Rv().Bool(), rvb.Bool(), itemA.Rv().Bool() == rvb.Bool())
+		//logging.Infof("Equal?  %v  %v  ==? %v", itemA.Rv().Bool(), rvb.Bool(), itemA.Rv().Bool() == rvb.Bool())
 		return rvb.Bool() == itemA.Rv().Bool(), nil
 	default:
-		u.Warnf("Unknown kind?  %v", rvb.Kind())
+		logging.Warnf("Unknown kind?  %v", rvb.Kind())
 	}
-	//u.Infof("Eq():    a:%T  b:%T     %v=%v? %v", itemA, itemB, itemA.Rv(), rvb, itemA.Rv() == rvb)
+	//logging.Infof("Eq():    a:%T  b:%T     %v=%v? %v", itemA, itemB, itemA.Rv(), rvb, itemA.Rv() == rvb)
 	return false, fmt.Errorf("Could not evaluate equals")
 }
 
@@ -255,8 +304,8 @@ func ToString(v reflect.Value) (string, bool) {
 			return v.Index(0).String(), true
 		} else {
 			// do we grab first one?   or fail?
-			u.Warnf("ToString() on slice of len=%d vals=%#v   v=%v?  What should we do?  %v", v.Len(), v, v, v.Type())
-			//u.LogTracef(u.WARN, "wtf")
+			logging.Warnf("ToString() on slice of len=%d vals=%#v   v=%v?  What should we do?  %v", v.Len(), v, v, v.Type())
+			//logging.Warnf("wtf")
 		}
 	}
 	// TODO:  this sucks, fix me
@@ -280,7 +329,7 @@ func ToStringUnchecked(v reflect.Value) string {
 		if v.Len() == 1 {
 			return v.Index(0).String()
 		}
-		u.Warnf("ToString() on slice of len=%d vals=%v ?  What should we do?", v.Len(), v)
+		logging.Warnf("ToString() on slice of len=%d vals=%v ?  What should we do?", v.Len(), v)
 	}
 	return fmt.Sprint(v.Interface())
 }
@@ -383,9 +432,9 @@ func convertToFloat64(depth int, v reflect.Value) float64 {
 	case reflect.Slice:
 		// Should we grab first one?
 		item1 := v.Index(0)
-		u.Warnf("ToFloat() but is slice?: %T first=%v", v, item1)
+		logging.Warnf("ToFloat() but is slice?: %T first=%v", v, item1)
 	default:
-		//u.Warnf("Cannot convert type?  %v", v.Kind())
+		//logging.Warnf("Cannot convert type?  %v", v.Kind())
 	}
 	return math.NaN()
 }
@@ -80,3 +80,30 @@ func TestCoerceNumbers(t *testing.T) {
 		assert.Tf(t, CloseEnuf(floatVal, cv.f), "should be == expect %v but was: %v", cv.f, floatVal)
 	}
 }
+
+func TestPromoteNumeric(t *testing.T) {
+	tests := []struct {
+		a, b Value
+		typ  ValueType
+		ok   bool
+	}{
+		{NewIntValue(1), NewIntValue(2), IntType, true},
+		{NewIntValue(1), NewNumberValue(2.5), NumberType, true},
+		{NewNumberValue(1.5), NewIntValue(2), NumberType, true},
+		{NewNumberValue(1.5), NewNumberValue(2.5), NumberType, true},
+		{NewStringValue("1"), NewIntValue(2), IntType, true},
+		{NewStringValue(" 1.5"), NewIntValue(2), NumberType, true},
+		{NewIntValue(2), NewStringValue("abc"), NilType, false},
+		{NewBoolValue(true), NewIntValue(2), NilType, false},
+	}
+	for _, tt := range tests {
+		a, b, ok := PromoteNumeric(tt.a, tt.b)
+		assert.Tf(t, ok == tt.ok, "%v, %v ok? %v", tt.a, tt.b, ok)
+		if !ok {
+			continue
+		}
+		assert.Tf(t, a.Type() == tt.typ && b.Type() == tt.typ, "%v, %v expected %v got %v, %v", tt.a, tt.b, tt.typ, a.Type(), b.Type())
+	}
+	a, b, _ := PromoteNumeric(NewIntValue(3), NewStringValue("2.5"))
+	assert.Tf(t, a.Value() == float64(3) && b.Value() == float64(2.5), "values %v %v", a, b)
+}
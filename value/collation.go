@@ -0,0 +1,146 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var (
+	// Byte-wise ordering, go's native string comparison, the default
+	CollationBinary Collation = binaryCollation{}
+	// Unicode lower-cased ordering,  'a' = 'A' and 'a' < 'B'
+	CollationCaseInsensitive Collation = caseInsensitiveCollation{}
+
+	_ Collation = (*localeCollation)(nil)
+)
+
+// Collation determines how strings are compared for equality,
+//  relational operators (< > <= >=) and sorting
+type Collation interface {
+	Name() string
+	// Compare returns -1, 0, 1 for a < b, a == b, a > b
+	Compare(a, b string) int
+}
+
+type binaryCollation struct{}
+
+func (m binaryCollation) Name() string { return "binary" }
+func (m binaryCollation) Compare(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type caseInsensitiveCollation struct{}
+
+func (m caseInsensitiveCollation) Name() string { return "nocase" }
+func (m caseInsensitiveCollation) Compare(a, b string) int {
+	return CollationBinary.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// Locale aware collation using the unicode collation algorithm
+//  for the given language
+type localeCollation struct {
+	name string
+	mu   sync.Mutex // collate.Collator is not safe for concurrent use
+	c    *collate.Collator
+}
+
+// Create a locale aware collation for a BCP 47 language tag such as
+//  "en", "de", "sv".   Optionally case-insensitive.
+//
+//     coll, err := value.NewCollationLocale("de", true)
+func NewCollationLocale(lang string, ignoreCase bool) (Collation, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collation language %q: %v", lang, err)
+	}
+	name := tag.String()
+	var c *collate.Collator
+	if ignoreCase {
+		name += "_ci"
+		c = collate.New(tag, collate.IgnoreCase)
+	} else {
+		c = collate.New(tag)
+	}
+	return &localeCollation{name: name, c: c}, nil
+}
+
+func (m *localeCollation) Name() string { return m.name }
+func (m *localeCollation) Compare(a, b string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.c.CompareString(a, b)
+}
+
+// CompareValues compares two values returning -1, 0, 1 for a < b, a == b, a > b
+//  using @coll for strings (nil collation means binary).  Nil values sort
+//  before all others, numerics (int, number, time) compare numerically, an
+//  error is returned for values that have no ordering between them.
+func CompareValues(a, b Value, coll Collation) (int, error) {
+	if coll == nil {
+		coll = CollationBinary
+	}
+	aNil, bNil := a == nil || a.Type() == NilType, b == nil || b.Type() == NilType
+	switch {
+	case aNil && bNil:
+		return 0, nil
+	case aNil:
+		return -1, nil
+	case bNil:
+		return 1, nil
+	}
+	switch at := a.(type) {
+	case StringValue:
+		if bt, ok := b.(StringValue); ok {
+			return coll.Compare(at.Val(), bt.Val()), nil
+		}
+	case BoolValue:
+		if bt, ok := b.(BoolValue); ok {
+			switch {
+			case at.Val() == bt.Val():
+				return 0, nil
+			case bt.Val():
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case NumericValue:
+		if bt, ok := b.(NumericValue); ok {
+			return compareFloats(at.Float(), bt.Float()), nil
+		}
+		// numeric vs string:  compare numerically if the string is a number
+		if bt, ok := b.(StringValue); ok {
+			if bf := ToFloat64(bt.Rv()); !math.IsNaN(bf) {
+				return compareFloats(at.Float(), bf), nil
+			}
+		}
+	}
+	if at, ok := a.(StringValue); ok {
+		if bt, ok := b.(NumericValue); ok {
+			if af := ToFloat64(at.Rv()); !math.IsNaN(af) {
+				return compareFloats(af, bt.Float()), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s to %s", a.Type(), b.Type())
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
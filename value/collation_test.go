@@ -0,0 +1,51 @@
+package value
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestCollations(t *testing.T) {
+	assert.Equal(t, 1, CollationBinary.Compare("a", "B"))
+	assert.Equal(t, -1, CollationCaseInsensitive.Compare("a", "B"))
+	assert.Equal(t, 0, CollationCaseInsensitive.Compare("abc", "ABC"))
+
+	coll, err := NewCollationLocale("en", true)
+	assert.Tf(t, err == nil, "no error %v", err)
+	assert.Equal(t, "en_ci", coll.Name())
+	assert.Equal(t, -1, coll.Compare("a", "B"))
+	assert.Equal(t, 0, coll.Compare("abc", "ABC"))
+
+	coll, err = NewCollationLocale("de", false)
+	assert.Tf(t, err == nil, "no error %v", err)
+	// ä sorts with a in german, binary puts it after z
+	assert.Equal(t, -1, coll.Compare("ä", "b"))
+	assert.Equal(t, 1, CollationBinary.Compare("ä", "b"))
+
+	_, err = NewCollationLocale("not a language!", false)
+	assert.T(t, err != nil)
+}
+
+func TestCompareValues(t *testing.T) {
+	cmp := func(a, b Value, coll Collation) int {
+		c, err := CompareValues(a, b, coll)
+		assert.Tf(t, err == nil, "no error %v", err)
+		return c
+	}
+	a, B := NewStringValue("a"), NewStringValue("B")
+	assert.Equal(t, 1, cmp(a, B, nil))
+	assert.Equal(t, 1, cmp(a, B, CollationBinary))
+	assert.Equal(t, -1, cmp(a, B, CollationCaseInsensitive))
+
+	assert.Equal(t, -1, cmp(NewIntValue(2), NewNumberValue(2.5), nil))
+	assert.Equal(t, 0, cmp(NewIntValue(10), NewStringValue("10"), nil))
+	assert.Equal(t, 1, cmp(NewStringValue("10"), NewIntValue(9), nil))
+	assert.Equal(t, -1, cmp(NewBoolValue(false), NewBoolValue(true), nil))
+	assert.Equal(t, -1, cmp(NewNilValue(), NewIntValue(1), nil))
+	assert.Equal(t, 1, cmp(a, nil, nil))
+	assert.Equal(t, 0, cmp(NewNilValue(), nil, nil))
+
+	_, err := CompareValues(NewBoolValue(true), NewStringValue("abc"), nil)
+	assert.T(t, err != nil)
+}
@@ -0,0 +1,76 @@
+package value
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// HashValue is a stable key for a value, for use as a map key (group by,
+// distinct).  The type is part of the key so int 1 and string "1" do not
+// collide, all NULLs (and nil) share one key, and composite values
+// (strings, slices, maps) are keyed by their elements, maps in key order.
+//
+//     seen[value.HashValue(v)] = struct{}{}
+func HashValue(v Value) string {
+	var buf bytes.Buffer
+	writeHash(&buf, v)
+	return buf.String()
+}
+
+func writeHash(buf *bytes.Buffer, v Value) {
+	if v == nil || v.Type() == NilType {
+		buf.WriteByte(byte(NilType))
+		return
+	}
+	buf.WriteByte(byte(v.Type()))
+	switch vt := v.(type) {
+	case NumberValue:
+		f := vt.Val()
+		switch {
+		case math.IsNaN(f):
+			buf.WriteString("NaN")
+			return
+		case f == 0:
+			f = 0 // -0 is 0
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case TimeValue:
+		// the instant, not its location
+		buf.WriteString(strconv.FormatInt(vt.Val().UnixNano(), 10))
+	case StringsValue:
+		writeLen(buf, len(vt.Val()))
+		for _, s := range vt.Val() {
+			writeString(buf, s)
+		}
+	case SliceValue:
+		writeLen(buf, len(vt.Val()))
+		for _, sv := range vt.Val() {
+			writeString(buf, HashValue(sv))
+		}
+	case MapIntValue:
+		keys := make([]string, 0, len(vt.Val()))
+		for k := range vt.Val() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeLen(buf, len(keys))
+		for _, k := range keys {
+			writeString(buf, k)
+			writeString(buf, strconv.FormatInt(vt.Val()[k], 10))
+		}
+	default:
+		buf.WriteString(v.ToString())
+	}
+}
+
+// elements of composites are length prefixed so  ["a,b"] != ["a","b"]
+func writeLen(buf *bytes.Buffer, n int) {
+	buf.WriteString(strconv.Itoa(n))
+	buf.WriteByte(':')
+}
+func writeString(buf *bytes.Buffer, s string) {
+	writeLen(buf, len(s))
+	buf.WriteString(s)
+}
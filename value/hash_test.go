@@ -0,0 +1,44 @@
+package value
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestHashValue(t *testing.T) {
+	// same value, different types, do not collide
+	distinct := []Value{
+		NewIntValue(1),
+		NewStringValue("1"),
+		NewNumberValue(1),
+		NewBoolValue(true),
+		NewStringsValue([]string{"1"}),
+		NewNilValue(),
+		NewStringValue(""),
+		NewIntValue(0),
+		NewStringsValue([]string{"a,b"}),
+		NewStringsValue([]string{"a", "b"}),
+	}
+	seen := make(map[string]Value)
+	for _, v := range distinct {
+		key := HashValue(v)
+		prev, dup := seen[key]
+		assert.Tf(t, !dup, "%T %v collides with %T %v", v, v.Value(), prev, prev)
+		seen[key] = v
+	}
+
+	// NULL is stable
+	assert.Equal(t, HashValue(NewNilValue()), HashValue(nil))
+	assert.Equal(t, HashValue(NewNilValue()), HashValue(NewNilValue()))
+
+	// equal values hash the same
+	assert.Equal(t, HashValue(NewNumberValue(0)), HashValue(NewNumberValue(math.Copysign(0, -1))))
+	now := time.Now()
+	assert.Equal(t, HashValue(NewTimeValue(now)), HashValue(NewTimeValue(now.UTC())))
+	m1 := NewMapIntValue(map[string]int64{"a": 1, "b": 2, "c": 3})
+	m2 := NewMapIntValue(map[string]int64{"c": 3, "b": 2, "a": 1})
+	assert.Equal(t, HashValue(m1), HashValue(m2))
+}
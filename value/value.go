@@ -5,16 +5,15 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	u "github.com/araddon/gou"
+	"github.com/araddon/qlbridge/logging"
 )
 
 var (
-	_ = u.EMPTY
-
 	// our DataTypes we support, a limited sub-set of go
 	floatRv   = reflect.ValueOf(float64(1.2))
 	int64Rv   = reflect.ValueOf(int64(1))
@@ -24,6 +23,7 @@ var (
 	boolRv    = reflect.ValueOf(true)
 	mapIntRv  = reflect.ValueOf(map[string]int64{"hello": int64(1)})
 	timeRv    = reflect.ValueOf(time.Time{})
+	durRv     = reflect.ValueOf(time.Duration(0))
 	nilRv     = reflect.ValueOf(nil)
 
 	RV_ZERO     = reflect.Value{}
@@ -57,6 +57,7 @@ const (
 	BoolType       ValueType = 12
 	TimeType       ValueType = 13
 	ByteSliceType  ValueType = 14
+	DurationType   ValueType = 15
 	StringType     ValueType = 20
 	StringsType    ValueType = 21
 	MapValueType   ValueType = 30
@@ -85,6 +86,8 @@ func (m ValueType) String() string {
 		return "time"
 	case ByteSliceType:
 		return "[]byte"
+	case DurationType:
+		return "duration"
 	case StringType:
 		return "string"
 	case StringsType:
@@ -116,6 +119,10 @@ type Value interface {
 	Value() interface{}
 	Rv() reflect.Value
 	ToString() string
+	// String is the display form of the value, for result writers and
+	//  debugging:  NULL, strings unquoted, time as RFC3339, floats without
+	//  trailing zeros, slices and maps in [] and {}
+	String() string
 	Type() ValueType
 }
 
@@ -156,6 +163,8 @@ func NewValue(goVal interface{}) Value {
 		return NewTimeValue(val)
 	case *time.Time:
 		return NewTimeValue(*val)
+	case time.Duration:
+		return NewDurationValue(val)
 	//case []byte:
 	// case []interface{}:
 	// case map[string]interface{}:
@@ -171,7 +180,7 @@ func NewValue(goVal interface{}) Value {
 		if valValue, ok := goVal.(Value); ok {
 			return valValue
 		}
-		u.Errorf("invalud value type %T.", val)
+		logging.Errorf("invalud value type %T.", val)
 	}
 	return NilValueVal
 }
@@ -186,6 +195,8 @@ func ValueTypeFromRT(rt reflect.Type) ValueType {
 		return IntType
 	case reflect.TypeOf(TimeValue{}):
 		return TimeType
+	case reflect.TypeOf(DurationValue{}):
+		return DurationType
 	case reflect.TypeOf(BoolValue{}):
 		return BoolType
 	case reflect.TypeOf(StringValue{}):
@@ -194,6 +205,8 @@ func ValueTypeFromRT(rt reflect.Type) ValueType {
 		return StringsType
 	case reflect.TypeOf(MapIntValue{}):
 		return MapIntType
+	case reflect.TypeOf(MapValue{}):
+		return MapValueType
 	case reflect.TypeOf(SliceValue{}):
 		return SliceValueType
 	case reflect.TypeOf(MapIntValue{}):
@@ -208,95 +221,97 @@ func ValueTypeFromRT(rt reflect.Type) ValueType {
 		// we should probably just allow it, as it is not telling us much
 		// info but isn't wrong
 		if "value.Value" == fmt.Sprintf("%v", rt) {
-			// ignore
+			return UnknownType
 		} else {
-			u.Warnf("Unrecognized Value Type Kind?  %v %T ", rt, rt)
+			logging.Warnf("Unrecognized Value Type Kind?  %v %T ", rt, rt)
 		}
 	}
 	return NilType
 }
 
+// the reflect.Value is built lazily in Rv(), these are the hot scalar
+// evaluation results and must not allocate
 type NumberValue struct {
-	v  float64
-	rv reflect.Value
+	v float64
 }
 
 func NewNumberValue(v float64) NumberValue {
-	return NumberValue{v: v, rv: reflect.ValueOf(v)}
+	return NumberValue{v: v}
 }
 
 func (m NumberValue) Nil() bool                         { return m.v == 0 }
 func (m NumberValue) Err() bool                         { return false }
 func (m NumberValue) Type() ValueType                   { return NumberType }
-func (m NumberValue) Rv() reflect.Value                 { return m.rv }
+func (m NumberValue) Rv() reflect.Value                 { return reflect.ValueOf(m.v) }
 func (m NumberValue) CanCoerce(toRv reflect.Value) bool { return CanCoerce(int64Rv, toRv) }
 func (m NumberValue) Value() interface{}                { return m.v }
 func (m NumberValue) Val() float64                      { return m.v }
 func (m NumberValue) MarshalJSON() ([]byte, error)      { return marshalFloat(float64(m.v)) }
 func (m NumberValue) ToString() string                  { return strconv.FormatFloat(float64(m.v), 'f', -1, 64) }
+func (m NumberValue) String() string                    { return m.ToString() }
 func (m NumberValue) Float() float64                    { return m.v }
 func (m NumberValue) Int() int64                        { return int64(m.v) }
 
 type IntValue struct {
-	v  int64
-	rv reflect.Value
+	v int64
 }
 
 func NewIntValue(v int64) IntValue {
-	return IntValue{v: v, rv: reflect.ValueOf(v)}
+	return IntValue{v: v}
 }
 
 func (m IntValue) Nil() bool                         { return m.v == 0 }
 func (m IntValue) Err() bool                         { return false }
 func (m IntValue) Type() ValueType                   { return IntType }
-func (m IntValue) Rv() reflect.Value                 { return m.rv }
+func (m IntValue) Rv() reflect.Value                 { return reflect.ValueOf(m.v) }
 func (m IntValue) CanCoerce(toRv reflect.Value) bool { return CanCoerce(int64Rv, toRv) }
 func (m IntValue) Value() interface{}                { return m.v }
 func (m IntValue) Val() int64                        { return m.v }
 func (m IntValue) MarshalJSON() ([]byte, error)      { return marshalFloat(float64(m.v)) }
 func (m IntValue) NumberValue() NumberValue          { return NewNumberValue(float64(m.v)) }
 func (m IntValue) ToString() string                  { return strconv.FormatInt(m.v, 10) }
+func (m IntValue) String() string                    { return m.ToString() }
 func (m IntValue) Float() float64                    { return float64(m.v) }
 func (m IntValue) Int() int64                        { return m.v }
 
 type BoolValue struct {
-	v  bool
-	rv reflect.Value
+	v bool
 }
 
 func NewBoolValue(v bool) BoolValue {
-	return BoolValue{v: v, rv: reflect.ValueOf(v)}
+	return BoolValue{v: v}
 }
 
 func (m BoolValue) Nil() bool                         { return false }
 func (m BoolValue) Err() bool                         { return false }
 func (m BoolValue) Type() ValueType                   { return BoolType }
-func (m BoolValue) Rv() reflect.Value                 { return m.rv }
+func (m BoolValue) Rv() reflect.Value                 { return reflect.ValueOf(m.v) }
 func (m BoolValue) CanCoerce(toRv reflect.Value) bool { return CanCoerce(boolRv, toRv) }
 func (m BoolValue) Value() interface{}                { return m.v }
 func (m BoolValue) Val() bool                         { return m.v }
 func (m BoolValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
 func (m BoolValue) ToString() string                  { return strconv.FormatBool(m.v) }
+func (m BoolValue) String() string                    { return m.ToString() }
 
 type StringValue struct {
-	v  string
-	rv reflect.Value
+	v string
 }
 
 func NewStringValue(v string) StringValue {
-	return StringValue{v: v, rv: reflect.ValueOf(v)}
+	return StringValue{v: v}
 }
 
 func (m StringValue) Nil() bool                          { return len(m.v) == 0 }
 func (m StringValue) Err() bool                          { return false }
 func (m StringValue) Type() ValueType                    { return StringType }
-func (m StringValue) Rv() reflect.Value                  { return m.rv }
+func (m StringValue) Rv() reflect.Value                  { return reflect.ValueOf(m.v) }
 func (m StringValue) CanCoerce(input reflect.Value) bool { return CanCoerce(stringRv, input) }
 func (m StringValue) Value() interface{}                 { return m.v }
 func (m StringValue) Val() string                        { return m.v }
 func (m StringValue) MarshalJSON() ([]byte, error)       { return json.Marshal(m.v) }
 func (m StringValue) NumberValue() NumberValue           { return NewNumberValue(ToFloat64(m.Rv())) }
 func (m StringValue) ToString() string                   { return m.v }
+func (m StringValue) String() string                     { return m.v }
 
 func (m StringValue) IntValue() IntValue {
 	iv, _ := ToInt64(m.Rv())
@@ -338,6 +353,9 @@ func (m StringsValue) IntValue() IntValue {
 }
 func (m StringsValue) ToString() string  { return strings.Join(m.v, ",") }
 func (m StringsValue) Strings() []string { return m.v }
+func (m StringsValue) String() string {
+	return "[" + strings.Join(m.v, ", ") + "]"
+}
 func (m StringsValue) Set() map[string]struct{} {
 	setvals := make(map[string]struct{})
 	for _, sv := range m.v {
@@ -366,6 +384,62 @@ func (m SliceValue) Val() []Value                 { return m.v }
 func (m *SliceValue) Append(v Value)              { m.v = append(m.v, v) }
 func (m SliceValue) MarshalJSON() ([]byte, error) { return json.Marshal(m.v) }
 func (m SliceValue) Len() int                     { return len(m.v) }
+func (m SliceValue) ToString() string {
+	strs := make([]string, len(m.v))
+	for i, v := range m.v {
+		strs[i] = v.ToString()
+	}
+	return strings.Join(strs, ",")
+}
+func (m SliceValue) String() string {
+	strs := make([]string, len(m.v))
+	for i, v := range m.v {
+		strs[i] = formatValue(v)
+	}
+	return "[" + strings.Join(strs, ", ") + "]"
+}
+
+// MapValue is a map of named values, ie a whole row
+type MapValue struct {
+	v  map[string]Value
+	rv reflect.Value
+}
+
+func NewMapValue(v map[string]Value) MapValue {
+	return MapValue{v: v, rv: reflect.ValueOf(v)}
+}
+
+func (m MapValue) Nil() bool                         { return len(m.v) == 0 }
+func (m MapValue) Err() bool                         { return false }
+func (m MapValue) Type() ValueType                   { return MapValueType }
+func (m MapValue) Rv() reflect.Value                 { return m.rv }
+func (m MapValue) CanCoerce(toRv reflect.Value) bool { return false }
+func (m MapValue) Value() interface{}                { return m.v }
+func (m MapValue) Val() map[string]Value             { return m.v }
+func (m MapValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
+func (m MapValue) Len() int                          { return len(m.v) }
+
+// ToString is the json object of the map
+func (m MapValue) ToString() string {
+	by, err := m.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("%v", m.v)
+	}
+	return string(by)
+}
+
+// String is the map with keys sorted  {a: 1, b: x}
+func (m MapValue) String() string {
+	keys := make([]string, 0, len(m.v))
+	for k := range m.v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + ": " + formatValue(m.v[k])
+	}
+	return "{" + strings.Join(keys, ", ") + "}"
+}
 
 type MapIntValue struct {
 	v  map[string]int64
@@ -386,6 +460,17 @@ func (m MapIntValue) Val() map[string]int64             { return m.v }
 func (m MapIntValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
 func (m MapIntValue) ToString() string                  { return fmt.Sprintf("%v", m.v) }
 func (m MapIntValue) MapInt() map[string]int64          { return m.v }
+func (m MapIntValue) String() string {
+	keys := make([]string, 0, len(m.v))
+	for k := range m.v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + ": " + strconv.FormatInt(m.v[k], 10)
+	}
+	return "{" + strings.Join(keys, ", ") + "}"
+}
 
 type StructValue struct {
 	v  interface{}
@@ -405,6 +490,7 @@ func (m StructValue) Value() interface{}                { return m.v }
 func (m StructValue) Val() interface{}                  { return m.v }
 func (m StructValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
 func (m StructValue) ToString() string                  { return fmt.Sprintf("%v", m.v) }
+func (m StructValue) String() string                    { return m.ToString() }
 
 type TimeValue struct {
 	v  time.Time
@@ -424,10 +510,34 @@ func (m TimeValue) Value() interface{}                { return m.v }
 func (m TimeValue) Val() time.Time                    { return m.v }
 func (m TimeValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
 func (m TimeValue) ToString() string                  { return strconv.FormatInt(m.Int(), 10) }
+func (m TimeValue) String() string                    { return m.v.Format(time.RFC3339) }
 func (m TimeValue) Float() float64                    { return float64(m.v.UnixNano() / 1e6) }
 func (m TimeValue) Int() int64                        { return m.v.UnixNano() / 1e6 }
 func (m TimeValue) Time() time.Time                   { return m.v }
 
+// DurationValue is an elapsed time, the result of  time - time  or an
+//  interval literal.   Float(), Int() are milliseconds, same as TimeValue
+type DurationValue struct {
+	v time.Duration
+}
+
+func NewDurationValue(d time.Duration) DurationValue {
+	return DurationValue{v: d}
+}
+
+func (m DurationValue) Nil() bool                         { return m.v == 0 }
+func (m DurationValue) Err() bool                         { return false }
+func (m DurationValue) Type() ValueType                   { return DurationType }
+func (m DurationValue) Rv() reflect.Value                 { return reflect.ValueOf(m.v) }
+func (m DurationValue) CanCoerce(toRv reflect.Value) bool { return CanCoerce(durRv, toRv) }
+func (m DurationValue) Value() interface{}                { return m.v }
+func (m DurationValue) Val() time.Duration                { return m.v }
+func (m DurationValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v.String()) }
+func (m DurationValue) ToString() string                  { return m.v.String() }
+func (m DurationValue) String() string                    { return m.v.String() }
+func (m DurationValue) Float() float64                    { return float64(m.v) / float64(time.Millisecond) }
+func (m DurationValue) Int() int64                        { return int64(m.v / time.Millisecond) }
+
 type ErrorValue struct {
 	v  string
 	rv reflect.Value
@@ -446,6 +556,7 @@ func (m ErrorValue) Value() interface{}                { return m.v }
 func (m ErrorValue) Val() string                       { return m.v }
 func (m ErrorValue) MarshalJSON() ([]byte, error)      { return json.Marshal(m.v) }
 func (m ErrorValue) ToString() string                  { return "" }
+func (m ErrorValue) String() string                    { return "ERROR: " + m.v }
 
 type NilValue struct{}
 
@@ -462,3 +573,12 @@ func (m NilValue) Value() interface{}                { return nil }
 func (m NilValue) Val() interface{}                  { return nil }
 func (m NilValue) MarshalJSON() ([]byte, error)      { return nil, nil }
 func (m NilValue) ToString() string                  { return "" }
+func (m NilValue) String() string                    { return "NULL" }
+
+// the String of a value, a nil value (not NilValue) is NULL as well
+func formatValue(v Value) string {
+	if v == nil {
+		return "NULL"
+	}
+	return v.String()
+}
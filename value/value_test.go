@@ -0,0 +1,40 @@
+package value
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestValueString(t *testing.T) {
+	ts := time.Date(2015, 3, 4, 17, 29, 39, 738e6, time.UTC)
+	tests := []struct {
+		v    Value
+		want string
+	}{
+		{NewNilValue(), "NULL"},
+		{NewIntValue(-42), "-42"},
+		{NewNumberValue(1.5), "1.5"},
+		{NewNumberValue(2), "2"},
+		{NewNumberValue(1e-7), "0.0000001"},
+		{NewBoolValue(true), "true"},
+		{NewStringValue(`say "hi"`), `say "hi"`},
+		{NewStringValue(""), ""},
+		{NewStringsValue([]string{"a", "b"}), "[a, b]"},
+		{NewSliceValues([]Value{NewIntValue(1), NewStringValue("x"), NewNilValue(), nil}), "[1, x, NULL, NULL]"},
+		{NewMapValue(map[string]Value{"b": NewNumberValue(2.25), "a": NewStringValue("x")}), "{a: x, b: 2.25}"},
+		{NewMapIntValue(map[string]int64{"z": 1, "y": 2}), "{y: 2, z: 1}"},
+		{NewTimeValue(ts), "2015-03-04T17:29:39Z"},
+		{NewTimeValue(ts.In(time.FixedZone("PST", -8*3600))), "2015-03-04T09:29:39-08:00"},
+		{NewDurationValue(90 * time.Second), "1m30s"},
+		{NewErrorValue("bad"), "ERROR: bad"},
+		{NewStructValue(struct{ A int }{5}), "{5}"},
+	}
+	for _, tt := range tests {
+		assert.Tf(t, tt.v.String() == tt.want, "%T want %q got %q", tt.v, tt.want, tt.v.String())
+		// fmt uses it as well, rather than printing the go struct
+		assert.Tf(t, fmt.Sprintf("%v", tt.v) == tt.want, "%T fmt got %q", tt.v, fmt.Sprintf("%v", tt.v))
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"reflect"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
@@ -69,7 +69,7 @@ func (m *SqlVm) Execute(writeContext datasource.ContextWriter, readContext datas
 	case lex.TokenDelete:
 		return m.ExecuteDelete(writeContext, readContext)
 	default:
-		u.Warnf("not implemented: %v", m.Keyword)
+		logging.Warnf("not implemented: %v", m.Keyword)
 		return fmt.Errorf("not implemented %v", m.Keyword)
 	}
 	return nil
@@ -90,7 +90,7 @@ func (m *SqlVm) ExecuteSelect(writeContext datasource.ContextWriter, readContext
 
 	// Check and see if we are where Guarded
 	if m.sel.Where != nil {
-		//u.Debugf("Has a Where:  %v", m.Request.Where.Root.StringAST())
+		//logging.Debugf("Has a Where:  %v", m.Request.Where.Root.StringAST())
 		whereValue, ok := s.Walk(m.sel.Where)
 		if !ok {
 			return SqlEvalError
@@ -98,11 +98,11 @@ func (m *SqlVm) ExecuteSelect(writeContext datasource.ContextWriter, readContext
 		switch whereVal := whereValue.(type) {
 		case value.BoolValue:
 			if whereVal == value.BoolValueFalse {
-				u.Debugf("Filtering out")
+				logging.Debugf("Filtering out")
 				return nil
 			}
 		}
-		//u.Debugf("Matched where: %v", whereValue)
+		//logging.Debugf("Matched where: %v", whereValue)
 	}
 	for _, col := range m.sel.Columns {
 		if col.Guard != nil {
@@ -113,7 +113,7 @@ func (m *SqlVm) ExecuteSelect(writeContext datasource.ContextWriter, readContext
 				writeContext.Put(&expr.Column{As: k}, nil, v)
 			}
 		} else {
-			//u.Debugf("tree.Root: as?%v %#v", col.As, col.Tree.Root)
+			//logging.Debugf("tree.Root: as?%v %#v", col.As, col.Tree.Root)
 			v, ok := s.Walk(col.Tree.Root)
 			if ok {
 				writeContext.Put(col, readContext, v)
@@ -132,9 +132,9 @@ func (m *SqlVm) ExecuteInsert(writeContext datasource.RowWriter) (err error) {
 
 		for i, col := range m.ins.Columns {
 
-			//u.Debugf("tree.Root: i, as, val:  %v %v %v", i, col.As, row[i])
+			//logging.Debugf("tree.Root: i, as, val:  %v %v %v", i, col.As, row[i])
 			if col.Tree != nil && col.Tree.Root != nil {
-				u.Warnf("Not implemented")
+				logging.Warnf("Not implemented")
 			}
 			//v, ok := s.Walk(col.Tree.Root)
 			writeContext.Put(col, nil, row[i])
@@ -159,14 +159,14 @@ func (m *SqlVm) ExecuteDelete(writeContext datasource.ContextWriter, readContext
 
 	// Check and see if we are where Guarded
 	if m.del.Where != nil {
-		u.Debugf("Has a Where:  %v", m.del.Where.StringAST())
+		logging.Debugf("Has a Where:  %v", m.del.Where.StringAST())
 
 		for row := scanner.Next(); ; row = scanner.Next() {
 			if row == nil {
 				break
 			}
 			whereValue, ok := s.Walk(m.del.Where)
-			u.Infof("where: %v %v", ok, whereValue)
+			logging.Infof("where: %v %v", ok, whereValue)
 			if !ok {
 				continue
 			}
@@ -174,14 +174,14 @@ func (m *SqlVm) ExecuteDelete(writeContext datasource.ContextWriter, readContext
 			case value.BoolValue:
 				if whereVal == value.BoolValueTrue {
 					if err := writeContext.Delete(row); err != nil {
-						u.Errorf("error %v", err)
+						logging.Errorf("error %v", err)
 					}
 				}
 			}
 		}
 
 	}
-	// //u.Debugf("tree.Root: as?%v %#v", col.As, col.Tree.Root)
+	// //logging.Debugf("tree.Root: as?%v %#v", col.As, col.Tree.Root)
 	// v, ok := s.Walk(col.Tree.Root)
 	// if ok {
 	// 	writeContext.Put(col, readContext, v)
@@ -4,6 +4,11 @@ import (
 	//u "github.com/araddon/gou"
 	"reflect"
 	"testing"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
 )
 
 /*
@@ -174,3 +179,66 @@ func BenchmarkReflectionOurType5(b *testing.B) {
 		}
 	}
 }
+
+/*
+	Allocations per evaluation of a simple arithmetic predicate, n is 5000 so
+	neither it nor the literals are ints go interns (0-255):
+
+		                                   interpreted      compiled
+		eager reflect.Value in each value  11 allocs/op     3 allocs/op
+		reflect.Value built lazily in Rv    7 allocs/op     1 allocs/op
+
+	the remaining allocs are boxing the int result, and interpreted boxing
+	each literal as it walks the tree.
+
+*/
+// go test -bench="EvalAllocs" -benchmem -run=none
+
+const allocsExpr = `n + 2000 > 3000`
+
+// allocs/op of allocsExpr when every value built its reflect.Value up front
+const (
+	allocsBaselineInterpreted = 11
+	allocsBaselineCompiled    = 3
+)
+
+func allocsSetup(t testing.TB) (expr.EvalContext, expr.Node, EvaluatorFunc) {
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{"n": value.NewIntValue(5000)})
+	exprVm, err := NewVm(allocsExpr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := Compile(exprVm.Tree.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx, exprVm.Tree.Root, compiled
+}
+
+func TestEvalAllocs(t *testing.T) {
+	ctx, node, compiled := allocsSetup(t)
+	interpreted := testing.AllocsPerRun(1000, func() { Eval(ctx, node) })
+	assert.Tf(t, interpreted < allocsBaselineInterpreted, "interpreted allocs/op should be below %v but was %v",
+		allocsBaselineInterpreted, interpreted)
+	compiledAllocs := testing.AllocsPerRun(1000, func() { compiled(ctx) })
+	assert.Tf(t, compiledAllocs < allocsBaselineCompiled, "compiled allocs/op should be below %v but was %v",
+		allocsBaselineCompiled, compiledAllocs)
+}
+
+func BenchmarkEvalAllocsInterpreted(b *testing.B) {
+	ctx, node, _ := allocsSetup(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Eval(ctx, node)
+	}
+}
+
+func BenchmarkEvalAllocsCompiled(b *testing.B) {
+	ctx, _, compiled := allocsSetup(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compiled(ctx)
+	}
+}
@@ -0,0 +1,257 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/logging"
+	"github.com/araddon/qlbridge/value"
+)
+
+// Compile walks a node tree once and returns a closure evaluating it,
+//  for hot loops (per row where clauses etc) where re-walking the tree
+//  and type-switching on every node per row is expensive.
+//
+//  - literals (numbers, strings, booleans) are converted to values once
+//  - identity keys, operators and funcs are resolved and captured
+//  - func args are pre-sized and evaluated without the per-arg node switch
+//
+// The compiled func returns the same results as Eval(ctx, node)
+//
+//     evaluator, err := vm.Compile(node)
+//     for _, row := range rows {
+//         v, ok := evaluator(row)
+//     }
+func Compile(arg expr.Node) (f EvaluatorFunc, err error) {
+	if arg == nil {
+		return nil, fmt.Errorf("cannot compile nil node")
+	}
+	if err = arg.Check(); err != nil {
+		return nil, err
+	}
+	defer errRecover(&err)
+	return compileNode(arg), nil
+}
+
+func compileNode(arg expr.Node) EvaluatorFunc {
+	switch n := arg.(type) {
+	case *expr.NumberNode:
+		v := n.Value()
+		return func(ctx expr.EvalContext) (value.Value, bool) { return v, true }
+	case *expr.StringNode:
+		v := value.NewStringValue(n.Text)
+		return func(ctx expr.EvalContext) (value.Value, bool) { return v, true }
+	case *expr.IntervalNode:
+		v := n.Value()
+		return func(ctx expr.EvalContext) (value.Value, bool) { return v, true }
+	case *expr.NullNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return value.NewNilValue(), true }
+	case *expr.IdentityNode:
+		return compileIdentity(n)
+	case *expr.BinaryNode:
+		return compileBinary(n)
+	case *expr.UnaryNode:
+		af := compileNode(n.Arg)
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			a, ok := af(ctx)
+			if !ok {
+				return a, false
+			}
+			return operateUnary(n, a)
+		}
+	case *expr.FuncNode:
+		return compileFunc(n)
+	case *expr.TriNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkTri(ctx, n) }
+	case *expr.MultiArgNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkMulti(ctx, n) }
+	case *expr.RowConstructorNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkRow(ctx, n) }
+	case *expr.MatchNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkMatch(ctx, n) }
+	case *expr.SubQueryNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkSubQuery(ctx, n) }
+	case *expr.ParamNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkParam(n) }
+	case *expr.SubscriptNode:
+		af, kf := compileNode(n.Arg), compileNode(n.Key)
+		return func(ctx expr.EvalContext) (value.Value, bool) {
+			a, ok := af(ctx)
+			if !ok {
+				return value.NewNilValue(), false
+			}
+			k, ok := kf(ctx)
+			if !ok {
+				return value.NewNilValue(), false
+			}
+			return operateSubscript(n, a, k)
+		}
+	}
+	panic(fmt.Errorf("%v: %T", ErrUnknownNodeType, arg))
+}
+
+func compileIdentity(n *expr.IdentityNode) EvaluatorFunc {
+	if n.IsBooleanIdentity() {
+		v := value.NewBoolValue(n.Bool())
+		return func(ctx expr.EvalContext) (value.Value, bool) { return v, true }
+	}
+	key := n.Text
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		if ctx == nil {
+			return value.NewStringValue(n.String()), true
+		}
+		return ctx.Get(key)
+	}
+}
+
+func compileBinary(n *expr.BinaryNode) EvaluatorFunc {
+	af, bf := compileNode(n.Args[0]), compileNode(n.Args[1])
+	// string literals may be coerced to the type of the other side
+	var aTyped, bTyped value.Value
+	if sn, ok := n.Args[0].(*expr.StringNode); ok {
+		aTyped, _ = sn.TypedValue()
+	}
+	if sn, ok := n.Args[1].(*expr.StringNode); ok {
+		bTyped, _ = sn.TypedValue()
+	}
+	calendar := false
+	for _, arg := range n.Args {
+		if in, ok := arg.(*expr.IntervalNode); ok && in.Months != 0 {
+			calendar = true
+		}
+	}
+	nullTest := isNullTest(n)
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		ar, aok := af(ctx)
+		br, bok := bf(ctx)
+		if !aok || !bok {
+			if nullTest {
+				// a missing value IS NULL
+				return operateNull(n, ar, br), true
+			}
+			return nil, true
+		}
+		if calendar {
+			if v, ok := operateCalendar(n, ar, br); ok {
+				return v, true
+			}
+		}
+		if bTyped != nil && isTypedValue(ar) {
+			br = coerceLiteral(bTyped, ar, br)
+		} else if aTyped != nil && isTypedValue(br) {
+			ar = coerceLiteral(aTyped, br, ar)
+		}
+		// int and string ops are by far the most common, skip the
+		// generic switch for them
+		switch at := ar.(type) {
+		case value.IntValue:
+			if bt, ok := br.(value.IntValue); ok && !isZeroDivisor(n.Operator, bt) {
+				return operateInts(ctx, n.Operator, at, bt), true
+			}
+		case value.StringValue:
+			if bt, ok := br.(value.StringValue); ok {
+				return operateStrings(n.Operator, at, bt, collation(ctx)), true
+			}
+		}
+		return operateValues(ctx, n, ar, br), true
+	}
+}
+
+// compiled coalesce funcs, as walkCoalesce
+func compileCoalesce(n *expr.FuncNode) EvaluatorFunc {
+	argFuncs := make([]EvaluatorFunc, len(n.Args))
+	for i, a := range n.Args {
+		argFuncs[i] = compileNode(a)
+	}
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		for _, af := range argFuncs {
+			if v, ok := af(ctx); !isNull(v, ok) {
+				return v, true
+			}
+		}
+		return value.NewNilValue(), true
+	}
+}
+
+// compiled func args mirror the arg handling of walkFunc
+func compileFunc(n *expr.FuncNode) EvaluatorFunc {
+	return compileMemo(n, compileCall(n))
+}
+
+func compileCall(n *expr.FuncNode) EvaluatorFunc {
+	if n.F.Coalesce {
+		return compileCoalesce(n)
+	}
+	if n.F.Table {
+		return func(ctx expr.EvalContext) (value.Value, bool) { return nil, false }
+	}
+	argFuncs := make([]func(ctx expr.EvalContext) value.Value, len(n.Args))
+	for i, a := range n.Args {
+		switch t := a.(type) {
+		case *expr.StringNode, *expr.NumberNode, *expr.IntervalNode:
+			v, _ := compileNode(t)(nil)
+			argFuncs[i] = func(ctx expr.EvalContext) value.Value { return v }
+		case *expr.IdentityNode:
+			if t.IsBooleanIdentity() {
+				v := value.NewBoolValue(t.Bool())
+				argFuncs[i] = func(ctx expr.EvalContext) value.Value { return v }
+				continue
+			}
+			key := t.Text
+			argFuncs[i] = func(ctx expr.EvalContext) value.Value {
+				v, ok := ctx.Get(key)
+				if !ok {
+					// nil arguments are valid
+					return value.NewNilValue()
+				}
+				if v == nil {
+					return value.NewStringValue("")
+				}
+				return v
+			}
+		case *expr.FuncNode, *expr.UnaryNode, *expr.SubscriptNode:
+			af := compileNode(t)
+			argFuncs[i] = func(ctx expr.EvalContext) value.Value {
+				v, ok := af(ctx)
+				if !ok {
+					return value.NewNilValue()
+				}
+				return v
+			}
+		case *expr.BinaryNode:
+			af := compileNode(t)
+			argFuncs[i] = func(ctx expr.EvalContext) value.Value {
+				v, _ := af(ctx)
+				return v
+			}
+		default:
+			af := compileNode(t)
+			argFuncs[i] = func(ctx expr.EvalContext) value.Value {
+				v, ok := af(ctx)
+				if !ok {
+					return value.NewNilValue()
+				}
+				return v
+			}
+		}
+	}
+	fn := n.F.F
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		funcArgs := make([]reflect.Value, len(argFuncs)+1)
+		funcArgs[0] = reflect.ValueOf(ctx)
+		for i, af := range argFuncs {
+			v := af(ctx)
+			if v == nil {
+				logging.Warnf("unknown type:  %v  %T", v, v)
+				v = value.NewNilValue()
+			}
+			funcArgs[i+1] = reflect.ValueOf(v)
+		}
+		fnRet := fn.Call(funcArgs)
+		if len(fnRet) > 1 && !fnRet[1].Bool() {
+			return value.EmptyStringValue, false
+		}
+		return fnRet[0].Interface().(value.Value), true
+	}
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+// evaluate, turning panics into failed evaluation
+func safeEval(f EvaluatorFunc, ctx expr.EvalContext) (v value.Value, ok bool, panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+		}
+	}()
+	v, ok = f(ctx)
+	return
+}
+
+func valString(v value.Value) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%T:%v", v, v.Value())
+}
+
+func TestCompileMatchesEval(t *testing.T) {
+
+	for _, test := range vmTests {
+		if !test.parseok {
+			continue
+		}
+		exprVm, err := NewVm(test.qlText)
+		assert.Tf(t, err == nil, "%v parse: %v", test.qlText, err)
+
+		compiled, err := Compile(exprVm.Tree.Root)
+		assert.Tf(t, err == nil, "%v compile: %v", test.qlText, err)
+
+		node := exprVm.Tree.Root
+		iv, iok, ipanic := safeEval(func(ctx expr.EvalContext) (value.Value, bool) { return Eval(ctx, node) }, test.context)
+		cv, cok, cpanic := safeEval(compiled, test.context)
+		assert.Tf(t, ipanic == cpanic, "%v panic mismatch: interpreted=%v compiled=%v", test.qlText, ipanic, cpanic)
+		assert.Tf(t, iok == cok, "%v ok mismatch: interpreted=%v compiled=%v", test.qlText, iok, cok)
+		assert.Tf(t, valString(iv) == valString(cv),
+			"%v result mismatch: interpreted=%v compiled=%v", test.qlText, valString(iv), valString(cv))
+	}
+
+	_, err := Compile(nil)
+	assert.T(t, err != nil)
+}
+
+/*
+	Compiled vs Interpreted (Eval) over a million rows
+
+BenchmarkEvalInterpreted	       2	 844231776 ns/op
+BenchmarkEvalCompiled   	       2	 549691298 ns/op
+
+*/
+// go test -bench="Eval(Interpreted|Compiled)" -run=none
+
+const benchRowCt = 1000000
+
+var benchExpr = `int5 * 2 + 10 > 15 AND user_id == "abc" AND (str5 != "x" OR int5 < 2)`
+
+func benchRows() []expr.ContextReader {
+	rows := make([]expr.ContextReader, 100)
+	for i := range rows {
+		rows[i] = datasource.NewContextSimpleData(map[string]value.Value{
+			"int5":    value.NewIntValue(int64(i)),
+			"str5":    value.NewStringValue(fmt.Sprintf("%d", i)),
+			"user_id": value.NewStringValue("abc"),
+		})
+	}
+	return rows
+}
+
+func BenchmarkEvalInterpreted(b *testing.B) {
+	exprVm, err := NewVm(benchExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	node := exprVm.Tree.Root
+	rows := benchRows()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < benchRowCt; j++ {
+			Eval(rows[j%len(rows)], node)
+		}
+	}
+}
+
+func BenchmarkEvalCompiled(b *testing.B) {
+	exprVm, err := NewVm(benchExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	compiled, err := Compile(exprVm.Tree.Root)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rows := benchRows()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < benchRowCt; j++ {
+			compiled(rows[j%len(rows)])
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package vm
+
+import (
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+// EvalFilter evaluates the FilterQL @filter against a row, @ok is false if
+//  the filter could not be evaluated (ie, missing fields), which is not a match
+//
+//     filter, _ := expr.ParseFilterQL(`FILTER AND (score > 20, country == "us")`)
+//     if matches, _ := vm.EvalFilter(row, filter); matches {
+func EvalFilter(ctx expr.EvalContext, filter *expr.FilterStatement) (matches bool, ok bool) {
+	v, ok := Eval(ctx, filter.Filter)
+	if !ok || v == nil {
+		return false, false
+	}
+	if bv, isBool := v.(value.BoolValue); isBool {
+		return bv.Val(), true
+	}
+	return false, false
+}
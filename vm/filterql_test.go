@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+	"github.com/bmizerany/assert"
+)
+
+func TestEvalFilter(t *testing.T) {
+
+	row := datasource.NewContextSimpleData(map[string]value.Value{
+		"score":   value.NewIntValue(25),
+		"country": value.NewStringValue("us"),
+		"name":    value.NewStringValue("Bob"),
+		"email":   value.NewStringValue("bob@example.com"),
+	})
+
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{`FILTER score > 20`, true},
+		{`FILTER AND (score > 20, country == "us")`, true},
+		{`FILTER AND (score > 20, country == "uk")`, false},
+		{`FILTER OR (score > 30, country == "us")`, true},
+		{`FILTER OR (score > 30, country == "uk")`, false},
+		{`FILTER AND (
+			score > 20,
+			OR ( country == "uk", email LIKE "%@example.com" ),
+			eq(name, "Bob")
+		)`, true},
+		{`FILTER AND (score > 20, OR (country == "uk", AND (name == "Bob", score < 10)))`, false},
+		{`FILTER NOT OR (score > 30, country == "uk")`, true},
+	}
+	for _, test := range tests {
+		filter, err := expr.ParseFilterQL(test.filter)
+		assert.Tf(t, err == nil, "%s  no error %v", test.filter, err)
+		matches, ok := EvalFilter(row, filter)
+		assert.Tf(t, ok, "%s  could evaluate", test.filter)
+		assert.Tf(t, matches == test.matches, "%s  want %v", test.filter, test.matches)
+	}
+
+	// not a boolean filter
+	filter, err := expr.ParseFilterQL(`FILTER score + 1`)
+	assert.Tf(t, err == nil, "no error %v", err)
+	matches, ok := EvalFilter(row, filter)
+	assert.T(t, !matches && !ok)
+}
@@ -0,0 +1,136 @@
+package vm
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/araddon/qlbridge/datasource"
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+// seed corpus for FuzzParseEval, the expressions of the vm tests plus the
+//  edge cases (empty IN, dangling operators, NULL) that used to panic
+var fuzzSeeds = []string{
+	`5 + 4`,
+	`-5 * (2 + int)`,
+	`int / 0`,
+	`str == "a" AND int > 1 OR NOT bool`,
+	`int BETWEEN 1 AND 10`,
+	`str LIKE "a%"`,
+	`str ILIKE "A%"`,
+	`int IN (1, 2, NULL)`,
+	`int IN ()`,
+	`str NOT IN ("a", "b")`,
+	`x IS NULL`,
+	`x IS NOT NULL`,
+	`NULL = NULL`,
+	`tags['env']`,
+	`arr[0] + arr[-1]`,
+	`toint(str) + 1`,
+	`eq(int, 5)`,
+	`eq(arr[0], tags["env"])`,
+	`ts > "now-1d"`,
+	`ts + INTERVAL 1 DAY`,
+	`"a" || str`,
+	`(1, 2) = (1, 2)`,
+	`int !`,
+	`(((`,
+	`)`,
+	`IN`,
+	`AND OR`,
+	`-`,
+	``,
+	`;`,
+	`NOT`,
+	`true[],`,
+	"&\u039e",
+	"A''\u07d9000",
+	`toint(5 + 4`,
+	`eq(yy(NOT`,
+	`toint(str, 1) + 1`,
+	`eq(arr[0], ta || gs["env"])`,
+	`bool IN (1, "b")`,
+	`str * ts - bool`,
+}
+
+// FuzzParseEval parses arbitrary text as an expression and, if it parses,
+//  type checks, evaluates and compiles it against a random context; none
+//  of which may panic.
+//
+//     go test -run none -fuzz FuzzParseEval ./vm
+//
+func FuzzParseEval(f *testing.F) {
+	for i, ql := range fuzzSeeds {
+		f.Add(ql, int64(i))
+	}
+	f.Fuzz(func(t *testing.T, ql string, seed int64) {
+		if err := parseEval(ql, seed); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// parse, check, eval and compile @ql, a panic along the way is the error,
+//  except the errors the parser panics with, which are only a bad @ql
+func parseEval(ql string, seed int64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic on %q seed=%d: %v", ql, seed, r)
+		}
+	}()
+	tree, err := parseFuzz(ql)
+	if err != nil || tree.Root == nil {
+		return nil
+	}
+	_ = tree.Root.String()
+	if err := tree.Root.Check(); err != nil {
+		return nil
+	}
+	ctx := fuzzContext(rand.New(rand.NewSource(seed)))
+	Eval(ctx, tree.Root)
+	if compiled, err := Compile(tree.Root); err == nil {
+		compiled(ctx)
+	}
+	return nil
+}
+
+func parseFuzz(ql string) (tree *expr.Tree, err error) {
+	defer errRecover(&err)
+	return expr.ParseExpression(ql)
+}
+
+// a context whose well known keys are each a random type of value
+func fuzzContext(r *rand.Rand) *datasource.ContextSimple {
+	vals := []value.Value{
+		value.NewNilValue(),
+		value.NewIntValue(r.Int63n(20) - 10),
+		value.NewNumberValue(r.NormFloat64()),
+		value.NewStringValue("a"),
+		value.NewStringValue(""),
+		value.NewBoolValue(r.Intn(2) == 0),
+		value.NewTimeValue(time.Unix(r.Int63n(2e9), 0)),
+		value.NewStringsValue([]string{"a", "b"}),
+		value.NewSliceValues([]value.Value{value.NewIntValue(1), value.NewNilValue()}),
+		value.NewMapValue(map[string]value.Value{"env": value.NewStringValue("prod")}),
+	}
+	data := make(map[string]value.Value)
+	for _, key := range []string{"int", "str", "bool", "ts", "x", "tags", "arr"} {
+		data[key] = vals[r.Intn(len(vals))]
+	}
+	return datasource.NewContextSimpleData(data)
+}
+
+func TestFuzzSeeds(t *testing.T) {
+	// the seeds run as part of FuzzParseEval, but run them across a few
+	//  contexts as well
+	for seed := int64(0); seed < 20; seed++ {
+		for _, ql := range fuzzSeeds {
+			if err := parseEval(ql, seed); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
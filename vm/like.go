@@ -0,0 +1,109 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	// default escape character for LIKE patterns
+	LikeEscapeDefault = '\\'
+
+	// compiled patterns, patterns are almost always literals so
+	// we avoid re-compiling per row
+	likeMu       sync.Mutex
+	likeCache    = make(map[string]*regexp.Regexp)
+	likeCacheMax = 1000
+)
+
+// LikeCompile converts a sql LIKE pattern into an anchored regular
+// expression, honoring @escape (0 for none) so literal wildcards can be matched
+//
+//     a%b       =>  ^a.*b$
+//     50\%      =>  ^50%$
+//     a!_b      =>  ^a_b$    (escape = '!')
+//
+func LikeCompile(pattern string, escape rune) (*regexp.Regexp, error) {
+	return likeCompile(pattern, escape, false)
+}
+
+func likeCompile(pattern string, escape rune, ignoreCase bool) (*regexp.Regexp, error) {
+
+	cacheKey := string(escape) + ":" + pattern
+	if ignoreCase {
+		cacheKey = "i" + cacheKey
+	}
+	likeMu.Lock()
+	re, ok := likeCache[cacheKey]
+	likeMu.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	var buf bytes.Buffer
+	if ignoreCase {
+		buf.WriteString("(?i)")
+	}
+	buf.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case escape != 0 && r == escape:
+			escaped = true
+		case r == '%':
+			buf.WriteString(".*")
+		case r == '_':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("LIKE pattern must not end with escape character: %q", pattern)
+	}
+	buf.WriteString("$")
+
+	re, err := regexp.Compile(buf.String())
+	if err != nil {
+		return nil, err
+	}
+	likeMu.Lock()
+	if len(likeCache) >= likeCacheMax {
+		likeCache = make(map[string]*regexp.Regexp)
+	}
+	likeCache[cacheKey] = re
+	likeMu.Unlock()
+	return re, nil
+}
+
+// LikeMatch evaluates  @val LIKE @pattern ESCAPE @escape
+func LikeMatch(val, pattern string, escape rune) (bool, error) {
+	return likeMatch(val, pattern, escape, false)
+}
+
+// ILikeMatch evaluates  @val ILIKE @pattern ESCAPE @escape, a LIKE that
+//  ignores case
+func ILikeMatch(val, pattern string, escape rune) (bool, error) {
+	return likeMatch(val, pattern, escape, true)
+}
+
+func likeMatch(val, pattern string, escape rune, ignoreCase bool) (bool, error) {
+	re, err := likeCompile(pattern, escape, ignoreCase)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(val), nil
+}
+
+// LIKE ignores case if the collation does, ILIKE always does
+func likeIgnoresCase(op lex.TokenType, coll value.Collation) bool {
+	return op == lex.TokenILike || (coll != nil && coll.Compare("a", "A") == 0)
+}
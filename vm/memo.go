@@ -0,0 +1,121 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/value"
+)
+
+var (
+	_ expr.ContextMemo         = (*MemoContext)(nil)
+	_ expr.ContextCollation    = (*MemoContext)(nil)
+	_ expr.ContextDivideByZero = (*MemoContext)(nil)
+	_ expr.ContextIntOverflow  = (*MemoContext)(nil)
+	_ expr.ContextNullArgs     = (*MemoContext)(nil)
+	_ expr.ContextSubQuery     = (*MemoContext)(nil)
+)
+
+// MemoContext is the context of a single row which memoizes the values of
+//  the pure funcs evaluated on it (see expr.IsPure), so a func used in
+//  several columns (or twice in a where) is called once for the row.  Use
+//  a new MemoContext per row.
+//
+//     memo := vm.NewMemoContext(row)
+//     for _, col := range cols {
+//         v, ok := vm.Eval(memo, col.Expr)
+//     }
+//
+// The optional context interfaces (collation, divide by zero etc) of the
+//  wrapped context are forwarded.
+type MemoContext struct {
+	expr.EvalContext
+	memo map[string]value.Value
+}
+
+func NewMemoContext(ctx expr.EvalContext) *MemoContext {
+	return &MemoContext{EvalContext: ctx}
+}
+
+func (m *MemoContext) Memo(fingerprint string) (value.Value, bool) {
+	v, ok := m.memo[fingerprint]
+	return v, ok
+}
+func (m *MemoContext) SetMemo(fingerprint string, v value.Value) {
+	if m.memo == nil {
+		m.memo = make(map[string]value.Value)
+	}
+	m.memo[fingerprint] = v
+}
+
+func (m *MemoContext) Collation() value.Collation {
+	if cctx, ok := m.EvalContext.(expr.ContextCollation); ok {
+		return cctx.Collation()
+	}
+	return nil
+}
+func (m *MemoContext) DivideByZero() expr.DivideByZeroPolicy {
+	if dctx, ok := m.EvalContext.(expr.ContextDivideByZero); ok {
+		return dctx.DivideByZero()
+	}
+	return expr.DivideByZeroNull
+}
+func (m *MemoContext) IntOverflow() expr.IntOverflowPolicy {
+	if octx, ok := m.EvalContext.(expr.ContextIntOverflow); ok {
+		return octx.IntOverflow()
+	}
+	return expr.IntOverflowPromote
+}
+func (m *MemoContext) NullArgs() expr.NullArgsPolicy {
+	if nctx, ok := m.EvalContext.(expr.ContextNullArgs); ok {
+		return nctx.NullArgs()
+	}
+	return expr.NullArgsIgnore
+}
+func (m *MemoContext) SubQuery(stmt *expr.SqlSelect) ([][]value.Value, error) {
+	if sctx, ok := m.EvalContext.(expr.ContextSubQuery); ok {
+		return sctx.SubQuery(stmt)
+	}
+	return nil, fmt.Errorf("context does not support sub-query: %T", m.EvalContext)
+}
+
+// the value of pure func @node, from the memo of the context if it has one
+//  and the func was already called on this row
+func memoFunc(ctx expr.EvalContext, node *expr.FuncNode, call EvaluatorFunc) (value.Value, bool) {
+	mctx, ok := ctx.(expr.ContextMemo)
+	if !ok || !expr.IsPure(node) {
+		return call(ctx)
+	}
+	fingerprint := expr.Fingerprint(node)
+	if v, ok := mctx.Memo(fingerprint); ok {
+		return v, true
+	}
+	v, ok := call(ctx)
+	// failed calls are not memoized, they are retried
+	if ok {
+		mctx.SetMemo(fingerprint, v)
+	}
+	return v, ok
+}
+
+// the compiled form of memoFunc, purity and fingerprint are known up front
+func compileMemo(node *expr.FuncNode, call EvaluatorFunc) EvaluatorFunc {
+	if !expr.IsPure(node) {
+		return call
+	}
+	fingerprint := expr.Fingerprint(node)
+	return func(ctx expr.EvalContext) (value.Value, bool) {
+		mctx, ok := ctx.(expr.ContextMemo)
+		if !ok {
+			return call(ctx)
+		}
+		if v, ok := mctx.Memo(fingerprint); ok {
+			return v, true
+		}
+		v, ok := call(ctx)
+		if ok {
+			mctx.SetMemo(fingerprint, v)
+		}
+		return v, ok
+	}
+}
@@ -8,9 +8,9 @@ import (
 	"runtime"
 	"time"
 
-	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/expr"
 	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 )
 
@@ -18,7 +18,6 @@ var (
 	ErrUnknownOp       = fmt.Errorf("expr: unknown op type")
 	ErrUnknownNodeType = fmt.Errorf("expr: unknown node type")
 	ErrExecute         = fmt.Errorf("Could not execute")
-	_                  = u.EMPTY
 
 	SchemaInfoEmpty = &NoSchema{}
 
@@ -95,12 +94,12 @@ func (m *Vm) Execute(writeContext expr.ContextWriter, readContext expr.ContextRe
 		ContextReader: readContext,
 	}
 	s.rv = reflect.ValueOf(s)
-	//u.Debugf("vm.Execute:  %#v", m.Tree.Root)
+	//logging.Debugf("vm.Execute:  %#v", m.Tree.Root)
 	v, ok := s.Walk(m.Tree.Root)
-	//u.Infof("v:%v  ok?%v", v, ok)
-	if ok && v != value.ErrValue {
+	//logging.Infof("v:%v  ok?%v", v, ok)
+	if ok && v != value.ErrValue && (v == nil || !v.Err()) {
 		// Special Vm that doesnt' have named fields, single tree expression
-		//u.Debugf("vm.Walk val:  %v", v)
+		//logging.Debugf("vm.Walk val:  %v", v)
 		writeContext.Put(SchemaInfoEmpty, readContext, v)
 		return nil
 	}
@@ -123,26 +122,19 @@ func errRecover(errp *error) {
 	}
 }
 
-// creates a new Value with a nil group and given value.
-// TODO:  convert this to an interface method on nodes called Value()
-func numberNodeToValue(t *expr.NumberNode) (v value.Value) {
-	//u.Debugf("nodeToValue()  isFloat?%v", t.IsFloat)
-	if t.IsInt {
-		v = value.NewIntValue(t.Int64)
-	} else if t.IsFloat {
-		v = value.NewNumberValue(value.ToFloat64(reflect.ValueOf(t.Text)))
-	} else {
-		u.Errorf("Could not find type? %v", t.Type())
-	}
-	//u.Debugf("return nodeToValue()	%v  %T  arg:%T", v, v, t)
-	return v
+// the value of an operation that does not apply to its operands, an
+//  error instead of a panic, so a bad row does not take down the caller
+func errValue(err error) value.ErrorValue {
+	return value.NewErrorValue(err.Error())
 }
 
+// creates a new Value with a nil group and given value.
+// TODO:  convert this to an interface method on nodes called Value()
 func Evaluator(arg expr.Node) EvaluatorFunc {
-	//u.Debugf("Evaluator() node=%T  %v", arg, arg)
+	//logging.Debugf("Evaluator() node=%T  %v", arg, arg)
 	switch argVal := arg.(type) {
 	case *expr.NumberNode:
-		return func(ctx expr.EvalContext) (value.Value, bool) { return numberNodeToValue(argVal), true }
+		return func(ctx expr.EvalContext) (value.Value, bool) { return argVal.Value(), true }
 	case *expr.BinaryNode:
 		return func(ctx expr.EvalContext) (value.Value, bool) { return walkBinary(ctx, argVal), true }
 	case *expr.UnaryNode:
@@ -153,22 +145,36 @@ func Evaluator(arg expr.Node) EvaluatorFunc {
 		return func(ctx expr.EvalContext) (value.Value, bool) { return walkIdentity(ctx, argVal) }
 	case *expr.StringNode:
 		return func(ctx expr.EvalContext) (value.Value, bool) { return value.NewStringValue(argVal.Text), true }
+	case *expr.IntervalNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return argVal.Value(), true }
 	case *expr.TriNode:
 		return func(ctx expr.EvalContext) (value.Value, bool) { return walkTri(ctx, argVal) }
 	case *expr.MultiArgNode:
 		return func(ctx expr.EvalContext) (value.Value, bool) { return walkMulti(ctx, argVal) }
+	case *expr.RowConstructorNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkRow(ctx, argVal) }
+	case *expr.SubscriptNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkSubscript(ctx, argVal) }
+	case *expr.MatchNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkMatch(ctx, argVal) }
+	case *expr.SubQueryNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkSubQuery(ctx, argVal) }
+	case *expr.ParamNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return walkParam(argVal) }
+	case *expr.NullNode:
+		return func(ctx expr.EvalContext) (value.Value, bool) { return value.NewNilValue(), true }
 	default:
-		u.Errorf("Unknonwn node type:  %T", argVal)
+		logging.Errorf("Unknonwn node type:  %T", argVal)
 		panic(ErrUnknownNodeType)
 	}
 }
 
 func Eval(ctx expr.EvalContext, arg expr.Node) (value.Value, bool) {
-	//u.Debugf("Eval() node=%T  %v", arg, arg)
+	//logging.Debugf("Eval() node=%T  %v", arg, arg)
 	// can we switch to arg.Type()
 	switch argVal := arg.(type) {
 	case *expr.NumberNode:
-		return numberNodeToValue(argVal), true
+		return argVal.Value(), true
 	case *expr.BinaryNode:
 		return walkBinary(ctx, argVal), true
 	case *expr.UnaryNode:
@@ -177,6 +183,16 @@ func Eval(ctx expr.EvalContext, arg expr.Node) (value.Value, bool) {
 		return walkTri(ctx, argVal)
 	case *expr.MultiArgNode:
 		return walkMulti(ctx, argVal)
+	case *expr.RowConstructorNode:
+		return walkRow(ctx, argVal)
+	case *expr.SubscriptNode:
+		return walkSubscript(ctx, argVal)
+	case *expr.MatchNode:
+		return walkMatch(ctx, argVal)
+	case *expr.SubQueryNode:
+		return walkSubQuery(ctx, argVal)
+	case *expr.ParamNode:
+		return walkParam(argVal)
 	case *expr.FuncNode:
 		//return walkFunc(argVal)
 		return walkFunc(ctx, argVal)
@@ -184,8 +200,12 @@ func Eval(ctx expr.EvalContext, arg expr.Node) (value.Value, bool) {
 		return walkIdentity(ctx, argVal)
 	case *expr.StringNode:
 		return value.NewStringValue(argVal.Text), true
+	case *expr.IntervalNode:
+		return argVal.Value(), true
+	case *expr.NullNode:
+		return value.NewNilValue(), true
 	default:
-		u.Errorf("Unknonwn node type:  %T", argVal)
+		logging.Errorf("Unknonwn node type:  %T", argVal)
 		panic(ErrUnknownNodeType)
 	}
 }
@@ -198,38 +218,107 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode) value.Value {
 	ar, aok := Eval(ctx, node.Args[0])
 	br, bok := Eval(ctx, node.Args[1])
 	if !aok || !bok {
-		u.Warnf("not ok: %v  l:%v  r:%v  %T  %T", node, ar, br, ar, br)
+		if isNullTest(node) {
+			// a missing value IS NULL
+			return operateNull(node, ar, br)
+		}
+		logging.Warnf("not ok: %v  l:%v  r:%v  %T  %T", node, ar, br, ar, br)
 		return nil
 	}
-	//u.Debugf("node.Args: %#v", node.Args)
-	//u.Debugf("walkBinary: %v  l:%v  r:%v  %T  %T", node, ar, br, ar, br)
-	switch at := ar.(type) {
-	case value.IntValue:
-		switch bt := br.(type) {
-		case value.IntValue:
-			//u.Debugf("doing operate ints  %v %v  %v", at, node.Operator.V, bt)
-			n := operateInts(node.Operator, at, bt)
-			return n
-		case value.NumberValue:
-			//u.Debugf("doing operate ints/numbers  %v %v  %v", at, node.Operator.V, bt)
-			n := operateNumbers(node.Operator, at.NumberValue(), bt)
-			return n
-		default:
-			u.Errorf("unknown type:  %T %v", bt, bt)
-			panic(ErrUnknownOp)
+	if v, ok := operateCalendar(node, ar, br); ok {
+		return v
+	}
+	if sn, ok := node.Args[1].(*expr.StringNode); ok && isTypedValue(ar) {
+		tv, _ := sn.TypedValue()
+		br = coerceLiteral(tv, ar, br)
+	} else if sn, ok := node.Args[0].(*expr.StringNode); ok && isTypedValue(br) {
+		tv, _ := sn.TypedValue()
+		ar = coerceLiteral(tv, br, ar)
+	}
+	return operateValues(ctx, node, ar, br)
+}
+
+// time +/- a calendar interval literal (months, years) must use the
+// calendar, not a fixed duration
+func operateCalendar(node *expr.BinaryNode, ar, br value.Value) (value.Value, bool) {
+	switch node.Operator.T {
+	case lex.TokenPlus, lex.TokenMinus:
+	default:
+		return nil, false
+	}
+	if in, ok := node.Args[1].(*expr.IntervalNode); ok && in.Months != 0 {
+		if at, ok := ar.(value.TimeValue); ok {
+			sign := 1
+			if node.Operator.T == lex.TokenMinus {
+				sign = -1
+			}
+			return value.NewTimeValue(in.AddTo(at.Val(), sign)), true
 		}
-	case value.NumberValue:
-		switch bt := br.(type) {
-		case value.IntValue:
-			n := operateNumbers(node.Operator, at, bt.NumberValue())
-			return n
-		case value.NumberValue:
-			n := operateNumbers(node.Operator, at, bt)
-			return n
-		default:
-			u.Errorf("unknown type:  %T %v", bt, bt)
-			panic(ErrUnknownOp)
+	}
+	if in, ok := node.Args[0].(*expr.IntervalNode); ok && in.Months != 0 && node.Operator.T == lex.TokenPlus {
+		if bt, ok := br.(value.TimeValue); ok {
+			return value.NewTimeValue(in.AddTo(bt.Val(), 1)), true
+		}
+	}
+	return nil, false
+}
+
+func isTypedValue(v value.Value) bool {
+	switch v.(type) {
+	case value.IntValue, value.NumberValue, value.BoolValue, value.TimeValue:
+		return true
+	}
+	return false
+}
+
+// coerceLiteral returns the typed form @tv of a string literal if it is
+// compatible with the @other side of a binary op, ie   age > "30", else
+// the original @lit value
+func coerceLiteral(tv, other, lit value.Value) value.Value {
+	switch other.(type) {
+	case value.IntValue, value.NumberValue:
+		switch tv.(type) {
+		case value.IntValue, value.NumberValue:
+			return tv
 		}
+	case value.BoolValue:
+		if _, ok := tv.(value.BoolValue); ok {
+			return tv
+		}
+	case value.TimeValue:
+		if _, ok := tv.(value.TimeValue); ok {
+			return tv
+		}
+	}
+	return lit
+}
+
+// Apply the binary operator to already evaluated left/right values
+func operateValues(ctx expr.EvalContext, node *expr.BinaryNode, ar, br value.Value) value.Value {
+	//logging.Debugf("node.Args: %#v", node.Args)
+	//logging.Debugf("walkBinary: %v  l:%v  r:%v  %T  %T", node, ar, br, ar, br)
+	if isNullValue(ar) || isNullValue(br) {
+		return operateNull(node, ar, br)
+	}
+	switch node.Operator.T {
+	case lex.TokenDivide, lex.TokenModulus:
+		if isZeroDivisor(node.Operator, br) {
+			return divideByZero(ctx, node.Operator, ar)
+		}
+	case lex.TokenConcat:
+		// ansi  ||  concat, null if either side is null
+		if ar == nil || br == nil || ar.Type() == value.NilType || br.Type() == value.NilType {
+			return value.NewNilValue()
+		}
+		return value.NewStringValue(ar.ToString() + br.ToString())
+	}
+	switch at := ar.(type) {
+	case value.IntValue, value.NumberValue:
+		if an, bn, ok := value.PromoteNumeric(ar, br); ok {
+			return operateNumeric(ctx, node.Operator, an, bn)
+		}
+		logging.Errorf("unknown type:  %T %v", br, br)
+		return errValue(ErrUnknownOp)
 	case value.BoolValue:
 		switch bt := br.(type) {
 		case value.BoolValue:
@@ -244,22 +333,43 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode) value.Value {
 			case lex.TokenNE:
 				return value.NewBoolValue(atv != btv)
 			default:
-				u.Infof("bool binary?:  %v  %v", at, bt)
-				panic(ErrUnknownOp)
+				logging.Infof("bool binary?:  %v  %v", at, bt)
+				return errValue(ErrUnknownOp)
 			}
 
 		default:
-			u.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), bt, bt.Value())
-			panic(ErrUnknownOp)
+			logging.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), bt, bt.Value())
+			return errValue(ErrUnknownOp)
+		}
+	case value.TimeValue:
+		switch bt := br.(type) {
+		case value.TimeValue:
+			return operateTimes(node.Operator, at, bt)
+		case value.DurationValue:
+			return operateTimeDuration(node.Operator, at, bt)
 		}
+		logging.Errorf("unknown type:  %T %v", br, br)
+		return errValue(ErrUnknownOp)
+	case value.DurationValue:
+		switch bt := br.(type) {
+		case value.DurationValue:
+			return operateDurations(node.Operator, at, bt)
+		case value.TimeValue:
+			// interval + time
+			if node.Operator.T == lex.TokenPlus {
+				return operateTimeDuration(node.Operator, bt, at)
+			}
+		}
+		logging.Errorf("unknown type:  %T %v", br, br)
+		return errValue(ErrUnknownOp)
 	case value.StringValue:
 		switch bt := br.(type) {
 		case value.StringValue:
 			// Nice, both strings
-			return operateStrings(node.Operator, at, bt)
+			return operateStrings(node.Operator, at, bt, collation(ctx))
 		case value.BoolValue:
 			if value.IsBool(at.Val()) {
-				//u.Warnf("bool eval:  %v %v %v  :: %v", value.BoolStringVal(at.Val()), node.Operator.T.String(), bt.Val(), value.NewBoolValue(value.BoolStringVal(at.Val()) == bt.Val()))
+				//logging.Warnf("bool eval:  %v %v %v  :: %v", value.BoolStringVal(at.Val()), node.Operator.T.String(), bt.Val(), value.NewBoolValue(value.BoolStringVal(at.Val()) == bt.Val()))
 				switch node.Operator.T {
 				case lex.TokenEqualEqual, lex.TokenEqual:
 					return value.NewBoolValue(value.BoolStringVal(at.Val()) == bt.Val())
@@ -268,6 +378,9 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode) value.Value {
 				}
 			}
 		default:
+			if an, bn, ok := value.PromoteNumeric(at, br); ok {
+				return operateNumeric(ctx, node.Operator, an, bn)
+			}
 			// TODO:  this doesn't make sense, we should be able to operate on other types
 			if at.CanCoerce(int64Rv) {
 				switch bt := br.(type) {
@@ -281,11 +394,11 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode) value.Value {
 					n := operateNumbers(node.Operator, at.NumberValue(), bt)
 					return n
 				default:
-					u.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), bt, bt.Value())
-					panic(ErrUnknownOp)
+					logging.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), bt, bt.Value())
+					return errValue(ErrUnknownOp)
 				}
 			} else {
-				u.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), br, br)
+				logging.Errorf("at?%T  %v  coerce?%v bt? %T     %v", at, at.Value(), at.CanCoerce(stringRv), br, br)
 			}
 		}
 
@@ -302,29 +415,71 @@ func walkBinary(ctx expr.EvalContext, node *expr.BinaryNode) value.Value {
 		// 		n := operateNumbers(node.Operator, NumberNaNValue, bt)
 		// 		return n
 		// 	case nil:
-		// 		u.Errorf("a && b nil? at?%v  %v    %v", at, bt, node.Operator)
+		// 		logging.Errorf("a && b nil? at?%v  %v    %v", at, bt, node.Operator)
 		// 	default:
-		// 		u.Errorf("nil at?%v  %T      %v", at, bt, node.Operator)
-		// 		panic(ErrUnknownOp)
+		// 		logging.Errorf("nil at?%v  %T      %v", at, bt, node.Operator)
+		// 		return errValue(ErrUnknownOp)
 		// 	}
 		// default:
-		u.Errorf("Unknown op?  %T  %T  %v", ar, at, ar)
-		panic(ErrUnknownOp)
+		logging.Errorf("Unknown op?  %T  %T  %v", ar, at, ar)
+		return errValue(ErrUnknownOp)
 	}
 
 	return nil
 }
 
+// Apply the binary operator when either side is NULL, per sql
+//
+//     NULL = 1, NULL = NULL, NULL > 1, NULL + 1   => NULL (unknown)
+//     NULL AND false, NULL OR true               => false, true  (three valued logic)
+//     x IS NULL, x IS NOT NULL                   => true or false, never NULL
+//
+func operateNull(node *expr.BinaryNode, ar, br value.Value) value.Value {
+	switch node.Operator.T {
+	case lex.TokenIs, lex.TokenIsNot:
+		isNull := isNullValue(ar) && isNullValue(br)
+		return value.NewBoolValue(isNull == (node.Operator.T == lex.TokenIs))
+	case lex.TokenLogicAnd, lex.TokenAnd:
+		if isBool(ar, false) || isBool(br, false) {
+			return value.BoolValueFalse
+		}
+	case lex.TokenLogicOr, lex.TokenOr:
+		if isBool(ar, true) || isBool(br, true) {
+			return value.BoolValueTrue
+		}
+	}
+	return value.NewNilValue()
+}
+
+// is @node   x IS [NOT] NULL
+func isNullTest(node *expr.BinaryNode) bool {
+	switch node.Operator.T {
+	case lex.TokenIs, lex.TokenIsNot:
+		return true
+	}
+	return false
+}
+
+// NULL, or not a value at all
+func isNullValue(v value.Value) bool {
+	return v == nil || v.Type() == value.NilType
+}
+
+func isBool(v value.Value, want bool) bool {
+	bv, ok := v.(value.BoolValue)
+	return ok && bv.Val() == want
+}
+
 func walkIdentity(ctx expr.EvalContext, node *expr.IdentityNode) (value.Value, bool) {
 
 	if node.IsBooleanIdentity() {
-		//u.Debugf("walkIdentity() boolean: node=%T  %v Bool:%v", node, node, node.Bool())
+		//logging.Debugf("walkIdentity() boolean: node=%T  %v Bool:%v", node, node, node.Bool())
 		return value.NewBoolValue(node.Bool()), true
 	}
 	if ctx == nil {
 		return value.NewStringValue(node.String()), true
 	}
-	//u.Debugf("walkIdentity() node=%T  %v", node, node)
+	//logging.Debugf("walkIdentity() node=%T  %v", node, node)
 	return ctx.Get(node.Text)
 }
 
@@ -332,25 +487,34 @@ func walkUnary(ctx expr.EvalContext, node *expr.UnaryNode) (value.Value, bool) {
 
 	a, ok := Eval(ctx, node.Arg)
 	if !ok {
-		u.Infof("whoops, %#v", node)
+		logging.Infof("whoops, %#v", node)
 		return a, false
 	}
+	return operateUnary(node, a)
+}
+
+// Apply the unary operator to an already evaluated value
+func operateUnary(node *expr.UnaryNode, a value.Value) (value.Value, bool) {
+	if isNullValue(a) {
+		// NOT NULL, -NULL are NULL
+		return value.NewNilValue(), true
+	}
 	switch node.Operator.T {
 	case lex.TokenNegate:
 		switch argVal := a.(type) {
 		case value.BoolValue:
-			//u.Infof("found urnary bool:  res=%v   expr=%v", !argVal.v, node.StringAST())
+			//logging.Infof("found urnary bool:  res=%v   expr=%v", !argVal.v, node.StringAST())
 			return value.NewBoolValue(!argVal.Val()), true
 		default:
-			//u.Errorf("urnary type not implementedUnknonwn node type:  %T", argVal)
-			panic(ErrUnknownNodeType)
+			//logging.Errorf("urnary type not implementedUnknonwn node type:  %T", argVal)
+			return errValue(ErrUnknownNodeType), false
 		}
 	case lex.TokenMinus:
 		if an, aok := a.(value.NumericValue); aok {
 			return value.NewNumberValue(-an.Float()), true
 		}
 	default:
-		u.Warnf("urnary not implemented:   %#v", node)
+		logging.Warnf("urnary not implemented:   %#v", node)
 	}
 
 	return value.NewNilValue(), false
@@ -359,22 +523,27 @@ func walkUnary(ctx expr.EvalContext, node *expr.UnaryNode) (value.Value, bool) {
 // TriNode evaluator
 //
 //     A   BETWEEN   B  AND C
+//     A   LIKE      B  ESCAPE C
 //
 func walkTri(ctx expr.EvalContext, node *expr.TriNode) (value.Value, bool) {
 
 	a, aok := Eval(ctx, node.Args[0])
 	b, bok := Eval(ctx, node.Args[1])
 	c, cok := Eval(ctx, node.Args[2])
-	//u.Infof("tri:  %T:%v  %v  %T:%v   %T:%v", a, a, node.Operator, b, b, c, c)
+	//logging.Infof("tri:  %T:%v  %v  %T:%v   %T:%v", a, a, node.Operator, b, b, c, c)
 	if !aok || !bok || !cok {
-		u.Infof("Could not evaluate args, %#v", node.String())
+		logging.Infof("Could not evaluate args, %#v", node.String())
 		return value.BoolValueFalse, false
 	}
+	if isNullValue(a) || isNullValue(b) || isNullValue(c) {
+		// NULL BETWEEN, LIKE are unknown
+		return value.NewNilValue(), true
+	}
 	switch node.Operator.T {
 	case lex.TokenBetween:
 		switch a.Type() {
 		case value.IntType:
-			//u.Infof("found tri:  %v %v %v  expr=%v", a, b, c, node.StringAST())
+			//logging.Infof("found tri:  %v %v %v  expr=%v", a, b, c, node.StringAST())
 			if aiv, ok := a.(value.IntValue); ok {
 				if biv, ok := b.(value.IntValue); ok {
 					if civ, ok := c.(value.IntValue); ok {
@@ -388,7 +557,7 @@ func walkTri(ctx expr.EvalContext, node *expr.TriNode) (value.Value, bool) {
 			}
 			return value.BoolValueFalse, false
 		case value.NumberType:
-			//u.Infof("found tri:  %v %v %v  expr=%v", a, b, c, node.StringAST())
+			//logging.Infof("found tri:  %v %v %v  expr=%v", a, b, c, node.StringAST())
 			if afv, ok := a.(value.NumberValue); ok {
 				if bfv, ok := b.(value.NumberValue); ok {
 					if cfv, ok := c.(value.NumberValue); ok {
@@ -402,10 +571,28 @@ func walkTri(ctx expr.EvalContext, node *expr.TriNode) (value.Value, bool) {
 			}
 			return value.BoolValueFalse, false
 		default:
-			u.Warnf("tri node walk not implemented:   %#v", node)
+			logging.Warnf("tri node walk not implemented:   %#v", node)
 		}
+	case lex.TokenLike, lex.TokenILike:
+		as, aok := a.(value.StringValue)
+		bs, bok := b.(value.StringValue)
+		cs, cok := c.(value.StringValue)
+		if !aok || !bok || !cok {
+			return value.BoolValueFalse, false
+		}
+		escape := []rune(cs.Val())
+		if len(escape) != 1 {
+			logging.Warnf("ESCAPE must be single character: %q", cs.Val())
+			return value.BoolValueFalse, false
+		}
+		match, err := likeMatch(as.Val(), bs.Val(), escape[0], likeIgnoresCase(node.Operator.T, collation(ctx)))
+		if err != nil {
+			logging.Warnf("invalid LIKE pattern: %v", err)
+			return value.BoolValueFalse, false
+		}
+		return value.NewBoolValue(match), true
 	default:
-		u.Warnf("tri node walk not implemented:   %#v", node)
+		logging.Warnf("tri node walk not implemented:   %#v", node)
 	}
 
 	return value.NewNilValue(), false
@@ -418,35 +605,284 @@ func walkTri(ctx expr.EvalContext, node *expr.TriNode) (value.Value, bool) {
 func walkMulti(ctx expr.EvalContext, node *expr.MultiArgNode) (value.Value, bool) {
 
 	a, aok := Eval(ctx, node.Args[0])
-	//u.Infof("multi:  %T:%v  %v", a, a, node.Operator)
+	//logging.Infof("multi:  %T:%v  %v", a, a, node.Operator)
 	if !aok {
-		u.Infof("Could not evaluate args, %#v", node.Args[0])
+		logging.Infof("Could not evaluate args, %#v", node.Args[0])
 		return value.BoolValueFalse, false
 	}
 	switch node.Operator.T {
 	case lex.TokenIN:
+		if len(node.Args) == 2 {
+			if sel, isSub := node.Args[1].(*expr.SqlSelect); isSub {
+				return walkInSubQuery(ctx, a, sel)
+			}
+		}
+		if isNullValue(a) {
+			return value.NewNilValue(), true
+		}
+		// x IN (1, NULL) is unknown, not false, if x is not 1
+		sawNull := false
 		for i := 1; i < len(node.Args); i++ {
 			v, ok := Eval(ctx, node.Args[i])
-			if ok {
-				//u.Debugf("in? %v %v", a, v)
-				if eq, err := value.Equal(a, v); eq && err == nil {
+			if ok && isNullValue(v) {
+				sawNull = true
+			} else if ok {
+				//logging.Debugf("in? %v %v", a, v)
+				if eq, err := valuesEqual(a, v); eq && err == nil {
 					return value.NewBoolValue(true), true
 				}
 			} else {
-				u.Warnf("could not evaluate arg: %v", node.Args[i])
+				logging.Warnf("could not evaluate arg: %v", node.Args[i])
 			}
 		}
+		if sawNull {
+			return value.NewNilValue(), true
+		}
 		return value.NewBoolValue(false), true
 	default:
-		u.Warnf("tri node walk not implemented:   %#v", node)
+		logging.Warnf("tri node walk not implemented:   %#v", node)
 	}
 
 	return value.NewNilValue(), false
 }
 
+// Row Constructor evaluator, evaluates to slice of values
+//
+//     (a, b)
+//
+func walkRow(ctx expr.EvalContext, node *expr.RowConstructorNode) (value.Value, bool) {
+	vals := make([]value.Value, len(node.Args))
+	for i, arg := range node.Args {
+		v, ok := Eval(ctx, arg)
+		if !ok {
+			return value.NewNilValue(), false
+		}
+		vals[i] = v
+	}
+	return value.NewSliceValues(vals), true
+}
+
+// Subscript evaluation, the key of a map valued expression or index of an
+//  array valued one, a missing key or out of range index is NULL, a
+//  negative index is from the end, a non map/array is an error
+//
+//     tags['env']
+//     arr[0]
+//     arr[-1]    last element
+//
+func walkSubscript(ctx expr.EvalContext, node *expr.SubscriptNode) (value.Value, bool) {
+	a, ok := Eval(ctx, node.Arg)
+	if !ok {
+		return value.NewNilValue(), false
+	}
+	k, ok := Eval(ctx, node.Key)
+	if !ok {
+		return value.NewNilValue(), false
+	}
+	return operateSubscript(node, a, k)
+}
+
+// Apply the subscript to an already evaluated map/array and key/index
+func operateSubscript(node *expr.SubscriptNode, a, k value.Value) (value.Value, bool) {
+	switch at := a.(type) {
+	case value.SliceValue:
+		if iv, isInt := k.(value.IntValue); isInt {
+			if i, ok := subscriptIndex(iv.Val(), at.Len()); ok {
+				return at.Val()[i], true
+			}
+			return value.NewNilValue(), true
+		}
+	case value.StringsValue:
+		if iv, isInt := k.(value.IntValue); isInt {
+			if i, ok := subscriptIndex(iv.Val(), at.Len()); ok {
+				return value.NewStringValue(at.Val()[i]), true
+			}
+			return value.NewNilValue(), true
+		}
+	case value.MapValue:
+		if v, ok := at.Val()[k.ToString()]; ok && v != nil {
+			return v, true
+		}
+		return value.NewNilValue(), true
+	case value.MapIntValue:
+		if v, ok := at.Val()[k.ToString()]; ok {
+			return value.NewIntValue(v), true
+		}
+		return value.NewNilValue(), true
+	case value.NilValue:
+		return value.NewNilValue(), true
+	}
+	logging.Warnf("cannot subscript %v: %T[%v]", node.Arg, a, k)
+	return value.ErrValue, false
+}
+
+// the position of index @i into an array of length @n, negative is from
+//  the end, not ok if out of range
+func subscriptIndex(i int64, n int) (int, bool) {
+	if i < 0 {
+		i += int64(n)
+	}
+	if i < 0 || i >= int64(n) {
+		return 0, false
+	}
+	return int(i), true
+}
+
+// IN evaluation against sub-select, context must implement ContextSubQuery
+//
+//     x IN (SELECT y FROM z)
+//     (a, b) IN (SELECT x, y FROM z)
+//
+func walkInSubQuery(ctx expr.EvalContext, a value.Value, sel *expr.SqlSelect) (value.Value, bool) {
+	subCtx, ok := ctx.(expr.ContextSubQuery)
+	if !ok {
+		logging.Warnf("context does not support sub-query: %T", ctx)
+		return value.BoolValueFalse, false
+	}
+	rows, err := subCtx.SubQuery(sel)
+	if err != nil {
+		logging.Warnf("could not evaluate sub-query: %v", err)
+		return value.BoolValueFalse, false
+	}
+	// as for  x IN (1, NULL),  no match with a NULL is unknown, not false
+	_, isRow := a.(value.SliceValue)
+	sawNull := false
+	for _, row := range rows {
+		var v value.Value
+		if isRow {
+			v = value.NewSliceValues(row)
+		} else if len(row) == 1 {
+			v = row[0]
+		} else {
+			logging.Warnf("sub-query must return single column: %v", sel)
+			return value.BoolValueFalse, false
+		}
+		if !isRow && (isNullValue(a) || isNullValue(v)) {
+			sawNull = true
+		} else if eq, err := valuesEqual(a, v); eq && err == nil {
+			return value.NewBoolValue(true), true
+		}
+	}
+	if sawNull {
+		return value.NewNilValue(), true
+	}
+	return value.NewBoolValue(false), true
+}
+
+// scalar sub-select, context must implement ContextSubQuery, the value is
+//  that of the one column of the one row, NULL if no rows
+//
+//     count(*) > (SELECT avg(ct) FROM daily)
+//
+func walkSubQuery(ctx expr.EvalContext, node *expr.SubQueryNode) (value.Value, bool) {
+	subCtx, ok := ctx.(expr.ContextSubQuery)
+	if !ok {
+		logging.Warnf("context does not support sub-query: %T", ctx)
+		return value.NewNilValue(), false
+	}
+	rows, err := subCtx.SubQuery(node.Select)
+	if err != nil {
+		logging.Warnf("could not evaluate sub-query: %v", err)
+		return value.NewNilValue(), false
+	}
+	switch {
+	case len(rows) == 0:
+		return value.NewNilValue(), true
+	case len(rows) > 1:
+		logging.Warnf("scalar sub-query returned %d rows: %v", len(rows), node)
+		return value.NewNilValue(), false
+	case len(rows[0]) != 1:
+		logging.Warnf("sub-query must return single column: %v", node)
+		return value.NewNilValue(), false
+	}
+	if rows[0][0] == nil {
+		return value.NewNilValue(), true
+	}
+	return rows[0][0], true
+}
+
+// a placeholder has no value until bound, see expr.BindParams
+func walkParam(node *expr.ParamNode) (value.Value, bool) {
+	logging.Warnf("unbound placeholder at %d", node.Pos)
+	return value.NewNilValue(), false
+}
+
+// equality that also compares tuples (row constructors) element wise
+func valuesEqual(a, b value.Value) (bool, error) {
+	at, aIsRow := a.(value.SliceValue)
+	bt, bIsRow := b.(value.SliceValue)
+	switch {
+	case aIsRow && bIsRow:
+		if at.Len() != bt.Len() {
+			return false, nil
+		}
+		bvals := bt.Val()
+		for i, av := range at.Val() {
+			if eq, err := value.Equal(av, bvals[i]); !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case aIsRow || bIsRow:
+		return false, nil
+	}
+	return value.Equal(a, b)
+}
+
+// coalesce funcs (ifnull, nvl) return the first arg which is not NULL,
+//  later args are not evaluated
+func walkCoalesce(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
+	for _, a := range node.Args {
+		if v, ok := Eval(ctx, a); !isNull(v, ok) {
+			return v, true
+		}
+	}
+	return value.NewNilValue(), true
+}
+
+// full text search without an index, true if any word of the AGAINST term
+//  is a word of any of the columns, see expr.MatchTerms
+//
+//     MATCH(title, body) AGAINST("quick fox")
+func walkMatch(ctx expr.EvalContext, node *expr.MatchNode) (value.Value, bool) {
+	terms := expr.MatchTerms(node.Against)
+	for _, col := range node.Cols {
+		v, ok := ctx.Get(col.Text)
+		if isNull(v, ok) {
+			continue
+		}
+		for _, word := range expr.MatchTerms(v.ToString()) {
+			for _, term := range terms {
+				if word == term {
+					return value.BoolValueTrue, true
+				}
+			}
+		}
+	}
+	return value.BoolValueFalse, true
+}
+
+// missing, or NULL values
+func isNull(v value.Value, ok bool) bool {
+	return !ok || v == nil || v.Type() == value.NilType
+}
+
 func walkFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
+	return memoFunc(ctx, node, func(ctx expr.EvalContext) (value.Value, bool) {
+		return callFunc(ctx, node)
+	})
+}
+
+func callFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
 
-	//u.Debugf("walk node --- %v   ", node.StringAST())
+	//logging.Debugf("walk node --- %v   ", node.StringAST())
+	if node.F.Coalesce {
+		return walkCoalesce(ctx, node)
+	}
+	if node.F.Table {
+		// table funcs are only valid in FROM, see exec
+		return nil, false
+	}
 
 	// we create a set of arguments to pass to the function, first arg
 	// is this Context
@@ -454,7 +890,7 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
 	funcArgs := []reflect.Value{reflect.ValueOf(ctx)}
 	for _, a := range node.Args {
 
-		//u.Debugf("arg %v  %T %v", a, a, a)
+		//logging.Debugf("arg %v  %T %v", a, a, a)
 
 		var v interface{}
 
@@ -467,7 +903,7 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
 				v = value.NewBoolValue(t.Bool())
 			} else {
 				v, ok = ctx.Get(t.Text)
-				//u.Debugf("get? %T %v %v", v, v, ok)
+				//logging.Debugf("get? %T %v %v", v, v, ok)
 				if !ok {
 					// nil arguments are valid
 					v = value.NewNilValue()
@@ -475,16 +911,18 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
 			}
 
 		case *expr.NumberNode:
-			v = numberNodeToValue(t)
+			v = t.Value()
+		case *expr.IntervalNode:
+			v = t.Value()
 		case *expr.FuncNode:
-			//u.Debugf("descending to %v()", t.Name)
+			//logging.Debugf("descending to %v()", t.Name)
 			v, ok = walkFunc(ctx, t)
 			if !ok {
 				//return value.NewNilValue(), false
 				// nil arguments are valid
 				v = value.NewNilValue()
 			}
-			//u.Debugf("result of %v() = %v, %T", t.Name, v, v)
+			//logging.Debugf("result of %v() = %v, %T", t.Name, v, v)
 			//v = extractScalar()
 		case *expr.UnaryNode:
 			//v = extractScalar(e.walkUnary(t))
@@ -497,42 +935,62 @@ func walkFunc(ctx expr.EvalContext, node *expr.FuncNode) (value.Value, bool) {
 		case *expr.BinaryNode:
 			//v = extractScalar(e.walkBinary(t))
 			v = walkBinary(ctx, t)
+		case *expr.SubscriptNode:
+			v, ok = walkSubscript(ctx, t)
+			if !ok {
+				// nil arguments are valid
+				v = value.NewNilValue()
+			}
 		default:
-			panic(fmt.Errorf("expr: unknown func arg type"))
+			v, ok = Eval(ctx, a)
+			if !ok {
+				// nil arguments are valid
+				v = value.NewNilValue()
+			}
 		}
 
 		if v == nil {
-			//u.Warnf("Nil vals?  %v  %T  arg:%T", v, v, a)
+			//logging.Warnf("Nil vals?  %v  %T  arg:%T", v, v, a)
 			// What do we do with Nil Values?
 			switch a.(type) {
 			case *expr.StringNode: // String Literal
-				u.Warnf("NOT IMPLEMENTED T:%T v:%v", a, a)
+				logging.Warnf("NOT IMPLEMENTED T:%T v:%v", a, a)
 			case *expr.IdentityNode: // Identity node = lookup in context
 				v = value.NewStringValue("")
 			default:
-				u.Warnf("unknown type:  %v  %T", v, v)
+				logging.Warnf("unknown type:  %v  %T", v, v)
+				v = value.NewNilValue()
 			}
 
 			funcArgs = append(funcArgs, reflect.ValueOf(v))
 		} else {
-			//u.Debugf(`found func arg:  "%v"  %T  arg:%T`, v, v, a)
+			//logging.Debugf(`found func arg:  "%v"  %T  arg:%T`, v, v, a)
 			funcArgs = append(funcArgs, reflect.ValueOf(v))
 		}
 
 	}
 	// Get the result of calling our Function (Value,bool)
-	//u.Debugf("Calling func:%v(%v) %v", node.F.Name, funcArgs, node.F.F)
+	//logging.Debugf("Calling func:%v(%v) %v", node.F.Name, funcArgs, node.F.F)
 	fnRet := node.F.F.Call(funcArgs)
-	//u.Debugf("fnRet: %v    ok?%v", fnRet, fnRet[1].Bool())
+	//logging.Debugf("fnRet: %v    ok?%v", fnRet, fnRet[1].Bool())
 	// check if has an error response?
 	if len(fnRet) > 1 && !fnRet[1].Bool() {
 		// What do we do if not ok?
 		return value.EmptyStringValue, false
 	}
-	//u.Debugf("response %v %v  %T", node.F.Name, fnRet[0].Interface(), fnRet[0].Interface())
+	//logging.Debugf("response %v %v  %T", node.F.Name, fnRet[0].Interface(), fnRet[0].Interface())
 	return fnRet[0].Interface().(value.Value), true
 }
 
+// Apply the operator to numeric values promoted by value.PromoteNumeric,
+// int op int stays int math, otherwise float64 math
+func operateNumeric(ctx expr.EvalContext, op lex.Token, av, bv value.Value) value.Value {
+	if ai, ok := av.(value.IntValue); ok {
+		return operateInts(ctx, op, ai, bv.(value.IntValue))
+	}
+	return operateNumbers(op, av.(value.NumberValue), bv.(value.NumberValue))
+}
+
 func operateNumbers(op lex.Token, av, bv value.NumberValue) value.Value {
 	switch op.T {
 	case lex.TokenPlus, lex.TokenStar, lex.TokenMultiply, lex.TokenDivide, lex.TokenMinus,
@@ -559,7 +1017,7 @@ func operateNumbers(op lex.Token, av, bv value.NumberValue) value.Value {
 
 	// Below here are Boolean Returns
 	case lex.TokenEqualEqual, lex.TokenEqual: //  ==
-		//u.Infof("==?  %v  %v", av, bv)
+		//logging.Infof("==?  %v  %v", av, bv)
 		if a == b {
 			return value.BoolValueTrue
 		} else {
@@ -610,60 +1068,143 @@ func operateNumbers(op lex.Token, av, bv value.NumberValue) value.Value {
 			return value.BoolValueFalse
 		}
 	}
-	panic(fmt.Errorf("expr: unknown operator %s", op))
+	return errValue(fmt.Errorf("expr: unknown operator %s", op))
 }
 
-func operateStrings(op lex.Token, av, bv value.StringValue) value.Value {
+func operateStrings(op lex.Token, av, bv value.StringValue, coll value.Collation) value.Value {
 
-	//  Any other ops besides eq/not ?
 	a, b := av.Val(), bv.Val()
 	switch op.T {
 	// Below here are Boolean Returns
 	case lex.TokenEqualEqual, lex.TokenEqual: //  ==
-		//u.Infof("==?  %v  %v", av, bv)
-		if a == b {
-			return value.BoolValueTrue
-		} else {
-			return value.BoolValueFalse
-		}
+		return value.NewBoolValue(coll.Compare(a, b) == 0)
 	case lex.TokenNE: //  !=
-		//u.Infof("==?  %v  %v", av, bv)
-		if a == b {
-			return value.BoolValueFalse
-		} else {
-			return value.BoolValueTrue
+		return value.NewBoolValue(coll.Compare(a, b) != 0)
+	case lex.TokenGT: //  >
+		return value.NewBoolValue(coll.Compare(a, b) > 0)
+	case lex.TokenGE: //  >=
+		return value.NewBoolValue(coll.Compare(a, b) >= 0)
+	case lex.TokenLT: //  <
+		return value.NewBoolValue(coll.Compare(a, b) < 0)
+	case lex.TokenLE: //  <=
+		return value.NewBoolValue(coll.Compare(a, b) <= 0)
+	case lex.TokenLike, lex.TokenILike: // a LIKE "pattern%"
+		match, err := likeMatch(a, b, LikeEscapeDefault, likeIgnoresCase(op.T, coll))
+		if err != nil {
+			return value.ErrValue
 		}
+		return value.NewBoolValue(match)
 	}
 	return value.ErrValue
 }
 
-func operateInts(op lex.Token, av, bv value.IntValue) value.Value {
+// The string collation for this context, binary unless the context
+//  implements expr.ContextCollation
+func collation(ctx expr.EvalContext) value.Collation {
+	if cctx, ok := ctx.(expr.ContextCollation); ok {
+		if coll := cctx.Collation(); coll != nil {
+			return coll
+		}
+	}
+	return value.CollationBinary
+}
+
+func operateTimes(op lex.Token, av, bv value.TimeValue) value.Value {
+	a, b := av.Val(), bv.Val()
+	switch op.T {
+	case lex.TokenMinus:
+		return value.NewDurationValue(a.Sub(b))
+	case lex.TokenEqualEqual, lex.TokenEqual:
+		return value.NewBoolValue(a.Equal(b))
+	case lex.TokenNE:
+		return value.NewBoolValue(!a.Equal(b))
+	case lex.TokenGT:
+		return value.NewBoolValue(a.After(b))
+	case lex.TokenGE:
+		return value.NewBoolValue(!a.Before(b))
+	case lex.TokenLT:
+		return value.NewBoolValue(a.Before(b))
+	case lex.TokenLE:
+		return value.NewBoolValue(!a.After(b))
+	}
+	return errValue(fmt.Errorf("expr: unknown operator %s", op))
+}
+
+// time +/- duration
+func operateTimeDuration(op lex.Token, av value.TimeValue, bv value.DurationValue) value.Value {
+	switch op.T {
+	case lex.TokenPlus:
+		return value.NewTimeValue(av.Val().Add(bv.Val()))
+	case lex.TokenMinus:
+		return value.NewTimeValue(av.Val().Add(-bv.Val()))
+	}
+	return errValue(fmt.Errorf("expr: unknown operator %s", op))
+}
+
+func operateDurations(op lex.Token, av, bv value.DurationValue) value.Value {
+	a, b := av.Val(), bv.Val()
+	switch op.T {
+	case lex.TokenPlus:
+		return value.NewDurationValue(a + b)
+	case lex.TokenMinus:
+		return value.NewDurationValue(a - b)
+	case lex.TokenEqualEqual, lex.TokenEqual:
+		return value.NewBoolValue(a == b)
+	case lex.TokenNE:
+		return value.NewBoolValue(a != b)
+	case lex.TokenGT:
+		return value.NewBoolValue(a > b)
+	case lex.TokenGE:
+		return value.NewBoolValue(a >= b)
+	case lex.TokenLT:
+		return value.NewBoolValue(a < b)
+	case lex.TokenLE:
+		return value.NewBoolValue(a <= b)
+	}
+	return errValue(fmt.Errorf("expr: unknown operator %s", op))
+}
+
+func operateInts(ctx expr.EvalContext, op lex.Token, av, bv value.IntValue) value.Value {
 	//if math.IsNaN(a) || math.IsNaN(b) {
 	//	return math.NaN()
 	//}
 	a, b := av.Val(), bv.Val()
-	//u.Infof("a op b:   %v %v %v", a, op.V, b)
+	//logging.Infof("a op b:   %v %v %v", a, op.V, b)
 	switch op.T {
 	case lex.TokenPlus: // +
-		//r = a + b
-		return value.NewIntValue(a + b)
+		r := a + b
+		if (a > 0 && b > 0 && r < 0) || (a < 0 && b < 0 && r >= 0) {
+			return intOverflow(ctx, op, av, bv)
+		}
+		return value.NewIntValue(r)
 	case lex.TokenStar, lex.TokenMultiply: // *
-		//r = a * b
-		return value.NewIntValue(a * b)
+		if a == 0 || b == 0 {
+			return value.NewIntValue(0)
+		}
+		r := a * b
+		if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return intOverflow(ctx, op, av, bv)
+		}
+		return value.NewIntValue(r)
 	case lex.TokenMinus: // -
-		//r = a - b
-		return value.NewIntValue(a - b)
+		r := a - b
+		if (a >= 0 && b < 0 && r < 0) || (a < 0 && b > 0 && r >= 0) {
+			return intOverflow(ctx, op, av, bv)
+		}
+		return value.NewIntValue(r)
 	case lex.TokenDivide: //    /
-		//r = a / b
-		//u.Debugf("divide:   %v / %v = %v", a, b, a/b)
+		//logging.Debugf("divide:   %v / %v = %v", a, b, a/b)
+		if a == math.MinInt64 && b == -1 {
+			return intOverflow(ctx, op, av, bv)
+		}
 		return value.NewIntValue(a / b)
 	case lex.TokenModulus: //    %
 		//r = a / b
-		//u.Debugf("modulus:   %v / %v = %v", a, b, a/b)
+		//logging.Debugf("modulus:   %v / %v = %v", a, b, a/b)
 		return value.NewIntValue(a % b)
 
 	// Below here are Boolean Returns
-	case lex.TokenEqualEqual: //  ==
+	case lex.TokenEqualEqual, lex.TokenEqual: //  ==
 		if a == b {
 			return value.BoolValueTrue
 		} else {
@@ -712,7 +1253,58 @@ func operateInts(op lex.Token, av, bv value.IntValue) value.Value {
 			return value.BoolValueFalse
 		}
 	}
-	panic(fmt.Errorf("expr: unknown operator %s", op))
+	return errValue(fmt.Errorf("expr: unknown operator %s", op))
+}
+
+// is @bv a zero divisor for the given / or % operation
+func isZeroDivisor(op lex.Token, bv value.Value) bool {
+	switch bt := bv.(type) {
+	case value.IntValue:
+		return bt.Val() == 0
+	case value.NumberValue:
+		if op.T == lex.TokenModulus {
+			// float modulus is performed on int64 truncated values
+			return int64(bt.Val()) == 0
+		}
+		return bt.Val() == 0
+	}
+	return false
+}
+
+// evaluate  @av / 0  according to the DivideByZeroPolicy of the context
+func divideByZero(ctx expr.EvalContext, op lex.Token, av value.Value) value.Value {
+	policy := expr.DivideByZeroNull
+	if dctx, ok := ctx.(expr.ContextDivideByZero); ok {
+		policy = dctx.DivideByZero()
+	}
+	switch policy {
+	case expr.DivideByZeroError:
+		return value.NewErrorValue(fmt.Sprintf("division by zero: %v %s 0", av.Value(), op.V))
+	case expr.DivideByZeroInf:
+		an, ok := av.(value.NumericValue)
+		if !ok || op.T == lex.TokenModulus || an.Float() == 0 || math.IsNaN(an.Float()) {
+			return value.NewNumberValue(math.NaN())
+		}
+		if an.Float() < 0 {
+			return value.NewNumberValue(math.Inf(-1))
+		}
+		return value.NewNumberValue(math.Inf(1))
+	}
+	return value.NewNilValue()
+}
+
+// handle int64 overflow of @op according to the IntOverflowPolicy of the
+// context
+func intOverflow(ctx expr.EvalContext, op lex.Token, av, bv value.IntValue) value.Value {
+	policy := expr.IntOverflowPromote
+	if octx, ok := ctx.(expr.ContextIntOverflow); ok {
+		policy = octx.IntOverflow()
+	}
+	switch policy {
+	case expr.IntOverflowError:
+		return value.NewErrorValue(fmt.Sprintf("integer overflow: %d %s %d", av.Val(), op.V, bv.Val()))
+	}
+	return operateNumbers(op, av.NumberValue(), bv.NumberValue())
 }
 
 func uoperate(op string, a float64) (r float64) {
@@ -2,13 +2,18 @@ package vm
 
 import (
 	"flag"
+	"math"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/araddon/dateparse"
 	u "github.com/araddon/gou"
 	"github.com/araddon/qlbridge/datasource"
 	"github.com/araddon/qlbridge/expr"
+	"github.com/araddon/qlbridge/lex"
+	"github.com/araddon/qlbridge/logging"
 	"github.com/araddon/qlbridge/value"
 	"github.com/bmizerany/assert"
 )
@@ -29,6 +34,7 @@ func init() {
 	if *VerboseTests {
 		u.SetupLogging("debug")
 		u.SetColorOutput()
+		logging.SetLogger(logging.GouLogger{})
 	}
 
 	expr.FuncAdd("eq", Eq)
@@ -42,11 +48,18 @@ var (
 	//  and be available to the VM runtime for evaluation by using
 	//  key's such as "int5" or "user_id"
 	msgContext = datasource.NewContextSimpleData(map[string]value.Value{
-		"int5":    value.NewIntValue(5),
-		"str5":    value.NewStringValue("5"),
-		"bvalt":   value.NewBoolValue(true),
-		"bvalf":   value.NewBoolValue(false),
-		"user_id": value.NewStringValue("abc"),
+		"int5":     value.NewIntValue(5),
+		"str5":     value.NewStringValue("5"),
+		"flt55":    value.NewNumberValue(5.5),
+		"str55":    value.NewStringValue("5.5"),
+		"bvalt":    value.NewBoolValue(true),
+		"bvalf":    value.NewBoolValue(false),
+		"user_id":  value.NewStringValue("abc"),
+		"pct":      value.NewStringValue("50%"),
+		"pct2":     value.NewStringValue("500"),
+		"under":    value.NewStringValue("a_c"),
+		"created":  value.NewTimeValue(dateparse.MustParse("2015-06-15")),
+		"created2": value.NewTimeValue(dateparse.MustParse("2015-06-14")),
 	})
 
 	// list of tests
@@ -61,18 +74,40 @@ var (
 		vmtall("multi-arg:   In (x,y,z) ", `10 IN ("a","b",20, 4.5)`, false, parseOk, evalError),
 		vmtall("multi-arg:   In (x,y,z) ", `"a" IN ("a","b",10, 4.5)`, true, parseOk, evalError),
 
+		// Row Constructor:  tuples in multi-arg
+		vmt("tuple in", `(int5, user_id) IN ((1, "x"), (5, "abc"))`, true, noError),
+		vmt("tuple not in", `(int5, user_id) IN ((1, "x"), (5, "abcd"))`, false, noError),
+		vmt("tuple in arity mismatch", `(int5, user_id) IN ((5, "abc", 1), 5)`, false, noError),
+
 		// Binary String
 		vmt("binary string ==", `user_id == "abc"`, true, noError),
 		vmt("binary string ==", `user_id != "abcd"`, true, noError),
 		vmt("binary string ==", `user_id == "abcd"`, false, noError),
 		vmt("binary string ==", `user_id != "abc"`, false, noError),
-		vmtall("binary math err on string +", `user_id > "abc"`, nil, parseOk, evalError),
+		vmt("binary string >", `user_id > "abc"`, false, noError),
+		vmt("binary string >=", `user_id >= "abc"`, true, noError),
+		vmt("binary string <", `user_id < "abd"`, true, noError),
+
+		// Like
+		vmt("like wildcard", `user_id LIKE "a%"`, true, noError),
+		vmt("like single char", `user_id LIKE "a_c"`, true, noError),
+		vmt("like no match", `user_id LIKE "b%"`, false, noError),
+		vmt("like escaped %", `pct LIKE "50\%"`, true, noError),
+		vmt("like escaped % literal", `pct2 LIKE "50\%"`, false, noError),
+		vmt("like escaped _", `under LIKE "a\_c"`, true, noError),
+		vmt("like escaped _ literal", `user_id LIKE "a\_c"`, false, noError),
+		vmt("like custom escape", `pct LIKE "50!%" ESCAPE "!"`, true, noError),
+		vmt("like custom escape literal", `pct2 LIKE "50!%" ESCAPE "!"`, false, noError),
+		vmt("like custom escape wildcard", `pct2 LIKE "50%" ESCAPE "!"`, true, noError),
+		vmt("like custom escape _", `under LIKE 'a!_%' ESCAPE '!'`, true, noError),
 
 		// Binary Bool
 		vmt("binary bool ==", `bvalt == true`, true, noError),
 		vmt("binary bool =", `bvalt = true`, true, noError),
 		vmt("binary bool ==", `bvalf == false`, true, noError),
 		vmt("binary bool =", `bvalf = false`, true, noError),
+		vmt("binary int =", `int5 = 5`, true, noError),
+		vmt("binary int =", `int5 = 4`, false, noError),
 		vmt("binary bool ==", `bvalt == bvalf`, false, noError),
 		vmt("binary bool !=", `bvalt != bvalf`, true, noError),
 		vmtall("binary error on bool == string", `user_id == true`, nil, parseOk, evalError),
@@ -103,9 +138,43 @@ var (
 		vmt("ctx cast str, multiply", `toint(str5 * 6)`, int64(30), noError),
 		vmt("ctx cast str, addition", `toint(str5 + 6)`, int64(11), noError),
 
+		// Numeric promotion:  int op int stays int, else float
+		vmt("promote int + int", `int5 + 2`, int64(7), noError),
+		vmt("promote int / int", `int5 / 2`, int64(2), noError),
+		vmt("promote int + float", `int5 + flt55`, float64(10.5), noError),
+		vmt("promote float + int", `flt55 * int5`, float64(27.5), noError),
+		vmt("promote float + float", `flt55 + flt55`, float64(11), noError),
+		vmt("promote int str + int", `str5 + int5`, int64(10), noError),
+		vmt("promote int + int str", `int5 - str5`, int64(0), noError),
+		vmt("promote float str + int", `str55 + int5`, float64(10.5), noError),
+		vmt("promote float + int str", `flt55 + str5`, float64(10.5), noError),
+
 		// context lookups? simple
 		vmt("ctx lookup ", `user_id`, "abc", noError),
 
+		// string literals coerced to the type of the other side
+		vmt("coerce str literal int", `int5 > "3"`, true, noError),
+		vmt("coerce str literal int eq", `int5 == "5"`, true, noError),
+		vmt("coerce str literal left", `"30" > int5`, true, noError),
+		vmt("coerce str literal float", `int5 > "5.5"`, false, noError),
+		vmt("coerce str literal bool", `bvalt == "true"`, true, noError),
+		vmt("coerce str literal date", `created > "2014-01-01"`, true, noError),
+		vmt("coerce str literal date lt", `created < "2014-01-01"`, false, noError),
+
+		// time, interval arithmetic
+		vmt("time + interval", `created + interval "1 day"`, dateparse.MustParse("2015-06-16"), noError),
+		vmt("time - interval", `created - INTERVAL "2 hours"`, dateparse.MustParse("2015-06-14 22:00:00"), noError),
+		vmt("interval + time", `interval "1 day" + created2`, dateparse.MustParse("2015-06-15"), noError),
+		vmt("time - time", `created - created2`, 24*time.Hour, noError),
+		vmt("time - interval compare", `created - interval "1 day" >= created2`, true, noError),
+		vmt("time - interval compare gt", `created - interval "1 day" > created2`, false, noError),
+		vmt("time diff vs interval", `created - created2 > interval "23h"`, true, noError),
+		vmt("time + interval unit", `created + interval 2 HOUR`, dateparse.MustParse("2015-06-15 02:00:00"), noError),
+		vmt("time - interval str unit", `created - interval "1" DAY`, dateparse.MustParse("2015-06-14"), noError),
+		vmt("time + calendar month", `created + interval 1 MONTH`, dateparse.MustParse("2015-07-15"), noError),
+		vmt("time - calendar year", `created - interval "1" YEAR`, dateparse.MustParse("2014-06-15"), noError),
+		vmt("calendar month + time", `interval 1 month + created`, dateparse.MustParse("2015-07-15"), noError),
+
 		// functional syntax
 		vmt("eq/toint types", `eq(toint(int5),5)`, true, noError),
 		vmt("eq/toint types", `eq(toint(int5),6)`, false, noError),
@@ -166,6 +235,552 @@ func TestRunExpr(t *testing.T) {
 	}
 }
 
+func TestIntOverflow(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"maxint": value.NewIntValue(math.MaxInt64),
+		"minint": value.NewIntValue(math.MinInt64),
+	})
+	evalInt := func(ql string) (value.Value, error) {
+		exprVm, err := NewVm(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		writeContext := datasource.NewContextSimple()
+		err = exprVm.Execute(writeContext, ctx)
+		v, _ := writeContext.Get("")
+		return v, err
+	}
+
+	// no overflow near the boundary is still int
+	v, err := evalInt(`maxint - 1 + 1`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	assert.Tf(t, v.Value() == int64(math.MaxInt64), "should be maxint: %v", v)
+	v, err = evalInt(`minint + 1`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	assert.Tf(t, v.Value() == int64(math.MinInt64+1), "should be minint+1: %v", v)
+
+	for _, ql := range []string{`maxint + 1`, `maxint * 2`, `minint * 2`, `minint - 1`} {
+		v, err = evalInt(ql)
+		assert.Tf(t, err == nil, "%v no error: %v", ql, err)
+		_, isFloat := v.(value.NumberValue)
+		assert.Tf(t, isFloat, "%v should promote to float: %T %v", ql, v, v)
+	}
+	v, _ = evalInt(`maxint + 1`)
+	assert.Tf(t, v.Value() == float64(math.MaxInt64)+1, "promoted value: %v", v)
+	v, _ = evalInt(`maxint * 2`)
+	assert.Tf(t, v.Value() == float64(math.MaxInt64)*2, "promoted value: %v", v)
+
+	// the policy is of the context, and of the context a MemoContext wraps
+	ctx.Overflow = expr.IntOverflowError
+	for _, ql := range []string{`maxint + 1`, `maxint * 2`, `minint * 2`, `minint - 1`} {
+		v, err = evalInt(ql)
+		assert.Tf(t, err != nil, "%v should error", ql)
+		assert.Tf(t, v == nil, "%v should not write result: %v", ql, v)
+	}
+	v, err = evalInt(`maxint * 1`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	assert.Tf(t, v.Value() == int64(math.MaxInt64), "should be maxint: %v", v)
+	n, err := expr.ParseExpression(`maxint + 1`)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	v, _ = Eval(NewMemoContext(ctx), n.Root)
+	_, isErr := v.(value.ErrorValue)
+	assert.Tf(t, isErr, "memo context forwards the policy: %T %v", v, v)
+	compiled, err := Compile(n.Root)
+	assert.Tf(t, err == nil, "no error: %v", err)
+	v, _ = compiled(ctx)
+	_, isErr = v.(value.ErrorValue)
+	assert.Tf(t, isErr, "compiled: %T %v", v, v)
+	v, _ = compiled(datasource.NewContextSimpleData(ctx.Data))
+	_, isFloat := v.(value.NumberValue)
+	assert.Tf(t, isFloat, "another context promotes: %T %v", v, v)
+}
+
+func TestCollation(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"name": value.NewStringValue("a"),
+	})
+	eval := func(ql string) bool {
+		exprVm, err := NewVm(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		writeContext := datasource.NewContextSimple()
+		err = exprVm.Execute(writeContext, ctx)
+		assert.Tf(t, err == nil, "%v no error: %v", ql, err)
+		v, _ := writeContext.Get("")
+		bv, ok := v.(value.BoolValue)
+		assert.Tf(t, ok, "%v should be bool: %T %v", ql, v, v)
+		return bv.Val()
+	}
+
+	// binary (default) "B" (0x42) sorts before "a" (0x61)
+	assert.T(t, eval(`"a" < "B"`) == false)
+	assert.T(t, eval(`name > "B"`) == true)
+	assert.T(t, eval(`name = "A"`) == false)
+	assert.T(t, eval(`name LIKE "A%"`) == false)
+	assert.T(t, eval(`name ILIKE "A%"`) == true)
+	assert.T(t, eval(`name ILIKE "A!%" ESCAPE "!"`) == false)
+
+	ctx.Collate = value.CollationCaseInsensitive
+	assert.T(t, eval(`"a" < "B"`) == true)
+	assert.T(t, eval(`name > "B"`) == false)
+	assert.T(t, eval(`name = "A"`) == true)
+	assert.T(t, eval(`name != "A"`) == false)
+	assert.T(t, eval(`name LIKE "A%"`) == true)
+	assert.T(t, eval(`name LIKE "A%" ESCAPE "!"`) == true)
+	assert.T(t, eval(`name ILIKE "A%"`) == true)
+
+	coll, err := value.NewCollationLocale("en", false)
+	assert.Tf(t, err == nil, "no error %v", err)
+	ctx.Collate = coll
+	assert.T(t, eval(`"a" < "B"`) == true)
+	assert.T(t, eval(`name = "A"`) == false)
+}
+
+func TestSubscript(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"tags": value.NewMapValue(map[string]value.Value{
+			"env": value.NewStringValue("prod"),
+		}),
+		"counts": value.NewMapIntValue(map[string]int64{"a": 3}),
+		"name":   value.NewStringValue("bob"),
+		"arr": value.NewSliceValues([]value.Value{
+			value.NewStringValue("a"), value.NewIntValue(2), value.NewStringValue("c"),
+		}),
+		"strs": value.NewStringsValue([]string{"x", "y"}),
+	})
+	eval := func(ql string) (value.Value, bool) {
+		tree, err := expr.ParseExpression(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		v, ok := Eval(ctx, tree.Root)
+		compiled, err := Compile(tree.Root)
+		assert.Tf(t, err == nil, "compile %v: %v", ql, err)
+		cv, cok := compiled(ctx)
+		assert.Tf(t, ok == cok && reflect.DeepEqual(v, cv), "compiled %v: %v != %v", ql, cv, v)
+		return v, ok
+	}
+
+	// present key
+	v, ok := eval(`tags['env']`)
+	assert.Tf(t, ok && v.ToString() == "prod", "tags['env']: %v", v)
+	v, ok = eval(`tags["env"] == "prod"`)
+	assert.Tf(t, ok && v.Value() == true, "tags[env] == prod: %v", v)
+	v, ok = eval(`counts["a"] + 1`)
+	assert.Tf(t, ok && v.Value() == int64(4), "counts[a] + 1: %v", v)
+
+	// missing key is NULL
+	v, ok = eval(`tags['nope']`)
+	assert.Tf(t, ok && v.Nil() && v.Type() == value.NilType, "missing key is nil: %#v", v)
+	v, ok = eval(`counts["b"]`)
+	assert.Tf(t, ok && v.Type() == value.NilType, "missing key is nil: %#v", v)
+
+	// non-map column
+	v, ok = eval(`name['env']`)
+	assert.Tf(t, !ok && v.Err(), "non-map subscript is error: %#v", v)
+
+	// array index in range
+	v, ok = eval(`arr[0]`)
+	assert.Tf(t, ok && v.ToString() == "a", "arr[0]: %v", v)
+	v, ok = eval(`arr[1] * 2`)
+	assert.Tf(t, ok && v.Value() == int64(4), "arr[1] * 2: %v", v)
+	v, ok = eval(`strs[1]`)
+	assert.Tf(t, ok && v.ToString() == "y", "strs[1]: %v", v)
+
+	// out of range is NULL
+	v, ok = eval(`arr[3]`)
+	assert.Tf(t, ok && v.Type() == value.NilType, "out of range is nil: %#v", v)
+	v, ok = eval(`arr[-4]`)
+	assert.Tf(t, ok && v.Type() == value.NilType, "out of range is nil: %#v", v)
+
+	// negative index is from the end
+	v, ok = eval(`arr[-1]`)
+	assert.Tf(t, ok && v.ToString() == "c", "arr[-1]: %v", v)
+	v, ok = eval(`strs[-2]`)
+	assert.Tf(t, ok && v.ToString() == "x", "strs[-2]: %v", v)
+
+	// as a function argument
+	v, ok = eval(`eq(arr[0], "a")`)
+	assert.Tf(t, ok && v.Value() == true, "eq(arr[0]): %v", v)
+	v, ok = eval(`toint(arr[1])`)
+	assert.Tf(t, ok && v.Value() == int64(2), "toint(arr[1]): %v", v)
+	v, ok = eval(`eq(tags["env"], "prod")`)
+	assert.Tf(t, ok && v.Value() == true, "eq(tags[env]): %v", v)
+	v, ok = eval(`eq(arr[9], "a")`)
+	assert.Tf(t, ok && v.Value() == false, "eq(arr[9]): %v", v)
+
+	// non-array column, or key into array
+	v, ok = eval(`name[0]`)
+	assert.Tf(t, !ok && v.Err(), "non-array index is error: %#v", v)
+	v, ok = eval(`arr["a"]`)
+	assert.Tf(t, !ok && v.Err(), "key of array is error: %#v", v)
+}
+
+func TestNullOperands(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"int": value.NewIntValue(1),
+		"str": value.NewStringValue("a"),
+		"nul": value.NewNilValue(),
+	})
+	eval := func(ql string) (v value.Value, ok bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("panic evaluating %v: %v", ql, r)
+			}
+		}()
+		tree, err := expr.ParseExpression(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		v, ok = Eval(ctx, tree.Root)
+		compiled, err := Compile(tree.Root)
+		assert.Tf(t, err == nil, "compile %v: %v", ql, err)
+		cv, cok := compiled(ctx)
+		assert.Tf(t, ok == cok && reflect.DeepEqual(v, cv), "compiled %v: %v != %v", ql, cv, v)
+		return v, ok
+	}
+	isNull := func(v value.Value) bool { return v != nil && v.Type() == value.NilType }
+
+	// NULL in every position of every operator is NULL (unknown)
+	nulls := []string{"NULL", "nul"}
+	others := []string{"int", "str", "2", `"b"`}
+	for _, op := range []string{"=", "==", "!=", "<", "<=", ">", ">=", "+", "-", "*", "/", "%"} {
+		for _, n := range nulls {
+			for _, o := range others {
+				for _, ql := range []string{n + " " + op + " " + o, o + " " + op + " " + n} {
+					v, ok := eval(ql)
+					assert.Tf(t, ok && isNull(v), "%v should be NULL: %#v", ql, v)
+				}
+			}
+		}
+	}
+	for _, n := range nulls {
+		for _, ql := range []string{
+			"NOT " + n,
+			"-" + n,
+			n + " BETWEEN 1 AND 3",
+			"int BETWEEN " + n + " AND 3",
+			"int BETWEEN 1 AND " + n,
+			n + ` LIKE "a%"`,
+			n + " IN (1, 2)",
+			"int IN (2, " + n + ")",
+			n + " AND true",
+			"true AND " + n,
+			n + " OR false",
+			"false OR " + n,
+			n + " = NULL",
+			"NULL != " + n,
+		} {
+			v, ok := eval(ql)
+			assert.Tf(t, ok && isNull(v), "%v should be NULL: %#v", ql, v)
+		}
+	}
+
+	// three valued logic, and a match in IN despite a NULL
+	for ql, want := range map[string]bool{
+		"nul AND false":       false,
+		"false AND nul":       false,
+		"nul OR true":         true,
+		"true OR nul":         true,
+		"int IN (nul, 1)":     true,
+		"nul IS NULL":         true,
+		"nul IS NOT NULL":     false,
+		"int IS NULL":         false,
+		"int IS NOT NULL":     true,
+		"missing IS NULL":     true,
+		"missing IS NOT NULL": false,
+		"NULL IS NULL":        true,
+		"(int = nul) IS NULL": true,
+	} {
+		v, ok := eval(ql)
+		assert.Tf(t, ok && v.Value() == want, "%v should be %v: %#v", ql, want, v)
+	}
+
+	// IN a sub-query is unknown the same way as IN a list
+	subCtx := &subQueryContext{ContextSimple: ctx}
+	evalIn := func(ql string, rows ...value.Value) value.Value {
+		subCtx.rows = make([][]value.Value, len(rows))
+		for i, v := range rows {
+			subCtx.rows[i] = []value.Value{v}
+		}
+		stmt, err := expr.ParseSql("SELECT a FROM t WHERE " + ql + " IN (SELECT x FROM t2)")
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		v, ok := Eval(subCtx, stmt.(*expr.SqlSelect).Where.Expr)
+		assert.Tf(t, ok, "evaluates %v", ql)
+		return v
+	}
+	one, five, null := value.NewIntValue(1), value.NewIntValue(5), value.NewNilValue()
+	v := evalIn("5", one, null)
+	assert.Tf(t, isNull(v), "5 IN {1, NULL} should be NULL: %#v", v)
+	v = evalIn("5", null, five)
+	assert.Tf(t, v.Value() == true, "5 IN {NULL, 5} should be true: %#v", v)
+	v = evalIn("5", one)
+	assert.Tf(t, v.Value() == false, "5 IN {1} should be false: %#v", v)
+	v = evalIn("nul", one)
+	assert.Tf(t, isNull(v), "NULL IN {1} should be NULL: %#v", v)
+	v = evalIn("nul")
+	assert.Tf(t, v.Value() == false, "NULL IN {} should be false: %#v", v)
+}
+
+func TestDivideByZero(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"int5":   value.NewIntValue(5),
+		"float5": value.NewNumberValue(5.5),
+		"zero":   value.NewIntValue(0),
+		"zerof":  value.NewNumberValue(0),
+	})
+	eval := func(ql string) (value.Value, error) {
+		exprVm, err := NewVm(ql)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		writeContext := datasource.NewContextSimple()
+		err = exprVm.Execute(writeContext, ctx)
+		v, _ := writeContext.Get("")
+		return v, err
+	}
+	exprs := []string{`int5 / zero`, `int5 % zero`, `float5 / zerof`, `float5 % zerof`, `float5 / zero`, `int5 / zerof`,
+		`int5 / 0`, `float5 % 0`}
+
+	// default policy is NULL
+	for _, ql := range exprs {
+		v, err := eval(ql)
+		assert.Tf(t, err == nil, "%v no error: %v", ql, err)
+		assert.Tf(t, v != nil && v.Type() == value.NilType, "%v should be NULL: %T %v", ql, v, v)
+	}
+
+	ctx.DivZero = expr.DivideByZeroError
+	for _, ql := range exprs {
+		v, err := eval(ql)
+		assert.Tf(t, err != nil, "%v should error", ql)
+		assert.Tf(t, v == nil, "%v should not write result: %v", ql, v)
+	}
+
+	ctx.DivZero = expr.DivideByZeroInf
+	for _, ql := range []string{`int5 / zero`, `float5 / zerof`, `int5 / 0`} {
+		v, err := eval(ql)
+		assert.Tf(t, err == nil, "%v no error: %v", ql, err)
+		assert.Tf(t, math.IsInf(v.Value().(float64), 1), "%v should be +Inf: %v", ql, v)
+	}
+	v, _ := eval(`zerof - float5 / zerof`)
+	assert.Tf(t, math.IsInf(v.Value().(float64), -1), "should be -Inf: %v", v)
+	for _, ql := range []string{`int5 % zero`, `float5 % zerof`, `zero / zero`} {
+		v, err := eval(ql)
+		assert.Tf(t, err == nil, "%v no error: %v", ql, err)
+		assert.Tf(t, math.IsNaN(v.Value().(float64)), "%v should be NaN: %v", ql, v)
+	}
+
+	// non-zero division unaffected by policy
+	v, _ = eval(`int5 / 2`)
+	assert.Tf(t, v.Value() == int64(2), "should be 2: %v", v)
+}
+
+func TestConcat(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"first": value.NewStringValue("bob"),
+		"age":   value.NewIntValue(22),
+		"none":  value.NewNilValue(),
+	})
+	eval := func(ql string) value.Value {
+		stmt, err := expr.ParseSqlDialect("SELECT "+ql+" AS x FROM users", lex.AnsiSqlDialect)
+		assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+		v, _ := Eval(ctx, stmt.(*expr.SqlSelect).Columns[0].Expr)
+		return v
+	}
+	v := eval(`"a" || "b"`)
+	assert.Tf(t, v.ToString() == "ab", "should be ab: %v", v)
+	v = eval(`first || " is " || age`)
+	assert.Tf(t, v.ToString() == "bob is 22", "should be 'bob is 22': %v", v)
+	v = eval(`first || none`)
+	assert.Tf(t, v != nil && v.Type() == value.NilType, "null in, null out: %v", v)
+}
+
+type subQueryContext struct {
+	*datasource.ContextSimple
+	rows [][]value.Value
+}
+
+func (m *subQueryContext) SubQuery(stmt *expr.SqlSelect) ([][]value.Value, error) {
+	return m.rows, nil
+}
+
+func TestInSubQuery(t *testing.T) {
+
+	ctx := &subQueryContext{
+		ContextSimple: msgContext,
+		rows: [][]value.Value{
+			{value.NewIntValue(1), value.NewStringValue("x")},
+			{value.NewIntValue(5), value.NewStringValue("abc")},
+		},
+	}
+	evalWhere := func(sql string, ctx expr.EvalContext) (value.Value, bool) {
+		stmt, err := expr.ParseSql(sql)
+		assert.Tf(t, err == nil, "parse %v: %v", sql, err)
+		sel := stmt.(*expr.SqlSelect)
+		return Eval(ctx, sel.Where.Expr)
+	}
+
+	v, ok := evalWhere(`SELECT a FROM t WHERE (int5, user_id) IN (SELECT x, y FROM t2)`, ctx)
+	assert.Tf(t, ok && v.Value() == true, "should be in sub-query: %v", v)
+
+	v, ok = evalWhere(`SELECT a FROM t WHERE (user_id, int5) IN (SELECT x, y FROM t2)`, ctx)
+	assert.Tf(t, ok && v.Value() == false, "should not be in sub-query: %v", v)
+
+	ctx.rows = [][]value.Value{{value.NewStringValue("abc")}}
+	v, ok = evalWhere(`SELECT a FROM t WHERE user_id IN (SELECT y FROM t2 WHERE x > 1)`, ctx)
+	assert.Tf(t, ok && v.Value() == true, "should be in sub-query: %v", v)
+
+	// context without sub-query support can't evaluate
+	_, ok = evalWhere(`SELECT a FROM t WHERE user_id IN (SELECT y FROM t2)`, msgContext)
+	assert.T(t, !ok)
+}
+
+func TestScalarSubQuery(t *testing.T) {
+
+	ctx := &subQueryContext{ContextSimple: msgContext, rows: [][]value.Value{{value.NewIntValue(4)}}}
+	evalWhere := func(sql string, ctx expr.EvalContext) (value.Value, bool) {
+		stmt, err := expr.ParseSql(sql)
+		assert.Tf(t, err == nil, "parse %v: %v", sql, err)
+		where := stmt.(*expr.SqlSelect).Where.Expr
+		v, ok := Eval(ctx, where)
+		compiled, err := Compile(where)
+		assert.Tf(t, err == nil, "compile %v: %v", sql, err)
+		cv, cok := compiled(ctx)
+		assert.Tf(t, ok == cok && (!ok || v.ToString() == cv.ToString()), "compiled %v %v", v, cv)
+		return v, ok
+	}
+
+	v, ok := evalWhere(`SELECT a FROM t WHERE int5 > (SELECT avg(x) FROM t2)`, ctx)
+	assert.Tf(t, ok && v.Value() == true, "5 > 4: %v", v)
+	v, ok = evalWhere(`SELECT a FROM t WHERE (SELECT avg(x) FROM t2) + 1 = int5`, ctx)
+	assert.Tf(t, ok && v.Value() == true, "4 + 1 = 5: %v", v)
+
+	// no rows is NULL
+	ctx.rows = nil
+	v, ok = evalWhere(`SELECT a FROM t WHERE int5 > (SELECT x FROM t2)`, ctx)
+	assert.Tf(t, ok && v.Type() == value.NilType, "should be NULL: %v", v)
+
+	// more than one row (or column) is not a scalar
+	stmt, _ := expr.ParseSql(`SELECT a FROM t WHERE int5 > (SELECT x FROM t2)`)
+	sub := stmt.(*expr.SqlSelect).Where.Expr.(*expr.BinaryNode).Args[1]
+	ctx.rows = [][]value.Value{{value.NewIntValue(1)}, {value.NewIntValue(2)}}
+	_, ok = Eval(ctx, sub)
+	assert.T(t, !ok)
+	ctx.rows = [][]value.Value{{value.NewIntValue(1), value.NewIntValue(2)}}
+	_, ok = Eval(ctx, sub)
+	assert.T(t, !ok)
+	// context without sub-query support can't evaluate
+	_, ok = Eval(msgContext, sub)
+	assert.T(t, !ok)
+}
+
+func TestMemoContext(t *testing.T) {
+
+	calls := 0
+	expr.FuncAdd("memocount", func(ctx expr.EvalContext, v value.Value) (value.IntValue, bool) {
+		calls++
+		return value.NewIntValue(int64(calls)), true
+	})
+	expr.VolatileFuncAdd("memovolatile", func(ctx expr.EvalContext, v value.Value) (value.IntValue, bool) {
+		calls++
+		return value.NewIntValue(int64(calls)), true
+	})
+	evalCalls := func(ctx expr.EvalContext, exprs ...string) int {
+		calls = 0
+		for _, ql := range exprs {
+			tree, err := expr.ParseExpression(ql)
+			assert.Tf(t, err == nil, "parse %v: %v", ql, err)
+			n := tree.Root
+			_, ok := Eval(ctx, n)
+			assert.Tf(t, ok, "should eval %v", ql)
+			compiled, err := Compile(n)
+			assert.Tf(t, err == nil, "compile %v: %v", ql, err)
+			_, ok = compiled(ctx)
+			assert.Tf(t, ok, "should eval compiled %v", ql)
+		}
+		return calls
+	}
+
+	// without memoization every call is evaluated
+	n := evalCalls(msgContext, `memocount(int5) + memocount(int5)`, `memocount(int5) > 1`)
+	assert.Tf(t, n == 6, "should call 6 times: %v", n)
+
+	// with it, once per row across exprs, evaluated or compiled
+	n = evalCalls(NewMemoContext(msgContext), `memocount(int5) + memocount(int5)`, `memocount(int5) > 1`)
+	assert.Tf(t, n == 1, "should call once: %v", n)
+
+	// different args are different calls
+	n = evalCalls(NewMemoContext(msgContext), `memocount(int5) + memocount(user_id)`)
+	assert.Tf(t, n == 2, "should call twice: %v", n)
+
+	// volatile funcs, and funcs of them, are not memoized
+	n = evalCalls(NewMemoContext(msgContext), `memovolatile(int5) + memovolatile(int5)`)
+	assert.Tf(t, n == 4, "should call 4 times: %v", n)
+	n = evalCalls(NewMemoContext(msgContext), `memocount(memovolatile(int5)) + memocount(memovolatile(int5))`)
+	assert.Tf(t, n == 8, "should call 8 times: %v", n)
+
+	t1, _ := expr.ParseExpression(`yy(memovolatile(created))`)
+	t2, _ := expr.ParseExpression(`yy(created)`)
+	assert.T(t, !expr.IsPure(t1.Root))
+	assert.T(t, expr.IsPure(t2.Root))
+
+	// the memo forwards the optional interfaces of the row
+	ctx := &subQueryContext{ContextSimple: msgContext, rows: [][]value.Value{{value.NewStringValue("abc")}}}
+	stmt, err := expr.ParseSql(`SELECT a FROM t WHERE user_id IN (SELECT y FROM t2)`)
+	assert.T(t, err == nil)
+	v, ok := Eval(NewMemoContext(ctx), stmt.(*expr.SqlSelect).Where.Expr)
+	assert.Tf(t, ok && v.Value() == true, "should be in sub-query: %v", v)
+}
+
+func TestMatch(t *testing.T) {
+
+	ctx := datasource.NewContextSimpleData(map[string]value.Value{
+		"title": value.NewStringValue("The Quick-Brown Fox!"),
+		"body":  value.NewStringValue("jumps over the lazy dog"),
+		"empty": value.NewNilValue(),
+	})
+	match := func(sql string) (value.Value, bool) {
+		stmt, err := expr.ParseSql(sql)
+		assert.Tf(t, err == nil, "parse %v: %v", sql, err)
+		where := stmt.(*expr.SqlSelect).Where.Expr
+		v, ok := Eval(ctx, where)
+		compiled, err := Compile(where)
+		assert.Tf(t, err == nil, "compile %v: %v", sql, err)
+		cv, cok := compiled(ctx)
+		assert.Tf(t, ok == cok && v.Value() == cv.Value(), "compiled matches eval %v: %v %v", sql, v, cv)
+		return v, ok
+	}
+	for sql, want := range map[string]bool{
+		`SELECT a FROM t WHERE MATCH(title) AGAINST("fox")`:             true,
+		`SELECT a FROM t WHERE MATCH(title) AGAINST("QUICK cat")`:       true,
+		`SELECT a FROM t WHERE MATCH(title) AGAINST("dog")`:             false,
+		`SELECT a FROM t WHERE MATCH(title, body) AGAINST("lazy")`:      true,
+		`SELECT a FROM t WHERE MATCH(title, body) AGAINST("fo")`:        false,
+		`SELECT a FROM t WHERE MATCH(empty, missing) AGAINST("fox")`:    false,
+		`SELECT a FROM t WHERE MATCH(title) AGAINST("brown") AND 1 = 2`: false,
+		`SELECT a FROM t WHERE NOT MATCH(body) AGAINST("quick")`:        true,
+		`SELECT a FROM t WHERE MATCH(title) AGAINST("") OR title = "x"`: false,
+	} {
+		v, ok := match(sql)
+		assert.Tf(t, ok && v.Value() == want, "%s want %v got %v", sql, want, v)
+	}
+}
+
+// the LIKE pushed down to mongo as a $regex matches what vm LIKE matches
+func TestLikeMongoRegex(t *testing.T) {
+	patterns := []string{`a%`, `a_c`, `%.%`, `50\%`, `a\_c`, `a\\%`, `[a]%`, `%`}
+	vals := []string{"abc", "a_c", "a.c", "50%", "50", "a\\x", "[a]b", "a\nc", "", "x"}
+	for _, pattern := range patterns {
+		n := expr.NewBinaryNode(lex.Token{T: lex.TokenLike, V: "LIKE"},
+			expr.NewIdentityNode(&lex.Token{T: lex.TokenIdentity, V: "v"}), expr.NewStringNode(0, pattern))
+		f, err := expr.ToMongoFilter(n)
+		assert.Tf(t, err == nil, "%s  no error %v", pattern, err)
+		re := regexp.MustCompile(f["v"].(map[string]interface{})["$regex"].(string))
+		for _, val := range vals {
+			match, err := LikeMatch(val, pattern, LikeEscapeDefault)
+			assert.Tf(t, err == nil, "no error %v", err)
+			assert.Tf(t, re.MatchString(val) == match, "%q LIKE %q is %v, regex %s", val, pattern, match, re)
+		}
+	}
+}
+
 //  Equal function?  returns true if items are equal
 //
 //      eq(item,5)